@@ -5,12 +5,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"time"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/sirupsen/logrus"
 	"github.com/a8m/lease"
+	"github.com/a8m/lease/logrusadapter"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -58,19 +60,19 @@ func main() {
 
 // worker should represent a single ec2 instance in the system.
 // each worker responsible to 'create' random leases, 'handle' and 'delete' the expired
-func newWorker(client *dynamodb.DynamoDB, log lease.Logger) chan struct{} {
+func newWorker(client *dynamodb.DynamoDB, log logrus.FieldLogger) chan struct{} {
 	// exit/done channel
 	done := make(chan struct{})
 
 	// lease
-	leaser := lease.New(&lease.Config{
-		Logger:     log,
+	leaser := lease.NewFromConfig(&lease.Config{
+		Logger:     logrusadapter.New(log),
 		Client:     client,
 		LeaseTable: "lease-table-test",
 	})
 
 	// start taking leases
-	err := leaser.Start()
+	err := leaser.Start(context.Background())
 
 	if err != nil {
 		log.WithError(err).Fatal("start leaser")
@@ -95,7 +97,7 @@ func newWorker(client *dynamodb.DynamoDB, log lease.Logger) chan struct{} {
 						// if this task handled successfully, remove it from the lease table
 						if ok && status.(int) == DONE {
 							log.WithField("expired task", task.Key).Info("deleting")
-							if err := leaser.Delete(task); err != nil {
+							if err := leaser.Delete(context.Background(), task); err != nil {
 								log.WithField("task name", task.Key).WithError(err).Error("deleting failed")
 							} else {
 								log.WithField("task name", task.Key).Info("deleted successfully")
@@ -124,7 +126,7 @@ func newWorker(client *dynamodb.DynamoDB, log lease.Logger) chan struct{} {
 						task.SetAs("results", []string{"200", "500", "404"}, lease.StringSet)
 						// after finishing the job handling we force updating to
 						// avoid duplication work
-						if _, err := leaser.ForceUpdate(task); err != nil {
+						if _, err := leaser.ForceUpdate(context.Background(), task); err != nil {
 							log.WithField("task name", task.Key).WithError(err).Error("update failed")
 						} else {
 							log.WithField("task name", task.Key).Info("updated successfully")
@@ -137,7 +139,7 @@ func newWorker(client *dynamodb.DynamoDB, log lease.Logger) chan struct{} {
 					task := lease.NewLease(fmt.Sprintf("task-%d-%d", i, rand.Intn(1e3)))
 					task.Set("created_at", time.Now().Unix())
 					task.Set(TASK_STATUS, CREATED)
-					l, err := leaser.Create(task)
+					l, err := leaser.Create(context.Background(), task)
 					if err != nil {
 						log.WithError(err).Error("create lease")
 					} else {
@@ -7,10 +7,12 @@
 package main
 
 import (
+	"context"
 	"time"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/sirupsen/logrus"
 	"github.com/a8m/lease"
+	"github.com/a8m/lease/logrusadapter"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -24,14 +26,14 @@ func main() {
 		Region: aws.String("us-east-1"),
 	})
 
-	leaser := lease.New(&lease.Config{
-		Logger:     log,
+	leaser := lease.NewFromConfig(&lease.Config{
+		Logger:     logrusadapter.New(log),
 		Client:     dynamodb.New(sess),
 		LeaseTable: "lease-table-test",
 	})
 
 	// start taking leases
-	err := leaser.Start()
+	err := leaser.Start(context.Background())
 
 	if err != nil {
 		log.WithError(err).Fatal("start leaser")
@@ -55,7 +57,7 @@ func main() {
 					// use Set() if you want this attribute to be a list.
 					task.SetAs("results", []string{"200", "500", "404"}, lease.StringSet)
 					task.Set("last_update", time.Now().Unix())
-					if _, err := leaser.Update(task); err != nil {
+					if _, err := leaser.Update(context.Background(), task); err != nil {
 						log.WithError(err).Error("updating lease")
 					}
 				}
@@ -7,10 +7,12 @@
 package main
 
 import (
+	"context"
 	"time"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/sirupsen/logrus"
 	"github.com/a8m/lease"
+	"github.com/a8m/lease/logrusadapter"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -28,8 +30,8 @@ func main() {
 	client := dynamodb.New(sess)
 
 	// leases creator
-	leaseCreator := lease.New(&lease.Config{
-		Logger:     log,
+	leaseCreator := lease.NewFromConfig(&lease.Config{
+		Logger:     logrusadapter.New(log),
 		Client:     client,
 		LeaseTable: "lease-table-test",
 	})
@@ -37,7 +39,7 @@ func main() {
 	// create four leases
 	tasks := []string{"foo", "bar", "baz", "qux"}
 	for _, task := range tasks {
-		l, err := leaseCreator.Create(lease.Lease{Key: task})
+		l, err := leaseCreator.Create(context.Background(), lease.Lease{Key: task})
 		if err != nil {
 			log.WithError(err).Error("create lease")
 		} else {
@@ -66,14 +68,14 @@ func worker(client *dynamodb.DynamoDB, log *logrus.Logger) chan struct{} {
 	done := make(chan struct{})
 
 	// leases creator
-	leaser := lease.New(&lease.Config{
-		Logger:     log,
+	leaser := lease.NewFromConfig(&lease.Config{
+		Logger:     logrusadapter.New(log),
 		Client:     client,
 		LeaseTable: "lease-table-test",
 	})
 
 	// start taking leases
-	err := leaser.Start()
+	err := leaser.Start(context.Background())
 
 	if err != nil {
 		log.WithError(err).Fatal("start leaser")
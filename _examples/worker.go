@@ -3,10 +3,12 @@
 package main
 
 import (
+	"context"
 	"time"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/sirupsen/logrus"
 	"github.com/a8m/lease"
+	"github.com/a8m/lease/logrusadapter"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -20,14 +22,13 @@ func main() {
 		Region: aws.String("us-east-1"),
 	})
 
-	leaser := lease.New(&lease.Config{
-		Logger:     log,
-		Client:     dynamodb.New(sess),
-		LeaseTable: "lease-table-test",
-	})
+	leaser := lease.New("lease-table-test",
+		lease.WithLogger(logrusadapter.New(log)),
+		lease.WithClient(dynamodb.New(sess)),
+	)
 
 	// start taking leases
-	err := leaser.Start()
+	err := leaser.Start(context.Background())
 
 	if err != nil {
 		log.WithError(err).Fatal("start leaser")
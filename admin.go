@@ -0,0 +1,288 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// ErrConfirmRequired error will be returns if a destructive admin operation is called
+	// directly, while Config.AdminConfirmWindow is set. See: Admin.Propose.
+	ErrConfirmRequired = errors.New("leaser: admin operation requires a confirmed intent")
+
+	// ErrIntentExpired error will be returns if the applied intent does not exist, was
+	// already applied (or is being applied), or was not applied within the confirm window
+	// of its proposer.
+	ErrIntentExpired = errors.New("leaser: admin intent does not exist or expired")
+
+	// ErrApproverRequired error will be returns if an intent that requires a second
+	// approver (see: Config.AdminRequireApprover) is applied by its proposer, or with no
+	// approver.
+	ErrApproverRequired = errors.New("leaser: admin intent must be applied by another approver")
+
+	// ErrDeleteTableUnsupported error will be returns if the table is deleted with a
+	// client that does not support deleting tables.
+	ErrDeleteTableUnsupported = errors.New("leaser: client does not support deleting tables")
+)
+
+// AdminOp is a destructive administrative operation. See: Admin.
+type AdminOp string
+
+const (
+	// AdminForceTake takes a lease for the worker of the admin config, regardless of its owner.
+	AdminForceTake AdminOp = "forceTake"
+	// AdminEvictAll sets all the leases in the table to have no owner.
+	AdminEvictAll AdminOp = "evictAll"
+	// AdminDeleteTable deletes the lease table.
+	AdminDeleteTable AdminOp = "deleteTable"
+)
+
+// Intent is a proposed admin operation, that waits to be applied. See: Admin.Propose.
+type Intent struct {
+	// Id is the token that is used to apply the intent.
+	Id string
+	Op AdminOp
+	// Key is the key of the lease that the operation targets, if any.
+	Key      string
+	Proposer string
+	// ExpiresAt is the end of the confirm window of the proposer.
+	ExpiresAt time.Time
+	// RequireApprover reports whether the intent must be applied by another approver
+	// than its proposer. It's set by the Config.AdminRequireApprover of the proposer.
+	RequireApprover bool
+}
+
+// tableDeleter is implemented by the clients that support deleting tables, e.g: *dynamodb.DynamoDB.
+type tableDeleter interface {
+	DeleteTableWithContext(aws.Context, *dynamodb.DeleteTableInput, ...request.Option) (*dynamodb.DeleteTableOutput, error)
+}
+
+// Admin runs destructive administrative operations on the lease table, e.g: from support
+// tooling. If Config.AdminConfirmWindow is set, the operations need two steps: an intent is
+// proposed and stored in the lease table, and it's applied by its token within the window,
+// optionally by a second approver (see: Config.AdminRequireApprover). It protects production
+// tables from fat-fingered tooling. The confirm window and the approver requirement of an
+// intent are stored with it, so they are enforced on Apply regardless of the config of the
+// approver.
+type Admin struct {
+	*Config
+	manager *LeaseManager
+}
+
+// NewAdmin create new Admin with the given config.
+func NewAdmin(config *Config) *Admin {
+	config.defaults()
+	return &Admin{config, &LeaseManager{config, newSerializer(config)}}
+}
+
+// ForceTake takes the lease with the given key for the worker of the config, regardless
+// of its current owner. Fails with ErrConfirmRequired if the operations need two steps.
+func (a *Admin) ForceTake(ctx context.Context, key string) error {
+	if a.AdminConfirmWindow > 0 {
+		return ErrConfirmRequired
+	}
+	_, err := a.apply(ctx, AdminForceTake, key)
+	return err
+}
+
+// EvictAll sets all the leases in the table to have no owner, and returns the number of
+// leases that were evicted. Fails with ErrConfirmRequired if the operations need two steps.
+func (a *Admin) EvictAll(ctx context.Context) (int, error) {
+	if a.AdminConfirmWindow > 0 {
+		return 0, ErrConfirmRequired
+	}
+	return a.apply(ctx, AdminEvictAll, "")
+}
+
+// DeleteTable deletes the lease table. Fails with ErrConfirmRequired if the operations
+// need two steps.
+func (a *Admin) DeleteTable(ctx context.Context) error {
+	if a.AdminConfirmWindow > 0 {
+		return ErrConfirmRequired
+	}
+	_, err := a.apply(ctx, AdminDeleteTable, "")
+	return err
+}
+
+// Propose stores an intent to run the given operation on the lease with the given key
+// (empty for the table-wide operations), and returns it. The intent expires after
+// Config.AdminConfirmWindow. Pass its Id to Apply to run the operation.
+func (a *Admin) Propose(ctx context.Context, op AdminOp, key, proposer string) (Intent, error) {
+	if a.AdminConfirmWindow <= 0 {
+		return Intent{}, errors.New("leaser: AdminConfirmWindow is not set")
+	}
+	id, err := uuid()
+	if err != nil {
+		return Intent{}, err
+	}
+	intent := Intent{Id: id, Op: op, Key: key, Proposer: proposer, ExpiresAt: a.now().Add(a.AdminConfirmWindow), RequireApprover: a.AdminRequireApprover}
+	_, err = a.Client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(a.LeaseTable),
+		Item: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey:            {S: aws.String(AdminIntentPrefix + id)},
+			LeaseAdminOpKey:        {S: aws.String(string(op))},
+			LeaseAdminTargetKey:    {S: aws.String(key)},
+			LeaseAdminProposerKey:  {S: aws.String(proposer)},
+			LeaseAdminExpiresAtKey: {N: aws.String(strconv.FormatInt(intent.ExpiresAt.UnixNano()/int64(time.Millisecond), 10))},
+			LeaseAdminRequireKey:   {BOOL: aws.Bool(intent.RequireApprover)},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String(LeaseKeyKey),
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#key)"),
+	})
+	if err != nil {
+		return Intent{}, a.manager.wrapError("propose", AdminIntentPrefix+id, err)
+	}
+	a.Logger.Infof("%s proposed admin operation %s %s (intent %s)", proposer, op, key, id)
+	return intent, nil
+}
+
+// Apply runs the operation of the intent with the given id, and consumes the intent once
+// the operation succeeds. The confirm window and the approver requirement are read from
+// the persisted intent, i.e: they are the ones of its proposer. Fails with ErrIntentExpired
+// if the intent does not exist, expired or is applied concurrently, and with
+// ErrApproverRequired if it needs a second approver, and it's applied by its proposer.
+// A failed operation leaves the intent to be applied again within its window.
+func (a *Admin) Apply(ctx context.Context, id, approver string) error {
+	key := map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey: {S: aws.String(AdminIntentPrefix + id)},
+	}
+	out, err := a.Client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(a.LeaseTable),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return a.manager.wrapError("apply", AdminIntentPrefix+id, err)
+	}
+	intent, ok := readIntent(id, out.Item)
+	if !ok || a.now().After(intent.ExpiresAt) {
+		return ErrIntentExpired
+	}
+	if (intent.RequireApprover || a.AdminRequireApprover) && (approver == "" || approver == intent.Proposer) {
+		return ErrApproverRequired
+	}
+	// claim the intent, so it's applied only once, even by concurrent approvers.
+	_, err = a.Client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(a.LeaseTable),
+		Key:       key,
+		ExpressionAttributeNames: map[string]*string{
+			"#key":      aws.String(LeaseKeyKey),
+			"#approver": aws.String(LeaseAdminApproverKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":approver": {S: aws.String(approver)},
+		},
+		ConditionExpression: aws.String("attribute_exists(#key) AND attribute_not_exists(#approver)"),
+		UpdateExpression:    aws.String("SET #approver = :approver"),
+	})
+	if isConditionalFailed(err) {
+		return ErrIntentExpired
+	}
+	if err != nil {
+		return a.manager.wrapError("apply", AdminIntentPrefix+id, err)
+	}
+	a.Logger.Infof("%s applied admin operation %s %s (intent %s of %s)", approver, intent.Op, intent.Key, id, intent.Proposer)
+	if _, err := a.apply(ctx, intent.Op, intent.Key); err != nil {
+		// release the claim, so the intent can be applied again.
+		_, uerr := a.Client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(a.LeaseTable),
+			Key:       key,
+			ExpressionAttributeNames: map[string]*string{
+				"#approver": aws.String(LeaseAdminApproverKey),
+			},
+			UpdateExpression: aws.String("REMOVE #approver"),
+		})
+		if uerr != nil {
+			a.Logger.WithError(uerr).Warnf("admin: failed to release intent %s", id)
+		}
+		return err
+	}
+	// consume the intent. the table does not exist anymore after AdminDeleteTable.
+	_, err = a.Client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(a.LeaseTable),
+		Key:       key,
+	})
+	if err != nil && !isNotFound(err) {
+		// the claimed intent can't be applied again, and it lapses with its window.
+		a.Logger.WithError(err).Warnf("admin: failed to consume intent %s", id)
+	}
+	return nil
+}
+
+// apply runs the given operation, and returns the number of affected leases.
+func (a *Admin) apply(ctx context.Context, op AdminOp, key string) (int, error) {
+	switch op {
+	case AdminForceTake:
+		lease, err := a.manager.GetLease(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		lease.takeReason = TakeoverForced
+		if err := a.manager.TakeLease(ctx, lease); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case AdminEvictAll:
+		list, err := a.manager.ListLeases(ctx)
+		if err != nil {
+			return 0, err
+		}
+		n := 0
+		for _, lease := range liveLeases(list) {
+			if lease.hasNoOwner() {
+				continue
+			}
+			if eerr := a.manager.EvictLease(ctx, lease); eerr != nil {
+				a.Logger.WithError(eerr).Warnf("admin: failed to evict lease %s", lease.Key)
+				err = eerr
+				continue
+			}
+			n++
+		}
+		a.Logger.Infof("admin: evicted %d leases", n)
+		return n, err
+	case AdminDeleteTable:
+		return 0, a.manager.DeleteLeaseTable(ctx)
+	}
+	return 0, errors.New("leaser: unknown admin operation " + string(op))
+}
+
+// readIntent reads the intent with the given id from the given item.
+func readIntent(id string, item map[string]*dynamodb.AttributeValue) (Intent, bool) {
+	if len(item) == 0 || item[LeaseAdminExpiresAtKey] == nil {
+		return Intent{}, false
+	}
+	ms, err := strconv.ParseInt(aws.StringValue(item[LeaseAdminExpiresAtKey].N), 10, 64)
+	if err != nil {
+		return Intent{}, false
+	}
+	intent := Intent{Id: id, ExpiresAt: time.Unix(0, ms*int64(time.Millisecond))}
+	if v := item[LeaseAdminOpKey]; v != nil {
+		intent.Op = AdminOp(aws.StringValue(v.S))
+	}
+	if v := item[LeaseAdminTargetKey]; v != nil {
+		intent.Key = aws.StringValue(v.S)
+	}
+	if v := item[LeaseAdminProposerKey]; v != nil {
+		intent.Proposer = aws.StringValue(v.S)
+	}
+	if v := item[LeaseAdminRequireKey]; v != nil {
+		intent.RequireApprover = aws.BoolValue(v.BOOL)
+	}
+	return intent, true
+}
+
+// isInternalItem test if the item with the given key is not a lease, but an internal
+// item of this package (e.g: the steal budget bucket, the maintenance mode, or an admin intent).
+func isInternalItem(key string) bool {
+	return key == StealBudgetKey || key == MaintenanceKey || strings.HasPrefix(key, AdminIntentPrefix)
+}
@@ -0,0 +1,124 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func intentItem(proposer string, expires time.Time) *dynamodb.GetItemOutput {
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:            {S: aws.String(AdminIntentPrefix + "id")},
+		LeaseAdminOpKey:        {S: aws.String(string(AdminForceTake))},
+		LeaseAdminTargetKey:    {S: aws.String("foo")},
+		LeaseAdminProposerKey:  {S: aws.String(proposer)},
+		LeaseAdminExpiresAtKey: {N: aws.String(strconv.FormatInt(expires.UnixNano()/int64(time.Millisecond), 10))},
+		LeaseAdminRequireKey:   {BOOL: aws.Bool(true)},
+	}}
+}
+
+func TestAdminConfirm(t *testing.T) {
+	lease := &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:     {S: aws.String("foo")},
+		LeaseOwnerKey:   {S: aws.String("2")},
+		LeaseCounterKey: {N: aws.String("5")},
+	}}
+	conflict := awserr.New(ConditionalFailed, "", nil)
+	client := newClientMock(map[method]args{
+		methodPutItem: {new(dynamodb.PutItemOutput)},
+		methodGetItem: {
+			intentItem("alice", time.Now().Add(time.Minute)),
+			intentItem("alice", time.Now().Add(-time.Second)),
+			intentItem("alice", time.Now().Add(time.Minute)),
+			lease,
+			intentItem("alice", time.Now().Add(time.Minute)),
+			lease,
+			intentItem("alice", time.Now().Add(time.Minute)),
+		},
+		methodDeleteItem: {new(dynamodb.DeleteItemOutput)},
+		methodUpdateItem: {
+			// the claim of the intent, the failed take, and the release of the claim.
+			new(dynamodb.UpdateItemOutput), conflict, new(dynamodb.UpdateItemOutput),
+			// the claim of the intent, and the take.
+			new(dynamodb.UpdateItemOutput), new(dynamodb.UpdateItemOutput),
+			// the claim of an intent that is applied concurrently.
+			conflict,
+		},
+	})
+	manager := newTestManager(client)
+	manager.AdminConfirmWindow = time.Minute
+	manager.AdminRequireApprover = true
+	admin := &Admin{manager.Config, manager}
+	ctx := context.Background()
+
+	assert(t, admin.ForceTake(ctx, "foo") == ErrConfirmRequired, "expect direct operations to require a confirmed intent")
+	intent, err := admin.Propose(ctx, AdminForceTake, "foo", "alice")
+	assert(t, err == nil && intent.Id != "" && intent.RequireApprover, "expect the intent to be stored")
+	assert(t, intent.ExpiresAt.After(time.Now()), "expect the intent to expire after the window")
+
+	// the approver requirement is read from the intent.
+	manager.AdminRequireApprover = false
+	assert(t, admin.Apply(ctx, "id", "alice") == ErrApproverRequired, "expect the proposer not to apply its own intent")
+	assert(t, admin.Apply(ctx, "id", "bob") == ErrIntentExpired, "expect expired intents not to be applied")
+	assert(t, admin.Apply(ctx, "id", "bob") != nil, "expect the failed operation to be returned")
+	assert(t, client.calls[methodDeleteItem] == 0 && client.calls[methodUpdateItem] == 3, "expect the intent to be released")
+	assert(t, admin.Apply(ctx, "id", "bob") == nil, "expect the intent to be applied by another approver")
+	assert(t, client.calls[methodDeleteItem] == 1, "expect the intent to be consumed")
+	assert(t, client.calls[methodUpdateItem] == 5, "expect the lease to be taken")
+	assert(t, admin.Apply(ctx, "id", "carol") == ErrIntentExpired, "expect a claimed intent not to be applied again")
+}
+
+func TestAdminDeleteTableUnsupported(t *testing.T) {
+	manager := newTestManager(newClientMock(nil))
+	admin := &Admin{manager.Config, manager}
+	err := admin.DeleteTable(context.Background())
+	assert(t, err != nil && errors.Is(err, ErrDeleteTableUnsupported), "expect to fail with clients that can't delete tables")
+}
+
+func TestAdminPause(t *testing.T) {
+	paused := map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:               {S: aws.String(MaintenanceKey)},
+		LeaseMaintenanceReasonKey: {S: aws.String("db upgrade")},
+	}
+	free := map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:     {S: aws.String("foo")},
+		LeaseOwnerKey:   {S: aws.String("NULL")},
+		LeaseCounterKey: {N: aws.String("1")},
+	}
+	client := newClientMock(map[method]args{
+		methodPutItem: {new(dynamodb.PutItemOutput)},
+		methodScan: {
+			&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{paused, free}},
+			&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{free}},
+		},
+		methodUpdateItem: {new(dynamodb.UpdateItemOutput)},
+	})
+	manager := newTestManager(client)
+	manager.ExpireAfter = time.Minute
+	manager.maintenance = &maintenance{}
+	admin := &Admin{manager.Config, manager}
+	ctx := context.Background()
+
+	mode, err := admin.Pause(ctx, "db upgrade", "alice", time.Hour)
+	assert(t, err == nil && mode.IsActive(time.Now()), "expect the fleet to be paused")
+	assert(t, !mode.IsActive(time.Now().Add(2*time.Hour)), "expect the maintenance mode to lapse")
+
+	taker := &leaseTaker{Config: manager.Config, manager: manager, allLeases: make(map[string]*Lease)}
+	assert(t, taker.Take(ctx) == nil, "expect the paused taker not to fail")
+	assert(t, client.calls[methodUpdateItem] == 0, "expect no leases to be taken while paused")
+	assert(t, taker.Take(ctx) == nil && client.calls[methodUpdateItem] == 1, "expect the taker to resume after the mode is cleared")
+}
+
+func TestAdminPauseBackend(t *testing.T) {
+	admin := NewAdmin(&Config{WorkerId: "1", LeaseTable: "test", Logger: testLogger(), Backend: NewMemoryBackend()})
+	ctx := context.Background()
+	_, err := admin.Pause(ctx, "db upgrade", "alice", time.Hour)
+	assert(t, errors.Is(err, ErrBackendUnsupported), "expect not to pause a fleet on a backend")
+	assert(t, errors.Is(admin.Resume(ctx), ErrBackendUnsupported), "expect not to resume a fleet on a backend")
+}
@@ -0,0 +1,140 @@
+package lease
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LeaseView is the JSON representation of a lease, e.g: in the AdminHandler responses.
+type LeaseView struct {
+	Key            string                 `json:"key"`
+	Owner          string                 `json:"owner"`
+	Counter        int64                  `json:"counter"`
+	Epoch          int                    `json:"epoch"`
+	OwnerHost      string                 `json:"ownerHost,omitempty"`
+	OwnerZone      string                 `json:"ownerZone,omitempty"`
+	OwnerVersion   string                 `json:"ownerVersion,omitempty"`
+	TakeoverReason TakeoverReason         `json:"takeoverReason,omitempty"`
+	ReservedBy     string                 `json:"reservedBy,omitempty"`
+	Holders        map[string]int64       `json:"holders,omitempty"`
+	Group          string                 `json:"group,omitempty"`
+	DependsOn      []string               `json:"dependsOn,omitempty"`
+	ParentKeys     []string               `json:"parentKeys,omitempty"`
+	CompletedAt    *time.Time             `json:"completedAt,omitempty"`
+	LoadHint       float64                `json:"loadHint,omitempty"`
+	Fields         map[string]interface{} `json:"fields,omitempty"`
+}
+
+// NewLeaseView returns the view of the given lease.
+func NewLeaseView(lease Lease) LeaseView {
+	v := LeaseView{
+		Key:            lease.Key,
+		Owner:          lease.Owner,
+		Counter:        lease.Counter,
+		Epoch:          lease.Epoch,
+		OwnerHost:      lease.OwnerHost,
+		OwnerZone:      lease.OwnerZone,
+		OwnerVersion:   lease.OwnerVersion,
+		TakeoverReason: lease.TakeoverReason,
+		ReservedBy:     lease.ReservedBy,
+		Holders:        lease.Holders,
+		Group:          lease.Group,
+		DependsOn:      lease.DependsOn,
+		ParentKeys:     lease.ParentKeys,
+		LoadHint:       lease.LoadHint,
+	}
+	if !lease.CompletedAt.IsZero() {
+		v.CompletedAt = &lease.CompletedAt
+	}
+	if fields := lease.Fields(); len(fields) > 0 {
+		v.Fields = fields
+	}
+	return v
+}
+
+// AdminHandler returns an http.Handler that lets operators inspect the lease table and
+// the worker, and intervene, without querying the table by hand. The responses are JSON:
+//
+//	GET  /leases             all the leases in the table.
+//	GET  /leases/held        the leases held by this worker.
+//	GET  /stats              the Stats of this worker.
+//	GET  /workers            the Workers of the fleet.
+//	POST /leases/{key}/evict sets the lease to have no owner, so it's taken again.
+//	POST /rebalance          runs the next take cycle of this worker now.
+//
+// The actions are not protected by Config.AdminConfirmWindow, so mount the handler behind
+// the authentication of the application. for example:
+//
+//	http.Handle("/admin/leases/", http.StripPrefix("/admin/leases", leaser.AdminHandler()))
+func (c *Coordinator) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(r.URL.Path, "/")
+		switch {
+		case r.Method == http.MethodGet && path == "leases":
+			list, err := c.Manager.ScanLeases(r.Context())
+			if err != nil {
+				adminError(w, err)
+				return
+			}
+			views := make([]LeaseView, 0, len(list))
+			for _, lease := range liveLeases(list) {
+				views = append(views, NewLeaseView(*lease))
+			}
+			writeJSON(w, http.StatusOK, views)
+		case r.Method == http.MethodGet && path == "leases/held":
+			held := c.GetHeldLeases()
+			views := make([]LeaseView, 0, len(held))
+			for _, lease := range held {
+				views = append(views, NewLeaseView(lease))
+			}
+			writeJSON(w, http.StatusOK, views)
+		case r.Method == http.MethodGet && path == "stats":
+			writeJSON(w, http.StatusOK, c.Stats())
+		case r.Method == http.MethodGet && path == "workers":
+			writeJSON(w, http.StatusOK, c.Workers())
+		case r.Method == http.MethodPost && strings.HasPrefix(path, "leases/") && strings.HasSuffix(path, "/evict"):
+			key := strings.TrimSuffix(strings.TrimPrefix(path, "leases/"), "/evict")
+			lease, err := c.Manager.GetLease(r.Context(), key)
+			if err == nil {
+				err = c.Manager.EvictLease(r.Context(), lease)
+			}
+			if err != nil {
+				adminError(w, err)
+				return
+			}
+			c.cache.invalidate(key)
+			c.Logger.Infof("admin: evicted lease %s", key)
+			writeJSON(w, http.StatusOK, NewLeaseView(*lease))
+		case r.Method == http.MethodPost && path == "rebalance":
+			select {
+			case c.takeNow <- struct{}{}:
+			default:
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// adminError writes the given error of an AdminHandler request.
+func adminError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrLeaseNotFound):
+		code = http.StatusNotFound
+	case isConditionalFailed(err):
+		code = http.StatusConflict
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// writeJSON writes the given value in JSON format, with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
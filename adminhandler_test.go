@@ -0,0 +1,47 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := NewFromConfig(&Config{WorkerId: "1", LeaseTable: "test", Logger: testLogger(), Backend: NewMemoryBackend()}).(*Coordinator)
+	_, err := c.EnsureLeases(ctx, []string{"foo", "bar"})
+	assert(t, err == nil, "expect the leases to be created")
+	assert(t, c.Start(ctx) == nil, "expect the coordinator to start")
+	defer c.Stop()
+	_, err = c.WaitForOwnership(ctx, "foo")
+	assert(t, err == nil, "expect the worker to take the lease")
+
+	h := c.AdminHandler()
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	var views []LeaseView
+	w := serve("GET", "/leases")
+	assert(t, w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &views) == nil, "expect to list the leases")
+	assert(t, len(views) == 2, "expect all the leases of the table")
+	w = serve("GET", "/leases/held")
+	assert(t, w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &views) == nil, "expect to list the held leases")
+	assert(t, len(views) > 0 && views[0].Owner == "1", "expect the leases held by the worker")
+	var stats Stats
+	w = serve("GET", "/stats")
+	assert(t, w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &stats) == nil && stats.WorkerId == "1", "expect the stats of the worker")
+
+	w = serve("POST", "/leases/foo/evict")
+	assert(t, w.Code == http.StatusOK, "expect the lease to be evicted")
+	lease, _ := c.Manager.GetLease(ctx, "foo")
+	assert(t, lease.hasNoOwner(), "expect the evicted lease to have no owner")
+	assert(t, serve("POST", "/leases/baz/evict").Code == http.StatusNotFound, "expect a missing lease not to be found")
+	assert(t, serve("POST", "/rebalance").Code == http.StatusAccepted, "expect the take cycle to be scheduled")
+	assert(t, serve("DELETE", "/leases").Code == http.StatusNotFound, "expect unknown routes not to be found")
+}
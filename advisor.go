@@ -0,0 +1,104 @@
+package lease
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Observation is the observed state of a fleet, that used by Advise to recommend the
+// table capacity and the interval settings.
+type Observation struct {
+	// Leases is the number of leases in the table, and Workers is the number of workers
+	// in the fleet.
+	Leases  int
+	Workers int
+	// RenewLatency is the latency of a single renewal (e.g: p99 of the RenewLease calls).
+	RenewLatency time.Duration
+	// ItemSize is the average size (in bytes) of a lease item. See: Lease.Size.
+	// defaults to 1KB.
+	ItemSize int
+	// ConsumedReadCapacity and ConsumedWriteCapacity are the consumed capacity units per
+	// second of the lease table (e.g: from CloudWatch).
+	ConsumedReadCapacity  float64
+	ConsumedWriteCapacity float64
+}
+
+// Advice is the recommendation of Advise. Warnings describes the settings that are
+// at risk, e.g: renewal overrun or under-provisioned table.
+type Advice struct {
+	ReadCapacity  int
+	WriteCapacity int
+	ExpireAfter   time.Duration
+	Warnings      []string
+}
+
+// capacityHeadroom is the factor of the recommended capacity over the expected usage.
+const capacityHeadroom = 1.5
+
+// Advise recommends the table capacity and the ExpireAfter setting for the given config
+// and observation. The estimation assumes that each worker scans the table on each taker
+// and renewer cycle, and that it renews its leases sequentially on each renewer cycle.
+//
+// A renewal overrun happens when a worker cannot renew all its leases within a renewer
+// cycle; its leases expire and they are taken by other workers, although it's alive.
+func Advise(config Config, obs Observation) Advice {
+	if config.ExpireAfter == 0 {
+		config.ExpireAfter = 10 * time.Second
+	}
+	if obs.ItemSize == 0 {
+		obs.ItemSize = 1 << 10
+	}
+	workers := max(obs.Workers, 1)
+	renewerInterval := config.ExpireAfter / 3
+	takerInterval := config.ExpireAfter * 2
+	advice := Advice{ExpireAfter: config.ExpireAfter}
+
+	// a scan reads all the items, and it's eventually consistent (0.5 unit per 4KB).
+	scanUnits := math.Ceil(float64(obs.Leases*obs.ItemSize)/(4<<10)) / 2
+	scansPerSec := float64(workers) * (1/renewerInterval.Seconds() + 1/takerInterval.Seconds())
+	reads := math.Max(scanUnits*scansPerSec, obs.ConsumedReadCapacity)
+	// each lease is renewed on each renewer cycle (1 unit per 1KB).
+	writeUnits := math.Ceil(float64(obs.ItemSize) / (1 << 10))
+	writes := math.Max(float64(obs.Leases)*writeUnits/renewerInterval.Seconds(), obs.ConsumedWriteCapacity)
+	advice.ReadCapacity = int(math.Ceil(reads * capacityHeadroom))
+	advice.WriteCapacity = int(math.Ceil(writes * capacityHeadroom))
+
+	// an on-demand table has no provisioned capacity to warn about.
+	if config.OnDemand {
+		config.LeaseTableReadCap, config.LeaseTableWriteCap = 0, 0
+	}
+	if config.LeaseTableReadCap > 0 && float64(config.LeaseTableReadCap) < reads {
+		advice.Warnings = append(advice.Warnings, fmt.Sprintf("read capacity %d is below the expected usage of %.1f units/sec",
+			config.LeaseTableReadCap,
+			reads))
+	}
+	if config.LeaseTableWriteCap > 0 && float64(config.LeaseTableWriteCap) < writes {
+		advice.Warnings = append(advice.Warnings, fmt.Sprintf("write capacity %d is below the expected usage of %.1f units/sec",
+			config.LeaseTableWriteCap,
+			writes))
+	}
+
+	// keep the renewal cycle within half of the renewer interval.
+	held := (obs.Leases + workers - 1) / workers
+	if cycle := time.Duration(held) * obs.RenewLatency; cycle > 0 {
+		if cycle > renewerInterval {
+			advice.Warnings = append(advice.Warnings, fmt.Sprintf("renewal overrun: renewing %d leases takes %s, and the renewer interval is %s",
+				held,
+				cycle,
+				renewerInterval))
+		}
+		if expireAfter := (6 * cycle).Round(time.Second); expireAfter > advice.ExpireAfter {
+			advice.ExpireAfter = expireAfter
+		}
+	}
+	return advice
+}
+
+// simple max function implemetation. see: min.
+func max(i, j int) int {
+	if i < j {
+		return j
+	}
+	return i
+}
@@ -0,0 +1,31 @@
+package lease
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdvise(t *testing.T) {
+	config := Config{ExpireAfter: 30 * time.Second, LeaseTableReadCap: 20, LeaseTableWriteCap: 10}
+	advice := Advise(config, Observation{Leases: 100, Workers: 10, RenewLatency: 10 * time.Millisecond})
+	// 100 leases renewed every 10s.
+	assert(t, advice.WriteCapacity == 15, "expect the write capacity to cover the renewals")
+	assert(t, advice.ReadCapacity > 0, "expect the read capacity to cover the scans")
+	assert(t, advice.ExpireAfter == config.ExpireAfter, "expect ExpireAfter not to be changed")
+	assert(t, len(advice.Warnings) == 0, "expect no warnings")
+
+	advice = Advise(config, Observation{Leases: 1000, Workers: 2, RenewLatency: 50 * time.Millisecond, ConsumedWriteCapacity: 200})
+	assert(t, advice.WriteCapacity == 300, "expect the write capacity to cover the consumed capacity")
+	assert(t, advice.ExpireAfter == 150*time.Second, "expect to recommend longer ExpireAfter")
+	var overrun bool
+	for _, w := range advice.Warnings {
+		overrun = overrun || strings.Contains(w, "renewal overrun")
+	}
+	assert(t, overrun, "expect to warn about renewal overrun")
+	assert(t, len(advice.Warnings) == 3, "expect to warn about the table capacity")
+
+	config.OnDemand = true
+	advice = Advise(config, Observation{Leases: 1000, Workers: 2, RenewLatency: 50 * time.Millisecond, ConsumedWriteCapacity: 200})
+	assert(t, len(advice.Warnings) == 1, "expect not to warn about the capacity of an on-demand table")
+}
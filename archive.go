@@ -0,0 +1,104 @@
+package lease
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Archiver used to keep a final snapshot of each deleted lease, so completed work
+// units remain auditable after cleanup. The snapshot is the lease item as it was
+// stored in the table (i.e: its owner, counter and extra fields), with the time it
+// was deleted (LeaseDeletedAtKey) and the worker that deleted it (LeaseDeletedByKey).
+// See: NewS3Archiver and NewTableArchiver.
+type Archiver interface {
+	Archive(snapshot map[string]*dynamodb.AttributeValue) error
+}
+
+// s3Archiver is an Archiver that stores each snapshot as a JSON object in S3.
+type s3Archiver struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Archiver returns an Archiver that stores the snapshots as JSON objects in the
+// given S3 bucket, under prefix/leaseKey/deletedAt.json.
+func NewS3Archiver(client s3iface.S3API, bucket, prefix string) Archiver {
+	return &s3Archiver{client, bucket, prefix}
+}
+
+func (a *s3Archiver) Archive(snapshot map[string]*dynamodb.AttributeValue) error {
+	fields := make(map[string]interface{})
+	if err := dynamodbattribute.UnmarshalMap(snapshot, &fields); err != nil {
+		return err
+	}
+	p, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	name := path.Join(a.prefix, aws.StringValue(snapshot[LeaseKeyKey].S), aws.StringValue(snapshot[LeaseDeletedAtKey].N)+".json")
+	_, err = a.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(name),
+		Body:        bytes.NewReader(p),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// tableArchiver is an Archiver that stores the snapshots in a DynamoDB table.
+type tableArchiver struct {
+	client Clientface
+	table  string
+}
+
+// NewTableArchiver returns an Archiver that stores the snapshots in the given DynamoDB
+// table. The table's key schema should be LeaseKeyKey (string) as the hash key, and
+// LeaseDeletedAtKey (number) as the range key, to keep the snapshots of leases that
+// were re-created and deleted again.
+func NewTableArchiver(client Clientface, table string) Archiver {
+	return &tableArchiver{client, table}
+}
+
+func (a *tableArchiver) Archive(snapshot map[string]*dynamodb.AttributeValue) error {
+	_, err := a.client.PutItemWithContext(aws.BackgroundContext(), &dynamodb.PutItemInput{
+		TableName: aws.String(a.table),
+		Item:      snapshot,
+	})
+	return err
+}
+
+// archive writes the final snapshot of the given deleted lease item.
+func (l *LeaseManager) archive(item map[string]*dynamodb.AttributeValue) error {
+	snapshot := make(map[string]*dynamodb.AttributeValue, len(item)+2)
+	for k, v := range item {
+		snapshot[k] = v
+	}
+	// the snapshot holds the overflowed extra fields, and not a pointer to them.
+	if v, ok := snapshot[LeaseOverflowKey]; ok && l.Overflow != nil {
+		p, err := l.Overflow.Get(aws.StringValue(v.S))
+		if err != nil {
+			return fmt.Errorf("load overflowed fields: %v", err)
+		}
+		delete(snapshot, LeaseOverflowKey)
+		if err := unpackFields(p, snapshot); err != nil {
+			return err
+		}
+	}
+	snapshot[LeaseDeletedAtKey] = &dynamodb.AttributeValue{
+		N: aws.String(strconv.FormatInt(l.now().UnixNano(), 10)),
+	}
+	snapshot[LeaseDeletedByKey] = &dynamodb.AttributeValue{
+		S: aws.String(l.WorkerId),
+	}
+	return l.Archiver.Archive(snapshot)
+}
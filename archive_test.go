@@ -0,0 +1,55 @@
+package lease
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// archiverMock is an in-memory Archiver.
+type archiverMock struct {
+	snapshots []map[string]*dynamodb.AttributeValue
+}
+
+func (a *archiverMock) Archive(snapshot map[string]*dynamodb.AttributeValue) error {
+	a.snapshots = append(a.snapshots, snapshot)
+	return nil
+}
+
+func TestDeleteLeaseArchive(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodDeleteItem: {
+			&dynamodb.DeleteItemOutput{
+				Attributes: map[string]*dynamodb.AttributeValue{
+					LeaseKeyKey:      {S: aws.String("foo")},
+					LeaseOwnerKey:    {S: aws.String("1")},
+					"checkpoint":     {N: aws.String("42")},
+					LeaseOverflowKey: {S: aws.String("mem://foo/0")},
+				},
+			},
+			// the lease does not exist
+			new(dynamodb.DeleteItemOutput),
+		},
+	})
+	archiver := &archiverMock{}
+	overflow := &overflowMock{objects: map[string][]byte{
+		"mem://foo/0": []byte(`{"status":{"S":"done"}}`),
+	}}
+	manager := newTestManager(client)
+	manager.Archiver = archiver
+	manager.Overflow = overflow
+
+	err := manager.DeleteLease(context.Background(), &Lease{Key: "foo", Owner: "1"})
+	assert(t, err == nil, "expect DeleteLease not to fail")
+	assert(t, len(archiver.snapshots) == 1, "expect the deleted lease to be archived")
+	s := archiver.snapshots[0]
+	assert(t, aws.StringValue(s["checkpoint"].N) == "42", "expect snapshot to hold the extra fields")
+	assert(t, s["status"] != nil && s[LeaseOverflowKey] == nil, "expect snapshot to hold the overflowed fields")
+	assert(t, aws.StringValue(s[LeaseDeletedByKey].S) == "1" && s[LeaseDeletedAtKey] != nil, "expect snapshot to hold the deletion")
+
+	err = manager.DeleteLease(context.Background(), &Lease{Key: "bar", Owner: "1"})
+	assert(t, err == nil, "expect DeleteLease not to fail")
+	assert(t, len(archiver.snapshots) == 1, "expect not to archive leases that do not exist")
+}
@@ -0,0 +1,563 @@
+package lease
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// AttributeNames are the names of the key, the owner and the counter attributes of the
+// leases in the lease table, e.g: to operate on a pre-existing table with a different
+// naming convention. The empty fields default to LeaseKeyKey, LeaseOwnerKey and
+// LeaseCounterKey. See: Config.AttributeNames.
+type AttributeNames struct {
+	Key     string
+	Owner   string
+	Counter string
+}
+
+// defaults sets the default names of the empty fields.
+func (a *AttributeNames) defaults() {
+	if a.Key == "" {
+		a.Key = LeaseKeyKey
+	}
+	if a.Owner == "" {
+		a.Owner = LeaseOwnerKey
+	}
+	if a.Counter == "" {
+		a.Counter = LeaseCounterKey
+	}
+}
+
+// renames returns the renamed attributes, from the names of this package to the names
+// of the table. it's empty if the names are the defaults.
+func (a *AttributeNames) renames() map[string]string {
+	m := make(map[string]string)
+	for from, to := range map[string]string{LeaseKeyKey: a.Key, LeaseOwnerKey: a.Owner, LeaseCounterKey: a.Counter} {
+		if from != to {
+			m[from] = to
+		}
+	}
+	return m
+}
+
+// validate test that the names are distinct, and that they are not used by other
+// attributes of this package.
+func (a *AttributeNames) validate() error {
+	if a.Key == a.Owner || a.Key == a.Counter || a.Owner == a.Counter {
+		return errors.New("must be distinct")
+	}
+	for _, name := range []string{a.Key, a.Owner, a.Counter} {
+		if name != LeaseKeyKey && name != LeaseOwnerKey && name != LeaseCounterKey && (isReserved(name) || isPacking(name)) {
+			return errors.New(name + " is used by the lease package")
+		}
+	}
+	return nil
+}
+
+// attributeClient is a Clientface that renames the key, the owner and the counter
+// attributes of the requests to the names of the table, and the attributes of the
+// responses back, so the rest of the package uses the default names. It renames the
+// attributes in the items, the keys, the expression attribute names and the attribute
+// names of the expressions.
+//
+// With a partition (see: Config.Partition), it also adds the partition key to the items
+// and the keys of the requests, removes it from the responses, and lists the leases by
+// querying the partition, instead of scanning the table.
+type attributeClient struct {
+	Clientface
+	// to maps the names of this package to the names of the table, and from vice versa.
+	to, from map[string]string
+	// partitionKey is the name of the hash key attribute, and partition is its value.
+	partitionKey, partition string
+}
+
+// newAttributeClient returns a Clientface that renames the attributes of the given client
+// according to the given names, and adds the given partition key, if it's not empty.
+func newAttributeClient(client Clientface, names AttributeNames, partitionKey, partition string) Clientface {
+	c := &attributeClient{
+		Clientface:   client,
+		to:           names.renames(),
+		from:         make(map[string]string),
+		partitionKey: partitionKey,
+		partition:    partition,
+	}
+	for k, v := range c.to {
+		c.from[v] = k
+	}
+	return c
+}
+
+// toTable returns a copy of the given item (or key) of a request, with its attributes
+// renamed to the table, and with the partition key.
+func (c *attributeClient) toTable(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	renamed := make(map[string]*dynamodb.AttributeValue, len(item)+1)
+	for k, v := range item {
+		if name, ok := c.to[k]; ok {
+			k = name
+		}
+		renamed[k] = v
+	}
+	if c.partition != "" {
+		renamed[c.partitionKey] = &dynamodb.AttributeValue{S: aws.String(c.partition)}
+	}
+	return renamed
+}
+
+// fromTable returns a copy of the given item (or key) of a response, with its attributes
+// renamed back, and without the partition key.
+func (c *attributeClient) fromTable(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	renamed := make(map[string]*dynamodb.AttributeValue, len(item))
+	for k, v := range item {
+		if c.partition != "" && k == c.partitionKey {
+			continue
+		}
+		if name, ok := c.from[k]; ok {
+			k = name
+		}
+		renamed[k] = v
+	}
+	return renamed
+}
+
+// fromTableItems returns a copy of the given items of a response. See: fromTable.
+func (c *attributeClient) fromTableItems(items []map[string]*dynamodb.AttributeValue) []map[string]*dynamodb.AttributeValue {
+	if items == nil {
+		return nil
+	}
+	renamed := make([]map[string]*dynamodb.AttributeValue, len(items))
+	for i, item := range items {
+		renamed[i] = c.fromTable(item)
+	}
+	return renamed
+}
+
+// conflict returns ErrFieldConflict if the given item (or expression attribute names) of a
+// write has an extra field with the name of a renamed attribute in the table, or with the
+// name of the partition key. such a field would overwrite the attribute of the table.
+func (c *attributeClient) conflict(item map[string]*dynamodb.AttributeValue, names map[string]*string) error {
+	conflicts := func(name string) bool {
+		if _, ok := c.to[name]; ok {
+			return false
+		}
+		_, ok := c.from[name]
+		return ok || c.partition != "" && name == c.partitionKey
+	}
+	for k := range item {
+		if conflicts(k) {
+			return ErrFieldConflict
+		}
+	}
+	for _, v := range names {
+		if conflicts(aws.StringValue(v)) {
+			return ErrFieldConflict
+		}
+	}
+	return nil
+}
+
+// names returns a copy of the given expression attribute names, renamed to the table.
+func (c *attributeClient) names(names map[string]*string) map[string]*string {
+	if names == nil {
+		return nil
+	}
+	renamed := make(map[string]*string, len(names))
+	for k, v := range names {
+		if name, ok := c.to[aws.StringValue(v)]; ok {
+			v = aws.String(name)
+		}
+		renamed[k] = v
+	}
+	return renamed
+}
+
+// expressions returns a copy of the given expression attribute names, renamed to the table,
+// and renames the attribute names of the given expressions in place. the renamed attributes
+// are written as placeholders, since the names of the table may be reserved words (e.g: key
+// or owner). it returns nil if there are no expression attribute names.
+func (c *attributeClient) expressions(names map[string]*string, exprs ...**string) map[string]*string {
+	renamed := c.names(names)
+	if renamed == nil {
+		renamed = make(map[string]*string)
+	}
+	for _, expr := range exprs {
+		*expr = c.expression(*expr, renamed)
+	}
+	if len(renamed) == 0 {
+		return nil
+	}
+	return renamed
+}
+
+// expression returns the given expression, with its renamed attribute names replaced by
+// placeholders that are added to the given expression attribute names. placeholders (i.e:
+// #name and :value) and nested attributes are left as is.
+func (c *attributeClient) expression(expr *string, names map[string]*string) *string {
+	if expr == nil {
+		return nil
+	}
+	s := *expr
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if !isNameChar(s[i]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && isNameChar(s[j]) {
+			j++
+		}
+		word := s[i:j]
+		if name, ok := c.to[word]; ok && (i == 0 || !strings.ContainsRune("#:.", rune(s[i-1]))) {
+			names["#"+word] = aws.String(name)
+			word = "#" + word
+		}
+		b.WriteString(word)
+		i = j
+	}
+	return aws.String(b.String())
+}
+
+// isNameChar test if the given character may be part of an attribute name in an expression.
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// error renames the attributes of the items that failed the condition checks back.
+func (c *attributeClient) error(err error) error {
+	var cerr *dynamodb.ConditionalCheckFailedException
+	if errors.As(err, &cerr) {
+		cerr.Item = c.fromTable(cerr.Item)
+	}
+	var terr *dynamodb.TransactionCanceledException
+	if errors.As(err, &terr) {
+		for _, reason := range terr.CancellationReasons {
+			reason.Item = c.fromTable(reason.Item)
+		}
+	}
+	return err
+}
+
+func (c *attributeClient) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	cin := *in
+	cin.Key = c.toTable(in.Key)
+	cin.ExpressionAttributeNames = c.expressions(in.ExpressionAttributeNames, &cin.ProjectionExpression)
+	out, err := c.Clientface.GetItemWithContext(ctx, &cin, opts...)
+	if out != nil {
+		out.Item = c.fromTable(out.Item)
+	}
+	return out, c.error(err)
+}
+
+// ScanWithContext renames the attributes of the scan. With a partition, the scan is sent
+// as a query of the partition, and it fails with an error if the client does not support
+// querying tables.
+func (c *attributeClient) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	if c.partition != "" {
+		out, err := c.QueryWithContext(ctx, &dynamodb.QueryInput{
+			TableName:                 in.TableName,
+			IndexName:                 in.IndexName,
+			ConsistentRead:            in.ConsistentRead,
+			Limit:                     in.Limit,
+			Select:                    in.Select,
+			ExclusiveStartKey:         in.ExclusiveStartKey,
+			ExpressionAttributeNames:  in.ExpressionAttributeNames,
+			ExpressionAttributeValues: in.ExpressionAttributeValues,
+			FilterExpression:          in.FilterExpression,
+			ProjectionExpression:      in.ProjectionExpression,
+			ReturnConsumedCapacity:    in.ReturnConsumedCapacity,
+		}, opts...)
+		if out == nil {
+			return nil, err
+		}
+		return &dynamodb.ScanOutput{
+			Items:            out.Items,
+			Count:            out.Count,
+			ScannedCount:     out.ScannedCount,
+			LastEvaluatedKey: out.LastEvaluatedKey,
+			ConsumedCapacity: out.ConsumedCapacity,
+		}, err
+	}
+	cin := *in
+	cin.ExclusiveStartKey = c.toTable(in.ExclusiveStartKey)
+	cin.ExpressionAttributeNames = c.expressions(in.ExpressionAttributeNames, &cin.FilterExpression, &cin.ProjectionExpression)
+	out, err := c.Clientface.ScanWithContext(ctx, &cin, opts...)
+	if out != nil {
+		out.Items = c.fromTableItems(out.Items)
+		out.LastEvaluatedKey = c.fromTable(out.LastEvaluatedKey)
+	}
+	return out, c.error(err)
+}
+
+// QueryWithContext renames the attributes of the query. With a partition, the query of the
+// table is limited to the partition, and the query of an index is filtered by it. it fails
+// with an error if the client does not support querying tables.
+func (c *attributeClient) QueryWithContext(ctx aws.Context, in *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	client, ok := c.Clientface.(queryClient)
+	if !ok {
+		return nil, errors.New("leaser: client does not support queries")
+	}
+	cin := *in
+	cin.ExclusiveStartKey = c.toTable(in.ExclusiveStartKey)
+	cin.ExpressionAttributeNames = c.expressions(in.ExpressionAttributeNames, &cin.KeyConditionExpression, &cin.FilterExpression, &cin.ProjectionExpression)
+	if c.partition != "" {
+		if cin.ExpressionAttributeNames == nil {
+			cin.ExpressionAttributeNames = make(map[string]*string)
+		}
+		cin.ExpressionAttributeNames["#leasePartition"] = aws.String(c.partitionKey)
+		values := make(map[string]*dynamodb.AttributeValue, len(in.ExpressionAttributeValues)+1)
+		for k, v := range in.ExpressionAttributeValues {
+			values[k] = v
+		}
+		values[":leasePartition"] = &dynamodb.AttributeValue{S: aws.String(c.partition)}
+		cin.ExpressionAttributeValues = values
+		// the items of an index query may belong to other partitions.
+		if in.IndexName != nil {
+			cin.FilterExpression = and(cin.FilterExpression, "#leasePartition = :leasePartition")
+		} else if aws.StringValue(cin.KeyConditionExpression) == "" {
+			cin.KeyConditionExpression = aws.String("#leasePartition = :leasePartition")
+		} else {
+			// key conditions can't be grouped, and the sort key condition comes last.
+			cin.KeyConditionExpression = aws.String("#leasePartition = :leasePartition AND " + *cin.KeyConditionExpression)
+		}
+	}
+	out, err := client.QueryWithContext(ctx, &cin, opts...)
+	if out != nil {
+		out.Items = c.fromTableItems(out.Items)
+		out.LastEvaluatedKey = c.fromTable(out.LastEvaluatedKey)
+	}
+	return out, c.error(err)
+}
+
+// and returns the conjunction of the given expression, if any, and the given condition.
+func and(expr *string, cond string) *string {
+	if aws.StringValue(expr) == "" {
+		return aws.String(cond)
+	}
+	return aws.String("(" + *expr + ") AND " + cond)
+}
+
+func (c *attributeClient) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	if err := c.conflict(in.Item, in.ExpressionAttributeNames); err != nil {
+		return nil, err
+	}
+	out, err := c.Clientface.PutItemWithContext(ctx, c.put(in), opts...)
+	if out != nil {
+		out.Attributes = c.fromTable(out.Attributes)
+	}
+	return out, c.error(err)
+}
+
+func (c *attributeClient) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if err := c.conflict(nil, in.ExpressionAttributeNames); err != nil {
+		return nil, err
+	}
+	out, err := c.Clientface.UpdateItemWithContext(ctx, c.update(in), opts...)
+	if out != nil {
+		out.Attributes = c.fromTable(out.Attributes)
+	}
+	return out, c.error(err)
+}
+
+func (c *attributeClient) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	out, err := c.Clientface.DeleteItemWithContext(ctx, c.delete(in), opts...)
+	if out != nil {
+		out.Attributes = c.fromTable(out.Attributes)
+	}
+	return out, c.error(err)
+}
+
+// TransactWriteItemsWithContext renames the attributes of the transaction items. it fails
+// with ErrTransactionsUnsupported if the client does not support transactions.
+func (c *attributeClient) TransactWriteItemsWithContext(ctx aws.Context, in *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	client, ok := c.Clientface.(transactClient)
+	if !ok {
+		return nil, ErrTransactionsUnsupported
+	}
+	cin := *in
+	cin.TransactItems = make([]*dynamodb.TransactWriteItem, len(in.TransactItems))
+	for i, item := range in.TransactItems {
+		citem := *item
+		if item.Put != nil {
+			if err := c.conflict(item.Put.Item, item.Put.ExpressionAttributeNames); err != nil {
+				return nil, err
+			}
+			put := c.put(&dynamodb.PutItemInput{
+				Item:                     item.Put.Item,
+				ExpressionAttributeNames: item.Put.ExpressionAttributeNames,
+				ConditionExpression:      item.Put.ConditionExpression,
+			})
+			cput := *item.Put
+			cput.Item, cput.ExpressionAttributeNames, cput.ConditionExpression = put.Item, put.ExpressionAttributeNames, put.ConditionExpression
+			citem.Put = &cput
+		}
+		if item.Update != nil {
+			if err := c.conflict(nil, item.Update.ExpressionAttributeNames); err != nil {
+				return nil, err
+			}
+			cupdate := *item.Update
+			cupdate.Key = c.toTable(item.Update.Key)
+			cupdate.ExpressionAttributeNames = c.expressions(item.Update.ExpressionAttributeNames, &cupdate.UpdateExpression, &cupdate.ConditionExpression)
+			citem.Update = &cupdate
+		}
+		if item.Delete != nil {
+			cdelete := *item.Delete
+			cdelete.Key = c.toTable(item.Delete.Key)
+			cdelete.ExpressionAttributeNames = c.expressions(item.Delete.ExpressionAttributeNames, &cdelete.ConditionExpression)
+			citem.Delete = &cdelete
+		}
+		if item.ConditionCheck != nil {
+			ccheck := *item.ConditionCheck
+			ccheck.Key = c.toTable(item.ConditionCheck.Key)
+			ccheck.ExpressionAttributeNames = c.expressions(item.ConditionCheck.ExpressionAttributeNames, &ccheck.ConditionExpression)
+			citem.ConditionCheck = &ccheck
+		}
+		cin.TransactItems[i] = &citem
+	}
+	out, err := client.TransactWriteItemsWithContext(ctx, &cin, opts...)
+	return out, c.error(err)
+}
+
+// CreateTableWithContext renames the attributes of the key schema and the indexes.
+func (c *attributeClient) CreateTableWithContext(ctx aws.Context, in *dynamodb.CreateTableInput, opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	cin := *in
+	cin.AttributeDefinitions = make([]*dynamodb.AttributeDefinition, len(in.AttributeDefinitions))
+	for i, def := range in.AttributeDefinitions {
+		cdef := *def
+		cdef.AttributeName = c.attribute(def.AttributeName)
+		cin.AttributeDefinitions[i] = &cdef
+	}
+	cin.KeySchema = c.keySchema(in.KeySchema)
+	// the lease key is the range key of a partitioned table.
+	if c.partition != "" {
+		cin.AttributeDefinitions = append(cin.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(c.partitionKey),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		})
+		for _, elem := range cin.KeySchema {
+			elem.KeyType = aws.String(dynamodb.KeyTypeRange)
+		}
+		cin.KeySchema = append([]*dynamodb.KeySchemaElement{{
+			AttributeName: aws.String(c.partitionKey),
+			KeyType:       aws.String(dynamodb.KeyTypeHash),
+		}}, cin.KeySchema...)
+	}
+	if in.GlobalSecondaryIndexes != nil {
+		cin.GlobalSecondaryIndexes = make([]*dynamodb.GlobalSecondaryIndex, len(in.GlobalSecondaryIndexes))
+		for i, index := range in.GlobalSecondaryIndexes {
+			cindex := *index
+			cindex.KeySchema = c.keySchema(index.KeySchema)
+			cin.GlobalSecondaryIndexes[i] = &cindex
+		}
+	}
+	return c.Clientface.CreateTableWithContext(ctx, &cin, opts...)
+}
+
+// The table operations below have no lease attributes. they are passed to the client, and
+// fail if the client does not support them.
+
+func (c *attributeClient) DeleteTableWithContext(ctx aws.Context, in *dynamodb.DeleteTableInput, opts ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	client, ok := c.Clientface.(tableDeleter)
+	if !ok {
+		return nil, ErrDeleteTableUnsupported
+	}
+	return client.DeleteTableWithContext(ctx, in, opts...)
+}
+
+func (c *attributeClient) UpdateTableWithContext(ctx aws.Context, in *dynamodb.UpdateTableInput, opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	client, ok := c.Clientface.(tableUpdater)
+	if !ok {
+		return nil, ErrUpdateTableUnsupported
+	}
+	return client.UpdateTableWithContext(ctx, in, opts...)
+}
+
+func (c *attributeClient) ListTagsOfResourceWithContext(ctx aws.Context, in *dynamodb.ListTagsOfResourceInput, opts ...request.Option) (*dynamodb.ListTagsOfResourceOutput, error) {
+	client, ok := c.Clientface.(tableTagger)
+	if !ok {
+		return nil, ErrTagTableUnsupported
+	}
+	return client.ListTagsOfResourceWithContext(ctx, in, opts...)
+}
+
+func (c *attributeClient) TagResourceWithContext(ctx aws.Context, in *dynamodb.TagResourceInput, opts ...request.Option) (*dynamodb.TagResourceOutput, error) {
+	client, ok := c.Clientface.(tableTagger)
+	if !ok {
+		return nil, ErrTagTableUnsupported
+	}
+	return client.TagResourceWithContext(ctx, in, opts...)
+}
+
+func (c *attributeClient) DescribeTimeToLiveWithContext(ctx aws.Context, in *dynamodb.DescribeTimeToLiveInput, opts ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	client, ok := c.Clientface.(tableTTLUpdater)
+	if !ok {
+		return nil, ErrTTLUnsupported
+	}
+	return client.DescribeTimeToLiveWithContext(ctx, in, opts...)
+}
+
+func (c *attributeClient) UpdateTimeToLiveWithContext(ctx aws.Context, in *dynamodb.UpdateTimeToLiveInput, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	client, ok := c.Clientface.(tableTTLUpdater)
+	if !ok {
+		return nil, ErrTTLUnsupported
+	}
+	return client.UpdateTimeToLiveWithContext(ctx, in, opts...)
+}
+
+func (c *attributeClient) UpdateContinuousBackupsWithContext(ctx aws.Context, in *dynamodb.UpdateContinuousBackupsInput, opts ...request.Option) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	client, ok := c.Clientface.(continuousBackupsUpdater)
+	if !ok {
+		return nil, ErrPITRUnsupported
+	}
+	return client.UpdateContinuousBackupsWithContext(ctx, in, opts...)
+}
+
+func (c *attributeClient) put(in *dynamodb.PutItemInput) *dynamodb.PutItemInput {
+	cin := *in
+	cin.Item = c.toTable(in.Item)
+	cin.ExpressionAttributeNames = c.expressions(in.ExpressionAttributeNames, &cin.ConditionExpression)
+	return &cin
+}
+
+func (c *attributeClient) update(in *dynamodb.UpdateItemInput) *dynamodb.UpdateItemInput {
+	cin := *in
+	cin.Key = c.toTable(in.Key)
+	cin.ExpressionAttributeNames = c.expressions(in.ExpressionAttributeNames, &cin.UpdateExpression, &cin.ConditionExpression)
+	return &cin
+}
+
+func (c *attributeClient) delete(in *dynamodb.DeleteItemInput) *dynamodb.DeleteItemInput {
+	cin := *in
+	cin.Key = c.toTable(in.Key)
+	cin.ExpressionAttributeNames = c.expressions(in.ExpressionAttributeNames, &cin.ConditionExpression)
+	return &cin
+}
+
+// attribute returns the given attribute name, renamed to the table.
+func (c *attributeClient) attribute(name *string) *string {
+	if to, ok := c.to[aws.StringValue(name)]; ok {
+		return aws.String(to)
+	}
+	return name
+}
+
+// keySchema returns a copy of the given key schema, with its attributes renamed.
+func (c *attributeClient) keySchema(schema []*dynamodb.KeySchemaElement) []*dynamodb.KeySchemaElement {
+	renamed := make([]*dynamodb.KeySchemaElement, len(schema))
+	for i, elem := range schema {
+		celem := *elem
+		celem.AttributeName = c.attribute(elem.AttributeName)
+		renamed[i] = &celem
+	}
+	return renamed
+}
@@ -0,0 +1,151 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestAttributeNamesValidate(t *testing.T) {
+	for _, names := range []AttributeNames{
+		{Key: "id", Owner: "id"},
+		{Owner: LeaseKeyKey},
+		{Counter: LeaseEpochKey},
+	} {
+		config := &Config{Logger: testLogger(), Client: newClientMock(nil), LeaseTable: "test", WorkerId: "1", AttributeNames: names}
+		var cerr *ConfigError
+		err := config.Validate()
+		assert(t, errors.As(err, &cerr) && cerr.Field == "AttributeNames", "expect the attribute names to be invalid")
+	}
+	config := &Config{Logger: testLogger(), Client: newClientMock(nil), LeaseTable: "test", WorkerId: "1"}
+	assert(t, config.Validate() == nil, "expect the default names to be valid")
+	_, ok := config.Client.(*attributeClient)
+	assert(t, !ok, "expect the client not to be wrapped with the default names")
+
+	config.AttributeNames = AttributeNames{Key: "id", Owner: "worker"}
+	assert(t, config.Validate() == nil && config.AttributeNames.Counter == LeaseCounterKey, "expect the custom names to be valid")
+	_, ok = config.Client.(*attributeClient)
+	assert(t, ok, "expect the client to be wrapped with the custom names")
+	assert(t, config.Validate() == nil, "expect the validation to be idempotent")
+	_, ok = config.Client.(*attributeClient).Clientface.(*attributeClient)
+	assert(t, !ok, "expect the client to be wrapped once")
+}
+
+func TestAttributeClientRequests(t *testing.T) {
+	client := newAttributeClient(nil, AttributeNames{Key: "id", Owner: "worker", Counter: LeaseCounterKey}, "", "").(*attributeClient)
+
+	names := make(map[string]*string)
+	expr := client.expression(aws.String("SET leaseOwner = :leaseOwner, leaseOwnerTier = :tier REMOVE leaseHolders.leaseOwner"), names)
+	assert(t, *expr == "SET #leaseOwner = :leaseOwner, leaseOwnerTier = :tier REMOVE leaseHolders.leaseOwner", "expect only the attribute names to be renamed")
+	assert(t, len(names) == 1 && *names["#leaseOwner"] == "worker", "expect the renamed attribute to be a placeholder")
+
+	in := &dynamodb.UpdateItemInput{
+		Key:                      map[string]*dynamodb.AttributeValue{LeaseKeyKey: {S: aws.String("foo")}},
+		ExpressionAttributeNames: map[string]*string{"#owner": aws.String(LeaseOwnerKey), "#counter": aws.String(LeaseCounterKey)},
+		ConditionExpression:      aws.String("#owner = :condOwner AND attribute_exists(leaseKey)"),
+	}
+	update := client.update(in)
+	assert(t, update.Key["id"] != nil && update.Key[LeaseKeyKey] == nil, "expect the key to be renamed")
+	assert(t, *update.ExpressionAttributeNames["#owner"] == "worker", "expect the expression attribute names to be renamed")
+	assert(t, *update.ExpressionAttributeNames["#counter"] == LeaseCounterKey, "expect the default names to be left as is")
+	assert(t, *update.ConditionExpression == "#owner = :condOwner AND attribute_exists(#leaseKey)", "expect the condition to be renamed")
+	assert(t, *update.ExpressionAttributeNames["#leaseKey"] == "id", "expect the renamed attribute to be a placeholder")
+	assert(t, len(in.ExpressionAttributeNames) == 2, "expect the input not to be changed")
+	assert(t, in.Key[LeaseKeyKey] != nil, "expect the input not to be changed")
+}
+
+func TestAttributeClientReservedWords(t *testing.T) {
+	client := newAttributeClient(nil, AttributeNames{Key: "key", Owner: "owner", Counter: "counter"}, "", "").(*attributeClient)
+	update := client.update(&dynamodb.UpdateItemInput{
+		Key:                 map[string]*dynamodb.AttributeValue{LeaseKeyKey: {S: aws.String("foo")}},
+		UpdateExpression:    aws.String("SET leaseOwner = :owner ADD leaseCounter :one"),
+		ConditionExpression: aws.String("attribute_exists(leaseKey) AND leaseCounter = :counter"),
+	})
+	assert(t, *update.UpdateExpression == "SET #leaseOwner = :owner ADD #leaseCounter :one", "expect the update to use placeholders")
+	assert(t, *update.ConditionExpression == "attribute_exists(#leaseKey) AND #leaseCounter = :counter", "expect the condition to use placeholders")
+	for placeholder, name := range map[string]string{"#leaseKey": "key", "#leaseOwner": "owner", "#leaseCounter": "counter"} {
+		assert(t, aws.StringValue(update.ExpressionAttributeNames[placeholder]) == name, "expect the placeholder to be the name of the table")
+	}
+	assert(t, update.Key["key"] != nil, "expect the key to be renamed")
+}
+
+func TestAttributeClientFieldConflict(t *testing.T) {
+	mock := newClientMock(map[method]args{})
+	client := newAttributeClient(mock, AttributeNames{Owner: "owner"}, "", "")
+	_, err := client.PutItemWithContext(context.Background(), &dynamodb.PutItemInput{
+		Item: map[string]*dynamodb.AttributeValue{LeaseKeyKey: {S: aws.String("foo")}, "owner": {S: aws.String("bar")}},
+	})
+	assert(t, err == ErrFieldConflict, "expect an extra field with the name of a renamed attribute to be rejected")
+	_, err = client.UpdateItemWithContext(context.Background(), &dynamodb.UpdateItemInput{
+		Key:                      map[string]*dynamodb.AttributeValue{LeaseKeyKey: {S: aws.String("foo")}},
+		ExpressionAttributeNames: map[string]*string{"#f0": aws.String("owner")},
+		UpdateExpression:         aws.String("SET #f0 = :f0"),
+	})
+	assert(t, err == ErrFieldConflict, "expect an updated field with the name of a renamed attribute to be rejected")
+	assert(t, client.(*attributeClient).conflict(map[string]*dynamodb.AttributeValue{LeaseOwnerKey: {S: aws.String("1")}}, nil) == nil, "expect the renamed attributes to be allowed")
+}
+
+func TestAttributeClientResponses(t *testing.T) {
+	mock := newClientMock(map[method]args{
+		methodScan: {
+			&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{
+				{"id": {S: aws.String("foo")}, "worker": {S: aws.String("1")}, LeaseCounterKey: {N: aws.String("3")}},
+			}},
+		},
+	})
+	manager := newTestManager(newAttributeClient(mock, AttributeNames{Key: "id", Owner: "worker", Counter: LeaseCounterKey}, "", ""))
+
+	leases, err := manager.ListLeases(context.Background())
+	assert(t, err == nil && len(leases) == 1, "expect to list the leases")
+	assert(t, leases[0].Key == "foo" && leases[0].Owner == "1" && leases[0].Counter == 3, "expect the attributes to be renamed back")
+	_, ok := leases[0].Get("id")
+	assert(t, !ok, "expect the renamed attributes not to be extra fields")
+}
+
+// createMock is a clientMock that records the created table.
+type createMock struct {
+	*clientMock
+	input *dynamodb.CreateTableInput
+}
+
+func (c *createMock) CreateTableWithContext(_ aws.Context, input *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	c.input = input
+	return new(dynamodb.CreateTableOutput), nil
+}
+
+func TestAttributeClientPartition(t *testing.T) {
+	mock := &queryMock{
+		clientMock: newClientMock(nil),
+		pages: []*dynamodb.QueryOutput{
+			{Items: []map[string]*dynamodb.AttributeValue{
+				{LeasePartitionKey: {S: aws.String("app")}, LeaseKeyKey: {S: aws.String("foo")}, LeaseOwnerKey: {S: aws.String("1")}},
+			}},
+		},
+	}
+	client := newAttributeClient(mock, AttributeNames{Key: LeaseKeyKey, Owner: LeaseOwnerKey, Counter: LeaseCounterKey}, LeasePartitionKey, "app").(*attributeClient)
+	manager := newTestManager(client)
+
+	leases, err := manager.ListLeases(context.Background())
+	assert(t, err == nil && len(leases) == 1, "expect to list the leases of the partition")
+	assert(t, mock.calls[methodScan] == 0 && len(mock.queries) == 1, "expect to query the partition, instead of scanning the table")
+	query := mock.queries[0]
+	assert(t, aws.StringValue(query.KeyConditionExpression) == "#leasePartition = :leasePartition", "expect to query the partition")
+	assert(t, aws.StringValue(query.ExpressionAttributeValues[":leasePartition"].S) == "app", "expect to query the partition")
+	_, ok := leases[0].Get(LeasePartitionKey)
+	assert(t, !ok, "expect the partition key not to be an extra field")
+
+	put := client.put(&dynamodb.PutItemInput{Item: map[string]*dynamodb.AttributeValue{LeaseKeyKey: {S: aws.String("foo")}}})
+	assert(t, aws.StringValue(put.Item[LeasePartitionKey].S) == "app", "expect the items to be written with the partition")
+
+	create := &createMock{clientMock: newClientMock(nil)}
+	client.Clientface = create
+	_, err = client.CreateTableWithContext(context.Background(), manager.createTableInput())
+	assert(t, err == nil && len(create.input.KeySchema) == 2, "expect a composite key")
+	assert(t, aws.StringValue(create.input.KeySchema[0].AttributeName) == LeasePartitionKey, "expect the partition key to be the hash key")
+	assert(t, aws.StringValue(create.input.KeySchema[1].KeyType) == dynamodb.KeyTypeRange, "expect the lease key to be the range key")
+	assert(t, len(create.input.AttributeDefinitions) == 2, "expect the partition key to be defined")
+}
@@ -0,0 +1,597 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// ErrConflict error will be returns by the conditional writes of a Backend, if the
+	// stored lease was changed since it was read.
+	ErrConflict = errors.New("leaser: lease was changed concurrently")
+
+	// ErrBackendUnsupported error will be returns if an operation that depends on DynamoDB
+	// (e.g: the steal budget or the migrations) is used with a Backend. See: Config.Backend.
+	ErrBackendUnsupported = errors.New("leaser: operation is not supported by the backend")
+)
+
+// Backend is a storage of leases, that the coordinator runs on instead of DynamoDB.
+// See: Config.Backend.
+//
+// The Backend only needs to provide conditional writes on a single lease; the conditions
+// of the lease logic (e.g: on the owner and the counter of the lease) are checked by this
+// package on the stored lease, and the write is conditional on the stored lease not being
+// changed since it was checked. The extra fields of a lease are returned by Lease.Fields,
+// and restored using Lease.Set. Transient errors should be retried by the Backend.
+//
+// Revisions: the Backend sets the revision of the leases it returns using Lease.SetRevision
+// (e.g: a version counter, or the modification index of the storage), and changes it on
+// every write of the lease. The conditional writes compare the revision of the old lease,
+// returned by Lease.Revision, with the stored one.
+type Backend interface {
+	// Get returns the lease with the given key.
+	// Fails with ErrLeaseNotFound if it does not exist.
+	Get(ctx context.Context, key string) (*Lease, error)
+
+	// List returns all the stored leases.
+	List(ctx context.Context) ([]*Lease, error)
+
+	// PutIfAbsent stores the given lease if no lease with the same key exists, and
+	// reports whether it was stored.
+	PutIfAbsent(ctx context.Context, lease *Lease) (bool, error)
+
+	// CompareAndUpdate replaces the stored lease old, as returned by Get or List, with
+	// the given lease. Fails with ErrConflict if the stored lease was changed or deleted
+	// since old was read, i.e: if its revision is not the revision of old.
+	CompareAndUpdate(ctx context.Context, old, lease *Lease) error
+
+	// Delete deletes the stored lease old, as returned by Get or List. Fails with
+	// ErrConflict if the stored lease was changed since old was read, and does nothing
+	// if it does not exist.
+	Delete(ctx context.Context, old *Lease) error
+}
+
+// Revision returns the revision of the lease in its Backend, as of the last time it was
+// read, or an empty string if it was not read from a Backend. See: Backend.
+func (l *Lease) Revision() string {
+	return l.revision
+}
+
+// SetRevision sets the revision of a lease that was read from a Backend. See: Backend.
+func (l *Lease) SetRevision(revision string) {
+	l.revision = revision
+}
+
+// newManager returns the Manager of the given config. a LeaseManager, or a manager that
+// runs on Config.Backend if it's set.
+func newManager(config *Config) Manager {
+	if config.Backend != nil {
+		return &backendManager{config, config.Backend}
+	}
+	return &LeaseManager{config, newSerializer(config)}
+}
+
+// backendManager is a Manager that runs on a Backend. it has the semantics of the
+// LeaseManager: the conditions of the LeaseManager writes are checked on the stored
+// lease, and the write is retried with a fresh read if the stored lease was changed
+// concurrently, so updates that do not break the condition (e.g: of the extra fields)
+// do not fail the operation. The ownership changes (i.e: renew, take and evict) run the
+// code of the LeaseManager on the condUpdate of the backendManager. see: leaseWriter.
+type backendManager struct {
+	*Config
+	backend Backend
+}
+
+// CreateLeaseTable does nothing. the Backend is responsible for its storage.
+func (b *backendManager) CreateLeaseTable(context.Context) error {
+	return nil
+}
+
+// ScanLeases returns all the leases stored in the Backend, like ListLeases.
+func (b *backendManager) ScanLeases(ctx context.Context) ([]*Lease, error) {
+	return b.ListLeases(ctx)
+}
+
+// ListLeasesByOwner returns the leases stored in the Backend that are held by the given owner.
+func (b *backendManager) ListLeasesByOwner(ctx context.Context, owner string) ([]*Lease, error) {
+	list, err := b.ListLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return leasesOf(list, owner), nil
+}
+
+// ListLeasesBySelector returns the leases stored in the Backend that match the given selector.
+func (b *backendManager) ListLeasesBySelector(ctx context.Context, selector Selector) ([]*Lease, error) {
+	list, err := b.ListLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return leasesOfSelector(list, selector), nil
+}
+
+// DeleteLeaseTable fails with ErrBackendUnsupported. the Backend is responsible for its
+// storage.
+func (b *backendManager) DeleteLeaseTable(context.Context) error {
+	return b.wrapError("delete table", "", ErrBackendUnsupported)
+}
+
+// UpdateLeaseTableCapacity fails with ErrBackendUnsupported. the Backend is responsible
+// for its storage.
+func (b *backendManager) UpdateLeaseTableCapacity(context.Context, TableCapacity) error {
+	return b.wrapError("update table", "", ErrBackendUnsupported)
+}
+
+// LeaseTableExists fails with ErrBackendUnsupported. the Backend is responsible for its
+// storage.
+func (b *backendManager) LeaseTableExists(context.Context) (bool, error) {
+	return false, b.wrapError("describe table", "", ErrBackendUnsupported)
+}
+
+// ListLeases returns all the leases stored in the Backend.
+func (b *backendManager) ListLeases(ctx context.Context) ([]*Lease, error) {
+	stored, err := b.backend.List(ctx)
+	if err != nil {
+		return nil, b.wrapError("list", "", err)
+	}
+	var list []*Lease
+	for _, lease := range stored {
+		if !isInternalItem(lease.Key) {
+			list = append(list, b.read(lease))
+		}
+	}
+	return list, nil
+}
+
+// GetLease returns the lease with the given key.
+// Fails with ErrLeaseNotFound if the lease does not exist.
+func (b *backendManager) GetLease(ctx context.Context, key string) (*Lease, error) {
+	lease, err := b.backend.Get(ctx, key)
+	if err != nil {
+		return nil, b.wrapError("get", key, err)
+	}
+	if lease.isTombstoned() {
+		return nil, b.wrapError("get", key, ErrLeaseNotFound)
+	}
+	return b.read(lease), nil
+}
+
+// RenewLease renews a lease by incrementing its counter. It runs the LeaseManager.RenewLease
+// logic on the Backend.
+func (b *backendManager) RenewLease(ctx context.Context, lease *Lease) error {
+	return b.renewLease(ctx, b, lease)
+}
+
+// TakeLease takes a lease for this worker. It runs the LeaseManager.TakeLease logic on
+// the Backend.
+func (b *backendManager) TakeLease(ctx context.Context, lease *Lease) error {
+	return b.takeLease(ctx, b, lease)
+}
+
+// TakeLeases fails with ErrTransactionsUnsupported. a Backend writes a single lease at a time.
+func (b *backendManager) TakeLeases(_ context.Context, leases []*Lease) error {
+	if len(leases) == 0 {
+		return nil
+	}
+	return b.wrapError("take group", leases[0].Group, ErrTransactionsUnsupported)
+}
+
+// EvictLease sets the owner of the lease to null. It runs the LeaseManager.EvictLease
+// logic on the Backend.
+func (b *backendManager) EvictLease(ctx context.Context, lease *Lease) error {
+	return b.evictLease(ctx, b, lease)
+}
+
+// DeleteLease deletes the given lease, conditional on its owner. does nothing when passed
+// a lease that does not exist. In soft-delete mode, the lease is tombstoned instead.
+func (b *backendManager) DeleteLease(ctx context.Context, lease *Lease) error {
+	if !b.SoftDelete {
+		return b.wrapError("delete", lease.Key, b.remove(ctx, lease.Key, owned(Lease{Owner: lease.Owner})))
+	}
+	now := time.Unix(b.now().Unix(), 0)
+	_, err := b.update(ctx, lease.Key, func(s *Lease) error {
+		if s.isTombstoned() {
+			return ErrConflict
+		}
+		return owned(Lease{Owner: lease.Owner})(s)
+	}, func(s *Lease) {
+		s.TombstonedAt = now
+		s.Owner = "NULL"
+		s.Counter = nextCounter(s.Counter)
+	})
+	if err != nil {
+		// the lease does not exist, or it's already tombstoned.
+		if _, gerr := b.GetLease(ctx, lease.Key); errors.Is(gerr, ErrLeaseNotFound) {
+			return nil
+		}
+		return b.wrapError("delete", lease.Key, err)
+	}
+	lease.TombstonedAt = now
+	lease.Owner = "NULL"
+	lease.Counter = nextCounter(lease.Counter)
+	return nil
+}
+
+// CreateLease creates a new lease, like LeaseManager.CreateLease. conditional on a lease
+// not already existing with different owner and counter. tombstoned leases are re-created.
+func (b *backendManager) CreateLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	if lease.Owner == "" {
+		lease.Owner = b.WorkerId
+		lease.OwnerTier = b.Tier
+		lease.OwnerVersion = b.Version
+		lease.OwnerHost = b.Host
+		lease.OwnerZone = b.Zone
+	}
+	if lease.Counter == 0 {
+		lease.Counter++
+	}
+	ok, err := b.put(ctx, lease, func(s *Lease) bool {
+		return s.isTombstoned() || s.Counter == lease.Counter && s.Owner == lease.Owner
+	})
+	if err == nil && !ok {
+		err = ErrConflict
+	}
+	if err != nil {
+		return nil, b.wrapError("create", lease.Key, err)
+	}
+	return lease, nil
+}
+
+// EnsureLease creates the given lease if it does not exist (or tombstoned), and reports
+// whether it was created.
+func (b *backendManager) EnsureLease(ctx context.Context, lease *Lease) (bool, error) {
+	if lease.Owner == "" {
+		lease.Owner = "NULL"
+	}
+	if lease.Counter == 0 {
+		lease.Counter++
+	}
+	ok, err := b.put(ctx, lease, (*Lease).isTombstoned)
+	return ok, b.wrapError("ensure", lease.Key, err)
+}
+
+// UpdateLease updates only the extra fields of the lease, like LeaseManager.UpdateLease.
+// The checkpoint and the payload are written only by the owner of the lease.
+func (b *backendManager) UpdateLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	if len(lease.extrafields) == 0 && len(lease.explicitfields) == 0 && len(lease.removedfields) == 0 && !lease.migrated && !lease.checkpointed && !lease.payloadSet {
+		return lease, nil
+	}
+	ulease, err := b.update(ctx, lease.Key, owned(lease.updateCondition(b.KCLSchema)), func(s *Lease) {
+		for k, v := range lease.extrafields {
+			s.Set(k, v)
+		}
+		for k, v := range lease.explicitfields {
+			if s.explicitfields == nil {
+				s.explicitfields = make(map[string]*dynamodb.AttributeValue)
+			}
+			s.explicitfields[k] = v
+			delete(s.extrafields, k)
+		}
+		for _, k := range lease.removedfields {
+			delete(s.extrafields, k)
+			delete(s.explicitfields, k)
+		}
+		if lease.migrated {
+			s.SchemaVersion = lease.SchemaVersion
+		}
+		if lease.checkpointed {
+			s.Checkpoint = lease.Checkpoint
+		}
+		if lease.payloadSet {
+			s.payload = lease.payload
+		}
+	})
+	if err != nil {
+		return nil, b.wrapError("update", lease.Key, err)
+	}
+	return b.read(ulease), nil
+}
+
+// ReserveLease reserves a lease for this worker until the given time, like
+// LeaseManager.ReserveLease.
+func (b *backendManager) ReserveLease(ctx context.Context, lease *Lease, until time.Time) error {
+	now := b.now()
+	until = time.Unix(until.Unix(), 0)
+	_, err := b.update(ctx, lease.Key, func(s *Lease) error {
+		if s.ReservedBy != "" && s.ReservedBy != b.WorkerId && !s.ReservedUntil.Before(now) {
+			return ErrConflict
+		}
+		return nil
+	}, func(s *Lease) {
+		s.ReservedBy = b.WorkerId
+		s.ReservedUntil = until
+	})
+	if err == nil {
+		lease.ReservedBy = b.WorkerId
+		lease.ReservedUntil = until
+	}
+	return b.wrapError("reserve", lease.Key, err)
+}
+
+// PreemptLease requests the owner of the lease to drain it and hand it over to this
+// worker, like LeaseManager.PreemptLease.
+func (b *backendManager) PreemptLease(ctx context.Context, lease *Lease) error {
+	until := time.Unix(b.now().Add(b.ExpireAfter*2).Unix(), 0)
+	_, err := b.update(ctx, lease.Key, func(s *Lease) error {
+		if s.Owner != lease.Owner || s.OwnerTier >= b.Tier || s.PreemptedBy != "" {
+			return ErrConflict
+		}
+		return nil
+	}, func(s *Lease) {
+		s.PreemptedBy = b.WorkerId
+		s.ReservedBy = b.WorkerId
+		s.ReservedUntil = until
+	})
+	if err == nil {
+		lease.PreemptedBy = b.WorkerId
+		lease.ReservedBy = b.WorkerId
+		lease.ReservedUntil = until
+	}
+	return b.wrapError("preempt", lease.Key, err)
+}
+
+// AcquireSharedLease acquires a lease in shared mode, like LeaseManager.AcquireSharedLease.
+func (b *backendManager) AcquireSharedLease(ctx context.Context, lease *Lease) error {
+	holders, err := b.acquiredHolders(lease)
+	if err != nil {
+		return b.wrapError("acquire shared", lease.Key, err)
+	}
+	_, err = b.update(ctx, lease.Key, func(s *Lease) error {
+		if s.Counter != lease.Counter || !s.hasNoOwner() {
+			return ErrConflict
+		}
+		return nil
+	}, func(s *Lease) {
+		s.Holders = holders
+		s.Counter = nextCounter(lease.Counter)
+	})
+	if err == nil {
+		lease.Counter = nextCounter(lease.Counter)
+		lease.Holders = holders
+	}
+	return b.wrapError("acquire shared", lease.Key, err)
+}
+
+// RenewSharedLease renews a lease that held in shared mode, like LeaseManager.RenewSharedLease.
+func (b *backendManager) RenewSharedLease(ctx context.Context, lease *Lease) error {
+	now := b.now().Unix()
+	ulease, err := b.sharedUpdate(ctx, lease, func(s *Lease) {
+		s.Holders[b.WorkerId] = now
+	})
+	if err == nil {
+		lease.Counter = ulease.Counter
+		lease.Holders = ulease.Holders
+	}
+	return b.wrapError("renew shared", lease.Key, err)
+}
+
+// ReleaseSharedLease releases a lease that held in shared mode, like
+// LeaseManager.ReleaseSharedLease.
+func (b *backendManager) ReleaseSharedLease(ctx context.Context, lease *Lease) error {
+	ulease, err := b.sharedUpdate(ctx, lease, func(s *Lease) {
+		delete(s.Holders, b.WorkerId)
+	})
+	if err == nil {
+		lease.Counter = ulease.Counter
+		lease.Holders = ulease.Holders
+	}
+	return b.wrapError("release shared", lease.Key, err)
+}
+
+// sharedUpdate applies the given change to the lease shared holders, and increments its
+// counter. it is conditional on this worker being one of the lease shared holders.
+func (b *backendManager) sharedUpdate(ctx context.Context, lease *Lease, change func(*Lease)) (*Lease, error) {
+	return b.update(ctx, lease.Key, func(s *Lease) error {
+		if _, ok := s.Holders[b.WorkerId]; !ok {
+			return ErrConflict
+		}
+		return nil
+	}, func(s *Lease) {
+		change(s)
+		s.Counter = nextCounter(s.Counter)
+	})
+}
+
+// MigrateLeases is supported only without a Config.Migrator.
+func (b *backendManager) MigrateLeases(context.Context) (int, error) {
+	if b.Migrator == nil {
+		return 0, nil
+	}
+	return 0, b.wrapError("migrate", "", ErrBackendUnsupported)
+}
+
+// PurgeLease deletes the given tombstoned lease. conditional on the lease not being
+// re-created since it was tombstoned.
+func (b *backendManager) PurgeLease(ctx context.Context, lease *Lease) error {
+	return b.wrapError("purge", lease.Key, b.remove(ctx, lease.Key, func(s *Lease) error {
+		if s.TombstonedAt.Unix() != lease.TombstonedAt.Unix() {
+			return ErrConflict
+		}
+		return nil
+	}))
+}
+
+// CompleteLease marks the given lease as completed, and releases it, like
+// LeaseManager.CompleteLease. the deleted leases are not archived.
+func (b *backendManager) CompleteLease(ctx context.Context, lease *Lease) error {
+	if b.DeleteCompleted {
+		return b.wrapError("complete", lease.Key, b.remove(ctx, lease.Key, owned(Lease{Owner: lease.Owner})))
+	}
+	now := time.Unix(b.now().Unix(), 0)
+	_, err := b.update(ctx, lease.Key, func(s *Lease) error {
+		if s.isCompleted() {
+			return ErrConflict
+		}
+		return owned(Lease{Owner: lease.Owner})(s)
+	}, func(s *Lease) {
+		s.CompletedAt = now
+		s.Owner = "NULL"
+		s.Counter = nextCounter(s.Counter)
+	})
+	if err != nil {
+		return b.wrapError("complete", lease.Key, err)
+	}
+	lease.CompletedAt = now
+	lease.Owner = "NULL"
+	lease.Counter = nextCounter(lease.Counter)
+	return nil
+}
+
+// AcquireStealBudget fails with ErrBackendUnsupported.
+func (b *backendManager) AcquireStealBudget(context.Context, int) (int, error) {
+	return 0, b.wrapError("steal budget", StealBudgetKey, ErrBackendUnsupported)
+}
+
+// ReshardLease fails with ErrTransactionsUnsupported. a Backend writes a single lease at a time.
+func (b *backendManager) ReshardLease(_ context.Context, lease *Lease, _ []*Lease) error {
+	return b.wrapError("reshard", lease.Key, ErrTransactionsUnsupported)
+}
+
+// condUpdate writes the ownership fields of the given update lease, like
+// LeaseManager.condUpdate. it is conditional on the owner and the counter of the
+// stored lease matching the ones of the given cond lease.
+func (b *backendManager) condUpdate(ctx context.Context, update, cond Lease) error {
+	_, err := b.update(ctx, update.Key, owned(cond), func(s *Lease) {
+		s.Owner = update.Owner
+		s.Counter = update.Counter
+		// the owner tier, version, host and zone change only with the owner.
+		if update.Owner != cond.Owner {
+			s.OwnerTier = update.OwnerTier
+			s.OwnerVersion = update.OwnerVersion
+			s.OwnerHost = update.OwnerHost
+			s.OwnerZone = update.OwnerZone
+			// the takeover reason describes the last take, and it's kept on eviction.
+			if !update.hasNoOwner() {
+				s.TakeoverReason = update.TakeoverReason
+			}
+		}
+		if update.Epoch != cond.Epoch {
+			s.Epoch = update.Epoch
+		}
+		if update.LoadHint != cond.LoadHint {
+			s.LoadHint = update.LoadHint
+		}
+		if update.OwnerSwitchesSinceCheckpoint != cond.OwnerSwitchesSinceCheckpoint {
+			s.OwnerSwitchesSinceCheckpoint = update.OwnerSwitchesSinceCheckpoint
+		}
+		if b.Heartbeat && !update.hasNoOwner() {
+			s.Heartbeat = b.now()
+		} else {
+			s.Heartbeat = time.Time{}
+		}
+		if cond.ReservedBy != "" && update.ReservedBy == "" {
+			s.ReservedBy = ""
+			s.ReservedUntil = time.Time{}
+		}
+		if cond.PreemptedBy != "" && update.PreemptedBy == "" {
+			s.PreemptedBy = ""
+		}
+		if cond.Holders != nil && update.Holders == nil {
+			s.Holders = nil
+		}
+	})
+	return err
+}
+
+// update applies the given change to a copy of the stored lease with the given key, if
+// the stored lease passes the given check, and writes it to the Backend. the write is
+// retried with a fresh read if the stored lease was changed concurrently.
+func (b *backendManager) update(ctx context.Context, key string, check func(*Lease) error, change func(*Lease)) (*Lease, error) {
+	for i := 0; ; i++ {
+		stored, err := b.backend.Get(ctx, key)
+		if errors.Is(err, ErrLeaseNotFound) {
+			return nil, &ConditionError{Err: ErrLeaseNotFound, Cause: ErrConflict}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := check(stored); err != nil {
+			return nil, err
+		}
+		ulease := stored.clone()
+		change(ulease)
+		err = b.backend.CompareAndUpdate(ctx, stored, ulease)
+		if errors.Is(err, ErrConflict) && i < maxUpdateRetries {
+			continue
+		}
+		return ulease, err
+	}
+}
+
+// remove deletes the stored lease with the given key, if it passes the given check.
+// does nothing if the lease does not exist.
+func (b *backendManager) remove(ctx context.Context, key string, check func(*Lease) error) error {
+	for i := 0; ; i++ {
+		stored, err := b.backend.Get(ctx, key)
+		if errors.Is(err, ErrLeaseNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := check(stored); err != nil {
+			return err
+		}
+		err = b.backend.Delete(ctx, stored)
+		if errors.Is(err, ErrConflict) && i < maxUpdateRetries {
+			continue
+		}
+		return err
+	}
+}
+
+// put stores the given lease if it does not exist, or if the stored lease can be
+// replaced, and reports whether it was stored.
+func (b *backendManager) put(ctx context.Context, lease *Lease, replace func(*Lease) bool) (bool, error) {
+	for i := 0; ; i++ {
+		ok, err := b.backend.PutIfAbsent(ctx, lease)
+		if err != nil || ok {
+			return ok, err
+		}
+		stored, err := b.backend.Get(ctx, lease.Key)
+		// the lease was deleted in the meantime.
+		if errors.Is(err, ErrLeaseNotFound) && i < maxUpdateRetries {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !replace(stored) {
+			return false, nil
+		}
+		err = b.backend.CompareAndUpdate(ctx, stored, lease)
+		if errors.Is(err, ErrConflict) && i < maxUpdateRetries {
+			continue
+		}
+		return err == nil, err
+	}
+}
+
+// read sets the fields that are not persisted on a lease that was read from the Backend,
+// like Serializer.Decode.
+func (b *backendManager) read(lease *Lease) *Lease {
+	lease.lastRenewal = b.now()
+	lease.concurrencyToken, _ = uuid()
+	return lease
+}
+
+// owned returns a check that the stored lease has the owner and the counter of the given
+// lease, and fails with a *ConditionError otherwise. like the LeaseManager conditions, the
+// owner and the counter are checked only if they are set.
+func owned(cond Lease) func(*Lease) error {
+	return func(s *Lease) error {
+		ce := &ConditionError{Owner: s.Owner, Counter: s.Counter, Cause: ErrConflict}
+		switch {
+		case cond.Owner != "" && s.Owner != cond.Owner:
+			ce.Err = ErrLeaseStolen
+		case cond.Counter > 0 && s.Counter != cond.Counter:
+			ce.Err = ErrLeaseCounterChanged
+		default:
+			return nil
+		}
+		return ce
+	}
+}
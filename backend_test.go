@@ -0,0 +1,121 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestBackendManager(worker string, backend Backend) Manager {
+	return newManager(&Config{
+		WorkerId:    worker,
+		LeaseTable:  "test",
+		Logger:      testLogger(),
+		ExpireAfter: time.Minute,
+		Backend:     backend,
+	})
+}
+
+func TestBackendManager(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	m1, m2 := newTestBackendManager("1", backend), newTestBackendManager("2", backend)
+
+	lease := NewLease("foo")
+	lease.Set("checkpoint", 10)
+	created, err := m1.EnsureLease(ctx, &lease)
+	assert(t, err == nil && created, "expect the lease to be created")
+	created, err = m2.EnsureLease(ctx, &Lease{Key: "foo"})
+	assert(t, err == nil && !created, "expect existing leases not to be re-created")
+
+	l1, err := m1.GetLease(ctx, "foo")
+	assert(t, err == nil && l1.Owner == "NULL", "expect to get the lease")
+	l2, _ := m2.GetLease(ctx, "foo")
+	assert(t, m1.TakeLease(ctx, l1) == nil && l1.Owner == "1" && l1.Epoch == 1, "expect the lease to be taken")
+	err = m2.TakeLease(ctx, l2)
+	assert(t, isConditionalFailed(err) && errors.Is(err, ErrLeaseStolen), "expect the take of a stale lease to fail the condition")
+	assert(t, m1.RenewLease(ctx, l1) == nil && l1.Counter == 3, "expect the lease to be renewed")
+
+	// updates of the extra fields do not fail the renewals of the owner.
+	l2, _ = m2.GetLease(ctx, "foo")
+	l2.Set("checkpoint", 20)
+	_, err = m2.UpdateLease(ctx, l2)
+	assert(t, err == nil, "expect the extra fields to be updated")
+	assert(t, m1.RenewLease(ctx, l1) == nil, "expect the renewal not to conflict with the update")
+	l2, _ = m2.GetLease(ctx, "foo")
+	v, _ := l2.Get("checkpoint")
+	assert(t, v == 20 && l2.Counter == 4, "expect the renewal to keep the extra fields")
+
+	err = m2.DeleteLease(ctx, &Lease{Key: "foo", Owner: "2"})
+	assert(t, errors.Is(err, ErrLeaseStolen), "expect the delete of another owner to fail")
+	assert(t, m1.DeleteLease(ctx, l1) == nil, "expect the owner to delete the lease")
+	_, err = m1.GetLease(ctx, "foo")
+	assert(t, errors.Is(err, ErrLeaseNotFound), "expect the lease to be deleted")
+	assert(t, isConditionalFailed(m1.RenewLease(ctx, l1)), "expect the renewal of a deleted lease to fail the condition")
+}
+
+func TestBackendManagerTable(t *testing.T) {
+	ctx := context.Background()
+	m := newTestBackendManager("1", NewMemoryBackend())
+	assert(t, m.CreateLeaseTable(ctx) == nil, "expect the backend storage to be created")
+	assert(t, errors.Is(m.DeleteLeaseTable(ctx), ErrBackendUnsupported), "expect not to delete the backend storage")
+	_, err := m.LeaseTableExists(ctx)
+	assert(t, errors.Is(err, ErrBackendUnsupported), "expect not to check the backend storage")
+	err = m.UpdateLeaseTableCapacity(ctx, TableCapacity{OnDemand: true})
+	assert(t, errors.Is(err, ErrBackendUnsupported), "expect not to change the backend capacity")
+}
+
+func TestMemoryBackendConcurrentTake(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	_, err := newTestBackendManager("0", backend).EnsureLease(ctx, &Lease{Key: "foo"})
+	assert(t, err == nil, "expect the lease to be created")
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		taken []string
+	)
+	for i := 1; i <= 10; i++ {
+		m := newTestBackendManager(strconv.Itoa(i), backend)
+		lease, _ := m.GetLease(ctx, "foo")
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			if err := m.TakeLease(ctx, lease); err == nil {
+				mu.Lock()
+				taken = append(taken, worker)
+				mu.Unlock()
+			} else if !isConditionalFailed(err) {
+				t.Errorf("expect the lost takes to fail the condition, got: %v", err)
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	assert(t, len(taken) == 1, "expect exactly one worker to take the lease")
+	lease, _ := newTestBackendManager("0", backend).GetLease(ctx, "foo")
+	assert(t, len(taken) == 1 && lease.Owner == taken[0] && lease.Counter == 2, "expect the stored lease to be owned by the taker")
+}
+
+func TestMemoryBackendRevision(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	ok, err := backend.PutIfAbsent(ctx, &Lease{Key: "foo", Owner: "NULL", Counter: 1})
+	assert(t, err == nil && ok, "expect the lease to be stored")
+	old, _ := backend.Get(ctx, "foo")
+	assert(t, old.Revision() != "", "expect the stored lease to have a revision")
+
+	update := old.clone()
+	update.Counter++
+	assert(t, backend.CompareAndUpdate(ctx, old, update) == nil, "expect the update of the read revision")
+	stored, _ := backend.Get(ctx, "foo")
+	assert(t, stored.Revision() != old.Revision(), "expect the revision to change on every write")
+	// an equal lease of an older revision is still a conflict.
+	stale := stored.clone()
+	stale.SetRevision(old.Revision())
+	assert(t, backend.CompareAndUpdate(ctx, stale, update) == ErrConflict, "expect the update of a stale revision to conflict")
+	assert(t, backend.Delete(ctx, stale) == ErrConflict, "expect the delete of a stale revision to conflict")
+}
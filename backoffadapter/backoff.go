@@ -0,0 +1,65 @@
+// Package backoffadapter adapts the strategies of cenkalti/backoff to lease.RetryPolicy.
+// For example:
+//
+//	policy := backoffadapter.New(func() backoff.BackOff {
+//		return backoff.NewExponentialBackOff()
+//	}, 5, time.Minute)
+//	leaser := lease.New("leases", lease.WithRetryPolicy(lease.RetryUpdate, policy))
+package backoffadapter
+
+import (
+	"time"
+
+	"github.com/a8m/lease"
+	"github.com/cenkalti/backoff/v4"
+)
+
+// Policy is a lease.RetryPolicy that is backed by a backoff.BackOff. The strategies of
+// cenkalti/backoff are stateful, and a new one is created for each delay, so the policy
+// can be shared by concurrent calls.
+type Policy struct {
+	// NewBackOff returns the strategy, e.g: backoff.NewExponentialBackOff.
+	NewBackOff func() backoff.BackOff
+	// Attempts is the maximum number of calls. 0 means no limit.
+	Attempts int
+	// Elapsed is the maximum duration since the first call. 0 means no limit.
+	Elapsed time.Duration
+}
+
+// New returns a lease.RetryPolicy with the strategies returned by newBackOff.
+func New(newBackOff func() backoff.BackOff, attempts int, elapsed time.Duration) lease.RetryPolicy {
+	return &Policy{NewBackOff: newBackOff, Attempts: attempts, Elapsed: elapsed}
+}
+
+// NextDelay returns the attempt-th delay of a new strategy.
+func (p *Policy) NextDelay(attempt int) time.Duration {
+	d := p.next(attempt)
+	if d == backoff.Stop {
+		return 0
+	}
+	return d
+}
+
+// ShouldRetry reports whether the strategy did not stop before the given attempt.
+func (p *Policy) ShouldRetry(attempt int, _ error) bool {
+	return p.next(attempt) != backoff.Stop
+}
+
+func (p *Policy) MaxAttempts() int {
+	return p.Attempts
+}
+
+func (p *Policy) MaxElapsed() time.Duration {
+	return p.Elapsed
+}
+
+// next steps a new strategy to its attempt-th delay.
+func (p *Policy) next(attempt int) time.Duration {
+	b := p.NewBackOff()
+	b.Reset()
+	d := b.NextBackOff()
+	for i := 1; i < attempt && d != backoff.Stop; i++ {
+		d = b.NextBackOff()
+	}
+	return d
+}
@@ -0,0 +1,39 @@
+package backoffadapter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+func TestPolicy(t *testing.T) {
+	p := New(func() backoff.BackOff {
+		return backoff.WithMaxRetries(&backoff.ConstantBackOff{Interval: time.Second}, 2)
+	}, 5, time.Minute)
+	if p.MaxAttempts() != 5 || p.MaxElapsed() != time.Minute {
+		t.Error("expect the limits to be kept")
+	}
+	if p.NextDelay(1) != time.Second || p.NextDelay(2) != time.Second {
+		t.Error("expect the delays of the strategy")
+	}
+	err := errors.New("failed")
+	if !p.ShouldRetry(2, err) || p.ShouldRetry(3, err) {
+		t.Error("expect not to retry after the strategy stopped")
+	}
+	if p.NextDelay(3) != 0 {
+		t.Error("expect no delay after the strategy stopped")
+	}
+
+	exp := New(func() backoff.BackOff {
+		b := backoff.NewExponentialBackOff()
+		b.RandomizationFactor = 0
+		b.InitialInterval = time.Second
+		b.Multiplier = 2
+		return b
+	}, 0, 0)
+	if exp.NextDelay(1) != time.Second || exp.NextDelay(3) != 4*time.Second {
+		t.Error("expect the delays to grow exponentially")
+	}
+}
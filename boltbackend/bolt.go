@@ -0,0 +1,137 @@
+// Package boltbackend runs the coordinator on a local bbolt file, instead of DynamoDB, e.g:
+// in a single-node deployment or a laptop development environment, without AWS credentials.
+//
+// The leases are stored as JSON values in a bucket of the file, and the conditional writes
+// of lease.Backend run in bbolt read-write transactions, that are serialized. A bbolt file
+// is opened by a single process at a time, so the workers of the lease table must run in
+// that process. For example:
+//
+//	backend, err := boltbackend.Open("leases.db", "leases")
+//	defer backend.Close()
+//	leaser := lease.New("leases", lease.WithBackend(backend))
+package boltbackend
+
+import (
+	"context"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Backend is a lease.Backend that stores the leases in a bbolt bucket.
+type Backend struct {
+	DB *bolt.DB
+	// Bucket is the name of the bucket of the lease table.
+	Bucket string
+}
+
+// Open opens (or creates) the bbolt file in the given path, and returns a Backend that
+// stores the leases in the given bucket. The Backend must be closed to release the file.
+func Open(path, bucket string) (*Backend, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	b, err := New(db, bucket)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// New returns a Backend that stores the leases in the given bucket of the given bbolt
+// database. The bucket is created if it's not already exists.
+func New(db *bolt.DB, bucket string) (*Backend, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{DB: db, Bucket: bucket}, nil
+}
+
+// Close closes the bbolt database.
+func (b *Backend) Close() error {
+	return b.DB.Close()
+}
+
+func (b *Backend) Get(_ context.Context, key string) (l *lease.Lease, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(b.Bucket)).Get([]byte(key))
+		if v == nil {
+			return lease.ErrLeaseNotFound
+		}
+		l, err = record.Decode(string(v))
+		return err
+	})
+	return
+}
+
+func (b *Backend) List(context.Context) (list []*lease.Lease, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(b.Bucket)).ForEach(func(_, v []byte) error {
+			l, err := record.Decode(string(v))
+			if err == nil {
+				list = append(list, l)
+			}
+			return err
+		})
+	})
+	return
+}
+
+func (b *Backend) PutIfAbsent(_ context.Context, l *lease.Lease) (ok bool, err error) {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return false, err
+	}
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(b.Bucket))
+		if bucket.Get([]byte(l.Key)) != nil {
+			return nil
+		}
+		ok = true
+		return bucket.Put([]byte(l.Key), []byte(raw))
+	})
+	return ok && err == nil, err
+}
+
+func (b *Backend) CompareAndUpdate(_ context.Context, old, l *lease.Lease) error {
+	oraw, err := record.Encode(old)
+	if err != nil {
+		return err
+	}
+	raw, err := record.Encode(l)
+	if err != nil {
+		return err
+	}
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(b.Bucket))
+		if v := bucket.Get([]byte(old.Key)); v == nil || string(v) != oraw {
+			return lease.ErrConflict
+		}
+		return bucket.Put([]byte(old.Key), []byte(raw))
+	})
+}
+
+func (b *Backend) Delete(_ context.Context, old *lease.Lease) error {
+	oraw, err := record.Encode(old)
+	if err != nil {
+		return err
+	}
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(b.Bucket))
+		v := bucket.Get([]byte(old.Key))
+		if v == nil {
+			return nil
+		}
+		if string(v) != oraw {
+			return lease.ErrConflict
+		}
+		return bucket.Delete([]byte(old.Key))
+	})
+}
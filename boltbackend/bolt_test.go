@@ -0,0 +1,64 @@
+package boltbackend
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/a8m/lease"
+)
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "leases.db")
+	b, err := Open(path, "leases")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := lease.NewLease("foo")
+	l.Owner = "1"
+	l.Counter = 1
+	l.Set("checkpoint", 10)
+	if ok, err := b.PutIfAbsent(ctx, &l); err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	if ok, _ := b.PutIfAbsent(ctx, &l); ok {
+		t.Error("expect existing leases not to be stored")
+	}
+	old, err := b.Get(ctx, "foo")
+	if err != nil || old.Owner != "1" {
+		t.Fatalf("expect to get the stored lease, got: %+v, %v", old, err)
+	}
+	update := *old
+	update.Counter++
+	if err := b.CompareAndUpdate(ctx, old, &update); err != nil {
+		t.Fatalf("expect the decoded lease to match the stored one: %v", err)
+	}
+	if err := b.CompareAndUpdate(ctx, old, &update); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the update of a changed lease to conflict, got: %v", err)
+	}
+	if err := b.Delete(ctx, old); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the delete of a changed lease to conflict, got: %v", err)
+	}
+
+	// the leases are kept in the file.
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = Open(path, "leases"); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	list, err := b.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Counter != 2 {
+		t.Fatalf("expect to list the updated lease, got: %v, %v", list, err)
+	}
+	if err := b.Delete(ctx, list[0]); err != nil {
+		t.Fatalf("expect the lease to be deleted: %v", err)
+	}
+	if _, err := b.Get(ctx, "foo"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect the lease not to be found, got: %v", err)
+	}
+}
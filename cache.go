@@ -0,0 +1,117 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// leaseCache caches the leases returned by Coordinator.Get. The entries of the leases
+// that are written by this worker, or that change owner on this worker, are invalidated.
+// It holds up to maxCacheEntries leases. A nil leaseCache caches nothing.
+type leaseCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// now is the clock of the entries age. see: Config.Clock.
+	now func() time.Time
+}
+
+// cacheEntry is a cached lease, and the time it was read.
+type cacheEntry struct {
+	lease Lease
+	at    time.Time
+}
+
+// maxCacheEntries is the maximum number of cached leases.
+const maxCacheEntries = 10000
+
+// get returns a copy of the cached lease with the given key, if it's younger than ttl.
+func (c *leaseCache) get(key string, ttl time.Duration) (Lease, bool) {
+	if c == nil {
+		return Lease{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || c.now().Sub(e.at) > ttl {
+		delete(c.entries, key)
+		return Lease{}, false
+	}
+	lease := e.lease
+	// keep the extra fields of the cached lease apart from the returned copies.
+	lease.extrafields = copyFields(lease.extrafields)
+	lease.explicitfields = copyItem(lease.explicitfields)
+	return lease, true
+}
+
+// set caches the given lease. If the cache is full, the entries older than ttl are
+// dropped, or the oldest one if none is.
+func (c *leaseCache) set(lease Lease, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	if _, ok := c.entries[lease.Key]; !ok && len(c.entries) >= maxCacheEntries {
+		c.evict(ttl)
+	}
+	lease.extrafields = copyFields(lease.extrafields)
+	lease.explicitfields = copyItem(lease.explicitfields)
+	c.entries[lease.Key] = cacheEntry{lease: lease, at: c.now()}
+}
+
+// evict drops the entries that are older than ttl, or the oldest entry if none is.
+func (c *leaseCache) evict(ttl time.Duration) {
+	var (
+		oldest string
+		at     time.Time
+		now    = c.now()
+	)
+	for key, e := range c.entries {
+		if now.Sub(e.at) > ttl {
+			delete(c.entries, key)
+		} else if oldest == "" || e.at.Before(at) {
+			oldest, at = key, e.at
+		}
+	}
+	if len(c.entries) >= maxCacheEntries {
+		delete(c.entries, oldest)
+	}
+}
+
+// invalidate drops the cached lease with the given key.
+func (c *leaseCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Get returns the lease with the given key. If Config.GetCacheTTL is set, the lease is
+// cached for that long, and the next calls return the cached lease, unless it was written
+// by this worker in the meantime. Use it in request paths that check the ownership of a
+// lease frequently, and can tolerate a stale view of other workers writes.
+//
+// Fails with ErrLeaseNotFound if the lease does not exist in the table.
+func (c *Coordinator) Get(ctx context.Context, key string) (Lease, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	if c.GetCacheTTL > 0 {
+		if lease, ok := c.cache.get(key, c.GetCacheTTL); ok {
+			return lease, nil
+		}
+	}
+	lease, err := c.Manager.GetLease(ctx, key)
+	if err != nil {
+		return Lease{}, err
+	}
+	if c.GetCacheTTL > 0 {
+		c.cache.set(*lease, c.GetCacheTTL)
+	}
+	return *lease, nil
+}
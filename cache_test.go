@@ -0,0 +1,70 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCoordinatorGet(t *testing.T) {
+	logger := testLogger()
+	foo := &Lease{Key: "foo", Owner: "1"}
+	manager := newManagerMock(map[method]args{
+		methodGet:    {foo, nil, foo, foo},
+		methodUpdate: {nil},
+	})
+	c := &Coordinator{
+		Config:  &Config{WorkerId: "1", Logger: logger, GetCacheTTL: time.Minute},
+		Manager: manager,
+		Renewer: &leaseHolder{heldLeases: map[string]*Lease{"foo": foo}},
+		cache:   &leaseCache{now: time.Now},
+	}
+	lease, err := c.Get(context.Background(), "foo")
+	assert(t, err == nil && lease.Owner == "1", "expect to get the lease")
+	lease, _ = c.Get(context.Background(), "foo")
+	assert(t, manager.calls[methodGet] == 1, "expect to get the lease from the cache")
+	lease.Set("status", "done")
+	lease, _ = c.Get(context.Background(), "foo")
+	_, ok := lease.Get("status")
+	assert(t, !ok, "expect the cached lease not to be changed by the returned copies")
+
+	_, err = c.Get(context.Background(), "bar")
+	assert(t, errors.Is(err, ErrLeaseNotFound), "expect to fail if the lease does not exist")
+
+	c.Update(context.Background(), lease)
+	c.Get(context.Background(), "foo")
+	assert(t, manager.calls[methodGet] == 3, "expect the lease to be invalidated on local writes")
+
+	c.cache.entries["foo"] = cacheEntry{lease: *foo, at: time.Now().Add(-time.Hour)}
+	c.Get(context.Background(), "foo")
+	assert(t, manager.calls[methodGet] == 4, "expect the cached lease to expire")
+}
+
+func TestLeaseCacheOwnerChange(t *testing.T) {
+	cache := &leaseCache{now: time.Now}
+	cache.set(Lease{Key: "foo", Owner: renewerId}, time.Minute)
+	manager := newManagerMock(map[method]args{
+		methodEvict: {nil},
+	})
+	holder := &leaseHolder{
+		Config:     &Config{WorkerId: renewerId, Logger: testLogger()},
+		manager:    manager,
+		heldLeases: map[string]*Lease{"foo": {Key: "foo", Owner: renewerId}},
+		cache:      cache,
+	}
+	err := holder.Release(context.Background(), Lease{Key: "foo"})
+	assert(t, err == nil, "expect release not to fail")
+	_, ok := cache.get("foo", time.Minute)
+	assert(t, !ok, "expect the released lease to be invalidated")
+
+	for i := 0; i < maxCacheEntries; i++ {
+		cache.set(Lease{Key: strconv.Itoa(i)}, time.Minute)
+	}
+	cache.entries["0"] = cacheEntry{lease: Lease{Key: "0"}, at: time.Now().Add(-time.Second)}
+	cache.set(Lease{Key: "foo"}, time.Minute)
+	assert(t, len(cache.entries) == maxCacheEntries, "expect the cache to be bounded")
+	_, ok = cache.get("0", time.Minute)
+	assert(t, !ok, "expect the oldest lease to be evicted")
+}
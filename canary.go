@@ -0,0 +1,43 @@
+package lease
+
+import "hash/fnv"
+
+// isCanaryLease test if the given lease is a canary lease; either it tagged as
+// a canary lease, or it's one of the CanaryPercent leases that selected by the
+// hash of their keys.
+func (c *Config) isCanaryLease(lease *Lease) bool {
+	if lease.Canary {
+		return true
+	}
+	if c.CanaryPercent <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(lease.Key))
+	return float64(h.Sum32()%10000) < c.CanaryPercent*100
+}
+
+// filterPool returns the leases that belong to the pool of this worker. canary
+// workers get only the canary leases and the rest of the workers get the others.
+func (c *Config) filterPool(list []*Lease) []*Lease {
+	if !c.Canary && c.CanaryPercent <= 0 && !hasCanary(list) {
+		return list
+	}
+	var pool []*Lease
+	for _, lease := range list {
+		if c.isCanaryLease(lease) == c.Canary {
+			pool = append(pool, lease)
+		}
+	}
+	return pool
+}
+
+// hasCanary test if one of the leases in the list tagged as canary.
+func hasCanary(list []*Lease) bool {
+	for _, lease := range list {
+		if lease.Canary {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package lease
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrUpdateTableUnsupported error will be returns if the capacity of the lease table is
+// updated with a client that does not support updating tables.
+var ErrUpdateTableUnsupported = errors.New("leaser: client does not support updating tables")
+
+// TableCapacity is the capacity of the lease table. See: Manager.UpdateLeaseTableCapacity.
+type TableCapacity struct {
+	// ReadCap and WriteCap are the provisioned throughput of the table, and of its owner
+	// index (see: Config.OwnerIndex). Ignored if OnDemand is set.
+	ReadCap  int
+	WriteCap int
+
+	// OnDemand switches the table to on-demand mode (i.e: the PAY_PER_REQUEST billing mode).
+	OnDemand bool
+}
+
+// tableUpdater is implemented by the clients that support updating tables, e.g: *dynamodb.DynamoDB.
+type tableUpdater interface {
+	UpdateTableWithContext(aws.Context, *dynamodb.UpdateTableInput, ...request.Option) (*dynamodb.UpdateTableOutput, error)
+}
+
+// UpdateLeaseTableCapacity changes the provisioned throughput of the lease table, or
+// switches its billing mode, and waits until the table is active. does nothing if the
+// table already has the given capacity. Fails with ErrUpdateTableUnsupported if the client
+// does not support updating tables.
+//
+// DynamoDB limits the number of capacity decreases and billing mode switches of a table.
+// See: https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/ServiceQuotas.html.
+func (l *LeaseManager) UpdateLeaseTableCapacity(ctx context.Context, capacity TableCapacity) (err error) {
+	if !capacity.OnDemand && (capacity.ReadCap <= 0 || capacity.WriteCap <= 0) {
+		return l.wrapError("update table", "", ErrInvalidCapacity)
+	}
+	client, ok := l.Client.(tableUpdater)
+	if !ok {
+		return l.wrapError("update table", "", ErrUpdateTableUnsupported)
+	}
+	desc, err := l.Client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(l.LeaseTable),
+	})
+	if err != nil {
+		return l.wrapError("update table", "", err)
+	}
+	input, ok := l.updateTableInput(desc.Table, capacity)
+	if !ok {
+		return nil
+	}
+	r := l.retrier(RetryUpdate)
+	for r.more() {
+		_, err = client.UpdateTableWithContext(ctx, input)
+		if err == nil {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to update table", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+	if err = l.retryError(r, "", err); err != nil {
+		return l.wrapError("update table", "", err)
+	}
+	l.Logger.WithFields(Fields{
+		"table name": l.LeaseTable,
+		"read cap":   capacity.ReadCap,
+		"write cap":  capacity.WriteCap,
+		"on demand":  capacity.OnDemand,
+	}).Infof("Worker %s updates the capacity of the lease table", l.WorkerId)
+	return l.wrapError("update table", "", l.waitTableActive(ctx))
+}
+
+// updateTableInput returns the UpdateTableInput that changes the given table to the given
+// capacity, and boolean that indicates if the table needs to be updated.
+func (l *LeaseManager) updateTableInput(table *dynamodb.TableDescription, capacity TableCapacity) (*dynamodb.UpdateTableInput, bool) {
+	onDemand := table.BillingModeSummary != nil &&
+		aws.StringValue(table.BillingModeSummary.BillingMode) == dynamodb.BillingModePayPerRequest
+	input := &dynamodb.UpdateTableInput{TableName: aws.String(l.LeaseTable)}
+	if capacity.OnDemand {
+		if onDemand {
+			return nil, false
+		}
+		input.BillingMode = aws.String(dynamodb.BillingModePayPerRequest)
+		return input, true
+	}
+	throughput := &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(int64(capacity.ReadCap)),
+		WriteCapacityUnits: aws.Int64(int64(capacity.WriteCap)),
+	}
+	// DynamoDB rejects updates that do not change the throughput of the table or an index.
+	if onDemand {
+		input.BillingMode = aws.String(dynamodb.BillingModeProvisioned)
+	}
+	if onDemand || !sameThroughput(table.ProvisionedThroughput, throughput) {
+		input.ProvisionedThroughput = throughput
+	}
+	// the indexes of a provisioned table have their own throughput.
+	for _, index := range table.GlobalSecondaryIndexes {
+		if !onDemand && sameThroughput(index.ProvisionedThroughput, throughput) {
+			continue
+		}
+		input.GlobalSecondaryIndexUpdates = append(input.GlobalSecondaryIndexUpdates, &dynamodb.GlobalSecondaryIndexUpdate{
+			Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+				IndexName:             index.IndexName,
+				ProvisionedThroughput: throughput,
+			},
+		})
+	}
+	return input, input.ProvisionedThroughput != nil || len(input.GlobalSecondaryIndexUpdates) > 0
+}
+
+// sameThroughput reports whether the provisioned throughput of a table or an index has the
+// read and the write capacity of the given throughput.
+func sameThroughput(desc *dynamodb.ProvisionedThroughputDescription, throughput *dynamodb.ProvisionedThroughput) bool {
+	return desc != nil &&
+		aws.Int64Value(desc.ReadCapacityUnits) == aws.Int64Value(throughput.ReadCapacityUnits) &&
+		aws.Int64Value(desc.WriteCapacityUnits) == aws.Int64Value(throughput.WriteCapacityUnits)
+}
@@ -0,0 +1,72 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// updaterMock is a clientMock that supports updating tables.
+type updaterMock struct {
+	*clientMock
+	updates []*dynamodb.UpdateTableInput
+}
+
+func (c *updaterMock) UpdateTableWithContext(_ aws.Context, input *dynamodb.UpdateTableInput, _ ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	c.updates = append(c.updates, input)
+	return new(dynamodb.UpdateTableOutput), nil
+}
+
+func TestUpdateLeaseTableCapacity(t *testing.T) {
+	throughput := func(read, write int64) *dynamodb.ProvisionedThroughputDescription {
+		return &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(read),
+			WriteCapacityUnits: aws.Int64(write),
+		}
+	}
+	client := &updaterMock{clientMock: newClientMock(map[method]args{
+		methodDescribeTable: {
+			&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableStatus:           aws.String(dynamodb.TableStatusActive),
+				ProvisionedThroughput: throughput(10, 10),
+				GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+					{IndexName: aws.String(OwnerIndexName), ProvisionedThroughput: throughput(5, 5)},
+				},
+			}},
+		},
+	})}
+	manager := newTestManager(client)
+	ctx := context.Background()
+
+	err := manager.UpdateLeaseTableCapacity(ctx, TableCapacity{ReadCap: 20, WriteCap: 10})
+	assert(t, err == nil && len(client.updates) == 1, "expect the table to be updated")
+	update := client.updates[0]
+	assert(t, aws.Int64Value(update.ProvisionedThroughput.ReadCapacityUnits) == 20, "expect the table throughput to be updated")
+	assert(t, len(update.GlobalSecondaryIndexUpdates) == 1, "expect the index throughput to be updated")
+
+	err = manager.UpdateLeaseTableCapacity(ctx, TableCapacity{ReadCap: 10, WriteCap: 10})
+	assert(t, err == nil && len(client.updates) == 2, "expect the index to be updated")
+	assert(t, client.updates[1].ProvisionedThroughput == nil, "expect the unchanged table throughput to be omitted")
+
+	err = manager.UpdateLeaseTableCapacity(ctx, TableCapacity{OnDemand: true})
+	assert(t, err == nil && len(client.updates) == 3, "expect the billing mode to be switched")
+	assert(t, aws.StringValue(client.updates[2].BillingMode) == dynamodb.BillingModePayPerRequest, "expect an on-demand table")
+
+	client.result[methodDescribeTable] = args{&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableStatus:           aws.String(dynamodb.TableStatusActive),
+		ProvisionedThroughput: throughput(10, 10),
+	}}}
+	err = manager.UpdateLeaseTableCapacity(ctx, TableCapacity{ReadCap: 10, WriteCap: 10})
+	assert(t, err == nil && len(client.updates) == 3, "expect the unchanged table not to be updated")
+
+	err = manager.UpdateLeaseTableCapacity(ctx, TableCapacity{ReadCap: 0, WriteCap: 10})
+	assert(t, errors.Is(err, ErrInvalidCapacity), "expect to fail with an invalid capacity")
+
+	manager.Client = client.clientMock
+	err = manager.UpdateLeaseTableCapacity(ctx, TableCapacity{ReadCap: 20, WriteCap: 10})
+	assert(t, errors.Is(err, ErrUpdateTableUnsupported), "expect to fail with clients that can't update tables")
+}
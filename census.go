@@ -0,0 +1,100 @@
+package lease
+
+import (
+	"sort"
+	"time"
+)
+
+// WorkerInfo describes a worker of the fleet, as seen from the leases it holds.
+// See: Leaser.Workers.
+type WorkerInfo struct {
+	Id string
+	// Host, Zone and Version are the host name, the availability zone and the application
+	// version of the worker, as recorded on the leases it owns. See: Config.Host, Config.Zone
+	// and Config.Version. they are empty for workers that hold leases only in shared mode.
+	Host    string
+	Zone    string
+	Version string
+	// Leases and Shared are the number of leases the worker holds exclusively and in
+	// shared mode.
+	Leases int
+	Shared int
+	// LastHeartbeat is the last time one of the worker leases was seen renewed.
+	LastHeartbeat time.Time
+	// Expired indicates that none of the worker leases was renewed within their expiry,
+	// i.e: the worker is probably gone, and its leases are about to be taken.
+	Expired bool
+}
+
+// Worker returns the worker that owns the lease, as recorded on the lease when it was taken.
+// It's the zero WorkerInfo if the lease has no owner. Only the identity of the worker is set;
+// the lease counts and the liveness are known only to the census. See: Leaser.Workers.
+func (l *Lease) Worker() WorkerInfo {
+	if l.hasNoOwner() {
+		return WorkerInfo{}
+	}
+	return WorkerInfo{
+		Id:      l.Owner,
+		Host:    l.OwnerHost,
+		Zone:    l.OwnerZone,
+		Version: l.OwnerVersion,
+	}
+}
+
+// Workers returns the workers of the fleet that hold leases, sorted by their id, using the
+// cached view of the last take cycle. The table is not read, so dashboards and orchestration
+// may call it frequently. Workers that hold no leases are not part of the census.
+func (c *Coordinator) Workers() []WorkerInfo {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	workers := make(map[string]*WorkerInfo)
+	worker := func(id string) *WorkerInfo {
+		w, ok := workers[id]
+		if !ok {
+			w = &WorkerInfo{Id: id, Expired: true}
+			workers[id] = w
+		}
+		return w
+	}
+	for _, lease := range c.Taker.Leases() {
+		expireAfter := c.expireAfter(lease.Key)
+		if !lease.hasNoOwner() {
+			w := worker(lease.Owner)
+			w.Leases++
+			w.Host, w.Zone, w.Version = lease.OwnerHost, lease.OwnerZone, lease.OwnerVersion
+			w.seen(lease.lastRenewal, expireAfter, c.now())
+		}
+		for id, renewed := range lease.activeHolders(expireAfter, c.now()) {
+			w := worker(id)
+			w.Shared++
+			w.seen(time.Unix(renewed, 0), expireAfter, c.now())
+		}
+	}
+	list := make([]WorkerInfo, 0, len(workers))
+	for _, w := range workers {
+		list = append(list, *w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	return list
+}
+
+// seen records a renewal of one of the worker leases, as of now.
+func (w *WorkerInfo) seen(renewed time.Time, expireAfter time.Duration, now time.Time) {
+	if renewed.After(w.LastHeartbeat) {
+		w.LastHeartbeat = renewed
+	}
+	if now.Sub(renewed) <= expireAfter {
+		w.Expired = false
+	}
+}
+
+// Leases returns the leases as of the last take cycle.
+func (l *leaseTaker) Leases() []Lease {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	leases := make([]Lease, 0, len(l.view))
+	for _, lease := range l.view {
+		leases = append(leases, lease)
+	}
+	return leases
+}
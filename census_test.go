@@ -0,0 +1,55 @@
+package lease
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestCoordinatorWorkers(t *testing.T) {
+	logger := testLogger()
+	config := &Config{Logger: logger, LeaseTable: "test", WorkerId: "1", ExpireAfter: time.Minute}
+	config.defaults()
+	taker := &leaseTaker{Config: config}
+	now := time.Now()
+	taker.updateLeases(context.Background(), []*Lease{
+		{Key: "foo", Owner: "2", OwnerHost: "host-2", OwnerZone: "us-east-1a", OwnerVersion: "v2", lastRenewal: now},
+		{Key: "bar", Owner: "2", OwnerHost: "host-2", OwnerZone: "us-east-1a", OwnerVersion: "v2", lastRenewal: now.Add(-time.Second)},
+		{Key: "baz", Owner: "3", lastRenewal: now.Add(-2 * time.Minute)},
+		{Key: "qux", Owner: "NULL", Holders: map[string]int64{"2": now.Unix(), "4": now.Unix(), "5": now.Add(-time.Hour).Unix()}, lastRenewal: now},
+	})
+	c := &Coordinator{Config: config, Taker: taker}
+
+	workers := c.Workers()
+	assert(t, len(workers) == 3, "expect the inactive shared holders not to be part of the census")
+	w := workers[0]
+	assert(t, w.Id == "2" && w.Host == "host-2" && w.Zone == "us-east-1a" && w.Version == "v2", "expect the owner metadata of the worker")
+	assert(t, w.Leases == 2 && w.Shared == 1 && !w.Expired, "expect the worker to hold 2 leases and 1 shared lease")
+	assert(t, w.LastHeartbeat.Unix() == now.Unix(), "expect the last heartbeat to be the latest renewal")
+	assert(t, workers[1].Id == "3" && workers[1].Expired, "expect the worker of expired leases to be expired")
+	assert(t, workers[2].Id == "4" && workers[2].Shared == 1 && workers[2].Leases == 0, "expect the shared holders to be part of the census")
+}
+
+func TestLeaseWorker(t *testing.T) {
+	manager := newTestManager(nil)
+	manager.Host, manager.Zone, manager.Version = "host-1", "us-east-1a", "v1"
+	lease := Lease{Key: "foo", Owner: "NULL", Counter: 1}
+	assert(t, lease.Worker() == WorkerInfo{}, "expect no worker for a lease without an owner")
+
+	taken := manager.takenLease(&lease)
+	w := taken.Worker()
+	assert(t, w.Id == "1" && w.Host == "host-1" && w.Zone == "us-east-1a" && w.Version == "v1", "expect the worker metadata on the taken lease")
+
+	input := manager.condUpdateInput(taken, lease)
+	assert(t, aws.StringValue(input.ExpressionAttributeValues[":zone"].S) == "us-east-1a", "expect the zone to be recorded on take")
+	item, err := manager.Serializer.Encode(&taken)
+	assert(t, err == nil && aws.StringValue(item[LeaseOwnerZoneKey].S) == "us-east-1a", "expect the zone to be encoded")
+
+	evicted := taken
+	evicted.Owner, evicted.OwnerZone = "NULL", ""
+	input = manager.condUpdateInput(evicted, taken)
+	assert(t, strings.Contains(*input.UpdateExpression, LeaseOwnerZoneKey), "expect the zone to be removed on evict")
+}
@@ -0,0 +1,110 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// updateMock is a clientMock that records the update requests, and returns their item.
+type updateMock struct {
+	*clientMock
+	inputs []*dynamodb.UpdateItemInput
+}
+
+func (c *updateMock) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	c.inputs = append(c.inputs, input)
+	item := map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:     input.Key[LeaseKeyKey],
+		LeaseOwnerKey:   {S: aws.String("1")},
+		LeaseCounterKey: {N: aws.String("1")},
+	}
+	if v, ok := input.ExpressionAttributeValues[":"+LeaseCheckpointKey]; ok {
+		item[LeaseCheckpointKey] = v
+	}
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+func TestCheckpointSerialize(t *testing.T) {
+	manager := newTestManager(nil)
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1, Checkpoint: "42"}
+	item, err := manager.Serializer.Encode(lease)
+	assert(t, err == nil && aws.StringValue(item[LeaseCheckpointKey].S) == "42", "expect the checkpoint to be encoded")
+	decoded, err := manager.Serializer.Decode(item)
+	assert(t, err == nil && decoded.Checkpoint == "42", "expect the checkpoint to be decoded")
+	_, ok := decoded.Get(LeaseCheckpointKey)
+	assert(t, !ok, "expect the checkpoint not to be an extra field")
+}
+
+func TestUpdateLeaseCheckpoint(t *testing.T) {
+	client := &updateMock{clientMock: newClientMock(nil)}
+	manager := newTestManager(client)
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+
+	lease.SetCheckpoint("42", 0)
+	ulease, err := manager.UpdateLease(context.Background(), lease)
+	assert(t, err == nil && ulease.Checkpoint == "42", "expect the checkpoint to be returned")
+	assert(t, strings.Contains(*client.inputs[0].UpdateExpression, LeaseCheckpointKey+" = :"+LeaseCheckpointKey), "expect the checkpoint to be set")
+	assert(t, aws.StringValue(client.inputs[0].ConditionExpression) == "#owner = :condOwner", "expect the checkpoint to be written only by the owner")
+
+	ulease.SetCheckpoint("", 0)
+	_, err = manager.UpdateLease(context.Background(), ulease)
+	assert(t, err == nil && strings.Contains(*client.inputs[1].UpdateExpression, "REMOVE "+LeaseCheckpointKey), "expect a cleared checkpoint to be removed")
+
+	ulease.Set("status", "done")
+	manager.UpdateLease(context.Background(), ulease)
+	assert(t, !strings.Contains(*client.inputs[2].UpdateExpression, LeaseCheckpointKey), "expect the checkpoint to be written only when it's set")
+	assert(t, client.inputs[2].ConditionExpression == nil, "expect the extra fields to be written unconditionally")
+
+	manager.KCLSchema = true
+	kcl := &Lease{Key: "foo", Owner: "1", Counter: 7}
+	kcl.SetCheckpoint("43", 0)
+	manager.UpdateLease(context.Background(), kcl)
+	assert(t, aws.StringValue(client.inputs[3].ConditionExpression) == "#owner = :condOwner AND #counter = :condCounter", "expect the KCL checkpoint to be conditional on the counter")
+	assert(t, aws.StringValue(client.inputs[3].ExpressionAttributeValues[":condCounter"].N) == "7", "expect the counter of the lease")
+}
+
+func TestUpdateLeaseCheckpointStolen(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {
+			&dynamodb.ConditionalCheckFailedException{Message_: aws.String("failed"), Item: map[string]*dynamodb.AttributeValue{
+				LeaseOwnerKey:   {S: aws.String("2")},
+				LeaseCounterKey: {N: aws.String("3")},
+			}},
+		},
+	})
+	manager := newTestManager(client)
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	lease.SetPayload("bar")
+	_, err := manager.UpdateLease(context.Background(), lease)
+	assert(t, errors.Is(err, ErrLeaseStolen), "expect the payload not to be written to a stolen lease")
+}
+
+func TestCoordinatorCheckpoint(t *testing.T) {
+	foo := &Lease{Key: "foo", Owner: "1", concurrencyToken: "token"}
+	manager := newManagerMock(map[method]args{
+		methodUpdate: {nil},
+	})
+	c := &Coordinator{
+		Config:  &Config{WorkerId: "1", Logger: testLogger()},
+		Manager: manager,
+		Renewer: &leaseHolder{heldLeases: map[string]*Lease{"foo": foo}},
+		cache:   &leaseCache{now: time.Now},
+	}
+	lease, err := c.Checkpoint(context.Background(), *foo, "42")
+	assert(t, err == nil && lease.Checkpoint == "42" && manager.calls[methodUpdate] == 1, "expect the checkpoint to be written")
+
+	stale := *foo
+	stale.concurrencyToken = "stale"
+	_, err = c.Checkpoint(context.Background(), stale, "43")
+	assert(t, err == ErrTokenNotMatch && manager.calls[methodUpdate] == 1, "expect not to checkpoint a lease that was re-acquired")
+
+	_, err = c.Checkpoint(context.Background(), Lease{Key: "bar"}, "1")
+	assert(t, err == ErrLeaseNotHeld, "expect not to checkpoint a lease that is not held")
+}
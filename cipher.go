@@ -0,0 +1,217 @@
+package lease
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// ErrInvalidCiphertext error will be returns if the encrypted fields of a lease
+// are malformed, or cannot be authenticated.
+var ErrInvalidCiphertext = errors.New("leaser: invalid ciphertext")
+
+// Cipher used to encrypt the lease extra fields on the client side before they
+// are written to the table, and to decrypt them on read.
+// The additional data is the lease key, that binds the ciphertext to its lease.
+type Cipher interface {
+	Encrypt(plaintext, additionalData []byte) ([]byte, error)
+	Decrypt(ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// aeadCipher is a Cipher that uses a user-provided AEAD, and prefix each
+// ciphertext with a random nonce.
+type aeadCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAEADCipher returns a Cipher that encrypts the lease extra fields using the given
+// AEAD (e.g: AES-GCM, or ChaCha20-Poly1305).
+func NewAEADCipher(aead cipher.AEAD) Cipher {
+	return &aeadCipher{aead}
+}
+
+func (c *aeadCipher) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (c *aeadCipher) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext, err := c.aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+// kmsCipher is a Cipher that uses envelope encryption with AWS KMS. The fields are
+// encrypted with AES-GCM by a data key, and the encrypted data key is stored as part
+// of the ciphertext:
+//
+//	[2 bytes length][encrypted data key][nonce][sealed fields]
+//
+// A data key is used for up to kmsKeyMaxUses encryptions, or kmsKeyMaxAge, before a new
+// one is generated.
+type kmsCipher struct {
+	sync.Mutex
+	client kmsiface.KMSAPI
+	keyId  string
+	now    func() time.Time
+	// current is the data key of the encryptions.
+	current *kmsKey
+	// keys caches the decrypted data keys, to avoid calling KMS on every read.
+	keys map[string]*kmsKey
+}
+
+// kmsKey is a plaintext data key, and its encrypted form.
+type kmsKey struct {
+	plaintext []byte
+	blob      []byte
+	created   time.Time
+	uses      int
+}
+
+const (
+	// kmsKeyMaxUses is the number of encryptions of a data key. it keeps the random nonces
+	// of AES-GCM far below their collision bound.
+	kmsKeyMaxUses = 1 << 20
+	// kmsKeyMaxAge is the time that a data key is used for encryptions, and that a
+	// decrypted data key is cached.
+	kmsKeyMaxAge = 5 * time.Minute
+	// kmsCacheSize is the number of the cached decrypted data keys.
+	kmsCacheSize = 256
+)
+
+// NewKMSCipher returns a Cipher that uses envelope encryption with the given KMS key.
+func NewKMSCipher(client kmsiface.KMSAPI, keyId string) Cipher {
+	return &kmsCipher{
+		client: client,
+		keyId:  keyId,
+		now:    time.Now,
+		keys:   make(map[string]*kmsKey),
+	}
+}
+
+func (c *kmsCipher) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	key, err := c.dataKey()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := c.aead(key.plaintext).Encrypt(plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, 2, 2+len(key.blob)+len(sealed))
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(key.blob)))
+	ciphertext = append(ciphertext, key.blob...)
+	return append(ciphertext, sealed...), nil
+}
+
+// dataKey returns the data key of an encryption, and generates a new one if the current
+// key was used kmsKeyMaxUses times, or for kmsKeyMaxAge.
+func (c *kmsCipher) dataKey() (*kmsKey, error) {
+	c.Lock()
+	defer c.Unlock()
+	now := c.now()
+	if k := c.current; k != nil && k.uses < kmsKeyMaxUses && now.Sub(k.created) < kmsKeyMaxAge {
+		k.uses++
+		return k, nil
+	}
+	out, err := c.client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(c.keyId),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.current = &kmsKey{plaintext: out.Plaintext, blob: out.CiphertextBlob, created: now, uses: 1}
+	c.cache(&kmsKey{plaintext: out.Plaintext, blob: out.CiphertextBlob, created: now})
+	return c.current, nil
+}
+
+func (c *kmsCipher) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < 2 {
+		return nil, ErrInvalidCiphertext
+	}
+	n := int(binary.BigEndian.Uint16(ciphertext))
+	if len(ciphertext) < 2+n {
+		return nil, ErrInvalidCiphertext
+	}
+	blob, sealed := ciphertext[2:2+n], ciphertext[2+n:]
+	c.Lock()
+	k, ok := c.keys[string(blob)]
+	if ok && c.now().Sub(k.created) >= kmsKeyMaxAge {
+		delete(c.keys, string(blob))
+		ok = false
+	}
+	c.Unlock()
+	if ok {
+		return c.aead(k.plaintext).Decrypt(sealed, additionalData)
+	}
+	out, err := c.client.Decrypt(&kms.DecryptInput{
+		KeyId:          aws.String(c.keyId),
+		CiphertextBlob: blob,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.Lock()
+	c.cache(&kmsKey{plaintext: out.Plaintext, blob: blob, created: c.now()})
+	c.Unlock()
+	return c.aead(out.Plaintext).Decrypt(sealed, additionalData)
+}
+
+// cache adds the given decrypted data key to the cache. If the cache is full, the
+// expired keys are removed, or the oldest one if none expired. it's called with the lock.
+func (c *kmsCipher) cache(key *kmsKey) {
+	if len(c.keys) >= kmsCacheSize {
+		var oldest *kmsKey
+		now := c.now()
+		for blob, k := range c.keys {
+			if now.Sub(k.created) >= kmsKeyMaxAge {
+				delete(c.keys, blob)
+			} else if oldest == nil || k.created.Before(oldest.created) {
+				oldest = k
+			}
+		}
+		if len(c.keys) >= kmsCacheSize {
+			delete(c.keys, string(oldest.blob))
+		}
+	}
+	c.keys[string(key.blob)] = key
+}
+
+// aead returns an AES-GCM Cipher for the given data key.
+func (c *kmsCipher) aead(key []byte) Cipher {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return errCipher{err}
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return errCipher{err}
+	}
+	return NewAEADCipher(gcm)
+}
+
+// errCipher is a Cipher that always fails with the given error.
+type errCipher struct {
+	err error
+}
+
+func (c errCipher) Encrypt([]byte, []byte) ([]byte, error) { return nil, c.err }
+func (c errCipher) Decrypt([]byte, []byte) ([]byte, error) { return nil, c.err }
@@ -0,0 +1,96 @@
+package lease
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// kmsMock is a KMS client that generates random data keys, and decrypts the data keys
+// it generated.
+type kmsMock struct {
+	kmsiface.KMSAPI
+	keys      map[string][]byte
+	generated int
+	decrypted int
+}
+
+func (m *kmsMock) GenerateDataKey(*kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	m.generated++
+	blob := fmt.Sprintf("key-%d", m.generated)
+	m.keys[blob] = key
+	return &kms.GenerateDataKeyOutput{Plaintext: key, CiphertextBlob: []byte(blob)}, nil
+}
+
+func (m *kmsMock) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	m.decrypted++
+	return &kms.DecryptOutput{Plaintext: m.keys[string(input.CiphertextBlob)]}, nil
+}
+
+func TestSerializerCipher(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 32))
+	gcm, _ := cipher.NewGCM(block)
+	s := newSerializer(&Config{Cipher: NewAEADCipher(gcm)})
+
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	lease.Set("checkpoint", "secret")
+	lease.SetAs("sequences", []string{"1", "2"}, NumberSet)
+
+	item, err := s.Encode(lease)
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, item[LeaseEncryptedKey] != nil, "expect extra fields to be encrypted")
+	assert(t, item["checkpoint"] == nil && item["sequences"] == nil, "expect extra fields not to be in plaintext")
+
+	decoded, err := s.Decode(item)
+	assert(t, err == nil, "expect Decode not to fail")
+	v, ok := decoded.Get("checkpoint")
+	assert(t, ok && v == "secret", "expect extra field to be decrypted")
+	_, ok = decoded.Get("sequences")
+	assert(t, ok, "expect explicit field to be decrypted")
+
+	// the ciphertext is bound to the lease key.
+	item, _ = s.Encode(lease)
+	item[LeaseKeyKey].S = &[]string{"bar"}[0]
+	_, err = s.Decode(item)
+	assert(t, err == ErrInvalidCiphertext, "expect Decode to fail with ErrInvalidCiphertext")
+}
+
+func TestKMSCipher(t *testing.T) {
+	client := &kmsMock{keys: make(map[string][]byte)}
+	now := time.Now()
+	c := NewKMSCipher(client, "alias/leases").(*kmsCipher)
+	c.now = func() time.Time { return now }
+
+	first, err := c.Encrypt([]byte("foo"), []byte("1"))
+	assert(t, err == nil, "expect Encrypt not to fail")
+	second, err := c.Encrypt([]byte("bar"), []byte("2"))
+	assert(t, err == nil && client.generated == 1, "expect the data key to be reused")
+	plaintext, err := c.Decrypt(first, []byte("1"))
+	assert(t, err == nil && string(plaintext) == "foo", "expect Decrypt to return the plaintext")
+	plaintext, err = c.Decrypt(second, []byte("2"))
+	assert(t, err == nil && string(plaintext) == "bar", "expect Decrypt to return the plaintext")
+	assert(t, client.decrypted == 0, "expect the generated data key to be cached")
+
+	now = now.Add(kmsKeyMaxAge)
+	_, err = c.Encrypt([]byte("baz"), []byte("3"))
+	assert(t, err == nil && client.generated == 2, "expect a new data key after its max age")
+	plaintext, err = c.Decrypt(first, []byte("1"))
+	assert(t, err == nil && string(plaintext) == "foo" && client.decrypted == 1, "expect an expired data key to be decrypted by KMS")
+
+	for i := 0; i < kmsCacheSize+10; i++ {
+		c.Lock()
+		c.cache(&kmsKey{blob: []byte(fmt.Sprint(i)), created: now.Add(time.Duration(i))})
+		c.Unlock()
+	}
+	assert(t, len(c.keys) == kmsCacheSize, "expect the cache to be bounded")
+	_, ok := c.keys["0"]
+	assert(t, !ok, "expect the oldest data keys to be evicted")
+}
@@ -0,0 +1,67 @@
+package lease
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time of the lease expiry, the intervals of the taker and renewer
+// loops, and the backoff of the retries. Replace it with a fake clock (e.g: the one of the
+// leasetest package) to test the worker logic without real waiting.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the
+	// returned channel.
+	After(time.Duration) <-chan time.Time
+	// Sleep pauses the current goroutine for at least the duration.
+	Sleep(time.Duration)
+}
+
+// SystemClock is the Clock of the time package. It's the default Clock of the Config.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time                         { return time.Now() }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (SystemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+// now returns the current time of the config clock.
+func (c *Config) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+// since returns the time elapsed since t, by the config clock.
+func (c *Config) since(t time.Time) time.Duration {
+	return c.now().Sub(t)
+}
+
+// after returns a channel that receives the time of the config clock, after d elapses.
+func (c *Config) after(d time.Duration) <-chan time.Time {
+	if c.Clock == nil {
+		return time.After(d)
+	}
+	return c.Clock.After(d)
+}
+
+// sleep pauses the current goroutine for the given duration, or until ctx is done.
+// it returns the error of ctx, if it's done before the duration elapses.
+func (c *Config) sleep(ctx context.Context, d time.Duration) error {
+	if _, ok := c.Clock.(SystemClock); ok || c.Clock == nil {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-c.Clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,189 @@
+// Command lease-admin inspects and edits a lease table, e.g: during an incident, when a
+// worker is stuck holding leases. Usage:
+//
+//	lease-admin -table leases [-region us-east-1] [-endpoint url] <command> [keys...]
+//
+// The commands are:
+//
+//	list          lists the leases, with their owner, counter and epoch.
+//	owners        shows the number of leases per owner.
+//	dump          writes all the leases in JSON format, with their extra fields.
+//	create keys   creates the given leases without an owner, if they do not exist.
+//	evict keys    sets the given leases to have no owner, so the workers take them again.
+//	delete keys   deletes the given leases.
+//
+// The evictions and the deletions are conditional on the owner that was read, so a lease
+// that changed hands meanwhile is left as is, and reported.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/a8m/lease"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// errUsage is returned for an unknown command, or missing arguments.
+var errUsage = errors.New("usage: lease-admin -table name <list|owners|dump|create|evict|delete> [keys...]")
+
+func main() {
+	var (
+		table    = flag.String("table", "", "the name of the lease table")
+		region   = flag.String("region", "", "the AWS region of the table. defaults to the AWS config")
+		endpoint = flag.String("endpoint", "", "the DynamoDB endpoint, e.g: of DynamoDB local")
+	)
+	flag.Parse()
+	if *table == "" || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, errUsage)
+		os.Exit(2)
+	}
+	cfg := aws.NewConfig()
+	if *region != "" {
+		cfg = cfg.WithRegion(*region)
+	}
+	if *endpoint != "" {
+		cfg = cfg.WithEndpoint(*endpoint)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{Config: *cfg, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	manager := lease.NewFromConfig(&lease.Config{
+		WorkerId:   "lease-admin",
+		LeaseTable: *table,
+		Client:     dynamodb.New(sess),
+		Logger:     lease.NewSlogLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+	}).(*lease.Coordinator).Manager
+	if err := run(context.Background(), manager, flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// run runs the given command with its arguments, and writes its output to w.
+func run(ctx context.Context, manager lease.Manager, args []string, w io.Writer) error {
+	cmd, keys := args[0], args[1:]
+	switch cmd {
+	case "list", "owners", "dump":
+		if len(keys) > 0 {
+			return errUsage
+		}
+	case "create", "evict", "delete":
+		if len(keys) == 0 {
+			return errUsage
+		}
+	default:
+		return errUsage
+	}
+	switch cmd {
+	case "list":
+		list, err := manager.ListLeases(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tOWNER\tCOUNTER\tEPOCH")
+		for _, l := range sorted(list) {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", l.Key, l.Owner, l.Counter, l.Epoch)
+		}
+		return tw.Flush()
+	case "owners":
+		list, err := manager.ListLeases(ctx)
+		if err != nil {
+			return err
+		}
+		counts := make(map[string]int)
+		for _, l := range list {
+			counts[l.Owner]++
+		}
+		owners := make([]string, 0, len(counts))
+		for owner := range counts {
+			owners = append(owners, owner)
+		}
+		sort.Slice(owners, func(i, j int) bool {
+			if counts[owners[i]] != counts[owners[j]] {
+				return counts[owners[i]] > counts[owners[j]]
+			}
+			return owners[i] < owners[j]
+		})
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "OWNER\tLEASES")
+		for _, owner := range owners {
+			fmt.Fprintf(tw, "%s\t%d\n", owner, counts[owner])
+		}
+		return tw.Flush()
+	case "dump":
+		list, err := manager.ListLeases(ctx)
+		if err != nil {
+			return err
+		}
+		views := make([]lease.LeaseView, 0, len(list))
+		for _, l := range sorted(list) {
+			views = append(views, lease.NewLeaseView(*l))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(views)
+	}
+	var failed error
+	for _, key := range keys {
+		msg, err := apply(ctx, manager, cmd, key)
+		if err != nil {
+			fmt.Fprintf(w, "%s: %v\n", key, err)
+			failed = fmt.Errorf("lease-admin: %s failed for some of the leases", cmd)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s\n", key, msg)
+	}
+	return failed
+}
+
+// apply runs the given edit command on the lease with the given key, and returns its outcome.
+func apply(ctx context.Context, manager lease.Manager, cmd, key string) (string, error) {
+	if cmd == "create" {
+		created, err := manager.EnsureLease(ctx, &lease.Lease{Key: key})
+		if err != nil {
+			return "", err
+		}
+		if !created {
+			return "exists", nil
+		}
+		return "created", nil
+	}
+	l, err := manager.GetLease(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if cmd == "evict" {
+		owner := l.Owner
+		if err := manager.EvictLease(ctx, l); err != nil {
+			return "", err
+		}
+		return "evicted from " + owner, nil
+	}
+	if err := manager.DeleteLease(ctx, l); err != nil {
+		return "", err
+	}
+	return "deleted", nil
+}
+
+// sorted sorts the given leases by their key.
+func sorted(list []*lease.Lease) []*lease.Lease {
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
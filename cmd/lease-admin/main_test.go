@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/a8m/lease"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	manager := lease.NewFromConfig(&lease.Config{WorkerId: "1", LeaseTable: "leases", Backend: lease.NewMemoryBackend()}).(*lease.Coordinator).Manager
+	exec := func(args ...string) (string, error) {
+		var buf bytes.Buffer
+		err := run(ctx, manager, args, &buf)
+		return buf.String(), err
+	}
+
+	if out, err := exec("create", "foo", "bar"); err != nil || !strings.Contains(out, "foo: created") {
+		t.Fatalf("expect the leases to be created, got: %q, %v", out, err)
+	}
+	if out, _ := exec("create", "foo"); !strings.Contains(out, "foo: exists") {
+		t.Errorf("expect existing leases not to be re-created, got: %q", out)
+	}
+	foo, _ := manager.GetLease(ctx, "foo")
+	if err := manager.TakeLease(ctx, foo); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := exec("list"); !strings.Contains(out, "foo") || !strings.Contains(out, "bar") {
+		t.Errorf("expect to list the leases, got: %q", out)
+	}
+	if out, _ := exec("owners"); strings.Join(strings.Fields(out), " ") != "OWNER LEASES 1 1 NULL 1" {
+		t.Errorf("expect the leases per owner, got: %q", out)
+	}
+	out, err := exec("dump")
+	var views []lease.LeaseView
+	if err != nil || json.Unmarshal([]byte(out), &views) != nil || len(views) != 2 || views[1].Owner != "1" {
+		t.Errorf("expect to dump the leases, got: %q, %v", out, err)
+	}
+	if out, err := exec("evict", "foo"); err != nil || !strings.Contains(out, "evicted from 1") {
+		t.Errorf("expect the lease to be evicted, got: %q, %v", out, err)
+	}
+	if out, err := exec("delete", "foo", "baz"); err == nil || !strings.Contains(out, "foo: deleted") {
+		t.Errorf("expect to report the missing lease, got: %q, %v", out, err)
+	}
+	if _, err := exec("evict"); !errors.Is(err, errUsage) {
+		t.Errorf("expect a usage error, got: %v", err)
+	}
+}
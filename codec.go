@@ -0,0 +1,81 @@
+package lease
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Codec used to encode the lease extra fields to DynamoDB attributes, and vice versa.
+// It allows alternate backends and interop scenarios to choose their wire format
+// (e.g: msgpack) without changing the manager logic. See: AttributeCodec and JSONCodec.
+// Note that fields set using SetAs are always stored as DynamoDB sets.
+type Codec interface {
+	Encode(fields map[string]interface{}) (map[string]*dynamodb.AttributeValue, error)
+	Decode(item map[string]*dynamodb.AttributeValue) (map[string]interface{}, error)
+}
+
+// AttributeCodec is the default Codec. It stores each extra field as a native DynamoDB
+// attribute, using the dynamodbattribute package.
+type AttributeCodec struct{}
+
+func (AttributeCodec) Encode(fields map[string]interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	return dynamodbattribute.MarshalMap(fields)
+}
+
+func (AttributeCodec) Decode(item map[string]*dynamodb.AttributeValue) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if err := dynamodbattribute.ConvertFromMap(item, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// JSONCodec is a Codec that stores all the extra fields as a single JSON document in a
+// string attribute (LeaseFieldsKey), for consumers that read the table with other tools.
+// Extra fields that are stored as native attributes (e.g: by the AttributeCodec) are
+// still decoded, to allow switching between them.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(fields map[string]interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	p, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*dynamodb.AttributeValue{
+		LeaseFieldsKey: {
+			S: aws.String(string(p)),
+		},
+	}, nil
+}
+
+func (JSONCodec) Decode(item map[string]*dynamodb.AttributeValue) (map[string]interface{}, error) {
+	var doc *dynamodb.AttributeValue
+	if v, ok := item[LeaseFieldsKey]; ok {
+		doc = v
+		item = copyItem(item)
+		delete(item, LeaseFieldsKey)
+	}
+	fields, err := AttributeCodec{}.Decode(item)
+	if err != nil || doc == nil {
+		return fields, err
+	}
+	if err := json.Unmarshal([]byte(aws.StringValue(doc.S)), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// copyItem returns a shallow copy of the given item.
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	m := make(map[string]*dynamodb.AttributeValue, len(item))
+	for k, v := range item {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,40 @@
+package lease
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestSerializerJSONCodec(t *testing.T) {
+	s := newSerializer(&Config{Codec: JSONCodec{}})
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	lease.Set("status", "done")
+	lease.Set("checkpoint", 42)
+	lease.SetAs("tags", []string{"a", "b"}, StringSet)
+
+	item, err := s.Encode(lease)
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, item["status"] == nil && item["checkpoint"] == nil, "expect extra fields to be encoded")
+	assert(t, aws.StringValue(item[LeaseFieldsKey].S) == `{"checkpoint":42,"status":"done"}`, "expect extra fields to be stored as JSON")
+	assert(t, item["tags"] != nil && item["tags"].SS != nil, "expect explicit fields to be stored as sets")
+
+	decoded, err := s.Decode(item)
+	assert(t, err == nil, "expect Decode not to fail")
+	v, ok := decoded.Get("status")
+	assert(t, ok && v == "done", "expect extra field to be decoded")
+	v, ok = decoded.Get("checkpoint")
+	assert(t, ok && v == float64(42), "expect extra field to be decoded")
+	_, ok = decoded.Get(LeaseFieldsKey)
+	assert(t, !ok, "expect the JSON document not to be an extra field")
+
+	// native attributes are still decoded
+	decoded, err = s.Decode(map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey: {S: aws.String("foo")},
+		"status":    {S: aws.String("done")},
+	})
+	assert(t, err == nil, "expect Decode not to fail")
+	v, ok = decoded.Get("status")
+	assert(t, ok && v == "done", "expect native attribute to be decoded")
+}
@@ -0,0 +1,101 @@
+package lease
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// pendingLeases returns the leases that their work was not completed.
+func pendingLeases(list []*Lease) []*Lease {
+	var pending []*Lease
+	for _, lease := range list {
+		if !lease.isCompleted() {
+			pending = append(pending, lease)
+		}
+	}
+	return pending
+}
+
+// CompleteLease marks the given lease as completed, and releases it. conditional on
+// the owner of the lease. If Config.DeleteCompleted is set, the lease is deleted
+// instead (and archived, if an Archiver is configured).
+func (l *LeaseManager) CompleteLease(ctx context.Context, lease *Lease) error {
+	if l.DeleteCompleted {
+		return l.wrapError("complete", lease.Key, l.deleteLease(ctx, lease, &dynamodb.DeleteItemInput{
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":condOwner": {
+					S: aws.String(lease.Owner),
+				},
+			},
+			ExpressionAttributeNames: map[string]*string{
+				"#owner": aws.String(LeaseOwnerKey),
+			},
+			ConditionExpression: aws.String("#owner = :condOwner"),
+		}))
+	}
+	now := l.now()
+	_, err := l.updateLease(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(l.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {
+				S: aws.String(lease.Key),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#completed": aws.String(LeaseCompletedAtKey),
+			"#owner":     aws.String(LeaseOwnerKey),
+			"#counter":   aws.String(LeaseCounterKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {
+				N: aws.String(strconv.FormatInt(now.Unix(), 10)),
+			},
+			":null": {
+				S: aws.String("NULL"),
+			},
+			":one": {
+				N: aws.String("1"),
+			},
+			":condOwner": {
+				S: aws.String(lease.Owner),
+			},
+		},
+		UpdateExpression:    aws.String("SET #completed = :now, #owner = :null ADD #counter :one"),
+		ConditionExpression: aws.String("#owner = :condOwner AND attribute_not_exists(#completed)"),
+		ReturnValues:        aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		return l.wrapError("complete", lease.Key, err)
+	}
+	lease.CompletedAt = time.Unix(now.Unix(), 0)
+	lease.Owner = "NULL"
+	lease.Counter = nextCounter(lease.Counter)
+	return nil
+}
+
+// Complete marks the given held lease as completed, and stop holding it.
+func (l *leaseHolder) Complete(ctx context.Context, lease Lease) error {
+	l.RLock()
+	hlease, ok := l.heldLeases[lease.Key]
+	if ok {
+		lease = *hlease
+	}
+	l.RUnlock()
+	if !ok {
+		return ErrLeaseNotHeld
+	}
+	if err := l.manager.CompleteLease(ctx, &lease); err != nil {
+		return err
+	}
+	l.Lock()
+	delete(l.heldLeases, lease.Key)
+	delete(l.heldSince, lease.Key)
+	delete(l.renewedAt, lease.Key)
+	l.Unlock()
+	l.ownerChange(OwnerChange{Key: lease.Key, Type: OwnerReleased, From: l.WorkerId}, &lease)
+	return nil
+}
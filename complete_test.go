@@ -0,0 +1,86 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestCompleteLease(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {
+			&dynamodb.UpdateItemOutput{
+				Attributes: map[string]*dynamodb.AttributeValue{
+					LeaseKeyKey: {S: aws.String("foo")},
+				},
+			},
+			// the lease is owned by another worker, or already completed
+			awserr.New(ConditionalFailed, "", errors.New("")),
+		},
+		methodDeleteItem: {&dynamodb.DeleteItemOutput{}},
+	})
+	manager := newTestManager(client)
+
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	err := manager.CompleteLease(context.Background(), lease)
+	assert(t, err == nil, "expect CompleteLease not to fail")
+	assert(t, lease.isCompleted() && lease.Owner == "NULL" && lease.Counter == 2, "expect lease to be completed and released")
+
+	err = manager.CompleteLease(context.Background(), &Lease{Key: "foo", Owner: "1"})
+	assert(t, isConditionalFailed(err), "expect to fail when the lease is not owned by this worker")
+
+	manager.DeleteCompleted = true
+	err = manager.CompleteLease(context.Background(), &Lease{Key: "foo", Owner: "1"})
+	assert(t, err == nil && client.calls[methodDeleteItem] == 1, "expect to delete the completed lease")
+}
+
+func TestCoordinatorComplete(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodComplete: {nil},
+	})
+	var changes []OwnerChange
+	config := &Config{WorkerId: renewerId, Logger: logger, ExpireAfter: time.Minute, OnOwnerChange: func(c OwnerChange) {
+		changes = append(changes, c)
+	}}
+	holder := &leaseHolder{
+		Config:         config,
+		manager:        manager,
+		heldLeases:     map[string]*Lease{"foo": {Key: "foo", Owner: renewerId}},
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+	}
+	c := &Coordinator{Config: config, Manager: manager, Renewer: holder}
+
+	err := c.Complete(context.Background(), Lease{Key: "bar"})
+	assert(t, err == ErrLeaseNotHeld, "expect to fail if the lease is not held")
+
+	err = c.Complete(context.Background(), Lease{Key: "foo"})
+	assert(t, err == nil, "expect Complete not to fail")
+	assert(t, manager.calls[methodComplete] == 1, "expect to complete the lease")
+	assert(t, len(c.GetHeldLeases()) == 0, "expect to stop holding the lease")
+	assert(t, len(changes) == 1 && changes[0].Type == OwnerReleased, "expect the completion to be reported")
+}
+
+func TestTakerCompleted(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{{Key: "foo", Owner: "NULL", CompletedAt: time.Now()}}},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTake] == 0, "expect not to take completed leases")
+}
@@ -0,0 +1,62 @@
+package lease
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+)
+
+// ErrUnknownCompression error will be returns if the extra fields of a lease
+// compressed with a compressor that is not configured.
+var ErrUnknownCompression = errors.New("leaser: unknown compression")
+
+// Compressor used to compress the lease extra fields when their size is above
+// Config.CompressThreshold. The compressor name stored alongside the compressed
+// payload, and used to select the compressor on read.
+type Compressor interface {
+	Name() string
+	Compress([]byte) ([]byte, error)
+	Decompress([]byte) ([]byte, error)
+}
+
+// GzipCompressor is a Compressor that uses gzip with the given compression level.
+// The zero value uses gzip.DefaultCompression.
+type GzipCompressor struct {
+	Level int
+}
+
+// Name returns the compressor name.
+func (g GzipCompressor) Name() string {
+	return "gzip"
+}
+
+// Compress the given payload.
+func (g GzipCompressor) Compress(p []byte) ([]byte, error) {
+	level := g.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(p); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress the given payload.
+func (g GzipCompressor) Decompress(p []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
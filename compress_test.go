@@ -0,0 +1,50 @@
+package lease
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"strings"
+	"testing"
+)
+
+func TestSerializerCompress(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 32))
+	gcm, _ := cipher.NewGCM(block)
+	for _, c := range []Cipher{nil, NewAEADCipher(gcm)} {
+		s := newSerializer(&Config{Compressor: GzipCompressor{}, CompressThreshold: 1 << 10, Cipher: c})
+
+		// below the threshold
+		lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+		lease.Set("small", "value")
+		item, err := s.Encode(lease)
+		assert(t, err == nil, "expect Encode not to fail")
+		assert(t, item[LeaseCompressedKey] == nil, "expect extra fields not to be compressed")
+
+		// above the threshold
+		large := strings.Repeat("checkpoint", 1<<10)
+		lease.Set("large", large)
+		item, err = s.Encode(lease)
+		assert(t, err == nil, "expect Encode not to fail")
+		assert(t, item["large"] == nil && item["small"] == nil, "expect extra fields to be packed")
+		if c == nil {
+			assert(t, item[LeaseCompressedKey] != nil, "expect extra fields to be compressed")
+			assert(t, len(item[LeaseCompressedKey].B) < len(large), "expect compressed payload to be smaller")
+		}
+
+		decoded, err := s.Decode(item)
+		assert(t, err == nil, "expect Decode not to fail")
+		v, ok := decoded.Get("large")
+		assert(t, ok && v == large, "expect extra field to be decompressed")
+		v, ok = decoded.Get("small")
+		assert(t, ok && v == "value", "expect extra field to be decompressed")
+	}
+}
+
+func TestSerializerUnknownCompression(t *testing.T) {
+	s := newSerializer(&Config{Compressor: GzipCompressor{}, CompressThreshold: 1})
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	lease.Set("field", "value")
+	item, _ := s.Encode(lease)
+	_, err := newSerializer(&Config{}).Decode(item)
+	assert(t, err == ErrUnknownCompression, "expect Decode to fail with ErrUnknownCompression")
+}
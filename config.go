@@ -2,25 +2,32 @@ package lease
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"os"
 	"sync"
 	"time"
 
-	"github.com/Sirupsen/logrus"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/jpillora/backoff"
 )
 
 // Clientface is a thin methods set of DynamoDB.
+// The context passed to the Manager methods is passed to each call, so in-flight calls
+// can be canceled.
 type Clientface interface {
-	Scan(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error)
-	PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
-	UpdateItem(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
-	DeleteItem(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)
-	CreateTable(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error)
-	DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error)
+	GetItemWithContext(aws.Context, *dynamodb.GetItemInput, ...request.Option) (*dynamodb.GetItemOutput, error)
+	ScanWithContext(aws.Context, *dynamodb.ScanInput, ...request.Option) (*dynamodb.ScanOutput, error)
+	PutItemWithContext(aws.Context, *dynamodb.PutItemInput, ...request.Option) (*dynamodb.PutItemOutput, error)
+	UpdateItemWithContext(aws.Context, *dynamodb.UpdateItemInput, ...request.Option) (*dynamodb.UpdateItemOutput, error)
+	DeleteItemWithContext(aws.Context, *dynamodb.DeleteItemInput, ...request.Option) (*dynamodb.DeleteItemOutput, error)
+	CreateTableWithContext(aws.Context, *dynamodb.CreateTableInput, ...request.Option) (*dynamodb.CreateTableOutput, error)
+	DescribeTableWithContext(aws.Context, *dynamodb.DescribeTableInput, ...request.Option) (*dynamodb.DescribeTableOutput, error)
 }
 
 // Backofface is the interface that holds the backoff strategy
@@ -30,36 +37,160 @@ type Backofface interface {
 	Duration() time.Duration
 }
 
-// Logger represents the desired API of both Logger and Entry.
-type Logger interface {
-	WithFields(logrus.Fields) *logrus.Entry
-	WithField(string, interface{}) *logrus.Entry
-	WithError(error) *logrus.Entry
-	Debug(...interface{})
-	Info(...interface{})
-	Error(...interface{})
-	Fatal(...interface{})
-	Debugf(string, ...interface{})
-	Infof(string, ...interface{})
-	Warnf(string, ...interface{})
-}
+var (
+	// ErrMissingTable error will be returns by Config.Validate if the LeaseTable is not set.
+	ErrMissingTable = errors.New("leaser: lease table is missing")
+
+	// ErrInvalidInterval error will be returns by Config.Validate if a duration field is
+	// negative, or it conflicts with the expiry of the leases.
+	ErrInvalidInterval = errors.New("leaser: invalid interval")
+
+	// ErrInvalidCapacity error will be returns by Config.Validate if the read or the write
+	// capacity of the lease table is negative.
+	ErrInvalidCapacity = errors.New("leaser: invalid table capacity")
+
+	// ErrInvalidValue error will be returns by Config.Validate for the rest of the invalid
+	// fields, e.g: negative counts or percents above 100.
+	ErrInvalidValue = errors.New("leaser: invalid value")
+)
 
 // Config is the representation of Coordinator settings.
 type Config struct {
 	// Client is a Clientface implemetation.
+	// Use NewFailoverClient for a regional failover with global tables.
 	Client Clientface
 
-	// Logger is the logger used. defaults to log.Log
+	// ReadClient is the client of the eventually consistent reads of the lease table (i.e:
+	// the scans of the taker, and of the read-only APIs like ListLeasesByOwner), e.g: a DAX
+	// client (github.com/aws/aws-dax-go), so the frequent scans of the takers are served from
+	// its cache, instead of the table RCUs. The writes, the consistent reads (e.g: GetLease)
+	// and the scans of the renewer go through Client, since the renewals are conditional on
+	// the counters they read. The cached scans are stale up to the query cache TTL of the
+	// cluster, and a lease that was renewed in this window looks expired, and its take fails
+	// the condition. Keep the TTL well below ExpireAfter.
+	// defaults to Client.
+	ReadClient Clientface
+
+	// Backend is the storage of the leases, instead of the DynamoDB table of Client. e.g:
+	// NewMemoryBackend, to run the workers without DynamoDB in tests. Lease groups, Reshard,
+	// migrations and the steal budget are not supported with a Backend, and the deleted
+	// leases are not archived. defaults to nil (i.e: DynamoDB).
+	Backend Backend
+
+	// Logger is the logger used. defaults to NewSlogLogger(slog.Default()).
 	Logger Logger
 
+	// Clock is the source of time of the lease expiry, the loop intervals and the retries
+	// backoff. Use a fake clock to test the worker logic without real waiting.
+	// defaults to SystemClock.
+	Clock Clock
+
 	// Backoff determines the backoff strategy for http failures.
 	// Defaults to lease.Backoff with min value of time.Second and jitter
 	// set to true.
 	Backoff Backofface
 
+	// IsRetryable classifies the errors of the DynamoDB calls. Only the errors that it
+	// reports as retryable are retried with the Backoff strategy, and the rest are returned
+	// immediately. Conditional check failures are never retried.
+	// defaults to IsRetryable.
+	IsRetryable func(err error) bool
+
+	// RetryPolicies sets the retry strategy of each operation type, instead of the Backoff
+	// strategy and the default maximum number of retries of the operation (e.g: 3 scans
+	// and 2 updates). See the backoffadapter package for policies of cenkalti/backoff.
+	// defaults to nil.
+	RetryPolicies map[RetryOp]RetryPolicy
+
+	// Codec used to encode the lease extra fields to DynamoDB attributes. See: JSONCodec.
+	// defaults to nil, means each extra field is stored as a native DynamoDB attribute
+	// (i.e: AttributeCodec).
+	Codec Codec
+
+	// Cipher used to encrypt the lease extra fields on the client side, before they are
+	// written to the table. See: NewAEADCipher and NewKMSCipher.
+	// defaults to nil, means the extra fields are not encrypted.
+	Cipher Cipher
+
+	// Compressor used to compress the lease extra fields, when their encoded size is
+	// above CompressThreshold. It reduces the item size and the consumed write capacity.
+	// See: GzipCompressor. defaults to nil, means the extra fields are not compressed.
+	Compressor Compressor
+
+	// CompressThreshold is the encoded size (in bytes) of the extra fields above which
+	// they are compressed. defaults to 4KB.
+	CompressThreshold int
+
+	// Overflow used to store the lease extra fields outside of the table, when their
+	// encoded size (after compression and encryption) is above OverflowThreshold, so large
+	// checkpoint blobs can't break lease writes. See: NewS3Overflow.
+	// defaults to nil, means the extra fields are always stored in the table.
+	Overflow Overflow
+
+	// OverflowThreshold is the encoded size (in bytes) of the extra fields above which
+	// they are stored using the Overflow. defaults to 100KB.
+	OverflowThreshold int
+
+	// ItemSizeWarnThreshold is the size (in bytes) of a lease item above which a warning
+	// is logged on write, before it reaches the DynamoDB item size limit (see: MaxItemSize).
+	// Writes of items above the limit fail with ItemSizeError. defaults to 300KB.
+	ItemSizeWarnThreshold int
+
+	// Migrator used to upgrade the leases to the latest schema version, so the table format
+	// can evolve without manual scripts. See: NewMigrator and Coordinator.Migrate.
+	// defaults to nil, means the leases are not migrated.
+	Migrator *Migrator
+
+	// KCLSchema makes the leases to be read and written in the lease schema of the Amazon
+	// Kinesis Client Library (i.e: the checkpoint, ownerSwitchesSinceCheckpoint and
+	// parentShardId attributes, and no owner attribute on leases that are not held), so the
+	// workers of this package share a lease table with Java KCL workers, e.g: to migrate a
+	// Kinesis consumer from KCL, shard by shard. The shard checkpoints are written using
+	// Lease.SetCheckpoint and Update. The KCL workers do not increment the leaseEpoch of the
+	// leases they take. defaults to false.
+	KCLSchema bool
+
+	// Archiver used to keep a final snapshot of each lease that is deleted, so completed
+	// work units remain auditable after cleanup. See: NewS3Archiver and NewTableArchiver.
+	// defaults to nil, means the deleted leases are not archived.
+	Archiver Archiver
+
+	// SoftDelete makes Delete mark the leases as tombstoned, instead of deleting them.
+	// Tombstoned leases are hidden from the takers, and they are purged (i.e: deleted)
+	// after TombstoneRetention. It protects against accidental deletion of active leases;
+	// a tombstoned lease can be restored by creating it again.
+	SoftDelete bool
+
+	// TombstoneRetention is the time to keep the tombstoned leases before they are
+	// purged. defaults to 24h.
+	TombstoneRetention time.Duration
+
+	// DeleteCompleted makes Complete delete the completed leases, instead of keeping them
+	// marked as completed. If an Archiver is configured, the final snapshot of the lease
+	// is archived.
+	DeleteCompleted bool
+
 	// The Amazon DynamoDB table name used for tracking leases.
 	LeaseTable string
 
+	// AttributeNames are the names of the key, the owner and the counter attributes of
+	// the leases, e.g: to operate on a pre-existing table with different column names.
+	// The Client and the ReadClient are wrapped to rename the attributes in the requests
+	// and the responses. defaults to leaseKey, leaseOwner and leaseCounter.
+	AttributeNames AttributeNames
+
+	// Partition shares the lease table between applications (e.g: the name of the
+	// application or the stream). The table is created with PartitionKey as the hash key
+	// and the lease key as the range key, each item is written with the partition, and the
+	// leases are listed by querying the partition, instead of scanning the table. The
+	// leases of the other partitions are invisible to the workers. It's not supported with
+	// KCLSchema. defaults to "", means the lease key is the hash key of the table.
+	Partition string
+
+	// PartitionKey is the name of the hash key attribute of a partitioned table.
+	// See: Partition. defaults to LeasePartitionKey.
+	PartitionKey string
+
 	// WorkerId used as a lease-owner.
 	WorkerId string
 
@@ -69,11 +200,35 @@ type Config struct {
 	// and it's shards will be assigned to other workers. defaults to 10s.
 	ExpireAfter time.Duration
 
+	// Heartbeat persists the time of the last renew or take of each lease (see:
+	// Lease.Heartbeat), and the taker uses it for the leases that it sees for the first
+	// time, instead of waiting a full ExpireAfter from the first scan. A freshly started
+	// worker then takes over the leases of dead workers faster. The clocks of the workers
+	// should be synchronized up to HeartbeatSkew. Every worker of the table should enable
+	// it, since the writes of a worker without it remove the persisted heartbeat, and the
+	// lease is then expired like without heartbeats. defaults to false.
+	Heartbeat bool
+
+	// HeartbeatSkew is the tolerated clock skew between the workers, that is added to the
+	// persisted heartbeats. The heartbeats are stored in seconds. defaults to 2s.
+	HeartbeatSkew time.Duration
+
 	// Max leases to steal from another worker at one time (for load balancing).
 	// Setting this to a higher number allow faster load convergence (e.g. during deployments, cold starts),
 	// but can cause higher churn in the system. defaults to 1.
 	MaxLeasesToStealAtOneTime int
 
+	// StealRate is the number of leases per second that the entire fleet may steal. The
+	// budget is a token bucket that is shared by all the workers, and stored in the lease
+	// table (see: StealBudgetKey). It prevents correlated storms where every worker steals
+	// at once, e.g: after a mass expiry event. Taking expired leases is not limited.
+	// defaults to 0, means unlimited.
+	StealRate float64
+
+	// StealBurst is the capacity of the steal budget bucket. defaults to StealRate rounded
+	// up, and at least 1.
+	StealBurst int
+
 	// The Amazon DynamoDB table used for tracking leases will be provisioned with this read capacity.
 	// Defaults to 10.
 	LeaseTableReadCap int
@@ -82,21 +237,327 @@ type Config struct {
 	// Defaults to 10.
 	LeaseTableWriteCap int
 
+	// OnDemand makes the Amazon DynamoDB table used for tracking leases to be created in
+	// on-demand mode (i.e: the PAY_PER_REQUEST billing mode), without provisioned throughput.
+	// LeaseTableReadCap and LeaseTableWriteCap are ignored.
+	OnDemand bool
+
+	// SSEEnabled makes the Amazon DynamoDB table used for tracking leases to be created
+	// with server-side encryption using an AWS KMS key, instead of the AWS owned key.
+	// Defaults to the AWS managed key (aws/dynamodb), unless SSEKMSKeyId is set.
+	SSEEnabled bool
+
+	// SSEKMSKeyId is the ARN, id or alias of the customer managed AWS KMS key that
+	// encrypts the lease table. Setting it implies SSEEnabled.
+	SSEKMSKeyId string
+
+	// LeaseTableTags are the tags (e.g: team, cost-center or environment) of the Amazon
+	// DynamoDB table used for tracking leases. They are applied when the table is created.
+	// Use LeaseManager.ReconcileTags to apply them to an existing table.
+	LeaseTableTags map[string]string
+
+	// OwnerIndex makes the Amazon DynamoDB table used for tracking leases to be created
+	// with a global secondary index on the owner of the leases (see: OwnerIndexName), so
+	// ListLeasesByOwner queries the index, instead of scanning the table. The index has the
+	// provisioned throughput of the table.
+	OwnerIndex bool
+
+	// PointInTimeRecovery enables the point-in-time recovery (continuous backups) of the
+	// Amazon DynamoDB table used for tracking leases. It's enabled by CreateLeaseTable,
+	// also if the table already exists.
+	PointInTimeRecovery bool
+
+	// LeaseTTL enables the DynamoDB Time to Live of the lease table on the leaseExpiresAt
+	// attribute, that is set to the time (unix seconds) after LeaseTTL on each take and
+	// renew, and removed when the lease is evicted. The leases of dead workers are then
+	// eventually deleted by DynamoDB, even if no other worker is running. The TTL is
+	// enabled by CreateLeaseTable, and it must be greater than ExpireAfter. A table has a
+	// single Time to Live attribute, so it can't be set with LeaseTemplate.TTL, and
+	// CreateLeaseTable fails with ErrTTLConflict if the table has another one.
+	// defaults to 0, means leases are never expired by DynamoDB.
+	LeaseTTL time.Duration
+
+	// DrainInterval is the time to wait between lease releases when the coordinator
+	// is drained. See: Coordinator.Drain. defaults to 1s.
+	DrainInterval time.Duration
+
+	// MaxHoldDuration is the maximum time this worker holds a lease before it hands it
+	// over to other workers, and waits one taker interval before it may take it back.
+	// It's a time-sliced fair scheduling for workloads with more workers than leases,
+	// so no single worker monopolizes scarce leases indefinitely.
+	// defaults to 0, means leases are held until they are lost or stolen.
+	MaxHoldDuration time.Duration
+
+	// Tier is the priority tier of this worker. Workers of higher tier preempt leases
+	// held by workers of lower tier (e.g: reserved capacity over spot capacity), using a
+	// drain-then-take protocol; the owner stops reporting the lease as held, releases it
+	// on its next renewal, and the preempting worker takes it.
+	// defaults to 0, means all workers are equal.
+	Tier int
+
+	// Version is the application version of this worker. It's recorded on the leases
+	// this worker holds. See: VersionTakeoverRate.
+	Version string
+
+	// Host is the host name of this worker. It's recorded on the leases this worker
+	// holds, to find where a lease is processed. See: Leaser.Owner.
+	// defaults to os.Hostname().
+	Host string
+
+	// Zone is the availability zone of this worker (e.g: "us-east-1a"). It's recorded on
+	// the leases this worker holds, like Host, so operators can see where each lease is
+	// processed. See: Lease.Worker.
+	// defaults to "", means the zone is not recorded.
+	Zone string
+
+	// DelegateURL is the URL of a Delegate handler in a cooperating process (e.g: a sidecar),
+	// that renews the held leases on behalf of this worker while it's paused. The held
+	// leases are sent to the delegate after each renewal. See: Delegate.
+	// defaults to "", means no delegation.
+	DelegateURL string
+
+	// DelegateToken is the shared secret of the worker and its Delegate. The worker sends
+	// it as a bearer token, and the delegate rejects the requests without it.
+	// defaults to "", means the delegate handler is not authenticated.
+	DelegateToken string
+
+	// DelegationTTL is the maximum time a Delegate keeps renewing the leases of a worker that
+	// stopped sending them. It bounds the pauses that the delegate covers, and delays the
+	// failover of a dead worker by the same time.
+	// defaults to ExpireAfter.
+	DelegationTTL time.Duration
+
+	// MaxStaleness is the maximum time the renewer keeps renewing the held leases from the
+	// last known view of the table, while listing the leases fails (e.g: during a DynamoDB
+	// outage). The renewals are still conditional. See: OnDegraded.
+	// defaults to ExpireAfter.
+	MaxStaleness time.Duration
+
+	// OnDegraded is called when the renewer starts operating from the last known view of
+	// the table (degraded is true), and when it recovers (degraded is false). It's called
+	// from the renewer loop, and should not block. See: Leaser.Degraded.
+	OnDegraded func(degraded bool)
+
+	// ThrottleWindow is the time the writes to the lease table must keep being throttled
+	// (e.g: ProvisionedThroughputExceededException) before the worker enters a throttled
+	// mode, that gives priority to the renewals of its held leases over taking and stealing
+	// new ones. The worker exits the mode after a window without throttled writes.
+	// defaults to ExpireAfter. See: OnThrottled.
+	ThrottleWindow time.Duration
+
+	// OnThrottled is called when the worker enters the throttled mode (throttled is true),
+	// and when it exits it (throttled is false). It's called from the taker and renewer
+	// loops, and should not block. See: Leaser.Throttled.
+	OnThrottled func(throttled bool)
+
+	// LockDir enables a local lock file (flock) in the given directory, keyed by WorkerId,
+	// so two copies of the same worker on a host cannot both start a coordinator. Start
+	// fails with ErrWorkerLocked if the lock is already held. defaults to "", means disabled.
+	LockDir string
+
+	// Source is the authoritative source of the work units of the application (e.g: the
+	// shard list). If it's set, Start compares the lease table with it before the leases
+	// are taken, and reports the missing and orphaned leases. See: Coordinator.Preflight.
+	// defaults to nil, means no preflight check.
+	Source Source
+
+	// OnDrift is called by Start with the drift between the lease table and Source,
+	// if there is one.
+	OnDrift func(Drift)
+
+	// StrictPreflight makes Start fail with a *DriftError if the lease table drifted from
+	// Source, or with the error of the preflight check, instead of logging it.
+	StrictPreflight bool
+
+	// FastStartWindow is the time after Start that the taker runs every FastStartInterval,
+	// instead of twice the ExpireAfter. It lets a cold-started fleet take the unowned leases,
+	// and the leases of the previous workers (once they expire), within seconds rather than
+	// several normal-length cycles. defaults to 0, means disabled.
+	FastStartWindow time.Duration
+
+	// FastStartInterval is the interval between the taker cycles within FastStartWindow.
+	// defaults to the renewer interval (i.e: a third of ExpireAfter).
+	FastStartInterval time.Duration
+
+	// GetCacheTTL is the time to cache the leases returned by Get. The cached leases
+	// are invalidated when this worker writes them. defaults to 0, means Get reads the
+	// table on each call.
+	GetCacheTTL time.Duration
+
+	// OwnerConsistentRead makes Owner fall back to a consistent read of the lease, when
+	// the requested key is missing from the cached view of the last take cycle.
+	// defaults to false, means Owner answers only from the cached view.
+	OwnerConsistentRead bool
+
+	// AdminConfirmWindow makes the destructive operations of Admin need two steps: an
+	// intent is proposed, and it's applied within this window. defaults to 0, means the
+	// operations are applied directly. See: Admin.Propose.
+	AdminConfirmWindow time.Duration
+
+	// AdminRequireApprover requires the admin intents to be applied by an approver other
+	// than their proposer. defaults to false.
+	AdminRequireApprover bool
+
+	// JournalSize is the number of recent renewal and take outcomes that are kept in
+	// memory, for post-mortems. See: Leaser.Stats. defaults to 256.
+	JournalSize int
+
+	// VersionTakeoverRate is the maximum number of leases to steal per take cycle from
+	// workers of older Version, regardless of the balancing target. It gives a built-in
+	// blue/green migration of leases to a new deployment. when enabled, workers also avoid
+	// stealing leases back from workers of newer version.
+	// defaults to 0, means disabled.
+	VersionTakeoverRate int
+
+	// CompareVersions used to compare workers versions, and returns 0 if a == b, -1
+	// if a < b, and +1 if a > b. defaults to lease.CompareVersions.
+	CompareVersions func(a, b string) int
+
+	// Canary labels this worker as a canary worker. Canary workers take only canary
+	// leases, and the rest of the workers never take them; they form two separate
+	// pools, each one balanced on its own. It allows new code to process a controlled
+	// slice of the leases before a full rollout.
+	// Note that canary leases are not processed if there are no canary workers.
+	Canary bool
+
+	// CanaryPercent is the percentage (0-100) of leases that are considered as canary
+	// leases, selected by the hash of their keys. Leases can also be tagged explicitly
+	// using the Lease.Canary field. It must be the same for all workers in the fleet.
+	// defaults to 0.
+	CanaryPercent float64
+
+	// DenyKeys are the prefixes of the lease keys that this worker does not take, e.g: to
+	// move a misbehaving key range off a worker at runtime (see: Coordinator.Reconfigure).
+	// The held leases that match are released on their next renewal.
+	DenyKeys []string
+
+	// StormPercent is the percentage (0-100) of leases that change owner within StormWindow,
+	// above which a takeover storm is reported to OnTakeoverStorm. A takeover storm gives
+	// an early warning of expiry misconfiguration, or fleet instability.
+	// defaults to 0, means disabled.
+	StormPercent float64
+
+	// StormWindow is the sliding window used to count the ownership changes.
+	// defaults to 1m.
+	StormWindow time.Duration
+
+	// OnTakeover is called after this worker takes a lease, with the previous owner and the
+	// reason of the take. It's called from the taker loop, and should not block.
+	// Use it to count the takeovers by their reason, and investigate lease churn.
+	OnTakeover func(Takeover)
+
+	// OnOwnerChange is called when this worker acquires, loses or releases a lease. It's
+	// called from the taker and renewer loops, and should not block. See: OwnerChange,
+	// and Webhook for delivering the changes to an HTTP endpoint.
+	OnOwnerChange func(OwnerChange)
+
+	// OnTakeoverStorm is called when a takeover storm is detected, at most once per
+	// StormWindow. It's called from the taker loop, and should not block.
+	OnTakeoverStorm func(TakeoverStorm)
+
+	// BalanceByLoad makes the workers equalize their total load, rather than their number
+	// of leases. The load of each lease is reported by its owner (see: Leaser.ReportLoad),
+	// and leases without a load report are considered as loaded as the average lease.
+	// Note that leases are not preempted by tier when enabled.
+	BalanceByLoad bool
+
+	// Namespace maps a lease key to the namespace(tenant) it belongs to.
+	// Used to look up the namespace quota. defaults to nil, means that all
+	// leases belong to the same (empty) namespace.
+	// See: PrefixNamespace.
+	Namespace func(key string) string
+
+	// Quotas limits the number of leases that may exist, or be held by a single
+	// worker, within a namespace. Namespaces without a quota are unlimited.
+	Quotas map[string]Quota
+
+	// Profiles overrides the expiry, the steal rate and the balancing strategy of the leases
+	// within a namespace. Namespaces without a profile use the config settings.
+	// See: Profile.
+	Profiles map[string]Profile
+
+	// Template holds the default extra fields and TTL of the created leases.
+	// See: LeaseTemplate.
+	Template LeaseTemplate
+
 	// Allow for some variance when calculating lease expirations. set to 25ms.
 	epsilonMills time.Duration
+
+	// scopes of the coordinator, that get the events of their leases. see: Coordinator.Scope.
+	scopes *scopes
+
+	// maintenance is the maintenance mode, as of the last scan of the lease table.
+	// see: Admin.Pause.
+	maintenance *maintenance
 }
 
-// defaults for configuration.
+// defaults for configuration. exits the process if the config is invalid.
 func (c *Config) defaults() {
+	c.initLogger()
+	if err := c.Validate(); err != nil {
+		c.Logger.Fatalf("%s", err)
+	}
+}
+
+// initLogger sets the default logger, and adds the package field to it.
+func (c *Config) initLogger() {
 	if c.Logger == nil {
-		c.Logger = logrus.New()
+		c.Logger = NewSlogLogger(slog.Default())
 	}
 	c.Logger = c.Logger.WithField("package", "leases")
+}
+
+// Validate sets the defaults of the fields that are not set, and validates the config.
+// It returns a *ConfigError that describes the first invalid field, e.g:
+//
+//	if err := config.Validate(); errors.Is(err, lease.ErrInvalidInterval) {
+//		// ...
+//	}
+//
+// Validate is called by Start, and the constructors of this package (e.g: New) exit the
+// process if the config is invalid, except of the Coordinator constructors, that leave it
+// to Start to fail.
+func (c *Config) Validate() error {
+	if c.Logger == nil {
+		c.Logger = NewSlogLogger(slog.Default())
+	}
 
 	if c.Client == nil {
 		c.Client = dynamodb.New(session.New(aws.NewConfig()))
 	}
 
+	if c.Clock == nil {
+		c.Clock = SystemClock{}
+	}
+	if f, ok := c.Client.(*failoverClient); ok {
+		f.Lock()
+		f.now = c.Clock.Now
+		f.Unlock()
+	}
+
+	c.AttributeNames.defaults()
+	if err := c.AttributeNames.validate(); err != nil {
+		return configError("AttributeNames", ErrInvalidValue, err.Error())
+	}
+	if c.PartitionKey == "" {
+		c.PartitionKey = LeasePartitionKey
+	}
+	if c.Partition != "" && c.KCLSchema {
+		return configError("Partition", ErrInvalidValue, "is not supported with KCLSchema")
+	}
+	if c.PartitionKey != LeasePartitionKey && (isReserved(c.PartitionKey) || isPacking(c.PartitionKey) ||
+		c.PartitionKey == c.AttributeNames.Key || c.PartitionKey == c.AttributeNames.Owner || c.PartitionKey == c.AttributeNames.Counter) {
+		return configError("PartitionKey", ErrInvalidValue, c.PartitionKey+" is used by the lease package")
+	}
+	if len(c.AttributeNames.renames()) > 0 || c.Partition != "" {
+		if _, ok := c.Client.(*attributeClient); !ok {
+			c.Client = newAttributeClient(c.Client, c.AttributeNames, c.PartitionKey, c.Partition)
+		}
+		if _, ok := c.ReadClient.(*attributeClient); !ok && c.ReadClient != nil {
+			c.ReadClient = newAttributeClient(c.ReadClient, c.AttributeNames, c.PartitionKey, c.Partition)
+		}
+	}
+
 	if c.Backoff == nil {
 		c.Backoff = &Backoff{
 			b: &backoff.Backoff{
@@ -105,48 +566,251 @@ func (c *Config) defaults() {
 			}}
 	}
 
-	if c.LeaseTable == "" {
-		c.Logger.Fatal("LeaseTable is required field")
+	if c.CompressThreshold == 0 {
+		c.CompressThreshold = 4 << 10
+	}
+	if c.CompressThreshold < 0 {
+		return configError("CompressThreshold", ErrInvalidValue, "must be greater than 0")
 	}
 
-	c.epsilonMills = time.Millisecond * 25
+	if c.OverflowThreshold == 0 {
+		c.OverflowThreshold = 100 << 10
+	}
+	if c.OverflowThreshold < 0 {
+		return configError("OverflowThreshold", ErrInvalidValue, "must be greater than 0")
+	}
 
-	if c.ExpireAfter == 0 {
-		c.ExpireAfter = time.Second * 10
+	if c.ItemSizeWarnThreshold == 0 {
+		c.ItemSizeWarnThreshold = 300 << 10
 	}
-	if c.ExpireAfter < time.Second*10 {
-		c.Logger.Fatal("ExpireAfter must be greater or equal to 10s")
+	if c.ItemSizeWarnThreshold < 0 || c.ItemSizeWarnThreshold > MaxItemSize {
+		return configError("ItemSizeWarnThreshold", ErrInvalidValue, "must be between 0 and MaxItemSize")
 	}
 
-	if c.MaxLeasesToStealAtOneTime == 0 {
-		c.MaxLeasesToStealAtOneTime = 1
+	if c.LeaseTable == "" {
+		return configError("LeaseTable", ErrMissingTable, "is required")
 	}
-	if c.MaxLeasesToStealAtOneTime < 0 {
-		c.Logger.Fatal("MaxLeasesToStealAtOneTime should be greater than 0")
+
+	c.epsilonMills = time.Millisecond * 25
+
+	if err := c.tunableDefaults(); err != nil {
+		return err
 	}
 
 	if c.LeaseTableReadCap == 0 {
 		c.LeaseTableReadCap = 10
 	}
 	if c.LeaseTableReadCap < 0 {
-		c.Logger.Fatal("LeaseTableReadCap must be greater than 0")
+		return configError("LeaseTableReadCap", ErrInvalidCapacity, "must be greater than 0")
 	}
 
 	if c.LeaseTableWriteCap == 0 {
 		c.LeaseTableWriteCap = 10
 	}
 	if c.LeaseTableWriteCap < 0 {
-		c.Logger.Fatal("LeaseTableWriteCap must be greater than 0")
+		return configError("LeaseTableWriteCap", ErrInvalidCapacity, "must be greater than 0")
+	}
+
+	if c.CompareVersions == nil {
+		c.CompareVersions = CompareVersions
+	}
+
+	if c.CanaryPercent < 0 || c.CanaryPercent > 100 {
+		return configError("CanaryPercent", ErrInvalidValue, "must be between 0 and 100")
+	}
+
+	if c.Host == "" {
+		c.Host, _ = os.Hostname()
+	}
+
+	if c.DelegationTTL == 0 {
+		c.DelegationTTL = c.ExpireAfter
+	}
+	if c.DelegationTTL < 0 {
+		return configError("DelegationTTL", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.FastStartWindow < 0 {
+		return configError("FastStartWindow", ErrInvalidInterval, "must be greater than 0")
+	}
+	if c.FastStartInterval == 0 {
+		c.FastStartInterval = c.renewerInterval()
+	}
+	if c.FastStartInterval < 0 {
+		return configError("FastStartInterval", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.GetCacheTTL < 0 {
+		return configError("GetCacheTTL", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.AdminConfirmWindow < 0 {
+		return configError("AdminConfirmWindow", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.HeartbeatSkew == 0 {
+		c.HeartbeatSkew = 2 * time.Second
+	}
+	if c.HeartbeatSkew < 0 {
+		return configError("HeartbeatSkew", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.LeaseTTL < 0 || c.LeaseTTL > 0 && c.LeaseTTL <= c.ExpireAfter {
+		return configError("LeaseTTL", ErrInvalidInterval, "must be greater than ExpireAfter")
+	}
+
+	if c.Template.TTLField == "" {
+		c.Template.TTLField = "ttl"
+	}
+	if c.Template.TTL < 0 {
+		return configError("Template.TTL", ErrInvalidInterval, "must be greater than 0")
+	}
+	if c.Template.TTL > 0 && c.LeaseTTL > 0 {
+		return configError("Template.TTL", ErrInvalidValue, "can't be set with LeaseTTL")
+	}
+
+	if c.JournalSize == 0 {
+		c.JournalSize = 256
+	}
+	if c.JournalSize < 0 {
+		return configError("JournalSize", ErrInvalidValue, "must be greater than 0")
+	}
+
+	if c.MaxStaleness == 0 {
+		c.MaxStaleness = c.ExpireAfter
+	}
+	if c.MaxStaleness < 0 {
+		return configError("MaxStaleness", ErrInvalidInterval, "must be greater than 0")
+	}
+	if c.MaxStaleness < c.renewerInterval() {
+		return configError("MaxStaleness", ErrInvalidInterval, "must be greater or equal to the renew interval (ExpireAfter/3)")
+	}
+
+	if c.ThrottleWindow == 0 {
+		c.ThrottleWindow = c.ExpireAfter
+	}
+	if c.ThrottleWindow < 0 {
+		return configError("ThrottleWindow", ErrInvalidInterval, "must be greater than 0")
 	}
 
 	if c.WorkerId == "" {
 		wid, err := uuid()
 		if err != nil {
-			c.Logger.Fatal("Failed to generate uuid. WorkerId is required field")
+			return &ConfigError{Field: "WorkerId", Reason: "failed to generate uuid", Err: err}
 		}
 		c.Logger.Infof("WorkerId does not provided in config. WorkerId is automatically assigned as: %s", wid)
 		c.WorkerId = wid
 	}
+	return nil
+}
+
+// tunableDefaults sets the defaults of the tunable fields, that can be changed at
+// runtime (see: Coordinator.Reconfigure), and returns an error if they are invalid.
+func (c *Config) tunableDefaults() error {
+	if c.ExpireAfter == 0 {
+		c.ExpireAfter = time.Second * 10
+	}
+	if c.ExpireAfter < time.Second*10 {
+		return configError("ExpireAfter", ErrInvalidInterval, "must be greater or equal to 10s")
+	}
+
+	if c.MaxLeasesToStealAtOneTime == 0 {
+		c.MaxLeasesToStealAtOneTime = 1
+	}
+	if c.MaxLeasesToStealAtOneTime < 0 {
+		return configError("MaxLeasesToStealAtOneTime", ErrInvalidValue, "should be greater than 0")
+	}
+
+	if c.StealRate < 0 {
+		return configError("StealRate", ErrInvalidValue, "must be greater than 0")
+	}
+	if c.StealBurst == 0 {
+		c.StealBurst = int(math.Max(1, math.Ceil(c.StealRate)))
+	}
+	if c.StealBurst < 0 {
+		return configError("StealBurst", ErrInvalidValue, "must be greater than 0")
+	}
+
+	if c.DrainInterval == 0 {
+		c.DrainInterval = time.Second
+	}
+	if c.DrainInterval < 0 {
+		return configError("DrainInterval", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.MaxHoldDuration < 0 {
+		return configError("MaxHoldDuration", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.TombstoneRetention == 0 {
+		c.TombstoneRetention = 24 * time.Hour
+	}
+	if c.TombstoneRetention < 0 {
+		return configError("TombstoneRetention", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	if c.VersionTakeoverRate < 0 {
+		return configError("VersionTakeoverRate", ErrInvalidValue, "must be greater than 0")
+	}
+
+	if c.StormPercent < 0 || c.StormPercent > 100 {
+		return configError("StormPercent", ErrInvalidValue, "must be between 0 and 100")
+	}
+	if c.StormWindow == 0 {
+		c.StormWindow = time.Minute
+	}
+	if c.StormWindow < 0 {
+		return configError("StormWindow", ErrInvalidInterval, "must be greater than 0")
+	}
+
+	for ns, q := range c.Quotas {
+		if q.MaxLeases < 0 || q.MaxLeasesPerWorker < 0 {
+			return configError(fmt.Sprintf("Quotas[%q]", ns), ErrInvalidValue, "must be greater than 0")
+		}
+	}
+	return c.validateProfiles()
+}
+
+// reconfigure replaces the tunable fields of the config with the fields of the given
+// config. the fields that default to a value derived from ExpireAfter are derived again,
+// unless the given config sets them. the merged config is validated first, with the
+// cross-field checks of Validate, and nothing is changed if it's invalid.
+func (c *Config) reconfigure(n Config) error {
+	merged := *c
+	merged.setTunables(&n)
+	merged.DelegationTTL = n.DelegationTTL
+	merged.ThrottleWindow = n.ThrottleWindow
+	merged.FastStartInterval = n.FastStartInterval
+	merged.MaxStaleness = n.MaxStaleness
+	if err := merged.Validate(); err != nil {
+		return err
+	}
+	c.setTunables(&merged)
+	c.DelegationTTL = merged.DelegationTTL
+	c.ThrottleWindow = merged.ThrottleWindow
+	c.FastStartInterval = merged.FastStartInterval
+	c.MaxStaleness = merged.MaxStaleness
+	return nil
+}
+
+// setTunables copies the tunable fields of the given config.
+func (c *Config) setTunables(n *Config) {
+	c.ExpireAfter = n.ExpireAfter
+	c.MaxLeasesToStealAtOneTime = n.MaxLeasesToStealAtOneTime
+	c.StealRate = n.StealRate
+	c.StealBurst = n.StealBurst
+	c.DrainInterval = n.DrainInterval
+	c.MaxHoldDuration = n.MaxHoldDuration
+	c.TombstoneRetention = n.TombstoneRetention
+	c.VersionTakeoverRate = n.VersionTakeoverRate
+	c.StormPercent = n.StormPercent
+	c.StormWindow = n.StormWindow
+	c.OnTakeoverStorm = n.OnTakeoverStorm
+	c.OnTakeover = n.OnTakeover
+	c.BalanceByLoad = n.BalanceByLoad
+	c.Quotas = n.Quotas
+	c.Profiles = n.Profiles
+	c.DenyKeys = n.DenyKeys
 }
 
 func uuid() (string, error) {
@@ -180,3 +844,8 @@ func (b *Backoff) Reset() {
 	b.b.Reset()
 	b.Unlock()
 }
+
+// renewerInterval returns the interval between the renewer cycles.
+func (c *Config) renewerInterval() time.Duration {
+	return c.ExpireAfter/3 - c.epsilonMills
+}
@@ -0,0 +1,40 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, tt := range []struct {
+		config Config
+		field  string
+		kind   error
+	}{
+		{Config{}, "LeaseTable", ErrMissingTable},
+		{Config{LeaseTable: "test", ExpireAfter: time.Second}, "ExpireAfter", ErrInvalidInterval},
+		{Config{LeaseTable: "test", LeaseTableReadCap: -1}, "LeaseTableReadCap", ErrInvalidCapacity},
+		{Config{LeaseTable: "test", MaxStaleness: time.Second}, "MaxStaleness", ErrInvalidInterval},
+		{Config{LeaseTable: "test", LeaseTTL: time.Second}, "LeaseTTL", ErrInvalidInterval},
+		{Config{LeaseTable: "test", LeaseTTL: time.Hour, Template: LeaseTemplate{TTL: time.Hour}}, "Template.TTL", ErrInvalidValue},
+		{Config{LeaseTable: "test", HeartbeatSkew: -time.Second}, "HeartbeatSkew", ErrInvalidInterval},
+		{Config{LeaseTable: "test", Quotas: map[string]Quota{"a": {MaxLeases: -1}}}, `Quotas["a"]`, ErrInvalidValue},
+	} {
+		tt.config.Logger = testLogger()
+		err := tt.config.Validate()
+		var cerr *ConfigError
+		assert(t, errors.As(err, &cerr) && cerr.Field == tt.field, "expect the invalid field to be "+tt.field)
+		assert(t, errors.Is(err, tt.kind), "expect the error of "+tt.field+" to wrap its kind")
+	}
+	config := &Config{Logger: testLogger(), LeaseTable: "test", WorkerId: "1"}
+	assert(t, config.Validate() == nil, "expect a valid config not to fail")
+	assert(t, config.ExpireAfter == 10*time.Second && config.LeaseTableReadCap == 10, "expect the defaults to be set")
+}
+
+func TestStartInvalidConfig(t *testing.T) {
+	leaser := NewFromConfig(&Config{Logger: testLogger(), Client: newClientMock(nil), WorkerId: "1"})
+	err := leaser.Start(context.Background())
+	assert(t, errors.Is(err, ErrMissingTable), "expect Start to fail with the config error")
+}
@@ -0,0 +1,212 @@
+// Package consulbackend runs the coordinator on the Consul KV store, instead of DynamoDB.
+//
+// The leases are stored as JSON values under a key prefix, and the conditional writes of
+// lease.Backend are check-and-set writes on the ModifyIndex of the stored lease, so the
+// conditions on the leaseOwner and the leaseCounter hold across workers.
+//
+// A lease that is taken by a worker records the Consul session of the worker. Once the
+// session is invalidated (e.g: the node of the worker failed its health checks, or the
+// session TTL lapsed), the lease is read as evicted, and it's taken by the other workers
+// without waiting for it to expire. For example:
+//
+//	client, err := api.NewClient(api.DefaultConfig())
+//	session, _, err := client.Session().Create(&api.SessionEntry{TTL: "30s"}, nil)
+//	go client.Session().RenewPeriodic("30s", session, nil, done)
+//	leaser := lease.New("leases", lease.WithBackend(consulbackend.New(client, "leases", session)))
+package consulbackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+	"github.com/hashicorp/consul/api"
+)
+
+// KV is the subset of the Consul KV API that is used by the backend. It's implemented by
+// *api.KV.
+type KV interface {
+	Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error)
+	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+	CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+}
+
+// Sessions is the subset of the Consul session API that is used by the backend. It's
+// implemented by *api.Session.
+type Sessions interface {
+	Info(id string, q *api.QueryOptions) (*api.SessionEntry, *api.QueryMeta, error)
+}
+
+// Backend is a lease.Backend that stores the leases in the Consul KV store.
+type Backend struct {
+	KV       KV
+	Sessions Sessions
+	// Prefix is the prefix of the keys of the lease table, e.g: its name.
+	Prefix string
+	// Session is the Consul session of this worker, that is recorded on the leases it
+	// takes. Empty means that the leases expire only by their leaseCounter.
+	Session string
+}
+
+// New returns a lease.Backend that stores the leases under the given key prefix, using the
+// given Consul client, and records the given session on the leases taken by this worker.
+func New(client *api.Client, prefix, session string) lease.Backend {
+	return &Backend{KV: client.KV(), Sessions: client.Session(), Prefix: prefix, Session: session}
+}
+
+// envelope is the stored value of a lease.
+type envelope struct {
+	// Session is the session of the lease owner.
+	Session string          `json:"session,omitempty"`
+	Lease   json.RawMessage `json:"lease"`
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (*lease.Lease, error) {
+	pair, _, err := b.KV.Get(b.key(key), (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil {
+		return nil, lease.ErrLeaseNotFound
+	}
+	l, _, err := b.view(ctx, pair, make(map[string]bool))
+	return l, err
+}
+
+func (b *Backend) List(ctx context.Context) ([]*lease.Lease, error) {
+	pairs, _, err := b.KV.List(b.key(""), (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	// the liveness of the owner sessions is checked once per listing.
+	alive := make(map[string]bool)
+	list := make([]*lease.Lease, 0, len(pairs))
+	for _, pair := range pairs {
+		l, _, err := b.view(ctx, pair, alive)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, nil
+}
+
+func (b *Backend) PutIfAbsent(ctx context.Context, l *lease.Lease) (bool, error) {
+	value, err := b.encode(l, "", "")
+	if err != nil {
+		return false, err
+	}
+	// a zero ModifyIndex writes the pair only if it does not exist.
+	ok, _, err := b.KV.CAS(&api.KVPair{Key: b.key(l.Key), Value: value}, (&api.WriteOptions{}).WithContext(ctx))
+	return ok, err
+}
+
+func (b *Backend) CompareAndUpdate(ctx context.Context, old, l *lease.Lease) error {
+	pair, session, err := b.compare(ctx, old)
+	if err != nil || pair == nil {
+		if err == nil {
+			err = lease.ErrConflict
+		}
+		return err
+	}
+	value, err := b.encode(l, old.Owner, session)
+	if err != nil {
+		return err
+	}
+	ok, _, err := b.KV.CAS(&api.KVPair{Key: pair.Key, Value: value, ModifyIndex: pair.ModifyIndex}, (&api.WriteOptions{}).WithContext(ctx))
+	if err == nil && !ok {
+		err = lease.ErrConflict
+	}
+	return err
+}
+
+func (b *Backend) Delete(ctx context.Context, old *lease.Lease) error {
+	pair, _, err := b.compare(ctx, old)
+	if err != nil || pair == nil {
+		// the lease does not exist.
+		return err
+	}
+	ok, _, err := b.KV.DeleteCAS(&api.KVPair{Key: pair.Key, ModifyIndex: pair.ModifyIndex}, (&api.WriteOptions{}).WithContext(ctx))
+	if err == nil && !ok {
+		err = lease.ErrConflict
+	}
+	return err
+}
+
+// compare returns the stored pair of the given lease, and the session of its owner, if
+// the stored lease was not changed since old was read. it returns a nil pair if the lease
+// does not exist, and fails with lease.ErrConflict if it was changed.
+func (b *Backend) compare(ctx context.Context, old *lease.Lease) (*api.KVPair, string, error) {
+	pair, _, err := b.KV.Get(b.key(old.Key), (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx))
+	if err != nil || pair == nil {
+		return nil, "", err
+	}
+	cur, session, err := b.view(ctx, pair, make(map[string]bool))
+	if err != nil {
+		return nil, "", err
+	}
+	craw, err := record.Encode(cur)
+	if err != nil {
+		return nil, "", err
+	}
+	oraw, err := record.Encode(old)
+	if err != nil {
+		return nil, "", err
+	}
+	if craw != oraw {
+		return nil, "", lease.ErrConflict
+	}
+	return pair, session, nil
+}
+
+// view returns the lease of the given pair, as it's seen by the workers, and the session
+// of its owner. leases that the session of their owner was invalidated have no owner.
+func (b *Backend) view(ctx context.Context, pair *api.KVPair, alive map[string]bool) (*lease.Lease, string, error) {
+	var env envelope
+	if err := json.Unmarshal(pair.Value, &env); err != nil {
+		return nil, "", errors.New("consulbackend: decode lease: " + err.Error())
+	}
+	l, err := record.Decode(string(env.Lease))
+	if err != nil || env.Session == "" || l.Owner == "NULL" {
+		return l, env.Session, err
+	}
+	ok, checked := alive[env.Session]
+	if !checked {
+		entry, _, err := b.Sessions.Info(env.Session, (&api.QueryOptions{}).WithContext(ctx))
+		if err != nil {
+			return nil, "", err
+		}
+		ok = entry != nil
+		alive[env.Session] = ok
+	}
+	if !ok {
+		l.Owner = "NULL"
+	}
+	return l, env.Session, nil
+}
+
+// encode returns the stored value of the given lease, that its previous owner is the given
+// owner, with the given session. the session is kept while the owner does not change, and
+// the session of this worker is recorded when the lease is taken.
+func (b *Backend) encode(l *lease.Lease, owner, session string) ([]byte, error) {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case l.Owner == "" || l.Owner == "NULL":
+		session = ""
+	case l.Owner != owner:
+		session = b.Session
+	}
+	return json.Marshal(envelope{Session: session, Lease: json.RawMessage(raw)})
+}
+
+// key returns the Consul key of the lease with the given key.
+func (b *Backend) key(key string) string {
+	return strings.TrimSuffix(b.Prefix, "/") + "/" + key
+}
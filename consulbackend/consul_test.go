@@ -0,0 +1,128 @@
+package consulbackend
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/a8m/lease"
+	"github.com/hashicorp/consul/api"
+)
+
+// kvMock is an in-memory KV, with the check-and-set semantics of Consul.
+type kvMock struct {
+	pairs map[string]*api.KVPair
+	index uint64
+}
+
+func (m *kvMock) Get(key string, _ *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if p, ok := m.pairs[key]; ok {
+		c := *p
+		return &c, nil, nil
+	}
+	return nil, nil, nil
+}
+
+func (m *kvMock) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	var pairs api.KVPairs
+	for k, p := range m.pairs {
+		if strings.HasPrefix(k, prefix) {
+			c := *p
+			pairs = append(pairs, &c)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil, nil
+}
+
+func (m *kvMock) CAS(p *api.KVPair, _ *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	cur, ok := m.pairs[p.Key]
+	if p.ModifyIndex == 0 && ok || p.ModifyIndex != 0 && (!ok || cur.ModifyIndex != p.ModifyIndex) {
+		return false, nil, nil
+	}
+	m.index++
+	m.pairs[p.Key] = &api.KVPair{Key: p.Key, Value: p.Value, ModifyIndex: m.index}
+	return true, nil, nil
+}
+
+func (m *kvMock) DeleteCAS(p *api.KVPair, _ *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if cur, ok := m.pairs[p.Key]; !ok || cur.ModifyIndex != p.ModifyIndex {
+		return false, nil, nil
+	}
+	delete(m.pairs, p.Key)
+	return true, nil, nil
+}
+
+// sessionsMock holds the live sessions.
+type sessionsMock map[string]bool
+
+func (s sessionsMock) Info(id string, _ *api.QueryOptions) (*api.SessionEntry, *api.QueryMeta, error) {
+	if !s[id] {
+		return nil, nil, nil
+	}
+	return &api.SessionEntry{ID: id}, nil, nil
+}
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	kv, sessions := &kvMock{pairs: make(map[string]*api.KVPair)}, sessionsMock{"s1": true}
+	b := &Backend{KV: kv, Sessions: sessions, Prefix: "leases", Session: "s1"}
+
+	l := lease.NewLease("foo")
+	l.Owner = "NULL"
+	l.Counter = 1
+	if ok, err := b.PutIfAbsent(ctx, &l); err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	if ok, _ := b.PutIfAbsent(ctx, &l); ok {
+		t.Error("expect existing leases not to be stored")
+	}
+
+	old, err := b.Get(ctx, "foo")
+	if err != nil || old.Counter != 1 {
+		t.Fatalf("expect to get the stored lease, got: %+v, %v", old, err)
+	}
+	taken := *old
+	taken.Owner = "1"
+	taken.Counter++
+	if err := b.CompareAndUpdate(ctx, old, &taken); err != nil {
+		t.Fatalf("expect the lease to be taken: %v", err)
+	}
+	if err := b.CompareAndUpdate(ctx, old, &taken); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the update of a changed lease to conflict, got: %v", err)
+	}
+	if held, _ := b.Get(ctx, "foo"); held.Owner != "1" {
+		t.Errorf("expect the lease to be held while the session is alive, got: %s", held.Owner)
+	}
+
+	// the session of the owner is invalidated.
+	delete(sessions, "s1")
+	list, err := b.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Owner != "NULL" {
+		t.Fatalf("expect the lease to be read as evicted, got: %v, %v", list, err)
+	}
+	b2 := &Backend{KV: kv, Sessions: sessions, Prefix: "leases", Session: "s2"}
+	sessions["s2"] = true
+	retaken := *list[0]
+	retaken.Owner = "2"
+	retaken.Counter++
+	if err := b2.CompareAndUpdate(ctx, list[0], &retaken); err != nil {
+		t.Fatalf("expect the evicted lease to be taken: %v", err)
+	}
+	if held, _ := b2.Get(ctx, "foo"); held.Owner != "2" {
+		t.Errorf("expect the lease to be held by the new owner, got: %s", held.Owner)
+	}
+
+	if err := b2.Delete(ctx, list[0]); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the delete of a changed lease to conflict, got: %v", err)
+	}
+	held, _ := b2.Get(ctx, "foo")
+	if err := b2.Delete(ctx, held); err != nil {
+		t.Fatalf("expect the lease to be deleted: %v", err)
+	}
+	if _, err := b2.Get(ctx, "foo"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect the lease not to be found, got: %v", err)
+	}
+}
@@ -1,6 +1,14 @@
 package lease
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
 
 // Coordinator is the implemtation of the Leaser interface.
 // It's abstracts away LeaseTaker and LeaseRenewer from the application
@@ -12,46 +20,116 @@ type Coordinator struct {
 	Renewer Renewer
 	Taker   Taker
 	// coordinator state
+	mu sync.Mutex
+	// cfgMu guards the tunable config fields; the taker and renewer cycles, and the
+	// methods that read them (directly or through the Manager) hold it for reading,
+	// and Reconfigure for writing.
+	cfgMu      sync.RWMutex
 	stopTaker  chan struct{}
 	stopRenwer chan struct{}
+	// started is the time the coordinator was started. see: Config.FastStartWindow.
+	started time.Time
+	// lock is the local lock file of the worker. see: Config.LockDir.
+	lock *os.File
+	// cache holds the leases returned by Get. see: Config.GetCacheTTL.
+	cache *leaseCache
+	// journal holds the recent renewal and take outcomes. see: Stats.
+	journal *journal
+	// throttle detects the write throttling of the table. see: Throttled.
+	throttle *throttle
+	// cancel cancels the in-flight calls of the taker and renewer loops. see: Stop.
+	cancel context.CancelFunc
+	// takeNow runs the next take cycle without waiting for its interval. see: AdminHandler.
+	takeNow chan struct{}
 }
 
 // Taker or Renewer loop function
-type loopFunc func() error
+type loopFunc func(context.Context) error
 
-// New create new Coordinator with the given config.
-func New(config *Config) Leaser {
-	config.defaults()
-	manager := &LeaseManager{config, newSerializer()}
+// NewFromConfig create new Coordinator with the given config. See: New.
+// If the config is invalid, the error is logged and returned by Start.
+func NewFromConfig(config *Config) Leaser {
+	config.initLogger()
+	if err := config.Validate(); err != nil {
+		config.Logger.WithError(err).Errorf("invalid coordinator config")
+	}
+	manager := newManager(config)
+	config.scopes = &scopes{}
+	config.maintenance = &maintenance{}
+	rotations := &rotations{now: config.now}
+	cache := &leaseCache{now: config.now}
+	journal := newJournal(config.JournalSize, config.now)
+	throttle := &throttle{Config: config}
 	return &Coordinator{
-		Config:  config,
-		Manager: manager,
+		Config:   config,
+		Manager:  manager,
+		cache:    cache,
+		journal:  journal,
+		throttle: throttle,
+		takeNow:  make(chan struct{}, 1),
 		Renewer: &leaseHolder{
-			Config:     config,
-			manager:    manager,
-			heldLeases: make(map[string]*Lease),
+			Config:         config,
+			manager:        manager,
+			heldLeases:     make(map[string]*Lease),
+			sharedLeases:   make(map[string]*Lease),
+			drainingLeases: make(map[string]*Lease),
+			rotations:      rotations,
+			cache:          cache,
+			journal:        journal,
+			throttle:       throttle,
 		},
 		Taker: &leaseTaker{
 			Config:    config,
 			manager:   manager,
 			allLeases: make(map[string]*Lease),
+			rotations: rotations,
+			cache:     cache,
+			journal:   journal,
+			throttle:  throttle,
 		},
 	}
 }
 
 // Start create the leases table if it's not exist and
 // then start background leaseHolder and leaseTaker handling.
-func (c *Coordinator) Start() error {
-	if err := c.Manager.CreateLeaseTable(); err != nil {
+// The given context is used for the creation of the table, and the background
+// handling runs until Stop is called.
+//
+// Fails with a *ConfigError if the config is invalid (see: Config.Validate), and with
+// a *DriftError if the lease table drifted from Config.Source in strict mode.
+func (c *Coordinator) Start(ctx context.Context) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if c.LockDir != "" {
+		lock, err := lockWorker(c.LockDir, c.WorkerId)
+		if err != nil {
+			return err
+		}
+		c.lock = lock
+	}
+	if err := c.Manager.CreateLeaseTable(ctx); err != nil {
+		c.unlock()
+		return err
+	}
+	// compare the lease table with the work units, before the leases are taken.
+	if err := c.preflight(ctx); err != nil {
+		c.unlock()
 		return err
 	}
 
-	takerIntervalMills := (c.ExpireAfter + c.epsilonMills) * 2
-	renewerIntervalMills := c.ExpireAfter/3 - c.epsilonMills
+	c.started = c.now()
+	takerIntervalMills := c.takerInterval()
+	renewerIntervalMills := c.renewerInterval()
 
-	c.stopTaker = c.loop(c.Taker.Take, takerIntervalMills, "take leases")
-	c.stopRenwer = c.loop(c.Renewer.Renew, renewerIntervalMills, "renew leases")
+	lctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.stopTaker = c.loop(lctx, c.Taker.Take, c.takerInterval, c.takeNow, "take leases")
+	c.stopRenwer = c.loop(lctx, c.renew, c.renewerInterval, nil, "renew leases")
 
+	if c.FastStartWindow > 0 {
+		c.Logger.Infof("Worker %s will take leases every %s for the first %s", c.WorkerId, c.FastStartInterval, c.FastStartWindow)
+	}
 	c.Logger.Infof("Start coordinator with failover time %s, and epsilon %s. "+
 		"LeaseCoordinator will renew leases every %s, take leases every %s "+
 		"and steal %d lease(s) at a time.",
@@ -64,15 +142,98 @@ func (c *Coordinator) Start() error {
 	return nil
 }
 
+// DebugHandler returns an http.Handler that writes the goroutine profile of the process
+// in text format, with the pprof labels of each goroutine. The taker and renewer loops
+// are labeled with "lease.worker", "lease.table" and "lease.loop". for example:
+//
+//	http.Handle("/debug/leases", leaser.DebugHandler())
+//
+// CPU profiles carry the same labels, e.g: "go tool pprof -tagfocus lease.loop=renew".
+// With the "stats" query parameter (e.g: "/debug/leases?stats"), it writes the Stats of
+// the worker in JSON format instead.
+func (c *Coordinator) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["stats"]; ok {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(c.Stats()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := pprof.Lookup("goroutine").WriteTo(w, 1); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// takerInterval returns the interval between the taker cycles. the cycles are shorter
+// within the fast-start window.
+func (c *Coordinator) takerInterval() time.Duration {
+	if c.FastStartWindow > 0 && c.since(c.started) < c.FastStartWindow {
+		return c.FastStartInterval
+	}
+	return (c.ExpireAfter + c.epsilonMills) * 2
+}
+
+// Reconfigure updates the tunable fields of the coordinator config at runtime, so the
+// fleet can be retuned without restarts that cause lease churn: ExpireAfter (and the
+// loop intervals derived from it), MaxLeasesToStealAtOneTime, StealRate, StealBurst, DrainInterval, MaxHoldDuration,
+// TombstoneRetention, VersionTakeoverRate, StormPercent, StormWindow, OnTakeoverStorm, OnTakeover,
+// BalanceByLoad, Quotas, Profiles and DenyKeys, and the fields that default to a value derived
+// from ExpireAfter: DelegationTTL, ThrottleWindow, FastStartInterval and MaxStaleness. The rest
+// of the fields are ignored.
+//
+// Zero values get the same defaults as in New, and the merged config is validated like in
+// New (e.g: LeaseTTL must still be greater than the new ExpireAfter). The changes are applied between the taker
+// and the renewer cycles, and the new intervals take effect after the current wait.
+// Note that ExpireAfter should be the same for all workers in the fleet.
+//
+// Fails if the given config is invalid, and nothing is changed.
+func (c *Coordinator) Reconfigure(config Config) error {
+	c.cfgMu.Lock()
+	defer c.cfgMu.Unlock()
+	if err := c.reconfigure(config); err != nil {
+		return err
+	}
+	c.Logger.Infof("Worker %s reconfigured with failover time %s, and steal %d lease(s) at a time.",
+		c.WorkerId,
+		c.ExpireAfter,
+		c.MaxLeasesToStealAtOneTime)
+	return nil
+}
+
+// WatchConfig reconfigures the coordinator with each config received from the given
+// channel (e.g: a watched config file), until the channel is closed or ctx is done.
+// Invalid configs are logged and ignored.
+func (c *Coordinator) WatchConfig(ctx context.Context, ch <-chan Config) error {
+	for {
+		select {
+		case config, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := c.Reconfigure(config); err != nil {
+				c.Logger.WithError(err).Errorf("failed to reconfigure coordinator")
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // Stop the coordinator gracefully. wait for background tasks to complete.
+// The in-flight DynamoDB calls of the background tasks are canceled.
 func (c *Coordinator) Stop() {
-	c.Logger.Info("stopping coordinator")
+	c.Logger.Infof("stopping coordinator")
 
-	// stop taker loop
-	c.stopTaker <- struct{}{}
+	// cancel the in-flight calls and retries.
+	if c.cancel != nil {
+		c.cancel()
+	}
 
-	// wait for close
-	<-c.stopTaker
+	// stop taker loop
+	c.stopTakerLoop()
 
 	// stop renewer loop
 	c.stopRenwer <- struct{}{}
@@ -80,7 +241,63 @@ func (c *Coordinator) Stop() {
 	// wait for close
 	<-c.stopRenwer
 
-	c.Logger.Info("stopped coordinator")
+	c.unlock()
+	c.Logger.Infof("stopped coordinator")
+}
+
+// unlock releases the local lock file of the worker, if it's held.
+func (c *Coordinator) unlock() {
+	if err := unlockWorker(c.lock); err != nil {
+		c.Logger.WithError(err).Warnf("Worker %s failed to release the lock file", c.WorkerId)
+	}
+	c.lock = nil
+}
+
+// stopTakerLoop stops the taker loop, and wait for close. does nothing if the
+// taker loop already stopped (e.g: by Drain).
+func (c *Coordinator) stopTakerLoop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopTaker == nil {
+		return
+	}
+	c.stopTaker <- struct{}{}
+	<-c.stopTaker
+	c.stopTaker = nil
+}
+
+// Drain stops taking new leases, and gradually releases the held leases, one lease
+// every DrainInterval, so other workers can take them over. Released leases are set
+// to have no owner, and handed over to the worker that reserved them, if any.
+// Drain resolves when the worker holds no leases, or when ctx is done.
+// Leases that held in shared mode are not released.
+//
+// Use it in deployment preStop hooks, before calling Stop.
+func (c *Coordinator) Drain(ctx context.Context) error {
+	c.Logger.Infof("draining coordinator")
+	c.stopTakerLoop()
+
+	for {
+		leases := c.Renewer.GetHeldLeases()
+		if len(leases) == 0 {
+			c.Logger.Infof("drained coordinator")
+			return nil
+		}
+		c.cache.invalidate(leases[0].Key)
+		if err := c.Renewer.Release(ctx, leases[0]); err != nil {
+			c.Logger.WithError(err).Debugf("Worker %s could not release lease with key %s", c.WorkerId, leases[0].Key)
+		} else {
+			c.Logger.Debugf("Worker %s released lease with key %s. %d leases left", c.WorkerId, leases[0].Key, len(leases)-1)
+		}
+		c.cfgMu.RLock()
+		interval := c.DrainInterval
+		c.cfgMu.RUnlock()
+		select {
+		case <-c.after(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // GetHeldLeases returns the currently held leases.
@@ -93,20 +310,114 @@ func (c *Coordinator) GetHeldLeases() []Lease {
 // Delete the given lease from DB. does nothing when passed a lease that does
 // not exist in the DB.
 // The deletion is conditional on the fact that the lease is being held by this worker.
-func (c *Coordinator) Delete(l Lease) error {
-	return c.Manager.DeleteLease(&l)
+//
+// In soft-delete mode (see: Config.SoftDelete), the lease is tombstoned and hidden
+// from the takers, and it's purged after the retention window.
+func (c *Coordinator) Delete(ctx context.Context, l Lease) error {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	defer c.cache.invalidate(l.Key)
+	return c.Manager.DeleteLease(ctx, &l)
+}
+
+// Complete marks the work of the given held lease as finished, and stops holding it.
+// Completed leases are kept in the table, and never taken again, or deleted if
+// Config.DeleteCompleted is set.
+//
+// Use it for finite units of work (e.g: backfills or file batches), instead of Delete.
+// Fails with ErrLeaseNotHeld if we do not hold the lease, and with ErrTokenNotMatch if
+// we lost and re-acquired it (see: Update).
+func (c *Coordinator) Complete(ctx context.Context, lease Lease) error {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	defer c.cache.invalidate(lease.Key)
+	for _, hlease := range c.Renewer.GetHeldLeases() {
+		if lease.Key == hlease.Key {
+			if hlease.concurrencyToken != lease.concurrencyToken {
+				return ErrTokenNotMatch
+			}
+			return c.Renewer.Complete(ctx, hlease)
+		}
+	}
+	return ErrLeaseNotHeld
 }
 
 // Create a new lease.
 // Conditional on a lease not already existing with different owner and counter.
-func (c *Coordinator) Create(lease Lease) (Lease, error) {
-	clease, err := c.Manager.CreateLease(&lease)
+//
+// The defaults of Config.Template are applied to the fields the lease does not set.
+//
+// Fails with ErrQuotaExceeded if the lease namespace already contains the maximum
+// number of leases allowed by its quota.
+func (c *Coordinator) Create(ctx context.Context, lease Lease) (Lease, error) {
+	defer c.cache.invalidate(lease.Key)
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	if q, ok := c.quota(lease.Key); ok && q.MaxLeases > 0 {
+		list, err := c.Manager.ListLeases(ctx)
+		if err != nil {
+			return lease, err
+		}
+		if c.exceedsQuota(lease.Key, liveLeases(list)) {
+			return lease, ErrQuotaExceeded
+		}
+	}
+	c.Template.apply(&lease, c.now())
+	clease, err := c.Manager.CreateLease(ctx, &lease)
 	if err != nil {
 		return lease, err
 	}
 	return *clease, nil
 }
 
+// EnsureLeases idempotently creates the leases of the given keys that do not exist,
+// and returns the number of leases that were created. Existing leases and their owners
+// are left as is, and new leases are created without an owner, so the takers pick them up,
+// with the defaults of Config.Template.
+// Use it to seed the leases at startup.
+//
+// Leases that exceed their namespace quota are not created, and ErrQuotaExceeded is
+// returned once all the other leases are created.
+func (c *Coordinator) EnsureLeases(ctx context.Context, keys []string) (int, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	// skip the existing leases, to create only the missing ones.
+	list, err := c.Manager.ListLeases(ctx)
+	if err != nil {
+		return 0, err
+	}
+	list = liveLeases(list)
+	existing := make(map[string]bool, len(list))
+	for _, lease := range list {
+		existing[lease.Key] = true
+	}
+	var (
+		created  int
+		quotaErr error
+	)
+	for _, key := range keys {
+		if existing[key] {
+			continue
+		}
+		if c.exceedsQuota(key, list) {
+			quotaErr = ErrQuotaExceeded
+			continue
+		}
+		lease := &Lease{Key: key}
+		c.Template.apply(lease, c.now())
+		ok, err := c.Manager.EnsureLease(ctx, lease)
+		if err != nil {
+			return created, err
+		}
+		existing[key] = true
+		list = append(list, lease)
+		if ok {
+			created++
+		}
+	}
+	return created, quotaErr
+}
+
 // Update used to update only the extra fields on the Lease object and
 // it cannot be used to update internal fields such as leaseCounter, leaseOwner.
 //
@@ -118,7 +429,10 @@ func (c *Coordinator) Create(lease Lease) (Lease, error) {
 // other fields.
 // for example: {"status": "done", "last_update": "unix seconds"}
 // To add extra fields on a Lease, use Lease.Set(key, val)
-func (c *Coordinator) Update(lease Lease) (Lease, error) {
+func (c *Coordinator) Update(ctx context.Context, lease Lease) (Lease, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	defer c.cache.invalidate(lease.Key)
 	var heldLease Lease
 	for _, hlease := range c.Renewer.GetHeldLeases() {
 		if lease.Key == hlease.Key {
@@ -137,13 +451,33 @@ func (c *Coordinator) Update(lease Lease) (Lease, error) {
 		return lease, ErrTokenNotMatch
 	}
 
-	ulease, err := c.Manager.UpdateLease(&lease)
+	// the checkpoint of a KCL lease is conditional on the counter of the held lease.
+	if lease.checkpointed {
+		lease.Counter = heldLease.Counter
+	}
+	ulease, err := c.Manager.UpdateLease(ctx, &lease)
 	if err != nil {
 		return lease, err
 	}
 	return *ulease, nil
 }
 
+// Checkpoint records the progress of the work of the given held lease (e.g: the sequence
+// number of the last processed record), and returns the updated lease. The checkpoint is
+// loaded into Lease.Checkpoint, so the next owner of the lease resumes the work from it.
+// An empty checkpoint removes the recorded one.
+//
+// Like Update, it fails with ErrLeaseNotHeld if the lease is not held by this worker, or
+// with ErrTokenNotMatch if the lease was lost and re-acquired since it was returned. The
+// write is conditional on the owner of the lease in the table, and fails with a
+// *ConditionError (e.g: ErrLeaseStolen) if the lease was taken in the meantime. With
+// Config.KCLSchema, it's also conditional on the lease counter, like the KCL checkpoints,
+// and fails with ErrLeaseCounterChanged if the lease was renewed concurrently.
+func (c *Coordinator) Checkpoint(ctx context.Context, lease Lease, checkpoint string) (Lease, error) {
+	lease.SetCheckpoint(checkpoint, 0)
+	return c.Update(ctx, lease)
+}
+
 // ForceUpdate used to update the lease object without checking if the concurrency
 // token is valid or if we already lost this lease.
 //
@@ -152,20 +486,128 @@ func (c *Coordinator) Update(lease Lease) (Lease, error) {
 //
 // for example: {"status": "done", "last_update": "unix seconds"}
 // To add extra fields on a Lease, use Lease.Set(key, val)
-func (c *Coordinator) ForceUpdate(lease Lease) (Lease, error) {
-	ulease, err := c.Manager.UpdateLease(&lease)
+func (c *Coordinator) ForceUpdate(ctx context.Context, lease Lease) (Lease, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	defer c.cache.invalidate(lease.Key)
+	ulease, err := c.Manager.UpdateLease(ctx, &lease)
 	if err != nil {
 		return lease, err
 	}
 	return *ulease, nil
 }
 
+// Reserve the given lease for this worker until the given time, e.g: when the
+// worker intends to take it after it finishes its warm-up.
+//
+// The reservation is visible to other workers; they deprioritize reserved leases
+// when choosing which leases to take or steal, while this worker prefers them.
+// The reservation is released once this worker takes the lease, or when it lapses.
+//
+// Fails if the lease is already reserved by another worker.
+func (c *Coordinator) Reserve(ctx context.Context, lease Lease, until time.Time) (Lease, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	defer c.cache.invalidate(lease.Key)
+	if err := c.Manager.ReserveLease(ctx, &lease, until); err != nil {
+		return lease, err
+	}
+	return lease, nil
+}
+
+// AcquireShared acquires the given lease in shared mode. Multiple workers may hold a
+// lease in shared mode concurrently, but a lease that has active shared holders is not
+// taken (held exclusively) by the Taker, and vice versa.
+//
+// Shared leases are renewed in the background, and reported by GetSharedLeases after
+// the next run of the Renewer. Leases that are meant to be used in shared mode should be
+// created without an owner(i.e: "NULL").
+//
+// Semaphore leases(see: NewSemaphoreLease) limit the number of concurrent holders.
+//
+// Fails with ErrLeaseHeldExclusively if the lease is held exclusively by a worker, or with
+// ErrLeaseFull if the semaphore lease already reached its maximum number of holders.
+func (c *Coordinator) AcquireShared(ctx context.Context, lease Lease) (Lease, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	defer c.cache.invalidate(lease.Key)
+	clease, err := c.Manager.GetLease(ctx, lease.Key)
+	if err != nil {
+		return lease, err
+	}
+	if !clease.hasNoOwner() {
+		return lease, ErrLeaseHeldExclusively
+	}
+	if err := c.Manager.AcquireSharedLease(ctx, clease); err != nil {
+		return lease, err
+	}
+	return *clease, nil
+}
+
+// ReleaseShared releases the given lease that held in shared mode by this worker.
+func (c *Coordinator) ReleaseShared(ctx context.Context, lease Lease) error {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	defer c.cache.invalidate(lease.Key)
+	return c.Manager.ReleaseSharedLease(ctx, &lease)
+}
+
+// GetSharedLeases returns the leases that are currently held by this worker in shared mode.
+// Lease objects returned are copies and their counters will not tick.
+func (c *Coordinator) GetSharedLeases() []Lease {
+	return c.Renewer.GetSharedLeases()
+}
+
+// ReportLoad reports the load (e.g: records/sec or backlog) of the given held lease.
+// The load is written on the next renewal of the lease, and used by the load-aware
+// balancing (see: Config.BalanceByLoad).
+//
+// Fails with ErrLeaseNotHeld if the lease is not held by this worker.
+func (c *Coordinator) ReportLoad(lease Lease, load float64) error {
+	return c.Renewer.ReportLoad(lease.Key, load)
+}
+
+// ExpiresIn returns the time remaining before other workers consider the given held lease
+// as expired, and take it. It's measured from the last successful renewal of the lease, and
+// it's zero if the lease may be already expired. Use it to checkpoint and wind down the work
+// of the lease proactively, when the renewals fail and the deadline approaches.
+//
+// Fails with ErrLeaseNotHeld if the lease is not held by this worker.
+func (c *Coordinator) ExpiresIn(lease Lease) (time.Duration, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	return c.Renewer.ExpiresIn(lease.Key)
+}
+
+// Degraded reports whether the coordinator operates from the last known view of the table,
+// since listing the leases fails. The held leases are still renewed, up to Config.MaxStaleness,
+// but new leases are not taken. See: Config.OnDegraded.
+func (c *Coordinator) Degraded() bool {
+	return c.Renewer.Degraded()
+}
+
+// Migrate upgrades all the leases in the table to the latest schema version eagerly,
+// and returns the number of leases that were upgraded. See: Config.Migrator.
+// It's safe to run it while the leases are held; it can be used as a batch command
+// after a deployment of new migrations.
+func (c *Coordinator) Migrate(ctx context.Context) (int, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	return c.Manager.MigrateLeases(ctx)
+}
+
 // loop spawn a goroutine and returns a "done" channel that linked to this goroutine.
 // the interval used to create a ticker to run the given loopFunc each x time and
 // the reason string used for logging.
-func (c *Coordinator) loop(fn loopFunc, interval time.Duration, reason string) chan struct{} {
+// the interval is evaluated before each wait, so config changes take effect.
+// a receive on the kick channel runs loopFunc without waiting for the interval.
+// the given context is passed to loopFunc, and the errors are not logged once it's done.
+// the goroutine is labeled with the worker id, the table name and the reason, so CPU and
+// goroutine profiles attribute their time to lease maintenance. See: DebugHandler.
+func (c *Coordinator) loop(ctx context.Context, fn loopFunc, interval func() time.Duration, kick <-chan struct{}, reason string) chan struct{} {
 	done := make(chan struct{})
-	go func() {
+	labels := pprof.Labels("lease.worker", c.WorkerId, "lease.table", c.LeaseTable, "lease.loop", reason)
+	go pprof.Do(ctx, labels, func(ctx context.Context) {
 		ticker := c.ticker(interval)
 		defer close(done)
 
@@ -173,29 +615,35 @@ func (c *Coordinator) loop(fn loopFunc, interval time.Duration, reason string) c
 			select {
 			// taker or renew old leases
 			case <-ticker():
-				if err := fn(); err != nil {
-					c.Logger.WithError(err).Errorf("Worker %s failed to %s", c.WorkerId, reason)
-				}
+			case <-kick:
 			// someone called stop and we need to exit.
 			case <-done:
 				return
 			}
+			c.cfgMu.RLock()
+			err := fn(ctx)
+			c.cfgMu.RUnlock()
+			if err != nil && ctx.Err() == nil {
+				c.Logger.WithError(err).Errorf("Worker %s failed to %s", c.WorkerId, reason)
+			}
 		}
-	}()
+	})
 
 	return done
 }
 
 // ticker returns time.Time channel that called with zero value in the first call.
 // used to start 'taking'(or 'renewing') leases immediately.
-func (c *Coordinator) ticker(d time.Duration) func() <-chan time.Time {
+func (c *Coordinator) ticker(d func() time.Duration) func() <-chan time.Time {
 	firstTime := true
 	return func() <-chan time.Time {
-		sleepTime := d
+		c.cfgMu.RLock()
+		sleepTime := d()
+		c.cfgMu.RUnlock()
 		if firstTime {
 			firstTime = false
 			sleepTime = 0
 		}
-		return time.After(sleepTime)
+		return c.after(sleepTime)
 	}
 }
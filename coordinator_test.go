@@ -0,0 +1,102 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoordinatorReconfigure(t *testing.T) {
+	logger := testLogger()
+	config := &Config{Logger: logger, LeaseTable: "test", WorkerId: "1"}
+	config.defaults()
+	c := &Coordinator{Config: config}
+
+	err := c.Reconfigure(Config{ExpireAfter: time.Minute, MaxLeasesToStealAtOneTime: 3, LeaseTable: "other", DenyKeys: []string{"orders/"}})
+	assert(t, err == nil, "expect Reconfigure not to fail")
+	assert(t, c.ExpireAfter == time.Minute && c.MaxLeasesToStealAtOneTime == 3, "expect tunables to be updated")
+	assert(t, c.denied("orders/1") && !c.denied("users/1"), "expect the denylist to be updated")
+	assert(t, c.DrainInterval == time.Second, "expect zero values to get their defaults")
+	assert(t, c.LeaseTable == "test", "expect non-tunable fields to be ignored")
+	assert(t, c.takerInterval() > 2*time.Minute, "expect intervals to be derived from the new config")
+	assert(t, c.ThrottleWindow == time.Minute && c.MaxStaleness == time.Minute && c.DelegationTTL == time.Minute, "expect the derived defaults to follow the new ExpireAfter")
+
+	err = c.Reconfigure(Config{ExpireAfter: time.Second, MaxLeasesToStealAtOneTime: 5})
+	assert(t, err != nil, "expect Reconfigure to fail with invalid config")
+	assert(t, c.ExpireAfter == time.Minute && c.MaxLeasesToStealAtOneTime == 3, "expect nothing to be changed")
+
+	// the cross-field checks of the merged config.
+	c.LeaseTTL = 2 * time.Minute
+	err = c.Reconfigure(Config{ExpireAfter: 3 * time.Minute})
+	assert(t, errors.Is(err, ErrInvalidInterval), "expect to reject an ExpireAfter above LeaseTTL")
+	err = c.Reconfigure(Config{ExpireAfter: time.Minute, MaxStaleness: 10 * time.Second})
+	assert(t, errors.Is(err, ErrInvalidInterval), "expect to reject a MaxStaleness below the renew interval")
+	assert(t, c.ExpireAfter == time.Minute && c.MaxStaleness == time.Minute, "expect nothing to be changed")
+}
+
+func TestCoordinatorLabels(t *testing.T) {
+	logger := testLogger()
+	config := &Config{Logger: logger, LeaseTable: "test", WorkerId: "1"}
+	config.defaults()
+	c := &Coordinator{Config: config}
+
+	called := make(chan struct{}, 1)
+	stop := c.loop(context.Background(), func(context.Context) error {
+		select {
+		case called <- struct{}{}:
+		default:
+		}
+		return nil
+	}, func() time.Duration { return time.Millisecond }, nil, "renew leases")
+	<-called
+
+	w := httptest.NewRecorder()
+	c.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	stop <- struct{}{}
+	<-stop
+	body := w.Body.String()
+	assert(t, strings.Contains(body, `"lease.loop":"renew leases"`), "expect the loop goroutine to be labeled")
+	assert(t, strings.Contains(body, `"lease.worker":"1"`), "expect the worker id label")
+}
+
+func TestCoordinatorFastStart(t *testing.T) {
+	logger := testLogger()
+	config := &Config{Logger: logger, LeaseTable: "test", WorkerId: "1", FastStartWindow: time.Minute}
+	config.defaults()
+	c := &Coordinator{Config: config, started: time.Now()}
+	assert(t, c.FastStartInterval == c.renewerInterval(), "expect the fast-start interval to default to the renewer interval")
+	assert(t, c.takerInterval() == c.FastStartInterval, "expect short taker cycles within the fast-start window")
+
+	c.started = time.Now().Add(-2 * time.Minute)
+	assert(t, c.takerInterval() == (c.ExpireAfter+c.epsilonMills)*2, "expect normal taker cycles after the fast-start window")
+}
+
+func TestCoordinatorStopCancels(t *testing.T) {
+	logger := testLogger()
+	config := &Config{Logger: logger, LeaseTable: "test", WorkerId: "1"}
+	config.defaults()
+	c := &Coordinator{Config: config}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	c.stopTaker = c.loop(ctx, block, func() time.Duration { return time.Hour }, nil, "take leases")
+	c.stopRenwer = c.loop(ctx, block, func() time.Duration { return time.Hour }, nil, "renew leases")
+
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("expect Stop to cancel the in-flight calls")
+	}
+}
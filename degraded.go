@@ -0,0 +1,83 @@
+package lease
+
+import (
+	"context"
+	"strings"
+)
+
+// renewStale renews the held leases from the last known view, after listing the leases
+// failed with the given error. The renewals are conditional on the lease counter and owner,
+// so leases that were taken by other workers in the meantime are lost, and not renewed.
+// It returns the error if the last known view is older than Config.MaxStaleness.
+func (l *leaseHolder) renewStale(ctx context.Context, err error) error {
+	l.setDegraded(true)
+	if l.scanned.IsZero() || l.since(l.scanned) > l.MaxStaleness {
+		return err
+	}
+	l.Logger.WithError(err).Warnf("Worker %s failed to list leases. renew held leases from the view of %s ago",
+		l.WorkerId,
+		l.since(l.scanned))
+
+	var lostLeases []string
+	for _, lease := range l.GetHeldLeases() {
+		lease := lease
+		renewed := l.now()
+		rerr := l.manager.RenewLease(ctx, &lease)
+		l.journal.record(lease.Key, "renew", renewed, rerr, l.isLate(lease.Key, renewed))
+		l.throttle.record(rerr)
+		if rerr == nil {
+			l.Lock()
+			if held, ok := l.heldLeases[lease.Key]; ok {
+				held.Counter = lease.Counter
+			}
+			l.Unlock()
+			l.markRenewed(lease.Key, renewed)
+			continue
+		}
+		if !isConditionalFailed(rerr) {
+			l.Logger.Debugf("Worker %s could not renew lease with key %s", l.WorkerId, lease.Key)
+			continue
+		}
+		l.Lock()
+		delete(l.heldLeases, lease.Key)
+		delete(l.heldSince, lease.Key)
+		delete(l.renewedAt, lease.Key)
+		l.Unlock()
+		lostLeases = append(lostLeases, lease.Key)
+		l.ownerChange(OwnerChange{Key: lease.Key, Type: OwnerLost, From: l.WorkerId}, &lease)
+	}
+	if n := len(lostLeases); n > 0 {
+		l.Logger.Debugf("Worker %s lost %d leases while degraded: %s",
+			l.WorkerId,
+			n,
+			strings.Join(lostLeases, ", "))
+	}
+	return nil
+}
+
+// setDegraded sets the degraded mode of the renewer, and calls Config.OnDegraded when
+// it changes.
+func (l *leaseHolder) setDegraded(degraded bool) {
+	l.Lock()
+	changed := l.degraded != degraded
+	l.degraded = degraded
+	l.Unlock()
+	if !changed {
+		return
+	}
+	if degraded {
+		l.Logger.Warnf("Worker %s entered degraded mode", l.WorkerId)
+	} else {
+		l.Logger.Infof("Worker %s recovered from degraded mode", l.WorkerId)
+	}
+	if l.OnDegraded != nil {
+		l.OnDegraded(degraded)
+	}
+}
+
+// Degraded reports whether the renewer operates from the last known view of the table.
+func (l *leaseHolder) Degraded() bool {
+	l.RLock()
+	defer l.RUnlock()
+	return l.degraded
+}
@@ -0,0 +1,197 @@
+package lease
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// delegation is the message that a worker sends to its delegate, with the leases it holds.
+// the lease counter is the fencing token of the delegated renewals; a renewal succeeds
+// only if the lease counter and owner did not change since they were sent, or since the
+// last renewal of the delegate.
+type delegation struct {
+	WorkerId string           `json:"workerId"`
+	Leases   []delegatedLease `json:"leases"`
+}
+
+type delegatedLease struct {
+	Key     string `json:"key"`
+	Counter int64  `json:"counter"`
+}
+
+// Delegate renews leases on behalf of a worker in another process of the same host (e.g:
+// a sidecar or a supervisor), so short pauses of the worker process (GC, snapshotting)
+// don't cause lease loss. The worker sends its held leases to the delegate handler after
+// each renewal (see: Config.DelegateURL), and the delegate renews them only while the
+// worker stopped sending them, up to Config.DelegationTTL. The delegate starts renewing
+// once the worker missed a renewal, so the delegated renewals are made within two renewer
+// intervals (i.e: two thirds of ExpireAfter) since the last renewal of the worker.
+//
+// Anyone that can reach the handler can replace the delegated leases, and keep them held
+// after the worker died. Serve it on a loopback address or a unix socket only, and set
+// Config.DelegateToken on both sides, so the requests without it are rejected.
+//
+// The delegate must be configured with the WorkerId and the LeaseTable of the worker.
+// For example:
+//
+//	d := lease.NewDelegate(&lease.Config{WorkerId: "worker-1", LeaseTable: "leases"})
+//	go d.Run(ctx)
+//	http.ListenAndServe("localhost:7070", d)
+type Delegate struct {
+	*Config
+	Manager Manager
+
+	mu     sync.Mutex
+	leases map[string]*Lease
+	// sent is the last time the worker sent its leases.
+	sent time.Time
+}
+
+// NewDelegate creates a new Delegate with the given config.
+func NewDelegate(config *Config) *Delegate {
+	config.defaults()
+	return &Delegate{
+		Config:  config,
+		Manager: newManager(config),
+		leases:  make(map[string]*Lease),
+	}
+}
+
+// ServeHTTP replaces the delegated leases with the leases that sent by the worker.
+func (d *Delegate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if d.DelegateToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+d.DelegateToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var msg delegation
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg.WorkerId != d.WorkerId {
+		http.Error(w, fmt.Sprintf("leases of worker %s are not delegated to this process", msg.WorkerId), http.StatusForbidden)
+		return
+	}
+	leases := make(map[string]*Lease, len(msg.Leases))
+	for _, l := range msg.Leases {
+		leases[l.Key] = &Lease{Key: l.Key, Owner: d.WorkerId, Counter: l.Counter}
+	}
+	d.mu.Lock()
+	d.leases, d.sent = leases, d.now()
+	d.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Run checks the delegated leases every half renewer interval, and renews them if the
+// worker missed its last renewal, until ctx is done.
+func (d *Delegate) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-d.after(d.renewerInterval() / 2):
+			d.renew(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// renew renews the delegated leases, if the worker missed its last renewal. The lock is
+// not held during the renewals, so the worker can update its leases in the meantime.
+func (d *Delegate) renew(ctx context.Context) {
+	d.mu.Lock()
+	idle := d.since(d.sent)
+	// the worker renews its leases by itself.
+	if idle < d.renewerInterval()+d.renewerInterval()/2 || len(d.leases) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	if idle > d.DelegationTTL {
+		d.Logger.Warnf("Delegate of worker %s stops renewing %d leases; no update for %s", d.WorkerId, len(d.leases), idle)
+		d.leases = make(map[string]*Lease)
+		d.mu.Unlock()
+		return
+	}
+	leases := make(map[string]*Lease, len(d.leases))
+	for key, lease := range d.leases {
+		leases[key] = lease
+	}
+	d.mu.Unlock()
+	for key, lease := range leases {
+		err := d.Manager.RenewLease(ctx, lease)
+		if err == nil {
+			d.Logger.Debugf("Delegate of worker %s renewed lease with key %s", d.WorkerId, key)
+			continue
+		}
+		if !isConditionalFailed(err) {
+			d.Logger.WithError(err).Debugf("Delegate of worker %s could not renew lease with key %s", d.WorkerId, key)
+			continue
+		}
+		// the lease was renewed by the worker in the meantime, or it was lost.
+		current, gerr := d.Manager.GetLease(ctx, key)
+		d.mu.Lock()
+		// the worker sent its leases in the meantime.
+		if d.leases[key] != lease {
+			d.mu.Unlock()
+			continue
+		}
+		if gerr == nil && current.Owner == d.WorkerId {
+			lease.Counter = current.Counter
+		} else {
+			d.Logger.Debugf("Delegate of worker %s lost lease with key %s", d.WorkerId, key)
+			delete(d.leases, key)
+		}
+		d.mu.Unlock()
+	}
+}
+
+// renew runs the renewer cycle, and sends the held leases to the delegate, if any.
+func (c *Coordinator) renew(ctx context.Context) error {
+	err := c.Renewer.Renew(ctx)
+	if c.DelegateURL != "" {
+		if derr := c.delegate(); derr != nil {
+			c.Logger.WithError(derr).Warnf("Worker %s failed to delegate leases", c.WorkerId)
+		}
+	}
+	return err
+}
+
+// delegate sends the held leases to the delegate handler at Config.DelegateURL.
+func (c *Coordinator) delegate() error {
+	msg := delegation{WorkerId: c.WorkerId}
+	for _, lease := range c.Renewer.GetHeldLeases() {
+		msg.Leases = append(msg.Leases, delegatedLease{lease.Key, lease.Counter})
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.renewerInterval())
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPut, c.DelegateURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.DelegateToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.DelegateToken)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("leaser: delegate responded with %s", resp.Status)
+	}
+	return nil
+}
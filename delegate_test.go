@@ -0,0 +1,62 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestDelegate(t *testing.T) {
+	logger := testLogger()
+	config := &Config{Logger: logger, LeaseTable: "test", WorkerId: "1"}
+	config.defaults()
+	manager := newManagerMock(map[method]args{
+		methodRenew: {nil, awserr.New(ConditionalFailed, "", errors.New("")), nil},
+		methodGet:   {&Lease{Key: "foo", Owner: "1", Counter: 5}, nil},
+	})
+	d := &Delegate{Config: config, Manager: manager, leases: make(map[string]*Lease)}
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	// the worker sends its held leases after renewal.
+	wconfig := *config
+	wconfig.DelegateURL = srv.URL
+	c := &Coordinator{Config: &wconfig, Renewer: &leaseHolder{
+		Config:     &wconfig,
+		heldLeases: map[string]*Lease{"foo": {Key: "foo", Owner: "1", Counter: 3}},
+	}}
+	err := c.delegate()
+	assert(t, err == nil, "expect delegate not to fail")
+	assert(t, len(d.leases) == 1 && d.leases["foo"].Counter == 3, "expect the held leases to be delegated")
+
+	d.renew(context.Background())
+	assert(t, manager.calls[methodRenew] == 0, "expect not to renew while the worker renews")
+
+	d.sent = time.Now().Add(-3 * config.renewerInterval())
+	d.renew(context.Background())
+	assert(t, manager.calls[methodRenew] == 1, "expect to renew while the worker is paused")
+
+	// the lease was renewed by the worker in the meantime.
+	d.renew(context.Background())
+	assert(t, len(d.leases) == 1 && d.leases["foo"].Counter == 5, "expect to adopt the lease counter")
+
+	d.sent = time.Now().Add(-2 * config.DelegationTTL)
+	d.renew(context.Background())
+	assert(t, manager.calls[methodRenew] == 2 && len(d.leases) == 0, "expect to stop renewing after the TTL")
+
+	c.WorkerId = "2"
+	err = c.delegate()
+	assert(t, err != nil, "expect the delegate to reject leases of other workers")
+
+	c.WorkerId = "1"
+	config.DelegateToken = "secret"
+	err = c.delegate()
+	assert(t, err != nil, "expect the delegate to reject requests without the token")
+	wconfig.DelegateToken = "secret"
+	err = c.delegate()
+	assert(t, err == nil, "expect the delegate to accept requests with the token")
+}
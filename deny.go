@@ -0,0 +1,44 @@
+package lease
+
+import (
+	"context"
+	"strings"
+)
+
+// denied test if the lease with the given key is denied to this worker. see: Config.DenyKeys.
+func (c *Config) denied(key string) bool {
+	for _, prefix := range c.DenyKeys {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterDenied returns the leases that are not denied to this worker.
+func (c *Config) filterDenied(list []*Lease) []*Lease {
+	if len(c.DenyKeys) == 0 {
+		return list
+	}
+	var allowed []*Lease
+	for _, lease := range list {
+		if !c.denied(lease.Key) {
+			allowed = append(allowed, lease)
+		}
+	}
+	return allowed
+}
+
+// releaseDenied releases the given held lease if it's denied to this worker. It returns
+// true if the lease was released.
+func (l *leaseHolder) releaseDenied(ctx context.Context, lease *Lease) bool {
+	if !l.denied(lease.Key) {
+		return false
+	}
+	if err := l.Release(ctx, *lease); err != nil {
+		l.Logger.Debugf("Worker %s could not release denied lease with key %s", l.WorkerId, lease.Key)
+		return false
+	}
+	l.Logger.Debugf("Worker %s released denied lease with key %s", l.WorkerId, lease.Key)
+	return true
+}
@@ -0,0 +1,42 @@
+package lease
+
+// readyLeases returns the leases in the given list, without the leases that are blocked
+// by their dependencies or parents. a lease is blocked if it has no owner, and at least one
+// of its dependencies or parents exists in the table and it's not done. See: Lease.DependsOn
+// and Lease.ParentKeys.
+func (l *leaseTaker) readyLeases(list []*Lease) []*Lease {
+	leases := make(map[string]*Lease, len(list))
+	for _, lease := range list {
+		leases[lease.Key] = lease
+	}
+	var ready []*Lease
+	for _, lease := range list {
+		if dep, ok := lease.blockedBy(leases); ok && lease.hasNoOwner() {
+			l.Logger.Debugf("Worker %s skip lease %s. it depends on lease %s that is not completed",
+				l.WorkerId,
+				lease.Key,
+				dep)
+			continue
+		}
+		ready = append(ready, lease)
+	}
+	return ready
+}
+
+// blockedBy returns the first dependency or parent of the lease that is not done, and a
+// boolean that indicates if such lease exists in the given leases.
+func (l *Lease) blockedBy(leases map[string]*Lease) (string, bool) {
+	for _, key := range l.DependsOn {
+		if dep, ok := leases[key]; ok && !dep.isCompleted() {
+			return key, true
+		}
+	}
+	for _, keys := range [][]string{l.ParentKeys, l.ParentShardIds} {
+		for _, key := range keys {
+			if parent, ok := leases[key]; ok && !parent.isCompleted() && parent.Checkpoint != kclShardEnd {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,61 @@
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTakerDependencies(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{
+			{Key: "a", Owner: "NULL"},
+			{Key: "b", Owner: "NULL", DependsOn: []string{"a"}},
+			{Key: "c", Owner: "NULL", DependsOn: []string{"d", "e"}},
+			{Key: "d", Owner: "NULL", CompletedAt: time.Now()},
+		}},
+		methodTake: {nil, nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTake] == 2, "expect to take only the leases that their dependencies are completed")
+	_, ok := taker.allLeases["b"]
+	assert(t, !ok, "expect to skip the lease that depends on a lease that is not completed")
+}
+
+func TestTakerParents(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{
+			{Key: "a", Owner: "2", Counter: 1},
+			{Key: "b", Owner: "NULL", ParentKeys: []string{"a"}},
+			{Key: "c", Owner: "NULL", ParentKeys: []string{"d", "e"}},
+			{Key: "d", Owner: "NULL", CompletedAt: time.Now()},
+			{Key: "f", Owner: "2", Counter: 1, Checkpoint: "SHARD_END"},
+			{Key: "g", Owner: "NULL", ParentShardIds: []string{"f"}},
+		}},
+		methodTake: {nil, nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTake] == 2, "expect to take only the leases that their parents are done")
+	_, ok := taker.allLeases["b"]
+	assert(t, !ok, "expect to skip the child of a lease that is not completed")
+}
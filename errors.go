@@ -0,0 +1,231 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// OpError is the error returned by the LeaseManager methods. It records the
+// context of the failed operation, and wraps the underlying error, so errors.Is
+// and errors.As still work. for example:
+//
+//	if errors.Is(err, lease.ErrLeaseNotFound) {
+//		// ...
+//	}
+type OpError struct {
+	// Op is the failed operation. e.g: "take", "renew" or "delete".
+	Op string
+	// Table is the name of the lease table.
+	Table string
+	// Key is the key of the lease. it's empty for table operations.
+	Key string
+	// Worker is the id of the worker that performed the operation.
+	Worker string
+	// Attempt is the number of retries that were made before the operation failed.
+	Attempt int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *OpError) Error() string {
+	s := "leaser: " + e.Op
+	if e.Key != "" {
+		s += fmt.Sprintf(" lease %q", e.Key)
+	}
+	s += fmt.Sprintf(" (table: %s, worker: %s", e.Table, e.Worker)
+	if e.Attempt > 0 {
+		s += fmt.Sprintf(", attempt: %d", e.Attempt)
+	}
+	return s + "): " + e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error { return e.Err }
+
+// ConfigError is the error returned by Config.Validate. It describes the invalid field,
+// and wraps the kind of the error (e.g: ErrInvalidInterval), so errors.Is still works.
+type ConfigError struct {
+	// Field is the name of the invalid field. e.g: "ExpireAfter" or "Quotas[\"a\"]".
+	Field string
+	// Reason describes what is wrong with the field.
+	Reason string
+	// Err is the kind of the error.
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return "leaser: invalid config field " + e.Field + ": " + e.Reason
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ConditionError is the error of a conditional write of a lease that failed, because the
+// lease in the table does not match the passed-in lease. It wraps the kind of the failure
+// (ErrLeaseStolen, ErrLeaseCounterChanged or ErrLeaseNotFound), and the original
+// ConditionalCheckFailedException, so both errors.Is and errors.As still work. for example:
+//
+//	if errors.Is(err, lease.ErrLeaseStolen) {
+//		// contention. the lease is lost.
+//	}
+type ConditionError struct {
+	// Err is the kind of the failure.
+	Err error
+	// Owner and Counter are the owner and the counter of the lease in the table. they
+	// are empty if the lease does not exist.
+	Owner   string
+	Counter int64
+	// Cause is the ConditionalCheckFailedException of DynamoDB.
+	Cause error
+}
+
+func (e *ConditionError) Error() string {
+	if e.Err == ErrLeaseNotFound {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s (owner: %s, counter: %d)", e.Err, e.Owner, e.Counter)
+}
+
+func (e *ConditionError) Unwrap() []error { return []error{e.Err, e.Cause} }
+
+// DriftError is the error returned by Start if the lease table drifted from Config.Source,
+// while Config.StrictPreflight is set. It wraps ErrDrift.
+type DriftError struct {
+	Drift Drift
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("%s (missing: %d, orphaned: %d)", ErrDrift, len(e.Drift.Missing), len(e.Drift.Orphaned))
+}
+
+func (e *DriftError) Unwrap() error { return ErrDrift }
+
+// conditionError returns the given error of a conditional write of the given lease, with
+// the failure described by a *ConditionError. It requires the item that failed the check
+// (see: ReturnValuesOnConditionCheckFailure). errors without it are returned as is.
+func conditionError(err error, cond Lease) error {
+	var cerr *dynamodb.ConditionalCheckFailedException
+	if !errors.As(err, &cerr) {
+		return err
+	}
+	ce := &ConditionError{Cause: cerr}
+	if len(cerr.Item) == 0 {
+		ce.Err = ErrLeaseNotFound
+	} else {
+		if v := cerr.Item[LeaseOwnerKey]; v != nil {
+			ce.Owner = aws.StringValue(v.S)
+		}
+		if v := cerr.Item[LeaseCounterKey]; v != nil {
+			ce.Counter, _ = strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+		}
+		switch {
+		case cond.Owner != "" && ce.Owner != cond.Owner:
+			ce.Err = ErrLeaseStolen
+		case cond.Counter > 0 && ce.Counter != cond.Counter:
+			ce.Err = ErrLeaseCounterChanged
+		default:
+			return err
+		}
+	}
+	// keep the context of the operation.
+	var oe *OpError
+	if errors.As(err, &oe) && oe.Err == error(cerr) {
+		oe.Err = ce
+		return err
+	}
+	return ce
+}
+
+// configError returns a *ConfigError for the given field.
+func configError(field string, kind error, reason string) error {
+	return &ConfigError{Field: field, Reason: reason, Err: kind}
+}
+
+// wrapError adds the context of the given operation to err. errors that already
+// carry their context (i.e: returned from the retry loops) only get the operation name.
+func (c *Config) wrapError(op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var oe *OpError
+	if errors.As(err, &oe) {
+		if oe.Op == "" {
+			oe.Op = op
+		}
+		return err
+	}
+	return &OpError{Op: op, Table: c.LeaseTable, Key: key, Worker: c.WorkerId, Err: err}
+}
+
+// retryError returns the error of the retry loop with its context, and resets the backoff.
+// the operation name is added by the caller.
+func (l *LeaseManager) retryError(r *retrier, key string, err error) error {
+	attempt := r.reset()
+	if err == nil {
+		return nil
+	}
+	return &OpError{Table: l.LeaseTable, Key: key, Worker: l.WorkerId, Attempt: attempt, Err: err}
+}
+
+// IsRetryable is the default error classifier of the retry loops (see: Config.IsRetryable).
+// It reports whether err is transient (e.g: throttling, internal server errors or network
+// errors), and the failed call should be retried. Permanent errors, such as validation
+// errors, access denied or conditional check failures, are not retried. Errors without an
+// AWS error code are retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return true
+	}
+	switch awsErr.Code() {
+	case ValidationFailed,
+		"AccessDeniedException",
+		"UnrecognizedClientException",
+		"MissingAuthenticationTokenException",
+		"InvalidSignatureException",
+		"IncompleteSignature",
+		"SerializationException",
+		dynamodb.ErrCodeResourceNotFoundException,
+		dynamodb.ErrCodeConditionalCheckFailedException,
+		dynamodb.ErrCodeTransactionCanceledException,
+		dynamodb.ErrCodeItemCollectionSizeLimitExceededException:
+		return false
+	}
+	return true
+}
+
+// retryable reports whether the given error of a retry loop should be retried, using
+// Config.IsRetryable.
+func (c *Config) retryable(err error) bool {
+	if c.IsRetryable != nil {
+		return c.IsRetryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// isNotFound reports whether err is a DynamoDB failure of a table that does not exist.
+func isNotFound(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeResourceNotFoundException
+}
+
+// isValidationFailed reports whether err is a DynamoDB failure of an invalid request, e.g:
+// a query of an index that does not exist.
+func isValidationFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == ValidationFailed
+}
+
+// isConditionalFailed reports whether err is a failure of a DynamoDB condition check, or
+// a conflict of a Backend write.
+func isConditionalFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == ConditionalFailed || errors.Is(err, ErrConflict)
+}
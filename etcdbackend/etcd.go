@@ -0,0 +1,218 @@
+// Package etcdbackend runs the coordinator on etcd, instead of DynamoDB, e.g: in
+// Kubernetes-native deployments.
+//
+// The leases are stored as JSON values under a key prefix, and the conditional writes of
+// lease.Backend are etcd transactions that compare the ModRevision of the stored lease, so
+// the takes, renewals and evictions keep their conditions on the leaseOwner and the
+// leaseCounter. For example:
+//
+//	client, err := clientv3.New(clientv3.Config{Endpoints: []string{"localhost:2379"}})
+//	leaser := lease.New("leases", lease.WithBackend(etcdbackend.New(client, "/leases")))
+//
+// Without a session, a lease of this package outlives its owner, and its expiry is detected
+// by the other workers, when its leaseCounter stops changing (see: lease.Config.ExpireAfter).
+// With a session (i.e: an etcd lease of the worker, see: NewSession), the ownership of the
+// leases that the worker takes is attached to its etcd lease, and the leases of a worker
+// whose etcd lease expired have no owner, so they are taken without waiting for their
+// expiry. All the workers of a lease table should use sessions, or none of them:
+//
+//	session, err := etcdbackend.NewSession(ctx, client, 10)
+//	backend := &etcdbackend.Backend{KV: client, Prefix: "/leases", Session: session}
+package etcdbackend
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// KV is the subset of the etcd client that is used by the backend. It's implemented by
+// *clientv3.Client.
+type KV interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Txn(ctx context.Context) clientv3.Txn
+}
+
+// Backend is a lease.Backend that stores the leases in etcd.
+type Backend struct {
+	KV KV
+	// Prefix is the prefix of the etcd keys of the lease table, e.g: "/leases".
+	Prefix string
+	// Session is the etcd lease of this worker, that the ownership of the leases it takes
+	// is attached to. Optional. See: NewSession.
+	Session clientv3.LeaseID
+}
+
+// New returns a lease.Backend that stores the leases under the given key prefix, using
+// the given etcd client.
+func New(kv KV, prefix string) lease.Backend {
+	return &Backend{KV: kv, Prefix: prefix}
+}
+
+// NewSession grants an etcd lease with the given TTL in seconds, and keeps it alive until
+// the given context is done, e.g: when the worker stops. The TTL should be shorter than
+// lease.Config.ExpireAfter.
+func NewSession(ctx context.Context, client clientv3.Lease, ttl int64) (clientv3.LeaseID, error) {
+	resp, err := client.Grant(ctx, ttl)
+	if err != nil {
+		return 0, err
+	}
+	ch, err := client.KeepAlive(ctx, resp.ID)
+	if err != nil {
+		return 0, err
+	}
+	go func() {
+		for range ch {
+		}
+	}()
+	return resp.ID, nil
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (*lease.Lease, error) {
+	resp, err := b.KV.Txn(ctx).
+		Then(clientv3.OpGet(b.key(key)), clientv3.OpGet(b.ownerKey(key))).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	kvs := resp.Responses[0].GetResponseRange().GetKvs()
+	if len(kvs) == 0 {
+		return nil, lease.ErrLeaseNotFound
+	}
+	return b.view(kvs[0], len(resp.Responses[1].GetResponseRange().GetKvs()) > 0)
+}
+
+func (b *Backend) List(ctx context.Context) ([]*lease.Lease, error) {
+	resp, err := b.KV.Txn(ctx).
+		Then(clientv3.OpGet(b.key(""), clientv3.WithPrefix()), clientv3.OpGet(b.ownerKey(""), clientv3.WithPrefix())).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool)
+	for _, kv := range resp.Responses[1].GetResponseRange().GetKvs() {
+		owned[string(kv.Key)[len(b.ownerKey("")):]] = true
+	}
+	kvs := resp.Responses[0].GetResponseRange().GetKvs()
+	list := make([]*lease.Lease, 0, len(kvs))
+	for _, kv := range kvs {
+		l, err := b.view(kv, owned[string(kv.Key)[len(b.key("")):]])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, nil
+}
+
+func (b *Backend) PutIfAbsent(ctx context.Context, l *lease.Lease) (bool, error) {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return false, err
+	}
+	k := b.key(l.Key)
+	ops := append([]clientv3.Op{clientv3.OpPut(k, raw)}, b.ownerOps(nil, l)...)
+	resp, err := b.KV.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+func (b *Backend) CompareAndUpdate(ctx context.Context, old, l *lease.Lease) error {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return err
+	}
+	k := b.key(old.Key)
+	rev, _ := strconv.ParseInt(old.Revision(), 10, 64)
+	cmps := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(k), "=", rev)}
+	// the owner keeps the lease only while its session is alive.
+	if b.Session != 0 && isOwned(old) && l.Owner == old.Owner {
+		cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(b.ownerKey(old.Key)), ">", 0))
+	}
+	ops := append([]clientv3.Op{clientv3.OpPut(k, raw)}, b.ownerOps(old, l)...)
+	resp, err := b.KV.Txn(ctx).
+		If(cmps...).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return lease.ErrConflict
+	}
+	return nil
+}
+
+func (b *Backend) Delete(ctx context.Context, old *lease.Lease) error {
+	k := b.key(old.Key)
+	rev, _ := strconv.ParseInt(old.Revision(), 10, 64)
+	resp, err := b.KV.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(k), "=", rev)).
+		Then(clientv3.OpDelete(k), clientv3.OpDelete(b.ownerKey(old.Key))).
+		// read the stored value, to tell a changed lease from a deleted one.
+		Else(clientv3.OpGet(k)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded && len(resp.Responses) > 0 && len(resp.Responses[0].GetResponseRange().GetKvs()) > 0 {
+		return lease.ErrConflict
+	}
+	return nil
+}
+
+// ownerOps returns the operations that attach the ownership of the given lease, that was
+// written over the given old one (or nil), to the session of this worker. the ownership is
+// attached when the lease is taken, and detached when it's released.
+func (b *Backend) ownerOps(old, l *lease.Lease) []clientv3.Op {
+	if b.Session == 0 {
+		return nil
+	}
+	switch {
+	case !isOwned(l):
+		return []clientv3.Op{clientv3.OpDelete(b.ownerKey(l.Key))}
+	case old == nil || l.Owner != old.Owner:
+		return []clientv3.Op{clientv3.OpPut(b.ownerKey(l.Key), l.Owner, clientv3.WithLease(b.Session))}
+	}
+	return nil
+}
+
+// view returns the lease of the given stored value, with its ModRevision, as it's seen by
+// the workers. with sessions, the leases that their ownership was detached (i.e: the etcd
+// lease of their owner expired) have no owner.
+func (b *Backend) view(kv *mvccpb.KeyValue, owned bool) (*lease.Lease, error) {
+	l, err := record.Decode(string(kv.Value))
+	if err != nil {
+		return nil, err
+	}
+	l.SetRevision(strconv.FormatInt(kv.ModRevision, 10))
+	if b.Session != 0 && isOwned(l) && !owned {
+		l.Owner = "NULL"
+	}
+	return l, nil
+}
+
+// isOwned test if the given lease has an owner.
+func isOwned(l *lease.Lease) bool {
+	return l.Owner != "" && l.Owner != "NULL"
+}
+
+// key returns the etcd key of the lease with the given key.
+func (b *Backend) key(key string) string {
+	return b.Prefix + "/" + key
+}
+
+// ownerKey returns the etcd key of the ownership of the lease with the given key, that is
+// attached to the session of its owner. it's outside of the prefix of the leases.
+func (b *Backend) ownerKey(key string) string {
+	return b.Prefix + "-owners/" + key
+}
@@ -0,0 +1,206 @@
+package etcdbackend
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/a8m/lease"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// kvMock is an in-memory KV, that evaluates the comparisons of the backend transactions.
+type kvMock struct {
+	data map[string]string
+	// mods are the mod revisions of the keys, and rev is the last revision.
+	mods map[string]int64
+	rev  int64
+}
+
+func newKVMock() *kvMock {
+	return &kvMock{data: make(map[string]string), mods: make(map[string]int64)}
+}
+
+func (m *kvMock) Get(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return (*clientv3.GetResponse)(m.get(clientv3.OpGet(key, opts...))), nil
+}
+
+func (m *kvMock) get(op clientv3.Op) *pb.RangeResponse {
+	key, prefix := string(op.KeyBytes()), len(op.RangeBytes()) > 0
+	var keys []string
+	for k := range m.data {
+		if k == key || prefix && strings.HasPrefix(k, key) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	resp := &pb.RangeResponse{}
+	for _, k := range keys {
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(m.data[k]), ModRevision: m.mods[k]})
+	}
+	return resp
+}
+
+func (m *kvMock) Txn(context.Context) clientv3.Txn {
+	return &txnMock{kv: m}
+}
+
+type txnMock struct {
+	kv        *kvMock
+	cmps      []clientv3.Cmp
+	then, els []clientv3.Op
+}
+
+func (t *txnMock) If(cs ...clientv3.Cmp) clientv3.Txn   { t.cmps = cs; return t }
+func (t *txnMock) Then(ops ...clientv3.Op) clientv3.Txn { t.then = ops; return t }
+func (t *txnMock) Else(ops ...clientv3.Op) clientv3.Txn { t.els = ops; return t }
+
+func (t *txnMock) Commit() (*clientv3.TxnResponse, error) {
+	ok := true
+	for i := range t.cmps {
+		c := t.cmps[i].GetCompare()
+		v, exists := t.kv.data[string(c.GetKey())]
+		switch c.GetTarget() {
+		case pb.Compare_CREATE:
+			created := int64(0)
+			if exists {
+				created = 1
+			}
+			if c.GetResult() == pb.Compare_EQUAL {
+				ok = ok && created == c.GetCreateRevision()
+			} else {
+				ok = ok && created > c.GetCreateRevision()
+			}
+		case pb.Compare_VALUE:
+			ok = ok && exists && v == string(c.GetValue())
+		case pb.Compare_MOD:
+			ok = ok && t.kv.mods[string(c.GetKey())] == c.GetModRevision()
+		}
+	}
+	ops := t.then
+	if !ok {
+		ops = t.els
+	}
+	resp := &pb.TxnResponse{Succeeded: ok}
+	t.kv.rev++
+	for _, op := range ops {
+		switch {
+		case op.IsPut():
+			t.kv.data[string(op.KeyBytes())] = string(op.ValueBytes())
+			t.kv.mods[string(op.KeyBytes())] = t.kv.rev
+		case op.IsDelete():
+			delete(t.kv.data, string(op.KeyBytes()))
+			delete(t.kv.mods, string(op.KeyBytes()))
+		case op.IsGet():
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{
+				Response: &pb.ResponseOp_ResponseRange{ResponseRange: t.kv.get(op)},
+			})
+		}
+	}
+	return (*clientv3.TxnResponse)(resp), nil
+}
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	b := New(newKVMock(), "/leases")
+
+	l := lease.NewLease("foo")
+	l.Owner = "1"
+	l.Counter = 1
+	l.Set("checkpoint", 10)
+	if ok, err := b.PutIfAbsent(ctx, &l); err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	if ok, _ := b.PutIfAbsent(ctx, &l); ok {
+		t.Error("expect existing leases not to be stored")
+	}
+
+	old, err := b.Get(ctx, "foo")
+	if err != nil || old.Owner != "1" {
+		t.Fatalf("expect to get the stored lease, got: %+v, %v", old, err)
+	}
+	update := *old
+	update.Counter++
+	if err := b.CompareAndUpdate(ctx, old, &update); err != nil {
+		t.Fatalf("expect the decoded lease to match the stored one: %v", err)
+	}
+	if err := b.CompareAndUpdate(ctx, old, &update); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the update of a changed lease to conflict, got: %v", err)
+	}
+	if err := b.Delete(ctx, old); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the delete of a changed lease to conflict, got: %v", err)
+	}
+
+	list, err := b.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Counter != 2 {
+		t.Fatalf("expect to list the updated lease, got: %v, %v", list, err)
+	}
+	if err := b.Delete(ctx, list[0]); err != nil {
+		t.Fatalf("expect the lease to be deleted: %v", err)
+	}
+	if err := b.Delete(ctx, list[0]); err != nil {
+		t.Errorf("expect the delete of a deleted lease to do nothing, got: %v", err)
+	}
+	if _, err := b.Get(ctx, "foo"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect the lease not to be found, got: %v", err)
+	}
+}
+
+func TestBackendRevision(t *testing.T) {
+	ctx := context.Background()
+	b := New(newKVMock(), "/leases")
+	if ok, err := b.PutIfAbsent(ctx, &lease.Lease{Key: "foo", Owner: "NULL", Counter: 1}); err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	old, _ := b.Get(ctx, "foo")
+	// the lease is written back with the same content, e.g: by a concurrent update.
+	if err := b.CompareAndUpdate(ctx, old, old); err != nil {
+		t.Fatalf("expect the lease to be updated: %v", err)
+	}
+	if err := b.CompareAndUpdate(ctx, old, old); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the ModRevision to be compared instead of the value, got: %v", err)
+	}
+}
+
+func TestBackendSession(t *testing.T) {
+	ctx := context.Background()
+	kv := newKVMock()
+	b := &Backend{KV: kv, Prefix: "/leases", Session: 1}
+	if ok, err := b.PutIfAbsent(ctx, &lease.Lease{Key: "foo", Owner: "1", Counter: 1}); err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	if _, ok := kv.data["/leases-owners/foo"]; !ok {
+		t.Fatal("expect the ownership to be attached to the session")
+	}
+	held, _ := b.Get(ctx, "foo")
+	if held.Owner != "1" {
+		t.Fatalf("expect the lease to be owned, got: %s", held.Owner)
+	}
+	if list, _ := b.List(ctx); len(list) != 1 || list[0].Owner != "1" {
+		t.Fatalf("expect the listed lease to be owned, got: %v", list)
+	}
+
+	// the etcd lease of the owner expired.
+	delete(kv.data, "/leases-owners/foo")
+	renewed := *held
+	renewed.Counter++
+	if err := b.CompareAndUpdate(ctx, held, &renewed); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the renewal of an expired session to conflict, got: %v", err)
+	}
+	expired, _ := b.Get(ctx, "foo")
+	if expired.Owner != "NULL" {
+		t.Fatalf("expect the lease of an expired session to have no owner, got: %s", expired.Owner)
+	}
+	taken := *expired
+	taken.Owner = "2"
+	if err := b.CompareAndUpdate(ctx, expired, &taken); err != nil {
+		t.Fatalf("expect the lease to be taken: %v", err)
+	}
+	if v := kv.data["/leases-owners/foo"]; v != "2" {
+		t.Errorf("expect the ownership of the new owner to be attached, got: %q", v)
+	}
+}
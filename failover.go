@@ -0,0 +1,230 @@
+package lease
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrFenced error will be returns by the failover client on writes, during the fence
+// period that follows a region switch. See: NewFailoverClient.
+var ErrFenced = errors.New("leaser: writes are fenced after a region switch")
+
+// failoverClient is a Clientface that fails over between a prioritized list of DynamoDB
+// clients (e.g: the replicas of a global table in different regions).
+//
+// Fencing: conditional writes on a global table are evaluated against the local replica
+// only, and replicas converge with last-writer-wins. Right after a region switch the new
+// replica may not have the latest renewals yet, so a worker could take a lease that is
+// still renewed by its owner in the other region, and both would hold it. To avoid that,
+// writes are rejected with ErrFenced for a fence period after each switch. During the
+// fence, the lease owners that can't reach the new region stop renewing (and stop
+// reporting the leases as held), and the renewals that were made in the old region are
+// replicated. The fence should be at least ExpireAfter plus the replication lag. The
+// renewals are not fenced, since they keep the owner of the lease; otherwise, the owners
+// would lose all of their leases on every switch.
+type failoverClient struct {
+	sync.Mutex
+	clients  []Clientface
+	failback time.Duration
+	fence    time.Duration
+	// the active client, and the last time it was switched.
+	active   int
+	switched time.Time
+	// the last time the primary client was probed for a failback.
+	probed time.Time
+	// now is the clock of the fence and failback periods. It's replaced with the
+	// Config.Clock of the config that uses the client.
+	now func() time.Time
+}
+
+// NewFailoverClient returns a Clientface that sends the requests to the first available
+// client of the given prioritized list. When a client is unavailable (i.e: network errors
+// or 5xx responses) it fails over to the next one. After the failback period, a request
+// is sent to the primary client once per period, and it fails back if the primary client
+// is available. Writes are fenced for the fence period after each switch.
+func NewFailoverClient(failback, fence time.Duration, clients ...Clientface) Clientface {
+	return &failoverClient{
+		clients:  clients,
+		failback: failback,
+		fence:    fence,
+		now:      time.Now,
+	}
+}
+
+// NewRegionalClients returns a DynamoDB client for each of the given regions, in the same
+// order. Use it with NewFailoverClient, and a global table that replicated to these regions.
+func NewRegionalClients(sess *session.Session, regions ...string) []Clientface {
+	clients := make([]Clientface, len(regions))
+	for i, region := range regions {
+		clients[i] = dynamodb.New(sess, aws.NewConfig().WithRegion(region))
+	}
+	return clients
+}
+
+// pick returns the client to use for the next request, and whether to probe the primary
+// client with it first, after the failback period. the fenced requests are not used as
+// probes, since they fail on the fence of the failback.
+func (f *failoverClient) pick(fenced bool) (int, bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	if fenced && !f.switched.IsZero() && f.now().Sub(f.switched) < f.fence {
+		return 0, false, ErrFenced
+	}
+	probe := !fenced && f.active != 0 && f.failback > 0 && f.now().Sub(f.switched) > f.failback && f.now().Sub(f.probed) > f.failback
+	if probe {
+		f.probed = f.now()
+	}
+	return f.active, probe, nil
+}
+
+// recover fails back to the primary client, after it was probed successfully.
+func (f *failoverClient) recover() {
+	f.Lock()
+	defer f.Unlock()
+	if f.active != 0 {
+		f.active, f.switched = 0, f.now()
+	}
+}
+
+// failover switches from the given unavailable client to the next one, and returns false
+// if there are no more clients.
+func (f *failoverClient) failover(i int) (int, bool) {
+	f.Lock()
+	defer f.Unlock()
+	// another request already switched.
+	if f.active != i {
+		return f.active, true
+	}
+	next := i + 1
+	if next == len(f.clients) {
+		return i, false
+	}
+	f.active, f.switched = next, f.now()
+	return next, true
+}
+
+// do sends the request to the active client, and fails over while it's unavailable.
+// the fenced requests are rejected during the fence period. see: failoverClient.
+func (f *failoverClient) do(fenced bool, fn func(Clientface) error) error {
+	i, probe, err := f.pick(fenced)
+	if err != nil {
+		return err
+	}
+	if probe {
+		if err = fn(f.clients[0]); !isUnavailable(err) {
+			f.recover()
+			return err
+		}
+	}
+	for {
+		err = fn(f.clients[i])
+		if !isUnavailable(err) {
+			return err
+		}
+		var ok bool
+		if i, ok = f.failover(i); !ok {
+			return err
+		}
+		if _, _, ferr := f.pick(fenced); ferr != nil {
+			return ferr
+		}
+	}
+}
+
+// isRenewal test if the given update renews the lease of its owner, i.e: it's conditional
+// on the owner of the lease, and it keeps it. see: LeaseManager.condUpdateInput.
+func isRenewal(in *dynamodb.UpdateItemInput) bool {
+	if in == nil {
+		return false
+	}
+	owner, cond := in.ExpressionAttributeValues[":owner"], in.ExpressionAttributeValues[":condOwner"]
+	return owner != nil && cond != nil && aws.StringValue(owner.S) == aws.StringValue(cond.S)
+}
+
+// isUnavailable test if the given error indicates that the endpoint is unavailable.
+func isUnavailable(err error) bool {
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		return reqErr.StatusCode() >= 500
+	}
+	if awsErr, ok := err.(awserr.Error); ok {
+		return awsErr.Code() == request.ErrCodeRequestError || awsErr.Code() == request.ErrCodeResponseTimeout
+	}
+	return false
+}
+
+func (f *failoverClient) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, opts ...request.Option) (out *dynamodb.GetItemOutput, err error) {
+	err = f.do(false, func(c Clientface) (err error) {
+		out, err = c.GetItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (f *failoverClient) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput, opts ...request.Option) (out *dynamodb.ScanOutput, err error) {
+	err = f.do(false, func(c Clientface) (err error) {
+		out, err = c.ScanWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (f *failoverClient) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (out *dynamodb.PutItemOutput, err error) {
+	err = f.do(true, func(c Clientface) (err error) {
+		out, err = c.PutItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (f *failoverClient) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput, opts ...request.Option) (out *dynamodb.UpdateItemOutput, err error) {
+	err = f.do(!isRenewal(in), func(c Clientface) (err error) {
+		out, err = c.UpdateItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (f *failoverClient) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, opts ...request.Option) (out *dynamodb.DeleteItemOutput, err error) {
+	err = f.do(true, func(c Clientface) (err error) {
+		out, err = c.DeleteItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// TransactWriteItemsWithContext sends the transaction to the active client. it fails with
+// ErrTransactionsUnsupported if the active client does not support transactions.
+func (f *failoverClient) TransactWriteItemsWithContext(ctx aws.Context, in *dynamodb.TransactWriteItemsInput, opts ...request.Option) (out *dynamodb.TransactWriteItemsOutput, err error) {
+	err = f.do(true, func(c Clientface) (err error) {
+		tc, ok := c.(transactClient)
+		if !ok {
+			return ErrTransactionsUnsupported
+		}
+		out, err = tc.TransactWriteItemsWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (f *failoverClient) CreateTableWithContext(ctx aws.Context, in *dynamodb.CreateTableInput, opts ...request.Option) (out *dynamodb.CreateTableOutput, err error) {
+	err = f.do(false, func(c Clientface) (err error) {
+		out, err = c.CreateTableWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (f *failoverClient) DescribeTableWithContext(ctx aws.Context, in *dynamodb.DescribeTableInput, opts ...request.Option) (out *dynamodb.DescribeTableOutput, err error) {
+	err = f.do(false, func(c Clientface) (err error) {
+		out, err = c.DescribeTableWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
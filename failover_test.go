@@ -0,0 +1,101 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// regionMock is a Clientface of a single region, that can be unavailable.
+type regionMock struct {
+	Clientface
+	down  bool
+	calls int
+}
+
+func (r *regionMock) GetItemWithContext(aws.Context, *dynamodb.GetItemInput, ...request.Option) (*dynamodb.GetItemOutput, error) {
+	r.calls++
+	if r.down {
+		return nil, awserr.New(request.ErrCodeRequestError, "", errors.New("connection refused"))
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (r *regionMock) PutItemWithContext(aws.Context, *dynamodb.PutItemInput, ...request.Option) (*dynamodb.PutItemOutput, error) {
+	r.calls++
+	if r.down {
+		return nil, awserr.NewRequestFailure(awserr.New("InternalServerError", "", nil), 500, "")
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (r *regionMock) UpdateItemWithContext(aws.Context, *dynamodb.UpdateItemInput, ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	r.calls++
+	if r.down {
+		return nil, awserr.NewRequestFailure(awserr.New("InternalServerError", "", nil), 500, "")
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestFailoverClient(t *testing.T) {
+	primary, secondary := &regionMock{down: true}, &regionMock{}
+	client := NewFailoverClient(time.Hour, 0, primary, secondary).(*failoverClient)
+
+	_, err := client.GetItemWithContext(context.Background(), nil)
+	assert(t, err == nil, "expect to fail over to the secondary region")
+	assert(t, primary.calls == 1 && secondary.calls == 1, "expect to try the primary region first")
+	_, err = client.PutItemWithContext(context.Background(), nil)
+	assert(t, err == nil && primary.calls == 1, "expect to stay on the secondary region")
+
+	// the primary region is probed after the failback period, and only once per period.
+	client.switched = time.Now().Add(-2 * time.Hour)
+	_, err = client.GetItemWithContext(context.Background(), nil)
+	assert(t, err == nil && primary.calls == 2 && client.active == 1, "expect not to fail back to an unavailable primary region")
+	_, err = client.GetItemWithContext(context.Background(), nil)
+	assert(t, err == nil && primary.calls == 2, "expect to probe the primary region once per failback period")
+
+	// fail back
+	primary.down = false
+	client.probed = time.Time{}
+	_, err = client.GetItemWithContext(context.Background(), nil)
+	assert(t, err == nil && primary.calls == 3 && client.active == 0, "expect to fail back to the primary region")
+
+	// all regions are down
+	primary.down, secondary.down = true, true
+	_, err = client.GetItemWithContext(context.Background(), nil)
+	assert(t, isUnavailable(err), "expect to fail if all regions are unavailable")
+
+	// conditional failures are not failovers
+	assert(t, !isUnavailable(awserr.New(ConditionalFailed, "", nil)), "expect conditional failures not to fail over")
+}
+
+func TestFailoverClientFence(t *testing.T) {
+	primary, secondary := &regionMock{down: true}, &regionMock{}
+	client := NewFailoverClient(time.Hour, time.Minute, primary, secondary)
+
+	_, err := client.PutItemWithContext(context.Background(), nil)
+	assert(t, err == ErrFenced, "expect writes to be fenced after a region switch")
+	_, err = client.GetItemWithContext(context.Background(), nil)
+	assert(t, err == nil, "expect reads not to be fenced")
+	_, err = client.PutItemWithContext(context.Background(), nil)
+	assert(t, err == ErrFenced && secondary.calls == 1, "expect writes to be fenced during the fence period")
+}
+
+func TestFailoverClientFenceRenewals(t *testing.T) {
+	primary, secondary := &regionMock{down: true}, &regionMock{}
+	client := NewFailoverClient(time.Hour, time.Minute, primary, secondary)
+	client.GetItemWithContext(context.Background(), nil)
+
+	renew := newTestManager(nil).condUpdateInput(Lease{Key: "foo", Owner: "1", Counter: 2}, Lease{Key: "foo", Owner: "1", Counter: 1})
+	_, err := client.UpdateItemWithContext(context.Background(), renew)
+	assert(t, err == nil && secondary.calls == 2, "expect the renewals not to be fenced")
+	take := newTestManager(nil).condUpdateInput(Lease{Key: "foo", Owner: "1", Counter: 2}, Lease{Key: "foo", Owner: "2", Counter: 1})
+	_, err = client.UpdateItemWithContext(context.Background(), take)
+	assert(t, err == ErrFenced, "expect the takes to be fenced")
+}
@@ -0,0 +1,140 @@
+// Package firestorebackend runs the coordinator on Google Cloud Firestore, instead of
+// DynamoDB, e.g: in GCP deployments, without crossing clouds.
+//
+// The leases are stored as documents of a collection, with the lease encoded as JSON in
+// their "lease" field, and the conditional writes of lease.Backend are Firestore
+// transactions that compare the stored lease, so the takes, renewals and evictions keep
+// their conditions on the leaseOwner and the leaseCounter. For example:
+//
+//	client, err := firestore.NewClient(ctx, "my-project")
+//	leaser := lease.New("leases", lease.WithBackend(firestorebackend.New(client, "leases")))
+//
+// The backend requires a database in Firestore Native mode. Datastore mode databases are
+// not supported by the Firestore client.
+package firestorebackend
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// field is the document field that holds the encoded lease.
+const field = "lease"
+
+// Backend is a lease.Backend that stores the leases in a Firestore collection.
+type Backend struct {
+	Client *firestore.Client
+	// Collection is the name of the collection of the lease table.
+	Collection string
+}
+
+// New returns a lease.Backend that stores the leases in the given collection, using the
+// given Firestore client.
+func New(client *firestore.Client, collection string) lease.Backend {
+	return &Backend{Client: client, Collection: collection}
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (*lease.Lease, error) {
+	snap, err := b.doc(key).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, lease.ErrLeaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(snap)
+}
+
+func (b *Backend) List(ctx context.Context) ([]*lease.Lease, error) {
+	var list []*lease.Lease
+	iter := b.Client.Collection(b.Collection).Documents(ctx)
+	defer iter.Stop()
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		l, err := decode(snap)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+}
+
+func (b *Backend) PutIfAbsent(ctx context.Context, l *lease.Lease) (bool, error) {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return false, err
+	}
+	_, err = b.doc(l.Key).Create(ctx, map[string]interface{}{field: raw})
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Backend) CompareAndUpdate(ctx context.Context, old, l *lease.Lease) error {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return err
+	}
+	return b.transact(ctx, old, func(tx *firestore.Transaction, ref *firestore.DocumentRef, exists bool) error {
+		if !exists {
+			return lease.ErrConflict
+		}
+		return tx.Set(ref, map[string]interface{}{field: raw})
+	})
+}
+
+func (b *Backend) Delete(ctx context.Context, old *lease.Lease) error {
+	return b.transact(ctx, old, func(tx *firestore.Transaction, ref *firestore.DocumentRef, exists bool) error {
+		if !exists {
+			return nil
+		}
+		return tx.Delete(ref)
+	})
+}
+
+// transact runs the given write in a transaction, if the stored lease is unchanged since
+// the old lease was read, or if it does not exist. Fails with lease.ErrConflict otherwise.
+func (b *Backend) transact(ctx context.Context, old *lease.Lease, write func(*firestore.Transaction, *firestore.DocumentRef, bool) error) error {
+	oraw, err := record.Encode(old)
+	if err != nil {
+		return err
+	}
+	ref := b.doc(old.Key)
+	return b.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return write(tx, ref, false)
+		}
+		if err != nil {
+			return err
+		}
+		if raw, _ := snap.Data()[field].(string); raw != oraw {
+			return lease.ErrConflict
+		}
+		return write(tx, ref, true)
+	})
+}
+
+// doc returns the document of the lease with the given key.
+func (b *Backend) doc(key string) *firestore.DocumentRef {
+	return b.Client.Collection(b.Collection).Doc(key)
+}
+
+// decode decodes the lease of the given document.
+func decode(snap *firestore.DocumentSnapshot) (*lease.Lease, error) {
+	raw, _ := snap.Data()[field].(string)
+	return record.Decode(raw)
+}
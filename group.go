@@ -0,0 +1,179 @@
+package lease
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// maxTransactItems is the maximum number of items in a DynamoDB transaction.
+const maxTransactItems = 100
+
+var (
+	// ErrTransactionsUnsupported error will be returns if a lease group is taken with a
+	// client that does not support DynamoDB transactions. See: Manager.TakeLeases.
+	ErrTransactionsUnsupported = errors.New("leaser: client does not support transactions")
+
+	// ErrGroupTooLarge error will be returns if a lease group exceeds the maximum number
+	// of items in a DynamoDB transaction.
+	ErrGroupTooLarge = errors.New("leaser: lease group is too large")
+
+	// ErrGroupHeld error will be returns by the taker if a lease of a group is taken while
+	// another lease of the group is held by another live worker. The group is taken once
+	// that lease is released or expired.
+	ErrGroupHeld = errors.New("leaser: lease group is partially held by another worker")
+)
+
+// transactClient is implemented by the clients that support DynamoDB transactions,
+// e.g: *dynamodb.DynamoDB.
+type transactClient interface {
+	TransactWriteItemsWithContext(aws.Context, *dynamodb.TransactWriteItemsInput, ...request.Option) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// TakeLeases takes all the given leases in a single transaction, or none of them.
+// Conditional on the leaseCounter and the owner in DynamoDB matching the ones of each input.
+// Mutates the passed-in lease objects, like TakeLease, after updating the records in DynamoDB.
+func (l *LeaseManager) TakeLeases(ctx context.Context, leases []*Lease) error {
+	if len(leases) == 0 {
+		return nil
+	}
+	key := leases[0].Group
+	client, ok := l.Client.(transactClient)
+	if !ok {
+		return l.wrapError("take group", key, ErrTransactionsUnsupported)
+	}
+	if len(leases) > maxTransactItems {
+		return l.wrapError("take group", key, ErrGroupTooLarge)
+	}
+	taken := make([]Lease, len(leases))
+	items := make([]*dynamodb.TransactWriteItem, len(leases))
+	for i, lease := range leases {
+		taken[i] = l.takenLease(lease)
+		input := l.condUpdateInput(taken[i], *lease)
+		items[i] = &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName:                 input.TableName,
+				Key:                       input.Key,
+				UpdateExpression:          input.UpdateExpression,
+				ConditionExpression:       input.ConditionExpression,
+				ExpressionAttributeNames:  input.ExpressionAttributeNames,
+				ExpressionAttributeValues: input.ExpressionAttributeValues,
+			},
+		}
+	}
+	if err := l.transactWrite(ctx, client, key, items); err != nil {
+		return l.wrapError("take group", key, err)
+	}
+	for i, lease := range leases {
+		*lease = taken[i]
+	}
+	return nil
+}
+
+// transactWrite writes the given items in a single transaction, with the retries logic.
+// a canceled transaction (i.e: one of the conditions failed) is not retried.
+func (l *LeaseManager) transactWrite(ctx context.Context, client transactClient, key string, items []*dynamodb.TransactWriteItem) (err error) {
+	r := l.retrier(RetryUpdate)
+	for r.more() {
+		_, err = client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+
+		if err == nil {
+			break
+		}
+
+		// one of the leases was changed since it was read.
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == TransactionCanceled {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to write lease transaction", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+	return l.retryError(r, key, err)
+}
+
+// takeLease takes the given lease for the given reason. leases of a group are taken
+// together with the rest of their group, in a single transaction. the group is expanded
+// only with the leases that are unowned or expired, and when the given lease is stolen,
+// with the leases of its owner. it fails with ErrGroupHeld if another lease of the group
+// is held by another live worker.
+func (l *leaseTaker) takeLease(ctx context.Context, lease *Lease, reason TakeoverReason) error {
+	if lease.Group == "" {
+		return l.takeLeases(ctx, []*Lease{lease}, reason)
+	}
+	// the group was already taken with one of its other leases.
+	if lease.Owner == l.WorkerId {
+		return nil
+	}
+	stolen := !l.isAvailable(lease)
+	group := []*Lease{lease}
+	for _, glease := range l.allLeases {
+		if glease.Group != lease.Group || glease.Key == lease.Key || glease.Owner == l.WorkerId {
+			continue
+		}
+		if !l.isAvailable(glease) && !(stolen && glease.Owner == lease.Owner) {
+			return ErrGroupHeld
+		}
+		group = append(group, glease)
+	}
+	return l.takeLeases(ctx, group, reason)
+}
+
+// stealCost returns the number of live leases that are stolen from their owner when the
+// given live lease is taken: the lease, and the other live leases of its group that the
+// same owner holds. see: takeLease.
+func (l *leaseTaker) stealCost(lease *Lease) int {
+	cost := 1
+	if lease.Group == "" {
+		return cost
+	}
+	for _, glease := range l.allLeases {
+		if glease.Group == lease.Group && glease.Key != lease.Key && glease.Owner == lease.Owner && !l.isAvailable(glease) {
+			cost++
+		}
+	}
+	return cost
+}
+
+// isAvailable test if the given lease is unowned or expired.
+func (l *leaseTaker) isAvailable(lease *Lease) bool {
+	return lease.hasNoOwner() || lease.isExpired(l.expireAfter(lease.Key), l.now())
+}
+
+// releaseGroups releases the held leases of the given groups, to not hold a
+// lease group partially.
+func (l *leaseHolder) releaseGroups(ctx context.Context, groups map[string]bool) {
+	if len(groups) == 0 {
+		return
+	}
+	for _, lease := range l.GetHeldLeases() {
+		if !groups[lease.Group] {
+			continue
+		}
+		if err := l.Release(ctx, lease); err != nil {
+			l.Logger.Debugf("Worker %s could not release lease with key %s of lost group %s", l.WorkerId, lease.Key, lease.Group)
+		} else {
+			l.Logger.Debugf("Worker %s released lease with key %s of lost group %s", l.WorkerId, lease.Key, lease.Group)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package lease
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a8m/lease/leasetest"
+)
+
+func TestCondUpdateHeartbeat(t *testing.T) {
+	manager := newTestManager(nil)
+	manager.Clock = leasetest.NewFakeClock(time.Unix(1000, 0))
+	lease := Lease{Key: "foo", Owner: "1", Counter: 1}
+	renewed := lease
+	renewed.Counter++
+
+	input := manager.condUpdateInput(renewed, lease)
+	assert(t, !strings.Contains(*input.UpdateExpression, LeaseHeartbeatKey), "expect no heartbeat by default")
+
+	manager.Heartbeat = true
+	input = manager.condUpdateInput(renewed, lease)
+	assert(t, strings.Contains(*input.UpdateExpression, LeaseHeartbeatKey+" = :heartbeat"), "expect the heartbeat to be set on renew")
+	assert(t, *input.ExpressionAttributeValues[":heartbeat"].N == "1000", "expect the heartbeat to be the time of the renew")
+
+	evicted := renewed
+	evicted.Owner = "NULL"
+	input = manager.condUpdateInput(evicted, lease)
+	assert(t, !strings.Contains(*input.UpdateExpression, LeaseHeartbeatKey+" = "), "expect no heartbeat on evict")
+
+	manager.Heartbeat = false
+	lease.Heartbeat = time.Unix(900, 0)
+	renewed.Heartbeat = lease.Heartbeat
+	input = manager.condUpdateInput(renewed, lease)
+	assert(t, strings.Contains(*input.UpdateExpression, "REMOVE "+LeaseHeartbeatKey), "expect a stale heartbeat to be removed on renew without heartbeats")
+}
+
+func TestTakerHeartbeat(t *testing.T) {
+	config := &Config{Logger: testLogger(), LeaseTable: "test", WorkerId: "1", ExpireAfter: time.Minute, Heartbeat: true}
+	config.defaults()
+	taker := &leaseTaker{Config: config}
+	now := time.Now()
+	taker.updateLeases(context.Background(), []*Lease{
+		{Key: "foo", Owner: "2", Heartbeat: now.Add(-2 * time.Minute), lastRenewal: now},
+		{Key: "bar", Owner: "2", Heartbeat: now.Add(-time.Second), lastRenewal: now},
+		{Key: "baz", Owner: "2", lastRenewal: now},
+	})
+	assert(t, taker.allLeases["foo"].isExpired(time.Minute, now), "expect a stale heartbeat to expire the lease on the first scan")
+	assert(t, !taker.allLeases["bar"].isExpired(time.Minute, now), "expect a fresh heartbeat not to expire the lease")
+	assert(t, taker.allLeases["baz"].lastRenewal.Equal(now), "expect the time of the scan without a heartbeat")
+
+	config.Heartbeat = false
+	taker.allLeases = nil
+	taker.updateLeases(context.Background(), []*Lease{
+		{Key: "foo", Owner: "2", Heartbeat: now.Add(-2 * time.Minute), lastRenewal: now},
+	})
+	assert(t, !taker.allLeases["foo"].isExpired(time.Minute, now), "expect the heartbeat to be ignored when disabled")
+}
@@ -1,7 +1,10 @@
 package lease
 
 import (
+	"context"
 	"errors"
+	"math"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -37,6 +40,33 @@ var (
 	// type.
 	// for example: StringSet type excepts only []string{...}
 	ErrValueNotMatch = errors.New("leaser: field value does not match the field type")
+	// ErrQuotaExceeded error will be returns only on the Create() call, if creating
+	// the passed-in lease object will exceed the quota of its namespace.
+	ErrQuotaExceeded = errors.New("leaser: lease namespace quota exceeded")
+	// ErrTableNotActive error will be returns if the lease table is not active within
+	// 5 minutes after it's created. See: Manager.CreateLeaseTable.
+	ErrTableNotActive = errors.New("leaser: lease table is not active")
+	// ErrLeaseNotFound error will be returns if the requested lease does not exist
+	// in the table.
+	ErrLeaseNotFound = errors.New("leaser: lease does not exist")
+	// ErrLeaseStolen error will be returns by the conditional writes of a lease (e.g: renew,
+	// take, evict or delete), if the lease is owned by another worker in the table.
+	// See: ConditionError.
+	ErrLeaseStolen = errors.New("leaser: lease is owned by another worker")
+	// ErrLeaseCounterChanged error will be returns by the conditional writes of a lease,
+	// if the lease counter in the table was changed since the lease was read, e.g: by
+	// a concurrent renewal. See: ConditionError.
+	ErrLeaseCounterChanged = errors.New("leaser: lease counter changed")
+	// ErrLeaseHeldExclusively error will be returns only on the AcquireShared() call,
+	// if the passed-in lease object is held exclusively by a worker.
+	ErrLeaseHeldExclusively = errors.New("leaser: lease is held exclusively by a worker")
+	// ErrLeaseFull error will be returns only on the AcquireShared() call, if the
+	// passed-in semaphore lease already reached its maximum number of holders.
+	ErrLeaseFull = errors.New("leaser: lease reached its maximum number of holders")
+	// ErrFieldConflict error will be returns by the writes of a lease, if one of its extra
+	// fields has the name of a renamed attribute in the table, or the name of the partition
+	// key. See: Config.AttributeNames and Config.Partition.
+	ErrFieldConflict = errors.New("leaser: extra field name is used by an attribute of the table")
 )
 
 // Lease type contains data pertianing to a Lease.
@@ -47,9 +77,105 @@ var (
 // or until it fails.
 // When the worker stops holding the lease, another worker will take and hold the lease.
 type Lease struct {
-	Key     string `dynamodbav:"leaseKey"`
-	Owner   string `dynamodbav:"leaseOwner"`
-	Counter int    `dynamodbav:"leaseCounter"`
+	Key   string `dynamodbav:"leaseKey"`
+	Owner string `dynamodbav:"leaseOwner"`
+	// Counter is incremented on every renewal and take, and it's the condition of the
+	// writes of the lease. It wraps around to 1 after math.MaxInt64. See: nextCounter.
+	Counter int64 `dynamodbav:"leaseCounter"`
+	// Epoch is the ownership generation of the lease. It's incremented on every take,
+	// unlike the Counter that is incremented on every renewal, so downstream systems
+	// can fence the writes of previous owners by comparing epochs.
+	Epoch int `dynamodbav:"leaseEpoch"`
+
+	// OwnerTier is the priority tier of the lease owner. See: Config.Tier.
+	OwnerTier int `dynamodbav:"leaseOwnerTier"`
+	// OwnerVersion is the application version of the lease owner. See: Config.Version.
+	OwnerVersion string `dynamodbav:"leaseOwnerVersion"`
+	// OwnerHost is the host name of the lease owner. See: Config.Host.
+	OwnerHost string `dynamodbav:"leaseOwnerHost"`
+
+	// OwnerZone is the availability zone of the lease owner. See: Config.Zone.
+	OwnerZone string `dynamodbav:"leaseOwnerZone"`
+	// TakeoverReason is the reason of the last take of this lease. See: TakeoverReason.
+	TakeoverReason TakeoverReason `dynamodbav:"leaseTakeoverReason"`
+	// PreemptedBy is the higher tier worker that requested the owner to drain
+	// this lease and hand it over.
+	PreemptedBy string `dynamodbav:"leasePreemptedBy"`
+
+	// ReservedBy is the worker that reserved this lease for a future handover,
+	// and ReservedUntil is the time that this reservation lapses.
+	// Other workers deprioritize leases that are reserved by someone else.
+	ReservedBy    string    `dynamodbav:"leaseReservedBy"`
+	ReservedUntil time.Time `dynamodbav:"leaseReservedUntil,unixtime"`
+
+	// Heartbeat is the time of the last renew or take of the lease, by the clock of its
+	// owner. It's persisted only with Config.Heartbeat. See: Config.Heartbeat.
+	Heartbeat time.Time `dynamodbav:"leaseHeartbeat,unixtime"`
+
+	// Canary tags this lease as a canary lease that only canary workers take.
+	// See: Config.Canary.
+	Canary bool `dynamodbav:"leaseCanary"`
+
+	// Holders maps the workers that hold this lease in shared mode to the last
+	// time (unix seconds) they renewed their hold. A lease with active shared
+	// holders cannot be held exclusively (i.e: taken), and vice versa.
+	Holders map[string]int64 `dynamodbav:"leaseHolders"`
+
+	// Group makes this lease a member of a lease group, that its leases are taken
+	// all together or none, in a single transaction. See: Manager.TakeLeases.
+	Group string `dynamodbav:"leaseGroup"`
+
+	// DependsOn are the keys of the leases that must be completed (see: Leaser.Complete),
+	// or deleted, before this lease is taken. e.g: downstream partitions of a pipeline that
+	// must not start before the upstream partitions finish. Unlike ParentKeys, that record
+	// the lineage of the lease, a dependency is done only once it's completed.
+	DependsOn []string `dynamodbav:"leaseDependsOn,stringset"`
+
+	// Labels are small key-value pairs that classify this lease, e.g: its region or its
+	// workload, so workers can target only the relevant leases. They are written when the
+	// lease is created. See: Selector and Manager.ListLeasesBySelector.
+	Labels map[string]string `dynamodbav:"leaseLabels"`
+
+	// ParentKeys are the keys of the leases that this lease was split or merged from, like
+	// the lineage of Kinesis shards. The lease is not taken before its parents are completed
+	// (see: Leaser.Complete) or deleted, so the records of a key are processed in order
+	// across resharding. Unlike DependsOn, a parent is also done once it's checkpointed at
+	// "SHARD_END", like a Kinesis shard that was fully consumed. With Config.KCLSchema, the
+	// ParentShardIds are parents as well.
+	ParentKeys []string `dynamodbav:"leaseParentKeys,stringset"`
+
+	// MaxHolders makes this lease a semaphore lease that up to MaxHolders workers may
+	// hold concurrently in shared mode. Semaphore leases are never held exclusively.
+	MaxHolders int `dynamodbav:"leaseMaxHolders"`
+
+	// SchemaVersion is the schema version of this lease. See: Config.Migrator.
+	SchemaVersion int `dynamodbav:"leaseSchemaVersion"`
+
+	// TombstonedAt is the time this lease was soft-deleted. See: Config.SoftDelete.
+	TombstonedAt time.Time `dynamodbav:"leaseTombstonedAt,unixtime"`
+
+	// CompletedAt is the time the work of this lease was completed. Completed leases
+	// are never taken again. See: Leaser.Complete.
+	CompletedAt time.Time `dynamodbav:"leaseCompletedAt,unixtime"`
+
+	// LoadHint is the load of this lease (e.g: records/sec or backlog), as reported by
+	// its owner. See: Leaser.ReportLoad and Config.BalanceByLoad.
+	LoadHint float64 `dynamodbav:"leaseLoadHint"`
+
+	// Checkpoint is the progress of the work of this lease, as recorded by its owner (e.g: the
+	// sequence number of the last processed record). It's stored in the leaseCheckpoint
+	// attribute, or in the checkpoint attribute with Config.KCLSchema. See: Leaser.Checkpoint.
+	//
+	// With Config.KCLSchema, Checkpoint is the KCL checkpoint of the shard of this lease (i.e: a
+	// sequence number, or a sentinel like "TRIM_HORIZON" and "SHARD_END"), and
+	// CheckpointSubSequenceNumber is the sub-sequence number of an aggregated record.
+	// OwnerSwitchesSinceCheckpoint counts the takes since the last checkpoint, and
+	// ParentShardIds are the keys of the leases of the parent shards. They are read and
+	// written only with Config.KCLSchema. See: Lease.SetCheckpoint.
+	Checkpoint                   string   `dynamodbav:"-"`
+	CheckpointSubSequenceNumber  int64    `dynamodbav:"-"`
+	OwnerSwitchesSinceCheckpoint int      `dynamodbav:"-"`
+	ParentShardIds               []string `dynamodbav:"-"`
 
 	// lastRenewal is used by LeaseTaker to track the last time a lease counter was incremented.
 	// It is deliberately not persisted in DynamoDB.
@@ -63,6 +189,28 @@ type Lease struct {
 	explicitfields map[string]*dynamodb.AttributeValue
 	// removed attributes; used to create the update expression.
 	removedfields []string
+	// unknownfields holds the attributes that belong to a newer version of this package.
+	unknownfields map[string]*dynamodb.AttributeValue
+	// overflow points to the extra fields that stored outside of the table.
+	overflow *overflowRef
+	// reportedLoad is the load reported by the owner, to be written on the next renewal.
+	reportedLoad *float64
+	// checkpointed is set if the checkpoint was set, to be written on the next update.
+	checkpointed bool
+	// payload is the typed payload of the lease, as stored in DynamoDB. See: Lease.SetPayload.
+	payload *dynamodb.AttributeValue
+	// payloadSet is set if the payload was set, to be written on the next update.
+	payloadSet bool
+	// migrated indicates that the lease was upgraded after it was read, and it needs
+	// to be written in its new schema version.
+	migrated bool
+	// size is the size of the lease item, as of the last time it was read or written.
+	size int
+	// takeReason is the reason to persist when the lease is taken by the taker.
+	takeReason TakeoverReason
+	// revision is the version of the lease in its Backend, as of the last time it was
+	// read. It is deliberately not persisted. See: Lease.Revision.
+	revision string
 }
 
 // NewLease gets a key(represents the lease key/name) and returns a new Lease object.
@@ -70,6 +218,12 @@ func NewLease(key string) Lease {
 	return Lease{Key: key}
 }
 
+// NewSemaphoreLease gets a key and the maximum number of concurrent holders, and returns
+// a new semaphore Lease object. Semaphore leases are acquired with Leaser.AcquireShared.
+func NewSemaphoreLease(key string, n int) Lease {
+	return Lease{Key: key, Owner: "NULL", MaxHolders: n}
+}
+
 // Set extra field(metadata) to the Lease object before you create or update it
 // using the Leaser.
 //
@@ -77,6 +231,9 @@ func NewLease(key string) Lease {
 //
 //    lease.Set("success", true)
 //    lease.Set("checkpoint", 35465786912)
+//
+// Keys that start with "lease", followed by an upper case letter, are reserved to this
+// package, and cannot be used as extra fields.
 func (l *Lease) Set(key string, val interface{}) {
 	if l.extrafields == nil {
 		l.extrafields = make(map[string]interface{})
@@ -131,7 +288,12 @@ func (l *Lease) SetAs(key string, val interface{}, typ AttributeType) error {
 }
 
 // Get extra field(metadata) from the Lease object that not belongs to this package.
+// If the overflowed extra fields fail to load, it reports the field as missing, and
+// Err returns the error. See: Load.
 func (l *Lease) Get(key string) (interface{}, bool) {
+	if err := l.Load(); err != nil {
+		return nil, false
+	}
 	if val, ok := l.extrafields[key]; ok {
 		return val, ok
 	}
@@ -149,8 +311,31 @@ func (l *Lease) Get(key string) (interface{}, bool) {
 	return nil, false
 }
 
+// Fields returns all the extra fields(metadata) of the Lease object. e.g: to store them
+// in a Backend, and restore them using Lease.Set.
+func (l *Lease) Fields() map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, k := range l.fieldKeys() {
+		fields[k], _ = l.Get(k)
+	}
+	return fields
+}
+
+// Token returns the concurrency token of the lease. It's set on the held leases (see:
+// Leaser.GetHeldLeases), and checked by Leaser.Update and Leaser.Complete.
+func (l *Lease) Token() string {
+	return l.concurrencyToken
+}
+
+// SetToken sets the concurrency token of the lease, e.g: to restore a held lease that
+// was passed across a process boundary (see: the leasegrpc package).
+func (l *Lease) SetToken(token string) {
+	l.concurrencyToken = token
+}
+
 // Del deletes extra field(metadata) of the lease object.
 func (l *Lease) Del(key string) {
+	l.Load()
 	var ok bool
 	if _, ok = l.extrafields[key]; ok {
 		delete(l.extrafields, key)
@@ -162,9 +347,36 @@ func (l *Lease) Del(key string) {
 	}
 }
 
-// isExpired test if the lease renewal is expired from the given time.
-func (l *Lease) isExpired(t time.Duration) bool {
-	return time.Since(l.lastRenewal) > t
+// Size returns the size (in bytes) of the lease item in DynamoDB, as of the last time
+// it was read or written. See: Config.ItemSizeWarnThreshold.
+func (l *Lease) Size() int {
+	return l.size
+}
+
+// fieldKeys returns the names of all the extra fields of the lease object.
+func (l *Lease) fieldKeys() (keys []string) {
+	for k := range l.extrafields {
+		keys = append(keys, k)
+	}
+	for k := range l.explicitfields {
+		keys = append(keys, k)
+	}
+	return
+}
+
+// isExpired test if the lease renewal is expired from the given time, as of now.
+func (l *Lease) isExpired(t time.Duration, now time.Time) bool {
+	return now.Sub(l.lastRenewal) > t
+}
+
+// nextCounter returns the lease counter that follows the given one. It wraps around to 1,
+// instead of overflowing, since the writes are conditional on the counter being equal,
+// and not greater. 0 is skipped, as it's the counter of a lease that was never written.
+func nextCounter(counter int64) int64 {
+	if counter == math.MaxInt64 || counter < 0 {
+		return 1
+	}
+	return counter + 1
 }
 
 // hasNoOwner return true if the current owner is null.
@@ -172,13 +384,82 @@ func (l *Lease) hasNoOwner() bool {
 	return l.Owner == "NULL" || l.Owner == ""
 }
 
+// isTombstoned test if the lease was soft-deleted.
+func (l *Lease) isTombstoned() bool {
+	return !l.TombstonedAt.IsZero()
+}
+
+// isCompleted test if the work of the lease was completed.
+func (l *Lease) isCompleted() bool {
+	return !l.CompletedAt.IsZero()
+}
+
+// isReservedFor test if the lease has an active reservation that was made
+// by the given worker.
+func (l *Lease) isReservedFor(workerId string, now time.Time) bool {
+	return l.ReservedBy == workerId && now.Before(l.ReservedUntil)
+}
+
+// isReservedByOther test if the lease has an active reservation that was made
+// by a worker other than the given one.
+func (l *Lease) isReservedByOther(workerId string, now time.Time) bool {
+	return l.ReservedBy != "" && l.ReservedBy != workerId && now.Before(l.ReservedUntil)
+}
+
+// activeHolders returns the shared holders that renewed their hold within the
+// given duration, as of now.
+func (l *Lease) activeHolders(t time.Duration, now time.Time) map[string]int64 {
+	holders := make(map[string]int64)
+	for worker, renewed := range l.Holders {
+		if now.Sub(time.Unix(renewed, 0)) <= t {
+			holders[worker] = renewed
+		}
+	}
+	return holders
+}
+
+// isShared test if the lease has at least one active shared holder.
+func (l *Lease) isShared(t time.Duration, now time.Time) bool {
+	return len(l.activeHolders(t, now)) > 0
+}
+
+// isSemaphore test if the lease is a semaphore lease.
+func (l *Lease) isSemaphore() bool {
+	return l.MaxHolders > 0
+}
+
 // Leaser is the interface that wraps the Coordinator methods.
+// The given context of the methods that access the table cancels their in-flight
+// DynamoDB calls, and the retries between them.
 type Leaser interface {
 	Stop()
-	Start() error
-	Delete(Lease) error
-	Create(Lease) (Lease, error)
-	Update(Lease) (Lease, error)
-	ForceUpdate(Lease) (Lease, error)
+	Start(context.Context) error
+	Drain(context.Context) error
+	Delete(context.Context, Lease) error
+	Complete(context.Context, Lease) error
+	Reshard(context.Context, Lease, []Lease) ([]Lease, error)
+	Create(context.Context, Lease) (Lease, error)
+	EnsureLeases(context.Context, []string) (int, error)
+	Update(context.Context, Lease) (Lease, error)
+	Checkpoint(context.Context, Lease, string) (Lease, error)
+	ForceUpdate(context.Context, Lease) (Lease, error)
+	Reserve(context.Context, Lease, time.Time) (Lease, error)
+	AcquireShared(context.Context, Lease) (Lease, error)
+	ReleaseShared(context.Context, Lease) error
 	GetHeldLeases() []Lease
+	GetSharedLeases() []Lease
+	Migrate(context.Context) (int, error)
+	Reconfigure(Config) error
+	DebugHandler() http.Handler
+	ReportLoad(Lease, float64) error
+	ExpiresIn(Lease) (time.Duration, error)
+	Degraded() bool
+	Throttled() bool
+	Stats() Stats
+	Owner(ctx context.Context, key string) (OwnerInfo, error)
+	Workers() []WorkerInfo
+	WaitForOwnership(ctx context.Context, key string) (Lease, error)
+	Get(ctx context.Context, key string) (Lease, error)
+	Scope(Selector) *Scope
+	Preflight(context.Context) (Drift, error)
 }
@@ -0,0 +1,141 @@
+// Package record encodes leases to JSON, for the backends that store them as documents.
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/a8m/lease"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// record is the stored representation of a lease. times are stored in unix seconds, like
+// in the DynamoDB table.
+type record struct {
+	Key            string                   `json:"leaseKey"`
+	Owner          string                   `json:"leaseOwner"`
+	Counter        int64                    `json:"leaseCounter"`
+	Epoch          int                      `json:"leaseEpoch,omitempty"`
+	OwnerTier      int                      `json:"leaseOwnerTier,omitempty"`
+	OwnerVersion   string                   `json:"leaseOwnerVersion,omitempty"`
+	OwnerHost      string                   `json:"leaseOwnerHost,omitempty"`
+	OwnerZone      string                   `json:"leaseOwnerZone,omitempty"`
+	TakeoverReason lease.TakeoverReason     `json:"leaseTakeoverReason,omitempty"`
+	PreemptedBy    string                   `json:"leasePreemptedBy,omitempty"`
+	ReservedBy     string                   `json:"leaseReservedBy,omitempty"`
+	ReservedUntil  int64                    `json:"leaseReservedUntil,omitempty"`
+	Heartbeat      int64                    `json:"leaseHeartbeat,omitempty"`
+	Canary         bool                     `json:"leaseCanary,omitempty"`
+	Holders        map[string]int64         `json:"leaseHolders,omitempty"`
+	Group          string                   `json:"leaseGroup,omitempty"`
+	DependsOn      []string                 `json:"leaseDependsOn,omitempty"`
+	ParentKeys     []string                 `json:"leaseParentKeys,omitempty"`
+	Labels         map[string]string        `json:"leaseLabels,omitempty"`
+	MaxHolders     int                      `json:"leaseMaxHolders,omitempty"`
+	SchemaVersion  int                      `json:"leaseSchemaVersion,omitempty"`
+	TombstonedAt   int64                    `json:"leaseTombstonedAt,omitempty"`
+	CompletedAt    int64                    `json:"leaseCompletedAt,omitempty"`
+	LoadHint       float64                  `json:"leaseLoadHint,omitempty"`
+	Checkpoint     string                   `json:"leaseCheckpoint,omitempty"`
+	Payload        *dynamodb.AttributeValue `json:"leasePayload,omitempty"`
+	Fields         map[string]interface{}   `json:"leaseFields,omitempty"`
+}
+
+// Encode returns the stored representation of the given lease. the encoding of a decoded
+// lease is the same as the stored one, so it can be used as the condition of the writes.
+func Encode(l *lease.Lease) (string, error) {
+	r := record{
+		Key:            l.Key,
+		Owner:          l.Owner,
+		Counter:        l.Counter,
+		Epoch:          l.Epoch,
+		OwnerTier:      l.OwnerTier,
+		OwnerVersion:   l.OwnerVersion,
+		OwnerHost:      l.OwnerHost,
+		OwnerZone:      l.OwnerZone,
+		TakeoverReason: l.TakeoverReason,
+		PreemptedBy:    l.PreemptedBy,
+		ReservedBy:     l.ReservedBy,
+		ReservedUntil:  unix(l.ReservedUntil),
+		Heartbeat:      unix(l.Heartbeat),
+		Canary:         l.Canary,
+		Holders:        l.Holders,
+		Group:          l.Group,
+		DependsOn:      l.DependsOn,
+		ParentKeys:     l.ParentKeys,
+		Labels:         l.Labels,
+		MaxHolders:     l.MaxHolders,
+		SchemaVersion:  l.SchemaVersion,
+		TombstonedAt:   unix(l.TombstonedAt),
+		CompletedAt:    unix(l.CompletedAt),
+		LoadHint:       l.LoadHint,
+		Checkpoint:     l.Checkpoint,
+		Payload:        l.RawPayload(),
+	}
+	if fields := l.Fields(); len(fields) > 0 {
+		r.Fields = fields
+	}
+	b, err := json.Marshal(r)
+	return string(b), err
+}
+
+// Decode returns the lease of the given stored representation.
+func Decode(raw string) (*lease.Lease, error) {
+	var r record
+	dec := json.NewDecoder(bytes.NewBufferString(raw))
+	// keep the numbers of the extra fields as they are stored.
+	dec.UseNumber()
+	if err := dec.Decode(&r); err != nil {
+		return nil, errors.New("lease: decode record: " + err.Error())
+	}
+	l := &lease.Lease{
+		Key:            r.Key,
+		Owner:          r.Owner,
+		Counter:        r.Counter,
+		Epoch:          r.Epoch,
+		OwnerTier:      r.OwnerTier,
+		OwnerVersion:   r.OwnerVersion,
+		OwnerHost:      r.OwnerHost,
+		OwnerZone:      r.OwnerZone,
+		TakeoverReason: r.TakeoverReason,
+		PreemptedBy:    r.PreemptedBy,
+		ReservedBy:     r.ReservedBy,
+		ReservedUntil:  fromUnix(r.ReservedUntil),
+		Heartbeat:      fromUnix(r.Heartbeat),
+		Canary:         r.Canary,
+		Holders:        r.Holders,
+		Group:          r.Group,
+		DependsOn:      r.DependsOn,
+		ParentKeys:     r.ParentKeys,
+		Labels:         r.Labels,
+		MaxHolders:     r.MaxHolders,
+		SchemaVersion:  r.SchemaVersion,
+		TombstonedAt:   fromUnix(r.TombstonedAt),
+		CompletedAt:    fromUnix(r.CompletedAt),
+		LoadHint:       r.LoadHint,
+		Checkpoint:     r.Checkpoint,
+	}
+	for k, v := range r.Fields {
+		l.Set(k, v)
+	}
+	l.SetRawPayload(r.Payload)
+	return l, nil
+}
+
+// unix returns the given time in unix seconds, or 0 for the zero time.
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// fromUnix returns the time of the given unix seconds, or the zero time for 0.
+func fromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
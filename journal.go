@@ -0,0 +1,106 @@
+package lease
+
+import (
+	"sync"
+	"time"
+)
+
+// Outcome is the outcome of a single renewal or take of a lease. See: Stats.
+type Outcome struct {
+	Key string `json:"key"`
+	// Op is "renew" or "take".
+	Op string `json:"op"`
+	// At is the time the call started, and Latency is the time it took.
+	At      time.Time     `json:"at"`
+	Latency time.Duration `json:"latency"`
+	// Error is the error of the call, and it's empty if it succeeded.
+	Error string `json:"error,omitempty"`
+	// Late indicates that the renewal started after the lease could already be seen as
+	// expired by other workers, i.e: more than ExpireAfter since its last successful renewal.
+	Late bool `json:"late,omitempty"`
+}
+
+// Stats is a snapshot of the worker state. See: Leaser.Stats.
+type Stats struct {
+	WorkerId string `json:"workerId"`
+	Held     int    `json:"held"`
+	Shared   int    `json:"shared"`
+	Degraded bool   `json:"degraded"`
+	// Throttled indicates that the writes are throttled. See: Config.ThrottleWindow.
+	Throttled bool `json:"throttled"`
+	// Outcomes are the recent renewal and take outcomes, oldest first. See: Config.JournalSize.
+	Outcomes []Outcome `json:"outcomes"`
+}
+
+// journal is a ring buffer of the recent outcomes. A nil journal records nothing.
+type journal struct {
+	mu      sync.Mutex
+	entries []Outcome
+	// next is the position of the next entry, once the buffer is full.
+	next int
+	size int
+	// now is the clock of the latencies. see: Config.Clock.
+	now func() time.Time
+}
+
+// newJournal returns a journal that keeps the last n outcomes, and measures their
+// latencies with the given clock.
+func newJournal(n int, now func() time.Time) *journal {
+	return &journal{entries: make([]Outcome, 0, n), size: n, now: now}
+}
+
+// record records the outcome of the given operation, that started at the given time.
+func (j *journal) record(key, op string, start time.Time, err error, late bool) {
+	if j == nil || j.size == 0 {
+		return
+	}
+	o := Outcome{Key: key, Op: op, At: start, Latency: j.now().Sub(start), Late: late}
+	if err != nil {
+		o.Error = err.Error()
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.entries) < j.size {
+		j.entries = append(j.entries, o)
+		return
+	}
+	j.entries[j.next] = o
+	j.next = (j.next + 1) % j.size
+}
+
+// outcomes returns a copy of the recorded outcomes, oldest first.
+func (j *journal) outcomes() []Outcome {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	list := make([]Outcome, 0, len(j.entries))
+	list = append(list, j.entries[j.next:]...)
+	return append(list, j.entries[:j.next]...)
+}
+
+// isLate test if the given renewal of a held lease started after the lease could be
+// seen as expired.
+func (l *leaseHolder) isLate(key string, start time.Time) bool {
+	l.RLock()
+	defer l.RUnlock()
+	renewed, ok := l.renewedAt[key]
+	return ok && start.Sub(renewed) > l.expireAfter(key)
+}
+
+// Stats returns a snapshot of the worker state, with the recent renewal and take
+// outcomes of its leases. Use it after an incident, to see which renewals failed or
+// missed the ExpireAfter window, and why. See: Config.JournalSize and DebugHandler.
+func (c *Coordinator) Stats() Stats {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	return Stats{
+		WorkerId:  c.WorkerId,
+		Held:      len(c.Renewer.GetHeldLeases()),
+		Shared:    len(c.Renewer.GetSharedLeases()),
+		Degraded:  c.Renewer.Degraded(),
+		Throttled: c.throttle.active(),
+		Outcomes:  c.journal.outcomes(),
+	}
+}
@@ -0,0 +1,64 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	j := newJournal(3, time.Now)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		j.record(key, "renew", time.Now(), nil, false)
+	}
+	j.record("e", "take", time.Now(), errors.New("take failed"), false)
+	list := j.outcomes()
+	assert(t, len(list) == 3, "expect to keep the last outcomes")
+	assert(t, list[0].Key == "c" && list[1].Key == "d" && list[2].Key == "e", "expect the outcomes to be ordered, oldest first")
+	assert(t, list[2].Op == "take" && list[2].Error == "take failed", "expect the error to be recorded")
+
+	// the latency is measured by the given clock.
+	start := time.Unix(100, 0)
+	j = newJournal(1, func() time.Time { return start.Add(time.Second) })
+	j.record("a", "renew", start, nil, false)
+	assert(t, j.outcomes()[0].Latency == time.Second, "expect the latency to be measured by the clock")
+
+	var nj *journal
+	nj.record("a", "renew", time.Now(), nil, false)
+	assert(t, len(nj.outcomes()) == 0, "expect a nil journal to record nothing")
+}
+
+func TestRenewerJournal(t *testing.T) {
+	logger := testLogger()
+	foo := &Lease{Key: "foo", Owner: renewerId}
+	manager := newManagerMock(map[method]args{
+		methodList:  {[]*Lease{foo}, []*Lease{foo}},
+		methodRenew: {nil, errors.New("renew failed")},
+	})
+	config := &Config{WorkerId: renewerId, Logger: logger, ExpireAfter: 10 * time.Second}
+	holder := &leaseHolder{
+		Config:         config,
+		manager:        manager,
+		heldLeases:     make(map[string]*Lease),
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+		journal:        newJournal(10, time.Now),
+	}
+	holder.Renew(context.Background())
+	// the last successful renewal was before the window.
+	holder.markRenewed("foo", time.Now().Add(-time.Minute))
+	holder.Renew(context.Background())
+
+	c := &Coordinator{Config: config, Renewer: holder, journal: holder.journal}
+	w := httptest.NewRecorder()
+	c.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/?stats", nil))
+	var stats Stats
+	assert(t, json.Unmarshal(w.Body.Bytes(), &stats) == nil, "expect the debug handler to write the stats")
+	assert(t, len(stats.Outcomes) == 2, "expect the renewals to be recorded")
+	assert(t, stats.Outcomes[0].Error == "" && !stats.Outcomes[0].Late, "expect the first renewal to succeed on time")
+	assert(t, stats.Outcomes[1].Error == "renew failed" && stats.Outcomes[1].Late, "expect the second renewal to be late and failed")
+	assert(t, stats.Held == 1 && stats.WorkerId == renewerId, "expect the worker state")
+}
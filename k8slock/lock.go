@@ -0,0 +1,191 @@
+// Package k8slock implements the resourcelock.Interface of client-go on a lease of this
+// package, so a DynamoDB table backs a Kubernetes-style leader election, e.g: of workloads
+// that run outside of a cluster. For example:
+//
+//	lock := k8slock.New(&lease.Config{WorkerId: hostname, LeaseTable: "leader-election"}, "my-controller")
+//	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+//		Lock:          lock,
+//		LeaseDuration: 15 * time.Second,
+//		RenewDeadline: 10 * time.Second,
+//		RetryPeriod:   2 * time.Second,
+//		Callbacks:     callbacks,
+//	})
+//
+// The holder of the election is the owner of the lease, each renewal increments its
+// leaseCounter, and the leader transitions are its leaseEpoch. The writes are conditional
+// on the lease that was last read or written by the lock, like the resourceVersion of the
+// Kubernetes locks. The lease duration of the leader is stored in the DurationField extra
+// field, since the candidates wait for it before they take over. The acquire and the renew
+// times of the records are not stored, since the candidates measure the expiry of the leader
+// by their own clock.
+//
+// The lease is not renewed by a Coordinator. Store it in a table that has no coordinators,
+// or the takers of the table take it as an expired lease.
+package k8slock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/a8m/lease"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// DurationField is the extra field of the lease that stores the LeaseDurationSeconds of the
+// election record.
+const DurationField = "electionLeaseDurationSeconds"
+
+// resource is the resource of the NotFound and AlreadyExists errors of the lock.
+var resource = schema.GroupResource{Group: "lease", Resource: "leases"}
+
+// Lock is a resourcelock.Interface that stores the election record in a lease.
+type Lock struct {
+	Manager lease.Manager
+	// Name is the key of the lease of the election.
+	Name string
+	// Id is the identity of this candidate. It must be the WorkerId of the Manager.
+	Id     string
+	Logger lease.Logger
+
+	mu sync.Mutex
+	// lease is the lease, as of the last time it was read or written by the lock.
+	lease *lease.Lease
+}
+
+// New returns a Lock of the election with the given name, that is stored in the lease
+// table of the given config. The identity of the candidate is the WorkerId of the config.
+func New(config *lease.Config, name string) *Lock {
+	c := lease.NewFromConfig(config).(*lease.Coordinator)
+	return &Lock{Manager: c.Manager, Name: name, Id: c.WorkerId, Logger: c.Logger}
+}
+
+var _ resourcelock.Interface = (*Lock)(nil)
+
+// Get returns the election record, and its raw form that changes on every write.
+func (l *Lock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	stored, err := l.Manager.GetLease(ctx, l.Name)
+	if errors.Is(err, lease.ErrLeaseNotFound) {
+		return nil, nil, apierrors.NewNotFound(resource, l.Name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	l.mu.Lock()
+	l.lease = stored
+	l.mu.Unlock()
+	record := recordOf(stored)
+	raw, err := json.Marshal(rawRecord{LeaderElectionRecord: record, Counter: stored.Counter})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &record, raw, nil
+}
+
+// Create creates the lease of the election, held by the holder of the given record.
+func (l *Lock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	created := &lease.Lease{Key: l.Name, Owner: ler.HolderIdentity}
+	created.Set(DurationField, ler.LeaseDurationSeconds)
+	ok, err := l.Manager.EnsureLease(ctx, created)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apierrors.NewAlreadyExists(resource, l.Name)
+	}
+	l.mu.Lock()
+	l.lease = created
+	l.mu.Unlock()
+	return nil
+}
+
+// Update writes the given record, conditional on the lease that was last read or written
+// by the lock. The record of this candidate renews or takes the lease, and a record with
+// no holder (i.e: a release) evicts it.
+func (l *Lock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lease == nil {
+		return errors.New("k8slock: lease not initialized, call Get or Create first")
+	}
+	current := *l.lease
+	var err error
+	switch ler.HolderIdentity {
+	case "":
+		err = l.Manager.EvictLease(ctx, &current)
+	case current.Owner:
+		err = l.Manager.RenewLease(ctx, &current)
+	case l.Id:
+		err = l.Manager.TakeLease(ctx, &current)
+	default:
+		return fmt.Errorf("k8slock: cannot write the record of holder %s by candidate %s", ler.HolderIdentity, l.Id)
+	}
+	if err != nil {
+		return err
+	}
+	l.lease = &current
+	// the lease duration changes only with the configuration of the leader.
+	if ler.HolderIdentity != "" && ler.LeaseDurationSeconds != durationOf(&current) {
+		update := &lease.Lease{Key: current.Key}
+		update.Set(DurationField, ler.LeaseDurationSeconds)
+		updated, err := l.Manager.UpdateLease(ctx, update)
+		if err != nil {
+			return err
+		}
+		l.lease = updated
+	}
+	return nil
+}
+
+// RecordEvent logs the given election event, e.g: "became leader".
+func (l *Lock) RecordEvent(s string) {
+	if l.Logger != nil {
+		l.Logger.Infof("leader election %s: %s %s", l.Name, l.Id, s)
+	}
+}
+
+// Identity returns the identity of this candidate.
+func (l *Lock) Identity() string {
+	return l.Id
+}
+
+// Describe returns the name of the election.
+func (l *Lock) Describe() string {
+	return l.Name
+}
+
+// rawRecord is the raw form of a record. It includes the counter of the lease, so it
+// changes on every renewal.
+type rawRecord struct {
+	resourcelock.LeaderElectionRecord
+	Counter int64 `json:"counter"`
+}
+
+// recordOf returns the election record of the given lease.
+func recordOf(stored *lease.Lease) resourcelock.LeaderElectionRecord {
+	record := resourcelock.LeaderElectionRecord{LeaderTransitions: stored.Epoch}
+	if stored.Owner != "NULL" {
+		record.HolderIdentity = stored.Owner
+		record.LeaseDurationSeconds = durationOf(stored)
+	}
+	return record
+}
+
+// durationOf returns the lease duration that is stored in the given lease, or 0 if it has
+// none. the extra fields are decoded as float64, unless they are read from a local copy.
+func durationOf(stored *lease.Lease) int {
+	v, _ := stored.Get(DurationField)
+	switch d := v.(type) {
+	case int:
+		return d
+	case int64:
+		return int(d)
+	case float64:
+		return int(d)
+	}
+	return 0
+}
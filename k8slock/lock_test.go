@@ -0,0 +1,138 @@
+package k8slock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/a8m/lease"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+func newTestLock(backend lease.Backend, id string) *Lock {
+	return New(&lease.Config{WorkerId: id, LeaseTable: "election", Backend: backend}, "leader")
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	backend := lease.NewMemoryBackend()
+	l1, l2 := newTestLock(backend, "1"), newTestLock(backend, "2")
+
+	if _, _, err := l1.Get(ctx); !apierrors.IsNotFound(err) {
+		t.Fatalf("expect a missing election to be not found, got: %v", err)
+	}
+	if err := l1.Create(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "1", LeaseDurationSeconds: 15}); err != nil {
+		t.Fatalf("expect the election to be created: %v", err)
+	}
+	if err := l2.Create(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "2"}); !apierrors.IsAlreadyExists(err) {
+		t.Errorf("expect an existing election not to be re-created, got: %v", err)
+	}
+	record, raw, err := l2.Get(ctx)
+	if err != nil || record.HolderIdentity != "1" || record.LeaseDurationSeconds != 15 {
+		t.Fatalf("expect the record of the leader, got: %+v, %v", record, err)
+	}
+
+	// the renewals are conditional on the lease the lock last wrote, and change the raw record.
+	if err := l1.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "1"}); err != nil {
+		t.Fatalf("expect the leader to renew: %v", err)
+	}
+	if _, renewed, _ := l2.Get(ctx); string(renewed) == string(raw) {
+		t.Error("expect the raw record to change on renewal")
+	}
+	if err := l2.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "2", LeaseDurationSeconds: 20}); err != nil {
+		t.Fatalf("expect the candidate to take over the observed record: %v", err)
+	}
+	if err := l1.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "1"}); err == nil {
+		t.Error("expect the renewal of the previous leader to fail the condition")
+	}
+	record, _, _ = l1.Get(ctx)
+	if record.HolderIdentity != "2" || record.LeaderTransitions != 1 || record.LeaseDurationSeconds != 20 {
+		t.Errorf("expect the new leader to be recorded with a transition, got: %+v", record)
+	}
+	if err := l2.Update(ctx, resourcelock.LeaderElectionRecord{}); err != nil {
+		t.Fatalf("expect the leader to release the election: %v", err)
+	}
+	if record, _, _ = l1.Get(ctx); record.HolderIdentity != "" {
+		t.Errorf("expect the released election to have no holder, got: %+v", record)
+	}
+}
+
+func TestLeaderElection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	started := make(chan string, 2)
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          newTestLock(lease.NewMemoryBackend(), "1"),
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   100 * time.Millisecond,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { started <- "1" },
+			OnStoppedLeading: func() {},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go le.Run(ctx)
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("expect the candidate to become the leader")
+	}
+	if !le.IsLeader() {
+		t.Error("expect the elector to report the leadership")
+	}
+}
+
+func TestLeaderElectionCandidates(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	backend := lease.NewMemoryBackend()
+	started := make(chan string, 2)
+	elector := func(id string) *leaderelection.LeaderElector {
+		le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            newTestLock(backend, id),
+			LeaseDuration:   time.Second,
+			RenewDeadline:   500 * time.Millisecond,
+			RetryPeriod:     100 * time.Millisecond,
+			ReleaseOnCancel: true,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(context.Context) { started <- id },
+				OnStoppedLeading: func() {},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return le
+	}
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	go elector("1").Run(ctx1)
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("expect the first candidate to become the leader")
+	}
+	go elector("2").Run(ctx)
+
+	// the second candidate waits for the stored lease duration of the renewing leader.
+	select {
+	case id := <-started:
+		t.Fatalf("expect candidate %s not to take over a renewed election", id)
+	case <-time.After(3 * time.Second):
+	}
+
+	cancel1()
+	select {
+	case id := <-started:
+		if id != "2" {
+			t.Errorf("expect the second candidate to become the leader, got: %s", id)
+		}
+	case <-ctx.Done():
+		t.Fatal("expect the second candidate to take over a released election")
+	}
+}
@@ -0,0 +1,245 @@
+// Package kafkalease assigns the partitions of Kafka topics to the workers by leases,
+// instead of by the group protocol of a consumer group. Each partition has a lease, and a
+// worker consumes the partitions of the leases it holds; a partition is resumed when its
+// lease is held, and paused when it's lost or released. The committed offsets are stored
+// as the checkpoints of the leases (see: lease.Lease.Checkpoint), so the new owner of a
+// partition continues from the offset of the previous one.
+//
+// The package is not tied to a Kafka client. The callbacks map to the pause and resume
+// of the client that is used, e.g: PauseFetchPartitions and ResumeFetchPartitions of
+// github.com/twmb/franz-go, with the partitions assigned manually (i.e: no group).
+//
+//	a, err := kafkalease.New(leaser, kafkalease.Callbacks{
+//		Resume: func(p kafkalease.Partition) { resume <- p },
+//		Pause:  func(p kafkalease.Partition) { pause <- p },
+//	})
+//	_, err = a.EnsurePartitions(ctx, "orders", 12)
+package kafkalease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/a8m/lease"
+)
+
+// KeyPrefix is the prefix of the keys of the partition leases.
+const KeyPrefix = "kafka/"
+
+var (
+	// ErrScopeUnsupported error will be returns if the Assignor is created with a Leaser
+	// that does not call the callbacks of its scopes (see: lease.Scope.Err).
+	ErrScopeUnsupported = errors.New("kafkalease: leaser does not support scopes")
+
+	// ErrNoOffset error will be returns if the partition has no committed offset, i.e: the
+	// consumer starts from the offset of its reset policy.
+	ErrNoOffset = errors.New("kafkalease: partition has no committed offset")
+)
+
+// Partition is a partition of a Kafka topic.
+type Partition struct {
+	Topic     string
+	Partition int32
+}
+
+// Key returns the lease key of the partition, e.g: "kafka/orders/3". The names of Kafka
+// topics cannot contain a slash.
+func (p Partition) Key() string {
+	return KeyPrefix + p.Topic + "/" + strconv.FormatInt(int64(p.Partition), 10)
+}
+
+// String returns the partition in the form of "topic/partition".
+func (p Partition) String() string {
+	return strings.TrimPrefix(p.Key(), KeyPrefix)
+}
+
+// ParseKey returns the partition of the given lease key, and false if it's not the key
+// of a partition lease.
+func ParseKey(key string) (Partition, bool) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return Partition{}, false
+	}
+	i := strings.LastIndexByte(key, '/')
+	n, err := strconv.ParseInt(key[i+1:], 10, 32)
+	if i < len(KeyPrefix) || err != nil {
+		return Partition{}, false
+	}
+	return Partition{Topic: key[len(KeyPrefix):i], Partition: int32(n)}, true
+}
+
+// Callbacks are called on the changes of the partition leases of this worker. They are
+// called from the loops of the Leaser, and should not block (e.g: send the partition to
+// the consumer loop).
+type Callbacks struct {
+	// Resume is called when this worker holds the lease of a partition, to resume its
+	// consumption from the committed offset (see: Assignor.Offset). It's called once the
+	// taken lease is picked up by the renewer (see: lease.Leaser.WaitForOwnership), so
+	// the partition is listed in Assigned, and its offsets can be committed. It's not
+	// called if the lease is lost before that.
+	Resume func(Partition)
+	// Pause is called when this worker loses or releases the lease of a partition, to
+	// stop its consumption.
+	Pause func(Partition)
+}
+
+// Assignor assigns the partitions to this worker by the partition leases of the Leaser.
+type Assignor struct {
+	Callbacks
+	leaser lease.Leaser
+	scope  *lease.Scope
+
+	// pending holds the cancel functions of the partitions that were taken, and wait to
+	// be held. see: resume.
+	mu      sync.Mutex
+	pending map[Partition]context.CancelFunc
+}
+
+// New returns an Assignor of the partition leases of the given leaser. The callbacks
+// are registered on the leaser, so create it before the leaser is started.
+func New(leaser lease.Leaser, callbacks Callbacks) (*Assignor, error) {
+	scope := leaser.Scope(lease.Selector{Prefix: KeyPrefix})
+	if scope == nil {
+		return nil, ErrScopeUnsupported
+	}
+	if err := scope.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScopeUnsupported, err)
+	}
+	a := &Assignor{Callbacks: callbacks, leaser: leaser, scope: scope, pending: make(map[Partition]context.CancelFunc)}
+	scope.OnOwnerChange(a.ownerChange)
+	return a, nil
+}
+
+// Close stops handling the partition leases, and releases the scope of the assignor
+// on the leaser. The leases are still held by the leaser until it's stopped.
+func (a *Assignor) Close() {
+	a.scope.Close()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for p, cancel := range a.pending {
+		cancel()
+		delete(a.pending, p)
+	}
+}
+
+// EnsurePartitions creates the leases of the partitions of the given topic that do not
+// exist, and returns the number of leases that were created. Call it again with the new
+// number of partitions when partitions are added to the topic. See: lease.Leaser.EnsureLeases.
+func (a *Assignor) EnsurePartitions(ctx context.Context, topic string, partitions int32) (int, error) {
+	keys := make([]string, partitions)
+	for i := range keys {
+		keys[i] = Partition{Topic: topic, Partition: int32(i)}.Key()
+	}
+	return a.leaser.EnsureLeases(ctx, keys)
+}
+
+// Assigned returns the partitions that are currently assigned to this worker, sorted by
+// topic and partition.
+func (a *Assignor) Assigned() []Partition {
+	var list []Partition
+	for _, l := range a.scope.GetHeldLeases() {
+		if p, ok := ParseKey(l.Key); ok {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Topic != list[j].Topic {
+			return list[i].Topic < list[j].Topic
+		}
+		return list[i].Partition < list[j].Partition
+	})
+	return list
+}
+
+// Offset returns the committed offset of the given partition, as it's stored in the
+// checkpoint of its lease. Fails with ErrNoOffset if the partition has no committed offset.
+func (a *Assignor) Offset(ctx context.Context, p Partition) (int64, error) {
+	l, err := a.leaser.Get(ctx, p.Key())
+	if err != nil {
+		return 0, err
+	}
+	if l.Checkpoint == "" {
+		return 0, ErrNoOffset
+	}
+	offset, err := strconv.ParseInt(l.Checkpoint, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("kafkalease: invalid offset of partition %s: %w", p, err)
+	}
+	return offset, nil
+}
+
+// Commit stores the given offset as the checkpoint of the lease of the given partition.
+// Fails with lease.ErrLeaseNotHeld if the partition is not assigned to this worker, and
+// like lease.Leaser.Checkpoint if the lease was taken by another worker in the meantime.
+func (a *Assignor) Commit(ctx context.Context, p Partition, offset int64) error {
+	l, ok := a.held(p)
+	if !ok {
+		return lease.ErrLeaseNotHeld
+	}
+	_, err := a.leaser.Checkpoint(ctx, l, strconv.FormatInt(offset, 10))
+	return err
+}
+
+// held returns the held lease of the given partition.
+func (a *Assignor) held(p Partition) (lease.Lease, bool) {
+	key := p.Key()
+	for _, l := range a.scope.GetHeldLeases() {
+		if l.Key == key {
+			return l, true
+		}
+	}
+	return lease.Lease{}, false
+}
+
+// ownerChange calls the callbacks of the partitions that were acquired, lost or released.
+func (a *Assignor) ownerChange(change lease.OwnerChange) {
+	p, ok := ParseKey(change.Key)
+	if !ok {
+		return
+	}
+	switch change.Type {
+	case lease.OwnerAcquired:
+		ctx, cancel := context.WithCancel(context.Background())
+		a.mu.Lock()
+		if prev, ok := a.pending[p]; ok {
+			prev()
+		}
+		a.pending[p] = cancel
+		a.mu.Unlock()
+		go a.resume(ctx, p)
+	case lease.OwnerLost, lease.OwnerReleased:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		// the partition was not resumed yet.
+		if cancel, ok := a.pending[p]; ok {
+			delete(a.pending, p)
+			cancel()
+			return
+		}
+		if a.Pause != nil {
+			a.Pause(p)
+		}
+	}
+}
+
+// resume calls the Resume callback of the given taken partition once its lease is held,
+// unless it's lost or released before that (i.e: ctx is canceled).
+func (a *Assignor) resume(ctx context.Context, p Partition) {
+	if _, err := a.leaser.WaitForOwnership(ctx, p.Key()); err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	a.pending[p]()
+	delete(a.pending, p)
+	if a.Resume != nil {
+		a.Resume(p)
+	}
+}
@@ -0,0 +1,86 @@
+package kafkalease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/a8m/lease"
+)
+
+func TestParseKey(t *testing.T) {
+	p := Partition{Topic: "orders.v1", Partition: 3}
+	if key := p.Key(); key != "kafka/orders.v1/3" {
+		t.Fatalf("expect the key of the partition, got: %s", key)
+	}
+	if parsed, ok := ParseKey(p.Key()); !ok || parsed != p {
+		t.Errorf("expect the key to be parsed to the partition, got: %v", parsed)
+	}
+	for _, key := range []string{"orders/3", "kafka/3", "kafka/orders/x", "kafka/orders/"} {
+		if _, ok := ParseKey(key); ok {
+			t.Errorf("expect %s not to be the key of a partition", key)
+		}
+	}
+}
+
+func TestAssignor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	leaser := lease.NewFromConfig(&lease.Config{
+		WorkerId:   "1",
+		LeaseTable: "partitions",
+		Backend:    lease.NewMemoryBackend(),
+	})
+	resumed, paused := make(chan Partition, 4), make(chan Partition, 4)
+	a, err := New(leaser, Callbacks{
+		Resume: func(p Partition) { resumed <- p },
+		Pause:  func(p Partition) { paused <- p },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := a.EnsurePartitions(ctx, "orders", 2); err != nil || n != 2 {
+		t.Fatalf("expect the partition leases to be created, got: %d, %v", n, err)
+	}
+	if _, err := leaser.Create(ctx, lease.NewLease("other")); err != nil {
+		t.Fatal(err)
+	}
+	if err := leaser.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer leaser.Stop()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			t.Fatal("expect the partitions to be resumed")
+		}
+	}
+	// the partitions are resumed once their leases are held.
+	if list := a.Assigned(); len(list) != 2 || list[0].Partition != 0 || list[1].Partition != 1 {
+		t.Fatalf("expect only the partitions to be assigned, got: %v", list)
+	}
+
+	p := Partition{Topic: "orders", Partition: 1}
+	if _, err := a.Offset(ctx, p); !errors.Is(err, ErrNoOffset) {
+		t.Errorf("expect a new partition to have no committed offset, got: %v", err)
+	}
+	if err := a.Commit(ctx, p, 1<<53+1); err != nil {
+		t.Fatalf("expect the offset to be committed: %v", err)
+	}
+	if offset, err := a.Offset(ctx, p); err != nil || offset != 1<<53+1 {
+		t.Errorf("expect the committed offset, got: %d, %v", offset, err)
+	}
+	if err := a.Commit(ctx, Partition{Topic: "orders", Partition: 5}, 1); !errors.Is(err, lease.ErrLeaseNotHeld) {
+		t.Errorf("expect the commit of an unassigned partition to fail, got: %v", err)
+	}
+
+	if err := leaser.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(paused) != 2 {
+		t.Errorf("expect the released partitions to be paused, got: %d", len(paused))
+	}
+}
@@ -0,0 +1,96 @@
+package lease
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// kclKeys are the attributes of the KCL lease schema. they belong to this package only
+// with Config.KCLSchema; otherwise, they are extra fields like any other.
+var kclKeys = []string{
+	KCLCheckpointKey,
+	KCLCheckpointSubSequenceNumberKey,
+	KCLOwnerSwitchesSinceCheckpointKey,
+	KCLParentShardIdKey,
+}
+
+// kclShardEnd is the checkpoint of a shard that was processed to its end.
+const kclShardEnd = "SHARD_END"
+
+// isKCL test if the given attribute name is one of the KCL attributes.
+func isKCL(name string) bool {
+	for _, k := range kclKeys {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCheckpoint sets the checkpoint of the lease, and resets its KCL owner switches, to be
+// written on the next Update. The sub-sequence number is written only with Config.KCLSchema.
+// See: Leaser.Checkpoint.
+//
+//	lease.SetCheckpoint(record.SequenceNumber, 0)
+//	lease, err = leaser.Update(ctx, lease)
+func (l *Lease) SetCheckpoint(checkpoint string, subSequenceNumber int64) {
+	l.Checkpoint = checkpoint
+	l.CheckpointSubSequenceNumber = subSequenceNumber
+	l.OwnerSwitchesSinceCheckpoint = 0
+	l.checkpointed = true
+}
+
+// decodeKCL reads the KCL attributes of the given item to the lease. a lease that
+// has no owner attribute is not held (i.e: "NULL").
+func decodeKCL(lease *Lease, item map[string]*dynamodb.AttributeValue) {
+	if lease.Owner == "" {
+		lease.Owner = "NULL"
+	}
+	if v := item[KCLCheckpointKey]; v != nil {
+		lease.Checkpoint = aws.StringValue(v.S)
+	}
+	if v := item[KCLCheckpointSubSequenceNumberKey]; v != nil {
+		lease.CheckpointSubSequenceNumber, _ = strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+	}
+	if v := item[KCLOwnerSwitchesSinceCheckpointKey]; v != nil {
+		lease.OwnerSwitchesSinceCheckpoint, _ = strconv.Atoi(aws.StringValue(v.N))
+	}
+	if v := item[KCLParentShardIdKey]; v != nil {
+		lease.ParentShardIds = aws.StringValueSlice(v.SS)
+	}
+}
+
+// encodeKCL writes the KCL attributes of the lease to the given item, and removes its
+// owner attribute if it's not held.
+func encodeKCL(lease *Lease, item map[string]*dynamodb.AttributeValue) {
+	if lease.hasNoOwner() {
+		delete(item, LeaseOwnerKey)
+	}
+	if lease.Checkpoint != "" {
+		item[KCLCheckpointKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.Checkpoint),
+		}
+	}
+	item[KCLCheckpointSubSequenceNumberKey] = &dynamodb.AttributeValue{
+		N: aws.String(strconv.FormatInt(lease.CheckpointSubSequenceNumber, 10)),
+	}
+	item[KCLOwnerSwitchesSinceCheckpointKey] = &dynamodb.AttributeValue{
+		N: aws.String(strconv.Itoa(lease.OwnerSwitchesSinceCheckpoint)),
+	}
+	if len(lease.ParentShardIds) > 0 {
+		item[KCLParentShardIdKey] = &dynamodb.AttributeValue{
+			SS: aws.StringSlice(lease.ParentShardIds),
+		}
+	}
+}
+
+// ownerCondition returns the condition expression that the #owner attribute is the given
+// owner (i.e: :condOwner). the KCL leases that are not held have no owner attribute.
+func (l *LeaseManager) ownerCondition(owner string) string {
+	if l.KCLSchema && (owner == "NULL" || owner == "") {
+		return "(attribute_not_exists(#owner) OR #owner = :condOwner)"
+	}
+	return "#owner = :condOwner"
+}
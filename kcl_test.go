@@ -0,0 +1,68 @@
+package lease
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestSerializerKCLSchema(t *testing.T) {
+	s := newSerializer(&Config{KCLSchema: true})
+	// a lease of a Java KCL worker that is not held.
+	lease, err := s.Decode(map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:                        {S: aws.String("shardId-000000000002")},
+		LeaseCounterKey:                    {N: aws.String("7")},
+		KCLCheckpointKey:                   {S: aws.String("49590338271490256608559692538361571095921575989136588898")},
+		KCLCheckpointSubSequenceNumberKey:  {N: aws.String("3")},
+		KCLOwnerSwitchesSinceCheckpointKey: {N: aws.String("2")},
+		KCLParentShardIdKey:                {SS: aws.StringSlice([]string{"shardId-000000000000"})},
+		"status":                           {S: aws.String("ok")},
+	})
+	assert(t, err == nil, "expect Decode not to fail")
+	assert(t, lease.Owner == "NULL" && lease.Counter == 7, "expect a lease with no owner attribute not to be held")
+	assert(t, lease.Checkpoint == "49590338271490256608559692538361571095921575989136588898" && lease.CheckpointSubSequenceNumber == 3, "expect the checkpoint to be decoded")
+	assert(t, lease.OwnerSwitchesSinceCheckpoint == 2 && len(lease.ParentShardIds) == 1, "expect the KCL attributes to be decoded")
+	_, ok := lease.Get(KCLCheckpointKey)
+	v, _ := lease.Get("status")
+	assert(t, !ok && v == "ok", "expect only the non KCL attributes to be extra fields")
+
+	item, err := s.Encode(lease)
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, item[LeaseOwnerKey] == nil, "expect a lease that is not held to have no owner attribute")
+	assert(t, aws.StringValue(item[KCLCheckpointKey].S) == lease.Checkpoint && aws.StringValue(item[KCLOwnerSwitchesSinceCheckpointKey].N) == "2", "expect the KCL attributes to be encoded")
+	assert(t, len(item[KCLParentShardIdKey].SS) == 1, "expect the parent shards to be a string set")
+
+	lease.SetCheckpoint("SHARD_END", 0)
+	assert(t, lease.checkpointed && lease.OwnerSwitchesSinceCheckpoint == 0, "expect a checkpoint to reset the owner switches")
+
+	// without the KCL schema, the KCL attributes are extra fields.
+	lease, err = newSerializer(&Config{}).Decode(map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:      {S: aws.String("foo")},
+		KCLCheckpointKey: {S: aws.String("TRIM_HORIZON")},
+	})
+	v, _ = lease.Get(KCLCheckpointKey)
+	assert(t, err == nil && lease.Checkpoint == "" && v == "TRIM_HORIZON", "expect the checkpoint to be an extra field")
+}
+
+func TestTakeKCLLease(t *testing.T) {
+	manager := newTestManager(newClientMock(nil))
+	manager.KCLSchema = true
+	lease := &Lease{Key: "shardId-000000000002", Counter: 7, Owner: "NULL", OwnerSwitchesSinceCheckpoint: 2}
+	taken := manager.takenLease(lease)
+	assert(t, taken.OwnerSwitchesSinceCheckpoint == 3, "expect the owner switches to be incremented on take")
+	input := manager.condUpdateInput(taken, *lease)
+	assert(t, strings.Contains(aws.StringValue(input.UpdateExpression), KCLOwnerSwitchesSinceCheckpointKey+" = :switches"), "expect the owner switches to be persisted")
+	assert(t, strings.Contains(aws.StringValue(input.ConditionExpression), "attribute_not_exists(#owner)"), "expect a lease with no owner attribute to be taken")
+
+	renewed := taken
+	renewed.Counter++
+	assert(t, manager.takenLease(&renewed).OwnerSwitchesSinceCheckpoint == 3, "expect the owner switches to be kept on re-take")
+
+	evicted := taken
+	evicted.Owner = "NULL"
+	input = manager.condUpdateInput(evicted, taken)
+	exp := aws.StringValue(input.UpdateExpression)
+	assert(t, strings.Contains(exp, "REMOVE "+LeaseOwnerKey) && input.ExpressionAttributeValues[":owner"] == nil, "expect the owner attribute to be removed on eviction")
+}
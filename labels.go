@@ -0,0 +1,171 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ListLeasesBySelector returns the leases that match the given selector. The leases are
+// filtered by their key prefix and their labels (see: Lease.Labels) in DynamoDB, so only
+// the matching leases are returned by the scan. The labels that are not set on a lease are
+// matched against its extra fields after the scan, like Selector.Matches.
+// The scan is eventually consistent, like the scans of ListLeases. With a partition (see:
+// Config.Partition), the key prefix is queried, since the lease key is the sort key of
+// the table.
+func (l *LeaseManager) ListLeasesBySelector(ctx context.Context, selector Selector) ([]*Lease, error) {
+	var (
+		list  []*Lease
+		err   error
+		res   *dynamodb.ScanOutput
+		query *dynamodb.QueryInput
+		input = selector.scanInput(l.LeaseTable)
+	)
+	if l.Partition != "" && selector.Prefix != "" {
+		query = selector.queryInput(l.LeaseTable)
+	}
+	r := l.retrier(RetryList)
+	for r.more() {
+		res, err = l.selectLeases(ctx, input, query)
+		if err != nil {
+			// permanent errors return immediately.
+			if !r.retryable(err) {
+				break
+			}
+
+			backoff := r.delay()
+
+			l.Logger.WithFields(Fields{
+				"backoff": backoff,
+				"attempt": r.attempt,
+			}).Warnf("Worker %s failed to scan leases table", l.WorkerId)
+
+			if serr := l.sleep(ctx, backoff); serr != nil {
+				err = serr
+				break
+			}
+			continue
+		}
+		for _, item := range res.Items {
+			if k := item[LeaseKeyKey]; k != nil && isInternalItem(aws.StringValue(k.S)) {
+				continue
+			}
+			if lease, err := l.Serializer.Decode(item); err != nil {
+				l.Logger.WithError(err).Errorf("decode lease")
+			} else if selector.Matches(*lease) {
+				list = append(list, lease)
+			}
+		}
+		if len(res.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
+	if err = l.retryError(r, "", err); err != nil {
+		return nil, l.wrapError("list by selector", "", err)
+	}
+	return list, nil
+}
+
+// selectLeases scans a page of the given scan input, or queries it from the given query
+// input, if any.
+func (l *LeaseManager) selectLeases(ctx context.Context, input *dynamodb.ScanInput, query *dynamodb.QueryInput) (*dynamodb.ScanOutput, error) {
+	if query == nil {
+		return l.readClient().ScanWithContext(ctx, input)
+	}
+	client, ok := l.readClient().(queryClient)
+	if !ok {
+		return nil, errors.New("leaser: client does not support queries")
+	}
+	query.ExclusiveStartKey = input.ExclusiveStartKey
+	out, err := client.QueryWithContext(ctx, query)
+	if out == nil {
+		return nil, err
+	}
+	return &dynamodb.ScanOutput{
+		Items:            out.Items,
+		Count:            out.Count,
+		ScannedCount:     out.ScannedCount,
+		LastEvaluatedKey: out.LastEvaluatedKey,
+	}, err
+}
+
+// scanInput returns the scan of the given table, filtered by the selector. a label filters
+// out only the leases that have a different value for it, since it may be matched by an
+// extra field as well.
+func (s Selector) scanInput(table string) *dynamodb.ScanInput {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+	}
+	var (
+		names  = make(map[string]*string)
+		values = make(map[string]*dynamodb.AttributeValue)
+	)
+	filters := s.labelFilters(names, values)
+	if s.Prefix != "" {
+		filters = append([]string{"begins_with(#key, :prefix)"}, filters...)
+		names["#key"] = aws.String(LeaseKeyKey)
+		values[":prefix"] = &dynamodb.AttributeValue{S: aws.String(s.Prefix)}
+	}
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+		input.ExpressionAttributeNames = names
+		input.ExpressionAttributeValues = values
+	}
+	return input
+}
+
+// queryInput returns the query of the given partitioned table, with the key prefix of the
+// selector as a key condition, since the sort key can't be filtered in queries. the
+// partition key condition is added by the attributeClient.
+func (s Selector) queryInput(table string) *dynamodb.QueryInput {
+	var (
+		names  = map[string]*string{"#key": aws.String(LeaseKeyKey)}
+		values = map[string]*dynamodb.AttributeValue{":prefix": {S: aws.String(s.Prefix)}}
+	)
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(table),
+		KeyConditionExpression:    aws.String("begins_with(#key, :prefix)"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}
+	if filters := s.labelFilters(names, values); len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+	return input
+}
+
+// labelFilters returns the filters of the selector labels, and adds their names and
+// values to the given expression attributes.
+func (s Selector) labelFilters(names map[string]*string, values map[string]*dynamodb.AttributeValue) []string {
+	var filters []string
+	labels := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		labels = append(labels, k)
+	}
+	sort.Strings(labels)
+	for i, k := range labels {
+		label := fmt.Sprintf("#labels.#l%d", i)
+		filters = append(filters, fmt.Sprintf("(attribute_not_exists(%s) OR %s = :l%d)", label, label, i))
+		names["#labels"] = aws.String(LeaseLabelsKey)
+		names[fmt.Sprintf("#l%d", i)] = aws.String(k)
+		values[fmt.Sprintf(":l%d", i)] = &dynamodb.AttributeValue{S: aws.String(s.Labels[k])}
+	}
+	return filters
+}
+
+// leasesOfSelector returns the leases in the given list that match the given selector.
+func leasesOfSelector(list []*Lease, selector Selector) []*Lease {
+	var selected []*Lease
+	for _, lease := range list {
+		if selector.Matches(*lease) {
+			selected = append(selected, lease)
+		}
+	}
+	return selected
+}
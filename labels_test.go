@@ -0,0 +1,77 @@
+package lease
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestSelectorLabels(t *testing.T) {
+	lease := Lease{Key: "orders/1", Labels: map[string]string{"region": "eu"}}
+	lease.Set("tier", 2)
+	assert(t, Selector{Labels: map[string]string{"region": "eu"}}.Matches(lease), "expect the lease labels to be matched")
+	assert(t, !Selector{Labels: map[string]string{"region": "us"}}.Matches(lease), "expect a different label not to be matched")
+	assert(t, Selector{Labels: map[string]string{"region": "eu", "tier": "2"}}.Matches(lease), "expect the extra fields to be matched without a label")
+
+	lease.Set("region", "us")
+	assert(t, Selector{Labels: map[string]string{"region": "eu"}}.Matches(lease), "expect the label to take precedence over the extra field")
+}
+
+func TestSelectorScanInput(t *testing.T) {
+	input := Selector{}.scanInput("test")
+	assert(t, input.FilterExpression == nil, "expect no filter for an empty selector")
+
+	input = Selector{Prefix: "orders/", Labels: map[string]string{"region": "eu", "app": "billing"}}.scanInput("test")
+	assert(t, *input.FilterExpression == "begins_with(#key, :prefix) AND (attribute_not_exists(#labels.#l0) OR #labels.#l0 = :l0) AND (attribute_not_exists(#labels.#l1) OR #labels.#l1 = :l1)", "expect the prefix and the labels to be filtered")
+	assert(t, *input.ExpressionAttributeNames["#l0"] == "app" && *input.ExpressionAttributeValues[":l0"].S == "billing", "expect the labels to be sorted")
+	assert(t, *input.ExpressionAttributeNames["#labels"] == LeaseLabelsKey, "expect the labels attribute")
+}
+
+func TestListLeasesBySelector(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodScan: {
+			&dynamodb.ScanOutput{
+				Items: []map[string]*dynamodb.AttributeValue{
+					{"leaseKey": {S: aws.String("orders/1")}, LeaseLabelsKey: {M: map[string]*dynamodb.AttributeValue{"region": {S: aws.String("eu")}}}},
+					{"leaseKey": {S: aws.String("orders/2")}, "region": {S: aws.String("eu")}},
+				},
+				LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"leaseKey": {S: aws.String("orders/2")}},
+			},
+			&dynamodb.ScanOutput{
+				Items: []map[string]*dynamodb.AttributeValue{
+					{"leaseKey": {S: aws.String("orders/3")}, "region": {S: aws.String("us")}},
+				},
+			},
+		},
+	})
+	manager := newTestManager(client)
+	leases, err := manager.ListLeasesBySelector(context.Background(), Selector{Prefix: "orders/", Labels: map[string]string{"region": "eu"}})
+	assert(t, err == nil && client.calls[methodScan] == 2, "expect to scan all the pages")
+	assert(t, len(leases) == 2 && leases[0].Labels["region"] == "eu", "expect the leases to be filtered by the selector")
+
+	item, err := manager.Serializer.Encode(leases[0])
+	assert(t, err == nil && aws.StringValue(item[LeaseLabelsKey].M["region"].S) == "eu", "expect the labels to be encoded")
+}
+
+func TestListLeasesBySelectorPartition(t *testing.T) {
+	mock := &queryMock{
+		clientMock: newClientMock(nil),
+		pages: []*dynamodb.QueryOutput{
+			{Items: []map[string]*dynamodb.AttributeValue{
+				{LeasePartitionKey: {S: aws.String("app")}, LeaseKeyKey: {S: aws.String("orders/1")}, "region": {S: aws.String("eu")}},
+			}},
+		},
+	}
+	client := newAttributeClient(mock, AttributeNames{Key: LeaseKeyKey, Owner: LeaseOwnerKey, Counter: LeaseCounterKey}, LeasePartitionKey, "app")
+	manager := newTestManager(client)
+	manager.Partition = "app"
+
+	leases, err := manager.ListLeasesBySelector(context.Background(), Selector{Prefix: "orders/", Labels: map[string]string{"region": "eu"}})
+	assert(t, err == nil && len(leases) == 1, "expect to list the leases of the partition")
+	assert(t, mock.calls[methodScan] == 0 && len(mock.queries) == 1, "expect to query the partition")
+	query := mock.queries[0]
+	assert(t, aws.StringValue(query.KeyConditionExpression) == "#leasePartition = :leasePartition AND begins_with(#key, :prefix)", "expect the prefix to be a key condition")
+	assert(t, aws.StringValue(query.FilterExpression) == "(attribute_not_exists(#labels.#l0) OR #labels.#l0 = :l0)", "expect the labels to be filtered")
+}
@@ -1,9 +1,14 @@
 package lease
 
 import (
+	"math"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
 func TestLeaseMetaData(t *testing.T) {
@@ -43,12 +48,64 @@ func TestLeaseMetaData(t *testing.T) {
 
 	// isExpired
 	l.lastRenewal = time.Now().Add(-time.Minute)
-	if !l.isExpired(time.Second * 15) {
+	if !l.isExpired(time.Second*15, time.Now()) {
 		t.Error("expect lease to be expired")
 	}
 
 	l.lastRenewal = time.Now().Add(+time.Minute)
-	if l.isExpired(time.Second * 15) {
+	if l.isExpired(time.Second*15, time.Now()) {
 		t.Error("expect lease not to be expired")
 	}
 }
+
+func TestUnknownFields(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		unknown bool
+	}{
+		{"leaseFutureField", true},
+		{LeaseOwnerKey, false},
+		{LeaseEncryptedKey, false},
+		{"leases", false},
+		{"lease", false},
+		{"status", false},
+	} {
+		assert(t, isUnknown(c.name) == c.unknown, "unexpected result for: "+c.name)
+	}
+
+	s := newSerializer(&Config{Compressor: GzipCompressor{}, CompressThreshold: 1})
+	lease, err := s.Decode(map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:        {S: aws.String("foo")},
+		LeaseOwnerKey:      {S: aws.String("1")},
+		LeaseCounterKey:    {N: aws.String("1")},
+		"leaseFutureField": {S: aws.String("value")},
+		"status":           {S: aws.String("done")},
+	})
+	assert(t, err == nil, "expect Decode not to fail")
+	_, ok := lease.Get("leaseFutureField")
+	assert(t, !ok, "expect unknown attributes not to be extra fields")
+
+	lease.Set("leaseFutureField", "override")
+	item, err := s.Encode(lease)
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, item["status"] == nil && item[LeaseCompressedKey] != nil, "expect extra fields to be packed")
+	v := item["leaseFutureField"]
+	assert(t, v != nil && aws.StringValue(v.S) == "value", "expect unknown attributes to be preserved as is")
+}
+
+func TestLeaseCounter(t *testing.T) {
+	assert(t, nextCounter(0) == 1 && nextCounter(41) == 42, "expect the counter to be incremented")
+	assert(t, nextCounter(math.MaxInt64) == 1, "expect the counter to wrap around to 1")
+
+	s := newSerializer(&Config{})
+	// counters that were written by older versions, and counters beyond 32 bits.
+	for _, n := range []string{"7", "4294967296", "9223372036854775807"} {
+		lease, err := s.Decode(map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey:     {S: aws.String("foo")},
+			LeaseCounterKey: {N: aws.String(n)},
+		})
+		assert(t, err == nil && strconv.FormatInt(lease.Counter, 10) == n, "expect the counter to be decoded: "+n)
+		item, err := s.Encode(lease)
+		assert(t, err == nil && aws.StringValue(item[LeaseCounterKey].N) == n, "expect the counter to be encoded: "+n)
+	}
+}
@@ -0,0 +1,329 @@
+package leasegrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/leasegrpc/leasepb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Client is a lease.Leaser that forwards its calls to a Server. The methods that take
+// no context (e.g: GetHeldLeases or Stats) are called with Timeout. The methods that
+// return no error, return the zero value if the call fails, and report the error to
+// OnError. Degraded reports true if the Server is unreachable.
+//
+// Reconfigure is not served remotely, and fails with ErrUnsupported. The leases of a Scope
+// are read from the Server, but its callbacks are never called (see: lease.Scope.Err).
+type Client struct {
+	API leasepb.LeaserClient
+	// Timeout is the timeout of the calls of the methods that take no context.
+	Timeout time.Duration
+	// OnError is called with the errors of the methods that return no error, e.g: the
+	// GetHeldLeases of an unreachable Server. Optional.
+	OnError func(method string, err error)
+}
+
+// NewClient returns a Client that calls the Server on the given connection, with a
+// 5 seconds timeout for the methods that take no context.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{API: leasepb.NewLeaserClient(conn), Timeout: 5 * time.Second}
+}
+
+var _ lease.Leaser = (*Client)(nil)
+
+func (c *Client) Start(ctx context.Context) error {
+	_, err := c.API.Start(ctx, &emptypb.Empty{})
+	return fromStatus(err)
+}
+
+func (c *Client) Stop() {
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err := c.API.Stop(ctx, &emptypb.Empty{})
+	c.report("Stop", err)
+}
+
+func (c *Client) Drain(ctx context.Context) error {
+	_, err := c.API.Drain(ctx, &emptypb.Empty{})
+	return fromStatus(err)
+}
+
+func (c *Client) Delete(ctx context.Context, l lease.Lease) error {
+	in, err := request(l)
+	if err != nil {
+		return err
+	}
+	_, err = c.API.Delete(ctx, in)
+	return fromStatus(err)
+}
+
+func (c *Client) Complete(ctx context.Context, l lease.Lease) error {
+	in, err := request(l)
+	if err != nil {
+		return err
+	}
+	_, err = c.API.Complete(ctx, in)
+	return fromStatus(err)
+}
+
+func (c *Client) Reshard(ctx context.Context, l lease.Lease, children []lease.Lease) ([]lease.Lease, error) {
+	pl, err := toProto(l)
+	if err != nil {
+		return nil, err
+	}
+	pchildren, err := toProtoList(children)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.API.Reshard(ctx, &leasepb.ReshardRequest{Lease: pl, Children: pchildren})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return fromProtoList(out.GetLeases())
+}
+
+func (c *Client) Create(ctx context.Context, l lease.Lease) (lease.Lease, error) {
+	return c.call(ctx, l, c.API.Create)
+}
+
+func (c *Client) EnsureLeases(ctx context.Context, keys []string) (int, error) {
+	out, err := c.API.EnsureLeases(ctx, &leasepb.EnsureLeasesRequest{Keys: keys})
+	return int(out.GetCount()), fromStatus(err)
+}
+
+func (c *Client) Update(ctx context.Context, l lease.Lease) (lease.Lease, error) {
+	return c.call(ctx, l, c.API.Update)
+}
+
+func (c *Client) Checkpoint(ctx context.Context, l lease.Lease, checkpoint string) (lease.Lease, error) {
+	pl, err := toProto(l)
+	if err != nil {
+		return l, err
+	}
+	out, err := c.API.Checkpoint(ctx, &leasepb.CheckpointRequest{Lease: pl, Checkpoint: checkpoint})
+	if err != nil {
+		return l, fromStatus(err)
+	}
+	return fromProto(out)
+}
+
+func (c *Client) ForceUpdate(ctx context.Context, l lease.Lease) (lease.Lease, error) {
+	return c.call(ctx, l, c.API.ForceUpdate)
+}
+
+func (c *Client) Reserve(ctx context.Context, l lease.Lease, until time.Time) (lease.Lease, error) {
+	pl, err := toProto(l)
+	if err != nil {
+		return l, err
+	}
+	out, err := c.API.Reserve(ctx, &leasepb.ReserveRequest{Lease: pl, Until: timestamppb.New(until)})
+	if err != nil {
+		return l, fromStatus(err)
+	}
+	return fromProto(out)
+}
+
+func (c *Client) AcquireShared(ctx context.Context, l lease.Lease) (lease.Lease, error) {
+	return c.call(ctx, l, c.API.AcquireShared)
+}
+
+func (c *Client) ReleaseShared(ctx context.Context, l lease.Lease) error {
+	in, err := request(l)
+	if err != nil {
+		return err
+	}
+	_, err = c.API.ReleaseShared(ctx, in)
+	return fromStatus(err)
+}
+
+func (c *Client) GetHeldLeases() []lease.Lease {
+	ctx, cancel := c.context()
+	defer cancel()
+	out, err := c.API.GetHeldLeases(ctx, &emptypb.Empty{})
+	if err != nil {
+		c.report("GetHeldLeases", err)
+		return nil
+	}
+	list, err := fromProtoList(out.GetLeases())
+	c.report("GetHeldLeases", err)
+	return list
+}
+
+func (c *Client) GetSharedLeases() []lease.Lease {
+	ctx, cancel := c.context()
+	defer cancel()
+	out, err := c.API.GetSharedLeases(ctx, &emptypb.Empty{})
+	if err != nil {
+		c.report("GetSharedLeases", err)
+		return nil
+	}
+	list, err := fromProtoList(out.GetLeases())
+	c.report("GetSharedLeases", err)
+	return list
+}
+
+func (c *Client) Migrate(ctx context.Context) (int, error) {
+	out, err := c.API.Migrate(ctx, &emptypb.Empty{})
+	return int(out.GetCount()), fromStatus(err)
+}
+
+// Reconfigure fails with ErrUnsupported. The config of the Server is changed by its process.
+func (c *Client) Reconfigure(lease.Config) error {
+	return ErrUnsupported
+}
+
+// DebugHandler returns an http.Handler that writes the Stats of the Server worker in JSON
+// format.
+func (c *Client) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, err := c.API.Stats(r.Context(), &emptypb.Empty{})
+		if err != nil {
+			http.Error(w, fromStatus(err).Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fromProtoStats(out))
+	})
+}
+
+func (c *Client) ReportLoad(l lease.Lease, load float64) error {
+	pl, err := toProto(l)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err = c.API.ReportLoad(ctx, &leasepb.ReportLoadRequest{Lease: pl, Load: load})
+	return fromStatus(err)
+}
+
+func (c *Client) ExpiresIn(l lease.Lease) (time.Duration, error) {
+	in, err := request(l)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	out, err := c.API.ExpiresIn(ctx, in)
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	return out.GetExpiresIn().AsDuration(), nil
+}
+
+// Degraded reports whether the Server worker is degraded, or the Server is unreachable.
+func (c *Client) Degraded() bool {
+	ctx, cancel := c.context()
+	defer cancel()
+	out, err := c.API.Stats(ctx, &emptypb.Empty{})
+	c.report("Degraded", err)
+	return err != nil || out.GetDegraded()
+}
+
+func (c *Client) Throttled() bool {
+	return c.Stats().Throttled
+}
+
+func (c *Client) Stats() lease.Stats {
+	ctx, cancel := c.context()
+	defer cancel()
+	out, err := c.API.Stats(ctx, &emptypb.Empty{})
+	if err != nil {
+		c.report("Stats", err)
+		return lease.Stats{}
+	}
+	return fromProtoStats(out)
+}
+
+func (c *Client) Owner(ctx context.Context, key string) (lease.OwnerInfo, error) {
+	out, err := c.API.Owner(ctx, &leasepb.KeyRequest{Key: key})
+	if err != nil {
+		return lease.OwnerInfo{}, fromStatus(err)
+	}
+	return fromProtoOwner(out), nil
+}
+
+func (c *Client) Workers() []lease.WorkerInfo {
+	ctx, cancel := c.context()
+	defer cancel()
+	out, err := c.API.Workers(ctx, &emptypb.Empty{})
+	if err != nil {
+		c.report("Workers", err)
+		return nil
+	}
+	return fromProtoWorkers(out.GetWorkers())
+}
+
+func (c *Client) WaitForOwnership(ctx context.Context, key string) (lease.Lease, error) {
+	out, err := c.API.WaitForOwnership(ctx, &leasepb.KeyRequest{Key: key})
+	if err != nil {
+		return lease.Lease{}, fromStatus(err)
+	}
+	return fromProto(out)
+}
+
+func (c *Client) Get(ctx context.Context, key string) (lease.Lease, error) {
+	out, err := c.API.Get(ctx, &leasepb.KeyRequest{Key: key})
+	if err != nil {
+		return lease.Lease{}, fromStatus(err)
+	}
+	return fromProto(out)
+}
+
+// Scope returns a view of the leases of the Server that match the given selector. The
+// events of the Server are not served remotely, so the callbacks of the scope are never
+// called, and its Err method returns ErrUnsupported.
+func (c *Client) Scope(selector lease.Selector) *lease.Scope {
+	return lease.NewScope(c, selector, ErrUnsupported)
+}
+
+func (c *Client) Preflight(ctx context.Context) (lease.Drift, error) {
+	out, err := c.API.Preflight(ctx, &emptypb.Empty{})
+	if err != nil {
+		return lease.Drift{}, fromStatus(err)
+	}
+	return lease.Drift{Missing: out.GetMissing(), Orphaned: out.GetOrphaned()}, nil
+}
+
+// call calls the given method with the given lease, and returns the lease of the reply.
+func (c *Client) call(ctx context.Context, l lease.Lease, method func(context.Context, *leasepb.LeaseRequest, ...grpc.CallOption) (*leasepb.Lease, error)) (lease.Lease, error) {
+	in, err := request(l)
+	if err != nil {
+		return l, err
+	}
+	out, err := method(ctx, in)
+	if err != nil {
+		return l, fromStatus(err)
+	}
+	return fromProto(out)
+}
+
+// report calls OnError with the given error of the given method, if it's not nil.
+func (c *Client) report(method string, err error) {
+	if err != nil && c.OnError != nil {
+		c.OnError(method, fromStatus(err))
+	}
+}
+
+// context returns the context of the calls of the methods that take no context.
+func (c *Client) context() (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), c.Timeout)
+}
+
+// request returns the request of a call with the given lease.
+func request(l lease.Lease) (*leasepb.LeaseRequest, error) {
+	pl, err := toProto(l)
+	if err != nil {
+		return nil, err
+	}
+	return &leasepb.LeaseRequest{Lease: pl}, nil
+}
@@ -0,0 +1,270 @@
+package leasegrpc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/leasegrpc/leasepb"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toProto converts the given lease to its wire representation. The extra fields must be
+// representable as JSON values (see: structpb.NewValue). The payload is carried as its
+// DynamoDB attribute value, so it keeps its types.
+func toProto(l lease.Lease) (*leasepb.Lease, error) {
+	fields, err := structpb.NewStruct(l.Fields())
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	if av := l.RawPayload(); av != nil {
+		if payload, err = json.Marshal(av); err != nil {
+			return nil, err
+		}
+	}
+	return &leasepb.Lease{
+		Key:            l.Key,
+		Owner:          l.Owner,
+		Counter:        l.Counter,
+		Epoch:          int64(l.Epoch),
+		OwnerTier:      int64(l.OwnerTier),
+		OwnerVersion:   l.OwnerVersion,
+		OwnerHost:      l.OwnerHost,
+		TakeoverReason: string(l.TakeoverReason),
+		PreemptedBy:    l.PreemptedBy,
+		ReservedBy:     l.ReservedBy,
+		ReservedUntil:  timestamp(l.ReservedUntil),
+		Canary:         l.Canary,
+		Holders:        l.Holders,
+		Group:          l.Group,
+		DependsOn:      l.DependsOn,
+		MaxHolders:     int64(l.MaxHolders),
+		SchemaVersion:  int64(l.SchemaVersion),
+		TombstonedAt:   timestamp(l.TombstonedAt),
+		CompletedAt:    timestamp(l.CompletedAt),
+		LoadHint:       l.LoadHint,
+		Fields:         fields,
+		Token:          l.Token(),
+		ParentKeys:     l.ParentKeys,
+		Checkpoint:     l.Checkpoint,
+		Labels:         l.Labels,
+		OwnerZone:      l.OwnerZone,
+		Payload:        payload,
+		PayloadSet:     l.IsPayloadSet(),
+
+		CheckpointSubSequenceNumber: l.CheckpointSubSequenceNumber,
+	}, nil
+}
+
+// fromProto converts the given wire representation to a lease. The numbers of the extra
+// fields are decoded as float64, like in JSON. A payload that was set is written on the
+// next Update, like on the sending side.
+func fromProto(pl *leasepb.Lease) (lease.Lease, error) {
+	l := lease.Lease{
+		Key:            pl.GetKey(),
+		Owner:          pl.GetOwner(),
+		Counter:        pl.GetCounter(),
+		Epoch:          int(pl.GetEpoch()),
+		OwnerTier:      int(pl.GetOwnerTier()),
+		OwnerVersion:   pl.GetOwnerVersion(),
+		OwnerHost:      pl.GetOwnerHost(),
+		TakeoverReason: lease.TakeoverReason(pl.GetTakeoverReason()),
+		PreemptedBy:    pl.GetPreemptedBy(),
+		ReservedBy:     pl.GetReservedBy(),
+		ReservedUntil:  fromTimestamp(pl.GetReservedUntil()),
+		Canary:         pl.GetCanary(),
+		Holders:        pl.GetHolders(),
+		Group:          pl.GetGroup(),
+		DependsOn:      pl.GetDependsOn(),
+		ParentKeys:     pl.GetParentKeys(),
+		Checkpoint:     pl.GetCheckpoint(),
+		Labels:         pl.GetLabels(),
+		OwnerZone:      pl.GetOwnerZone(),
+		MaxHolders:     int(pl.GetMaxHolders()),
+		SchemaVersion:  int(pl.GetSchemaVersion()),
+		TombstonedAt:   fromTimestamp(pl.GetTombstonedAt()),
+		CompletedAt:    fromTimestamp(pl.GetCompletedAt()),
+		LoadHint:       pl.GetLoadHint(),
+
+		CheckpointSubSequenceNumber: pl.GetCheckpointSubSequenceNumber(),
+	}
+	for k, v := range pl.GetFields().AsMap() {
+		l.Set(k, v)
+	}
+	l.SetToken(pl.GetToken())
+	var av *dynamodb.AttributeValue
+	if len(pl.GetPayload()) > 0 {
+		av = new(dynamodb.AttributeValue)
+		if err := json.Unmarshal(pl.GetPayload(), av); err != nil {
+			return l, err
+		}
+	}
+	if !pl.GetPayloadSet() {
+		l.SetRawPayload(av)
+	} else if av == nil {
+		l.SetPayload(nil)
+	} else if err := l.SetPayload(rawPayload{av}); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// rawPayload is a payload that is marshaled as the DynamoDB attribute value it holds.
+type rawPayload struct {
+	av *dynamodb.AttributeValue
+}
+
+func (p rawPayload) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	*av = *p.av
+	return nil
+}
+
+// toProtoList converts the given leases to their wire representation.
+func toProtoList(list []lease.Lease) ([]*leasepb.Lease, error) {
+	plist := make([]*leasepb.Lease, 0, len(list))
+	for _, l := range list {
+		pl, err := toProto(l)
+		if err != nil {
+			return nil, err
+		}
+		plist = append(plist, pl)
+	}
+	return plist, nil
+}
+
+// fromProtoList converts the given wire representations to leases.
+func fromProtoList(plist []*leasepb.Lease) ([]lease.Lease, error) {
+	list := make([]lease.Lease, 0, len(plist))
+	for _, pl := range plist {
+		l, err := fromProto(pl)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, nil
+}
+
+func toProtoOwner(o lease.OwnerInfo) *leasepb.OwnerInfo {
+	return &leasepb.OwnerInfo{
+		Key:            o.Key,
+		Owner:          o.Owner,
+		Host:           o.Host,
+		Tier:           int64(o.Tier),
+		Version:        o.Version,
+		Counter:        o.Counter,
+		Epoch:          int64(o.Epoch),
+		TakeoverReason: string(o.TakeoverReason),
+		LastRenewal:    timestamp(o.LastRenewal),
+		Expired:        o.Expired,
+	}
+}
+
+func fromProtoOwner(po *leasepb.OwnerInfo) lease.OwnerInfo {
+	return lease.OwnerInfo{
+		Key:            po.GetKey(),
+		Owner:          po.GetOwner(),
+		Host:           po.GetHost(),
+		Tier:           int(po.GetTier()),
+		Version:        po.GetVersion(),
+		Counter:        po.GetCounter(),
+		Epoch:          int(po.GetEpoch()),
+		TakeoverReason: lease.TakeoverReason(po.GetTakeoverReason()),
+		LastRenewal:    fromTimestamp(po.GetLastRenewal()),
+		Expired:        po.GetExpired(),
+	}
+}
+
+func toProtoWorkers(workers []lease.WorkerInfo) []*leasepb.WorkerInfo {
+	pworkers := make([]*leasepb.WorkerInfo, 0, len(workers))
+	for _, w := range workers {
+		pworkers = append(pworkers, &leasepb.WorkerInfo{
+			Id:            w.Id,
+			Host:          w.Host,
+			Version:       w.Version,
+			Leases:        int64(w.Leases),
+			Shared:        int64(w.Shared),
+			LastHeartbeat: timestamp(w.LastHeartbeat),
+			Expired:       w.Expired,
+		})
+	}
+	return pworkers
+}
+
+func fromProtoWorkers(pworkers []*leasepb.WorkerInfo) []lease.WorkerInfo {
+	workers := make([]lease.WorkerInfo, 0, len(pworkers))
+	for _, pw := range pworkers {
+		workers = append(workers, lease.WorkerInfo{
+			Id:            pw.GetId(),
+			Host:          pw.GetHost(),
+			Version:       pw.GetVersion(),
+			Leases:        int(pw.GetLeases()),
+			Shared:        int(pw.GetShared()),
+			LastHeartbeat: fromTimestamp(pw.GetLastHeartbeat()),
+			Expired:       pw.GetExpired(),
+		})
+	}
+	return workers
+}
+
+func toProtoStats(s lease.Stats) *leasepb.StatsResponse {
+	ps := &leasepb.StatsResponse{
+		WorkerId:  s.WorkerId,
+		Held:      int64(s.Held),
+		Shared:    int64(s.Shared),
+		Degraded:  s.Degraded,
+		Throttled: s.Throttled,
+	}
+	for _, o := range s.Outcomes {
+		ps.Outcomes = append(ps.Outcomes, &leasepb.Outcome{
+			Key:     o.Key,
+			Op:      o.Op,
+			At:      timestamp(o.At),
+			Latency: durationpb.New(o.Latency),
+			Error:   o.Error,
+			Late:    o.Late,
+		})
+	}
+	return ps
+}
+
+func fromProtoStats(ps *leasepb.StatsResponse) lease.Stats {
+	s := lease.Stats{
+		WorkerId:  ps.GetWorkerId(),
+		Held:      int(ps.GetHeld()),
+		Shared:    int(ps.GetShared()),
+		Degraded:  ps.GetDegraded(),
+		Throttled: ps.GetThrottled(),
+	}
+	for _, po := range ps.GetOutcomes() {
+		s.Outcomes = append(s.Outcomes, lease.Outcome{
+			Key:     po.GetKey(),
+			Op:      po.GetOp(),
+			At:      fromTimestamp(po.GetAt()),
+			Latency: po.GetLatency().AsDuration(),
+			Error:   po.GetError(),
+			Late:    po.GetLate(),
+		})
+	}
+	return s
+}
+
+// timestamp converts the given time to a timestamp. The zero time is converted to nil.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp converts the given timestamp to a time. nil is converted to the zero time.
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,139 @@
+// Package leasegrpc exposes a Leaser over gRPC, so the workers of other languages, or the
+// applications behind a sidecar, participate in the same lease table.
+//
+// The Server wraps a running coordinator, and the Client is a thin lease.Leaser that
+// forwards its calls to the Server. The service is defined in leasepb/lease.proto, to
+// generate the clients of other languages. For example:
+//
+//	// the sidecar.
+//	s := grpc.NewServer()
+//	leasepb.RegisterLeaserServer(s, leasegrpc.NewServer(lease.New("leases")))
+//	s.Serve(lis)
+//
+//	// the application.
+//	conn, err := grpc.NewClient("localhost:7000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	leaser := leasegrpc.NewClient(conn)
+//
+// The Server has no authentication of its own, and any client that reaches it can stop,
+// drain or force-update the leases of its worker. Serve it on a local socket, or authorize
+// its calls with WithAuthorize:
+//
+//	s := grpc.NewServer(grpc.Creds(creds), leasegrpc.WithAuthorize(authorize))
+//
+// The errors of the coordinator are returned with their kind (e.g: lease.ErrLeaseNotHeld),
+// so errors.Is works on both ends. The leases keep their checkpoint, labels and payload on
+// the wire, and a payload that was set (see: lease.Lease.SetPayload) is written by the
+// Update of the Server.
+package leasegrpc
+
+//go:generate protoc -I leasepb --go_out=leasepb --go_opt=paths=source_relative --go-grpc_out=leasepb --go-grpc_opt=paths=source_relative lease.proto
+
+import (
+	"context"
+	"errors"
+
+	"github.com/a8m/lease"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnsupported error will be returns by the Client methods that are not served remotely.
+var ErrUnsupported = errors.New("leaser: operation is not supported by the remote leaser")
+
+// domain is the domain of the ErrorInfo details of the errors.
+const domain = "lease"
+
+// kinds are the kinds of the errors that are passed to the clients, and their reasons.
+// The more specific kinds come first, since an error may wrap more than one kind (e.g:
+// a *lease.ConditionError of a deleted lease).
+var kinds = []struct {
+	reason string
+	code   codes.Code
+	err    error
+}{
+	{"LEASE_STOLEN", codes.Aborted, lease.ErrLeaseStolen},
+	{"LEASE_COUNTER_CHANGED", codes.Aborted, lease.ErrLeaseCounterChanged},
+	{"CONFLICT", codes.Aborted, lease.ErrConflict},
+	{"LEASE_NOT_FOUND", codes.NotFound, lease.ErrLeaseNotFound},
+	{"LEASE_NOT_HELD", codes.FailedPrecondition, lease.ErrLeaseNotHeld},
+	{"TOKEN_NOT_MATCH", codes.FailedPrecondition, lease.ErrTokenNotMatch},
+	{"VALUE_NOT_MATCH", codes.FailedPrecondition, lease.ErrValueNotMatch},
+	{"LEASE_HELD_EXCLUSIVELY", codes.FailedPrecondition, lease.ErrLeaseHeldExclusively},
+	{"LEASE_FULL", codes.ResourceExhausted, lease.ErrLeaseFull},
+	{"QUOTA_EXCEEDED", codes.ResourceExhausted, lease.ErrQuotaExceeded},
+	{"FENCED", codes.FailedPrecondition, lease.ErrFenced},
+	{"WORKER_LOCKED", codes.FailedPrecondition, lease.ErrWorkerLocked},
+	{"DRIFT", codes.FailedPrecondition, lease.ErrDrift},
+	{"GROUP_TOO_LARGE", codes.InvalidArgument, lease.ErrGroupTooLarge},
+	{"ITEM_TOO_LARGE", codes.InvalidArgument, lease.ErrItemTooLarge},
+	{"INVALID_MIGRATION", codes.InvalidArgument, lease.ErrInvalidMigration},
+	{"INVALID_RESHARD", codes.InvalidArgument, lease.ErrInvalidReshard},
+	{"TRANSACTIONS_UNSUPPORTED", codes.Unimplemented, lease.ErrTransactionsUnsupported},
+	{"BACKEND_UNSUPPORTED", codes.Unimplemented, lease.ErrBackendUnsupported},
+	{"UNSUPPORTED", codes.Unimplemented, ErrUnsupported},
+}
+
+// toStatus converts the given error of the coordinator to a gRPC status error, with the
+// reason of its kind.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	var cerr *lease.ConfigError
+	if errors.As(err, &cerr) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			st, derr := status.New(k.code, err.Error()).WithDetails(&errdetails.ErrorInfo{Reason: k.reason, Domain: domain})
+			if derr != nil {
+				return status.Error(k.code, err.Error())
+			}
+			return st.Err()
+		}
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// remoteError is an error that was returned by the Server. It wraps the kind of the error.
+type remoteError struct {
+	st  *status.Status
+	err error
+}
+
+func (e *remoteError) Error() string { return e.st.Message() }
+
+func (e *remoteError) Unwrap() error { return e.err }
+
+// GRPCStatus returns the status of the error, e.g: for status.Code.
+func (e *remoteError) GRPCStatus() *status.Status { return e.st }
+
+// fromStatus converts the given status error of the Server to an error that wraps its kind.
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+	switch st.Code() {
+	case codes.Canceled:
+		return &remoteError{st, context.Canceled}
+	case codes.DeadlineExceeded:
+		return &remoteError{st, context.DeadlineExceeded}
+	}
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != domain {
+			continue
+		}
+		for _, k := range kinds {
+			if k.reason == info.GetReason() {
+				return &remoteError{st, k.err}
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package leasegrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/leasegrpc/leasepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient returns a Client of a Server that wraps a coordinator on a memory backend.
+func newTestClient(t *testing.T, opts ...grpc.ServerOption) *Client {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(opts...)
+	leasepb.RegisterLeaserServer(s, NewServer(lease.NewFromConfig(&lease.Config{
+		WorkerId:   "1",
+		LeaseTable: "leases",
+		Backend:    lease.NewMemoryBackend(),
+	})))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := newTestClient(t)
+	l := lease.NewLease("foo")
+	l.Set("status", "new")
+	if _, err := c.Create(ctx, l); err != nil {
+		t.Fatalf("expect the lease to be created: %v", err)
+	}
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("expect the coordinator to start: %v", err)
+	}
+	defer c.Stop()
+	held, err := c.WaitForOwnership(ctx, "foo")
+	if err != nil || held.Owner != "1" {
+		t.Fatalf("expect the lease to be taken by the worker, got: %+v, %v", held, err)
+	}
+	if list := c.GetHeldLeases(); len(list) != 1 || list[0].Token() == "" {
+		t.Fatalf("expect the held lease to carry its token, got: %+v", list)
+	}
+	held = c.GetHeldLeases()[0]
+
+	held.Set("status", "running")
+	if _, err := c.Update(ctx, held); err != nil {
+		t.Fatalf("expect the held lease to be updated: %v", err)
+	}
+	checkpointed, err := c.Checkpoint(ctx, held, "42")
+	if err != nil || checkpointed.Checkpoint != "42" {
+		t.Fatalf("expect the held lease to be checkpointed, got: %+v, %v", checkpointed, err)
+	}
+	stale := held
+	stale.SetToken("stale")
+	if _, err := c.Update(ctx, stale); !errors.Is(err, lease.ErrTokenNotMatch) {
+		t.Errorf("expect the update with a stale token to fail with ErrTokenNotMatch, got: %v", err)
+	}
+	if _, err := c.Update(ctx, lease.NewLease("bar")); !errors.Is(err, lease.ErrLeaseNotHeld) {
+		t.Errorf("expect the update of a lease that is not held to fail with ErrLeaseNotHeld, got: %v", err)
+	}
+	got, err := c.Get(ctx, "foo")
+	if v, _ := got.Get("status"); err != nil || v != "running" {
+		t.Errorf("expect to get the updated lease, got: %v, %v", v, err)
+	}
+	if _, err := c.Get(ctx, "bar"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect a missing lease to fail with ErrLeaseNotFound, got: %v", err)
+	}
+	if o, err := c.Owner(ctx, "foo"); err != nil || o.Owner != "1" {
+		t.Errorf("expect the owner to be the worker, got: %+v, %v", o, err)
+	}
+	if d, err := c.ExpiresIn(held); err != nil || d <= 0 {
+		t.Errorf("expect the held lease to expire in the future, got: %v, %v", d, err)
+	}
+	if s := c.Stats(); s.WorkerId != "1" || s.Held != 1 || c.Degraded() {
+		t.Errorf("expect the stats of the worker, got: %+v", s)
+	}
+	if err := c.Reconfigure(lease.Config{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expect Reconfigure to be unsupported, got: %v", err)
+	}
+	if err := c.Complete(ctx, held); err != nil {
+		t.Fatalf("expect the lease to be completed: %v", err)
+	}
+	if list := c.GetHeldLeases(); len(list) != 0 {
+		t.Errorf("expect the completed lease not to be held, got: %+v", list)
+	}
+}
+
+func TestClientScope(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := newTestClient(t)
+	for _, key := range []string{"orders/1", "users/1"} {
+		if _, err := c.Create(ctx, lease.NewLease(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	if _, err := c.WaitForOwnership(ctx, "users/1"); err != nil {
+		t.Fatal(err)
+	}
+	scope := c.Scope(lease.Selector{Prefix: "orders/"})
+	if !errors.Is(scope.Err(), ErrUnsupported) {
+		t.Errorf("expect the scope to report that its callbacks are unsupported, got: %v", scope.Err())
+	}
+	if _, err := scope.Get(ctx, "users/1"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect a lease out of the scope to fail with ErrLeaseNotFound, got: %v", err)
+	}
+	if _, err := c.WaitForOwnership(ctx, "orders/1"); err != nil {
+		t.Fatal(err)
+	}
+	if list := scope.GetHeldLeases(); len(list) != 1 || list[0].Key != "orders/1" {
+		t.Errorf("expect the held leases of the scope, got: %+v", list)
+	}
+}
+
+func TestWithAuthorize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := newTestClient(t, WithAuthorize(func(_ context.Context, method string) error {
+		if method == leasepb.Leaser_Drain_FullMethodName {
+			return errors.New("not allowed")
+		}
+		return nil
+	}))
+	var reported []string
+	c.OnError = func(method string, err error) {
+		reported = append(reported, method)
+	}
+	if err := c.Drain(ctx); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expect the unauthorized call to be denied, got: %v", err)
+	}
+	if _, err := c.Create(ctx, lease.NewLease("foo")); err != nil {
+		t.Errorf("expect the authorized call to be served: %v", err)
+	}
+	if s := c.Stats(); s.WorkerId != "1" || len(reported) != 0 {
+		t.Errorf("expect the stats of the worker, got: %+v, %v", s, reported)
+	}
+
+	c = newTestClient(t, WithAuthorize(func(context.Context, string) error {
+		return status.Error(codes.Unauthenticated, "no token")
+	}))
+	c.OnError = func(method string, err error) {
+		if status.Code(err) == codes.Unauthenticated {
+			reported = append(reported, method)
+		}
+	}
+	if list := c.GetHeldLeases(); list != nil || len(reported) != 1 || reported[0] != "GetHeldLeases" {
+		t.Errorf("expect the failure of GetHeldLeases to be reported, got: %v", reported)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	l := lease.NewLease("foo")
+	l.DependsOn = []string{"bar"}
+	l.ParentKeys = []string{"baz"}
+	l.Checkpoint = "42"
+	l.Labels = map[string]string{"region": "eu"}
+	l.OwnerZone = "eu-west-1a"
+	if err := l.SetPayload(map[string]int64{"sequence": 1<<53 + 1}); err != nil {
+		t.Fatal(err)
+	}
+	pl, err := toProto(l)
+	if err != nil {
+		t.Fatalf("expect the lease to be converted: %v", err)
+	}
+	got, err := fromProto(pl)
+	if err != nil {
+		t.Fatalf("expect the lease to be converted back: %v", err)
+	}
+	if len(got.DependsOn) != 1 || len(got.ParentKeys) != 1 || got.ParentKeys[0] != "baz" {
+		t.Errorf("expect the lineage of the lease to be carried, got: %+v", got)
+	}
+	if got.Checkpoint != "42" || got.Labels["region"] != "eu" || got.OwnerZone != "eu-west-1a" {
+		t.Errorf("expect the checkpoint, the labels and the zone to be carried, got: %+v", got)
+	}
+	var payload map[string]int64
+	if err := got.Payload(&payload); err != nil || payload["sequence"] != 1<<53+1 || !got.IsPayloadSet() {
+		t.Errorf("expect the payload to be carried with its types, got: %v, %v", payload, err)
+	}
+}
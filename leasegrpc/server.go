@@ -0,0 +1,265 @@
+package leasegrpc
+
+import (
+	"context"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/leasegrpc/leasepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Server is a leasepb.LeaserServer that serves the calls of the clients with the given
+// Leaser. The leases are held by the worker of the Leaser, i.e: all the clients of a
+// Server share its held leases.
+type Server struct {
+	leasepb.UnimplementedLeaserServer
+	Leaser lease.Leaser
+}
+
+// NewServer returns a Server that serves the calls with the given Leaser. Register it
+// with leasepb.RegisterLeaserServer.
+func NewServer(leaser lease.Leaser) *Server {
+	return &Server{Leaser: leaser}
+}
+
+// Authorize authorizes the calls of a Server. It's called with the context of the call
+// (e.g: to read its peer.Peer, or its metadata), and the full name of its method (e.g:
+// leasepb.Leaser_Drain_FullMethodName). See: WithAuthorize.
+type Authorize func(ctx context.Context, method string) error
+
+// WithAuthorize returns a grpc.ServerOption that rejects the calls that the given function
+// fails to authorize, before they reach the Server. The error of the function is returned
+// to the client as is if it's a status error (e.g: codes.Unauthenticated), and with the
+// codes.PermissionDenied code otherwise. For example:
+//
+//	s := grpc.NewServer(leasegrpc.WithAuthorize(func(ctx context.Context, method string) error {
+//		if method == leasepb.Leaser_GetHeldLeases_FullMethodName {
+//			return nil
+//		}
+//		return checkToken(ctx)
+//	}))
+func WithAuthorize(authorize Authorize) grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, info.FullMethod); err != nil {
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(ctx, req)
+	})
+}
+
+func (s *Server) Start(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return empty(s.Leaser.Start(ctx))
+}
+
+func (s *Server) Stop(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	s.Leaser.Stop()
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Drain(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return empty(s.Leaser.Drain(ctx))
+}
+
+func (s *Server) Create(ctx context.Context, in *leasepb.LeaseRequest) (*leasepb.Lease, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return reply(s.Leaser.Create(ctx, l))
+}
+
+func (s *Server) EnsureLeases(ctx context.Context, in *leasepb.EnsureLeasesRequest) (*leasepb.CountResponse, error) {
+	n, err := s.Leaser.EnsureLeases(ctx, in.GetKeys())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &leasepb.CountResponse{Count: int64(n)}, nil
+}
+
+func (s *Server) Update(ctx context.Context, in *leasepb.LeaseRequest) (*leasepb.Lease, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return reply(s.Leaser.Update(ctx, l))
+}
+
+func (s *Server) Checkpoint(ctx context.Context, in *leasepb.CheckpointRequest) (*leasepb.Lease, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return reply(s.Leaser.Checkpoint(ctx, l, in.GetCheckpoint()))
+}
+
+func (s *Server) ForceUpdate(ctx context.Context, in *leasepb.LeaseRequest) (*leasepb.Lease, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return reply(s.Leaser.ForceUpdate(ctx, l))
+}
+
+func (s *Server) Delete(ctx context.Context, in *leasepb.LeaseRequest) (*emptypb.Empty, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return empty(s.Leaser.Delete(ctx, l))
+}
+
+func (s *Server) Complete(ctx context.Context, in *leasepb.LeaseRequest) (*emptypb.Empty, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return empty(s.Leaser.Complete(ctx, l))
+}
+
+func (s *Server) Reshard(ctx context.Context, in *leasepb.ReshardRequest) (*leasepb.LeasesResponse, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	children, err := fromProtoList(in.GetChildren())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return replyList(s.Leaser.Reshard(ctx, l, children))
+}
+
+func (s *Server) Reserve(ctx context.Context, in *leasepb.ReserveRequest) (*leasepb.Lease, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return reply(s.Leaser.Reserve(ctx, l, fromTimestamp(in.GetUntil())))
+}
+
+func (s *Server) AcquireShared(ctx context.Context, in *leasepb.LeaseRequest) (*leasepb.Lease, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return reply(s.Leaser.AcquireShared(ctx, l))
+}
+
+func (s *Server) ReleaseShared(ctx context.Context, in *leasepb.LeaseRequest) (*emptypb.Empty, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return empty(s.Leaser.ReleaseShared(ctx, l))
+}
+
+func (s *Server) GetHeldLeases(context.Context, *emptypb.Empty) (*leasepb.LeasesResponse, error) {
+	return replyList(s.Leaser.GetHeldLeases(), nil)
+}
+
+func (s *Server) GetSharedLeases(context.Context, *emptypb.Empty) (*leasepb.LeasesResponse, error) {
+	return replyList(s.Leaser.GetSharedLeases(), nil)
+}
+
+func (s *Server) Get(ctx context.Context, in *leasepb.KeyRequest) (*leasepb.Lease, error) {
+	return reply(s.Leaser.Get(ctx, in.GetKey()))
+}
+
+func (s *Server) WaitForOwnership(ctx context.Context, in *leasepb.KeyRequest) (*leasepb.Lease, error) {
+	return reply(s.Leaser.WaitForOwnership(ctx, in.GetKey()))
+}
+
+func (s *Server) Owner(ctx context.Context, in *leasepb.KeyRequest) (*leasepb.OwnerInfo, error) {
+	o, err := s.Leaser.Owner(ctx, in.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoOwner(o), nil
+}
+
+func (s *Server) ReportLoad(_ context.Context, in *leasepb.ReportLoadRequest) (*emptypb.Empty, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return empty(s.Leaser.ReportLoad(l, in.GetLoad()))
+}
+
+func (s *Server) ExpiresIn(_ context.Context, in *leasepb.LeaseRequest) (*leasepb.ExpiresInResponse, error) {
+	l, err := fromProto(in.GetLease())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	d, err := s.Leaser.ExpiresIn(l)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &leasepb.ExpiresInResponse{ExpiresIn: durationpb.New(d)}, nil
+}
+
+func (s *Server) Migrate(ctx context.Context, _ *emptypb.Empty) (*leasepb.CountResponse, error) {
+	n, err := s.Leaser.Migrate(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &leasepb.CountResponse{Count: int64(n)}, nil
+}
+
+func (s *Server) Preflight(ctx context.Context, _ *emptypb.Empty) (*leasepb.Drift, error) {
+	drift, err := s.Leaser.Preflight(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &leasepb.Drift{Missing: drift.Missing, Orphaned: drift.Orphaned}, nil
+}
+
+func (s *Server) Stats(context.Context, *emptypb.Empty) (*leasepb.StatsResponse, error) {
+	return toProtoStats(s.Leaser.Stats()), nil
+}
+
+func (s *Server) Workers(context.Context, *emptypb.Empty) (*leasepb.WorkersResponse, error) {
+	return &leasepb.WorkersResponse{Workers: toProtoWorkers(s.Leaser.Workers())}, nil
+}
+
+// empty returns the empty reply of a call that failed with the given error.
+func empty(err error) (*emptypb.Empty, error) {
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// invalid returns the status of a request that its lease can't be decoded.
+func invalid(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// reply returns the reply of a call that returned the given lease.
+func reply(l lease.Lease, err error) (*leasepb.Lease, error) {
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	pl, err := toProto(l)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return pl, nil
+}
+
+// replyList returns the reply of a call that returned the given leases.
+func replyList(list []lease.Lease, err error) (*leasepb.LeasesResponse, error) {
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	plist, err := toProtoList(list)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &leasepb.LeasesResponse{Leases: plist}, nil
+}
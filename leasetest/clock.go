@@ -0,0 +1,90 @@
+// Package leasetest provides utilities for testing the workers of the lease package,
+// without real waiting. For example:
+//
+//	clock := leasetest.NewFakeClock(time.Now())
+//	leaser := lease.New("leases", lease.WithConfig(func(c *lease.Config) {
+//		c.Clock = clock
+//	}))
+//	...
+//	// let the leases of the other workers expire.
+//	clock.Advance(time.Minute)
+package leasetest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a lease.Clock that moves only when it's advanced. The channels returned
+// by After receive the time when the clock is advanced past their deadline.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+	// added is signaled when a waiter is added. see: BlockUntil.
+	added *sync.Cond
+}
+
+type waiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewFakeClock returns a FakeClock that is set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.added = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time of the clock, when it's advanced by d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, waiter{until: c.now.Add(d), ch: ch})
+	c.added.Broadcast()
+	return ch
+}
+
+// Sleep blocks until the clock is advanced by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d, and fires the waiters whose deadline passed.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.until.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// BlockUntil blocks until there are at least n waiters, i.e: goroutines that wait on
+// the clock in After or Sleep. Use it to advance the clock after a loop went to sleep.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.added.Wait()
+	}
+}
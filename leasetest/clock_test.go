@@ -0,0 +1,38 @@
+package leasetest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewFakeClock(start)
+	ch := clock.After(time.Minute)
+	done := make(chan struct{})
+	go func() {
+		clock.Sleep(2 * time.Minute)
+		close(done)
+	}()
+	clock.BlockUntil(2)
+
+	clock.Advance(time.Minute)
+	select {
+	case now := <-ch:
+		if !now.Equal(start.Add(time.Minute)) {
+			t.Errorf("expect the time of the clock, got: %v", now)
+		}
+	default:
+		t.Error("expect the waiter to fire after the clock was advanced")
+	}
+	select {
+	case <-done:
+		t.Error("expect the sleep to wait for its deadline")
+	default:
+	}
+	clock.Advance(time.Minute)
+	<-done
+	if !clock.Now().Equal(start.Add(2 * time.Minute)) {
+		t.Error("expect the clock to move only when it's advanced")
+	}
+}
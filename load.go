@@ -0,0 +1,147 @@
+package lease
+
+import (
+	"context"
+	"sort"
+)
+
+// leaseWeights returns the load of each lease that can be taken. leases without a load
+// hint weigh as the average load of the hinted leases, or 1 if there are no such leases.
+func (l *leaseTaker) leaseWeights() map[string]float64 {
+	var (
+		sum     float64
+		hinted  int
+		weights = make(map[string]float64)
+	)
+	for _, lease := range l.allLeases {
+		if lease.LoadHint > 0 {
+			sum += lease.LoadHint
+			hinted++
+		}
+	}
+	avg := 1.0
+	if hinted > 0 {
+		avg = sum / float64(hinted)
+	}
+	for key, lease := range l.allLeases {
+		if lease.isShared(l.ExpireAfter, l.now()) || lease.isSemaphore() {
+			continue
+		}
+		weights[key] = avg
+		if lease.LoadHint > 0 {
+			weights[key] = lease.LoadHint
+		}
+	}
+	return weights
+}
+
+// computeWorkerLoads returns the total load of each worker, based on the given weights.
+func (l *leaseTaker) computeWorkerLoads(weights map[string]float64) map[string]float64 {
+	loads := map[string]float64{
+		l.WorkerId: 0,
+	}
+	for key, w := range weights {
+		if lease := l.allLeases[key]; !lease.hasNoOwner() {
+			loads[lease.Owner] += w
+		}
+	}
+	return loads
+}
+
+// chooseLeasesByLoad returns the leases to take in order to reach the average load
+// of the workers. expired leases are taken first, and if there are no such leases,
+// it steals the leases of the most loaded worker that reduce the load gap between us.
+func (l *leaseTaker) chooseLeasesByLoad(ctx context.Context) []*Lease {
+	weights := l.leaseWeights()
+	loads := l.computeWorkerLoads(weights)
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	target := total / float64(len(loads))
+	needed := target - loads[l.WorkerId]
+	if needed <= 0 {
+		l.Logger.Debugf("Worker %s does not need to take leases. our load is %.2f, and the target is: %.2f",
+			l.WorkerId,
+			loads[l.WorkerId],
+			target)
+		return nil
+	}
+
+	heldLeases := l.getHeldLeases()
+	if expiredLeases := l.filterQuota(heldLeases, l.getExpiredLeases()); len(expiredLeases) > 0 {
+		l.tiebreak(expiredLeases)
+		l.prioritize(expiredLeases)
+		var list []*Lease
+		for _, lease := range expiredLeases {
+			if needed <= 0 {
+				break
+			}
+			list = append(list, lease)
+			needed -= weights[lease.Key]
+		}
+		return list
+	}
+
+	var mostLoadedWorker string
+	for worker, load := range loads {
+		if worker != l.WorkerId && (mostLoadedWorker == "" || loads[mostLoadedWorker] < load) {
+			mostLoadedWorker = worker
+		}
+	}
+	// do not steal back leases from workers of newer version.
+	if l.VersionTakeoverRate > 0 && l.Version != "" {
+		if v := l.workerVersions()[mostLoadedWorker]; v != "" && l.CompareVersions(v, l.Version) > 0 {
+			return nil
+		}
+	}
+
+	var candidates []*Lease
+	for key := range weights {
+		if lease := l.allLeases[key]; lease.Owner == mostLoadedWorker {
+			candidates = append(candidates, lease)
+		}
+	}
+	// prefer the heaviest leases, to converge faster.
+	shuffle(candidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return weights[candidates[i].Key] > weights[candidates[j].Key]
+	})
+
+	// a lease reduces the gap only if it's lighter than the gap itself.
+	var list []*Lease
+	gap := loads[mostLoadedWorker] - loads[l.WorkerId]
+	for _, lease := range candidates {
+		if len(list) == l.MaxLeasesToStealAtOneTime {
+			break
+		}
+		if w := weights[lease.Key]; w < gap {
+			list = append(list, lease)
+			gap -= 2 * w
+		}
+	}
+	if len(list) > 0 {
+		l.Logger.Debugf("Worker %s will attempt to steal %d leases from most loaded worker %s.\n"+
+			"He has load of %.2f, our load is %.2f, and the target is %.2f.",
+			l.WorkerId,
+			len(list),
+			mostLoadedWorker,
+			loads[mostLoadedWorker],
+			loads[l.WorkerId],
+			target)
+	}
+	return l.stealBudget(ctx, l.filterQuota(heldLeases, list))
+}
+
+// takeByLoad takes the leases that chosen by chooseLeasesByLoad.
+func (l *leaseTaker) takeByLoad(ctx context.Context) {
+	for _, lease := range l.chooseLeasesByLoad(ctx) {
+		if err := l.takeLease(ctx, lease, l.takeoverReason(lease)); err != nil {
+			l.Logger.WithError(err).Debugf("Worker %s could not take lease with key %s.",
+				l.WorkerId,
+				lease.Key)
+		} else {
+			l.Logger.Debugf("Worker %s took lease: %s successfully.", l.WorkerId, lease.Key)
+		}
+	}
+}
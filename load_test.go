@@ -0,0 +1,56 @@
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestTakerBalanceByLoad(t *testing.T) {
+	logger := testLogger()
+	leases := []*Lease{
+		{Key: "foo", Owner: "1", LoadHint: 10, lastRenewal: time.Now()},
+		{Key: "bar", Owner: "1", LoadHint: 1, lastRenewal: time.Now()},
+		{Key: "baz", Owner: "1", lastRenewal: time.Now()},
+		{Key: "qux", Owner: "1", LoadHint: 1, lastRenewal: time.Now()},
+	}
+	manager := newManagerMock(map[method]args{
+		methodList: {leases},
+		methodTake: {nil, nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 2,
+			BalanceByLoad:             true,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTake] == 1, "expect to steal only the leases that reduce the load gap")
+	assert(t, leases[0].Owner == takerId, "expect to steal the heaviest lease")
+}
+
+func TestRenewerReportLoad(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {&dynamodb.UpdateItemOutput{}},
+	})
+	manager := newTestManager(client)
+	holder := &leaseHolder{
+		Config:     manager.Config,
+		manager:    manager,
+		heldLeases: map[string]*Lease{"foo": {Key: "foo", Owner: "1"}},
+	}
+	assert(t, holder.ReportLoad("bar", 1) == ErrLeaseNotHeld, "expect to fail if the lease is not held")
+	assert(t, holder.ReportLoad("foo", 5) == nil, "expect ReportLoad not to fail")
+
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	load := holder.loads["foo"]
+	lease.reportedLoad = &load
+	err := manager.RenewLease(context.Background(), lease)
+	assert(t, err == nil && lease.LoadHint == 5 && lease.Counter == 2, "expect the reported load to be written on renewal")
+}
@@ -0,0 +1,45 @@
+package lease
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// ErrWorkerLocked error will be returns only on the Start() call, if another process on
+// this host already started a coordinator with the same WorkerId. See: Config.LockDir.
+var ErrWorkerLocked = errors.New("leaser: worker is already running on this host")
+
+// lockWorker acquires the local lock file of the given worker in the given directory.
+// the lock is released by the operating system if the process exits.
+func lockWorker(dir, workerId string) (*os.File, error) {
+	path := filepath.Join(dir, url.PathEscape(workerId)+".lock")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("leaser: open lock file: %v", err)
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// the pid of the holder helps to find the other copy of the worker.
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+	return f, nil
+}
+
+// unlockWorker releases the given lock file. the file is not removed, to not race
+// with processes that opened it, and wait for the lock.
+func unlockWorker(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	if err := funlock(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
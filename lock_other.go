@@ -0,0 +1,17 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package lease
+
+import (
+	"errors"
+	"os"
+)
+
+// errLockUnsupported error will be returns if Config.LockDir is set on a platform
+// without flock.
+var errLockUnsupported = errors.New("leaser: local worker lock is not supported on this platform")
+
+func flock(*os.File) error { return errLockUnsupported }
+
+func funlock(*os.File) error { return errLockUnsupported }
@@ -0,0 +1,24 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package lease
+
+import "testing"
+
+func TestLockWorker(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := lockWorker(dir, "worker/1")
+	assert(t, err == nil, "expect to acquire the lock")
+
+	_, err = lockWorker(dir, "worker/1")
+	assert(t, err == ErrWorkerLocked, "expect to fail if the lock is held")
+
+	other, err := lockWorker(dir, "worker/2")
+	assert(t, err == nil, "expect the lock to be keyed by the worker id")
+	unlockWorker(other)
+
+	assert(t, unlockWorker(lock) == nil, "expect to release the lock")
+	lock, err = lockWorker(dir, "worker/1")
+	assert(t, err == nil, "expect to acquire the released lock")
+	unlockWorker(lock)
+}
@@ -0,0 +1,27 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package lease
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// flock acquires an exclusive lock on the given file, without blocking.
+func flock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return ErrWorkerLocked
+	}
+	if err != nil {
+		return fmt.Errorf("leaser: lock file: %v", err)
+	}
+	return nil
+}
+
+// funlock releases the lock on the given file.
+func funlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,85 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Fields is a set of structured fields that are attached to the log entries.
+type Fields map[string]interface{}
+
+// Logger is the logging API used by this package. Loggers with fields are derived
+// using WithFields, WithField and WithError, and Fatalf exits the process after the
+// entry is written.
+//
+// It's implemented by NewSlogLogger for log/slog, and by the adapters in the
+// logrusadapter and zapadapter packages.
+type Logger interface {
+	WithFields(Fields) Logger
+	WithField(key string, value interface{}) Logger
+	WithError(error) Logger
+	Debugf(string, ...interface{})
+	Infof(string, ...interface{})
+	Warnf(string, ...interface{})
+	Errorf(string, ...interface{})
+	Fatalf(string, ...interface{})
+}
+
+// slogLogger is a Logger that writes to a log/slog logger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a Logger that writes to the given slog logger. It's the default
+// Logger of the Config, with slog.Default().
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l}
+}
+
+func (s *slogLogger) WithFields(fields Fields) Logger {
+	args := make([]interface{}, 0, 2*len(fields))
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	return &slogLogger{s.l.With(args...)}
+}
+
+func (s *slogLogger) WithField(key string, value interface{}) Logger {
+	return &slogLogger{s.l.With(key, value)}
+}
+
+func (s *slogLogger) WithError(err error) Logger {
+	return &slogLogger{s.l.With("error", err)}
+}
+
+func (s *slogLogger) Debugf(format string, args ...interface{}) {
+	s.log(slog.LevelDebug, format, args...)
+}
+
+func (s *slogLogger) Infof(format string, args ...interface{}) {
+	s.log(slog.LevelInfo, format, args...)
+}
+
+func (s *slogLogger) Warnf(format string, args ...interface{}) {
+	s.log(slog.LevelWarn, format, args...)
+}
+
+func (s *slogLogger) Errorf(format string, args ...interface{}) {
+	s.log(slog.LevelError, format, args...)
+}
+
+func (s *slogLogger) Fatalf(format string, args ...interface{}) {
+	s.log(slog.LevelError, format, args...)
+	os.Exit(1)
+}
+
+// log writes the formatted message, if the given level is enabled.
+func (s *slogLogger) log(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	s.l.Log(ctx, level, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,22 @@
+package lease
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil))).WithField("package", "leases")
+
+	logger.Debugf("skipped %d", 1)
+	logger.WithFields(Fields{"leaseKey": "foo"}).WithError(errors.New("boom")).Warnf("failed to take %d leases", 2)
+	out := buf.String()
+	assert(t, !strings.Contains(out, "skipped"), "expect debug entries to be skipped")
+	for _, s := range []string{"level=WARN", `msg="failed to take 2 leases"`, "package=leases", "leaseKey=foo", "error=boom"} {
+		assert(t, strings.Contains(out, s), "expect the entry to contain "+s)
+	}
+}
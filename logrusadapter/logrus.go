@@ -0,0 +1,36 @@
+// Package logrusadapter adapts logrus loggers to lease.Logger. For example:
+//
+//	leaser := lease.New("leases", lease.WithLogger(logrusadapter.New(logrus.New())))
+package logrusadapter
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/a8m/lease"
+)
+
+type logger struct {
+	l logrus.FieldLogger
+}
+
+// New returns a lease.Logger that writes to the given logrus logger (or entry).
+func New(l logrus.FieldLogger) lease.Logger {
+	return &logger{l}
+}
+
+func (l *logger) WithFields(fields lease.Fields) lease.Logger {
+	return &logger{l.l.WithFields(logrus.Fields(fields))}
+}
+
+func (l *logger) WithField(key string, value interface{}) lease.Logger {
+	return &logger{l.l.WithField(key, value)}
+}
+
+func (l *logger) WithError(err error) lease.Logger {
+	return &logger{l.l.WithError(err)}
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) { l.l.Debugf(format, args...) }
+func (l *logger) Infof(format string, args ...interface{})  { l.l.Infof(format, args...) }
+func (l *logger) Warnf(format string, args ...interface{})  { l.l.Warnf(format, args...) }
+func (l *logger) Errorf(format string, args ...interface{}) { l.l.Errorf(format, args...) }
+func (l *logger) Fatalf(format string, args ...interface{}) { l.l.Fatalf(format, args...) }
@@ -0,0 +1,29 @@
+package logrusadapter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	l.Level = logrus.InfoLevel
+	logger := New(l).WithField("package", "leases")
+
+	logger.Debugf("skipped %d", 1)
+	logger.WithFields(map[string]interface{}{"leaseKey": "foo"}).Infof("took %d leases", 2)
+	out := buf.String()
+	if strings.Contains(out, "skipped") {
+		t.Error("expect debug entries to be skipped")
+	}
+	for _, s := range []string{"took 2 leases", "package=leases", "leaseKey=foo"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expect the entry to contain %q, got: %s", s, out)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package lease
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Maintenance is the fleet-wide maintenance mode of a lease table. While it's set, the
+// coordinators do not take or steal leases, and keep renewing the leases they hold, so
+// a maintenance window (e.g: of a downstream system) needs no redeploy of the workers.
+// The mode is stored in the lease table under MaintenanceKey, and the coordinators pick
+// it up on their next scan of the table. See: Admin.Pause.
+type Maintenance struct {
+	// Reason describes the maintenance. It's logged by the paused workers.
+	Reason string
+	// By is the operator that paused the fleet.
+	By string
+	// Since is the time the fleet was paused.
+	Since time.Time
+	// Until is the time the maintenance mode lapses, and the coordinators resume taking
+	// leases, even if it was not cleared (e.g: the tooling crashed). Zero means until
+	// Admin.Resume is called.
+	Until time.Time
+}
+
+// IsActive test if the maintenance mode is in effect at the given time.
+func (m *Maintenance) IsActive(now time.Time) bool {
+	return m != nil && (m.Until.IsZero() || now.Before(m.Until))
+}
+
+// maintenance holds the maintenance mode, as of the last scan of the lease table.
+// a nil maintenance has no mode.
+type maintenance struct {
+	sync.Mutex
+	mode *Maintenance
+}
+
+// set sets the maintenance mode that was read in the last scan. nil clears it.
+func (m *maintenance) set(mode *Maintenance) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	m.mode = mode
+	m.Unlock()
+}
+
+// active returns the maintenance mode if it's in effect at the given time, or nil.
+func (m *maintenance) active(now time.Time) *Maintenance {
+	if m == nil {
+		return nil
+	}
+	m.Lock()
+	defer m.Unlock()
+	if !m.mode.IsActive(now) {
+		return nil
+	}
+	return m.mode
+}
+
+// Pause sets the fleet-wide maintenance mode of the lease table, so all the coordinators
+// stop taking and stealing leases on their next scan, for the given duration (zero means
+// until Resume is called). The renewals continue. Pausing a paused fleet replaces its mode.
+// It fails with ErrBackendUnsupported with a Backend (see: Config.Backend), since only the
+// scans of DynamoDB read the mode.
+func (a *Admin) Pause(ctx context.Context, reason, by string, d time.Duration) (Maintenance, error) {
+	if a.Backend != nil {
+		return Maintenance{}, a.manager.wrapError("pause", MaintenanceKey, ErrBackendUnsupported)
+	}
+	mode := Maintenance{Reason: reason, By: by, Since: a.now()}
+	item := map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:               {S: aws.String(MaintenanceKey)},
+		LeaseMaintenanceByKey:     {S: aws.String(by)},
+		LeaseMaintenanceSinceKey:  {N: aws.String(strconv.FormatInt(mode.Since.UnixNano()/int64(time.Millisecond), 10))},
+		LeaseMaintenanceReasonKey: {S: aws.String(reason)},
+	}
+	if d > 0 {
+		mode.Until = mode.Since.Add(d)
+		item[LeaseMaintenanceUntilKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(mode.Until.UnixNano()/int64(time.Millisecond), 10)),
+		}
+	}
+	_, err := a.Client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(a.LeaseTable),
+		Item:      item,
+	})
+	if err != nil {
+		return Maintenance{}, a.manager.wrapError("pause", MaintenanceKey, err)
+	}
+	a.Logger.Infof("%s paused the fleet for maintenance: %s", by, reason)
+	return mode, nil
+}
+
+// Resume clears the maintenance mode of the lease table, so the coordinators resume
+// taking leases on their next scan. does nothing if the fleet is not paused. It fails with
+// ErrBackendUnsupported with a Backend, like Pause.
+func (a *Admin) Resume(ctx context.Context) error {
+	if a.Backend != nil {
+		return a.manager.wrapError("resume", MaintenanceKey, ErrBackendUnsupported)
+	}
+	_, err := a.Client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(a.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {S: aws.String(MaintenanceKey)},
+		},
+	})
+	if err != nil {
+		return a.manager.wrapError("resume", MaintenanceKey, err)
+	}
+	a.Logger.Infof("admin: resumed the fleet from maintenance")
+	return nil
+}
+
+// Maintenance returns the maintenance mode of the lease table, or nil if the fleet is
+// not paused. The returned mode may already be lapsed (see: Maintenance.IsActive). It
+// fails with ErrBackendUnsupported with a Backend, like Pause.
+func (a *Admin) Maintenance(ctx context.Context) (*Maintenance, error) {
+	if a.Backend != nil {
+		return nil, a.manager.wrapError("maintenance", MaintenanceKey, ErrBackendUnsupported)
+	}
+	out, err := a.Client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(a.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {S: aws.String(MaintenanceKey)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, a.manager.wrapError("maintenance", MaintenanceKey, err)
+	}
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+	return readMaintenance(out.Item), nil
+}
+
+// readMaintenance reads the maintenance mode from the given item.
+func readMaintenance(item map[string]*dynamodb.AttributeValue) *Maintenance {
+	mode := &Maintenance{}
+	if v := item[LeaseMaintenanceReasonKey]; v != nil {
+		mode.Reason = aws.StringValue(v.S)
+	}
+	if v := item[LeaseMaintenanceByKey]; v != nil {
+		mode.By = aws.StringValue(v.S)
+	}
+	if v := item[LeaseMaintenanceSinceKey]; v != nil {
+		if ms, err := strconv.ParseInt(aws.StringValue(v.N), 10, 64); err == nil {
+			mode.Since = time.Unix(0, ms*int64(time.Millisecond))
+		}
+	}
+	if v := item[LeaseMaintenanceUntilKey]; v != nil {
+		if ms, err := strconv.ParseInt(aws.StringValue(v.N), 10, 64); err == nil {
+			mode.Until = time.Unix(0, ms*int64(time.Millisecond))
+		}
+	}
+	return mode
+}
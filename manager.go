@@ -1,15 +1,17 @@
 package lease
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/Sirupsen/logrus"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
 const (
@@ -18,46 +20,291 @@ const (
 	LeaseOwnerKey   = "leaseOwner"
 	LeaseCounterKey = "leaseCounter"
 
+	// Lease reservation
+	LeaseReservedByKey    = "leaseReservedBy"
+	LeaseReservedUntilKey = "leaseReservedUntil"
+
+	// Shared mode
+	LeaseHoldersKey    = "leaseHolders"
+	LeaseMaxHoldersKey = "leaseMaxHolders"
+
+	// Priority preemption
+	LeaseOwnerTierKey   = "leaseOwnerTier"
+	LeasePreemptedByKey = "leasePreemptedBy"
+
+	// Client-side encryption and compression. hold all the extra fields packed.
+	LeaseEncryptedKey   = "leaseEncrypted"
+	LeaseCompressedKey  = "leaseCompressed"
+	LeaseCompressionKey = "leaseCompression"
+	LeaseOverflowKey    = "leaseOverflow"
+	LeaseFieldsKey      = "leaseFields"
+
+	// Deployment version
+	LeaseOwnerVersionKey = "leaseOwnerVersion"
+	LeaseCanaryKey       = "leaseCanary"
+
+	// Schema migration
+	LeaseSchemaVersionKey = "leaseSchemaVersion"
+
+	// Deleted leases snapshot
+	LeaseDeletedAtKey = "leaseDeletedAt"
+	LeaseDeletedByKey = "leaseDeletedBy"
+
+	// Soft delete
+	LeaseTombstonedAtKey = "leaseTombstonedAt"
+
+	// Load aware balancing
+	LeaseLoadHintKey = "leaseLoadHint"
+
+	// Owner lookup
+	LeaseOwnerHostKey = "leaseOwnerHost"
+	LeaseOwnerZoneKey = "leaseOwnerZone"
+
+	// Lease groups
+	LeaseGroupKey = "leaseGroup"
+
+	// Work-unit completion
+	LeaseCompletedAtKey = "leaseCompletedAt"
+
+	// Lease dependencies
+	LeaseDependsOnKey = "leaseDependsOn"
+
+	// Lease lineage. see: Lease.ParentKeys.
+	LeaseParentKeysKey = "leaseParentKeys"
+
+	// Lease labels. see: Lease.Labels.
+	LeaseLabelsKey = "leaseLabels"
+
+	// Typed payloads. see: Lease.SetPayload.
+	LeasePayloadKey = "leasePayload"
+
+	// Takeover reasons
+	LeaseTakeoverReasonKey = "leaseTakeoverReason"
+
+	// Ownership generations
+	LeaseEpochKey = "leaseEpoch"
+
+	// DynamoDB Time to Live. see: Config.LeaseTTL.
+	LeaseExpiresAtKey = "leaseExpiresAt"
+
+	// Persisted heartbeats. see: Config.Heartbeat.
+	LeaseHeartbeatKey = "leaseHeartbeat"
+
+	// Checkpoints. see: Leaser.Checkpoint.
+	LeaseCheckpointKey = "leaseCheckpoint"
+
+	// Partitioned tables. see: Config.Partition.
+	LeasePartitionKey = "leasePartition"
+
+	// Fleet-wide steal budget. see: Config.StealRate.
+	StealBudgetKey          = "leaseStealBudget"
+	LeaseStealTokensKey     = "leaseStealTokens"
+	LeaseStealRefilledAtKey = "leaseStealRefilledAt"
+
+	// Admin intents. see: Admin.Propose.
+	AdminIntentPrefix      = "leaseAdminIntent/"
+	LeaseAdminOpKey        = "leaseAdminOp"
+	LeaseAdminTargetKey    = "leaseAdminTarget"
+	LeaseAdminProposerKey  = "leaseAdminProposer"
+	LeaseAdminExpiresAtKey = "leaseAdminExpiresAt"
+	LeaseAdminRequireKey   = "leaseAdminRequireApprover"
+	LeaseAdminApproverKey  = "leaseAdminApprover"
+
+	// Maintenance mode. see: Admin.Pause.
+	MaintenanceKey            = "leaseMaintenance"
+	LeaseMaintenanceReasonKey = "leaseMaintenanceReason"
+	LeaseMaintenanceByKey     = "leaseMaintenanceBy"
+	LeaseMaintenanceSinceKey  = "leaseMaintenanceSince"
+	LeaseMaintenanceUntilKey  = "leaseMaintenanceUntil"
+
+	// KCL lease schema. see: Config.KCLSchema.
+	KCLCheckpointKey                   = "checkpoint"
+	KCLCheckpointSubSequenceNumberKey  = "checkpointSubSequenceNumber"
+	KCLOwnerSwitchesSinceCheckpointKey = "ownerSwitchesSinceCheckpoint"
+	KCLParentShardIdKey                = "parentShardId"
+
 	// AWS exception
-	AlreadyExist      = "ResourceInUseException"
-	ConditionalFailed = "ConditionalCheckFailedException"
+	AlreadyExist        = "ResourceInUseException"
+	ConditionalFailed   = "ConditionalCheckFailedException"
+	TransactionCanceled = "TransactionCanceledException"
+	ValidationFailed    = "ValidationException"
 
 	// Max number of retries
 	maxScanRetries   = 3
+	maxGetRetries    = 3
 	maxCreateRetries = 3
 	maxUpdateRetries = 2
 	maxDeleteRetries = 2
 
-	// Maximum duration to wait until the table in active state
-	maxDurationTableStatus = time.Minute * 5
-	durationBetweenPolls   = time.Second * 10
+	// Maximum duration to wait until the table in active state. the polls of the table
+	// status back off from minDurationBetweenPolls to durationBetweenPolls.
+	maxDurationTableStatus  = time.Minute * 5
+	minDurationBetweenPolls = time.Second
+	durationBetweenPolls    = time.Second * 10
 )
 
 // Manager wrap the basic operations for leases.
+// The given context cancels the in-flight DynamoDB calls, and the retries between them.
 type Manager interface {
 	// Creates the table that will store leases if it's not already exists.
-	CreateLeaseTable() error
+	CreateLeaseTable(context.Context) error
+
+	// Deletes the table that stores the leases, if it exists.
+	DeleteLeaseTable(context.Context) error
+
+	// Check if the table that stores the leases exists.
+	LeaseTableExists(context.Context) (bool, error)
+
+	// Change the capacity or the billing mode of the table that stores the leases.
+	UpdateLeaseTableCapacity(context.Context, TableCapacity) error
 
 	// List all leases(objects) in table.
-	ListLeases() ([]*Lease, error)
+	ListLeases(context.Context) ([]*Lease, error)
+
+	// List all leases(objects) in table through the eventually consistent reads of
+	// Config.ReadClient. The result may be stale, and it must not feed the conditional
+	// writes of held leases (e.g: renewals).
+	ScanLeases(context.Context) ([]*Lease, error)
+
+	// List the leases(objects) held by the given owner.
+	ListLeasesByOwner(context.Context, string) ([]*Lease, error)
+
+	// List the leases(objects) that match the given selector.
+	ListLeasesBySelector(context.Context, Selector) ([]*Lease, error)
+
+	// Get a lease by its key
+	GetLease(context.Context, string) (*Lease, error)
 
 	// Renew a lease
-	RenewLease(*Lease) error
+	RenewLease(context.Context, *Lease) error
 
 	// Take a lease
-	TakeLease(*Lease) error
+	TakeLease(context.Context, *Lease) error
+
+	// Take a group of leases, all or none
+	TakeLeases(context.Context, []*Lease) error
 
 	// Evict a lease
-	EvictLease(*Lease) error
+	EvictLease(context.Context, *Lease) error
 
 	// Delete a lease
-	DeleteLease(*Lease) error
+	DeleteLease(context.Context, *Lease) error
 
 	// Create a lease
-	CreateLease(*Lease) (*Lease, error)
+	CreateLease(context.Context, *Lease) (*Lease, error)
+
+	// Create a lease if it does not exist
+	EnsureLease(context.Context, *Lease) (bool, error)
 
 	// Update a lease
-	UpdateLease(*Lease) (*Lease, error)
+	UpdateLease(context.Context, *Lease) (*Lease, error)
+
+	// Reserve a lease until the given time
+	ReserveLease(context.Context, *Lease, time.Time) error
+
+	// Request a lease to be drained by its owner, and handed over to this worker
+	PreemptLease(context.Context, *Lease) error
+
+	// Acquire, renew or release a lease in shared mode
+	AcquireSharedLease(context.Context, *Lease) error
+	RenewSharedLease(context.Context, *Lease) error
+	ReleaseSharedLease(context.Context, *Lease) error
+
+	// Migrate all the leases in table to the latest schema version
+	MigrateLeases(context.Context) (int, error)
+
+	// Purge a tombstoned lease
+	PurgeLease(context.Context, *Lease) error
+
+	// Mark a lease as completed, or delete it
+	CompleteLease(context.Context, *Lease) error
+
+	// Acquire up to n leases from the fleet-wide steal budget
+	AcquireStealBudget(ctx context.Context, n int) (int, error)
+
+	// Replace a lease with new leases, in a single transaction
+	ReshardLease(context.Context, *Lease, []*Lease) error
+}
+
+// reservedKeys are the attributes that belong to this package and cannot
+// be set as extra fields.
+var reservedKeys = []string{
+	LeaseKeyKey,
+	LeaseOwnerKey,
+	LeaseCounterKey,
+	LeaseReservedByKey,
+	LeaseReservedUntilKey,
+	LeaseHoldersKey,
+	LeaseMaxHoldersKey,
+	LeaseOwnerTierKey,
+	LeasePreemptedByKey,
+	LeaseOwnerVersionKey,
+	LeaseCanaryKey,
+	LeaseSchemaVersionKey,
+	LeaseTombstonedAtKey,
+	LeaseLoadHintKey,
+	LeaseOwnerHostKey,
+	LeaseOwnerZoneKey,
+	LeaseGroupKey,
+	LeaseCompletedAtKey,
+	LeaseDependsOnKey,
+	LeaseParentKeysKey,
+	LeaseLabelsKey,
+	LeasePayloadKey,
+	LeaseTakeoverReasonKey,
+	LeaseEpochKey,
+	LeaseExpiresAtKey,
+	LeaseHeartbeatKey,
+	LeaseCheckpointKey,
+	LeasePartitionKey,
+}
+
+// packingKeys are the attributes that used by the serializer to hold the extra fields
+// packed (i.e: encrypted or compressed). unlike reservedKeys, they are written as part
+// of the extra fields.
+var packingKeys = []string{
+	LeaseEncryptedKey,
+	LeaseCompressedKey,
+	LeaseCompressionKey,
+	LeaseOverflowKey,
+	LeaseFieldsKey,
+}
+
+// isReserved test if the given attribute name belongs to this package.
+func isReserved(name string) bool {
+	for _, k := range reservedKeys {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isPacking test if the given attribute name is one of the packing attributes.
+func isPacking(name string) bool {
+	for _, k := range packingKeys {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnknown test if the given attribute name belongs to this package (i.e: it has the
+// "lease" prefix, followed by an upper case letter), but it's not known to this version
+// of it. For example, an attribute that was added by a newer version.
+//
+// Unknown attributes are preserved; they are written back as is, and they are never
+// packed, updated or removed. It allows workers of different versions to share the same
+// table during a rollout, without erasing each other's attributes.
+func isUnknown(name string) bool {
+	if len(name) <= len("lease") || !strings.HasPrefix(name, "lease") {
+		return false
+	}
+	if c := name[len("lease")]; c < 'A' || c > 'Z' {
+		return false
+	}
+	return !isReserved(name) && !isPacking(name)
 }
 
 // LeaseManager is the default implemntation of Manager
@@ -67,61 +314,14 @@ type LeaseManager struct {
 	Serializer Serializer
 }
 
-// CreateLeaseTable creates the table that will store the leases. succeeds
-// if it's  already exists.
-func (l *LeaseManager) CreateLeaseTable() (err error) {
-	for l.Backoff.Attempt() < maxCreateRetries {
-		_, err = l.Client.CreateTable(&dynamodb.CreateTableInput{
-			TableName: aws.String(l.LeaseTable),
-			AttributeDefinitions: []*dynamodb.AttributeDefinition{
-				{
-					AttributeName: aws.String(LeaseKeyKey),
-					AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
-				},
-			},
-			KeySchema: []*dynamodb.KeySchemaElement{
-				{
-					AttributeName: aws.String(LeaseKeyKey),
-					KeyType:       aws.String("HASH"),
-				},
-			},
-			ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-				ReadCapacityUnits:  aws.Int64(int64(l.LeaseTableReadCap)),
-				WriteCapacityUnits: aws.Int64(int64(l.LeaseTableWriteCap)),
-			},
-		})
-
-		// if the operation finished successfully, we need to "wait" until
-		// the lease table exists and active.
+// CreateLeaseTable creates the table that will store the leases, and waits until it's
+// active, so the first reads and writes do not race the table provisioning. succeeds
+// if it's  already exists (and it's active, e.g: if it was created by another worker).
+func (l *LeaseManager) CreateLeaseTable(ctx context.Context) (err error) {
+	r := l.retrier(RetryCreate)
+	for r.more() {
+		_, err = l.Client.CreateTableWithContext(ctx, l.createTableInput())
 		if err == nil {
-			l.Logger.WithField("table name", l.LeaseTable).Debugf("Worker %s creates the lease table and "+
-				"wait maximum %s until it will be %q",
-				l.WorkerId,
-				maxDurationTableStatus,
-				dynamodb.TableStatusActive)
-
-			duration := maxDurationTableStatus
-
-			for {
-				success := false
-
-				if status, ok := l.tableStatus(); ok && status == dynamodb.TableStatusActive {
-					success = true
-				}
-
-				if success || duration == 0 {
-					l.Logger.WithFields(logrus.Fields{
-						"success":    success,
-						"table name": l.LeaseTable,
-						"time taken": maxDurationTableStatus - duration,
-					}).Debugf("Worker %s stop waiting for table creation", l.WorkerId)
-					break
-				}
-
-				time.Sleep(durationBetweenPolls)
-				duration -= durationBetweenPolls
-			}
-
 			break
 		}
 
@@ -130,25 +330,128 @@ func (l *LeaseManager) CreateLeaseTable() (err error) {
 			break
 		}
 
-		backoff := l.Backoff.Duration()
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
 
-		l.Logger.WithFields(logrus.Fields{
+		l.Logger.WithFields(Fields{
 			"backoff": backoff,
-			"attempt": int(l.Backoff.Attempt()),
+			"attempt": r.attempt,
 		}).Warnf("Worker %s failed to create table", l.WorkerId)
 
-		time.Sleep(backoff)
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+	if err = l.retryError(r, "", err); err != nil {
+		return l.wrapError("create table", "", err)
+	}
+	if err = l.waitTableActive(ctx); err != nil {
+		return l.wrapError("create table", "", err)
+	}
+	if l.LeaseTTL > 0 {
+		if err = l.enableTTL(ctx); err != nil {
+			return l.wrapError("enable ttl", "", err)
+		}
+	}
+	if l.PointInTimeRecovery {
+		return l.wrapError("enable pitr", "", l.enablePITR(ctx))
+	}
+	return nil
+}
+
+// waitTableActive polls the status of the lease table until it's active, with a backoff
+// between the polls. Fails with ErrTableNotActive if it's not active within
+// maxDurationTableStatus, or with the error of the context if it's done.
+func (l *LeaseManager) waitTableActive(ctx context.Context) error {
+	start := l.now()
+	delay := minDurationBetweenPolls
+	for {
+		// the table may not be described yet, right after it's created.
+		status, ok := l.tableStatus(ctx)
+		if ok && status == dynamodb.TableStatusActive {
+			l.Logger.WithFields(Fields{
+				"table name": l.LeaseTable,
+				"time taken": l.now().Sub(start),
+			}).Debugf("Worker %s stop waiting for table creation", l.WorkerId)
+			return nil
+		}
+		if l.now().Sub(start)+delay > maxDurationTableStatus {
+			return ErrTableNotActive
+		}
+		l.Logger.WithField("table name", l.LeaseTable).Debugf("Worker %s waits %s until the lease table will be %q (status: %q)",
+			l.WorkerId,
+			delay,
+			dynamodb.TableStatusActive,
+			status)
+		if err := l.sleep(ctx, delay); err != nil {
+			return err
+		}
+		if delay *= 2; delay > durationBetweenPolls {
+			delay = durationBetweenPolls
+		}
 	}
-	l.Backoff.Reset()
-	return
+}
+
+// createTableInput returns the CreateTableInput of the lease table, in on-demand mode or
+// with the provisioned throughput of the config, and with its server-side encryption, tags
+// and owner index.
+func (l *LeaseManager) createTableInput() *dynamodb.CreateTableInput {
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(l.LeaseTable),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(LeaseKeyKey),
+				AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(LeaseKeyKey),
+				KeyType:       aws.String("HASH"),
+			},
+		},
+	}
+	if l.OnDemand {
+		input.BillingMode = aws.String(dynamodb.BillingModePayPerRequest)
+	} else {
+		input.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(int64(l.LeaseTableReadCap)),
+			WriteCapacityUnits: aws.Int64(int64(l.LeaseTableWriteCap)),
+		}
+	}
+	if len(l.LeaseTableTags) > 0 {
+		input.Tags = l.tableTags()
+	}
+	if l.OwnerIndex {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(LeaseOwnerKey),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		})
+		input.GlobalSecondaryIndexes = []*dynamodb.GlobalSecondaryIndex{l.ownerIndex()}
+	}
+	if l.SSEEnabled || l.SSEKMSKeyId != "" {
+		input.SSESpecification = &dynamodb.SSESpecification{
+			Enabled: aws.Bool(true),
+			SSEType: aws.String(dynamodb.SSETypeKms),
+		}
+		if l.SSEKMSKeyId != "" {
+			input.SSESpecification.KMSMasterKeyId = aws.String(l.SSEKMSKeyId)
+		}
+	}
+	return input
 }
 
 // tableStatus returns the "status" of the table, and boolean
 // that indicates if the operation success.
 //
 // The status could be: "CREATING", "UPDATING", "DELETING" or "ACTIVE"
-func (l *LeaseManager) tableStatus() (string, bool) {
-	resp, err := l.Client.DescribeTable(&dynamodb.DescribeTableInput{
+func (l *LeaseManager) tableStatus(ctx context.Context) (string, bool) {
+	resp, err := l.Client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
 		TableName: aws.String(l.LeaseTable),
 	})
 	if err != nil {
@@ -157,134 +460,732 @@ func (l *LeaseManager) tableStatus() (string, bool) {
 	return *resp.Table.TableStatus, true
 }
 
+// DeleteLeaseTable deletes the table that stores the leases, e.g: to tear down the table
+// of an integration test or an ephemeral environment. succeeds if it does not exist.
+// Fails with ErrDeleteTableUnsupported if the client does not support deleting tables.
+func (l *LeaseManager) DeleteLeaseTable(ctx context.Context) (err error) {
+	client, ok := l.Client.(tableDeleter)
+	if !ok {
+		return l.wrapError("delete table", "", ErrDeleteTableUnsupported)
+	}
+	r := l.retrier(RetryDelete)
+	for r.more() {
+		_, err = client.DeleteTableWithContext(ctx, &dynamodb.DeleteTableInput{
+			TableName: aws.String(l.LeaseTable),
+		})
+		if err == nil {
+			break
+		}
+
+		if isNotFound(err) {
+			err = nil
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to delete table", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+	return l.wrapError("delete table", "", l.retryError(r, "", err))
+}
+
+// LeaseTableExists reports whether the table that stores the leases exists. The table
+// may exist but not be active yet, e.g: if it's being created by another worker.
+func (l *LeaseManager) LeaseTableExists(ctx context.Context) (exists bool, err error) {
+	r := l.retrier(RetryGet)
+	for r.more() {
+		_, err = l.Client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(l.LeaseTable),
+		})
+		if err == nil {
+			exists = true
+			break
+		}
+
+		if isNotFound(err) {
+			err = nil
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to describe table", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+	if err = l.retryError(r, "", err); err != nil {
+		return false, l.wrapError("describe table", "", err)
+	}
+	return exists, nil
+}
+
 // Renew a lease by incrementing the lease counter.
 // Conditional on the leaseCounter in DynamoDB matching the leaseCounter of the input
 // Mutates the leaseCounter of the passed-in lease object after updating the record in DynamoDB.
-func (l *LeaseManager) RenewLease(lease *Lease) (err error) {
+func (l *LeaseManager) RenewLease(ctx context.Context, lease *Lease) error {
+	return l.renewLease(ctx, l, lease)
+}
+
+// leaseWriter is the conditional write of the ownership fields of a lease, that the
+// ownership changes (i.e: renew, take and evict) of the managers run on. it's a DynamoDB
+// conditional update in the LeaseManager, and a compare-and-update of the stored lease
+// in the backendManager.
+type leaseWriter interface {
+	condUpdate(ctx context.Context, updateLease, condLease Lease) error
+}
+
+// renewLease renews the given lease using the given writer. see: LeaseManager.RenewLease.
+func (c *Config) renewLease(ctx context.Context, w leaseWriter, lease *Lease) (err error) {
 	clease := *lease
-	clease.Counter++
-	if err = l.condUpdate(clease, *lease); err == nil {
+	clease.Counter = nextCounter(clease.Counter)
+	// write the load that reported by the owner.
+	if lease.reportedLoad != nil {
+		clease.LoadHint = *lease.reportedLoad
+	}
+	if err = w.condUpdate(ctx, clease, *lease); err == nil {
 		lease.Counter = clease.Counter
+		lease.LoadHint = clease.LoadHint
 	}
-	return
+	return c.wrapError("renew", lease.Key, err)
 }
 
 // Evict the current owner of lease by setting owner to null
 // Conditional on the owner in DynamoDB matching the owner of the input.
 // Mutates the lease owner of the passed-in lease object after updating the record in DynamoDB.
-func (l *LeaseManager) EvictLease(lease *Lease) (err error) {
+func (l *LeaseManager) EvictLease(ctx context.Context, lease *Lease) error {
+	return l.evictLease(ctx, l, lease)
+}
+
+// evictLease evicts the owner of the given lease using the given writer. see:
+// LeaseManager.EvictLease.
+func (c *Config) evictLease(ctx context.Context, w leaseWriter, lease *Lease) (err error) {
 	clease := *lease
 	clease.Owner = "NULL"
-	if err = l.condUpdate(clease, *lease); err == nil {
+	clease.OwnerTier = 0
+	clease.OwnerVersion = ""
+	clease.OwnerHost = ""
+	clease.OwnerZone = ""
+	if err = w.condUpdate(ctx, clease, *lease); err == nil {
 		lease.Owner = clease.Owner
+		lease.OwnerTier = clease.OwnerTier
+		lease.OwnerVersion = clease.OwnerVersion
+		lease.OwnerHost = clease.OwnerHost
+		lease.OwnerZone = clease.OwnerZone
 	}
-	return
+	return c.wrapError("evict", lease.Key, err)
 }
 
 // Take a lease by incrementing its leaseCounter and setting its owner field.
 // Conditional on the leaseCounter in DynamoDB matching the leaseCounter of the input
 // Mutates the lease counter and owner of the passed-in lease object after updating the record in DynamoDB.
-func (l *LeaseManager) TakeLease(lease *Lease) (err error) {
-	clease := *lease
-	clease.Counter++
-	clease.Owner = l.WorkerId
-	if err = l.condUpdate(clease, *lease); err == nil {
+func (l *LeaseManager) TakeLease(ctx context.Context, lease *Lease) error {
+	return l.takeLease(ctx, l, lease)
+}
+
+// takeLease takes the given lease for this worker using the given writer. see:
+// LeaseManager.TakeLease.
+func (c *Config) takeLease(ctx context.Context, w leaseWriter, lease *Lease) (err error) {
+	clease := c.takenLease(lease)
+	if err = w.condUpdate(ctx, clease, *lease); err == nil {
 		lease.Owner = clease.Owner
 		lease.Counter = clease.Counter
+		lease.Epoch = clease.Epoch
+		lease.OwnerTier = clease.OwnerTier
+		lease.OwnerVersion = clease.OwnerVersion
+		lease.OwnerHost = clease.OwnerHost
+		lease.OwnerZone = clease.OwnerZone
+		lease.TakeoverReason = clease.TakeoverReason
+		lease.PreemptedBy = clease.PreemptedBy
+		lease.ReservedBy = clease.ReservedBy
+		lease.ReservedUntil = clease.ReservedUntil
+		lease.Holders = clease.Holders
+		lease.OwnerSwitchesSinceCheckpoint = clease.OwnerSwitchesSinceCheckpoint
 	}
-	return
+	return c.wrapError("take", lease.Key, err)
+}
+
+// takenLease returns a copy of the given lease, as it's after this worker takes it.
+func (c *Config) takenLease(lease *Lease) Lease {
+	clease := *lease
+	clease.Counter = nextCounter(clease.Counter)
+	clease.Epoch++
+	clease.Owner = c.WorkerId
+	clease.OwnerTier = c.Tier
+	clease.OwnerVersion = c.Version
+	clease.OwnerHost = c.Host
+	clease.OwnerZone = c.Zone
+	clease.TakeoverReason = lease.takeReason
+	clease.PreemptedBy = ""
+	if c.KCLSchema && clease.Owner != lease.Owner {
+		clease.OwnerSwitchesSinceCheckpoint++
+	}
+	// the reservation is fulfilled once the reserving worker takes the lease.
+	if clease.ReservedBy == c.WorkerId {
+		clease.ReservedBy = ""
+		clease.ReservedUntil = time.Time{}
+	}
+	// the lease is held exclusively from now on. the caller is responsible to
+	// not take leases with active shared holders, and the counter condition
+	// protects against shared holders that joined after the last scan.
+	clease.Holders = nil
+	return clease
 }
 
-// ListLeasses returns all the lease units stored in the table.
-func (l *LeaseManager) ListLeases() (list []*Lease, err error) {
+// Reserve a lease for this worker until the given time, by setting its reservation fields.
+// Conditional on the lease not being reserved by another worker, or that the existing
+// reservation already lapsed.
+// Mutates the reservation fields of the passed-in lease object after updating the record in DynamoDB.
+func (l *LeaseManager) ReserveLease(ctx context.Context, lease *Lease, until time.Time) error {
+	_, err := l.updateLease(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(l.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {
+				S: aws.String(lease.Key),
+			},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":by": {
+				S: aws.String(l.WorkerId),
+			},
+			":until": {
+				N: aws.String(strconv.FormatInt(until.Unix(), 10)),
+			},
+			":now": {
+				N: aws.String(strconv.FormatInt(l.now().Unix(), 10)),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#key":   aws.String(LeaseKeyKey),
+			"#by":    aws.String(LeaseReservedByKey),
+			"#until": aws.String(LeaseReservedUntilKey),
+		},
+		UpdateExpression:    aws.String("SET #by = :by, #until = :until"),
+		ConditionExpression: aws.String("attribute_exists(#key) AND (attribute_not_exists(#by) OR #by = :by OR #until < :now)"),
+	})
+	if err == nil {
+		lease.ReservedBy = l.WorkerId
+		lease.ReservedUntil = time.Unix(until.Unix(), 0)
+	}
+	return l.wrapError("reserve", lease.Key, err)
+}
+
+// Preempt a lease by requesting its owner to drain it and hand it over to this worker.
+// The lease is also reserved for this worker, so other workers will not take it after it released.
+// Conditional on the owner in DynamoDB matching the owner of the input, having a lower tier than
+// this worker, and on the lease not being preempted already.
+// Mutates the preemption and the reservation fields of the passed-in lease object after updating the record in DynamoDB.
+func (l *LeaseManager) PreemptLease(ctx context.Context, lease *Lease) error {
+	until := l.now().Add(l.ExpireAfter * 2)
+	_, err := l.updateLease(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(l.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {
+				S: aws.String(lease.Key),
+			},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":by": {
+				S: aws.String(l.WorkerId),
+			},
+			":until": {
+				N: aws.String(strconv.FormatInt(until.Unix(), 10)),
+			},
+			":tier": {
+				N: aws.String(strconv.Itoa(l.Tier)),
+			},
+			":condOwner": {
+				S: aws.String(lease.Owner),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":     aws.String(LeaseOwnerKey),
+			"#tier":      aws.String(LeaseOwnerTierKey),
+			"#preempted": aws.String(LeasePreemptedByKey),
+			"#by":        aws.String(LeaseReservedByKey),
+			"#until":     aws.String(LeaseReservedUntilKey),
+		},
+		UpdateExpression: aws.String("SET #preempted = :by, #by = :by, #until = :until"),
+		ConditionExpression: aws.String("#owner = :condOwner AND (attribute_not_exists(#tier) OR #tier < :tier) " +
+			"AND attribute_not_exists(#preempted)"),
+	})
+	if err == nil {
+		lease.PreemptedBy = l.WorkerId
+		lease.ReservedBy = l.WorkerId
+		lease.ReservedUntil = time.Unix(until.Unix(), 0)
+	}
+	return l.wrapError("preempt", lease.Key, err)
+}
+
+// Acquire a lease in shared mode by adding this worker to its shared holders, and
+// incrementing its leaseCounter. expired holders are removed on the way.
+// Conditional on the leaseCounter in DynamoDB matching the leaseCounter of the input, and
+// on the lease not being held exclusively.
+// Fails with ErrLeaseFull if the passed-in lease is a semaphore lease that already reached its
+// maximum number of holders; the leaseCounter condition guarantees that this limit holds even
+// when multiple workers acquire the lease concurrently.
+// Mutates the lease counter and holders of the passed-in lease object after updating the record in DynamoDB.
+func (l *LeaseManager) AcquireSharedLease(ctx context.Context, lease *Lease) error {
+	holders, err := l.acquiredHolders(lease)
+	if err != nil {
+		return l.wrapError("acquire shared", lease.Key, err)
+	}
+	av, err := dynamodbattribute.Marshal(holders)
+	if err != nil {
+		return l.wrapError("acquire shared", lease.Key, err)
+	}
+	_, err = l.updateLease(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(l.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {
+				S: aws.String(lease.Key),
+			},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":holders": av,
+			":count": {
+				N: aws.String(strconv.FormatInt(nextCounter(lease.Counter), 10)),
+			},
+			":condCounter": {
+				N: aws.String(strconv.FormatInt(lease.Counter, 10)),
+			},
+			":null": {
+				S: aws.String("NULL"),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#holders": aws.String(LeaseHoldersKey),
+			"#counter": aws.String(LeaseCounterKey),
+			"#owner":   aws.String(LeaseOwnerKey),
+		},
+		UpdateExpression:    aws.String("SET #holders = :holders, #counter = :count"),
+		ConditionExpression: aws.String("#counter = :condCounter AND (attribute_not_exists(#owner) OR #owner = :null)"),
+	})
+	if err == nil {
+		lease.Counter = nextCounter(lease.Counter)
+		lease.Holders = holders
+	}
+	return l.wrapError("acquire shared", lease.Key, err)
+}
+
+// acquiredHolders returns the shared holders of the given lease, after this worker acquires
+// it. expired holders are removed, and it fails with ErrLeaseFull if the lease is a semaphore
+// that already reached its maximum number of holders. see: LeaseManager.AcquireSharedLease.
+func (c *Config) acquiredHolders(lease *Lease) (map[string]int64, error) {
+	holders := lease.activeHolders(c.ExpireAfter, c.now())
+	if _, ok := holders[c.WorkerId]; !ok && lease.isSemaphore() && len(holders) >= lease.MaxHolders {
+		return nil, ErrLeaseFull
+	}
+	holders[c.WorkerId] = c.now().Unix()
+	return holders, nil
+}
+
+// Renew a lease that held in shared mode by refreshing the renewal time of this worker,
+// and incrementing its leaseCounter.
+// Conditional on this worker being one of the lease shared holders.
+// Mutates the lease counter and holders of the passed-in lease object after updating the record in DynamoDB.
+func (l *LeaseManager) RenewSharedLease(ctx context.Context, lease *Lease) error {
+	now := l.now().Unix()
+	ulease, err := l.sharedUpdate(ctx, lease, "SET #holders.#worker = :now ADD #counter :one", map[string]*dynamodb.AttributeValue{
+		":now": {
+			N: aws.String(strconv.FormatInt(now, 10)),
+		},
+	})
+	if err == nil {
+		lease.Counter = ulease.Counter
+		lease.Holders = ulease.Holders
+	}
+	return l.wrapError("renew shared", lease.Key, err)
+}
+
+// Release a lease that held in shared mode by removing this worker from its shared holders,
+// and incrementing its leaseCounter.
+// Conditional on this worker being one of the lease shared holders.
+// Mutates the lease counter and holders of the passed-in lease object after updating the record in DynamoDB.
+func (l *LeaseManager) ReleaseSharedLease(ctx context.Context, lease *Lease) error {
+	ulease, err := l.sharedUpdate(ctx, lease, "REMOVE #holders.#worker ADD #counter :one", nil)
+	if err == nil {
+		lease.Counter = ulease.Counter
+		lease.Holders = ulease.Holders
+	}
+	return l.wrapError("release shared", lease.Key, err)
+}
+
+// sharedUpdate updates the lease shared holders using the given update expression.
+// it is conditional on this worker being one of the lease shared holders.
+func (l *LeaseManager) sharedUpdate(ctx context.Context, lease *Lease, exp string, values map[string]*dynamodb.AttributeValue) (*Lease, error) {
+	if values == nil {
+		values = make(map[string]*dynamodb.AttributeValue)
+	}
+	values[":one"] = &dynamodb.AttributeValue{N: aws.String("1")}
+	return l.updateLease(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(l.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {
+				S: aws.String(lease.Key),
+			},
+		},
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+		ExpressionAttributeValues: values,
+		ExpressionAttributeNames: map[string]*string{
+			"#holders": aws.String(LeaseHoldersKey),
+			"#worker":  aws.String(l.WorkerId),
+			"#counter": aws.String(LeaseCounterKey),
+		},
+		UpdateExpression:    aws.String(exp),
+		ConditionExpression: aws.String("attribute_exists(#holders.#worker)"),
+	})
+}
+
+// GetLease returns the lease with the given key using a consistent read.
+// Fails with ErrLeaseNotFound if the lease does not exist.
+func (l *LeaseManager) GetLease(ctx context.Context, key string) (*Lease, error) {
+	var (
+		err error
+		out *dynamodb.GetItemOutput
+	)
+	r := l.retrier(RetryGet)
+	for r.more() {
+		out, err = l.Client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(l.LeaseTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				LeaseKeyKey: {
+					S: aws.String(key),
+				},
+			},
+			ConsistentRead: aws.Bool(true),
+		})
+
+		if err == nil {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to get lease", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+
+	if err = l.retryError(r, key, err); err != nil {
+		return nil, l.wrapError("get", key, err)
+	}
+
+	if len(out.Item) == 0 {
+		return nil, l.wrapError("get", key, ErrLeaseNotFound)
+	}
+
+	lease, err := l.Serializer.Decode(out.Item)
+	if err != nil {
+		return nil, l.wrapError("get", key, err)
+	}
+	if lease.isTombstoned() {
+		return nil, l.wrapError("get", key, ErrLeaseNotFound)
+	}
+	return lease, nil
+}
+
+// readClient returns the client of the eventually consistent reads. See: Config.ReadClient.
+func (l *LeaseManager) readClient() Clientface {
+	if l.ReadClient != nil {
+		return l.ReadClient
+	}
+	return l.Client
+}
+
+// ListLeasses returns all the lease units stored in the table. The scan goes through
+// Client, so the renewer reads the counters that its conditional writes are checked against.
+func (l *LeaseManager) ListLeases(ctx context.Context) ([]*Lease, error) {
+	return l.scanLeases(ctx, l.Client)
+}
+
+// ScanLeases returns all the lease units stored in the table, like ListLeases, but the
+// scan goes through the ReadClient. The result may be stale, so it's used only by the
+// taker and by the read-only APIs. See: Config.ReadClient.
+func (l *LeaseManager) ScanLeases(ctx context.Context) ([]*Lease, error) {
+	return l.scanLeases(ctx, l.readClient())
+}
+
+// scanLeases returns all the lease units stored in the table, using the given client.
+func (l *LeaseManager) scanLeases(ctx context.Context, client Clientface) (list []*Lease, err error) {
 	var res *dynamodb.ScanOutput
-	for l.Backoff.Attempt() < maxScanRetries {
-		res, err = l.Client.Scan(&dynamodb.ScanInput{
+	var mode *Maintenance
+	r := l.retrier(RetryList)
+	for r.more() {
+		res, err = client.ScanWithContext(ctx, &dynamodb.ScanInput{
 			TableName: aws.String(l.LeaseTable),
 		})
 		if err != nil {
-			backoff := l.Backoff.Duration()
+			// permanent errors return immediately.
+			if !r.retryable(err) {
+				break
+			}
 
-			l.Logger.WithFields(logrus.Fields{
+			backoff := r.delay()
+
+			l.Logger.WithFields(Fields{
 				"backoff": backoff,
-				"attempt": int(l.Backoff.Attempt()),
+				"attempt": r.attempt,
 			}).Warnf("Worker %s failed to scan leases table", l.WorkerId)
 
-			time.Sleep(backoff)
+			if serr := l.sleep(ctx, backoff); serr != nil {
+				err = serr
+				break
+			}
 			continue
 		}
 		for _, item := range res.Items {
+			// the steal budget bucket, the maintenance mode and the admin intents are not leases.
+			if k := item[LeaseKeyKey]; k != nil && isInternalItem(aws.StringValue(k.S)) {
+				if aws.StringValue(k.S) == MaintenanceKey {
+					mode = readMaintenance(item)
+				}
+				continue
+			}
 			if lease, err := l.Serializer.Decode(item); err != nil {
-				l.Logger.WithError(err).Error("decode lease")
+				l.Logger.WithError(err).Errorf("decode lease")
 			} else {
 				list = append(list, lease)
 			}
 		}
+		l.maintenance.set(mode)
 		break
 	}
-	l.Backoff.Reset()
-	return
+	return list, l.wrapError("list", "", l.retryError(r, "", err))
 }
 
 // Delete the given lease from DynamoDB. does nothing when passed a
 // lease that does not exist in DynamoDB.
-func (l *LeaseManager) DeleteLease(lease *Lease) (err error) {
-	for l.Backoff.Attempt() < maxDeleteRetries {
-		_, err = l.Client.DeleteItem(&dynamodb.DeleteItemInput{
-			TableName: aws.String(l.LeaseTable),
-			Key: map[string]*dynamodb.AttributeValue{
-				LeaseKeyKey: {
-					S: aws.String(lease.Key),
-				},
+// If an Archiver is configured, the final snapshot of the lease is archived.
+// In soft-delete mode (see: Config.SoftDelete), the lease is tombstoned instead.
+func (l *LeaseManager) DeleteLease(ctx context.Context, lease *Lease) error {
+	if l.SoftDelete {
+		return l.wrapError("delete", lease.Key, l.tombstoneLease(ctx, lease))
+	}
+	err := l.deleteLease(ctx, lease, &dynamodb.DeleteItemInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":condOwner": {
+				S: aws.String(lease.Owner),
 			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String(LeaseOwnerKey),
+			"#key":   aws.String(LeaseKeyKey),
+		},
+		ConditionExpression:                 aws.String("attribute_not_exists(#key) OR " + l.ownerCondition(lease.Owner)),
+		ReturnValuesOnConditionCheckFailure: aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld),
+	})
+	return l.wrapError("delete", lease.Key, conditionError(err, Lease{Owner: lease.Owner}))
+}
+
+// deleteLease gets a DeleteItemInput with the condition of the deletion, and calls
+// Client.DeleteItem with the retries logic.
+func (l *LeaseManager) deleteLease(ctx context.Context, lease *Lease, input *dynamodb.DeleteItemInput) (err error) {
+	input.TableName = aws.String(l.LeaseTable)
+	input.Key = map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey: {
+			S: aws.String(lease.Key),
+		},
+	}
+	input.ReturnValues = aws.String(dynamodb.ReturnValueAllOld)
+	var out *dynamodb.DeleteItemOutput
+	r := l.retrier(RetryDelete)
+	for r.more() {
+		out, err = l.Client.DeleteItemWithContext(ctx, input)
+
+		if err == nil {
+			break
+		}
+
+		if isConditionalFailed(err) {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to delete lease", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+	err = l.retryError(r, lease.Key, err)
+
+	// archive the final snapshot of the deleted lease. the lease is already deleted,
+	// so a failure is logged, and not returned.
+	if err == nil && l.Archiver != nil && out != nil && len(out.Attributes) > 0 {
+		if aerr := l.archive(out.Attributes); aerr != nil {
+			l.Logger.WithError(aerr).Errorf("Worker %s failed to archive lease %s", l.WorkerId, lease.Key)
+		}
+	}
+
+	// delete the overflowed extra fields of the deleted lease.
+	if err == nil && lease.overflow != nil && l.Overflow != nil {
+		if oerr := l.Overflow.Delete(lease.overflow.pointer); oerr != nil {
+			l.Logger.WithError(oerr).Warnf("Worker %s failed to delete overflowed fields of lease %s", l.WorkerId, lease.Key)
+		}
+	}
+	return
+}
+
+// Create a new lease. conditional on a lease not already existing with different
+// owner and counter. tombstoned leases are re-created.
+func (l *LeaseManager) CreateLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	if lease.Owner == "" {
+		lease.Owner = l.WorkerId
+		lease.OwnerTier = l.Tier
+		lease.OwnerVersion = l.Version
+		lease.OwnerHost = l.Host
+		lease.OwnerZone = l.Zone
+	}
+	if lease.Counter == 0 {
+		lease.Counter++
+	}
+	item, err := l.Serializer.Encode(lease)
+	if err != nil {
+		return lease, l.wrapError("create", lease.Key, err)
+	}
+	r := l.retrier(RetryCreate)
+	for r.more() {
+		_, err = l.Client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(l.LeaseTable),
+			Item:      item,
 			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 				":condOwner": {
 					S: aws.String(lease.Owner),
 				},
+				":condCounter": {
+					N: aws.String(strconv.FormatInt(lease.Counter, 10)),
+				},
 			},
 			ExpressionAttributeNames: map[string]*string{
-				"#owner": aws.String(LeaseOwnerKey),
-				"#key":   aws.String(LeaseKeyKey),
+				"#counter":   aws.String(LeaseCounterKey),
+				"#owner":     aws.String(LeaseOwnerKey),
+				"#key":       aws.String(LeaseKeyKey),
+				"#tombstone": aws.String(LeaseTombstonedAtKey),
 			},
-			ConditionExpression: aws.String("attribute_not_exists(#key) OR #owner = :condOwner"),
+			ConditionExpression: aws.String("attribute_not_exists(#key) OR #counter = :condCounter AND " + l.ownerCondition(lease.Owner) +
+				" OR attribute_exists(#tombstone)"),
 		})
 
 		if err == nil {
 			break
 		}
 
-		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == ConditionalFailed {
+		if isConditionalFailed(err) {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
 			break
 		}
 
-		backoff := l.Backoff.Duration()
+		backoff := r.delay()
 
-		l.Logger.WithFields(logrus.Fields{
+		l.Logger.WithFields(Fields{
 			"backoff": backoff,
-			"attempt": int(l.Backoff.Attempt()),
-		}).Warnf("Worker %s failed to delete lease", l.WorkerId)
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to create lease", l.WorkerId)
 
-		time.Sleep(backoff)
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
 	}
-	l.Backoff.Reset()
-	return
+
+	if err = l.retryError(r, lease.Key, err); err != nil {
+		return nil, l.wrapError("create", lease.Key, err)
+	}
+
+	// the ReturnValues argument can only be ALL_OLD or NONE, it means that
+	// our lease object is the most updated.
+	return lease, nil
 }
 
-// Create a new lease. conditional on a lease not already existing with different
-// owner and counter.
-func (l *LeaseManager) CreateLease(lease *Lease) (*Lease, error) {
-	if lease.Owner == "" {
-		lease.Owner = l.WorkerId
+// MigrateLeases upgrades all the leases in the table to the latest schema version,
+// and returns the number of leases that were upgraded. Each lease is written only if
+// it was not changed since it was read; leases that changed in the meantime are left
+// to the next run, or to their next update.
+func (l *LeaseManager) MigrateLeases(ctx context.Context) (n int, err error) {
+	if l.Migrator == nil {
+		return 0, nil
 	}
-	if lease.Counter == 0 {
-		lease.Counter++
+	list, err := l.ListLeases(ctx)
+	if err != nil {
+		return 0, l.wrapError("migrate", "", err)
+	}
+	for _, lease := range list {
+		if !lease.migrated {
+			continue
+		}
+		if err = l.putLease(ctx, lease); err != nil {
+			if isConditionalFailed(err) {
+				continue
+			}
+			return n, l.wrapError("migrate", lease.Key, err)
+		}
+		n++
 	}
+	return n, nil
+}
+
+// putLease replaces the stored lease with the given lease object. conditional on the
+// lease not being changed since it was read.
+func (l *LeaseManager) putLease(ctx context.Context, lease *Lease) error {
 	item, err := l.Serializer.Encode(lease)
 	if err != nil {
-		return lease, err
+		return err
 	}
-	for l.Backoff.Attempt() < maxCreateRetries {
-		_, err = l.Client.PutItem(&dynamodb.PutItemInput{
+	r := l.retrier(RetryUpdate)
+	for r.more() {
+		_, err = l.Client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
 			TableName: aws.String(l.LeaseTable),
 			Item:      item,
 			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
@@ -292,44 +1193,109 @@ func (l *LeaseManager) CreateLease(lease *Lease) (*Lease, error) {
 					S: aws.String(lease.Owner),
 				},
 				":condCounter": {
-					N: aws.String(strconv.Itoa(lease.Counter)),
+					N: aws.String(strconv.FormatInt(lease.Counter, 10)),
 				},
 			},
 			ExpressionAttributeNames: map[string]*string{
 				"#counter": aws.String(LeaseCounterKey),
 				"#owner":   aws.String(LeaseOwnerKey),
-				"#key":     aws.String(LeaseKeyKey),
 			},
-			ConditionExpression: aws.String("attribute_not_exists(#key) OR #counter = :condCounter AND #owner = :condOwner"),
+			ConditionExpression: aws.String("#counter = :condCounter AND " + l.ownerCondition(lease.Owner)),
 		})
 
 		if err == nil {
 			break
 		}
 
-		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == ConditionalFailed {
+		if isConditionalFailed(err) {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
 			break
 		}
 
-		backoff := l.Backoff.Duration()
+		backoff := r.delay()
 
-		l.Logger.WithFields(logrus.Fields{
+		l.Logger.WithFields(Fields{
 			"backoff": backoff,
-			"attempt": int(l.Backoff.Attempt()),
-		}).Warnf("Worker %s failed to create lease", l.WorkerId)
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to migrate lease", l.WorkerId)
 
-		time.Sleep(backoff)
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
 	}
 
-	l.Backoff.Reset()
+	err = l.retryError(r, lease.Key, err)
+
+	if err == nil {
+		lease.migrated = false
+	}
+	return err
+}
 
+// EnsureLease creates the given lease if it does not exist (or if it's tombstoned),
+// and returns true if it was created. existing leases are left as is.
+func (l *LeaseManager) EnsureLease(ctx context.Context, lease *Lease) (bool, error) {
+	if lease.Owner == "" {
+		lease.Owner = "NULL"
+	}
+	if lease.Counter == 0 {
+		lease.Counter++
+	}
+	item, err := l.Serializer.Encode(lease)
 	if err != nil {
-		return nil, err
+		return false, l.wrapError("ensure", lease.Key, err)
 	}
+	r := l.retrier(RetryCreate)
+	for r.more() {
+		_, err = l.Client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(l.LeaseTable),
+			Item:      item,
+			ExpressionAttributeNames: map[string]*string{
+				"#key":       aws.String(LeaseKeyKey),
+				"#tombstone": aws.String(LeaseTombstonedAtKey),
+			},
+			ConditionExpression: aws.String("attribute_not_exists(#key) OR attribute_exists(#tombstone)"),
+		})
 
-	// the ReturnValues argument can only be ALL_OLD or NONE, it means that
-	// our lease object is the most updated.
-	return lease, nil
+		if err == nil {
+			break
+		}
+
+		if isConditionalFailed(err) {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to create lease", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+
+	if err = l.retryError(r, lease.Key, err); err != nil {
+		// the lease already exists.
+		if isConditionalFailed(err) {
+			return false, nil
+		}
+		return false, l.wrapError("ensure", lease.Key, err)
+	}
+	return true, nil
 }
 
 // UpdateLease used to update only the extra fields on the Lease object.
@@ -337,22 +1303,30 @@ func (l *LeaseManager) CreateLease(lease *Lease) (*Lease, error) {
 // other fields.
 // for example: {"status": "done", "last_update": "unix seconds"}
 // To add extra fields on a Lease, use Lease.Set(key, val)
-func (l *LeaseManager) UpdateLease(lease *Lease) (*Lease, error) {
+// The checkpoint is written if it was set using Lease.SetCheckpoint (see: Leaser.Checkpoint),
+// and the payload if it was set using Lease.SetPayload. These writes are conditional on the
+// owner of the lease (and with Config.KCLSchema, on its counter), and fail with a
+// *ConditionError if the lease was taken by another worker.
+func (l *LeaseManager) UpdateLease(ctx context.Context, lease *Lease) (*Lease, error) {
 	var (
-		attExp     string
-		attVal     map[string]*dynamodb.AttributeValue
-		isReserved = func(w string) bool { return w == LeaseKeyKey || w == LeaseOwnerKey || w == LeaseCounterKey }
+		attExp string
+		attVal map[string]*dynamodb.AttributeValue
+		rmSet  = make(map[string]bool)
 	)
 
 	// set fields
-	if len(lease.extrafields) > 0 || len(lease.explicitfields) > 0 {
+	if len(lease.extrafields) > 0 || len(lease.explicitfields) > 0 || len(lease.removedfields) > 0 || lease.migrated || lease.checkpointed || lease.payloadSet {
 		item, err := l.Serializer.Encode(lease)
 		if err != nil {
-			return lease, err
+			return lease, l.wrapError("update", lease.Key, err)
 		}
 		setExp := make([]string, 0)
 		for k, v := range item {
-			if !isReserved(k) {
+			// the KCL attributes are written only with a new checkpoint.
+			if l.KCLSchema && isKCL(k) && !lease.checkpointed {
+				continue
+			}
+			if !isReserved(k) && !isUnknown(k) {
 				// if it's the first time we add entry to the map
 				if attVal == nil {
 					attVal = make(map[string]*dynamodb.AttributeValue)
@@ -361,30 +1335,66 @@ func (l *LeaseManager) UpdateLease(lease *Lease) (*Lease, error) {
 				attVal[":"+k] = v
 			}
 		}
+		// write the schema version of the upgraded lease, with its upgraded fields.
+		if v, ok := item[LeaseSchemaVersionKey]; ok && lease.migrated {
+			if attVal == nil {
+				attVal = make(map[string]*dynamodb.AttributeValue)
+			}
+			setExp = append(setExp, fmt.Sprintf("%s = :%s", LeaseSchemaVersionKey, LeaseSchemaVersionKey))
+			attVal[":"+LeaseSchemaVersionKey] = v
+		}
+		// write the new checkpoint and payload, or remove them if they were cleared.
+		for _, k := range lease.setKeys(l.KCLSchema) {
+			if v, ok := item[k]; ok {
+				if attVal == nil {
+					attVal = make(map[string]*dynamodb.AttributeValue)
+				}
+				setExp = append(setExp, fmt.Sprintf("%s = :%s", k, k))
+				attVal[":"+k] = v
+			} else {
+				rmSet[k] = true
+			}
+		}
 		if len(setExp) > 0 {
 			attExp += "SET " + strings.Join(setExp, ", ")
 		}
+		// remove the fields that packed by the serializer (i.e: encoded, encrypted or compressed),
+		// and the packing attributes that are no longer in use.
+		if l.Cipher != nil || l.Compressor != nil || l.Overflow != nil || l.Codec != nil {
+			for _, k := range lease.fieldKeys() {
+				if _, ok := item[k]; !ok {
+					rmSet[k] = true
+				}
+			}
+			for _, k := range packingKeys {
+				if _, ok := item[k]; !ok {
+					rmSet[k] = true
+				}
+			}
+		}
 	}
 
 	// remove fields
-	if len(lease.removedfields) > 0 {
-		rmExp := make([]string, 0)
-		for _, f := range lease.removedfields {
-			if !isReserved(f) {
-				rmExp = append(rmExp, f)
-			}
-		}
-		if len(rmExp) > 0 {
-			attExp += " REMOVE " + strings.Join(rmExp, ", ")
+	for _, f := range lease.removedfields {
+		rmSet[f] = true
+	}
+	rmExp := make([]string, 0)
+	for f := range rmSet {
+		if (!isReserved(f) || f == LeaseCheckpointKey || f == LeasePayloadKey) && !isUnknown(f) {
+			rmExp = append(rmExp, f)
 		}
 	}
+	if len(rmExp) > 0 {
+		sort.Strings(rmExp)
+		attExp += " REMOVE " + strings.Join(rmExp, ", ")
+	}
 
 	// if there's nothing to update
 	if attExp == "" {
 		return lease, nil
 	}
 
-	return l.updateLease(&dynamodb.UpdateItemInput{
+	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(l.LeaseTable),
 		Key: map[string]*dynamodb.AttributeValue{
 			LeaseKeyKey: {
@@ -394,12 +1404,39 @@ func (l *LeaseManager) UpdateLease(lease *Lease) (*Lease, error) {
 		UpdateExpression:          aws.String(attExp),
 		ExpressionAttributeValues: attVal,
 		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
-	})
+	}
+	cond := lease.updateCondition(l.KCLSchema)
+	if cond.Owner != "" {
+		if input.ExpressionAttributeValues == nil {
+			input.ExpressionAttributeValues = make(map[string]*dynamodb.AttributeValue)
+		}
+		input.ExpressionAttributeValues[":condOwner"] = &dynamodb.AttributeValue{S: aws.String(cond.Owner)}
+		input.ExpressionAttributeNames = map[string]*string{"#owner": aws.String(LeaseOwnerKey)}
+		condExp := "#owner = :condOwner"
+		if cond.Counter > 0 {
+			input.ExpressionAttributeValues[":condCounter"] = &dynamodb.AttributeValue{
+				N: aws.String(strconv.FormatInt(cond.Counter, 10)),
+			}
+			input.ExpressionAttributeNames["#counter"] = aws.String(LeaseCounterKey)
+			condExp += " AND #counter = :condCounter"
+		}
+		input.ConditionExpression = aws.String(condExp)
+		// return the lease that failed the condition. see: ConditionError.
+		input.ReturnValuesOnConditionCheckFailure = aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld)
+	}
+	ulease, err := l.updateLease(ctx, input)
+	return ulease, l.wrapError("update", lease.Key, conditionError(err, cond))
 }
 
 // condLease gets a 2 Lease objects. the first one is for the update attributes
 // and the second used to construct the condition expression.
-func (l *LeaseManager) condUpdate(updateLease, condLease Lease) (err error) {
+func (l *LeaseManager) condUpdate(ctx context.Context, updateLease, condLease Lease) (err error) {
+	_, err = l.updateLease(ctx, l.condUpdateInput(updateLease, condLease))
+	return conditionError(err, condLease)
+}
+
+// condUpdateInput returns the conditional UpdateItemInput of condUpdate.
+func (l *LeaseManager) condUpdateInput(updateLease, condLease Lease) *dynamodb.UpdateItemInput {
 	updateInput := &dynamodb.UpdateItemInput{
 		TableName: aws.String(l.LeaseTable),
 		Key: map[string]*dynamodb.AttributeValue{
@@ -408,29 +1445,141 @@ func (l *LeaseManager) condUpdate(updateLease, condLease Lease) (err error) {
 			},
 		},
 		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+		// return the lease that failed the condition. see: ConditionError.
+		ReturnValuesOnConditionCheckFailure: aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld),
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 			":owner": {
 				S: aws.String(updateLease.Owner),
 			},
 			":count": {
-				N: aws.String(strconv.Itoa(updateLease.Counter)),
+				N: aws.String(strconv.FormatInt(updateLease.Counter, 10)),
 			},
 		},
-		UpdateExpression: aws.String(fmt.Sprintf(
-			"SET %s = :owner, %s = :count",
-			LeaseOwnerKey,
-			LeaseCounterKey,
-		)),
 	}
 
+	setExp := []string{
+		fmt.Sprintf("%s = :owner", LeaseOwnerKey),
+		fmt.Sprintf("%s = :count", LeaseCounterKey),
+	}
+	// remove the owner version, the reservation, the preemption request or the shared holders
+	// if they were released.
+	var rmExp []string
+	// the KCL leases have no owner attribute if they are not held.
+	if l.KCLSchema && updateLease.hasNoOwner() {
+		delete(updateInput.ExpressionAttributeValues, ":owner")
+		setExp = setExp[1:]
+		rmExp = append(rmExp, LeaseOwnerKey)
+	}
+	// the owner tier, version, host and zone change only with the owner.
+	if updateLease.Owner != condLease.Owner {
+		updateInput.ExpressionAttributeValues[":tier"] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.Itoa(updateLease.OwnerTier)),
+		}
+		setExp = append(setExp, fmt.Sprintf("%s = :tier", LeaseOwnerTierKey))
+		if updateLease.OwnerVersion != "" {
+			updateInput.ExpressionAttributeValues[":version"] = &dynamodb.AttributeValue{
+				S: aws.String(updateLease.OwnerVersion),
+			}
+			setExp = append(setExp, fmt.Sprintf("%s = :version", LeaseOwnerVersionKey))
+		} else if condLease.OwnerVersion != "" {
+			rmExp = append(rmExp, LeaseOwnerVersionKey)
+		}
+		if updateLease.OwnerHost != "" {
+			updateInput.ExpressionAttributeValues[":host"] = &dynamodb.AttributeValue{
+				S: aws.String(updateLease.OwnerHost),
+			}
+			setExp = append(setExp, fmt.Sprintf("%s = :host", LeaseOwnerHostKey))
+		} else if condLease.OwnerHost != "" {
+			rmExp = append(rmExp, LeaseOwnerHostKey)
+		}
+		if updateLease.OwnerZone != "" {
+			updateInput.ExpressionAttributeValues[":zone"] = &dynamodb.AttributeValue{
+				S: aws.String(updateLease.OwnerZone),
+			}
+			setExp = append(setExp, fmt.Sprintf("%s = :zone", LeaseOwnerZoneKey))
+		} else if condLease.OwnerZone != "" {
+			rmExp = append(rmExp, LeaseOwnerZoneKey)
+		}
+		// the takeover reason describes the last take, and it's kept on eviction.
+		if updateLease.TakeoverReason != condLease.TakeoverReason && !updateLease.hasNoOwner() {
+			if updateLease.TakeoverReason != "" {
+				updateInput.ExpressionAttributeValues[":reason"] = &dynamodb.AttributeValue{
+					S: aws.String(string(updateLease.TakeoverReason)),
+				}
+				setExp = append(setExp, fmt.Sprintf("%s = :reason", LeaseTakeoverReasonKey))
+			} else {
+				rmExp = append(rmExp, LeaseTakeoverReasonKey)
+			}
+		}
+	}
+	if updateLease.Epoch != condLease.Epoch {
+		updateInput.ExpressionAttributeValues[":epoch"] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.Itoa(updateLease.Epoch)),
+		}
+		setExp = append(setExp, fmt.Sprintf("%s = :epoch", LeaseEpochKey))
+	}
+	if updateLease.LoadHint != condLease.LoadHint {
+		updateInput.ExpressionAttributeValues[":load"] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatFloat(updateLease.LoadHint, 'f', -1, 64)),
+		}
+		setExp = append(setExp, fmt.Sprintf("%s = :load", LeaseLoadHintKey))
+	}
+	if updateLease.OwnerSwitchesSinceCheckpoint != condLease.OwnerSwitchesSinceCheckpoint {
+		updateInput.ExpressionAttributeValues[":switches"] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.Itoa(updateLease.OwnerSwitchesSinceCheckpoint)),
+		}
+		setExp = append(setExp, fmt.Sprintf("%s = :switches", KCLOwnerSwitchesSinceCheckpointKey))
+	}
+	if l.Heartbeat && !updateLease.hasNoOwner() {
+		updateInput.ExpressionAttributeValues[":heartbeat"] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(l.now().Unix(), 10)),
+		}
+		setExp = append(setExp, fmt.Sprintf("%s = :heartbeat", LeaseHeartbeatKey))
+	} else if !condLease.Heartbeat.IsZero() {
+		// a heartbeat that is not refreshed by this write would expire the lease early.
+		rmExp = append(rmExp, LeaseHeartbeatKey)
+	}
+	// the expiry of DynamoDB TTL is extended while the lease is owned.
+	if l.LeaseTTL > 0 {
+		if updateLease.hasNoOwner() {
+			rmExp = append(rmExp, LeaseExpiresAtKey)
+		} else {
+			updateInput.ExpressionAttributeValues[":expiresAt"] = &dynamodb.AttributeValue{
+				N: aws.String(strconv.FormatInt(l.now().Add(l.LeaseTTL).Unix(), 10)),
+			}
+			setExp = append(setExp, fmt.Sprintf("%s = :expiresAt", LeaseExpiresAtKey))
+		}
+	}
+	attrExp := make(map[string]*string)
+	// the TTL field of the template is extended while the lease is owned.
+	if l.Template.TTL > 0 && !updateLease.hasNoOwner() {
+		updateInput.ExpressionAttributeValues[":ttl"] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(l.now().Add(l.Template.TTL).Unix(), 10)),
+		}
+		attrExp["#ttl"] = aws.String(l.Template.TTLField)
+		setExp = append(setExp, "#ttl = :ttl")
+	}
+	updateExp := "SET " + strings.Join(setExp, ", ")
+
+	if condLease.ReservedBy != "" && updateLease.ReservedBy == "" {
+		rmExp = append(rmExp, LeaseReservedByKey, LeaseReservedUntilKey)
+	}
+	if condLease.PreemptedBy != "" && updateLease.PreemptedBy == "" {
+		rmExp = append(rmExp, LeasePreemptedByKey)
+	}
+	if condLease.Holders != nil && updateLease.Holders == nil {
+		rmExp = append(rmExp, LeaseHoldersKey)
+	}
+	if len(rmExp) > 0 {
+		updateExp += " REMOVE " + strings.Join(rmExp, ", ")
+	}
+	updateInput.UpdateExpression = aws.String(updateExp)
+
 	// add conditions only to veteran leases
-	var (
-		condExp string
-		attrExp = make(map[string]*string)
-	)
+	var condExp string
 	if condLease.Counter > 0 {
 		updateInput.ExpressionAttributeValues[":condCounter"] = &dynamodb.AttributeValue{
-			N: aws.String(strconv.Itoa(condLease.Counter)),
+			N: aws.String(strconv.FormatInt(condLease.Counter, 10)),
 		}
 		attrExp["#counter"] = aws.String(LeaseCounterKey)
 		condExp = ":condCounter = #counter"
@@ -443,50 +1592,56 @@ func (l *LeaseManager) condUpdate(updateLease, condLease Lease) (err error) {
 		if condExp != "" {
 			condExp += " AND "
 		}
-		condExp += ":condOwner = #owner"
+		condExp += l.ownerCondition(condLease.Owner)
 	}
-	if condExp != "" {
+	if len(attrExp) > 0 {
 		updateInput.ExpressionAttributeNames = attrExp
+	}
+	if condExp != "" {
 		updateInput.ConditionExpression = aws.String(condExp)
 	}
-
-	_, err = l.updateLease(updateInput)
-
-	return
+	return updateInput
 }
 
 // updateLease gets updateInput and call Client.Update with the retries logic.
 // use this method to reduce duplicate code.
 // if the operation success we serialize the response and return the result.
-func (l *LeaseManager) updateLease(input *dynamodb.UpdateItemInput) (*Lease, error) {
+func (l *LeaseManager) updateLease(ctx context.Context, input *dynamodb.UpdateItemInput) (*Lease, error) {
 	var (
 		err error
 		out *dynamodb.UpdateItemOutput
 	)
-	for l.Backoff.Attempt() < maxUpdateRetries {
-		out, err = l.Client.UpdateItem(input)
+	r := l.retrier(RetryUpdate)
+	for r.more() {
+		out, err = l.Client.UpdateItemWithContext(ctx, input)
 
 		if err == nil {
 			break
 		}
 
-		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == ConditionalFailed {
+		if isConditionalFailed(err) {
 			break
 		}
 
-		backoff := l.Backoff.Duration()
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
 
-		l.Logger.WithFields(logrus.Fields{
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
 			"backoff": backoff,
-			"attempt": int(l.Backoff.Attempt()),
+			"attempt": r.attempt,
 		}).Warnf("Worker %s failed to update lease", l.WorkerId)
 
-		time.Sleep(backoff)
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
 	}
 
-	l.Backoff.Reset()
-
-	if err != nil {
+	if err = l.retryError(r, aws.StringValue(input.Key[LeaseKeyKey].S), err); err != nil {
 		return nil, err
 	}
 
@@ -1,13 +1,19 @@
 package lease
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/a8m/lease/leasetest"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/jpillora/backoff"
 )
@@ -30,19 +36,142 @@ func TestCreateTable(t *testing.T) {
 	})
 	manager := newTestManager(client)
 
-	err := manager.CreateLeaseTable()
+	err := manager.CreateLeaseTable(context.Background())
 	assert(t, err == nil, "expect not to fail while getting 'table already exist' error")
 	assert(t, client.calls[methodCreateTable] == 1, "number of calls should be 1")
 
-	err = manager.CreateLeaseTable()
+	err = manager.CreateLeaseTable(context.Background())
 	assert(t, client.calls[methodCreateTable] == 4, "should retry 4 times")
 	assert(t, err != nil, "expect to returns the error")
 
-	err = manager.CreateLeaseTable()
+	err = manager.CreateLeaseTable(context.Background())
 	assert(t, err == nil, "expect not to fail when the request success")
 	assert(t, client.calls[methodCreateTable] == 5, "number of calls should be 5")
 }
 
+func TestCreateTableWaitActive(t *testing.T) {
+	creating := &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableStatus: aws.String(dynamodb.TableStatusCreating),
+	}}
+	client := newClientMock(map[method]args{
+		methodCreateTable: {
+			// the table was created by another worker, and it's still being created.
+			awserr.New(AlreadyExist, "", errors.New("")),
+			new(dynamodb.CreateTableOutput),
+		},
+		methodDescribeTable: {
+			// the table is not described yet.
+			nil,
+			creating,
+			&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableStatus: aws.String(dynamodb.TableStatusActive),
+			}},
+		},
+	})
+	clock := leasetest.NewFakeClock(time.Now())
+	manager := newTestManager(client)
+	manager.Clock = clock
+
+	done := make(chan error)
+	go func() { done <- manager.CreateLeaseTable(context.Background()) }()
+	// the polls back off.
+	for _, d := range []time.Duration{time.Second, 2 * time.Second} {
+		clock.BlockUntil(1)
+		clock.Advance(d)
+	}
+	err := <-done
+	assert(t, err == nil, "expect to wait until the table is active")
+	assert(t, client.calls[methodDescribeTable] == 3, "expect to poll the table status until it's active")
+
+	client.result[methodDescribeTable] = args{creating}
+	go func() { done <- manager.CreateLeaseTable(context.Background()) }()
+	for {
+		select {
+		case err = <-done:
+			assert(t, errors.Is(err, ErrTableNotActive), "expect to fail if the table is not active in time")
+			return
+		case <-time.After(time.Millisecond):
+			clock.Advance(durationBetweenPolls)
+		}
+	}
+}
+
+func TestDeleteLeaseTable(t *testing.T) {
+	notFound := awserr.New(dynamodb.ErrCodeResourceNotFoundException, "", errors.New(""))
+	client := deleterMock{newClientMock(map[method]args{
+		methodDeleteTable: {
+			// getting error, should retry
+			nil,
+			new(dynamodb.DeleteTableOutput),
+			// the table does not exist
+			notFound,
+		},
+	})}
+	manager := newTestManager(client)
+
+	err := manager.DeleteLeaseTable(context.Background())
+	assert(t, err == nil, "expect to retry until the table is deleted")
+	assert(t, client.calls[methodDeleteTable] == 2, "number of calls should be 2")
+
+	err = manager.DeleteLeaseTable(context.Background())
+	assert(t, err == nil, "expect not to fail if the table does not exist")
+	assert(t, client.calls[methodDeleteTable] == 3, "number of calls should be 3")
+
+	manager.Client = client.clientMock
+	err = manager.DeleteLeaseTable(context.Background())
+	assert(t, errors.Is(err, ErrDeleteTableUnsupported), "expect to fail with clients that can't delete tables")
+}
+
+func TestLeaseTableExists(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodDescribeTable: {
+			// getting error, should retry
+			nil,
+			&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableStatus: aws.String(dynamodb.TableStatusCreating),
+			}},
+			awserr.New(dynamodb.ErrCodeResourceNotFoundException, "", errors.New("")),
+		},
+	})
+	manager := newTestManager(client)
+
+	exists, err := manager.LeaseTableExists(context.Background())
+	assert(t, err == nil && exists, "expect the table to exist")
+	assert(t, client.calls[methodDescribeTable] == 2, "number of calls should be 2")
+
+	exists, err = manager.LeaseTableExists(context.Background())
+	assert(t, err == nil && !exists, "expect the table not to exist")
+}
+
+func TestCreateTableOnDemand(t *testing.T) {
+	manager := newTestManager(nil)
+	input := manager.createTableInput()
+	assert(t, input.BillingMode == nil && aws.Int64Value(input.ProvisionedThroughput.WriteCapacityUnits) == 10, "expect a provisioned table by default")
+
+	manager.OnDemand = true
+	input = manager.createTableInput()
+	assert(t, aws.StringValue(input.BillingMode) == dynamodb.BillingModePayPerRequest, "expect an on-demand table")
+	assert(t, input.ProvisionedThroughput == nil, "expect an on-demand table to have no provisioned throughput")
+}
+
+func TestCreateTableSSE(t *testing.T) {
+	manager := newTestManager(nil)
+	input := manager.createTableInput()
+	assert(t, input.SSESpecification == nil, "expect the default encryption by default")
+
+	manager.SSEEnabled = true
+	input = manager.createTableInput()
+	assert(t, aws.BoolValue(input.SSESpecification.Enabled), "expect the table to be encrypted with a KMS key")
+	assert(t, aws.StringValue(input.SSESpecification.SSEType) == dynamodb.SSETypeKms, "expect the table to be encrypted with a KMS key")
+	assert(t, input.SSESpecification.KMSMasterKeyId == nil, "expect the AWS managed key")
+
+	manager.SSEEnabled = false
+	manager.SSEKMSKeyId = "alias/leases"
+	input = manager.createTableInput()
+	assert(t, aws.BoolValue(input.SSESpecification.Enabled), "expect the key to imply encryption")
+	assert(t, aws.StringValue(input.SSESpecification.KMSMasterKeyId) == "alias/leases", "expect the customer managed key")
+}
+
 func TestListLeases(t *testing.T) {
 	client := newClientMock(map[method]args{
 		methodScan: {
@@ -60,11 +189,11 @@ func TestListLeases(t *testing.T) {
 	})
 	manager := newTestManager(client)
 
-	leases, err := manager.ListLeases()
+	leases, err := manager.ListLeases(context.Background())
 	assert(t, err != nil, "expect to returns the error")
 	assert(t, client.calls[methodScan] == 3, "number of calls should be 3")
 
-	leases, err = manager.ListLeases()
+	leases, err = manager.ListLeases(context.Background())
 	assert(t, err == nil, "expect not to fail when the request success")
 	assert(t, client.calls[methodScan] == 4, "number of calls should be 4")
 
@@ -75,6 +204,44 @@ func TestListLeases(t *testing.T) {
 	}
 }
 
+func TestListLeasesCanceled(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodScan: {nil, nil, nil},
+	})
+	manager := newTestManager(client)
+	manager.Backoff = &Backoff{b: &backoff.Backoff{Min: time.Hour, Max: time.Hour}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := manager.ListLeases(ctx)
+	assert(t, errors.Is(err, context.Canceled), "expect the retries to be canceled")
+	assert(t, client.calls[methodScan] == 1, "expect not to retry after the context is done")
+}
+
+func TestListLeasesReadClient(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodGetItem: {&dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"leaseKey": {S: aws.String("foo")}}}},
+		methodScan:    {&dynamodb.ScanOutput{}},
+	})
+	cache := newClientMock(map[method]args{
+		methodScan: {&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{{"leaseKey": {S: aws.String("foo")}}}}},
+	})
+	manager := newTestManager(client)
+	manager.ReadClient = cache
+
+	leases, err := manager.ScanLeases(context.Background())
+	assert(t, err == nil && len(leases) == 1, "expect to list the leases")
+	assert(t, cache.calls[methodScan] == 1 && client.calls[methodScan] == 0, "expect the scans of the taker to go through the read client")
+	_, err = manager.ListLeases(context.Background())
+	assert(t, err == nil && cache.calls[methodScan] == 1 && client.calls[methodScan] == 1, "expect the scans of the renewer to go through the client")
+	_, err = manager.GetLease(context.Background(), "foo")
+	assert(t, err == nil, "expect to get the lease")
+	assert(t, client.calls[methodGetItem] == 1 && cache.calls[methodGetItem] == 0, "expect the consistent reads to go through the client")
+}
+
 func TestRenewLease(t *testing.T) {
 	client := newClientMock(map[method]args{
 		methodUpdateItem: {
@@ -87,15 +254,103 @@ func TestRenewLease(t *testing.T) {
 	manager := newTestManager(client)
 
 	leaseToRenew := &Lease{Key: "foo", Counter: 10, Owner: "o1"}
-	err := manager.RenewLease(leaseToRenew)
+	err := manager.RenewLease(context.Background(), leaseToRenew)
 	assert(t, err == nil, "expect not to fail")
 	assert(t, leaseToRenew.Counter == 11, "expect leaseCounter to be 11")
-	err = manager.RenewLease(leaseToRenew)
+	err = manager.RenewLease(context.Background(), leaseToRenew)
 	assert(t, err != nil, "expect to returns the error")
 	assert(t, leaseToRenew.Counter == 11, "expect leaseCounter to be 11")
 	assert(t, client.calls[methodUpdateItem] == 3, "number of calls should be 3")
 }
 
+func TestConditionErrors(t *testing.T) {
+	failed := func(item map[string]*dynamodb.AttributeValue) *dynamodb.ConditionalCheckFailedException {
+		return &dynamodb.ConditionalCheckFailedException{Message_: aws.String("failed"), Item: item}
+	}
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {
+			failed(map[string]*dynamodb.AttributeValue{
+				LeaseOwnerKey:   {S: aws.String("o2")},
+				LeaseCounterKey: {N: aws.String("12")},
+			}),
+			failed(map[string]*dynamodb.AttributeValue{
+				LeaseOwnerKey:   {S: aws.String("o1")},
+				LeaseCounterKey: {N: aws.String("11")},
+			}),
+			failed(nil),
+		},
+		methodDeleteItem: {
+			failed(map[string]*dynamodb.AttributeValue{LeaseOwnerKey: {S: aws.String("o2")}}),
+		},
+	})
+	manager := newTestManager(client)
+	ctx := context.Background()
+
+	err := manager.RenewLease(ctx, &Lease{Key: "foo", Counter: 10, Owner: "o1"})
+	var cerr *ConditionError
+	assert(t, errors.Is(err, ErrLeaseStolen) && errors.As(err, &cerr) && cerr.Owner == "o2", "expect the lease to be stolen")
+	assert(t, isConditionalFailed(err), "expect the DynamoDB error to be kept")
+	var oerr *OpError
+	assert(t, errors.As(err, &oerr) && oerr.Op == "renew", "expect the operation context to be kept")
+	err = manager.TakeLease(ctx, &Lease{Key: "foo", Counter: 10, Owner: "o1"})
+	assert(t, errors.Is(err, ErrLeaseCounterChanged), "expect the lease counter to be changed")
+	err = manager.EvictLease(ctx, &Lease{Key: "foo", Counter: 10, Owner: "o1"})
+	assert(t, errors.Is(err, ErrLeaseNotFound), "expect the lease to be missing")
+	err = manager.DeleteLease(ctx, &Lease{Key: "foo", Owner: "o1"})
+	assert(t, errors.Is(err, ErrLeaseStolen), "expect the deleted lease to be owned by another worker")
+}
+
+func TestPermanentErrors(t *testing.T) {
+	assert(t, IsRetryable(errors.New("connection reset")), "expect errors without a code to be retryable")
+	assert(t, IsRetryable(awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "", nil)), "expect throttling to be retryable")
+	assert(t, !IsRetryable(awserr.New("AccessDeniedException", "", nil)), "expect access denied to be permanent")
+	assert(t, !IsRetryable(context.Canceled), "expect canceled calls not to be retried")
+
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {awserr.New("ValidationException", "invalid expression", nil), nil, nil},
+	})
+	manager := newTestManager(client)
+	err := manager.RenewLease(context.Background(), &Lease{Key: "foo", Counter: 10, Owner: "o1"})
+	assert(t, err != nil && client.calls[methodUpdateItem] == 1, "expect permanent errors to return immediately")
+
+	// the classifier can be overridden.
+	manager.IsRetryable = func(error) bool { return false }
+	err = manager.RenewLease(context.Background(), &Lease{Key: "foo", Counter: 10, Owner: "o1"})
+	assert(t, err != nil && client.calls[methodUpdateItem] == 2, "expect the custom classifier to be used")
+}
+
+// retryPolicyMock retries all the errors, up to the given number of attempts.
+type retryPolicyMock struct {
+	attempts int
+	delays   []int
+}
+
+func (p *retryPolicyMock) NextDelay(attempt int) time.Duration {
+	p.delays = append(p.delays, attempt)
+	return 0
+}
+func (p *retryPolicyMock) ShouldRetry(int, error) bool { return true }
+func (p *retryPolicyMock) MaxAttempts() int            { return p.attempts }
+func (p *retryPolicyMock) MaxElapsed() time.Duration   { return 0 }
+
+func TestRetryPolicy(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {nil, nil, nil, new(dynamodb.UpdateItemOutput), nil, nil, nil, nil},
+	})
+	manager := newTestManager(client)
+	policy := &retryPolicyMock{attempts: 4}
+	manager.RetryPolicies = map[RetryOp]RetryPolicy{RetryUpdate: policy}
+	err := manager.RenewLease(context.Background(), &Lease{Key: "foo", Counter: 10, Owner: "o1"})
+	assert(t, err == nil && client.calls[methodUpdateItem] == 4, "expect the policy attempts to be used")
+	assert(t, fmt.Sprint(policy.delays) == "[1 2 3]", "expect a delay for each failed attempt")
+
+	err = manager.RenewLease(context.Background(), &Lease{Key: "foo", Counter: 10, Owner: "o1"})
+	var oe *OpError
+	assert(t, errors.As(err, &oe) && oe.Attempt == 3, "expect the number of retries to be reported")
+	assert(t, client.calls[methodUpdateItem] == 8, "expect not to call after the last attempt")
+	assert(t, len(policy.delays) == 6, "expect not to wait after the last attempt")
+}
+
 func TestEvictLease(t *testing.T) {
 	client := newClientMock(map[method]args{
 		methodUpdateItem: {
@@ -108,12 +363,12 @@ func TestEvictLease(t *testing.T) {
 	manager := newTestManager(client)
 
 	leaseToEvict := &Lease{Key: "foo", Counter: 10, Owner: "o1"}
-	err := manager.EvictLease(leaseToEvict)
+	err := manager.EvictLease(context.Background(), leaseToEvict)
 	assert(t, err != nil, "expect to returns the error")
 	assert(t, leaseToEvict.Owner == "o1", "expect leaseOwner to be the same")
 	assert(t, client.calls[methodUpdateItem] == 2, "number of calls should be 2")
 
-	err = manager.EvictLease(leaseToEvict)
+	err = manager.EvictLease(context.Background(), leaseToEvict)
 	assert(t, err == nil, "expect not to fail")
 	assert(t, leaseToEvict.Counter == 10, "expect leaseCounter to be the same")
 	assert(t, leaseToEvict.Owner == "NULL", "expect leaseOwner to be the 'NULL'")
@@ -131,16 +386,154 @@ func TestTakeLease(t *testing.T) {
 	manager := newTestManager(client)
 
 	leaseToTake := &Lease{Key: "foo", Counter: 10, Owner: "o1"}
-	err := manager.TakeLease(leaseToTake)
+	err := manager.TakeLease(context.Background(), leaseToTake)
 	assert(t, err != nil, "expect to returns the error")
 	assert(t, leaseToTake.Owner == "o1" && leaseToTake.Counter == 10, "expect leaseOwner and leaseCounter to be the same")
 
-	err = manager.TakeLease(leaseToTake)
+	err = manager.TakeLease(context.Background(), leaseToTake)
 	assert(t, err == nil, "expect not to fail")
 	assert(t, leaseToTake.Owner == manager.WorkerId, "expect owner to equal workerId")
 	assert(t, leaseToTake.Counter == 11, "expect counter to be increment by 1")
 }
 
+func TestTakeLeases(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodTransactWriteItems: {
+			// one of the leases was changed
+			awserr.New(TransactionCanceled, "", errors.New("")),
+			// transaction finished successfully
+			new(dynamodb.TransactWriteItemsOutput),
+		},
+	})
+	manager := newTestManager(client)
+
+	group := []*Lease{
+		{Key: "foo", Counter: 10, Owner: "o1", Group: "g"},
+		{Key: "bar", Counter: 5, Owner: "NULL", Group: "g"},
+	}
+	err := manager.TakeLeases(context.Background(), group)
+	assert(t, err != nil, "expect to returns the error")
+	assert(t, client.calls[methodTransactWriteItems] == 1, "expect not to retry a canceled transaction")
+	assert(t, group[0].Owner == "o1" && group[1].Owner == "NULL", "expect the leases to be the same")
+
+	err = manager.TakeLeases(context.Background(), group)
+	assert(t, err == nil, "expect not to fail")
+	assert(t, group[0].Owner == manager.WorkerId && group[1].Owner == manager.WorkerId, "expect owner to equal workerId")
+	assert(t, group[0].Counter == 11 && group[1].Counter == 6, "expect counters to be increment by 1")
+
+	manager.Client = &regionMock{}
+	err = manager.TakeLeases(context.Background(), group)
+	assert(t, errors.Is(err, ErrTransactionsUnsupported), "expect to fail without transactions support")
+}
+
+func TestReserveLease(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {
+			// getting "conditional error"
+			awserr.New("ConditionalCheckFailedException", "", errors.New("")),
+			// update item finsihed successfully
+			new(dynamodb.UpdateItemOutput),
+			new(dynamodb.UpdateItemOutput),
+		},
+	})
+	manager := newTestManager(client)
+
+	until := time.Now().Add(time.Minute)
+	leaseToReserve := &Lease{Key: "foo", Counter: 10, Owner: "o1", ReservedBy: "o2", ReservedUntil: until}
+	err := manager.ReserveLease(context.Background(), leaseToReserve, until)
+	assert(t, err != nil, "expect to returns the conditional error")
+	assert(t, client.calls[methodUpdateItem] == 1, "expect not retry on conditional failure")
+	assert(t, leaseToReserve.ReservedBy == "o2", "expect reservation to be the same")
+
+	err = manager.ReserveLease(context.Background(), leaseToReserve, until)
+	assert(t, err == nil, "expect not to fail")
+	assert(t, leaseToReserve.ReservedBy == manager.WorkerId, "expect lease to be reserved by workerId")
+	assert(t, leaseToReserve.ReservedUntil.Unix() == until.Unix(), "expect reservation time to be set")
+
+	err = manager.TakeLease(context.Background(), leaseToReserve)
+	assert(t, err == nil, "expect not to fail")
+	assert(t, leaseToReserve.ReservedBy == "", "expect reservation to be released after take")
+}
+
+func TestGetLease(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodGetItem: {
+			// getting error from dynamodb
+			nil, nil, nil,
+			// lease does not exist
+			new(dynamodb.GetItemOutput),
+			// get item finished successfully
+			&dynamodb.GetItemOutput{
+				Item: map[string]*dynamodb.AttributeValue{
+					"leaseKey":     {S: aws.String("foo")},
+					"leaseHolders": {M: map[string]*dynamodb.AttributeValue{"1": {N: aws.String("10")}}},
+				},
+			},
+		},
+	})
+	manager := newTestManager(client)
+
+	_, err := manager.GetLease(context.Background(), "foo")
+	assert(t, err != nil, "expect to returns the error")
+	assert(t, client.calls[methodGetItem] == 3, "number of calls should be 3")
+	var oe *OpError
+	assert(t, errors.As(err, &oe), "expect the error to be an OpError")
+	assert(t, oe.Op == "get" && oe.Key == "foo" && oe.Table == "test" && oe.Attempt == 3, "expect the error to carry the operation context")
+
+	_, err = manager.GetLease(context.Background(), "foo")
+	assert(t, errors.Is(err, ErrLeaseNotFound), "expect to returns ErrLeaseNotFound")
+
+	lease, err := manager.GetLease(context.Background(), "foo")
+	assert(t, err == nil, "expect not to fail when the request success")
+	assert(t, lease.Key == "foo" && lease.Holders["1"] == 10, "expect lease to be decoded")
+}
+
+func TestSharedLease(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {
+			// getting "conditional error"
+			awserr.New("ConditionalCheckFailedException", "", errors.New("")),
+			// update item finsihed successfully
+			new(dynamodb.UpdateItemOutput),
+		},
+	})
+	manager := newTestManager(client)
+
+	expired := time.Now().Add(-time.Hour).Unix()
+	leaseToAcquire := &Lease{Key: "foo", Counter: 10, Owner: "NULL", Holders: map[string]int64{"2": expired}}
+	err := manager.AcquireSharedLease(context.Background(), leaseToAcquire)
+	assert(t, err != nil, "expect to returns the conditional error")
+	assert(t, leaseToAcquire.Counter == 10, "expect leaseCounter to be the same")
+
+	err = manager.AcquireSharedLease(context.Background(), leaseToAcquire)
+	assert(t, err == nil, "expect not to fail")
+	assert(t, leaseToAcquire.Counter == 11, "expect counter to be increment by 1")
+	_, expiredHolder := leaseToAcquire.Holders["2"]
+	_, workerHolder := leaseToAcquire.Holders[manager.WorkerId]
+	assert(t, !expiredHolder && workerHolder, "expect expired holders to be replaced by workerId")
+}
+
+func TestSemaphoreLease(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {
+			// update item finsihed successfully
+			new(dynamodb.UpdateItemOutput),
+		},
+	})
+	manager := newTestManager(client)
+
+	now := time.Now().Unix()
+	leaseToAcquire := &Lease{Key: "foo", Counter: 10, Owner: "NULL", MaxHolders: 2, Holders: map[string]int64{"2": now, "3": now}}
+	err := manager.AcquireSharedLease(context.Background(), leaseToAcquire)
+	assert(t, errors.Is(err, ErrLeaseFull), "expect to returns ErrLeaseFull")
+	assert(t, client.calls[methodUpdateItem] == 0, "expect not to call dynamodb")
+
+	leaseToAcquire.Holders["3"] = time.Now().Add(-time.Hour).Unix()
+	err = manager.AcquireSharedLease(context.Background(), leaseToAcquire)
+	assert(t, err == nil, "expect not to fail when one of the holders expired")
+	assert(t, len(leaseToAcquire.Holders) == 2, "expect number of holders to equal 2")
+}
+
 func TestDeleteLease(t *testing.T) {
 	client := newClientMock(map[method]args{
 		methodDeleteItem: {
@@ -155,11 +548,11 @@ func TestDeleteLease(t *testing.T) {
 	manager := newTestManager(client)
 
 	leaseToDelete := &Lease{Key: "foo"}
-	err := manager.DeleteLease(leaseToDelete)
+	err := manager.DeleteLease(context.Background(), leaseToDelete)
 	assert(t, err == nil, "expect not to fail")
 	assert(t, client.calls[methodDeleteItem] == 1, "expect number of calls to equal 1")
 
-	err = manager.DeleteLease(leaseToDelete)
+	err = manager.DeleteLease(context.Background(), leaseToDelete)
 	assert(t, err != nil, "expect returns the conditional error")
 	assert(t, client.calls[methodDeleteItem] == 2, "expect number of calls to equal 2")
 }
@@ -191,20 +584,100 @@ func TestCreateLease(t *testing.T) {
 	manager.WorkerId = "workerId"
 
 	leaseToCreate := &Lease{Key: "bar"}
-	lease, err := manager.CreateLease(leaseToCreate)
+	lease, err := manager.CreateLease(context.Background(), leaseToCreate)
 	assert(t, err == nil, "expect CreateLease not to fail")
 	assert(t, client.calls[methodPutItem] == 1, "expect number of calls to equal 1")
 	assert(t, lease.Owner == manager.WorkerId && lease.Counter == 1, "expect taking the lease")
 
-	_, err = manager.CreateLease(leaseToCreate)
+	_, err = manager.CreateLease(context.Background(), leaseToCreate)
 	assert(t, err != nil, "expect CreateLease to fail")
 	assert(t, client.calls[methodPutItem] == 2, "expect not retry on conditional failure")
 
-	_, err = manager.CreateLease(leaseToCreate)
+	_, err = manager.CreateLease(context.Background(), leaseToCreate)
 	assert(t, err != nil, "expect CreateLease to fail")
 	assert(t, client.calls[methodPutItem] == 5, "expect CreateLease to retry 3 times")
 }
 
+// putMock is a clientMock that records the put requests.
+type putMock struct {
+	*clientMock
+	items []map[string]*dynamodb.AttributeValue
+}
+
+func (c *putMock) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	c.items = append(c.items, input.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestExtraFieldsRoundTrip(t *testing.T) {
+	client := &putMock{clientMock: newClientMock(nil)}
+	manager := newTestManager(client)
+
+	lease := &Lease{Key: "foo"}
+	lease.Set("status", "pending")
+	lease.Set("attempts", 3)
+	_, err := manager.CreateLease(context.Background(), lease)
+	assert(t, err == nil && len(client.items) == 1, "expect the lease to be created")
+	item := client.items[0]
+	assert(t, aws.StringValue(item["status"].S) == "pending" && aws.StringValue(item["attempts"].N) == "3", "expect the extra fields to be written on create")
+
+	manager.Client = newClientMock(map[method]args{
+		methodScan: {&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{item}}},
+	})
+	leases, err := manager.ListLeases(context.Background())
+	assert(t, err == nil && len(leases) == 1, "expect to list the lease")
+	status, _ := leases[0].Get("status")
+	attempts, _ := leases[0].Get("attempts")
+	assert(t, status == "pending" && attempts == float64(3), "expect the extra fields to be read on list")
+
+	update := &updateMock{clientMock: newClientMock(nil)}
+	manager.Client = update
+	leases[0].Set("status", "done")
+	_, err = manager.UpdateLease(context.Background(), leases[0])
+	assert(t, err == nil && strings.Contains(*update.inputs[0].UpdateExpression, "status = :status"), "expect the extra fields to be written on update")
+	assert(t, aws.StringValue(update.inputs[0].ExpressionAttributeValues[":status"].S) == "done", "expect the updated value to be written")
+}
+
+func TestEnsureLease(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodPutItem: {
+			&dynamodb.PutItemOutput{},
+			// the lease already exists
+			awserr.New(ConditionalFailed, "", errors.New("")),
+			// getting error from dynamodb
+			nil, nil, nil,
+		},
+	})
+	manager := newTestManager(client)
+
+	lease := &Lease{Key: "foo"}
+	ok, err := manager.EnsureLease(context.Background(), lease)
+	assert(t, err == nil && ok, "expect the lease to be created")
+	assert(t, lease.Owner == "NULL" && lease.Counter == 1, "expect the lease to be created without an owner")
+
+	ok, err = manager.EnsureLease(context.Background(), &Lease{Key: "foo"})
+	assert(t, err == nil && !ok, "expect existing lease to be left as is")
+	assert(t, client.calls[methodPutItem] == 2, "expect not retry on conditional failure")
+
+	_, err = manager.EnsureLease(context.Background(), &Lease{Key: "foo"})
+	assert(t, err != nil, "expect EnsureLease to fail")
+	assert(t, client.calls[methodPutItem] == 5, "expect EnsureLease to retry 3 times")
+}
+
+func TestEnsureLeases(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList:   {[]*Lease{{Key: "foo", Owner: "1"}}},
+		methodEnsure: {true, false},
+	})
+	coordinator := &Coordinator{Config: &Config{Logger: logger}, Manager: manager}
+
+	n, err := coordinator.EnsureLeases(context.Background(), []string{"foo", "bar", "baz"})
+	assert(t, err == nil, "expect EnsureLeases not to fail")
+	assert(t, manager.calls[methodEnsure] == 2, "expect to create only the missing leases")
+	assert(t, n == 1, "expect to count only the created leases")
+}
+
 type (
 	method int
 	args   []interface{}
@@ -214,20 +687,39 @@ const (
 	// Manager methods
 	methodCreate = iota
 	methodLCreate
+	methodEnsure
 	methodUpdate
 	methodDelete
 	methodRenew
 	methodEvict
 	methodTake
+	methodTakeGroup
+	methodReserve
+	methodPreempt
+	methodGet
+	methodAcquireShared
+	methodRenewShared
+	methodReleaseShared
+	methodMigrate
+	methodPurge
+	methodComplete
+	methodStealBudget
+	methodReshard
+	methodDeleteLeaseTable
+	methodTableExists
+	methodUpdateCapacity
 	methodList
 
 	// Clientface methods
+	methodGetItem
 	methodScan
 	methodPutItem
 	methodUpdateItem
 	methodDeleteItem
 	methodCreateTable
 	methodDescribeTable
+	methodTransactWriteItems
+	methodDeleteTable
 )
 
 func (m method) String() string {
@@ -239,19 +731,38 @@ func (m method) String() string {
 }
 
 var methodNames = map[method]string{
-	methodCreate:        "CreateLeaseTable",
-	methodLCreate:       "CreateLease",
-	methodDelete:        "DeleteLease",
-	methodRenew:         "RenewLease",
-	methodEvict:         "EvictLease",
-	methodTake:          "TakeLease",
-	methodList:          "ListLeases",
-	methodScan:          "Scan",
-	methodPutItem:       "PutItem",
-	methodUpdateItem:    "UpdateItem",
-	methodDeleteItem:    "DeleteItem",
-	methodCreateTable:   "CreateTable",
-	methodDescribeTable: "DescribeTable",
+	methodCreate:             "CreateLeaseTable",
+	methodLCreate:            "CreateLease",
+	methodEnsure:             "EnsureLease",
+	methodDelete:             "DeleteLease",
+	methodRenew:              "RenewLease",
+	methodEvict:              "EvictLease",
+	methodTake:               "TakeLease",
+	methodTakeGroup:          "TakeLeases",
+	methodReserve:            "ReserveLease",
+	methodPreempt:            "PreemptLease",
+	methodGet:                "GetLease",
+	methodAcquireShared:      "AcquireSharedLease",
+	methodRenewShared:        "RenewSharedLease",
+	methodReleaseShared:      "ReleaseSharedLease",
+	methodMigrate:            "MigrateLeases",
+	methodPurge:              "PurgeLease",
+	methodComplete:           "CompleteLease",
+	methodStealBudget:        "AcquireStealBudget",
+	methodReshard:            "ReshardLease",
+	methodDeleteLeaseTable:   "DeleteLeaseTable",
+	methodTableExists:        "LeaseTableExists",
+	methodUpdateCapacity:     "UpdateLeaseTableCapacity",
+	methodGetItem:            "GetItem",
+	methodList:               "ListLeases",
+	methodScan:               "Scan",
+	methodPutItem:            "PutItem",
+	methodUpdateItem:         "UpdateItem",
+	methodDeleteItem:         "DeleteItem",
+	methodCreateTable:        "CreateTable",
+	methodDescribeTable:      "DescribeTable",
+	methodTransactWriteItems: "TransactWriteItems",
+	methodDeleteTable:        "DeleteTable",
 }
 
 type clientMock struct {
@@ -275,7 +786,15 @@ func (c *clientMock) mcalled(name method) int {
 	return c.calls[name]
 }
 
-func (c *clientMock) Scan(*dynamodb.ScanInput) (out *dynamodb.ScanOutput, err error) {
+func (c *clientMock) GetItemWithContext(aws.Context, *dynamodb.GetItemInput, ...request.Option) (*dynamodb.GetItemOutput, error) {
+	i := c.mcalled(methodGetItem)
+	if v := c.result[methodGetItem][i-1]; v != nil {
+		return v.(*dynamodb.GetItemOutput), nil
+	}
+	return nil, errors.New("get item failed")
+}
+
+func (c *clientMock) ScanWithContext(aws.Context, *dynamodb.ScanInput, ...request.Option) (out *dynamodb.ScanOutput, err error) {
 	i := c.mcalled(methodScan)
 	if v := c.result[methodScan][i-1]; v != nil {
 		out = v.(*dynamodb.ScanOutput)
@@ -285,7 +804,7 @@ func (c *clientMock) Scan(*dynamodb.ScanInput) (out *dynamodb.ScanOutput, err er
 	return
 }
 
-func (c *clientMock) PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+func (c *clientMock) PutItemWithContext(aws.Context, *dynamodb.PutItemInput, ...request.Option) (*dynamodb.PutItemOutput, error) {
 	i := c.mcalled(methodPutItem)
 	result := c.result[methodPutItem][i-1]
 	if result != nil {
@@ -300,7 +819,7 @@ func (c *clientMock) PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, e
 	return nil, errors.New("put item failed")
 }
 
-func (c *clientMock) UpdateItem(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+func (c *clientMock) UpdateItemWithContext(aws.Context, *dynamodb.UpdateItemInput, ...request.Option) (*dynamodb.UpdateItemOutput, error) {
 	i := c.mcalled(methodUpdateItem)
 	result := c.result[methodUpdateItem][i-1]
 	if result != nil {
@@ -315,7 +834,7 @@ func (c *clientMock) UpdateItem(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItem
 	return nil, errors.New("update item failed")
 }
 
-func (c *clientMock) DeleteItem(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+func (c *clientMock) DeleteItemWithContext(aws.Context, *dynamodb.DeleteItemInput, ...request.Option) (*dynamodb.DeleteItemOutput, error) {
 	i := c.mcalled(methodDeleteItem)
 	result := c.result[methodDeleteItem][i-1]
 	if result != nil {
@@ -330,7 +849,7 @@ func (c *clientMock) DeleteItem(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItem
 	return nil, errors.New("delete item failed")
 }
 
-func (c *clientMock) CreateTable(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+func (c *clientMock) CreateTableWithContext(aws.Context, *dynamodb.CreateTableInput, ...request.Option) (*dynamodb.CreateTableOutput, error) {
 	i := c.mcalled(methodCreateTable)
 	result := c.result[methodCreateTable][i-1]
 	if result != nil {
@@ -345,9 +864,10 @@ func (c *clientMock) CreateTable(*dynamodb.CreateTableInput) (*dynamodb.CreateTa
 	return nil, errors.New("create table failed")
 }
 
-func (c *clientMock) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
-	c.mcalled(methodDescribeTable)
-	result := c.result[methodDescribeTable][0]
+func (c *clientMock) DescribeTableWithContext(aws.Context, *dynamodb.DescribeTableInput, ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	// the last result is repeated.
+	i := min(c.mcalled(methodDescribeTable), len(c.result[methodDescribeTable]))
+	result := c.result[methodDescribeTable][i-1]
 	if result != nil {
 		out, ok := result.(*dynamodb.DescribeTableOutput)
 		if ok {
@@ -360,9 +880,48 @@ func (c *clientMock) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.Desc
 	return nil, errors.New("describe table failed")
 }
 
+// deleterMock is a clientMock that supports deleting tables.
+type deleterMock struct {
+	*clientMock
+}
+
+func (c deleterMock) DeleteTableWithContext(aws.Context, *dynamodb.DeleteTableInput, ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	i := c.mcalled(methodDeleteTable)
+	result := c.result[methodDeleteTable][i-1]
+	if result != nil {
+		out, ok := result.(*dynamodb.DeleteTableOutput)
+		if ok {
+			return out, nil
+		}
+		// allows custom errors. for example: 'ResourceNotFoundException'
+		err, ok := result.(awserr.Error)
+		return nil, err
+	}
+	return nil, errors.New("delete table failed")
+}
+
+func (c *clientMock) TransactWriteItemsWithContext(aws.Context, *dynamodb.TransactWriteItemsInput, ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	i := c.mcalled(methodTransactWriteItems)
+	result := c.result[methodTransactWriteItems][i-1]
+	if result != nil {
+		out, ok := result.(*dynamodb.TransactWriteItemsOutput)
+		if ok {
+			return out, nil
+		}
+		// allows custom errors. for example: 'TransactionCanceled'
+		err, ok := result.(awserr.Error)
+		return nil, err
+	}
+	return nil, errors.New("transact write items failed")
+}
+
+// testLogger returns a logger that discards its entries.
+func testLogger() Logger {
+	return NewSlogLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
 func newTestManager(client Clientface) *LeaseManager {
-	logger := logrus.New()
-	logger.Level = logrus.PanicLevel
+	logger := testLogger()
 	config := &Config{
 		WorkerId:   "1",
 		LeaseTable: "test",
@@ -371,7 +930,7 @@ func newTestManager(client Clientface) *LeaseManager {
 		Backoff:    &Backoff{b: &backoff.Backoff{Min: 0, Max: 0}},
 	}
 	config.defaults()
-	return &LeaseManager{config, newSerializer()}
+	return &LeaseManager{config, newSerializer(config)}
 }
 
 type managerMock struct {
@@ -405,36 +964,142 @@ func (m *managerMock) errOnly(name method) (err error) {
 	return
 }
 
-func (m *managerMock) CreateLeaseTable() error {
+func (m *managerMock) CreateLeaseTable(context.Context) error {
 	return m.errOnly(methodCreate)
 }
 
-func (m *managerMock) DeleteLease(*Lease) error {
+func (m *managerMock) ScanLeases(ctx context.Context) ([]*Lease, error) {
+	return m.ListLeases(ctx)
+}
+
+func (m *managerMock) ListLeasesByOwner(ctx context.Context, owner string) ([]*Lease, error) {
+	list, err := m.ListLeases(ctx)
+	return leasesOf(list, owner), err
+}
+
+func (m *managerMock) ListLeasesBySelector(ctx context.Context, selector Selector) ([]*Lease, error) {
+	list, err := m.ListLeases(ctx)
+	return leasesOfSelector(list, selector), err
+}
+
+func (m *managerMock) DeleteLeaseTable(context.Context) error {
+	return m.errOnly(methodDeleteLeaseTable)
+}
+
+func (m *managerMock) UpdateLeaseTableCapacity(context.Context, TableCapacity) error {
+	return m.errOnly(methodUpdateCapacity)
+}
+
+func (m *managerMock) LeaseTableExists(context.Context) (bool, error) {
+	i := m.mcalled(methodTableExists)
+	if v, ok := m.result[methodTableExists][i-1].(bool); ok {
+		return v, nil
+	}
+	return false, errors.New("lease table exists failed")
+}
+
+func (m *managerMock) DeleteLease(context.Context, *Lease) error {
 	return m.errOnly(methodDelete)
 }
 
-func (m *managerMock) CreateLease(l *Lease) (*Lease, error) {
+func (m *managerMock) CreateLease(_ context.Context, l *Lease) (*Lease, error) {
 	return l, m.errOnly(methodLCreate)
 }
 
-func (m *managerMock) UpdateLease(l *Lease) (*Lease, error) {
+func (m *managerMock) EnsureLease(_ context.Context, l *Lease) (bool, error) {
+	i := m.mcalled(methodEnsure)
+	if v, ok := m.result[methodEnsure][i-1].(bool); ok {
+		return v, nil
+	}
+	return false, errors.New("ensure lease failed")
+}
+
+func (m *managerMock) UpdateLease(_ context.Context, l *Lease) (*Lease, error) {
 	return l, m.errOnly(methodUpdate)
 }
 
-func (m *managerMock) RenewLease(*Lease) error {
+func (m *managerMock) RenewLease(context.Context, *Lease) error {
 	return m.errOnly(methodRenew)
 }
 
-func (m *managerMock) TakeLease(*Lease) error {
-	return m.errOnly(methodTake)
+func (m *managerMock) TakeLease(_ context.Context, l *Lease) (err error) {
+	if err = m.errOnly(methodTake); err == nil {
+		l.Owner = takerId
+	}
+	return
+}
+
+func (m *managerMock) TakeLeases(_ context.Context, leases []*Lease) (err error) {
+	if err = m.errOnly(methodTakeGroup); err == nil {
+		for _, l := range leases {
+			l.Owner = takerId
+		}
+	}
+	return
+}
+
+func (m *managerMock) ReserveLease(context.Context, *Lease, time.Time) error {
+	return m.errOnly(methodReserve)
+}
+
+func (m *managerMock) PreemptLease(context.Context, *Lease) error {
+	return m.errOnly(methodPreempt)
+}
+
+func (m *managerMock) AcquireSharedLease(context.Context, *Lease) error {
+	return m.errOnly(methodAcquireShared)
+}
+
+func (m *managerMock) RenewSharedLease(context.Context, *Lease) error {
+	return m.errOnly(methodRenewShared)
+}
+
+func (m *managerMock) ReleaseSharedLease(context.Context, *Lease) error {
+	return m.errOnly(methodReleaseShared)
+}
+
+func (m *managerMock) MigrateLeases(context.Context) (int, error) {
+	return 0, m.errOnly(methodMigrate)
+}
+
+func (m *managerMock) PurgeLease(context.Context, *Lease) error {
+	return m.errOnly(methodPurge)
+}
+
+func (m *managerMock) CompleteLease(_ context.Context, l *Lease) error {
+	l.Owner = "NULL"
+	return m.errOnly(methodComplete)
+}
+
+func (m *managerMock) AcquireStealBudget(_ context.Context, n int) (int, error) {
+	i := m.mcalled(methodStealBudget)
+	switch v := m.result[methodStealBudget][i-1].(type) {
+	case int:
+		return min(n, v), nil
+	case error:
+		return 0, v
+	}
+	return n, nil
+}
+
+func (m *managerMock) ReshardLease(context.Context, *Lease, []*Lease) error {
+	return m.errOnly(methodReshard)
+}
+
+func (m *managerMock) GetLease(_ context.Context, key string) (*Lease, error) {
+	i := m.mcalled(methodGet)
+	if v := m.result[methodGet][i-1]; v != nil {
+		return v.(*Lease), nil
+	}
+	return nil, ErrLeaseNotFound
 }
 
-func (m *managerMock) EvictLease(l *Lease) error {
+func (m *managerMock) EvictLease(_ context.Context, l *Lease) error {
 	l.Owner = "NULL"
 	return m.errOnly(methodEvict)
 }
 
-func (m *managerMock) ListLeases() (leases []*Lease, err error) {
+func (m *managerMock) ListLeases(context.Context) (leases []*Lease, err error) {
 	i := m.mcalled(methodList)
 	if v := m.result[methodList][i-1]; v != nil {
 		leases = v.([]*Lease)
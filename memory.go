@@ -0,0 +1,158 @@
+package lease
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// memoryBackend is a Backend that stores the leases in memory. See: NewMemoryBackend.
+type memoryBackend struct {
+	sync.Mutex
+	leases map[string]*Lease
+	// revision is the last revision that was written.
+	revision int64
+}
+
+// NewMemoryBackend returns a Backend that stores the leases in memory, e.g: to unit test
+// the handoff logic of an application with workers in the same process, or to run it in
+// a single process in local development, without DynamoDB. It's goroutine-safe, and the
+// conditional writes have the semantics of the LeaseManager (on the owner and the counter
+// of the lease).
+//
+//	backend := lease.NewMemoryBackend()
+//	w1 := lease.New("leases", lease.WithBackend(backend))
+//	w2 := lease.New("leases", lease.WithBackend(backend))
+func NewMemoryBackend() Backend {
+	return &memoryBackend{leases: make(map[string]*Lease)}
+}
+
+func (m *memoryBackend) Get(_ context.Context, key string) (*Lease, error) {
+	m.Lock()
+	defer m.Unlock()
+	lease, ok := m.leases[key]
+	if !ok {
+		return nil, ErrLeaseNotFound
+	}
+	return lease.clone(), nil
+}
+
+func (m *memoryBackend) List(context.Context) ([]*Lease, error) {
+	m.Lock()
+	defer m.Unlock()
+	list := make([]*Lease, 0, len(m.leases))
+	for _, lease := range m.leases {
+		list = append(list, lease.clone())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list, nil
+}
+
+func (m *memoryBackend) PutIfAbsent(_ context.Context, lease *Lease) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.leases[lease.Key]; ok {
+		return false, nil
+	}
+	m.store(lease)
+	return true, nil
+}
+
+func (m *memoryBackend) CompareAndUpdate(_ context.Context, old, lease *Lease) error {
+	m.Lock()
+	defer m.Unlock()
+	if stored, ok := m.leases[old.Key]; !ok || stored.revision != old.revision {
+		return ErrConflict
+	}
+	m.store(lease)
+	return nil
+}
+
+func (m *memoryBackend) Delete(_ context.Context, old *Lease) error {
+	m.Lock()
+	defer m.Unlock()
+	stored, ok := m.leases[old.Key]
+	if !ok {
+		return nil
+	}
+	if stored.revision != old.revision {
+		return ErrConflict
+	}
+	delete(m.leases, old.Key)
+	return nil
+}
+
+// store stores a copy of the given lease with a new revision.
+func (m *memoryBackend) store(lease *Lease) {
+	m.revision++
+	stored := lease.clone()
+	stored.revision = strconv.FormatInt(m.revision, 10)
+	m.leases[lease.Key] = stored
+}
+
+// clone returns a copy of the persisted fields of the lease, that does not share its
+// maps and slices with the lease.
+func (l *Lease) clone() *Lease {
+	c := &Lease{
+		Key:            l.Key,
+		Owner:          l.Owner,
+		Counter:        l.Counter,
+		Epoch:          l.Epoch,
+		OwnerTier:      l.OwnerTier,
+		OwnerVersion:   l.OwnerVersion,
+		OwnerHost:      l.OwnerHost,
+		OwnerZone:      l.OwnerZone,
+		TakeoverReason: l.TakeoverReason,
+		PreemptedBy:    l.PreemptedBy,
+		ReservedBy:     l.ReservedBy,
+		ReservedUntil:  l.ReservedUntil,
+		Heartbeat:      l.Heartbeat,
+		payload:        l.payload,
+		Canary:         l.Canary,
+		Group:          l.Group,
+		MaxHolders:     l.MaxHolders,
+		SchemaVersion:  l.SchemaVersion,
+		TombstonedAt:   l.TombstonedAt,
+		CompletedAt:    l.CompletedAt,
+		LoadHint:       l.LoadHint,
+		revision:       l.revision,
+
+		Checkpoint:                   l.Checkpoint,
+		CheckpointSubSequenceNumber:  l.CheckpointSubSequenceNumber,
+		OwnerSwitchesSinceCheckpoint: l.OwnerSwitchesSinceCheckpoint,
+	}
+	if l.Holders != nil {
+		c.Holders = make(map[string]int64, len(l.Holders))
+		for k, v := range l.Holders {
+			c.Holders[k] = v
+		}
+	}
+	if l.DependsOn != nil {
+		c.DependsOn = append([]string(nil), l.DependsOn...)
+	}
+	if l.Labels != nil {
+		c.Labels = make(map[string]string, len(l.Labels))
+		for k, v := range l.Labels {
+			c.Labels[k] = v
+		}
+	}
+	if l.ParentKeys != nil {
+		c.ParentKeys = append([]string(nil), l.ParentKeys...)
+	}
+	if l.ParentShardIds != nil {
+		c.ParentShardIds = append([]string(nil), l.ParentShardIds...)
+	}
+	for k, v := range l.extrafields {
+		c.Set(k, v)
+	}
+	for k, v := range l.explicitfields {
+		if c.explicitfields == nil {
+			c.explicitfields = make(map[string]*dynamodb.AttributeValue)
+		}
+		c.explicitfields[k] = v
+	}
+	return c
+}
@@ -0,0 +1,78 @@
+package lease
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrInvalidMigration error will be returns by NewMigrator if the migrations
+// versions are not positive and unique.
+var ErrInvalidMigration = errors.New("leaser: migration versions must be positive and unique")
+
+// Migration is a single step that upgrades a lease object from the previous schema
+// version to Version. Up may use the lease Get, Set and Del methods to evolve its extra
+// fields, or change its exported fields.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(*Lease) error
+}
+
+// Migrator upgrades lease objects to the latest schema version, by running their
+// pending migrations in order. Leases are upgraded lazily when they are read, and
+// written in the new format on their next Create or Update. Leaser.Migrate upgrades
+// all the leases in the table eagerly.
+//
+// The schema version is recorded on each lease (see: Lease.SchemaVersion). Workers that
+// run an older set of migrations leave newer leases as is, so migrations should be
+// backward compatible with the previous version during a rolling deployment.
+type Migrator struct {
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the given migrations. The migrations are sorted
+// by their versions.
+func NewMigrator(migrations ...Migration) (*Migrator, error) {
+	m := &Migrator{append([]Migration(nil), migrations...)}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	for i, step := range m.migrations {
+		if step.Version <= 0 || i > 0 && m.migrations[i-1].Version == step.Version {
+			return nil, ErrInvalidMigration
+		}
+	}
+	return m, nil
+}
+
+// Version returns the latest schema version.
+func (m *Migrator) Version() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Migrate runs the pending migrations of the given lease object in order.
+func (m *Migrator) Migrate(lease *Lease) error {
+	if lease.SchemaVersion >= m.Version() {
+		return nil
+	}
+	// the migrations may need the overflowed extra fields.
+	if err := lease.Load(); err != nil {
+		return err
+	}
+	for _, step := range m.migrations {
+		if step.Version <= lease.SchemaVersion {
+			continue
+		}
+		if step.Up != nil {
+			if err := step.Up(lease); err != nil {
+				return err
+			}
+		}
+		lease.SchemaVersion = step.Version
+		lease.migrated = true
+	}
+	return nil
+}
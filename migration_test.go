@@ -0,0 +1,94 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// renameStatus is a test migration that renames the "status" field to "state".
+var renameStatus = Migration{
+	Version: 2,
+	Name:    "rename status to state",
+	Up: func(l *Lease) error {
+		if v, ok := l.Get("status"); ok {
+			l.Set("state", v)
+			l.Del("status")
+		}
+		return nil
+	},
+}
+
+func TestNewMigrator(t *testing.T) {
+	m, err := NewMigrator(renameStatus, Migration{Version: 1})
+	assert(t, err == nil, "expect NewMigrator not to fail")
+	assert(t, m.Version() == 2, "expect latest version to be 2")
+	assert(t, m.migrations[0].Version == 1, "expect migrations to be sorted")
+
+	_, err = NewMigrator(Migration{Version: 1}, Migration{Version: 1})
+	assert(t, err == ErrInvalidMigration, "expect duplicate versions to fail")
+	_, err = NewMigrator(Migration{Version: 0})
+	assert(t, err == ErrInvalidMigration, "expect non-positive versions to fail")
+}
+
+func TestSerializerMigrate(t *testing.T) {
+	m, _ := NewMigrator(Migration{Version: 1}, renameStatus)
+	s := newSerializer(&Config{Migrator: m})
+
+	lease, err := s.Decode(map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey:     {S: aws.String("foo")},
+		LeaseOwnerKey:   {S: aws.String("1")},
+		LeaseCounterKey: {N: aws.String("1")},
+		"status":        {S: aws.String("done")},
+	})
+	assert(t, err == nil, "expect Decode not to fail")
+	assert(t, lease.SchemaVersion == 2 && lease.migrated, "expect lease to be upgraded on read")
+	v, ok := lease.Get("state")
+	assert(t, ok && v == "done", "expect migration to rename the field")
+	_, ok = lease.Get("status")
+	assert(t, !ok, "expect old field to be removed")
+
+	item, err := s.Encode(&Lease{Key: "bar"})
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, aws.StringValue(item[LeaseSchemaVersionKey].N) == "2", "expect new leases to be in the latest version")
+
+	lease, err = s.Decode(item)
+	assert(t, err == nil && !lease.migrated, "expect up to date lease not to be upgraded")
+
+	failing, _ := NewMigrator(Migration{Version: 1, Up: func(*Lease) error { return errors.New("failed") }})
+	_, err = newSerializer(&Config{Migrator: failing}).Decode(map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey: {S: aws.String("foo")},
+	})
+	assert(t, err != nil, "expect Decode to fail if the migration fails")
+}
+
+func TestMigrateLeases(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodScan: {
+			&dynamodb.ScanOutput{
+				Items: []map[string]*dynamodb.AttributeValue{
+					{LeaseKeyKey: {S: aws.String("foo")}, LeaseCounterKey: {N: aws.String("1")}},
+					{LeaseKeyKey: {S: aws.String("bar")}, LeaseSchemaVersionKey: {N: aws.String("2")}},
+					{LeaseKeyKey: {S: aws.String("baz")}, LeaseCounterKey: {N: aws.String("3")}},
+				},
+			},
+		},
+		methodPutItem: {
+			&dynamodb.PutItemOutput{},
+			// the lease changed since it was read
+			awserr.New(ConditionalFailed, "", errors.New("")),
+		},
+	})
+	manager := newTestManager(client)
+	manager.Migrator, _ = NewMigrator(Migration{Version: 1}, renameStatus)
+	manager.Serializer = newSerializer(manager.Config)
+
+	n, err := manager.MigrateLeases(context.Background())
+	assert(t, err == nil, "expect MigrateLeases not to fail")
+	assert(t, client.calls[methodPutItem] == 2, "expect to write only the outdated leases")
+	assert(t, n == 1, "expect to skip the leases that changed in the meantime")
+}
@@ -0,0 +1,97 @@
+package lease
+
+import "time"
+
+// Option configures the Coordinator that is created by New.
+type Option func(*Config)
+
+// New create new Coordinator for the given lease table, configured with the given
+// options. The fields that are not set by the options get their defaults, the same
+// as NewFromConfig. For example:
+//
+//	leaser := lease.New("leases",
+//		lease.WithWorkerID("worker-1"),
+//		lease.WithIntervals(10*time.Second, 0),
+//		lease.WithLogger(logger),
+//	)
+func New(leaseTable string, opts ...Option) Leaser {
+	config := &Config{LeaseTable: leaseTable}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return NewFromConfig(config)
+}
+
+// WithWorkerID sets the Config.WorkerId.
+func WithWorkerID(id string) Option {
+	return func(c *Config) {
+		c.WorkerId = id
+	}
+}
+
+// WithIntervals sets the Config.ExpireAfter, that the taker and renewer intervals are
+// derived from, and the Config.DrainInterval. Zero values keep the defaults.
+func WithIntervals(expireAfter, drainInterval time.Duration) Option {
+	return func(c *Config) {
+		c.ExpireAfter = expireAfter
+		c.DrainInterval = drainInterval
+	}
+}
+
+// WithBackoff sets the Config.Backoff.
+func WithBackoff(b Backofface) Option {
+	return func(c *Config) {
+		c.Backoff = b
+	}
+}
+
+// WithRetryPolicy sets the retry strategy of the given operation type. See: Config.RetryPolicies.
+func WithRetryPolicy(op RetryOp, p RetryPolicy) Option {
+	return func(c *Config) {
+		if c.RetryPolicies == nil {
+			c.RetryPolicies = make(map[RetryOp]RetryPolicy)
+		}
+		c.RetryPolicies[op] = p
+	}
+}
+
+// WithLogger sets the Config.Logger.
+func WithLogger(l Logger) Option {
+	return func(c *Config) {
+		c.Logger = l
+	}
+}
+
+// WithClient sets the Config.Client.
+func WithClient(client Clientface) Option {
+	return func(c *Config) {
+		c.Client = client
+	}
+}
+
+// WithReadClient sets the Config.ReadClient, e.g: to serve the scans from a DAX cluster.
+func WithReadClient(client Clientface) Option {
+	return func(c *Config) {
+		c.ReadClient = client
+	}
+}
+
+// WithBackend sets the Config.Backend.
+func WithBackend(b Backend) Option {
+	return func(c *Config) {
+		c.Backend = b
+	}
+}
+
+// WithKCLSchema sets the Config.KCLSchema, to share the lease table with Java KCL workers.
+func WithKCLSchema() Option {
+	return func(c *Config) {
+		c.KCLSchema = true
+	}
+}
+
+// WithConfig calls the given function with the Config, to set the fields that have no
+// dedicated option.
+func WithConfig(fn func(*Config)) Option {
+	return fn
+}
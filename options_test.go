@@ -0,0 +1,24 @@
+package lease
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	client := newClientMock(nil)
+	backoff := &Backoff{}
+	leaser := New("leases",
+		WithWorkerID("1"),
+		WithIntervals(time.Minute, 0),
+		WithBackoff(backoff),
+		WithLogger(testLogger()),
+		WithClient(client),
+		WithConfig(func(c *Config) { c.MaxLeasesToStealAtOneTime = 3 }),
+	)
+	c := leaser.(*Coordinator)
+	assert(t, c.LeaseTable == "leases" && c.WorkerId == "1", "expect the table and worker to be set")
+	assert(t, c.ExpireAfter == time.Minute && c.Backoff == backoff && c.Client == client, "expect the options to be applied")
+	assert(t, c.MaxLeasesToStealAtOneTime == 3, "expect the config function to be applied")
+	assert(t, c.DrainInterval == time.Second && c.JournalSize == 256, "expect the defaults to be applied to the rest of the fields")
+}
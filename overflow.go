@@ -0,0 +1,157 @@
+package lease
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// ErrInvalidPointer error will be returns if the overflow pointer of a lease is malformed.
+var ErrInvalidPointer = errors.New("leaser: invalid overflow pointer")
+
+// Overflow used to store the lease extra fields outside of the table, when their
+// encoded size is above Config.OverflowThreshold. The table holds only a pointer
+// to the stored payload, and the extra fields are fetched lazily on read.
+type Overflow interface {
+	// Put stores the payload of the given lease key, and returns a pointer to it.
+	Put(key string, payload []byte) (pointer string, err error)
+	// Get returns the payload that the given pointer points to.
+	Get(pointer string) ([]byte, error)
+	// Delete the payload that the given pointer points to.
+	Delete(pointer string) error
+}
+
+// overflowRef holds the pointer to the overflowed extra fields of a lease object,
+// and knows how to load them.
+type overflowRef struct {
+	pointer string
+	loaded  bool
+	load    func(*Lease) error
+	// err is the error of the last load.
+	err error
+}
+
+// Load fetches the extra fields of the lease object that overflowed to external
+// storage (see: Config.Overflow). Get and Del call it lazily, and it does nothing
+// if there are no such fields, or if they already loaded. A failed load is retried
+// on the next call.
+func (l *Lease) Load() error {
+	if l.overflow == nil || l.overflow.loaded {
+		return nil
+	}
+	// the lease object was copied; keep its extra fields apart from the other copies.
+	l.extrafields = copyFields(l.extrafields)
+	explicitfields := make(map[string]*dynamodb.AttributeValue, len(l.explicitfields))
+	for k, v := range l.explicitfields {
+		explicitfields[k] = v
+	}
+	l.explicitfields = explicitfields
+	if err := l.overflow.load(l); err != nil {
+		// the copies of the lease object share the ref; record the error on this one only.
+		l.overflow = &overflowRef{pointer: l.overflow.pointer, load: l.overflow.load, err: err}
+		return err
+	}
+	l.overflow = &overflowRef{pointer: l.overflow.pointer, loaded: true}
+	return nil
+}
+
+// Err returns the error of the last lazy load of the overflowed extra fields (see: Load),
+// or nil if it succeeded. Get reports the fields as missing if their load fails, so check
+// Err to tell a missing field from an unavailable one.
+func (l *Lease) Err() error {
+	if l.overflow == nil {
+		return nil
+	}
+	return l.overflow.err
+}
+
+// copyFields returns a shallow copy of the given extra fields.
+func copyFields(fields map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		m[k] = v
+	}
+	return m
+}
+
+// s3Overflow is an Overflow that stores the payloads as S3 objects.
+type s3Overflow struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Overflow returns an Overflow that stores the payloads in the given S3 bucket,
+// under the given prefix. Objects are content-addressed (prefix/leaseKey/sha256), so
+// readers of an older pointer are never broken by a newer write.
+//
+// The object of a lease is deleted with the lease, but the objects that a newer write
+// superseded are kept, since readers may still hold their pointers. Don't expire the
+// objects by age (e.g: with a bucket lifecycle rule): the object of a lease that was not
+// written for a while is still referenced by the lease. Instead, delete the objects under
+// prefix/leaseKey that are not the current pointer of the lease, once they are older than
+// the longest read of a lease.
+func NewS3Overflow(client s3iface.S3API, bucket, prefix string) Overflow {
+	return &s3Overflow{client, bucket, prefix}
+}
+
+func (o *s3Overflow) Put(key string, payload []byte) (string, error) {
+	sum := sha256.Sum256(payload)
+	name := path.Join(o.prefix, key, hex.EncodeToString(sum[:]))
+	_, err := o.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(o.bucket),
+		Key:    aws.String(name),
+		Body:   bytes.NewReader(payload),
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", o.bucket, name), nil
+}
+
+func (o *s3Overflow) Get(pointer string) ([]byte, error) {
+	bucket, name, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	out, err := o.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return ioutil.ReadAll(out.Body)
+}
+
+func (o *s3Overflow) Delete(pointer string) error {
+	bucket, name, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	_, err = o.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(name),
+	})
+	return err
+}
+
+// parsePointer returns the bucket and the object key of the given S3 pointer.
+func parsePointer(pointer string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(pointer, "s3://"), "/", 2)
+	if !strings.HasPrefix(pointer, "s3://") || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrInvalidPointer
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,83 @@
+package lease
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// overflowMock is an in-memory Overflow.
+type overflowMock struct {
+	objects map[string][]byte
+	gets    int
+}
+
+func (o *overflowMock) Put(key string, payload []byte) (string, error) {
+	pointer := fmt.Sprintf("mem://%s/%d", key, len(o.objects))
+	o.objects[pointer] = payload
+	return pointer, nil
+}
+
+func (o *overflowMock) Get(pointer string) ([]byte, error) {
+	o.gets++
+	p, ok := o.objects[pointer]
+	if !ok {
+		return nil, ErrInvalidPointer
+	}
+	return p, nil
+}
+
+func (o *overflowMock) Delete(pointer string) error {
+	delete(o.objects, pointer)
+	return nil
+}
+
+func TestSerializerOverflow(t *testing.T) {
+	o := &overflowMock{objects: make(map[string][]byte)}
+	s := newSerializer(&Config{Overflow: o, OverflowThreshold: 1 << 10})
+
+	// below the threshold
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	lease.Set("small", "value")
+	item, err := s.Encode(lease)
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, item[LeaseOverflowKey] == nil, "expect extra fields not to overflow")
+
+	// above the threshold
+	large := strings.Repeat("checkpoint", 1<<10)
+	lease.Set("large", large)
+	item, err = s.Encode(lease)
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, item["large"] == nil && item["small"] == nil, "expect extra fields to overflow")
+	assert(t, item[LeaseOverflowKey] != nil && len(o.objects) == 1, "expect pointer to the stored fields")
+
+	decoded, err := s.Decode(item)
+	assert(t, err == nil, "expect Decode not to fail")
+	assert(t, o.gets == 0, "expect overflowed fields to be loaded lazily")
+	v, ok := decoded.Get("large")
+	assert(t, ok && v == large, "expect extra field to be loaded")
+	v, ok = decoded.Get("small")
+	assert(t, ok && v == "value", "expect extra field to be loaded")
+	assert(t, o.gets == 1, "expect overflowed fields to be loaded once")
+	assert(t, decoded.Err() == nil, "expect no load error")
+
+	// broken pointer
+	item, _ = s.Encode(lease)
+	for k := range o.objects {
+		delete(o.objects, k)
+	}
+	decoded, _ = s.Decode(item)
+	_, ok = decoded.Get("large")
+	assert(t, !ok && decoded.Err() == ErrInvalidPointer, "expect the load error to be reported")
+	_, err = s.Encode(decoded)
+	assert(t, err == ErrInvalidPointer, "expect Encode to fail if the fields can't be loaded")
+}
+
+func TestParsePointer(t *testing.T) {
+	bucket, key, err := parsePointer("s3://bucket/prefix/foo/abc")
+	assert(t, err == nil && bucket == "bucket" && key == "prefix/foo/abc", "expect pointer to be parsed")
+	for _, p := range []string{"", "s3://", "s3://bucket", "s3:///key", "http://bucket/key"} {
+		_, _, err := parsePointer(p)
+		assert(t, err == ErrInvalidPointer, "expect invalid pointer: "+p)
+	}
+}
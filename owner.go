@@ -0,0 +1,132 @@
+package lease
+
+import (
+	"context"
+	"time"
+)
+
+// OwnerInfo describes the current owner of a lease. See: Leaser.Owner.
+type OwnerInfo struct {
+	Key string
+	// Owner is the worker id of the lease owner. empty if the lease has no owner.
+	Owner string
+	// Host, Zone, Tier and Version are the host name, the availability zone, the priority
+	// tier and the application version of the lease owner. See: Config.Host, Config.Zone,
+	// Config.Tier and Config.Version.
+	Host    string
+	Zone    string
+	Tier    int
+	Version string
+	Counter int64
+	// Epoch is the ownership generation of the lease. See: Lease.Epoch.
+	Epoch int
+	// TakeoverReason is the reason of the last take of the lease.
+	TakeoverReason TakeoverReason
+	// LastRenewal is the last time the lease counter was seen changed by the take cycles.
+	// it's zero if the lease is missing from the cached view, and it was read from the table.
+	LastRenewal time.Time
+	// Expired indicates that the owner did not renew the lease within Config.ExpireAfter,
+	// and it's about to be taken by other workers.
+	Expired bool
+}
+
+// Owner returns the current owner of the lease with the given key, using the cached view
+// of the last take cycle. If the key is missing from the view, Owner fails with ErrLeaseNotFound,
+// or falls back to a consistent read if Config.OwnerConsistentRead is set.
+// Use it to route requests to the worker that processes the lease, or in support tooling.
+func (c *Coordinator) Owner(ctx context.Context, key string) (OwnerInfo, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	lease, ok := c.Taker.Lookup(key)
+	if !ok {
+		if !c.OwnerConsistentRead {
+			return OwnerInfo{}, ErrLeaseNotFound
+		}
+		l, err := c.Manager.GetLease(ctx, key)
+		if err != nil {
+			return OwnerInfo{}, err
+		}
+		lease = *l
+		// the renewal time is known only to the take cycles.
+		lease.lastRenewal = time.Time{}
+	}
+	info := OwnerInfo{
+		Key:            lease.Key,
+		Owner:          lease.Owner,
+		Host:           lease.OwnerHost,
+		Zone:           lease.OwnerZone,
+		Tier:           lease.OwnerTier,
+		Version:        lease.OwnerVersion,
+		Counter:        lease.Counter,
+		Epoch:          lease.Epoch,
+		TakeoverReason: lease.TakeoverReason,
+		LastRenewal:    lease.lastRenewal,
+	}
+	if lease.hasNoOwner() {
+		info.Owner = ""
+	} else if !lease.lastRenewal.IsZero() {
+		info.Expired = lease.isExpired(c.expireAfter(lease.Key), c.now())
+	}
+	return info, nil
+}
+
+// Lookup returns the lease with the given key, as of the last take cycle.
+func (l *leaseTaker) Lookup(key string) (Lease, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	lease, ok := l.view[key]
+	return lease, ok
+}
+
+// setView replaces the cached view of the leases with a snapshot of the given leases.
+// the snapshot is safe for lookups while the take cycle mutates the leases.
+func (l *leaseTaker) setView(leases map[string]*Lease) {
+	view := make(map[string]Lease, len(leases))
+	for key, lease := range leases {
+		view[key] = *lease
+	}
+	l.mu.Lock()
+	l.view = view
+	l.mu.Unlock()
+}
+
+// OwnerChangeType is the type of a lease lifecycle event. See: OwnerChange.
+type OwnerChangeType string
+
+const (
+	// OwnerAcquired means this worker took the lease.
+	OwnerAcquired OwnerChangeType = "acquired"
+	// OwnerLost means the lease was taken by another worker, or deleted, while this
+	// worker held it.
+	OwnerLost OwnerChangeType = "lost"
+	// OwnerReleased means this worker released the lease, e.g: on drain, when it hands
+	// the lease over or rotates it, or when it completes the lease.
+	OwnerReleased OwnerChangeType = "released"
+)
+
+// OwnerChange is a change of the owner of a lease, as seen by this worker.
+// See: Config.OnOwnerChange.
+type OwnerChange struct {
+	Key  string          `json:"key"`
+	Type OwnerChangeType `json:"type"`
+	// Worker is the id of the worker that reports the change.
+	Worker string `json:"worker"`
+	// From and To are the owners before and after the change. they are empty if the
+	// lease had no owner, or it was deleted.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Reason is the reason of the take, for acquired leases.
+	Reason TakeoverReason `json:"reason,omitempty"`
+	At     time.Time      `json:"at"`
+}
+
+// ownerChange calls Config.OnOwnerChange, and the callbacks of the scopes of the given
+// lease, with the given change.
+func (c *Config) ownerChange(change OwnerChange, lease *Lease) {
+	change.Worker = c.WorkerId
+	change.At = c.now()
+	if c.OnOwnerChange != nil {
+		c.OnOwnerChange(change)
+	}
+	c.scopes.ownerChange(change, lease)
+}
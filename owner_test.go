@@ -0,0 +1,41 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCoordinatorOwner(t *testing.T) {
+	logger := testLogger()
+	config := &Config{Logger: logger, LeaseTable: "test", WorkerId: "1", ExpireAfter: time.Minute}
+	config.defaults()
+	taker := &leaseTaker{Config: config}
+	taker.updateLeases(context.Background(), []*Lease{
+		{Key: "foo", Owner: "2", OwnerHost: "host-2", Counter: 3, lastRenewal: time.Now()},
+		{Key: "bar", Owner: "NULL", lastRenewal: time.Now()},
+		{Key: "baz", Owner: "3", lastRenewal: time.Now().Add(-2 * time.Minute)},
+	})
+	manager := newManagerMock(map[method]args{
+		methodGet: {&Lease{Key: "qux", Owner: "4", OwnerHost: "host-4"}},
+	})
+	c := &Coordinator{Config: config, Taker: taker, Manager: manager}
+
+	info, err := c.Owner(context.Background(), "foo")
+	assert(t, err == nil, "expect Owner not to fail")
+	assert(t, info.Owner == "2" && info.Host == "host-2" && info.Counter == 3 && !info.Expired, "expect the owner from the cached view")
+	info, _ = c.Owner(context.Background(), "bar")
+	assert(t, info.Owner == "", "expect no owner for released leases")
+	info, _ = c.Owner(context.Background(), "baz")
+	assert(t, info.Owner == "3" && info.Expired, "expect the lease to be expired")
+
+	_, err = c.Owner(context.Background(), "qux")
+	assert(t, errors.Is(err, ErrLeaseNotFound), "expect to fail without the consistent fallback")
+	assert(t, manager.calls[methodGet] == 0, "expect not to read the table")
+
+	c.OwnerConsistentRead = true
+	info, err = c.Owner(context.Background(), "qux")
+	assert(t, err == nil && info.Owner == "4" && info.Host == "host-4", "expect to fall back to a consistent read")
+	assert(t, info.LastRenewal.IsZero() && !info.Expired, "expect the renewal time to be unknown")
+}
@@ -0,0 +1,133 @@
+package lease
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// OwnerIndexName is the name of the global secondary index on the owner of the leases.
+// See: Config.OwnerIndex.
+const OwnerIndexName = "leaseOwnerIndex"
+
+// queryClient is implemented by the clients that support querying tables, e.g: *dynamodb.DynamoDB.
+type queryClient interface {
+	QueryWithContext(aws.Context, *dynamodb.QueryInput, ...request.Option) (*dynamodb.QueryOutput, error)
+}
+
+// ownerIndex returns the global secondary index on the owner of the leases, with the
+// provisioned throughput of the table, if it's not in on-demand mode.
+func (c *Config) ownerIndex() *dynamodb.GlobalSecondaryIndex {
+	index := &dynamodb.GlobalSecondaryIndex{
+		IndexName: aws.String(OwnerIndexName),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(LeaseOwnerKey),
+				KeyType:       aws.String("HASH"),
+			},
+		},
+		Projection: &dynamodb.Projection{
+			ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
+		},
+	}
+	if !c.OnDemand {
+		index.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(int64(c.LeaseTableReadCap)),
+			WriteCapacityUnits: aws.Int64(int64(c.LeaseTableWriteCap)),
+		}
+	}
+	return index
+}
+
+// ListLeasesByOwner returns the leases held by the given owner. If Config.OwnerIndex is
+// set, and the client supports querying tables, the leases are queried from the owner
+// index. Otherwise, or if the table was created without the index, the leases of the
+// table are scanned and filtered by their owner.
+// The index is eventually consistent, like the scans of ListLeases.
+func (l *LeaseManager) ListLeasesByOwner(ctx context.Context, owner string) ([]*Lease, error) {
+	client, ok := l.readClient().(queryClient)
+	if !l.OwnerIndex || !ok {
+		return l.scanLeasesOf(ctx, owner)
+	}
+	var (
+		list  []*Lease
+		err   error
+		res   *dynamodb.QueryOutput
+		input = &dynamodb.QueryInput{
+			TableName:              aws.String(l.LeaseTable),
+			IndexName:              aws.String(OwnerIndexName),
+			KeyConditionExpression: aws.String("#owner = :owner"),
+			ExpressionAttributeNames: map[string]*string{
+				"#owner": aws.String(LeaseOwnerKey),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":owner": {S: aws.String(owner)},
+			},
+		}
+	)
+	r := l.retrier(RetryList)
+	for r.more() {
+		res, err = client.QueryWithContext(ctx, input)
+		if err != nil {
+			// permanent errors return immediately.
+			if !r.retryable(err) {
+				break
+			}
+
+			backoff := r.delay()
+
+			l.Logger.WithFields(Fields{
+				"backoff": backoff,
+				"attempt": r.attempt,
+			}).Warnf("Worker %s failed to query leases table", l.WorkerId)
+
+			if serr := l.sleep(ctx, backoff); serr != nil {
+				err = serr
+				break
+			}
+			continue
+		}
+		for _, item := range res.Items {
+			if lease, err := l.Serializer.Decode(item); err != nil {
+				l.Logger.WithError(err).Errorf("decode lease")
+			} else {
+				list = append(list, lease)
+			}
+		}
+		if len(res.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
+	// tables that were created before Config.OwnerIndex was set have no index.
+	if isValidationFailed(err) {
+		l.Logger.WithError(err).Warnf("Worker %s failed to query the owner index, scanning leases table", l.WorkerId)
+		return l.scanLeasesOf(ctx, owner)
+	}
+	if err = l.retryError(r, "", err); err != nil {
+		return nil, l.wrapError("list by owner", "", err)
+	}
+	return list, nil
+}
+
+// scanLeasesOf scans the leases table and returns the leases held by the given owner.
+func (l *LeaseManager) scanLeasesOf(ctx context.Context, owner string) ([]*Lease, error) {
+	list, err := l.ScanLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return leasesOf(list, owner), nil
+}
+
+// leasesOf returns the leases in the given list that are held by the given owner.
+func leasesOf(list []*Lease, owner string) []*Lease {
+	var owned []*Lease
+	for _, lease := range list {
+		if lease.Owner == owner {
+			owned = append(owned, lease)
+		}
+	}
+	return owned
+}
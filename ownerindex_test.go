@@ -0,0 +1,91 @@
+package lease
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// queryMock is a clientMock that supports querying tables. it returns the given pages,
+// or the given error if it's set.
+type queryMock struct {
+	*clientMock
+	err     error
+	pages   []*dynamodb.QueryOutput
+	queries []*dynamodb.QueryInput
+}
+
+func (c *queryMock) QueryWithContext(_ aws.Context, input *dynamodb.QueryInput, _ ...request.Option) (*dynamodb.QueryOutput, error) {
+	copied := *input
+	c.queries = append(c.queries, &copied)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.pages[len(c.queries)-1], nil
+}
+
+func TestCreateTableOwnerIndex(t *testing.T) {
+	manager := newTestManager(nil)
+	input := manager.createTableInput()
+	assert(t, input.GlobalSecondaryIndexes == nil, "expect no index by default")
+
+	manager.OwnerIndex = true
+	input = manager.createTableInput()
+	assert(t, len(input.AttributeDefinitions) == 2, "expect the owner attribute to be defined")
+	assert(t, len(input.GlobalSecondaryIndexes) == 1, "expect the owner index")
+	index := input.GlobalSecondaryIndexes[0]
+	assert(t, aws.StringValue(index.IndexName) == OwnerIndexName, "expect the owner index")
+	assert(t, aws.StringValue(index.KeySchema[0].AttributeName) == LeaseOwnerKey, "expect the index to be on the owner")
+	assert(t, aws.Int64Value(index.ProvisionedThroughput.ReadCapacityUnits) == 10, "expect the index to have the table throughput")
+
+	manager.OnDemand = true
+	input = manager.createTableInput()
+	assert(t, input.GlobalSecondaryIndexes[0].ProvisionedThroughput == nil, "expect an on-demand index")
+}
+
+func TestListLeasesByOwner(t *testing.T) {
+	item := func(key, owner string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey:   {S: aws.String(key)},
+			LeaseOwnerKey: {S: aws.String(owner)},
+		}
+	}
+	client := &queryMock{
+		clientMock: newClientMock(map[method]args{
+			methodScan: {
+				&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{
+					item("foo", "1"), item("bar", "2"), item("baz", "1"),
+				}},
+				&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{
+					item("foo", "1"), item("bar", "2"), item("baz", "1"),
+				}},
+			},
+		}),
+		pages: []*dynamodb.QueryOutput{
+			{Items: []map[string]*dynamodb.AttributeValue{item("foo", "1")}, LastEvaluatedKey: item("foo", "1")},
+			{Items: []map[string]*dynamodb.AttributeValue{item("baz", "1")}},
+		},
+	}
+	manager := newTestManager(client)
+
+	leases, err := manager.ListLeasesByOwner(context.Background(), "1")
+	assert(t, err == nil && len(leases) == 2, "expect to list the leases of the owner")
+	assert(t, client.calls[methodScan] == 1 && len(client.queries) == 0, "expect to scan the table without the owner index")
+
+	manager.OwnerIndex = true
+	leases, err = manager.ListLeasesByOwner(context.Background(), "1")
+	assert(t, err == nil && len(leases) == 2, "expect to list the leases of the owner")
+	assert(t, leases[0].Key == "foo" && leases[1].Key == "baz", "expect to list the leases of all the pages")
+	assert(t, client.calls[methodScan] == 1 && len(client.queries) == 2, "expect to query the owner index")
+	assert(t, aws.StringValue(client.queries[0].IndexName) == OwnerIndexName, "expect to query the owner index")
+	assert(t, client.queries[1].ExclusiveStartKey != nil, "expect to query the next page")
+
+	client.err = awserr.New(ValidationFailed, "The table does not have the specified index", nil)
+	leases, err = manager.ListLeasesByOwner(context.Background(), "1")
+	assert(t, err == nil && len(leases) == 2, "expect to list the leases of the owner")
+	assert(t, client.calls[methodScan] == 2 && len(client.queries) == 3, "expect to scan the table without the owner index")
+}
@@ -0,0 +1,95 @@
+package lease
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// SetPayload sets the payload of the lease to the given value (e.g: a struct), to be written
+// on the next Update, or when the lease is created. The value is marshaled to a single
+// attribute (LeasePayloadKey) using the dynamodbattribute package, so it keeps its types
+// on the round-trip, unlike the extra fields. A nil value removes the payload.
+//
+//	type Shard struct {
+//		Stream   string
+//		Sequence int64
+//		Since    time.Time
+//	}
+//
+//	lease.SetPayload(Shard{Stream: "orders", Sequence: 42, Since: time.Now()})
+//	lease, err = leaser.Update(ctx, lease)
+//
+// Unlike the extra fields, the payload is not packed by the Codec, the Cipher, the
+// Compressor or the Overflow.
+func (l *Lease) SetPayload(v interface{}) error {
+	var payload *dynamodb.AttributeValue
+	if v != nil {
+		av, err := dynamodbattribute.Marshal(v)
+		if err != nil {
+			return err
+		}
+		payload = av
+	}
+	l.payload = payload
+	l.payloadSet = true
+	return nil
+}
+
+// Payload unmarshals the payload of the lease into the value pointed to by v. It does
+// nothing if the lease has no payload. See: Lease.SetPayload.
+//
+//	var shard Shard
+//	if err := lease.Payload(&shard); err != nil {
+//		return err
+//	}
+func (l *Lease) Payload(v interface{}) error {
+	if l.payload == nil {
+		return nil
+	}
+	return dynamodbattribute.Unmarshal(l.payload, v)
+}
+
+// RawPayload returns the payload of the lease as it's stored in DynamoDB, or nil if the
+// lease has no payload. e.g: to store it in a Backend, and restore it using SetRawPayload.
+func (l *Lease) RawPayload() *dynamodb.AttributeValue {
+	return l.payload
+}
+
+// SetRawPayload restores the stored payload of the lease. Unlike SetPayload, it's not
+// written on the next Update.
+func (l *Lease) SetRawPayload(payload *dynamodb.AttributeValue) {
+	l.payload = payload
+}
+
+// IsPayloadSet test if the payload was set using SetPayload, and it's written on the next
+// Update. e.g: to forward the lease to a remote Leaser.
+func (l *Lease) IsPayloadSet() bool {
+	return l.payloadSet
+}
+
+// updateCondition returns the lease that UpdateLease conditions the write on. the checkpoint
+// and the payload are written only by the owner of the lease, and with the KCL schema, only
+// if the lease was not renewed since it was read, like the KCL checkpoints. the other extra
+// fields are written unconditionally.
+func (l *Lease) updateCondition(kcl bool) (cond Lease) {
+	if (!l.checkpointed && !l.payloadSet) || l.hasNoOwner() {
+		return
+	}
+	cond.Owner = l.Owner
+	if kcl && l.checkpointed {
+		cond.Counter = l.Counter
+	}
+	return
+}
+
+// setKeys returns the attributes of this package that were set on the lease, and written
+// by UpdateLease. The KCL checkpoint is written with the KCL attributes.
+func (l *Lease) setKeys(kcl bool) (keys []string) {
+	if l.checkpointed && !kcl {
+		keys = append(keys, LeaseCheckpointKey)
+	}
+	if l.payloadSet {
+		keys = append(keys, LeasePayloadKey)
+	}
+	return
+}
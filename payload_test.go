@@ -0,0 +1,62 @@
+package lease
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+type testPayload struct {
+	Stream   string
+	Sequence int64
+	Since    time.Time
+	Tags     []string
+}
+
+func TestPayloadSerialize(t *testing.T) {
+	manager := newTestManager(nil)
+	since := time.Unix(1000, 0).UTC()
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	err := lease.SetPayload(testPayload{Stream: "orders", Sequence: math.MaxInt64, Since: since, Tags: []string{"a"}})
+	assert(t, err == nil, "expect the payload to be set")
+
+	item, err := manager.Serializer.Encode(lease)
+	assert(t, err == nil && item[LeasePayloadKey].M != nil, "expect the payload to be encoded as a map")
+	decoded, err := manager.Serializer.Decode(item)
+	assert(t, err == nil, "expect the lease to be decoded")
+	_, ok := decoded.Get(LeasePayloadKey)
+	assert(t, !ok, "expect the payload not to be an extra field")
+
+	var payload testPayload
+	err = decoded.Payload(&payload)
+	assert(t, err == nil && payload.Stream == "orders" && payload.Sequence == math.MaxInt64, "expect the payload to keep its types")
+	assert(t, payload.Since.Equal(since) && len(payload.Tags) == 1, "expect the payload to be decoded")
+
+	var empty testPayload
+	assert(t, (&Lease{}).Payload(&empty) == nil && empty.Stream == "", "expect nothing to be decoded without a payload")
+}
+
+func TestUpdateLeasePayload(t *testing.T) {
+	client := &updateMock{clientMock: newClientMock(nil)}
+	manager := newTestManager(client)
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+
+	lease.SetPayload(testPayload{Stream: "orders"})
+	_, err := manager.UpdateLease(context.Background(), lease)
+	assert(t, err == nil && strings.Contains(*client.inputs[0].UpdateExpression, LeasePayloadKey+" = :"+LeasePayloadKey), "expect the payload to be set")
+	assert(t, aws.StringValue(client.inputs[0].ExpressionAttributeValues[":"+LeasePayloadKey].M["Stream"].S) == "orders", "expect the payload to be written")
+
+	lease.SetPayload(nil)
+	manager.UpdateLease(context.Background(), lease)
+	assert(t, strings.Contains(*client.inputs[1].UpdateExpression, "REMOVE "+LeasePayloadKey), "expect a cleared payload to be removed")
+
+	decoded := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	decoded.SetRawPayload(client.inputs[0].ExpressionAttributeValues[":"+LeasePayloadKey])
+	decoded.Set("status", "done")
+	manager.UpdateLease(context.Background(), decoded)
+	assert(t, !strings.Contains(*client.inputs[2].UpdateExpression, LeasePayloadKey), "expect the payload to be written only when it's set")
+}
@@ -0,0 +1,60 @@
+package lease
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrPITRUnsupported error will be returns if Config.PointInTimeRecovery is set, and the
+// lease table is created with a client that does not support updating continuous backups.
+var ErrPITRUnsupported = errors.New("leaser: client does not support point-in-time recovery")
+
+// continuousBackupsUpdater is implemented by the clients that support the continuous
+// backups of tables, e.g: *dynamodb.DynamoDB.
+type continuousBackupsUpdater interface {
+	UpdateContinuousBackupsWithContext(aws.Context, *dynamodb.UpdateContinuousBackupsInput, ...request.Option) (*dynamodb.UpdateContinuousBackupsOutput, error)
+}
+
+// enablePITR enables the point-in-time recovery of the lease table. Enabling it is
+// idempotent. The continuous backups may be unavailable for a short time after the table
+// is created, and the call is retried.
+func (l *LeaseManager) enablePITR(ctx context.Context) (err error) {
+	client, ok := l.Client.(continuousBackupsUpdater)
+	if !ok {
+		return ErrPITRUnsupported
+	}
+	r := l.retrier(RetryCreate)
+	for r.more() {
+		_, err = client.UpdateContinuousBackupsWithContext(ctx, &dynamodb.UpdateContinuousBackupsInput{
+			TableName: aws.String(l.LeaseTable),
+			PointInTimeRecoverySpecification: &dynamodb.PointInTimeRecoverySpecification{
+				PointInTimeRecoveryEnabled: aws.Bool(true),
+			},
+		})
+		if err == nil {
+			break
+		}
+
+		// permanent errors return immediately.
+		if !r.retryable(err) {
+			break
+		}
+
+		backoff := r.delay()
+
+		l.Logger.WithFields(Fields{
+			"backoff": backoff,
+			"attempt": r.attempt,
+		}).Warnf("Worker %s failed to enable point-in-time recovery", l.WorkerId)
+
+		if serr := l.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+	}
+	return l.retryError(r, "", err)
+}
@@ -0,0 +1,54 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// backupsMock is a clientMock that supports continuous backups. the first calls fail
+// with the given errors.
+type backupsMock struct {
+	*clientMock
+	errs    []error
+	updates int
+}
+
+func (c *backupsMock) UpdateContinuousBackupsWithContext(aws.Context, *dynamodb.UpdateContinuousBackupsInput, ...request.Option) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	if c.updates++; c.updates <= len(c.errs) {
+		return nil, c.errs[c.updates-1]
+	}
+	return new(dynamodb.UpdateContinuousBackupsOutput), nil
+}
+
+func TestCreateTablePITR(t *testing.T) {
+	active := &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableStatus: aws.String(dynamodb.TableStatusActive),
+	}}
+	client := &backupsMock{
+		clientMock: newClientMock(map[method]args{
+			methodCreateTable:   {new(dynamodb.CreateTableOutput)},
+			methodDescribeTable: {active},
+		}),
+		// the continuous backups are not available yet.
+		errs: []error{awserr.New(dynamodb.ErrCodeContinuousBackupsUnavailableException, "", errors.New(""))},
+	}
+	manager := newTestManager(client)
+	manager.PointInTimeRecovery = true
+
+	err := manager.CreateLeaseTable(context.Background())
+	assert(t, err == nil, "expect not to fail")
+	assert(t, client.updates == 2, "expect to retry until the point-in-time recovery is enabled")
+
+	manager.Client = newClientMock(map[method]args{
+		methodCreateTable:   {new(dynamodb.CreateTableOutput)},
+		methodDescribeTable: {active},
+	})
+	err = manager.CreateLeaseTable(context.Background())
+	assert(t, errors.Is(err, ErrPITRUnsupported), "expect to fail with clients that can't enable the point-in-time recovery")
+}
@@ -0,0 +1,173 @@
+// Package postgresbackend runs the coordinator on PostgreSQL, instead of DynamoDB.
+//
+// Each lease is a row of the lease table, with its owner and counter in columns, the lease
+// itself as a JSON document, and a version that is incremented on every write. The
+// conditional writes of lease.Backend are conditional UPDATE statements on the version
+// (i.e: WHERE lease_version = $n), so the optimistic concurrency of the DynamoDB condition
+// expressions holds across workers. The package uses database/sql, and
+// works with any Postgres driver. For example:
+//
+//	db, err := sql.Open("pgx", "postgres://localhost:5432/app")
+//	err = postgresbackend.Migrate(ctx, db, "leases")
+//	leaser := lease.New("leases", lease.WithBackend(postgresbackend.New(db, "leases")))
+package postgresbackend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+)
+
+// Backend is a lease.Backend that stores the leases in a Postgres table.
+type Backend struct {
+	DB *sql.DB
+	// Table is the name of the lease table. See: Migrate.
+	Table string
+}
+
+// New returns a lease.Backend that stores the leases in the given table.
+func New(db *sql.DB, table string) lease.Backend {
+	return &Backend{DB: db, Table: table}
+}
+
+// Migrate creates the lease table with the given name, if it's not already exists, and
+// adds the version column to a table that was created without it.
+func Migrate(ctx context.Context, db *sql.DB, table string) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+quote(table)+` (
+	lease_key TEXT PRIMARY KEY,
+	lease_owner TEXT NOT NULL,
+	lease_counter BIGINT NOT NULL,
+	lease_data TEXT NOT NULL,
+	lease_version BIGINT NOT NULL DEFAULT 1
+)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `ALTER TABLE `+quote(table)+` ADD COLUMN IF NOT EXISTS lease_version BIGINT NOT NULL DEFAULT 1`)
+	return err
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (*lease.Lease, error) {
+	var (
+		raw     string
+		version int64
+	)
+	err := b.DB.QueryRowContext(ctx, `SELECT lease_data, lease_version FROM `+quote(b.Table)+` WHERE lease_key = $1`, key).Scan(&raw, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, lease.ErrLeaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(raw, version)
+}
+
+func (b *Backend) List(ctx context.Context) ([]*lease.Lease, error) {
+	rows, err := b.DB.QueryContext(ctx, `SELECT lease_data, lease_version FROM `+quote(b.Table)+` ORDER BY lease_key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*lease.Lease
+	for rows.Next() {
+		var (
+			raw     string
+			version int64
+		)
+		if err := rows.Scan(&raw, &version); err != nil {
+			return nil, err
+		}
+		l, err := decode(raw, version)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+func (b *Backend) PutIfAbsent(ctx context.Context, l *lease.Lease) (bool, error) {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return false, err
+	}
+	res, err := b.DB.ExecContext(ctx, `INSERT INTO `+quote(b.Table)+` (lease_key, lease_owner, lease_counter, lease_data)
+VALUES ($1, $2, $3, $4) ON CONFLICT (lease_key) DO NOTHING`, l.Key, l.Owner, l.Counter, raw)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// CompareAndUpdate replaces the stored lease, and increments its version, conditional on
+// its version not being changed since old was read.
+func (b *Backend) CompareAndUpdate(ctx context.Context, old, l *lease.Lease) error {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return err
+	}
+	version, err := strconv.ParseInt(old.Revision(), 10, 64)
+	if err != nil {
+		return lease.ErrConflict
+	}
+	res, err := b.DB.ExecContext(ctx, `UPDATE `+quote(b.Table)+` SET lease_owner = $2, lease_counter = $3, lease_data = $4, lease_version = lease_version + 1
+WHERE lease_key = $1 AND lease_version = $5`,
+		old.Key, l.Owner, l.Counter, raw, version)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		if err == nil {
+			err = lease.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes the stored lease in a transaction, that locks its row using SELECT ...
+// FOR UPDATE, to tell a changed lease from a deleted one.
+func (b *Backend) Delete(ctx context.Context, old *lease.Lease) error {
+	tx, err := b.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var version int64
+	err = tx.QueryRowContext(ctx, `SELECT lease_version FROM `+quote(b.Table)+` WHERE lease_key = $1 FOR UPDATE`, old.Key).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strconv.FormatInt(version, 10) != old.Revision() {
+		return lease.ErrConflict
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+quote(b.Table)+` WHERE lease_key = $1`, old.Key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// decode returns the lease of the given stored document, with the given version as its
+// revision.
+func decode(raw string, version int64) (*lease.Lease, error) {
+	l, err := record.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	l.SetRevision(strconv.FormatInt(version, 10))
+	return l, nil
+}
+
+// quote returns the given identifier quoted for Postgres.
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,85 @@
+package postgresbackend
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+)
+
+func TestBackend(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	b := New(db, "leases")
+
+	l := &lease.Lease{Key: "foo", Owner: "1", Counter: 1}
+	raw, _ := record.Encode(l)
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS "leases"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE "leases" ADD COLUMN IF NOT EXISTS lease_version`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := Migrate(ctx, db, "leases"); err != nil {
+		t.Fatalf("expect the table to be created: %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "leases"`)).
+		WithArgs("foo", "1", 1, raw).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if ok, err := b.PutIfAbsent(ctx, l); err != nil || ok {
+		t.Errorf("expect existing leases not to be stored, got: %v, %v", ok, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT lease_data, lease_version FROM "leases" WHERE lease_key = $1`)).
+		WithArgs("foo").
+		WillReturnRows(sqlmock.NewRows([]string{"lease_data", "lease_version"}).AddRow(raw, 3))
+	old, err := b.Get(ctx, "foo")
+	if err != nil || old.Owner != "1" || old.Counter != 1 || old.Revision() != "3" {
+		t.Fatalf("expect to get the stored lease, got: %+v, %v", old, err)
+	}
+
+	update := *old
+	update.Counter++
+	uraw, _ := record.Encode(&update)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "leases" SET lease_owner = $2, lease_counter = $3, lease_data = $4, lease_version = lease_version + 1
+WHERE lease_key = $1 AND lease_version = $5`)).
+		WithArgs("foo", "1", 2, uraw, 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := b.CompareAndUpdate(ctx, old, &update); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the update of a changed lease to conflict, got: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT lease_version FROM "leases" WHERE lease_key = $1 FOR UPDATE`)).
+		WithArgs("foo").
+		WillReturnRows(sqlmock.NewRows([]string{"lease_version"}).AddRow(4))
+	mock.ExpectRollback()
+	if err := b.Delete(ctx, old); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the delete of a changed lease to conflict, got: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT lease_version FROM "leases" WHERE lease_key = $1 FOR UPDATE`)).
+		WithArgs("foo").
+		WillReturnRows(sqlmock.NewRows([]string{"lease_version"}).AddRow(3))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "leases"`)).WithArgs("foo").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := b.Delete(ctx, old); err != nil {
+		t.Errorf("expect the lease to be deleted: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT lease_data, lease_version FROM "leases" WHERE lease_key = $1`)).
+		WithArgs("bar").
+		WillReturnRows(sqlmock.NewRows([]string{"lease_data", "lease_version"}))
+	if _, err := b.Get(ctx, "bar"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect the lease not to be found, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
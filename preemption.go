@@ -0,0 +1,20 @@
+package lease
+
+// chooseLeasesToPreempt returns up to needed leases (no more than MaxLeasesToStealAtOneTime)
+// that held by workers of lower tier than this worker and that not already preempted.
+func (l *leaseTaker) chooseLeasesToPreempt(needed int) []*Lease {
+	var candidates []*Lease
+	for _, lease := range l.allLeases {
+		if lease.hasNoOwner() || lease.Owner == l.WorkerId || lease.PreemptedBy != "" {
+			continue
+		}
+		if lease.OwnerTier < l.Tier && !lease.isExpired(l.expireAfter(lease.Key), l.now()) {
+			candidates = append(candidates, lease)
+		}
+	}
+	shuffle(candidates)
+	if n := min(needed, l.MaxLeasesToStealAtOneTime); len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
@@ -0,0 +1,104 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// ErrDrift error will be returns by Start if the lease table drifted from Config.Source,
+// while Config.StrictPreflight is set. See: DriftError.
+var ErrDrift = errors.New("leaser: lease table drifted from the work unit source")
+
+// Source is the authoritative source of the work units of the application, e.g: the shard
+// list of a stream, or the tenant list. Each work unit is expected to have a lease with the
+// same key. See: Config.Source.
+type Source interface {
+	Keys(context.Context) ([]string, error)
+}
+
+// SourceFunc is an adapter to allow the use of ordinary functions as Source.
+type SourceFunc func(context.Context) ([]string, error)
+
+// Keys calls f(ctx).
+func (f SourceFunc) Keys(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// Drift is the difference between the lease table and the work units of Config.Source.
+type Drift struct {
+	// Missing are the keys of the work units that have no lease. they are never processed.
+	Missing []string
+	// Orphaned are the keys of the leases that have no work unit, e.g: leases of closed
+	// shards or deleted tenants.
+	Orphaned []string
+}
+
+// Empty test if the lease table matches the source.
+func (d Drift) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Orphaned) == 0
+}
+
+// Preflight compares the lease table with the work units of Config.Source, and returns the
+// drift between them. Tombstoned leases are ignored. Use EnsureLeases to create the missing
+// leases. See: Config.StrictPreflight.
+func (c *Coordinator) Preflight(ctx context.Context) (Drift, error) {
+	var drift Drift
+	if c.Source == nil {
+		return drift, nil
+	}
+	keys, err := c.Source.Keys(ctx)
+	if err != nil {
+		return drift, err
+	}
+	list, err := c.Manager.ListLeases(ctx)
+	if err != nil {
+		return drift, err
+	}
+	existing := make(map[string]bool)
+	for _, lease := range liveLeases(list) {
+		existing[lease.Key] = true
+	}
+	units := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		units[key] = true
+		if !existing[key] {
+			drift.Missing = append(drift.Missing, key)
+		}
+	}
+	for key := range existing {
+		if !units[key] {
+			drift.Orphaned = append(drift.Orphaned, key)
+		}
+	}
+	sort.Strings(drift.Missing)
+	sort.Strings(drift.Orphaned)
+	return drift, nil
+}
+
+// preflight runs the preflight check of Start, and reports the drift. it fails only if
+// Config.StrictPreflight is set.
+func (c *Coordinator) preflight(ctx context.Context) error {
+	drift, err := c.Preflight(ctx)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Worker %s failed to run the preflight check", c.WorkerId)
+		if c.StrictPreflight {
+			return err
+		}
+		return nil
+	}
+	if drift.Empty() {
+		return nil
+	}
+	c.Logger.WithFields(Fields{
+		"missing":  len(drift.Missing),
+		"orphaned": len(drift.Orphaned),
+	}).Warnf("Worker %s found drift between the lease table and the work unit source", c.WorkerId)
+	if c.OnDrift != nil {
+		c.OnDrift(drift)
+	}
+	if c.StrictPreflight {
+		return &DriftError{drift}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPreflight(t *testing.T) {
+	list := []*Lease{
+		{Key: "shard-1"},
+		{Key: "shard-2"},
+		{Key: "shard-old"},
+		{Key: "shard-3", TombstonedAt: time.Now()},
+	}
+	manager := newManagerMock(map[method]args{
+		methodList: {list, list, list},
+	})
+	var reported Drift
+	config := &Config{Logger: testLogger(), WorkerId: "1",
+		Source: SourceFunc(func(context.Context) ([]string, error) {
+			return []string{"shard-1", "shard-2", "shard-3"}, nil
+		}),
+		OnDrift: func(d Drift) { reported = d },
+	}
+	c := &Coordinator{Config: config, Manager: manager}
+
+	drift, err := c.Preflight(context.Background())
+	assert(t, err == nil, "expect the preflight check not to fail")
+	assert(t, len(drift.Missing) == 1 && drift.Missing[0] == "shard-3", "expect the tombstoned lease to be missing")
+	assert(t, len(drift.Orphaned) == 1 && drift.Orphaned[0] == "shard-old", "expect the lease without a work unit to be orphaned")
+
+	assert(t, c.preflight(context.Background()) == nil, "expect the drift to be reported only")
+	assert(t, len(reported.Missing) == 1, "expect the drift to be reported to OnDrift")
+
+	config.StrictPreflight = true
+	err = c.preflight(context.Background())
+	var derr *DriftError
+	assert(t, errors.Is(err, ErrDrift) && errors.As(err, &derr) && len(derr.Drift.Orphaned) == 1, "expect to fail in strict mode")
+}
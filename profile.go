@@ -0,0 +1,119 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Balancing is the strategy used to balance the leases between the workers.
+type Balancing int
+
+const (
+	// InheritBalancing uses the balancing strategy of the config. See: Config.BalanceByLoad.
+	InheritBalancing Balancing = iota
+	// BalanceLeaseCount equalizes the number of leases of the workers.
+	BalanceLeaseCount
+	// BalanceLoad equalizes the total load of the workers.
+	BalanceLoad
+)
+
+// Profile overrides the config settings for the leases of a single namespace, so mixed
+// workloads (e.g: fast stream shards and slow batch jobs) can share one table. The leases
+// of each namespace that has a profile are balanced separately from the rest of the leases.
+// Zero value fields means the config settings are used. The capacity of a namespace is
+// limited with Quotas.
+type Profile struct {
+	// ExpireAfter is the expiry of the leases in the namespace. The renewer renews all
+	// leases at the interval that derived from Config.ExpireAfter, so it can only be
+	// longer than Config.ExpireAfter.
+	ExpireAfter time.Duration
+
+	// MaxLeasesToStealAtOneTime is the maximum number of leases of the namespace to
+	// steal at one time.
+	MaxLeasesToStealAtOneTime int
+
+	// Balancing is the strategy used to balance the leases of the namespace.
+	Balancing Balancing
+}
+
+// profile returns the profile for the namespace of the given lease key, and boolean
+// that indicates if such profile exists.
+func (c *Config) profile(key string) (Profile, bool) {
+	p, ok := c.Profiles[c.namespace(key)]
+	return p, ok
+}
+
+// expireAfter returns the expiry of the lease with the given key.
+func (c *Config) expireAfter(key string) time.Duration {
+	if p, ok := c.profile(key); ok && p.ExpireAfter > 0 {
+		return p.ExpireAfter
+	}
+	return c.ExpireAfter
+}
+
+// validateProfiles returns an error if one of the profiles is invalid.
+func (c *Config) validateProfiles() error {
+	for ns, p := range c.Profiles {
+		if p.ExpireAfter != 0 && p.ExpireAfter < c.ExpireAfter {
+			return configError(fmt.Sprintf("Profiles[%q].ExpireAfter", ns), ErrInvalidInterval, "must be greater or equal to ExpireAfter")
+		}
+		if p.MaxLeasesToStealAtOneTime < 0 {
+			return configError(fmt.Sprintf("Profiles[%q].MaxLeasesToStealAtOneTime", ns), ErrInvalidValue, "must be greater than 0")
+		}
+		if p.Balancing < InheritBalancing || p.Balancing > BalanceLoad {
+			return configError(fmt.Sprintf("Profiles[%q].Balancing", ns), ErrInvalidValue, "is unknown")
+		}
+	}
+	return nil
+}
+
+// apply returns a copy of the given config, with the settings of the profile.
+func (p Profile) apply(c *Config) *Config {
+	n := *c
+	if p.ExpireAfter > 0 {
+		n.ExpireAfter = p.ExpireAfter
+	}
+	if p.MaxLeasesToStealAtOneTime > 0 {
+		n.MaxLeasesToStealAtOneTime = p.MaxLeasesToStealAtOneTime
+	}
+	switch p.Balancing {
+	case BalanceLeaseCount:
+		n.BalanceByLoad = false
+	case BalanceLoad:
+		n.BalanceByLoad = true
+	}
+	return &n
+}
+
+// balanceProfiles balances the leases of each namespace that has a profile separately,
+// with the settings of its profile, and the rest of the leases together.
+func (l *leaseTaker) balanceProfiles(ctx context.Context) {
+	all, config := l.allLeases, l.Config
+	defer func() {
+		l.allLeases, l.Config = all, config
+	}()
+	var (
+		rest  = make(map[string]*Lease)
+		parts = make(map[string]map[string]*Lease)
+	)
+	for key, lease := range all {
+		ns := l.namespace(key)
+		if _, ok := l.Profiles[ns]; !ok {
+			rest[key] = lease
+			continue
+		}
+		if parts[ns] == nil {
+			parts[ns] = make(map[string]*Lease)
+		}
+		parts[ns][key] = lease
+	}
+	if len(rest) > 0 {
+		l.allLeases = rest
+		l.balance(ctx)
+	}
+	for ns, leases := range parts {
+		l.allLeases, l.Config = leases, l.Profiles[ns].apply(config)
+		l.balance(ctx)
+	}
+}
@@ -0,0 +1,87 @@
+package lease
+
+import "strings"
+
+// Quota limits the number of leases within a single namespace(tenant).
+// Zero value fields means unlimited.
+type Quota struct {
+	// MaxLeases is the maximum number of leases that may exist in the namespace.
+	// Enforced on Create.
+	MaxLeases int
+
+	// MaxLeasesPerWorker is the maximum number of leases in the namespace that
+	// a single worker may hold. Enforced on Take.
+	MaxLeasesPerWorker int
+}
+
+// PrefixNamespace returns a namespace function that uses the part of the lease
+// key that precedes the first occurrence of sep as the namespace.
+// Keys that do not contain the separator belong to the empty namespace.
+//
+//	PrefixNamespace("/")("tenant-a/shard-1")  // "tenant-a"
+func PrefixNamespace(sep string) func(string) string {
+	return func(key string) string {
+		if i := strings.Index(key, sep); i > 0 {
+			return key[:i]
+		}
+		return ""
+	}
+}
+
+// namespace returns the namespace of the given lease key.
+func (c *Config) namespace(key string) string {
+	if c.Namespace == nil {
+		return ""
+	}
+	return c.Namespace(key)
+}
+
+// quota returns the quota for the namespace of the given lease key, and boolean
+// that indicates if such quota exists.
+func (c *Config) quota(key string) (Quota, bool) {
+	q, ok := c.Quotas[c.namespace(key)]
+	return q, ok
+}
+
+// exceedsQuota test if a new lease with the given key can be added to
+// list of existing leases without exceeding its namespace quota.
+func (c *Config) exceedsQuota(key string, list []*Lease) bool {
+	q, ok := c.quota(key)
+	if !ok || q.MaxLeases <= 0 {
+		return false
+	}
+	ns, count := c.namespace(key), 0
+	for _, lease := range list {
+		// re-creating an existing lease does not change the number of leases.
+		if lease.Key == key {
+			return false
+		}
+		if c.namespace(lease.Key) == ns {
+			count++
+		}
+	}
+	return count >= q.MaxLeases
+}
+
+// filterQuota returns the candidates that the worker can take without exceeding
+// the per-worker quota of their namespaces. held is the list of leases that the
+// worker currently holds. the order of the candidates is preserved.
+func (c *Config) filterQuota(held, candidates []*Lease) []*Lease {
+	if len(c.Quotas) == 0 {
+		return candidates
+	}
+	counts := make(map[string]int)
+	for _, lease := range held {
+		counts[c.namespace(lease.Key)]++
+	}
+	var list []*Lease
+	for _, lease := range candidates {
+		ns := c.namespace(lease.Key)
+		if q, ok := c.Quotas[ns]; ok && q.MaxLeasesPerWorker > 0 && counts[ns] >= q.MaxLeasesPerWorker {
+			continue
+		}
+		counts[ns]++
+		list = append(list, lease)
+	}
+	return list
+}
@@ -0,0 +1,81 @@
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPrefixNamespace(t *testing.T) {
+	ns := PrefixNamespace("/")
+	for key, expected := range map[string]string{
+		"a/foo":   "a",
+		"a/b/foo": "a",
+		"foo":     "",
+		"/foo":    "",
+	} {
+		assert(t, ns(key) == expected, "expect namespace of "+key+" to equal "+expected)
+	}
+}
+
+func TestQuotaCreate(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {
+			[]*Lease{{Key: "a/foo"}, {Key: "a/bar"}, {Key: "b/foo"}},
+			[]*Lease{{Key: "a/foo"}, {Key: "a/bar"}, {Key: "b/foo"}},
+			[]*Lease{{Key: "a/foo"}, {Key: "b/foo"}},
+		},
+		methodLCreate: {nil, nil, nil},
+	})
+	coordinator := &Coordinator{
+		Config: &Config{
+			Logger:    logger,
+			Namespace: PrefixNamespace("/"),
+			Quotas:    map[string]Quota{"a": {MaxLeases: 2}},
+		},
+		Manager: manager,
+	}
+
+	_, err := coordinator.Create(context.Background(), NewLease("a/baz"))
+	assert(t, err == ErrQuotaExceeded, "expect to fail with ErrQuotaExceeded")
+
+	_, err = coordinator.Create(context.Background(), NewLease("a/foo"))
+	assert(t, err == nil, "expect not to fail when re-creating an existing lease")
+
+	_, err = coordinator.Create(context.Background(), NewLease("a/baz"))
+	assert(t, err == nil, "expect not to fail when the namespace is below its quota")
+
+	_, err = coordinator.Create(context.Background(), NewLease("b/bar"))
+	assert(t, err == nil, "expect not to fail when the namespace has no quota")
+	assert(t, manager.calls[methodList] == 3, "expect not to list leases for namespace without quota")
+	assert(t, manager.calls[methodLCreate] == 3, "expect number of creations to equal 3")
+}
+
+func TestQuotaTake(t *testing.T) {
+	logger := testLogger()
+	expired := time.Now().Add(-time.Hour)
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{
+			{Key: "a/foo", Owner: takerId, lastRenewal: time.Now()},
+			{Key: "a/bar", Owner: "1", lastRenewal: expired},
+			{Key: "a/baz", Owner: "1", lastRenewal: expired},
+			{Key: "b/foo", Owner: "1", lastRenewal: expired},
+		}},
+		methodTake: {nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{
+			WorkerId:                  takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+			Namespace:                 PrefixNamespace("/"),
+			Quotas:                    map[string]Quota{"a": {MaxLeasesPerWorker: 1}},
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTake] == 1, "expect to take only the lease that is not in namespace 'a'")
+}
@@ -0,0 +1,130 @@
+package lease
+
+import (
+	"context"
+	"time"
+)
+
+// Reconciler keeps the leases table a mirror of a desired set of lease keys (e.g:
+// shards, queues or tenants). It creates the missing leases, and deletes the orphan
+// leases, that are not in the desired set.
+//
+// For example:
+//
+//	r := lease.NewReconciler(config, func() ([]string, error) {
+//		return listShards()
+//	})
+//	go r.Run(ctx, time.Minute)
+type Reconciler struct {
+	*Config
+	Manager Manager
+	// Desired returns the desired set of lease keys.
+	Desired func() ([]string, error)
+}
+
+// NewReconciler creates a new Reconciler with the given config, and the source of the
+// desired lease keys.
+func NewReconciler(config *Config, desired func() ([]string, error)) *Reconciler {
+	config.defaults()
+	return &Reconciler{
+		Config:  config,
+		Manager: newManager(config),
+		Desired: desired,
+	}
+}
+
+// Run reconciles the leases table with the desired lease keys every interval, until ctx
+// is done. Failures are logged, and retried on the next interval.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if keys, err := r.Desired(); err != nil {
+			r.Logger.WithError(err).Errorf("reconciler: failed to get the desired lease keys")
+		} else if _, _, err := r.Reconcile(ctx, keys); err != nil {
+			r.Logger.WithError(err).Errorf("reconciler: failed to reconcile leases")
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Watch reconciles the leases table with each desired set of lease keys that received
+// from the given channel, until the channel is closed or ctx is done.
+func (r *Reconciler) Watch(ctx context.Context, ch <-chan []string) error {
+	for {
+		select {
+		case keys, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if _, _, err := r.Reconcile(ctx, keys); err != nil {
+				r.Logger.WithError(err).Errorf("reconciler: failed to reconcile leases")
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Reconcile creates the missing leases of the given desired keys, and deletes the
+// orphan leases. It returns the number of leases that were created and deleted.
+//
+// New leases are created without an owner, so the takers pick them up. Orphans are
+// deleted on the condition that their owner did not change since they were listed.
+// As a safeguard, orphans are not deleted if the desired set is empty.
+func (r *Reconciler) Reconcile(ctx context.Context, keys []string) (created, deleted int, err error) {
+	list, err := r.Manager.ListLeases(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	list = liveLeases(list)
+
+	existing := make(map[string]bool, len(list))
+	for _, lease := range list {
+		existing[lease.Key] = true
+	}
+	desired := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		desired[key] = true
+		if existing[key] {
+			continue
+		}
+		if r.exceedsQuota(key, list) {
+			r.Logger.Warnf("reconciler: lease %s exceeds its namespace quota", key)
+			continue
+		}
+		lease := &Lease{Key: key}
+		ok, err := r.Manager.EnsureLease(ctx, lease)
+		if err != nil {
+			r.Logger.WithError(err).Warnf("reconciler: failed to create lease %s", key)
+			continue
+		}
+		existing[key] = true
+		list = append(list, lease)
+		if ok {
+			created++
+		}
+	}
+
+	if len(desired) == 0 {
+		if len(list) > 0 {
+			r.Logger.Warnf("reconciler: the desired set is empty. skip deleting %d leases", len(list))
+		}
+		return created, 0, nil
+	}
+	for _, lease := range list {
+		if desired[lease.Key] {
+			continue
+		}
+		if err := r.Manager.DeleteLease(ctx, lease); err != nil {
+			r.Logger.WithError(err).Warnf("reconciler: failed to delete orphan lease %s", lease.Key)
+			continue
+		}
+		deleted++
+	}
+	return created, deleted, nil
+}
@@ -0,0 +1,28 @@
+package lease
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {
+			[]*Lease{{Key: "foo", Owner: "1"}, {Key: "bar", Owner: "2"}},
+			[]*Lease{{Key: "foo", Owner: "1"}},
+		},
+		methodEnsure: {true, true},
+		methodDelete: {nil},
+	})
+	r := &Reconciler{Config: &Config{Logger: logger}, Manager: manager}
+
+	created, deleted, err := r.Reconcile(context.Background(), []string{"foo", "baz", "qux"})
+	assert(t, err == nil, "expect Reconcile not to fail")
+	assert(t, created == 2 && manager.calls[methodEnsure] == 2, "expect to create the missing leases")
+	assert(t, deleted == 1 && manager.calls[methodDelete] == 1, "expect to delete the orphan leases")
+
+	_, deleted, err = r.Reconcile(context.Background(), nil)
+	assert(t, err == nil, "expect Reconcile not to fail")
+	assert(t, deleted == 0 && manager.calls[methodDelete] == 1, "expect not to delete leases if the desired set is empty")
+}
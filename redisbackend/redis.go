@@ -0,0 +1,163 @@
+// Package redisbackend runs the coordinator on Redis, instead of DynamoDB.
+//
+// The leases are stored as hashes with the JSON of the lease and a version counter, that is
+// incremented on every write. The conditional writes of lease.Backend are Lua scripts that
+// compare-and-swap the version of the stored lease, so the conditions on the leaseOwner and
+// the leaseCounter hold across workers. For example:
+//
+//	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	leaser := lease.New("leases", lease.WithBackend(redisbackend.New(client, "leases")))
+//
+// All the keys of a lease table share the hash tag of its prefix (e.g: "{leases}:lease:foo"),
+// so the scripts also run on Redis Cluster.
+package redisbackend
+
+import (
+	"context"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// putIfAbsent stores the lease in KEYS[1] with its first version, and adds its key to
+	// the index in KEYS[2], if the lease does not exist.
+	putIfAbsent = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'lease', ARGV[1], 'version', 1)
+redis.call('SADD', KEYS[2], ARGV[2])
+return 1`)
+
+	// compareAndUpdate replaces the lease in KEYS[1] with ARGV[2], and increments its
+	// version, if its version is ARGV[1].
+	compareAndUpdate = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'version') ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'lease', ARGV[2])
+redis.call('HINCRBY', KEYS[1], 'version', 1)
+return 1`)
+
+	// compareAndDelete deletes the lease in KEYS[1], and removes its key from the index in
+	// KEYS[2], if its version is ARGV[1]. does nothing if the lease does not exist.
+	compareAndDelete = redis.NewScript(`
+local v = redis.call('HGET', KEYS[1], 'version')
+if not v then
+	return 1
+end
+if v ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+redis.call('SREM', KEYS[2], ARGV[2])
+return 1`)
+)
+
+// Backend is a lease.Backend that stores the leases in Redis.
+type Backend struct {
+	Client redis.UniversalClient
+	// Prefix is the prefix of the Redis keys of the lease table, e.g: its name.
+	Prefix string
+}
+
+// New returns a lease.Backend that stores the leases with the given key prefix, using
+// the given Redis client.
+func New(client redis.UniversalClient, prefix string) lease.Backend {
+	return &Backend{Client: client, Prefix: prefix}
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (*lease.Lease, error) {
+	values, err := b.Client.HMGet(ctx, b.leaseKey(key), "lease", "version").Result()
+	if err != nil {
+		return nil, err
+	}
+	l, ok, err := decode(values)
+	if err == nil && !ok {
+		err = lease.ErrLeaseNotFound
+	}
+	return l, err
+}
+
+func (b *Backend) List(ctx context.Context) ([]*lease.Lease, error) {
+	keys, err := b.Client.SMembers(ctx, b.indexKey()).Result()
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	pipe := b.Client.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(keys))
+	for i, k := range keys {
+		cmds[i] = pipe.HMGet(ctx, b.leaseKey(k), "lease", "version")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	list := make([]*lease.Lease, 0, len(cmds))
+	for _, cmd := range cmds {
+		l, ok, err := decode(cmd.Val())
+		if err != nil {
+			return nil, err
+		}
+		// the lease was deleted after the index was read.
+		if ok {
+			list = append(list, l)
+		}
+	}
+	return list, nil
+}
+
+func (b *Backend) PutIfAbsent(ctx context.Context, l *lease.Lease) (bool, error) {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return false, err
+	}
+	return putIfAbsent.Run(ctx, b.Client, []string{b.leaseKey(l.Key), b.indexKey()}, raw, l.Key).Bool()
+}
+
+func (b *Backend) CompareAndUpdate(ctx context.Context, old, l *lease.Lease) error {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return err
+	}
+	ok, err := compareAndUpdate.Run(ctx, b.Client, []string{b.leaseKey(old.Key)}, old.Revision(), raw).Bool()
+	if err == nil && !ok {
+		err = lease.ErrConflict
+	}
+	return err
+}
+
+func (b *Backend) Delete(ctx context.Context, old *lease.Lease) error {
+	ok, err := compareAndDelete.Run(ctx, b.Client, []string{b.leaseKey(old.Key), b.indexKey()}, old.Revision(), old.Key).Bool()
+	if err == nil && !ok {
+		err = lease.ErrConflict
+	}
+	return err
+}
+
+// decode returns the lease of the given values of its hash (i.e: the JSON of the lease and
+// its version), with its revision, and reports whether it exists.
+func decode(values []interface{}) (*lease.Lease, bool, error) {
+	raw, ok := values[0].(string)
+	if !ok {
+		return nil, false, nil
+	}
+	l, err := record.Decode(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	version, _ := values[1].(string)
+	l.SetRevision(version)
+	return l, true, nil
+}
+
+// leaseKey returns the Redis key of the lease with the given key.
+func (b *Backend) leaseKey(key string) string {
+	return "{" + b.Prefix + "}:lease:" + key
+}
+
+// indexKey returns the Redis key of the set of the lease keys.
+func (b *Backend) indexKey() string {
+	return "{" + b.Prefix + "}:keys"
+}
@@ -0,0 +1,113 @@
+package redisbackend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/a8m/lease"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	b := New(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}), "leases")
+
+	l := lease.NewLease("foo")
+	l.Owner = "1"
+	l.Counter = 1
+	l.ReservedUntil = time.Unix(1700000000, 0)
+	l.Set("checkpoint", 35465786912)
+	l.Set("tags", map[string]interface{}{"region": "eu"})
+	ok, err := b.PutIfAbsent(ctx, &l)
+	if err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	if ok, _ := b.PutIfAbsent(ctx, &l); ok {
+		t.Error("expect existing leases not to be stored")
+	}
+
+	old, err := b.Get(ctx, "foo")
+	if err != nil || old.Owner != "1" || !old.ReservedUntil.Equal(l.ReservedUntil) {
+		t.Fatalf("expect to get the stored lease, got: %+v, %v", old, err)
+	}
+	if v, _ := old.Get("checkpoint"); v.(interface{ String() string }).String() != "35465786912" {
+		t.Errorf("expect the extra fields to be kept, got: %v", v)
+	}
+	update := *old
+	update.Counter++
+	if err := b.CompareAndUpdate(ctx, old, &update); err != nil {
+		t.Fatalf("expect the decoded lease to match the stored one: %v", err)
+	}
+	if err := b.CompareAndUpdate(ctx, old, &update); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the update of a changed lease to conflict, got: %v", err)
+	}
+	if err := b.Delete(ctx, old); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the delete of a changed lease to conflict, got: %v", err)
+	}
+
+	list, err := b.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Counter != 2 {
+		t.Fatalf("expect to list the updated lease, got: %v, %v", list, err)
+	}
+	if err := b.Delete(ctx, list[0]); err != nil {
+		t.Fatalf("expect the lease to be deleted: %v", err)
+	}
+	if _, err := b.Get(ctx, "foo"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect the lease not to be found, got: %v", err)
+	}
+	if list, _ := b.List(ctx); len(list) != 0 {
+		t.Error("expect the deleted lease to be removed from the index")
+	}
+}
+
+func TestBackendRevision(t *testing.T) {
+	ctx := context.Background()
+	b := New(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}), "leases")
+	if ok, err := b.PutIfAbsent(ctx, &lease.Lease{Key: "foo", Owner: "NULL", Counter: 1}); err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	old, _ := b.Get(ctx, "foo")
+	// the lease is written back with the same content, e.g: by a concurrent update.
+	if err := b.CompareAndUpdate(ctx, old, old); err != nil {
+		t.Fatalf("expect the lease to be updated: %v", err)
+	}
+	if err := b.CompareAndUpdate(ctx, old, old); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the version to be compared instead of the content, got: %v", err)
+	}
+	stored, _ := b.Get(ctx, "foo")
+	if stored.Revision() == old.Revision() {
+		t.Error("expect the version to change on every write")
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	b := New(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}), "leases")
+	c := lease.NewFromConfig(&lease.Config{WorkerId: "1", LeaseTable: "leases", Backend: b}).(*lease.Coordinator)
+
+	l := lease.NewLease("foo")
+	if _, err := c.Manager.EnsureLease(ctx, &l); err != nil {
+		t.Fatalf("expect the lease to be created: %v", err)
+	}
+	held, err := c.Manager.GetLease(ctx, "foo")
+	if err != nil {
+		t.Fatalf("expect to get the lease: %v", err)
+	}
+	if err := c.Manager.TakeLease(ctx, held); err != nil || held.Owner != "1" {
+		t.Fatalf("expect the lease to be taken: %v", err)
+	}
+	held.Set("status", "running")
+	if _, err := c.Manager.UpdateLease(ctx, held); err != nil {
+		t.Fatalf("expect the lease to be updated: %v", err)
+	}
+	if err := c.Manager.RenewLease(ctx, held); err != nil || held.Counter != 3 {
+		t.Fatalf("expect the lease to be renewed: %v", err)
+	}
+	stale := lease.Lease{Key: "foo", Owner: "2", Counter: 1}
+	if err := c.Manager.RenewLease(ctx, &stale); !errors.Is(err, lease.ErrLeaseStolen) {
+		t.Errorf("expect the renewal of another owner to fail, got: %v", err)
+	}
+}
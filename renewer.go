@@ -1,16 +1,25 @@
 package lease
 
 import (
+	"context"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Renewer used by the LeaseCoordinator to renew leases held by the system.
 // Each LeaseCoordinator instance corresponds to one worker and uses exactly one LeaseRenewer
 // to manage lease renewal for that worker.
 type Renewer interface {
-	Renew() error
+	Renew(context.Context) error
+	Release(context.Context, Lease) error
+	Complete(context.Context, Lease) error
 	GetHeldLeases() []Lease
+	GetSharedLeases() []Lease
+	ReportLoad(key string, load float64) error
+	ExpiresIn(key string) (time.Duration, error)
+	Degraded() bool
+	Acquired() <-chan struct{}
 }
 
 // leaseHolder is the default implementation of Renewer that uses DynamoDB
@@ -18,20 +27,58 @@ type Renewer interface {
 type leaseHolder struct {
 	sync.RWMutex
 	*Config
-	manager    Manager
-	heldLeases map[string]*Lease
+	manager        Manager
+	heldLeases     map[string]*Lease
+	sharedLeases   map[string]*Lease
+	drainingLeases map[string]*Lease
+	// loads reported by the application for the held leases.
+	loads map[string]float64
+	// heldSince tracks when the held leases were acquired, to rotate them.
+	heldSince map[string]time.Time
+	// renewedAt tracks the last successful renewal of the held leases. see: ExpiresIn.
+	renewedAt map[string]time.Time
+	rotations *rotations
+	// cache is invalidated for the leases that change owner. see: Coordinator.Get.
+	cache *leaseCache
+	// scanned is the last time the leases were listed successfully, and degraded indicates
+	// that the held leases are renewed from the last known view. see: renewStale.
+	scanned  time.Time
+	degraded bool
+	// journal records the outcomes of the renewals. see: Coordinator.Stats.
+	journal *journal
+	// throttle records the throttled renewals. see: Coordinator.Throttled.
+	throttle *throttle
+	// acquired is closed when new leases are held. see: Acquired.
+	acquired chan struct{}
+}
+
+// ownerChange invalidates the cached lease of the given change, and reports the change.
+// see: Config.ownerChange.
+func (l *leaseHolder) ownerChange(change OwnerChange, lease *Lease) {
+	l.cache.invalidate(change.Key)
+	l.Config.ownerChange(change, lease)
 }
 
 // Attempt to renew all currently held leases.
-func (l *leaseHolder) Renew() error {
-	leases, err := l.manager.ListLeases()
+func (l *leaseHolder) Renew(ctx context.Context) error {
+	leases, err := l.manager.ListLeases(ctx)
 	if err != nil {
-		return err
+		return l.renewStale(ctx, err)
 	}
+	l.scanned = l.now()
+	l.setDegraded(false)
+	// tombstoned leases are considered as deleted.
+	leases = liveLeases(leases)
 
 	// remove leases that deleted from the DynamoDB table.
-	var lostLeases []string
-	for key := range l.heldLeases {
+	var (
+		lostLeases []string
+		// the groups of the lost leases. see: releaseGroups.
+		lostGroups = make(map[string]bool)
+		// acquired indicates that new leases are held since the last run. see: Acquired.
+		acquired bool
+	)
+	for key, held := range l.heldLeases {
 		exist := false
 		for _, lease := range leases {
 			if lease.Key == key {
@@ -41,6 +88,26 @@ func (l *leaseHolder) Renew() error {
 		if !exist {
 			l.Lock()
 			delete(l.heldLeases, key)
+			delete(l.heldSince, key)
+			delete(l.renewedAt, key)
+			l.Unlock()
+			lostLeases = append(lostLeases, key)
+			l.ownerChange(OwnerChange{Key: key, Type: OwnerLost, From: l.WorkerId}, held)
+			if held.Group != "" {
+				lostGroups[held.Group] = true
+			}
+		}
+	}
+	for key := range l.sharedLeases {
+		exist := false
+		for _, lease := range leases {
+			if lease.Key == key {
+				exist = true
+			}
+		}
+		if !exist {
+			l.Lock()
+			delete(l.sharedLeases, key)
 			l.Unlock()
 			lostLeases = append(lostLeases, key)
 		}
@@ -55,13 +122,73 @@ func (l *leaseHolder) Renew() error {
 	// remove all the leases that stoled from this worker, or renew the leases
 	// that we still hold.
 	for _, lease := range leases {
-		if lease.Owner == l.WorkerId {
+		// renew the leases that we hold in shared mode.
+		if _, ok := lease.Holders[l.WorkerId]; ok {
+			l.Lock()
+			l.sharedLeases[lease.Key] = lease
+			l.Unlock()
+			if err := l.manager.RenewSharedLease(ctx, lease); err != nil {
+				l.Logger.Debugf("Worker %s could not renew shared lease with key %s", l.WorkerId, lease.Key)
+			}
+		} else if _, ok := l.sharedLeases[lease.Key]; ok {
+			l.Logger.Debugf("Worker %s lost shared lease with key %s", l.WorkerId, lease.Key)
+			l.Lock()
+			delete(l.sharedLeases, lease.Key)
+			l.Unlock()
+		}
+
+		if lease.Owner == l.WorkerId && lease.PreemptedBy != "" {
+			if _, ok := l.drainingLeases[lease.Key]; ok {
+				// the lease was drained since the last run. hand it over.
+				l.Lock()
+				delete(l.drainingLeases, lease.Key)
+				l.Unlock()
+				if err := l.manager.EvictLease(ctx, lease); err != nil {
+					l.Logger.Debugf("Worker %s could not release preempted lease with key %s", l.WorkerId, lease.Key)
+				} else {
+					l.Logger.Debugf("Worker %s released lease with key %s to worker %s", l.WorkerId, lease.Key, lease.PreemptedBy)
+					l.ownerChange(OwnerChange{Key: lease.Key, Type: OwnerReleased, From: l.WorkerId}, lease)
+				}
+			} else {
+				// stop reporting the lease as held, but keep renewing it until the next run.
+				l.Logger.Debugf("Worker %s drain lease with key %s preempted by worker %s", l.WorkerId, lease.Key, lease.PreemptedBy)
+				l.Lock()
+				delete(l.heldLeases, lease.Key)
+				l.drainingLeases[lease.Key] = lease
+				l.Unlock()
+				renewed := l.now()
+				err := l.manager.RenewLease(ctx, lease)
+				l.journal.record(lease.Key, "renew", renewed, err, false)
+				l.throttle.record(err)
+				if err != nil {
+					l.Logger.Debugf("Worker %s could not renew lease with key %s", l.WorkerId, lease.Key)
+				}
+			}
+		} else if lease.Owner == l.WorkerId {
+			// hand over the lease, if it's denied to this worker, or its time slice is over.
+			if l.releaseDenied(ctx, lease) || l.rotate(ctx, lease) {
+				continue
+			}
 			// if we took this lease and it's not holds by this renewer
 			l.Lock()
+			if _, ok := l.heldLeases[lease.Key]; !ok {
+				acquired = true
+			}
 			l.heldLeases[lease.Key] = lease
+			if load, ok := l.loads[lease.Key]; ok {
+				lease.reportedLoad = &load
+			}
 			l.Unlock()
-			if err := l.manager.RenewLease(lease); err != nil {
+			// other workers see the renewal after it's written, so the time before
+			// the write bounds the expiry deadline.
+			renewed := l.now()
+			err := l.manager.RenewLease(ctx, lease)
+			l.journal.record(lease.Key, "renew", renewed, err, l.isLate(lease.Key, renewed))
+			l.throttle.record(err)
+			if err != nil {
 				l.Logger.Debugf("Worker %s could not renew lease with key %s", l.WorkerId, lease.Key)
+			} else {
+				l.markRenewed(lease.Key, renewed)
 			}
 		} else {
 			if _, ok := l.heldLeases[lease.Key]; ok {
@@ -69,10 +196,30 @@ func (l *leaseHolder) Renew() error {
 				l.Lock()
 				delete(l.heldLeases, lease.Key)
 				l.Unlock()
+				change := OwnerChange{Key: lease.Key, Type: OwnerLost, From: l.WorkerId}
+				if !lease.hasNoOwner() {
+					change.To = lease.Owner
+				}
+				l.ownerChange(change, lease)
+				if lease.Group != "" {
+					lostGroups[lease.Group] = true
+				}
 			}
+			l.Lock()
+			delete(l.drainingLeases, lease.Key)
+			delete(l.loads, lease.Key)
+			delete(l.heldSince, lease.Key)
+			delete(l.renewedAt, lease.Key)
+			l.Unlock()
 		}
 	}
 
+	// release the rest of the groups that we lost partially.
+	l.releaseGroups(ctx, lostGroups)
+	if acquired {
+		l.notifyAcquired()
+	}
+
 	// print the currently held leases belongs to this worker.
 	if keys := l.keys(); len(keys) > 0 {
 		l.Logger.Debugf("Worker %s hold leases: %s", l.WorkerId, strings.Join(keys, ", "))
@@ -80,6 +227,68 @@ func (l *leaseHolder) Renew() error {
 	return nil
 }
 
+// Release the given held lease by setting its owner to null, and stop holding it.
+func (l *leaseHolder) Release(ctx context.Context, lease Lease) error {
+	l.RLock()
+	if hlease, ok := l.heldLeases[lease.Key]; ok {
+		lease = *hlease
+	}
+	l.RUnlock()
+	if err := l.manager.EvictLease(ctx, &lease); err != nil {
+		return err
+	}
+	l.Lock()
+	delete(l.heldLeases, lease.Key)
+	delete(l.heldSince, lease.Key)
+	delete(l.renewedAt, lease.Key)
+	l.Unlock()
+	l.ownerChange(OwnerChange{Key: lease.Key, Type: OwnerReleased, From: l.WorkerId}, &lease)
+	return nil
+}
+
+// ReportLoad records the load of the given held lease, to be written on its next renewal.
+func (l *leaseHolder) ReportLoad(key string, load float64) error {
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := l.heldLeases[key]; !ok {
+		return ErrLeaseNotHeld
+	}
+	if l.loads == nil {
+		l.loads = make(map[string]float64)
+	}
+	l.loads[key] = load
+	return nil
+}
+
+// ExpiresIn returns the time remaining before other workers consider the given held lease
+// as expired, measured from its last successful renewal. it's zero if the lease may be
+// already expired.
+func (l *leaseHolder) ExpiresIn(key string) (time.Duration, error) {
+	l.RLock()
+	defer l.RUnlock()
+	if _, ok := l.heldLeases[key]; !ok {
+		return 0, ErrLeaseNotHeld
+	}
+	renewed, ok := l.renewedAt[key]
+	if !ok {
+		return 0, nil
+	}
+	if d := renewed.Add(l.expireAfter(key)).Sub(l.now()); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
+// markRenewed records the time of the last successful renewal of the given lease.
+func (l *leaseHolder) markRenewed(key string, renewed time.Time) {
+	l.Lock()
+	defer l.Unlock()
+	if l.renewedAt == nil {
+		l.renewedAt = make(map[string]time.Time)
+	}
+	l.renewedAt[key] = renewed
+}
+
 // Returns currently held leases.
 // A lease is currently held if we successfully renewed it on the last
 // run of Renew()
@@ -93,8 +302,21 @@ func (l *leaseHolder) GetHeldLeases() (leases []Lease) {
 	return
 }
 
+// Returns the leases that are currently held in shared mode.
+// Lease objects returned are copies and their lease counters will not tick.
+func (l *leaseHolder) GetSharedLeases() (leases []Lease) {
+	l.RLock()
+	defer l.RUnlock()
+	for _, lease := range l.sharedLeases {
+		leases = append(leases, *lease)
+	}
+	return
+}
+
 // keys return all worker's leases
 func (l *leaseHolder) keys() (keys []string) {
+	l.RLock()
+	defer l.RUnlock()
 	for k := range l.heldLeases {
 		keys = append(keys, k)
 	}
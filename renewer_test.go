@@ -1,9 +1,12 @@
 package lease
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 )
 
 type renewerTest struct {
@@ -19,6 +22,8 @@ var (
 	lease1    = &Lease{Key: "foo", Owner: "2"}
 	lease2    = &Lease{Key: "bar", Owner: renewerId}
 	lease3    = &Lease{Key: "baz", Owner: renewerId}
+	lease5    = &Lease{Key: "quux", Owner: renewerId, PreemptedBy: "2"}
+	lease4    = &Lease{Key: "qux", Owner: "NULL", Holders: map[string]int64{renewerId: 1, "2": 1}}
 )
 
 var renewerTestCases = []renewerTest{
@@ -118,19 +123,98 @@ var renewerTestCases = []renewerTest{
 		},
 		[]Lease{},
 	},
+	{
+		"we holds 1 lease, and 1 lease in shared mode. expect to renew 1 and renew 1 shared",
+		map[string]*Lease{
+			lease2.Key: lease2,
+		},
+		map[method]args{
+			methodList:        {[]*Lease{lease1, lease2, lease4}},
+			methodRenew:       {nil},
+			methodRenewShared: {nil},
+		},
+		map[method]int{
+			methodList:        1,
+			methodRenew:       1,
+			methodRenewShared: 1,
+		},
+		[]Lease{*lease2},
+	},
+	{
+		"we holds 2 leases, and 1 of them preempted. expect to renew 2 and stop holding the preempted one",
+		map[string]*Lease{
+			lease2.Key: lease2,
+			lease5.Key: lease5,
+		},
+		map[method]args{
+			methodList:  {[]*Lease{lease2, lease5}},
+			methodRenew: {nil, nil},
+		},
+		map[method]int{
+			methodList:  1,
+			methodRenew: 2,
+			methodEvict: 0,
+		},
+		[]Lease{*lease2},
+	},
+	{
+		"we holds 2 leases of a group, and 1 of them stolen. expect to renew 1 and release the rest of the group",
+		map[string]*Lease{
+			"foo": {Key: "foo", Owner: renewerId, Group: "g"},
+			"bar": {Key: "bar", Owner: renewerId, Group: "g"},
+		},
+		map[method]args{
+			methodList: {[]*Lease{
+				{Key: "foo", Owner: "2", Group: "g"},
+				{Key: "bar", Owner: renewerId, Group: "g"},
+			}},
+			methodRenew: {nil},
+			methodEvict: {nil},
+		},
+		map[method]int{
+			methodList:  1,
+			methodRenew: 1,
+			methodEvict: 1,
+		},
+		[]Lease{},
+	},
+}
+
+func TestRenewerDrain(t *testing.T) {
+	logger := testLogger()
+	preempted := &Lease{Key: "quux", Owner: renewerId, PreemptedBy: "2"}
+	manager := newManagerMock(map[method]args{
+		methodList:  {[]*Lease{preempted}, []*Lease{preempted}},
+		methodRenew: {nil},
+		methodEvict: {nil},
+	})
+	holder := &leaseHolder{
+		Config:         &Config{WorkerId: renewerId, Logger: logger},
+		manager:        manager,
+		heldLeases:     map[string]*Lease{preempted.Key: preempted},
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+	}
+	holder.Renew(context.Background())
+	assert(t, len(holder.GetHeldLeases()) == 0, "expect preempted lease not to be held")
+	assert(t, manager.calls[methodRenew] == 1 && manager.calls[methodEvict] == 0, "expect to renew the draining lease")
+	holder.Renew(context.Background())
+	assert(t, manager.calls[methodRenew] == 1 && manager.calls[methodEvict] == 1, "expect to release the drained lease")
+	assert(t, len(holder.drainingLeases) == 0, "expect no draining leases")
 }
 
 func TestRenewerCases(t *testing.T) {
 	for _, test := range renewerTestCases {
-		logger := logrus.New()
-		logger.Level = logrus.PanicLevel
+		logger := testLogger()
 		manager := newManagerMock(test.managerBehavior)
 		holder := &leaseHolder{
-			Config:     &Config{WorkerId: renewerId, Logger: logger},
-			manager:    manager,
-			heldLeases: test.prevState,
+			Config:         &Config{WorkerId: renewerId, Logger: logger},
+			manager:        manager,
+			heldLeases:     test.prevState,
+			sharedLeases:   make(map[string]*Lease),
+			drainingLeases: make(map[string]*Lease),
 		}
-		holder.Renew()
+		holder.Renew(context.Background())
 		// test method calls expectations
 		for method, calls := range test.expectedCalls {
 			if n := manager.calls[method]; n != calls {
@@ -155,5 +239,162 @@ func TestRenewerCases(t *testing.T) {
 				t.Errorf("%s: expected lease to be exists in result:\n\t%+v\n", test.name, l1)
 			}
 		}
+		// test GetSharedLeases
+		for _, l := range holder.GetSharedLeases() {
+			if _, ok := l.Holders[renewerId]; !ok {
+				t.Errorf("%s: expected shared lease to be held by the worker:\n\t%+v\n", test.name, l)
+			}
+		}
+	}
+}
+
+func TestRenewerRelease(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodEvict: {errors.New("evict failed"), nil},
+	})
+	holder := &leaseHolder{
+		Config:     &Config{WorkerId: renewerId, Logger: logger},
+		manager:    manager,
+		heldLeases: map[string]*Lease{"foo": {Key: "foo", Owner: renewerId}},
+	}
+	err := holder.Release(context.Background(), Lease{Key: "foo"})
+	assert(t, err != nil, "expect to returns the error")
+	assert(t, len(holder.GetHeldLeases()) == 1, "expect to keep holding the lease")
+	err = holder.Release(context.Background(), Lease{Key: "foo"})
+	assert(t, err == nil, "expect not to fail")
+	assert(t, len(holder.GetHeldLeases()) == 0, "expect to stop holding the lease")
+}
+
+func TestRenewerDenied(t *testing.T) {
+	logger := testLogger()
+	leases := []*Lease{{Key: "orders/1", Owner: renewerId}, {Key: "users/1", Owner: renewerId}}
+	manager := newManagerMock(map[method]args{
+		methodList:  {leases},
+		methodRenew: {nil},
+		methodEvict: {nil},
+	})
+	holder := &leaseHolder{
+		Config:         &Config{WorkerId: renewerId, Logger: logger, ExpireAfter: 10 * time.Second, DenyKeys: []string{"orders/"}},
+		manager:        manager,
+		heldLeases:     map[string]*Lease{"orders/1": leases[0], "users/1": leases[1]},
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+	}
+	holder.Renew(context.Background())
+	held := holder.GetHeldLeases()
+	assert(t, len(held) == 1 && held[0].Key == "users/1", "expect to release the denied lease")
+	assert(t, manager.calls[methodEvict] == 1 && manager.calls[methodRenew] == 1, "expect to renew only the allowed lease")
+
+	taker := &leaseTaker{Config: holder.Config}
+	list := taker.filterDenied(leases)
+	assert(t, len(list) == 1 && list[0].Key == "users/1", "expect the taker not to take the denied lease")
+}
+
+func TestRenewerRotate(t *testing.T) {
+	logger := testLogger()
+	lease := &Lease{Key: "foo", Owner: renewerId}
+	manager := newManagerMock(map[method]args{
+		methodList:  {[]*Lease{lease}, []*Lease{lease}},
+		methodRenew: {nil},
+		methodEvict: {nil},
+	})
+	rotations := &rotations{now: time.Now}
+	var changes []OwnerChange
+	holder := &leaseHolder{
+		Config: &Config{WorkerId: renewerId, Logger: logger, MaxHoldDuration: time.Minute, ExpireAfter: 10 * time.Second, OnOwnerChange: func(c OwnerChange) {
+			changes = append(changes, c)
+		}},
+		manager:        manager,
+		heldLeases:     make(map[string]*Lease),
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+		rotations:      rotations,
 	}
+	holder.Renew(context.Background())
+	assert(t, len(holder.GetHeldLeases()) == 1 && manager.calls[methodRenew] == 1, "expect to renew the lease within its time slice")
+
+	holder.heldSince[lease.Key] = time.Now().Add(-2 * time.Minute)
+	holder.Renew(context.Background())
+	assert(t, len(holder.GetHeldLeases()) == 0 && manager.calls[methodEvict] == 1, "expect to release the lease when its time slice is over")
+	assert(t, len(changes) == 1 && changes[0].Type == OwnerReleased, "expect the rotation to be reported")
+
+	taker := &leaseTaker{Config: holder.Config, rotations: rotations, allLeases: map[string]*Lease{lease.Key: lease}}
+	assert(t, len(taker.getExpiredLeases()) == 0, "expect not to take back the rotated lease")
+}
+
+func TestRenewerExpiresIn(t *testing.T) {
+	logger := testLogger()
+	lease := &Lease{Key: "foo", Owner: renewerId}
+	manager := newManagerMock(map[method]args{
+		methodList:  {[]*Lease{lease}, []*Lease{lease}},
+		methodRenew: {nil, errors.New("renew failed")},
+	})
+	holder := &leaseHolder{
+		Config:         &Config{WorkerId: renewerId, Logger: logger, ExpireAfter: 10 * time.Second},
+		manager:        manager,
+		heldLeases:     make(map[string]*Lease),
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+	}
+	_, err := holder.ExpiresIn("foo")
+	assert(t, err == ErrLeaseNotHeld, "expect to fail if the lease is not held")
+
+	holder.Renew(context.Background())
+	d, err := holder.ExpiresIn("foo")
+	assert(t, err == nil && d > 9*time.Second && d <= 10*time.Second, "expect the deadline to be measured from the renewal")
+
+	// the deadline is not extended by failed renewals.
+	holder.renewedAt["foo"] = time.Now().Add(-8 * time.Second)
+	holder.Renew(context.Background())
+	d, _ = holder.ExpiresIn("foo")
+	assert(t, d > 0 && d <= 2*time.Second, "expect the deadline to approach")
+
+	holder.renewedAt["foo"] = time.Now().Add(-time.Minute)
+	d, _ = holder.ExpiresIn("foo")
+	assert(t, d == 0, "expect zero if the lease may be expired")
+}
+
+func TestRenewerDegraded(t *testing.T) {
+	logger := testLogger()
+	foo, bar := &Lease{Key: "foo", Owner: renewerId}, &Lease{Key: "bar", Owner: renewerId}
+	var signals []bool
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{foo, bar}, nil, nil, []*Lease{foo}},
+		methodRenew: {
+			nil,
+			nil,
+			nil,
+			awserr.New(ConditionalFailed, "", errors.New("")),
+			nil,
+		},
+	})
+	holder := &leaseHolder{
+		Config: &Config{
+			WorkerId:     renewerId,
+			Logger:       logger,
+			ExpireAfter:  10 * time.Second,
+			MaxStaleness: 10 * time.Second,
+			OnDegraded:   func(degraded bool) { signals = append(signals, degraded) },
+		},
+		manager:        manager,
+		heldLeases:     make(map[string]*Lease),
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+	}
+	assert(t, holder.Renew(context.Background()) == nil, "expect renewal to succeed")
+	assert(t, !holder.Degraded(), "expect not to be degraded")
+
+	// renew the held leases from the last view, and drop the lost ones.
+	assert(t, holder.Renew(context.Background()) == nil, "expect to renew from the last view")
+	assert(t, holder.Degraded(), "expect to be degraded")
+	assert(t, len(holder.GetHeldLeases()) == 1, "expect to drop the lost lease")
+
+	// fail above the staleness window.
+	holder.scanned = time.Now().Add(-time.Minute)
+	assert(t, holder.Renew(context.Background()) != nil, "expect to fail if the view is too old")
+
+	assert(t, holder.Renew(context.Background()) == nil, "expect renewal to succeed")
+	assert(t, !holder.Degraded(), "expect to recover")
+	assert(t, len(signals) == 2 && signals[0] && !signals[1], "expect to signal the mode changes")
 }
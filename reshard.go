@@ -0,0 +1,157 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrInvalidReshard error will be returns if the replacements of a resharded lease have
+// duplicate keys, or the key of the replaced lease.
+var ErrInvalidReshard = errors.New("leaser: reshard replacement keys must be distinct")
+
+// ReshardLease deletes the given lease and creates its replacements in a single
+// transaction, so there is no window where neither of them exists. The deletion is
+// conditional on the owner and the leaseCounter in DynamoDB matching the ones of the
+// input, and the replacements must not exist (or be tombstoned).
+// Replacements without an owner inherit the owner of the deleted lease. The replacement
+// keys must be distinct, and different from the key of the deleted lease, or it fails with
+// ErrInvalidReshard.
+// Mutates the passed-in replacements, like CreateLease, after writing the records in DynamoDB.
+func (l *LeaseManager) ReshardLease(ctx context.Context, lease *Lease, leases []*Lease) error {
+	client, ok := l.Client.(transactClient)
+	if !ok {
+		return l.wrapError("reshard", lease.Key, ErrTransactionsUnsupported)
+	}
+	if len(leases)+1 > maxTransactItems {
+		return l.wrapError("reshard", lease.Key, ErrGroupTooLarge)
+	}
+	if !distinctKeys(lease, leases) {
+		return l.wrapError("reshard", lease.Key, ErrInvalidReshard)
+	}
+	created := make([]Lease, len(leases))
+	items := []*dynamodb.TransactWriteItem{
+		{
+			Delete: &dynamodb.Delete{
+				TableName: aws.String(l.LeaseTable),
+				Key: map[string]*dynamodb.AttributeValue{
+					LeaseKeyKey: {
+						S: aws.String(lease.Key),
+					},
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":condOwner": {
+						S: aws.String(lease.Owner),
+					},
+					":condCounter": {
+						N: aws.String(strconv.FormatInt(lease.Counter, 10)),
+					},
+				},
+				ExpressionAttributeNames: map[string]*string{
+					"#owner":   aws.String(LeaseOwnerKey),
+					"#counter": aws.String(LeaseCounterKey),
+				},
+				ConditionExpression: aws.String("#owner = :condOwner AND #counter = :condCounter"),
+			},
+		},
+	}
+	for i, nlease := range leases {
+		created[i] = *nlease
+		// preserve the ownership of the deleted lease.
+		if created[i].Owner == "" {
+			created[i].Owner = lease.Owner
+			created[i].OwnerTier = lease.OwnerTier
+			created[i].OwnerVersion = lease.OwnerVersion
+			created[i].OwnerHost = lease.OwnerHost
+			created[i].OwnerZone = lease.OwnerZone
+		}
+		if created[i].Owner == "" {
+			created[i].Owner = "NULL"
+		}
+		// the KCL workers process the child shards after their parent.
+		if l.KCLSchema && len(created[i].ParentShardIds) == 0 {
+			created[i].ParentShardIds = []string{lease.Key}
+		}
+		if created[i].Counter == 0 {
+			created[i].Counter++
+		}
+		item, err := l.Serializer.Encode(&created[i])
+		if err != nil {
+			return l.wrapError("reshard", nlease.Key, err)
+		}
+		items = append(items, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName: aws.String(l.LeaseTable),
+				Item:      item,
+				ExpressionAttributeNames: map[string]*string{
+					"#key":       aws.String(LeaseKeyKey),
+					"#tombstone": aws.String(LeaseTombstonedAtKey),
+				},
+				ConditionExpression: aws.String("attribute_not_exists(#key) OR attribute_exists(#tombstone)"),
+			},
+		})
+	}
+	if err := l.transactWrite(ctx, client, lease.Key, items); err != nil {
+		return l.wrapError("reshard", lease.Key, err)
+	}
+	for i, nlease := range leases {
+		*nlease = created[i]
+	}
+	return nil
+}
+
+// Reshard replaces the given held lease with the given leases in a single transaction,
+// e.g: when a partition is split or merged. The replacements without an owner are owned
+// by the owner of the replaced lease, so the work continues without a takeover. The
+// replaced lease is dropped from the held leases on the next run of the Renewer.
+//
+// Like Update, it fails with ErrLeaseNotHeld if the lease is not held by this worker, or
+// with ErrTokenNotMatch if the lease was lost and re-acquired since it was returned. It
+// also fails if the lease was changed in the table since it was last renewed, if one of
+// the replacements already exists, or with ErrInvalidReshard if the replacement keys are
+// not distinct. nothing is changed in that case.
+func (c *Coordinator) Reshard(ctx context.Context, lease Lease, leases []Lease) ([]Lease, error) {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	list := make([]*Lease, len(leases))
+	for i := range leases {
+		list[i] = &leases[i]
+		defer c.cache.invalidate(leases[i].Key)
+	}
+	defer c.cache.invalidate(lease.Key)
+	var heldLease Lease
+	for _, hlease := range c.Renewer.GetHeldLeases() {
+		if lease.Key == hlease.Key {
+			heldLease = hlease
+			break
+		}
+	}
+	if heldLease.hasNoOwner() {
+		return nil, ErrLeaseNotHeld
+	}
+	if heldLease.concurrencyToken != lease.concurrencyToken {
+		return nil, ErrTokenNotMatch
+	}
+	// the deletion is conditional on the owner and the counter of the held lease, rather
+	// than the ones of the given copy.
+	if err := c.Manager.ReshardLease(ctx, &heldLease, list); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// distinctKeys test if the keys of the given replacements are distinct, and different
+// from the key of the replaced lease.
+func distinctKeys(lease *Lease, leases []*Lease) bool {
+	keys := map[string]bool{lease.Key: true}
+	for _, nlease := range leases {
+		if keys[nlease.Key] {
+			return false
+		}
+		keys[nlease.Key] = true
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestReshardLease(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodTransactWriteItems: {
+			// the lease was changed, or one of the replacements exists
+			awserr.New(TransactionCanceled, "", errors.New("")),
+			new(dynamodb.TransactWriteItemsOutput),
+		},
+	})
+	manager := newTestManager(client)
+
+	lease := &Lease{Key: "shard", Counter: 3, Owner: "o1", OwnerHost: "h1"}
+	leases := []*Lease{{Key: "shard-a"}, {Key: "shard-b", Owner: "NULL"}}
+	err := manager.ReshardLease(context.Background(), lease, leases)
+	assert(t, err != nil, "expect to returns the error")
+	assert(t, client.calls[methodTransactWriteItems] == 1, "expect not to retry a canceled transaction")
+	assert(t, leases[0].Owner == "" && leases[0].Counter == 0, "expect the replacements to be the same")
+
+	err = manager.ReshardLease(context.Background(), lease, leases)
+	assert(t, err == nil, "expect not to fail")
+	assert(t, leases[0].Owner == "o1" && leases[0].OwnerHost == "h1" && leases[0].Counter == 1, "expect to inherit the ownership")
+	assert(t, leases[1].Owner == "NULL", "expect to keep the explicit owner")
+
+	err = manager.ReshardLease(context.Background(), lease, []*Lease{{Key: "shard-a"}, {Key: "shard-a"}})
+	assert(t, errors.Is(err, ErrInvalidReshard), "expect to fail with duplicate replacement keys")
+	err = manager.ReshardLease(context.Background(), lease, []*Lease{{Key: "shard"}})
+	assert(t, errors.Is(err, ErrInvalidReshard), "expect to fail with the key of the replaced lease")
+	assert(t, client.calls[methodTransactWriteItems] == 2, "expect not to write invalid replacements")
+
+	manager.Client = &regionMock{}
+	err = manager.ReshardLease(context.Background(), lease, leases)
+	assert(t, errors.Is(err, ErrTransactionsUnsupported), "expect to fail without transactions support")
+}
+
+func TestCoordinatorReshard(t *testing.T) {
+	manager := newManagerMock(map[method]args{
+		methodReshard: {nil},
+	})
+	held := &Lease{Key: "shard", Owner: renewerId, Counter: 5, concurrencyToken: "token"}
+	config := &Config{WorkerId: renewerId, Logger: testLogger(), ExpireAfter: time.Minute}
+	holder := &leaseHolder{
+		Config:     config,
+		manager:    manager,
+		heldLeases: map[string]*Lease{"shard": held},
+	}
+	c := &Coordinator{Config: config, Manager: manager, Renewer: holder}
+	ctx := context.Background()
+
+	_, err := c.Reshard(ctx, Lease{Key: "other"}, []Lease{{Key: "other-a"}})
+	assert(t, err == ErrLeaseNotHeld, "expect to fail if the lease is not held")
+	_, err = c.Reshard(ctx, Lease{Key: "shard", Owner: renewerId, Counter: 1, concurrencyToken: "stale"}, []Lease{{Key: "shard-a"}})
+	assert(t, err == ErrTokenNotMatch, "expect to fail if the token does not match")
+	assert(t, manager.calls[methodReshard] == 0, "expect nothing to be resharded")
+
+	leases, err := c.Reshard(ctx, Lease{Key: "shard", Owner: renewerId, Counter: 1, concurrencyToken: "token"}, []Lease{{Key: "shard-a"}})
+	assert(t, err == nil && len(leases) == 1, "expect the held lease to be resharded")
+	assert(t, manager.calls[methodReshard] == 1, "expect the held lease to be resharded")
+}
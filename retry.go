@@ -0,0 +1,115 @@
+package lease
+
+import "time"
+
+// RetryOp is the operation type of a retry loop. See: Config.RetryPolicies.
+type RetryOp string
+
+const (
+	// RetryGet is the retry loop of the consistent reads of single leases.
+	RetryGet RetryOp = "get"
+	// RetryList is the retry loop of the scans of the lease table.
+	RetryList RetryOp = "list"
+	// RetryCreate is the retry loop of creating the lease table and new leases.
+	RetryCreate RetryOp = "create"
+	// RetryUpdate is the retry loop of the lease writes, e.g: take, renew, evict or update.
+	RetryUpdate RetryOp = "update"
+	// RetryDelete is the retry loop of deleting leases.
+	RetryDelete RetryOp = "delete"
+)
+
+// RetryPolicy is the interface that holds the retry strategy of an operation type.
+// The attempts are numbered from 1, and attempt n is the n-th call that failed. The
+// policy is shared by the concurrent calls of the worker, and it should be stateless
+// (or thread-safe).
+type RetryPolicy interface {
+	// NextDelay returns the duration to wait before retrying the given failed attempt.
+	NextDelay(attempt int) time.Duration
+	// ShouldRetry reports whether the given failed attempt should be retried. The
+	// errors that Config.IsRetryable treats as permanent are never retried.
+	ShouldRetry(attempt int, err error) bool
+	// MaxAttempts returns the maximum number of calls. 0 means no limit.
+	MaxAttempts() int
+	// MaxElapsed returns the maximum duration since the first call, after which the
+	// failed calls are not retried. 0 means no limit.
+	MaxElapsed() time.Duration
+}
+
+// retrier holds the state of a single retry loop. Operations without a RetryPolicy
+// use the Config.Backoff strategy, with the maximum number of retries of the operation.
+type retrier struct {
+	*Config
+	policy  RetryPolicy
+	max     int
+	attempt int
+	start   time.Time
+}
+
+// retrier returns the retry loop state of the given operation type.
+func (c *Config) retrier(op RetryOp) *retrier {
+	r := &retrier{Config: c, policy: c.RetryPolicies[op], start: c.now()}
+	switch op {
+	case RetryGet:
+		r.max = maxGetRetries
+	case RetryList:
+		r.max = maxScanRetries
+	case RetryCreate:
+		r.max = maxCreateRetries
+	case RetryUpdate:
+		r.max = maxUpdateRetries
+	case RetryDelete:
+		r.max = maxDeleteRetries
+	}
+	if r.policy != nil {
+		r.max = r.policy.MaxAttempts()
+	}
+	return r
+}
+
+// more reports whether the loop should make another call.
+func (r *retrier) more() bool {
+	if r.policy == nil {
+		return r.Backoff.Attempt() < float64(r.max)
+	}
+	return r.max <= 0 || r.attempt < r.max
+}
+
+// retryable reports whether the failed call should be retried. with a RetryPolicy, it
+// also stops the loop when there are no attempts left, instead of waiting for nothing.
+func (r *retrier) retryable(err error) bool {
+	if !r.Config.retryable(err) {
+		return false
+	}
+	if r.policy == nil {
+		return true
+	}
+	n := r.attempt + 1
+	if r.max > 0 && n >= r.max {
+		return false
+	}
+	if d := r.policy.MaxElapsed(); d > 0 && r.since(r.start) >= d {
+		return false
+	}
+	return r.policy.ShouldRetry(n, err)
+}
+
+// delay returns the duration to wait before the next call, and counts the retry.
+func (r *retrier) delay() time.Duration {
+	if r.policy == nil {
+		d := r.Backoff.Duration()
+		r.attempt = int(r.Backoff.Attempt())
+		return d
+	}
+	r.attempt++
+	return r.policy.NextDelay(r.attempt)
+}
+
+// reset ends the loop, and returns the number of retries that were made.
+func (r *retrier) reset() int {
+	if r.policy == nil {
+		attempt := int(r.Backoff.Attempt())
+		r.Backoff.Reset()
+		return attempt
+	}
+	return r.attempt
+}
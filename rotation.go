@@ -0,0 +1,89 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rotations records the leases that this worker released when their time slice was
+// over (see: Config.MaxHoldDuration). It's shared by the renewer, that releases the
+// leases, and the taker, that avoids taking them back before other workers could.
+type rotations struct {
+	sync.Mutex
+	released map[string]time.Time
+	// now is the clock of the cooldowns. see: Config.Clock.
+	now func() time.Time
+}
+
+// add records that the lease with the given key was rotated now.
+func (r *rotations) add(key string) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.released == nil {
+		r.released = make(map[string]time.Time)
+	}
+	r.released[key] = r.now()
+}
+
+// recent test if the lease with the given key was rotated within the given duration.
+func (r *rotations) recent(key string, d time.Duration) bool {
+	if r == nil {
+		return false
+	}
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.released[key]
+	if ok && r.now().Sub(t) >= d {
+		delete(r.released, key)
+		ok = false
+	}
+	return ok
+}
+
+// rotationCooldown is the time that a rotated lease is left for other workers to take,
+// before this worker may take it back. it's one interval of the taker loop.
+func (c *Config) rotationCooldown() time.Duration {
+	return (c.ExpireAfter + c.epsilonMills) * 2
+}
+
+// rotated test if the given lease was rotated by this worker recently.
+func (l *leaseTaker) rotated(lease *Lease) bool {
+	return l.MaxHoldDuration > 0 && l.rotations.recent(lease.Key, l.rotationCooldown())
+}
+
+// rotate releases the given held lease if it was held for longer than MaxHoldDuration,
+// so other workers get their time slice. It returns true if the lease was released.
+func (l *leaseHolder) rotate(ctx context.Context, lease *Lease) bool {
+	if l.MaxHoldDuration <= 0 {
+		return false
+	}
+	l.Lock()
+	if l.heldSince == nil {
+		l.heldSince = make(map[string]time.Time)
+	}
+	since, ok := l.heldSince[lease.Key]
+	if !ok {
+		l.heldSince[lease.Key] = l.now()
+	}
+	l.Unlock()
+	if !ok || l.since(since) < l.MaxHoldDuration {
+		return false
+	}
+	if err := l.manager.EvictLease(ctx, lease); err != nil {
+		l.Logger.Debugf("Worker %s could not rotate lease with key %s", l.WorkerId, lease.Key)
+		return false
+	}
+	l.Lock()
+	delete(l.heldLeases, lease.Key)
+	delete(l.heldSince, lease.Key)
+	delete(l.renewedAt, lease.Key)
+	l.Unlock()
+	l.rotations.add(lease.Key)
+	l.Logger.Debugf("Worker %s rotated lease with key %s after holding it for %s", l.WorkerId, lease.Key, l.since(since))
+	l.ownerChange(OwnerChange{Key: lease.Key, Type: OwnerReleased, From: l.WorkerId}, lease)
+	return true
+}
@@ -0,0 +1,199 @@
+// Package s3backend runs the coordinator on Amazon S3, instead of DynamoDB, e.g: in
+// low-cost deployments that don't need the throughput of a DynamoDB table.
+//
+// The leases are stored as one JSON object per lease under a key prefix, and the
+// conditional writes of lease.Backend are conditional PUTs and DELETEs (If-Match on the
+// ETag of the stored lease, and If-None-Match for the new leases), so the conditions on
+// the leaseOwner and the leaseCounter hold across workers. For example:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	leaser := lease.New("leases", lease.WithBackend(s3backend.New(s3.NewFromConfig(cfg), "bucket", "leases/")))
+//
+// Each scan of the lease table lists the prefix and gets all the leases, so the request
+// cost grows with the number of leases and the workers. The workers scan every twice the
+// lease.Config.ExpireAfter, and renew their leases every third of it, so set it accordingly.
+package s3backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/a8m/lease"
+	"github.com/a8m/lease/internal/record"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// API is the subset of the S3 client of aws-sdk-go-v2 that is used by the backend. It's
+// implemented by *s3.Client.
+type API interface {
+	GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(context.Context, *s3.DeleteObjectInput, ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// Backend is a lease.Backend that stores the leases in an S3 bucket.
+type Backend struct {
+	API    API
+	Bucket string
+	// Prefix is the prefix of the object keys of the lease table, e.g: "leases/".
+	Prefix string
+}
+
+// New returns a lease.Backend that stores the leases under the given key prefix of the
+// given bucket, using the given S3 client.
+func New(api API, bucket, prefix string) lease.Backend {
+	return &Backend{API: api, Bucket: bucket, Prefix: prefix}
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (*lease.Lease, error) {
+	raw, _, err := b.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return record.Decode(raw)
+}
+
+func (b *Backend) List(ctx context.Context) ([]*lease.Lease, error) {
+	var list []*lease.Lease
+	p := s3.NewListObjectsV2Paginator(b.API, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.Bucket),
+		Prefix: aws.String(b.Prefix),
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range out.Contents {
+			l, err := b.Get(ctx, strings.TrimPrefix(aws.ToString(obj.Key), b.Prefix))
+			// the lease was deleted after the listing.
+			if errors.Is(err, lease.ErrLeaseNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, l)
+		}
+	}
+	return list, nil
+}
+
+func (b *Backend) PutIfAbsent(ctx context.Context, l *lease.Lease) (bool, error) {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return false, err
+	}
+	_, err = b.API.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.Bucket),
+		Key:         aws.String(b.Prefix + l.Key),
+		Body:        strings.NewReader(raw),
+		ContentType: aws.String("application/json"),
+		IfNoneMatch: aws.String("*"),
+	})
+	if isPreconditionFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Backend) CompareAndUpdate(ctx context.Context, old, l *lease.Lease) error {
+	raw, err := record.Encode(l)
+	if err != nil {
+		return err
+	}
+	etag, err := b.compare(ctx, old)
+	if errors.Is(err, lease.ErrLeaseNotFound) {
+		return lease.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	_, err = b.API.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.Bucket),
+		Key:         aws.String(b.Prefix + l.Key),
+		Body:        strings.NewReader(raw),
+		ContentType: aws.String("application/json"),
+		IfMatch:     aws.String(etag),
+	})
+	if isPreconditionFailed(err) {
+		return lease.ErrConflict
+	}
+	return err
+}
+
+func (b *Backend) Delete(ctx context.Context, old *lease.Lease) error {
+	etag, err := b.compare(ctx, old)
+	if errors.Is(err, lease.ErrLeaseNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = b.API.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(b.Bucket),
+		Key:     aws.String(b.Prefix + old.Key),
+		IfMatch: aws.String(etag),
+	})
+	if isPreconditionFailed(err) {
+		return lease.ErrConflict
+	}
+	return err
+}
+
+// compare returns the ETag of the stored lease, if it's unchanged since the old lease was
+// read. Fails with lease.ErrConflict if it was changed, or lease.ErrLeaseNotFound if it
+// does not exist.
+func (b *Backend) compare(ctx context.Context, old *lease.Lease) (string, error) {
+	oraw, err := record.Encode(old)
+	if err != nil {
+		return "", err
+	}
+	raw, etag, err := b.get(ctx, old.Key)
+	if err != nil {
+		return "", err
+	}
+	if raw != oraw {
+		return "", lease.ErrConflict
+	}
+	return etag, nil
+}
+
+// get returns the stored lease object with the given key, and its ETag.
+func (b *Backend) get(ctx context.Context, key string) (string, string, error) {
+	out, err := b.API.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.Prefix + key),
+	})
+	var nsk *types.NoSuchKey
+	if errors.As(err, &nsk) {
+		return "", "", lease.ErrLeaseNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+	defer out.Body.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, out.Body); err != nil {
+		return "", "", err
+	}
+	return buf.String(), aws.ToString(out.ETag), nil
+}
+
+// isPreconditionFailed test if the given error is a failed condition of a conditional
+// write, or a conflict with a concurrent conditional write of the same object.
+func isPreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	code := apiErr.ErrorCode()
+	return code == "PreconditionFailed" || code == "ConditionalRequestConflict"
+}
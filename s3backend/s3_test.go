@@ -0,0 +1,142 @@
+package s3backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a8m/lease"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+type object struct {
+	body string
+	etag string
+}
+
+// apiMock is an in-memory bucket, that evaluates the conditions of the writes.
+type apiMock struct {
+	objects map[string]object
+	version int
+	// before is called before each write, e.g: to simulate a concurrent write.
+	before func()
+}
+
+var errPrecondition = &smithy.GenericAPIError{Code: "PreconditionFailed"}
+
+func (m *apiMock) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	obj, ok := m.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(obj.body)), ETag: aws.String(obj.etag)}, nil
+}
+
+func (m *apiMock) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if m.before != nil {
+		m.before()
+	}
+	obj, ok := m.objects[aws.ToString(in.Key)]
+	if in.IfNoneMatch != nil && ok || in.IfMatch != nil && (!ok || obj.etag != aws.ToString(in.IfMatch)) {
+		return nil, errPrecondition
+	}
+	body, _ := io.ReadAll(in.Body)
+	m.version++
+	m.objects[aws.ToString(in.Key)] = object{body: string(body), etag: strconv.Itoa(m.version)}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *apiMock) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if m.before != nil {
+		m.before()
+	}
+	obj, ok := m.objects[aws.ToString(in.Key)]
+	if in.IfMatch != nil && ok && obj.etag != aws.ToString(in.IfMatch) {
+		return nil, errPrecondition
+	}
+	delete(m.objects, aws.ToString(in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *apiMock) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	var keys []string
+	for k := range m.objects {
+		if strings.HasPrefix(k, aws.ToString(in.Prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	out := &s3.ListObjectsV2Output{}
+	for _, k := range keys {
+		out.Contents = append(out.Contents, types.Object{Key: aws.String(k)})
+	}
+	return out, nil
+}
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	api := &apiMock{objects: make(map[string]object)}
+	b := New(api, "bucket", "leases/")
+
+	l := lease.NewLease("foo")
+	l.Owner = "1"
+	l.Counter = 1
+	l.Set("checkpoint", 10)
+	if ok, err := b.PutIfAbsent(ctx, &l); err != nil || !ok {
+		t.Fatalf("expect the lease to be stored: %v", err)
+	}
+	if ok, err := b.PutIfAbsent(ctx, &l); err != nil || ok {
+		t.Errorf("expect existing leases not to be stored, got: %v", err)
+	}
+	if _, ok := api.objects["leases/foo"]; !ok {
+		t.Fatal("expect the lease to be stored under the prefix")
+	}
+	old, err := b.Get(ctx, "foo")
+	if err != nil || old.Owner != "1" {
+		t.Fatalf("expect to get the stored lease, got: %+v, %v", old, err)
+	}
+	update := *old
+	update.Counter++
+	if err := b.CompareAndUpdate(ctx, old, &update); err != nil {
+		t.Fatalf("expect the decoded lease to match the stored one: %v", err)
+	}
+	if err := b.CompareAndUpdate(ctx, old, &update); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the update of a changed lease to conflict, got: %v", err)
+	}
+	if err := b.Delete(ctx, old); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect the delete of a changed lease to conflict, got: %v", err)
+	}
+
+	// a write between the read and the conditional write fails the ETag condition.
+	current, _ := b.Get(ctx, "foo")
+	api.before = func() {
+		api.before = nil
+		api.version++
+		obj := api.objects["leases/foo"]
+		api.objects["leases/foo"] = object{body: obj.body, etag: strconv.Itoa(api.version)}
+	}
+	if err := b.CompareAndUpdate(ctx, current, &update); !errors.Is(err, lease.ErrConflict) {
+		t.Errorf("expect a concurrent write to conflict, got: %v", err)
+	}
+
+	list, err := b.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Counter != 2 {
+		t.Fatalf("expect to list the updated lease, got: %v, %v", list, err)
+	}
+	if err := b.Delete(ctx, list[0]); err != nil {
+		t.Fatalf("expect the lease to be deleted: %v", err)
+	}
+	if _, err := b.Get(ctx, "foo"); !errors.Is(err, lease.ErrLeaseNotFound) {
+		t.Errorf("expect the lease not to be found, got: %v", err)
+	}
+	if err := b.Delete(ctx, list[0]); err != nil {
+		t.Errorf("expect the delete of a missing lease to do nothing, got: %v", err)
+	}
+}
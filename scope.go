@@ -0,0 +1,203 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Selector selects the leases of a Scope. A lease matches if its key starts with Prefix,
+// and it has all the given Labels. A label is matched against Lease.Labels, or against the
+// extra fields (see: Lease.Get) if the lease has no such label. The values of the extra
+// fields are compared in their formatted form, e.g: the label "shard": "3" matches the
+// extra field shard=3. An empty Selector matches all the leases. See: Manager.ListLeasesBySelector.
+type Selector struct {
+	Prefix string
+	Labels map[string]string
+}
+
+// Matches test if the given lease is selected by the selector.
+func (s Selector) Matches(lease Lease) bool {
+	if !strings.HasPrefix(lease.Key, s.Prefix) {
+		return false
+	}
+	for k, v := range s.Labels {
+		if label, ok := lease.Labels[k]; ok {
+			if label != v {
+				return false
+			}
+			continue
+		}
+		val, ok := lease.Get(k)
+		if !ok || fmt.Sprint(val) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Scope is a view of the Leaser that covers only the leases that match its Selector,
+// so different subsystems of a process can each consume their slice of the lease table
+// without filtering everywhere. The leases are still taken and renewed by the Leaser.
+// See: Leaser.Scope.
+type Scope struct {
+	Selector
+	leaser Leaser
+	err    error
+	// scopes is the registry that dispatches the events to the scope. see: Close.
+	scopes *scopes
+
+	mu            sync.RWMutex
+	onOwnerChange []func(OwnerChange)
+	onTakeover    []func(Takeover)
+}
+
+// Scope returns a view of the coordinator that covers only the leases that match the
+// given selector. The callbacks of the scope are called in addition to the callbacks
+// of the config, until the scope is closed.
+func (c *Coordinator) Scope(selector Selector) *Scope {
+	s := &Scope{Selector: selector, leaser: c, scopes: c.scopes}
+	c.scopes.add(s)
+	return s
+}
+
+// NewScope returns a view of the given leaser that covers only the leases that match the
+// given selector, for a Leaser that does not dispatch the events of its leases to scopes
+// (e.g: a remote one). The leases of the scope are read from the leaser, its callbacks
+// are never called, and Err returns the given error.
+func NewScope(leaser Leaser, selector Selector, err error) *Scope {
+	return &Scope{Selector: selector, leaser: leaser, err: err}
+}
+
+// Err returns the reason the callbacks of the scope are never called, or nil if they are
+// called on the events of its leases. See: NewScope.
+func (s *Scope) Err() error {
+	return s.err
+}
+
+// Close stops calling the callbacks of the scope, and releases it. Its leases can still
+// be read. It does nothing for the scopes of NewScope.
+func (s *Scope) Close() {
+	s.scopes.remove(s)
+}
+
+// GetHeldLeases returns the currently held leases of the scope.
+func (s *Scope) GetHeldLeases() []Lease {
+	return s.filter(s.leaser.GetHeldLeases())
+}
+
+// GetSharedLeases returns the leases of the scope that are currently held in shared mode.
+func (s *Scope) GetSharedLeases() []Lease {
+	return s.filter(s.leaser.GetSharedLeases())
+}
+
+// Get returns the lease with the given key. See: Leaser.Get.
+//
+// Fails with ErrLeaseNotFound if the lease does not match the selector.
+func (s *Scope) Get(ctx context.Context, key string) (Lease, error) {
+	if !strings.HasPrefix(key, s.Prefix) {
+		return Lease{}, ErrLeaseNotFound
+	}
+	lease, err := s.leaser.Get(ctx, key)
+	if err != nil {
+		return Lease{}, err
+	}
+	if !s.Matches(lease) {
+		return Lease{}, ErrLeaseNotFound
+	}
+	return lease, nil
+}
+
+// OnOwnerChange registers the given callback to be called on the owner changes of the
+// leases of the scope. See: Config.OnOwnerChange.
+func (s *Scope) OnOwnerChange(fn func(OwnerChange)) {
+	s.mu.Lock()
+	s.onOwnerChange = append(s.onOwnerChange, fn)
+	s.mu.Unlock()
+}
+
+// OnTakeover registers the given callback to be called after this worker takes a lease
+// of the scope. See: Config.OnTakeover.
+func (s *Scope) OnTakeover(fn func(Takeover)) {
+	s.mu.Lock()
+	s.onTakeover = append(s.onTakeover, fn)
+	s.mu.Unlock()
+}
+
+// filter returns the leases of the given list that match the selector.
+func (s *Scope) filter(list []Lease) []Lease {
+	var matched []Lease
+	for _, lease := range list {
+		if s.Matches(lease) {
+			matched = append(matched, lease)
+		}
+	}
+	return matched
+}
+
+// scopes holds the scopes of a coordinator, and dispatches the events of their leases.
+// the nil value has no scopes.
+type scopes struct {
+	mu   sync.RWMutex
+	list []*Scope
+}
+
+func (s *scopes) add(scope *Scope) {
+	s.mu.Lock()
+	s.list = append(s.list, scope)
+	s.mu.Unlock()
+}
+
+func (s *scopes) remove(scope *Scope) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sc := range s.list {
+		if sc == scope {
+			s.list = append(s.list[:i:i], s.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// matching returns the scopes that the given lease matches.
+func (s *scopes) matching(lease *Lease) (list []*Scope) {
+	if s == nil || lease == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, scope := range s.list {
+		if scope.Matches(*lease) {
+			list = append(list, scope)
+		}
+	}
+	return
+}
+
+// ownerChange calls the OnOwnerChange callbacks of the scopes of the given lease.
+func (s *scopes) ownerChange(change OwnerChange, lease *Lease) {
+	for _, scope := range s.matching(lease) {
+		scope.mu.RLock()
+		fns := scope.onOwnerChange
+		scope.mu.RUnlock()
+		for _, fn := range fns {
+			fn(change)
+		}
+	}
+}
+
+// takeover calls the OnTakeover callbacks of the scopes of the given lease.
+func (s *scopes) takeover(t Takeover, lease *Lease) {
+	for _, scope := range s.matching(lease) {
+		scope.mu.RLock()
+		fns := scope.onTakeover
+		scope.mu.RUnlock()
+		for _, fn := range fns {
+			fn(t)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScope(t *testing.T) {
+	config := &Config{Logger: testLogger(), LeaseTable: "test", WorkerId: "1", ExpireAfter: time.Minute, Client: newClientMock(nil)}
+	c := NewFromConfig(config).(*Coordinator)
+	shard := NewLease("orders/1")
+	shard.Set("region", "eu")
+	other := NewLease("orders/2")
+	other.Set("region", "us")
+	c.Renewer.(*leaseHolder).heldLeases = map[string]*Lease{
+		"orders/1":  &shard,
+		"orders/2":  &other,
+		"billing/1": {Key: "billing/1"},
+	}
+
+	orders := c.Scope(Selector{Prefix: "orders/"})
+	eu := c.Scope(Selector{Prefix: "orders/", Labels: map[string]string{"region": "eu"}})
+	assert(t, len(orders.GetHeldLeases()) == 2, "expect the held leases to be filtered by prefix")
+	held := eu.GetHeldLeases()
+	assert(t, len(held) == 1 && held[0].Key == "orders/1", "expect the held leases to be filtered by labels")
+	_, err := eu.Get(context.Background(), "billing/1")
+	assert(t, err == ErrLeaseNotFound, "expect leases out of the scope not to be found")
+
+	var changes []OwnerChange
+	eu.OnOwnerChange(func(change OwnerChange) {
+		changes = append(changes, change)
+	})
+	config.ownerChange(OwnerChange{Key: "orders/1", Type: OwnerLost}, &shard)
+	config.ownerChange(OwnerChange{Key: "orders/2", Type: OwnerLost}, &other)
+	assert(t, len(changes) == 1 && changes[0].Key == "orders/1" && changes[0].Worker == "1", "expect only the changes of the scope leases")
+
+	eu.Close()
+	config.ownerChange(OwnerChange{Key: "orders/1", Type: OwnerAcquired}, &shard)
+	assert(t, len(changes) == 1, "expect the callbacks of a closed scope not to be called")
+	assert(t, len(c.scopes.list) == 1 && c.scopes.list[0] == orders, "expect the closed scope to be removed")
+	NewScope(c, Selector{}, nil).Close()
+}
@@ -0,0 +1,213 @@
+// Package sdkv2 runs the lease manager on the DynamoDB client of aws-sdk-go-v2.
+//
+// The client of the new SDK is adapted to lease.Clientface, so the lease manager and the
+// Lease/Leaser API are left intact. The calls use the native context support of the new
+// SDK, and its retry middleware, on top of the retries of the lease manager. For example:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	leaser := lease.New("leases", lease.WithClient(sdkv2.New(dynamodb.NewFromConfig(cfg))))
+//
+// The errors of the new SDK are returned as awserr.Error (and awserr.RequestFailure if
+// they have an HTTP status code) with the same error codes, so the conditional failures
+// and the failover logic of the lease package keep working.
+package sdkv2
+
+import (
+	"context"
+	"errors"
+
+	"github.com/a8m/lease"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v1 "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/smithy-go"
+)
+
+// API is the subset of the DynamoDB client of aws-sdk-go-v2 that is used by the lease
+// manager. It's implemented by *dynamodb.Client.
+type API interface {
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	Scan(context.Context, *dynamodb.ScanInput, ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	CreateTable(context.Context, *dynamodb.CreateTableInput, ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	DescribeTable(context.Context, *dynamodb.DescribeTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	DeleteTable(context.Context, *dynamodb.DeleteTableInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+}
+
+// Client is a lease.Clientface that is backed by the DynamoDB client of aws-sdk-go-v2.
+// It supports DynamoDB transactions, so lease groups and Reshard can be used with it, and
+// deleting tables (see: lease.Admin).
+//
+// The request.Option arguments belong to the old SDK, and they are ignored. Use Options
+// to customize the calls of the new SDK.
+type Client struct {
+	API API
+	// Options are applied to all the calls.
+	Options []func(*dynamodb.Options)
+}
+
+// New returns a lease.Clientface that uses the given client of aws-sdk-go-v2.
+func New(api API) lease.Clientface {
+	return &Client{API: api}
+}
+
+func (c *Client) GetItemWithContext(ctx aws.Context, in *v1.GetItemInput, _ ...request.Option) (*v1.GetItemOutput, error) {
+	out, err := c.API.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:                in.TableName,
+		Key:                      toItem(in.Key),
+		ConsistentRead:           in.ConsistentRead,
+		ProjectionExpression:     in.ProjectionExpression,
+		ExpressionAttributeNames: toNames(in.ExpressionAttributeNames),
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.GetItemOutput{Item: fromItem(out.Item)}, nil
+}
+
+func (c *Client) ScanWithContext(ctx aws.Context, in *v1.ScanInput, _ ...request.Option) (*v1.ScanOutput, error) {
+	out, err := c.API.Scan(ctx, &dynamodb.ScanInput{
+		TableName:                 in.TableName,
+		ExclusiveStartKey:         toItem(in.ExclusiveStartKey),
+		Limit:                     toInt32(in.Limit),
+		Segment:                   toInt32(in.Segment),
+		TotalSegments:             toInt32(in.TotalSegments),
+		ConsistentRead:            in.ConsistentRead,
+		FilterExpression:          in.FilterExpression,
+		ProjectionExpression:      in.ProjectionExpression,
+		ExpressionAttributeNames:  toNames(in.ExpressionAttributeNames),
+		ExpressionAttributeValues: toItem(in.ExpressionAttributeValues),
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	items := make([]map[string]*v1.AttributeValue, len(out.Items))
+	for i, item := range out.Items {
+		items[i] = fromItem(item)
+	}
+	return &v1.ScanOutput{
+		Items:            items,
+		LastEvaluatedKey: fromItem(out.LastEvaluatedKey),
+		Count:            aws.Int64(int64(out.Count)),
+		ScannedCount:     aws.Int64(int64(out.ScannedCount)),
+	}, nil
+}
+
+func (c *Client) PutItemWithContext(ctx aws.Context, in *v1.PutItemInput, _ ...request.Option) (*v1.PutItemOutput, error) {
+	out, err := c.API.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 in.TableName,
+		Item:                      toItem(in.Item),
+		ConditionExpression:       in.ConditionExpression,
+		ExpressionAttributeNames:  toNames(in.ExpressionAttributeNames),
+		ExpressionAttributeValues: toItem(in.ExpressionAttributeValues),
+		ReturnValues:              toReturnValue(in.ReturnValues),
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.PutItemOutput{Attributes: fromItem(out.Attributes)}, nil
+}
+
+func (c *Client) UpdateItemWithContext(ctx aws.Context, in *v1.UpdateItemInput, _ ...request.Option) (*v1.UpdateItemOutput, error) {
+	out, err := c.API.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                           in.TableName,
+		Key:                                 toItem(in.Key),
+		UpdateExpression:                    in.UpdateExpression,
+		ConditionExpression:                 in.ConditionExpression,
+		ExpressionAttributeNames:            toNames(in.ExpressionAttributeNames),
+		ExpressionAttributeValues:           toItem(in.ExpressionAttributeValues),
+		ReturnValues:                        toReturnValue(in.ReturnValues),
+		ReturnValuesOnConditionCheckFailure: toReturnValuesOnFailure(in.ReturnValuesOnConditionCheckFailure),
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.UpdateItemOutput{Attributes: fromItem(out.Attributes)}, nil
+}
+
+func (c *Client) DeleteItemWithContext(ctx aws.Context, in *v1.DeleteItemInput, _ ...request.Option) (*v1.DeleteItemOutput, error) {
+	out, err := c.API.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                           in.TableName,
+		Key:                                 toItem(in.Key),
+		ConditionExpression:                 in.ConditionExpression,
+		ExpressionAttributeNames:            toNames(in.ExpressionAttributeNames),
+		ExpressionAttributeValues:           toItem(in.ExpressionAttributeValues),
+		ReturnValues:                        toReturnValue(in.ReturnValues),
+		ReturnValuesOnConditionCheckFailure: toReturnValuesOnFailure(in.ReturnValuesOnConditionCheckFailure),
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.DeleteItemOutput{Attributes: fromItem(out.Attributes)}, nil
+}
+
+func (c *Client) CreateTableWithContext(ctx aws.Context, in *v1.CreateTableInput, _ ...request.Option) (*v1.CreateTableOutput, error) {
+	out, err := c.API.CreateTable(ctx, toCreateTable(in), c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.CreateTableOutput{TableDescription: fromTable(out.TableDescription)}, nil
+}
+
+func (c *Client) DescribeTableWithContext(ctx aws.Context, in *v1.DescribeTableInput, _ ...request.Option) (*v1.DescribeTableOutput, error) {
+	out, err := c.API.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: in.TableName,
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.DescribeTableOutput{Table: fromTable(out.Table)}, nil
+}
+
+func (c *Client) TransactWriteItemsWithContext(ctx aws.Context, in *v1.TransactWriteItemsInput, _ ...request.Option) (*v1.TransactWriteItemsOutput, error) {
+	_, err := c.API.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems:      toTransactItems(in.TransactItems),
+		ClientRequestToken: in.ClientRequestToken,
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.TransactWriteItemsOutput{}, nil
+}
+
+func (c *Client) DeleteTableWithContext(ctx aws.Context, in *v1.DeleteTableInput, _ ...request.Option) (*v1.DeleteTableOutput, error) {
+	out, err := c.API.DeleteTable(ctx, &dynamodb.DeleteTableInput{
+		TableName: in.TableName,
+	}, c.Options...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &v1.DeleteTableOutput{TableDescription: fromTable(out.TableDescription)}, nil
+}
+
+// toError converts the given error of the new SDK to an awserr.Error with the same code,
+// that wraps the original error. errors without an API error code (e.g: context errors)
+// are returned as is. conditional check failures are converted to the exception of the
+// old SDK, with the item that failed the check (see: lease.ConditionError).
+func toError(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	var respErr interface{ HTTPStatusCode() int }
+	hasStatus := errors.As(err, &respErr)
+	var cerr *types.ConditionalCheckFailedException
+	if errors.As(err, &cerr) {
+		out := &v1.ConditionalCheckFailedException{Message_: cerr.Message, Item: fromItem(cerr.Item)}
+		if hasStatus {
+			out.RespMetadata.StatusCode = respErr.HTTPStatusCode()
+		}
+		return out
+	}
+	aerr := awserr.New(apiErr.ErrorCode(), apiErr.ErrorMessage(), err)
+	if hasStatus {
+		return awserr.NewRequestFailure(aerr, respErr.HTTPStatusCode(), "")
+	}
+	return aerr
+}
@@ -0,0 +1,88 @@
+package sdkv2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	v1 "github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// apiMock is an API that returns the given item, or fails with the given error.
+type apiMock struct {
+	API
+	item  map[string]types.AttributeValue
+	err   error
+	input *dynamodb.UpdateItemInput
+}
+
+func (a *apiMock) UpdateItem(_ context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	a.input = in
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &dynamodb.UpdateItemOutput{Attributes: a.item}, nil
+}
+
+func TestUpdateItem(t *testing.T) {
+	api := &apiMock{item: map[string]types.AttributeValue{
+		"leaseKey":     &types.AttributeValueMemberS{Value: "foo"},
+		"leaseCounter": &types.AttributeValueMemberN{Value: "2"},
+		"leaseHolders": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"1": &types.AttributeValueMemberN{Value: "10"},
+		}},
+	}}
+	client := New(api)
+	out, err := client.UpdateItemWithContext(context.Background(), &v1.UpdateItemInput{
+		TableName:        aws.String("leases"),
+		Key:              map[string]*v1.AttributeValue{"leaseKey": {S: aws.String("foo")}},
+		UpdateExpression: aws.String("ADD #c :one"),
+		ExpressionAttributeNames: map[string]*string{
+			"#c": aws.String("leaseCounter"),
+		},
+		ExpressionAttributeValues: map[string]*v1.AttributeValue{
+			":one": {N: aws.String("1")},
+		},
+		ReturnValues: aws.String(v1.ReturnValueAllNew),
+	})
+	if err != nil {
+		t.Fatalf("expect update not to fail: %v", err)
+	}
+	if s := api.input.Key["leaseKey"].(*types.AttributeValueMemberS); s.Value != "foo" {
+		t.Error("expect the key to be converted")
+	}
+	if api.input.ExpressionAttributeNames["#c"] != "leaseCounter" || api.input.ReturnValues != types.ReturnValueAllNew {
+		t.Error("expect the expression arguments to be converted")
+	}
+	if aws.StringValue(out.Attributes["leaseCounter"].N) != "2" || aws.StringValue(out.Attributes["leaseHolders"].M["1"].N) != "10" {
+		t.Error("expect the attributes to be converted")
+	}
+}
+
+func TestConditionalFailure(t *testing.T) {
+	client := New(&apiMock{err: &types.ConditionalCheckFailedException{Message: aws.String("failed")}})
+	_, err := client.UpdateItemWithContext(context.Background(), &v1.UpdateItemInput{})
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != "ConditionalCheckFailedException" {
+		t.Errorf("expect the error code to be kept, got: %v", err)
+	}
+
+	client = New(&apiMock{err: &types.ConditionalCheckFailedException{Item: map[string]types.AttributeValue{
+		"leaseOwner": &types.AttributeValueMemberS{Value: "2"},
+	}}})
+	_, err = client.UpdateItemWithContext(context.Background(), &v1.UpdateItemInput{})
+	var cerr *v1.ConditionalCheckFailedException
+	if !errors.As(err, &cerr) || aws.StringValue(cerr.Item["leaseOwner"].S) != "2" {
+		t.Errorf("expect the item that failed the check to be kept, got: %v", err)
+	}
+
+	client = New(&apiMock{err: context.Canceled})
+	_, err = client.UpdateItemWithContext(context.Background(), &v1.UpdateItemInput{})
+	if err != context.Canceled {
+		t.Errorf("expect errors without a code to be returned as is, got: %v", err)
+	}
+}
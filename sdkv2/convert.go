@@ -0,0 +1,210 @@
+package sdkv2
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	v1 "github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// toItem converts an item of the old SDK to an item of the new SDK. empty items are
+// omitted, since DynamoDB rejects empty expression attribute values.
+func toItem(item map[string]*v1.AttributeValue) map[string]types.AttributeValue {
+	if len(item) == 0 {
+		return nil
+	}
+	m := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		m[k] = toValue(v)
+	}
+	return m
+}
+
+// toValue converts an attribute value of the old SDK to an attribute value of the new SDK.
+func toValue(v *v1.AttributeValue) types.AttributeValue {
+	switch {
+	case v == nil:
+		return nil
+	case v.S != nil:
+		return &types.AttributeValueMemberS{Value: *v.S}
+	case v.N != nil:
+		return &types.AttributeValueMemberN{Value: *v.N}
+	case v.B != nil:
+		return &types.AttributeValueMemberB{Value: v.B}
+	case v.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *v.BOOL}
+	case v.NULL != nil:
+		return &types.AttributeValueMemberNULL{Value: *v.NULL}
+	case v.SS != nil:
+		return &types.AttributeValueMemberSS{Value: aws.StringValueSlice(v.SS)}
+	case v.NS != nil:
+		return &types.AttributeValueMemberNS{Value: aws.StringValueSlice(v.NS)}
+	case v.BS != nil:
+		return &types.AttributeValueMemberBS{Value: v.BS}
+	case v.M != nil:
+		return &types.AttributeValueMemberM{Value: toItem(v.M)}
+	case v.L != nil:
+		l := make([]types.AttributeValue, len(v.L))
+		for i, e := range v.L {
+			l[i] = toValue(e)
+		}
+		return &types.AttributeValueMemberL{Value: l}
+	}
+	return nil
+}
+
+// fromItem converts an item of the new SDK to an item of the old SDK.
+func fromItem(item map[string]types.AttributeValue) map[string]*v1.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	m := make(map[string]*v1.AttributeValue, len(item))
+	for k, v := range item {
+		m[k] = fromValue(v)
+	}
+	return m
+}
+
+// fromValue converts an attribute value of the new SDK to an attribute value of the old SDK.
+func fromValue(v types.AttributeValue) *v1.AttributeValue {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return &v1.AttributeValue{S: aws.String(v.Value)}
+	case *types.AttributeValueMemberN:
+		return &v1.AttributeValue{N: aws.String(v.Value)}
+	case *types.AttributeValueMemberB:
+		return &v1.AttributeValue{B: v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return &v1.AttributeValue{BOOL: aws.Bool(v.Value)}
+	case *types.AttributeValueMemberNULL:
+		return &v1.AttributeValue{NULL: aws.Bool(v.Value)}
+	case *types.AttributeValueMemberSS:
+		return &v1.AttributeValue{SS: aws.StringSlice(v.Value)}
+	case *types.AttributeValueMemberNS:
+		return &v1.AttributeValue{NS: aws.StringSlice(v.Value)}
+	case *types.AttributeValueMemberBS:
+		return &v1.AttributeValue{BS: v.Value}
+	case *types.AttributeValueMemberM:
+		return &v1.AttributeValue{M: fromItem(v.Value)}
+	case *types.AttributeValueMemberL:
+		l := make([]*v1.AttributeValue, len(v.Value))
+		for i, e := range v.Value {
+			l[i] = fromValue(e)
+		}
+		return &v1.AttributeValue{L: l}
+	}
+	return nil
+}
+
+// toNames converts the expression attribute names of the old SDK. empty names are omitted.
+func toNames(names map[string]*string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	return aws.StringValueMap(names)
+}
+
+// toInt32 converts an optional int64 argument of the old SDK to the int32 of the new SDK.
+func toInt32(n *int64) *int32 {
+	if n == nil {
+		return nil
+	}
+	v := int32(*n)
+	return &v
+}
+
+// toReturnValue converts an optional ReturnValues argument of the old SDK.
+func toReturnValue(s *string) types.ReturnValue {
+	return types.ReturnValue(aws.StringValue(s))
+}
+
+// toReturnValuesOnFailure converts an optional ReturnValuesOnConditionCheckFailure argument
+// of the old SDK.
+func toReturnValuesOnFailure(s *string) types.ReturnValuesOnConditionCheckFailure {
+	return types.ReturnValuesOnConditionCheckFailure(aws.StringValue(s))
+}
+
+// toCreateTable converts a CreateTable input of the old SDK.
+func toCreateTable(in *v1.CreateTableInput) *dynamodb.CreateTableInput {
+	out := &dynamodb.CreateTableInput{
+		TableName:   in.TableName,
+		BillingMode: types.BillingMode(aws.StringValue(in.BillingMode)),
+	}
+	for _, d := range in.AttributeDefinitions {
+		out.AttributeDefinitions = append(out.AttributeDefinitions, types.AttributeDefinition{
+			AttributeName: d.AttributeName,
+			AttributeType: types.ScalarAttributeType(aws.StringValue(d.AttributeType)),
+		})
+	}
+	for _, k := range in.KeySchema {
+		out.KeySchema = append(out.KeySchema, types.KeySchemaElement{
+			AttributeName: k.AttributeName,
+			KeyType:       types.KeyType(aws.StringValue(k.KeyType)),
+		})
+	}
+	if p := in.ProvisionedThroughput; p != nil {
+		out.ProvisionedThroughput = &types.ProvisionedThroughput{
+			ReadCapacityUnits:  p.ReadCapacityUnits,
+			WriteCapacityUnits: p.WriteCapacityUnits,
+		}
+	}
+	return out
+}
+
+// fromTable converts a table description of the new SDK.
+func fromTable(t *types.TableDescription) *v1.TableDescription {
+	if t == nil {
+		return nil
+	}
+	return &v1.TableDescription{
+		TableName:      t.TableName,
+		TableStatus:    aws.String(string(t.TableStatus)),
+		ItemCount:      t.ItemCount,
+		TableSizeBytes: t.TableSizeBytes,
+	}
+}
+
+// toTransactItems converts the items of a TransactWriteItems input of the old SDK.
+func toTransactItems(items []*v1.TransactWriteItem) []types.TransactWriteItem {
+	out := make([]types.TransactWriteItem, len(items))
+	for i, item := range items {
+		if u := item.Update; u != nil {
+			out[i].Update = &types.Update{
+				TableName:                 u.TableName,
+				Key:                       toItem(u.Key),
+				UpdateExpression:          u.UpdateExpression,
+				ConditionExpression:       u.ConditionExpression,
+				ExpressionAttributeNames:  toNames(u.ExpressionAttributeNames),
+				ExpressionAttributeValues: toItem(u.ExpressionAttributeValues),
+			}
+		}
+		if p := item.Put; p != nil {
+			out[i].Put = &types.Put{
+				TableName:                 p.TableName,
+				Item:                      toItem(p.Item),
+				ConditionExpression:       p.ConditionExpression,
+				ExpressionAttributeNames:  toNames(p.ExpressionAttributeNames),
+				ExpressionAttributeValues: toItem(p.ExpressionAttributeValues),
+			}
+		}
+		if d := item.Delete; d != nil {
+			out[i].Delete = &types.Delete{
+				TableName:                 d.TableName,
+				Key:                       toItem(d.Key),
+				ConditionExpression:       d.ConditionExpression,
+				ExpressionAttributeNames:  toNames(d.ExpressionAttributeNames),
+				ExpressionAttributeValues: toItem(d.ExpressionAttributeValues),
+			}
+		}
+		if c := item.ConditionCheck; c != nil {
+			out[i].ConditionCheck = &types.ConditionCheck{
+				TableName:                 c.TableName,
+				Key:                       toItem(c.Key),
+				ConditionExpression:       c.ConditionExpression,
+				ExpressionAttributeNames:  toNames(c.ExpressionAttributeNames),
+				ExpressionAttributeValues: toItem(c.ExpressionAttributeValues),
+			}
+		}
+	}
+	return out
+}
@@ -1,6 +1,7 @@
 package lease
 
 import (
+	"encoding/json"
 	"strconv"
 	"time"
 
@@ -21,12 +22,49 @@ type Serializer interface {
 // serializer implement the Serializer interface
 type serializer struct {
 	schemakeys []string
+	// codec used to encode the extra fields.
+	codec Codec
+	// cipher used to encrypt the extra fields. optional.
+	cipher Cipher
+	// compressor used to compress the extra fields above the threshold. optional.
+	compressor Compressor
+	threshold  int
+	// overflow used to store the extra fields above the threshold. optional.
+	overflow          Overflow
+	overflowThreshold int
+	// migrator used to upgrade the leases on read. optional.
+	migrator *Migrator
+	// logger used to warn about items that approach the size limit. optional.
+	logger        Logger
+	sizeThreshold int
+	// now returns the time of the config clock. see: Config.Clock.
+	now func() time.Time
+	// kcl reads and writes the KCL lease schema. see: Config.KCLSchema.
+	kcl bool
 }
 
-func newSerializer() Serializer {
-	return &serializer{
-		schemakeys: []string{LeaseKeyKey, LeaseOwnerKey, LeaseCounterKey},
+func newSerializer(c *Config) Serializer {
+	s := &serializer{
+		schemakeys:        reservedKeys,
+		codec:             c.Codec,
+		cipher:            c.Cipher,
+		compressor:        c.Compressor,
+		threshold:         c.CompressThreshold,
+		overflow:          c.Overflow,
+		overflowThreshold: c.OverflowThreshold,
+		migrator:          c.Migrator,
+		logger:            c.Logger,
+		sizeThreshold:     c.ItemSizeWarnThreshold,
+		now:               c.now,
+		kcl:               c.KCLSchema,
 	}
+	if s.kcl {
+		s.schemakeys = append(append([]string(nil), reservedKeys...), kclKeys...)
+	}
+	if s.codec == nil {
+		s.codec = AttributeCodec{}
+	}
+	return s
 }
 
 func (s *serializer) Decode(item map[string]*dynamodb.AttributeValue) (*Lease, error) {
@@ -34,35 +72,131 @@ func (s *serializer) Decode(item map[string]*dynamodb.AttributeValue) (*Lease, e
 	if err := dynamodbattribute.UnmarshalMap(item, lease); err != nil {
 		return nil, err
 	}
+	lease.size = itemSize(item)
 
-	lease.lastRenewal = time.Now()
+	lease.lastRenewal = s.now()
 	lease.concurrencyToken, _ = uuid()
 
+	if s.kcl {
+		decodeKCL(lease, item)
+	} else if v := item[LeaseCheckpointKey]; v != nil {
+		lease.Checkpoint = aws.StringValue(v.S)
+	}
+	lease.payload = item[LeasePayloadKey]
+
 	// delete all the keys that belong to this package
 	for _, k := range s.schemakeys {
 		delete(item, k)
 	}
+
+	// the extra fields overflowed to external storage, and loaded lazily.
+	if v, ok := item[LeaseOverflowKey]; ok && s.overflow != nil {
+		delete(item, LeaseOverflowKey)
+		pointer := aws.StringValue(v.S)
+		lease.overflow = &overflowRef{
+			pointer: pointer,
+			load: func(lease *Lease) error {
+				p, err := s.overflow.Get(pointer)
+				if err != nil {
+					return err
+				}
+				fields := make(map[string]*dynamodb.AttributeValue)
+				if err := unpackFields(p, fields); err != nil {
+					return err
+				}
+				return s.decodeFields(lease, fields)
+			},
+		}
+	}
+
+	if err := s.decodeFields(lease, item); err != nil {
+		return nil, err
+	}
+
+	// upgrade the lease to the latest schema version.
+	if s.migrator != nil {
+		if err := s.migrator.Migrate(lease); err != nil {
+			return nil, err
+		}
+	}
+	return lease, nil
+}
+
+// decodeFields decrypts and decompresses the packed extra fields in the given item,
+// and set them on the lease object.
+func (s *serializer) decodeFields(lease *Lease, item map[string]*dynamodb.AttributeValue) error {
+	// decrypt the extra fields, and add them to the item.
+	if v, ok := item[LeaseEncryptedKey]; ok && s.cipher != nil {
+		plaintext, err := s.cipher.Decrypt(v.B, []byte(lease.Key))
+		if err != nil {
+			return err
+		}
+		delete(item, LeaseEncryptedKey)
+		if err := unpackFields(plaintext, item); err != nil {
+			return err
+		}
+	}
+
+	// decompress the extra fields, and add them to the item.
+	if v, ok := item[LeaseCompressedKey]; ok && item[LeaseCompressionKey] != nil {
+		name := aws.StringValue(item[LeaseCompressionKey].S)
+		if s.compressor == nil || s.compressor.Name() != name {
+			return ErrUnknownCompression
+		}
+		p, err := s.compressor.Decompress(v.B)
+		if err != nil {
+			return err
+		}
+		delete(item, LeaseCompressedKey)
+		delete(item, LeaseCompressionKey)
+		if err := unpackFields(p, item); err != nil {
+			return err
+		}
+	}
+
+	// keep the unknown attributes apart, to write them back as is.
+	for k, v := range item {
+		if isUnknown(k) {
+			if lease.unknownfields == nil {
+				lease.unknownfields = make(map[string]*dynamodb.AttributeValue)
+			}
+			lease.unknownfields[k] = v
+			delete(item, k)
+		}
+	}
+
 	if len(item) > 0 {
-		var (
-			extrafields    = make(map[string]interface{})
-			explicitfields = make(map[string]*dynamodb.AttributeValue)
-		)
+		if lease.extrafields == nil {
+			lease.extrafields = make(map[string]interface{})
+		}
+		if lease.explicitfields == nil {
+			lease.explicitfields = make(map[string]*dynamodb.AttributeValue)
+		}
 		// set the explicit fields
 		for k, v := range item {
 			if v.SS != nil || v.BS != nil || v.NS != nil {
-				explicitfields[k] = v
+				lease.explicitfields[k] = v
 				delete(item, k)
 			}
 		}
 		// set the extra fields
-		dynamodbattribute.ConvertFromMap(item, &extrafields)
-		lease.extrafields = extrafields
-		lease.explicitfields = explicitfields
+		extrafields, err := s.codec.Decode(item)
+		if err != nil {
+			return err
+		}
+		for k, v := range extrafields {
+			lease.extrafields[k] = v
+		}
 	}
-	return lease, nil
+	return nil
 }
 
 func (s *serializer) Encode(lease *Lease) (map[string]*dynamodb.AttributeValue, error) {
+	// the overflowed extra fields are written again, with the rest of the fields.
+	if err := lease.Load(); err != nil {
+		return nil, err
+	}
+
 	item := map[string]*dynamodb.AttributeValue{
 		LeaseKeyKey: {
 			S: aws.String(lease.Key),
@@ -71,10 +205,153 @@ func (s *serializer) Encode(lease *Lease) (map[string]*dynamodb.AttributeValue,
 			S: aws.String(lease.Owner),
 		},
 		LeaseCounterKey: {
-			N: aws.String(strconv.Itoa(lease.Counter)),
+			N: aws.String(strconv.FormatInt(lease.Counter, 10)),
 		},
 	}
 
+	if lease.OwnerTier != 0 {
+		item[LeaseOwnerTierKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.Itoa(lease.OwnerTier)),
+		}
+	}
+
+	if lease.OwnerVersion != "" {
+		item[LeaseOwnerVersionKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.OwnerVersion),
+		}
+	}
+
+	if lease.OwnerHost != "" {
+		item[LeaseOwnerHostKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.OwnerHost),
+		}
+	}
+
+	if lease.OwnerZone != "" {
+		item[LeaseOwnerZoneKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.OwnerZone),
+		}
+	}
+
+	if lease.TakeoverReason != "" {
+		item[LeaseTakeoverReasonKey] = &dynamodb.AttributeValue{
+			S: aws.String(string(lease.TakeoverReason)),
+		}
+	}
+
+	if lease.Epoch > 0 {
+		item[LeaseEpochKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.Itoa(lease.Epoch)),
+		}
+	}
+
+	if lease.Group != "" {
+		item[LeaseGroupKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.Group),
+		}
+	}
+
+	if lease.PreemptedBy != "" {
+		item[LeasePreemptedByKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.PreemptedBy),
+		}
+	}
+
+	if lease.ReservedBy != "" {
+		item[LeaseReservedByKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.ReservedBy),
+		}
+		item[LeaseReservedUntilKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(lease.ReservedUntil.Unix(), 10)),
+		}
+	}
+
+	// the KCL checkpoint is written with the KCL attributes. see: encodeKCL.
+	if lease.Checkpoint != "" && !s.kcl {
+		item[LeaseCheckpointKey] = &dynamodb.AttributeValue{
+			S: aws.String(lease.Checkpoint),
+		}
+	}
+
+	if !lease.Heartbeat.IsZero() {
+		item[LeaseHeartbeatKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(lease.Heartbeat.Unix(), 10)),
+		}
+	}
+
+	if lease.isTombstoned() {
+		item[LeaseTombstonedAtKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(lease.TombstonedAt.Unix(), 10)),
+		}
+	}
+
+	if len(lease.DependsOn) > 0 {
+		item[LeaseDependsOnKey] = &dynamodb.AttributeValue{
+			SS: aws.StringSlice(lease.DependsOn),
+		}
+	}
+
+	// the payload is stored as is. it's never packed, like the other attributes of this package.
+	if lease.payload != nil {
+		item[LeasePayloadKey] = lease.payload
+	}
+
+	if len(lease.Labels) > 0 {
+		labels, err := dynamodbattribute.Marshal(lease.Labels)
+		if err != nil {
+			return nil, err
+		}
+		item[LeaseLabelsKey] = labels
+	}
+
+	if len(lease.ParentKeys) > 0 {
+		item[LeaseParentKeysKey] = &dynamodb.AttributeValue{
+			SS: aws.StringSlice(lease.ParentKeys),
+		}
+	}
+
+	if lease.isCompleted() {
+		item[LeaseCompletedAtKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(lease.CompletedAt.Unix(), 10)),
+		}
+	}
+
+	if lease.LoadHint != 0 {
+		item[LeaseLoadHintKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatFloat(lease.LoadHint, 'f', -1, 64)),
+		}
+	}
+
+	if len(lease.Holders) > 0 {
+		holders, err := dynamodbattribute.Marshal(lease.Holders)
+		if err != nil {
+			return nil, err
+		}
+		item[LeaseHoldersKey] = holders
+	}
+
+	if lease.Canary {
+		item[LeaseCanaryKey] = &dynamodb.AttributeValue{
+			BOOL: aws.Bool(true),
+		}
+	}
+
+	// the encoded lease is always in the latest schema version.
+	if s.migrator != nil && lease.SchemaVersion < s.migrator.Version() {
+		lease.SchemaVersion = s.migrator.Version()
+	}
+	if lease.SchemaVersion > 0 {
+		item[LeaseSchemaVersionKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.Itoa(lease.SchemaVersion)),
+		}
+	}
+
+	if lease.MaxHolders > 0 {
+		item[LeaseMaxHoldersKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.Itoa(lease.MaxHolders)),
+		}
+	}
+
 	// make sure we remove the keys that belog to this package
 	// and avoid unwanted behavior
 	for _, k := range s.schemakeys {
@@ -88,7 +365,7 @@ func (s *serializer) Encode(lease *Lease) (map[string]*dynamodb.AttributeValue,
 	}
 
 	if len(lease.extrafields) > 0 {
-		if fields, err := dynamodbattribute.MarshalMap(lease.extrafields); err != nil {
+		if fields, err := s.codec.Encode(lease.extrafields); err != nil {
 			return nil, err
 		} else {
 			for k, v := range fields {
@@ -97,5 +374,140 @@ func (s *serializer) Encode(lease *Lease) (map[string]*dynamodb.AttributeValue,
 		}
 	}
 
+	// extra fields cannot override unknown attributes.
+	for k := range item {
+		if isUnknown(k) {
+			delete(item, k)
+		}
+	}
+
+	// make sure that the packing attributes are not set as extra fields.
+	for _, k := range packingKeys {
+		if _, ok := lease.extrafields[k]; ok && (s.cipher != nil || s.compressor != nil) {
+			delete(item, k)
+		}
+	}
+
+	if s.compressor != nil {
+		if err := s.compress(item); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.cipher != nil {
+		if err := s.encrypt(lease.Key, item); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.overflow != nil {
+		if err := s.spill(lease.Key, item); err != nil {
+			return nil, err
+		}
+	}
+
+	// the KCL attributes are never packed.
+	if s.kcl {
+		encodeKCL(lease, item)
+	}
+
+	// the unknown attributes are written back as is.
+	for k, v := range lease.unknownfields {
+		item[k] = v
+	}
+
+	if err := s.checkSize(lease, item); err != nil {
+		return nil, err
+	}
 	return item, nil
 }
+
+// spill replaces all the extra fields in the item (packed or not) with a pointer to
+// external storage that holds them, if their size is above the threshold.
+func (s *serializer) spill(key string, item map[string]*dynamodb.AttributeValue) error {
+	fields, p, err := packFields(item)
+	if err != nil || len(p) <= s.overflowThreshold {
+		return err
+	}
+	pointer, err := s.overflow.Put(key, p)
+	if err != nil {
+		return err
+	}
+	for k := range fields {
+		delete(item, k)
+	}
+	item[LeaseOverflowKey] = &dynamodb.AttributeValue{
+		S: aws.String(pointer),
+	}
+	return nil
+}
+
+// compress replaces all the extra fields in the item with a single binary attribute
+// that holds them compressed, if their size is above the threshold.
+func (s *serializer) compress(item map[string]*dynamodb.AttributeValue) error {
+	fields, p, err := packFields(item)
+	if err != nil || len(p) <= s.threshold {
+		return err
+	}
+	compressed, err := s.compressor.Compress(p)
+	if err != nil {
+		return err
+	}
+	for k := range fields {
+		delete(item, k)
+	}
+	item[LeaseCompressedKey] = &dynamodb.AttributeValue{
+		B: compressed,
+	}
+	item[LeaseCompressionKey] = &dynamodb.AttributeValue{
+		S: aws.String(s.compressor.Name()),
+	}
+	return nil
+}
+
+// encrypt replaces all the extra fields in the item (compressed or not) with a single
+// binary attribute that holds them encrypted.
+func (s *serializer) encrypt(key string, item map[string]*dynamodb.AttributeValue) error {
+	fields, plaintext, err := packFields(item)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+	ciphertext, err := s.cipher.Encrypt(plaintext, []byte(key))
+	if err != nil {
+		return err
+	}
+	for k := range fields {
+		delete(item, k)
+	}
+	item[LeaseEncryptedKey] = &dynamodb.AttributeValue{
+		B: ciphertext,
+	}
+	return nil
+}
+
+// packFields returns the extra fields in the item, and their JSON encoding.
+func packFields(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, []byte, error) {
+	fields := make(map[string]*dynamodb.AttributeValue)
+	for k, v := range item {
+		if !isReserved(k) && !isUnknown(k) {
+			fields[k] = v
+		}
+	}
+	if len(fields) == 0 {
+		return fields, nil, nil
+	}
+	p, err := json.Marshal(fields)
+	return fields, p, err
+}
+
+// unpackFields decodes the given JSON encoding of extra fields, and adds them to the item.
+func unpackFields(p []byte, item map[string]*dynamodb.AttributeValue) error {
+	fields := make(map[string]*dynamodb.AttributeValue)
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return err
+	}
+	for k, v := range fields {
+		item[k] = v
+	}
+	return nil
+}
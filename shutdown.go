@@ -0,0 +1,72 @@
+package lease
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultTerminationGracePeriod is the default Kubernetes terminationGracePeriodSeconds.
+const DefaultTerminationGracePeriod = 30 * time.Second
+
+// drainDeadline returns the portion of the grace period that used to drain the leases.
+// the rest of it is left for stopping the coordinator and the application shutdown.
+func drainDeadline(gracePeriod time.Duration) time.Duration {
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultTerminationGracePeriod
+	}
+	return gracePeriod * 8 / 10
+}
+
+// DrainOnSignal blocks until one of the given signals (defaults to SIGTERM and SIGINT)
+// is received, or until ctx is done. On signal, it drains the leaser within 80% of the
+// given grace period (that should match the pod terminationGracePeriodSeconds) and
+// then stops it. The returned error is the drain error, if any.
+//
+// For example:
+//
+//	go func() {
+//		if err := lease.DrainOnSignal(ctx, leaser, lease.DefaultTerminationGracePeriod); err != nil {
+//			log.WithError(err).Warn("leases were not drained gracefully")
+//		}
+//		cancel()
+//	}()
+func DrainOnSignal(ctx context.Context, leaser Leaser, gracePeriod time.Duration, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	defer signal.Stop(ch)
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	dctx, cancel := context.WithTimeout(context.Background(), drainDeadline(gracePeriod))
+	defer cancel()
+	err := leaser.Drain(dctx)
+	leaser.Stop()
+	return err
+}
+
+// PreStopHandler returns an http.Handler for the Kubernetes preStop httpGet hook.
+// The handler drains the leaser within 80% of the given grace period, and responds
+// when it's done. The coordinator is left to be stopped on SIGTERM (see: DrainOnSignal),
+// that sent once the hook returns.
+func PreStopHandler(leaser Leaser, gracePeriod time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), drainDeadline(gracePeriod))
+		defer cancel()
+		if err := leaser.Drain(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,109 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package lease
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// shutdownLeaser is a Leaser that records the calls of the shutdown path.
+type shutdownLeaser struct {
+	Leaser
+	calls    []string
+	deadline time.Duration
+	err      error
+}
+
+func (l *shutdownLeaser) Drain(ctx context.Context) error {
+	l.calls = append(l.calls, "drain")
+	if d, ok := ctx.Deadline(); ok {
+		l.deadline = time.Until(d)
+	}
+	if l.err != nil {
+		return l.err
+	}
+	return ctx.Err()
+}
+
+func (l *shutdownLeaser) Stop() {
+	l.calls = append(l.calls, "stop")
+}
+
+// signalUntil sends SIGUSR1 to the test process until DrainOnSignal returns, since it
+// may not wait for the signal yet.
+func signalUntil(done <-chan error) error {
+	for {
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	assert(t, drainDeadline(0) == 24*time.Second, "expect the default grace period")
+	assert(t, drainDeadline(10*time.Second) == 8*time.Second, "expect 80% of the grace period")
+}
+
+func TestDrainOnSignal(t *testing.T) {
+	// keep the test process alive on the signals that are sent before DrainOnSignal waits.
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	defer signal.Stop(ch)
+
+	leaser := &shutdownLeaser{}
+	done := make(chan error, 1)
+	go func() {
+		done <- DrainOnSignal(context.Background(), leaser, 10*time.Second, syscall.SIGUSR1)
+	}()
+	err := signalUntil(done)
+	assert(t, err == nil, "expect the drain not to fail")
+	assert(t, len(leaser.calls) == 2 && leaser.calls[0] == "drain" && leaser.calls[1] == "stop", "expect to drain and then stop")
+	assert(t, leaser.deadline > 7*time.Second && leaser.deadline <= 8*time.Second, "expect to drain within 80% of the grace period")
+
+	// the context is done before a signal is received.
+	leaser = &shutdownLeaser{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = DrainOnSignal(ctx, leaser, time.Second, syscall.SIGUSR1)
+	assert(t, errors.Is(err, context.Canceled), "expect the error of the context")
+	assert(t, len(leaser.calls) == 0, "expect not to drain or stop without a signal")
+
+	// the drain error is returned, and the leaser is stopped anyway.
+	leaser = &shutdownLeaser{err: errors.New("drain failed")}
+	go func() {
+		done <- DrainOnSignal(context.Background(), leaser, time.Second, syscall.SIGUSR1)
+	}()
+	err = signalUntil(done)
+	assert(t, err == leaser.err, "expect the drain error")
+	assert(t, len(leaser.calls) == 2 && leaser.calls[1] == "stop", "expect to stop after a failed drain")
+}
+
+func TestPreStopHandler(t *testing.T) {
+	leaser := &shutdownLeaser{}
+	w := httptest.NewRecorder()
+	PreStopHandler(leaser, 10*time.Second).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/prestop", nil))
+	assert(t, w.Code == http.StatusOK, "expect the hook to succeed")
+	assert(t, len(leaser.calls) == 1 && leaser.calls[0] == "drain", "expect to drain, and leave the stop to the signal")
+	assert(t, leaser.deadline > 7*time.Second && leaser.deadline <= 8*time.Second, "expect to drain within 80% of the grace period")
+
+	// the request is cancelled (e.g: the kubelet gave up on the hook).
+	leaser = &shutdownLeaser{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	PreStopHandler(leaser, 10*time.Second).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/prestop", nil).WithContext(ctx))
+	assert(t, w.Code == http.StatusInternalServerError, "expect the hook to fail if the request is cancelled")
+	assert(t, len(leaser.calls) == 1, "expect not to stop the leaser")
+}
@@ -0,0 +1,100 @@
+package lease
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// MaxItemSize is the maximum size (in bytes) of a DynamoDB item.
+const MaxItemSize = 400 << 10
+
+// ErrItemTooLarge error will be returns if the encoded lease exceeds the DynamoDB
+// item size limit. It's wrapped by ItemSizeError.
+var ErrItemTooLarge = errors.New("leaser: lease item is too large")
+
+// ItemSizeError error will be returns if the encoded lease exceeds MaxItemSize. The write
+// is rejected before it's sent to DynamoDB. Use Config.Overflow or Config.Compressor to
+// reduce the size of large extra fields.
+type ItemSizeError struct {
+	Key  string
+	Size int
+}
+
+func (e *ItemSizeError) Error() string {
+	return fmt.Sprintf("leaser: lease %q item size %d exceeds the limit of %d bytes", e.Key, e.Size, MaxItemSize)
+}
+
+func (e *ItemSizeError) Unwrap() error { return ErrItemTooLarge }
+
+// checkSize records the size of the encoded lease item, warns if it's above the
+// threshold, and fails if it's above the DynamoDB limit.
+func (s *serializer) checkSize(lease *Lease, item map[string]*dynamodb.AttributeValue) error {
+	size := itemSize(item)
+	if size > MaxItemSize {
+		return &ItemSizeError{Key: lease.Key, Size: size}
+	}
+	lease.size = size
+	if s.logger != nil && s.sizeThreshold > 0 && size > s.sizeThreshold {
+		s.logger.Warnf("Lease %s item size %d bytes approaches the limit of %d bytes", lease.Key, size, MaxItemSize)
+	}
+	return nil
+}
+
+// itemSize returns the size of the given item, as DynamoDB calculates it: the sum of
+// the lengths of the attribute names and the sizes of their values.
+func itemSize(item map[string]*dynamodb.AttributeValue) (n int) {
+	for k, v := range item {
+		n += len(k) + attributeSize(v)
+	}
+	return
+}
+
+// attributeSize returns the size of the given attribute value. List and map values have
+// an overhead of 3 bytes, plus 1 byte for each element.
+func attributeSize(v *dynamodb.AttributeValue) (n int) {
+	switch {
+	case v == nil:
+	case v.S != nil:
+		n = len(*v.S)
+	case v.N != nil:
+		n = numberSize(*v.N)
+	case v.B != nil:
+		n = len(v.B)
+	case v.BOOL != nil, v.NULL != nil:
+		n = 1
+	case v.SS != nil:
+		for _, s := range v.SS {
+			n += len(*s)
+		}
+	case v.NS != nil:
+		for _, s := range v.NS {
+			n += numberSize(*s)
+		}
+	case v.BS != nil:
+		for _, b := range v.BS {
+			n += len(b)
+		}
+	case v.L != nil:
+		n = 3
+		for _, e := range v.L {
+			n += 1 + attributeSize(e)
+		}
+	case v.M != nil:
+		n = 3
+		for k, e := range v.M {
+			n += 1 + len(k) + attributeSize(e)
+		}
+	}
+	return
+}
+
+// numberSize returns the size of a number attribute: 1 byte plus 1 byte for each
+// 2 significant digits, up to 21 bytes.
+func numberSize(s string) int {
+	s = strings.TrimLeft(strings.TrimPrefix(s, "-"), "0")
+	digits := len(strings.Replace(s, ".", "", 1))
+	return min((digits+1)/2+1, 21)
+}
@@ -0,0 +1,39 @@
+package lease
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestItemSize(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"leaseKey":     {S: aws.String("foo")},
+		"leaseCounter": {N: aws.String("12345")},
+		"done":         {BOOL: aws.Bool(true)},
+		"tags":         {L: []*dynamodb.AttributeValue{{S: aws.String("a")}, {S: aws.String("bc")}}},
+	}
+	// 8+3, 12+4, 4+1, 4+(3+2+3)
+	assert(t, itemSize(item) == 44, "expect the item size to be calculated as DynamoDB does")
+}
+
+func TestSerializerItemSize(t *testing.T) {
+	s := newSerializer(&Config{ItemSizeWarnThreshold: 1 << 10})
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	lease.Set("status", "done")
+	item, err := s.Encode(lease)
+	assert(t, err == nil, "expect Encode not to fail")
+	assert(t, lease.Size() == itemSize(item), "expect the item size to be recorded")
+
+	decoded, err := s.Decode(item)
+	assert(t, err == nil && decoded.Size() == lease.Size(), "expect the item size to be recorded on read")
+
+	lease.Set("checkpoint", strings.Repeat("a", MaxItemSize))
+	_, err = s.Encode(lease)
+	var serr *ItemSizeError
+	assert(t, errors.As(err, &serr) && serr.Key == "foo" && serr.Size > MaxItemSize, "expect Encode to fail with ItemSizeError")
+	assert(t, errors.Is(err, ErrItemTooLarge), "expect the error to wrap ErrItemTooLarge")
+}
@@ -0,0 +1,142 @@
+package lease
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// stealBudget returns the given leases to take, without the steals that don't fit into
+// the fleet-wide steal budget. The expired and ownerless leases are not steals, and they
+// are not charged. A steal is charged a token for each lease it takes from its owner,
+// i.e: for each live lease of its group (see: stealCost). nothing is stolen if the budget
+// is unavailable.
+func (l *leaseTaker) stealBudget(ctx context.Context, list []*Lease) []*Lease {
+	if l.StealRate <= 0 || len(list) == 0 {
+		return list
+	}
+	var (
+		free, steals []*Lease
+		costs        []int
+		total        int
+	)
+	for _, lease := range list {
+		if l.isAvailable(lease) {
+			free = append(free, lease)
+			continue
+		}
+		cost := l.stealCost(lease)
+		steals = append(steals, lease)
+		costs = append(costs, cost)
+		total += cost
+	}
+	if len(steals) == 0 {
+		return list
+	}
+	n, err := l.manager.AcquireStealBudget(ctx, total)
+	if err != nil {
+		l.Logger.WithError(err).Warnf("Worker %s failed to acquire steal budget", l.WorkerId)
+		return free
+	}
+	if n < total {
+		l.Logger.Debugf("Worker %s steal budget allows %d of %d leases", l.WorkerId, n, total)
+	}
+	for i, lease := range steals {
+		if costs[i] > n {
+			break
+		}
+		n -= costs[i]
+		free = append(free, lease)
+	}
+	return free
+}
+
+// AcquireStealBudget takes up to n tokens from the fleet-wide steal budget, and returns
+// the number of tokens it took. The budget is a token bucket stored in the lease table
+// under StealBudgetKey, that refills at Config.StealRate tokens per second, up to
+// Config.StealBurst tokens. The bucket update is conditional on the last refill time,
+// so concurrent workers never spend the same tokens.
+func (l *LeaseManager) AcquireStealBudget(ctx context.Context, n int) (int, error) {
+	key := map[string]*dynamodb.AttributeValue{
+		LeaseKeyKey: {
+			S: aws.String(StealBudgetKey),
+		},
+	}
+	for i := 0; i < maxUpdateRetries; i++ {
+		out, err := l.Client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(l.LeaseTable),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return 0, l.wrapError("acquire steal budget", StealBudgetKey, err)
+		}
+		now := l.now()
+		tokens, refilled, exists := readBucket(out.Item)
+		if exists {
+			elapsed := math.Max(0, now.Sub(time.Unix(0, refilled*int64(time.Millisecond))).Seconds())
+			tokens = math.Min(float64(l.StealBurst), tokens+elapsed*l.StealRate)
+		} else {
+			tokens = float64(l.StealBurst)
+		}
+		taken := min(n, int(tokens))
+		if taken == 0 {
+			return 0, nil
+		}
+		input := &dynamodb.PutItemInput{
+			TableName: aws.String(l.LeaseTable),
+			Item: map[string]*dynamodb.AttributeValue{
+				LeaseKeyKey: {
+					S: aws.String(StealBudgetKey),
+				},
+				LeaseStealTokensKey: {
+					N: aws.String(strconv.FormatFloat(tokens-float64(taken), 'f', -1, 64)),
+				},
+				LeaseStealRefilledAtKey: {
+					N: aws.String(strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)),
+				},
+			},
+			ExpressionAttributeNames: map[string]*string{
+				"#refilled": aws.String(LeaseStealRefilledAtKey),
+			},
+			ConditionExpression: aws.String("attribute_not_exists(#refilled)"),
+		}
+		if exists {
+			input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+				":condRefilled": {
+					N: aws.String(strconv.FormatInt(refilled, 10)),
+				},
+			}
+			input.ConditionExpression = aws.String("#refilled = :condRefilled")
+		}
+		if _, err = l.Client.PutItemWithContext(ctx, input); err == nil {
+			return taken, nil
+		}
+		if !isConditionalFailed(err) {
+			return 0, l.wrapError("acquire steal budget", StealBudgetKey, err)
+		}
+		// another worker spent tokens concurrently. re-read the bucket.
+	}
+	return 0, nil
+}
+
+// readBucket returns the number of tokens and the last refill time (unix milliseconds)
+// of the given steal budget item, and a boolean that indicates if the bucket exists.
+func readBucket(item map[string]*dynamodb.AttributeValue) (tokens float64, refilled int64, ok bool) {
+	t, r := item[LeaseStealTokensKey], item[LeaseStealRefilledAtKey]
+	if t == nil || t.N == nil || r == nil || r.N == nil {
+		return 0, 0, false
+	}
+	var err error
+	if tokens, err = strconv.ParseFloat(*t.N, 64); err != nil {
+		return 0, 0, false
+	}
+	if refilled, err = strconv.ParseInt(*r.N, 10, 64); err != nil {
+		return 0, 0, false
+	}
+	return tokens, refilled, true
+}
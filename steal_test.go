@@ -0,0 +1,97 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestAcquireStealBudget(t *testing.T) {
+	bucket := func(tokens string, refilled time.Time) *dynamodb.GetItemOutput {
+		return &dynamodb.GetItemOutput{
+			Item: map[string]*dynamodb.AttributeValue{
+				LeaseKeyKey:             {S: aws.String(StealBudgetKey)},
+				LeaseStealTokensKey:     {N: aws.String(tokens)},
+				LeaseStealRefilledAtKey: {N: aws.String(strconv.FormatInt(refilled.UnixNano()/int64(time.Millisecond), 10))},
+			},
+		}
+	}
+	client := newClientMock(map[method]args{
+		methodGetItem: {
+			// the bucket does not exist
+			&dynamodb.GetItemOutput{},
+			// the bucket is empty
+			bucket("0", time.Now()),
+			// the bucket refilled concurrently
+			bucket("0", time.Now().Add(-time.Second)),
+			bucket("0", time.Now().Add(-2*time.Second)),
+		},
+		methodPutItem: {
+			&dynamodb.PutItemOutput{},
+			awserr.New(ConditionalFailed, "", errors.New("")),
+			&dynamodb.PutItemOutput{},
+		},
+	})
+	manager := newTestManager(client)
+	manager.StealRate = 1
+	manager.StealBurst = 2
+
+	n, err := manager.AcquireStealBudget(context.Background(), 5)
+	assert(t, err == nil && n == 2, "expect to take the full bucket")
+
+	n, err = manager.AcquireStealBudget(context.Background(), 5)
+	assert(t, err == nil && n == 0, "expect the bucket to be empty")
+	assert(t, client.calls[methodPutItem] == 1, "expect not to write an empty bucket")
+
+	n, err = manager.AcquireStealBudget(context.Background(), 5)
+	assert(t, err == nil && n == 2, "expect to retry after conditional failure")
+	assert(t, client.calls[methodGetItem] == 4, "expect to re-read the bucket")
+}
+
+func TestTakerStealBudget(t *testing.T) {
+	logger := testLogger()
+	var leases []*Lease
+	for _, key := range []string{"a", "b", "c", "d"} {
+		leases = append(leases, &Lease{Key: key, Owner: "4", Counter: 1, lastRenewal: time.Now()})
+	}
+	manager := newManagerMock(map[method]args{
+		methodList:        {leases},
+		methodStealBudget: {1, 0},
+		methodTake:        {nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 2,
+			StealRate:                 1,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodStealBudget] == 1, "expect to acquire the steal budget")
+	assert(t, manager.calls[methodTake] == 1, "expect to steal within the budget")
+
+	// the expired leases are not charged.
+	expired := &Lease{Key: "e", Owner: "4", Counter: 1, lastRenewal: time.Now().Add(-2 * time.Minute)}
+	list := taker.stealBudget(context.Background(), []*Lease{expired})
+	assert(t, len(list) == 1 && manager.calls[methodStealBudget] == 1, "expect not to charge the expired leases")
+	list = taker.stealBudget(context.Background(), []*Lease{leases[0], expired})
+	assert(t, len(list) == 1 && list[0] == expired, "expect to take the expired leases without budget")
+
+	// a group steal is charged for each live lease of the group.
+	g1 := &Lease{Key: "g1", Owner: "4", Group: "g", Counter: 1, lastRenewal: time.Now()}
+	g2 := &Lease{Key: "g2", Owner: "4", Group: "g", Counter: 1, lastRenewal: time.Now()}
+	taker.allLeases["g1"], taker.allLeases["g2"] = g1, g2
+	assert(t, taker.stealCost(g1) == 2 && taker.stealCost(leases[0]) == 1, "expect to charge each stolen lease of the group")
+	manager.result[methodStealBudget] = args{1, 0, 2}
+	list = taker.stealBudget(context.Background(), []*Lease{g1, leases[0]})
+	assert(t, len(list) == 1 && list[0] == g1, "expect the group steal to use 2 tokens")
+}
@@ -0,0 +1,64 @@
+package lease
+
+import "time"
+
+// TakeoverStorm describes an abnormal spike in ownership changes. See: Config.StormPercent.
+type TakeoverStorm struct {
+	// Takeovers is the number of ownership changes within the window.
+	Takeovers int
+	// Leases is the number of leases seen on the last scan.
+	Leases int
+	// Window is the window the ownership changes were counted in.
+	Window time.Duration
+	// Reasons counts the ownership changes within the window by their takeover reason.
+	Reasons map[TakeoverReason]int
+}
+
+// takeover is an ownership change that was seen by the taker.
+type takeover struct {
+	at     time.Time
+	reason TakeoverReason
+}
+
+// detectStorm records the ownership changes between the given list of leases and the
+// previous scan, and reports a takeover storm if there are too many of them within the
+// storm window. a change to no owner (i.e: eviction) is not a takeover.
+func (l *leaseTaker) detectStorm(list []*Lease) {
+	if l.StormPercent <= 0 {
+		return
+	}
+	now := l.now()
+	owners := make(map[string]string, len(list))
+	for _, lease := range list {
+		owners[lease.Key] = lease.Owner
+		if owner, ok := l.owners[lease.Key]; ok && owner != lease.Owner && !lease.hasNoOwner() {
+			l.takeovers = append(l.takeovers, takeover{at: now, reason: lease.TakeoverReason})
+		}
+	}
+	l.owners = owners
+
+	// drop the ownership changes that are out of the window.
+	i := 0
+	for i < len(l.takeovers) && now.Sub(l.takeovers[i].at) > l.StormWindow {
+		i++
+	}
+	l.takeovers = l.takeovers[i:]
+
+	n := len(l.takeovers)
+	if len(list) == 0 || float64(n)*100 <= l.StormPercent*float64(len(list)) || now.Sub(l.lastStorm) < l.StormWindow {
+		return
+	}
+	l.lastStorm = now
+	reasons := make(map[TakeoverReason]int)
+	for _, t := range l.takeovers {
+		reasons[t.reason]++
+	}
+	l.Logger.WithField("takeovers", n).WithField("reasons", reasons).Warnf("Worker %s detected a takeover storm: %d of %d leases changed owner within %s",
+		l.WorkerId,
+		n,
+		len(list),
+		l.StormWindow)
+	if l.OnTakeoverStorm != nil {
+		l.OnTakeoverStorm(TakeoverStorm{Takeovers: n, Leases: len(list), Window: l.StormWindow, Reasons: reasons})
+	}
+}
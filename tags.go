@@ -0,0 +1,84 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrTagTableUnsupported error will be returns if the tags of the lease table are
+// reconciled with a client that does not support tagging tables.
+var ErrTagTableUnsupported = errors.New("leaser: client does not support tagging tables")
+
+// tableTagger is implemented by the clients that support tagging tables, e.g: *dynamodb.DynamoDB.
+type tableTagger interface {
+	ListTagsOfResourceWithContext(aws.Context, *dynamodb.ListTagsOfResourceInput, ...request.Option) (*dynamodb.ListTagsOfResourceOutput, error)
+	TagResourceWithContext(aws.Context, *dynamodb.TagResourceInput, ...request.Option) (*dynamodb.TagResourceOutput, error)
+}
+
+// tableTags returns the Config.LeaseTableTags as DynamoDB tags, sorted by key.
+func (c *Config) tableTags() []*dynamodb.Tag {
+	tags := make([]*dynamodb.Tag, 0, len(c.LeaseTableTags))
+	for k, v := range c.LeaseTableTags {
+		tags = append(tags, &dynamodb.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return *tags[i].Key < *tags[j].Key
+	})
+	return tags
+}
+
+// ReconcileTags applies the Config.LeaseTableTags to an existing lease table, e.g: a
+// table that was created before the tags were configured. Tags that are missing or have
+// a different value are set; other tags of the table are left as is. Fails with
+// ErrTagTableUnsupported if the client does not support tagging tables.
+func (l *LeaseManager) ReconcileTags(ctx context.Context) error {
+	if len(l.LeaseTableTags) == 0 {
+		return nil
+	}
+	client, ok := l.Client.(tableTagger)
+	if !ok {
+		return l.wrapError("tag table", "", ErrTagTableUnsupported)
+	}
+	desc, err := l.Client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(l.LeaseTable),
+	})
+	if err != nil {
+		return l.wrapError("tag table", "", err)
+	}
+	arn := desc.Table.TableArn
+	current := make(map[string]string)
+	input := &dynamodb.ListTagsOfResourceInput{ResourceArn: arn}
+	for {
+		out, err := client.ListTagsOfResourceWithContext(ctx, input)
+		if err != nil {
+			return l.wrapError("tag table", "", err)
+		}
+		for _, tag := range out.Tags {
+			current[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	var tags []*dynamodb.Tag
+	for _, tag := range l.tableTags() {
+		if v, ok := current[*tag.Key]; !ok || v != *tag.Value {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	l.Logger.WithField("table name", l.LeaseTable).Infof("Worker %s tags the lease table with %d tags", l.WorkerId, len(tags))
+	_, err = client.TagResourceWithContext(ctx, &dynamodb.TagResourceInput{
+		ResourceArn: arn,
+		Tags:        tags,
+	})
+	return l.wrapError("tag table", "", err)
+}
@@ -0,0 +1,79 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// taggerMock is a clientMock that supports tagging tables.
+type taggerMock struct {
+	*clientMock
+	tags   []*dynamodb.Tag
+	tagged []*dynamodb.Tag
+}
+
+func (c *taggerMock) ListTagsOfResourceWithContext(_ aws.Context, input *dynamodb.ListTagsOfResourceInput, _ ...request.Option) (*dynamodb.ListTagsOfResourceOutput, error) {
+	// returns a tag per page.
+	i := 0
+	if input.NextToken != nil {
+		i = int(aws.StringValue(input.NextToken)[0] - '0')
+	}
+	out := &dynamodb.ListTagsOfResourceOutput{}
+	if i < len(c.tags) {
+		out.Tags = c.tags[i : i+1]
+	}
+	if i+1 < len(c.tags) {
+		out.NextToken = aws.String(string(rune('0' + i + 1)))
+	}
+	return out, nil
+}
+
+func (c *taggerMock) TagResourceWithContext(_ aws.Context, input *dynamodb.TagResourceInput, _ ...request.Option) (*dynamodb.TagResourceOutput, error) {
+	c.tagged = append(c.tagged, input.Tags...)
+	return new(dynamodb.TagResourceOutput), nil
+}
+
+func TestCreateTableTags(t *testing.T) {
+	manager := newTestManager(nil)
+	input := manager.createTableInput()
+	assert(t, input.Tags == nil, "expect no tags by default")
+
+	manager.LeaseTableTags = map[string]string{"team": "infra", "env": "prod"}
+	input = manager.createTableInput()
+	assert(t, len(input.Tags) == 2, "expect the table to be tagged")
+	assert(t, *input.Tags[0].Key == "env" && *input.Tags[1].Key == "team", "expect the tags to be sorted by key")
+}
+
+func TestReconcileTags(t *testing.T) {
+	client := &taggerMock{
+		clientMock: newClientMock(map[method]args{
+			methodDescribeTable: {
+				&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+					TableArn: aws.String("arn:aws:dynamodb:us-east-1:123456789012:table/test"),
+				}},
+			},
+		}),
+		tags: []*dynamodb.Tag{
+			{Key: aws.String("team"), Value: aws.String("infra")},
+			{Key: aws.String("env"), Value: aws.String("staging")},
+			{Key: aws.String("owner"), Value: aws.String("someone")},
+		},
+	}
+	manager := newTestManager(client)
+	manager.LeaseTableTags = map[string]string{"team": "infra", "env": "prod", "cost-center": "42"}
+
+	err := manager.ReconcileTags(context.Background())
+	assert(t, err == nil, "expect not to fail")
+	assert(t, len(client.tagged) == 2, "expect only the missing and changed tags to be set")
+	assert(t, *client.tagged[0].Key == "cost-center" && *client.tagged[1].Key == "env", "expect the missing and changed tags to be set")
+	assert(t, *client.tagged[1].Value == "prod", "expect the changed tag to be updated")
+
+	manager.Client = client.clientMock
+	err = manager.ReconcileTags(context.Background())
+	assert(t, errors.Is(err, ErrTagTableUnsupported), "expect to fail with clients that can't tag tables")
+}
@@ -0,0 +1,75 @@
+package lease
+
+import "context"
+
+// TakeoverReason describes why the last ownership change of a lease happened. It's
+// persisted on the lease when it's taken. See: Lease.TakeoverReason and Config.OnTakeover.
+type TakeoverReason string
+
+const (
+	// TakeoverExpired means the previous owner failed to renew the lease.
+	TakeoverExpired TakeoverReason = "expired"
+	// TakeoverStolen means the lease was stolen from a more loaded worker for balancing.
+	TakeoverStolen TakeoverReason = "stolen"
+	// TakeoverHandoff means the previous owner released the lease (e.g: on shutdown,
+	// rotation or preemption), or that the lease never had an owner.
+	TakeoverHandoff TakeoverReason = "handoff"
+	// TakeoverForced means the lease was taken regardless of the balance, from a worker
+	// of an older version. See: Config.VersionTakeoverRate.
+	TakeoverForced TakeoverReason = "forced"
+)
+
+// Takeover describes a lease that was taken by this worker. See: Config.OnTakeover.
+type Takeover struct {
+	Key string
+	// From is the previous owner of the lease. empty if the lease had no owner.
+	From   string
+	Reason TakeoverReason
+}
+
+// takeoverReason returns the reason to take the given lease for balancing. leases that
+// were not renewed since the last scans are expired, even if they were already evicted.
+func (l *leaseTaker) takeoverReason(lease *Lease) TakeoverReason {
+	switch {
+	case lease.isExpired(l.expireAfter(lease.Key), l.now()):
+		return TakeoverExpired
+	case lease.hasNoOwner():
+		return TakeoverHandoff
+	}
+	return TakeoverStolen
+}
+
+// takeLeases takes the given leases for the given reason, and reports the takeovers
+// to Config.OnTakeover. a lease group is taken in a single transaction.
+func (l *leaseTaker) takeLeases(ctx context.Context, list []*Lease, reason TakeoverReason) (err error) {
+	owners := make([]string, len(list))
+	for i, lease := range list {
+		l.cache.invalidate(lease.Key)
+		lease.takeReason = reason
+		if !lease.hasNoOwner() {
+			owners[i] = lease.Owner
+		}
+	}
+	start := l.now()
+	if len(list) == 1 && list[0].Group == "" {
+		err = l.manager.TakeLease(ctx, list[0])
+	} else {
+		err = l.manager.TakeLeases(ctx, list)
+	}
+	for _, lease := range list {
+		l.journal.record(lease.Key, "take", start, err, false)
+		l.throttle.record(err)
+	}
+	if err != nil {
+		return
+	}
+	for i, lease := range list {
+		takeover := Takeover{Key: lease.Key, From: owners[i], Reason: reason}
+		if l.OnTakeover != nil {
+			l.OnTakeover(takeover)
+		}
+		l.scopes.takeover(takeover, lease)
+		l.ownerChange(OwnerChange{Key: lease.Key, Type: OwnerAcquired, From: owners[i], To: l.WorkerId, Reason: reason}, lease)
+	}
+	return
+}
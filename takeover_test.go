@@ -0,0 +1,65 @@
+package lease
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestTakeoverReason(t *testing.T) {
+	manager := newTestManager(newClientMock(nil))
+	lease := &Lease{Key: "foo", Counter: 1, Owner: "o1", takeReason: TakeoverStolen}
+	taken := manager.takenLease(lease)
+	assert(t, taken.TakeoverReason == TakeoverStolen, "expect the reason to be set on take")
+	input := manager.condUpdateInput(taken, *lease)
+	assert(t, strings.Contains(aws.StringValue(input.UpdateExpression), LeaseTakeoverReasonKey+" = :reason"), "expect the reason to be persisted")
+
+	evicted := taken
+	evicted.Owner = "NULL"
+	input = manager.condUpdateInput(evicted, taken)
+	assert(t, !strings.Contains(aws.StringValue(input.UpdateExpression), LeaseTakeoverReasonKey), "expect the reason to be kept on eviction")
+}
+
+func TestLeaseEpoch(t *testing.T) {
+	manager := newTestManager(newClientMock(nil))
+	lease := &Lease{Key: "foo", Counter: 7, Epoch: 2, Owner: "o1"}
+	taken := manager.takenLease(lease)
+	assert(t, taken.Epoch == 3 && taken.Counter == 8, "expect the epoch to be incremented on take")
+	input := manager.condUpdateInput(taken, *lease)
+	assert(t, strings.Contains(aws.StringValue(input.UpdateExpression), LeaseEpochKey+" = :epoch"), "expect the epoch to be persisted")
+
+	renewed := taken
+	renewed.Counter++
+	input = manager.condUpdateInput(renewed, taken)
+	assert(t, !strings.Contains(aws.StringValue(input.UpdateExpression), LeaseEpochKey), "expect the epoch to be kept on renewal")
+}
+
+func TestTakerTakeoverReasons(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{
+			{Key: "a", Owner: "4", lastRenewal: time.Now().Add(-time.Hour)},
+			{Key: "b", Owner: "NULL", lastRenewal: time.Now()},
+			{Key: "c", Owner: "4", lastRenewal: time.Now()},
+		}},
+		methodTake: {nil, nil},
+	})
+	takeovers := make(map[string]Takeover)
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+			OnTakeover:                func(t Takeover) { takeovers[t.Key] = t },
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, len(takeovers) == 2, "expect to report the takeovers")
+	assert(t, takeovers["a"].Reason == TakeoverExpired && takeovers["a"].From == "4", "expect the lease to be taken as expired")
+	assert(t, takeovers["b"].Reason == TakeoverHandoff && takeovers["b"].From == "", "expect the lease to be taken as handoff")
+}
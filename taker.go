@@ -1,13 +1,21 @@
 package lease
 
-import "math/rand"
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
 
-// Taker is the interface that wraps the Take method.
+// Taker is the interface that wraps the Take and Lookup methods.
 // It  used by Coordinator to take new leases, or leases that other workers fail to renew.
 // Each Coordinator instance corresponds to one worker and uses exactly one Taker to take
 // leases for that worker.
 type Taker interface {
-	Take() error
+	Take(context.Context) error
+	Lookup(key string) (Lease, bool)
+	Leases() []Lease
 }
 
 // An implementation of Taker that uses DynamoDB via LeaseManager
@@ -17,6 +25,22 @@ type leaseTaker struct {
 
 	// leaseTaker state
 	allLeases map[string]*Lease
+	rotations *rotations
+	// cache is invalidated for the leases that this worker takes or evicts. see: Coordinator.Get.
+	cache *leaseCache
+	// journal records the outcomes of the takes. see: Coordinator.Stats.
+	journal *journal
+	// throttle pauses the takes while the writes are throttled. see: Coordinator.Throttled.
+	throttle *throttle
+
+	// view is a snapshot of allLeases for lookups from other goroutines. see: Lookup.
+	mu   sync.RWMutex
+	view map[string]Lease
+
+	// takeover storm detection state. see: detectStorm.
+	owners    map[string]string
+	takeovers []takeover
+	lastStorm time.Time
 }
 
 // Compute the set of leases available to be taken and attempt to take them. Lease taking process is:
@@ -24,22 +48,79 @@ type leaseTaker struct {
 // 1) If a lease's counter hasn't changed in long enough(i.e: "expired") set its owner to null.
 // 2) Compute the "leases per worker" and the number we should take.
 // 3) If we need to take leases, try to take expired leases. if there are no expired leases, consider stealing.
-func (l *leaseTaker) Take() error {
-	list, err := l.manager.ListLeases()
+func (l *leaseTaker) Take(ctx context.Context) error {
+	list, err := l.manager.ScanLeases(ctx)
 	if err != nil {
 		return err
 	}
 
-	l.updateLeases(list)
+	// tombstoned leases are never taken.
+	l.purgeTombstones(ctx, list)
+	list = liveLeases(list)
+	list = l.readyLeases(list)
+	list = pendingLeases(list)
+
+	// consider only the leases that belong to our pool (canary or not), and that are
+	// not denied to this worker.
+	list = l.filterPool(list)
+	list = l.filterDenied(list)
+	l.detectStorm(list)
+	l.updateLeases(ctx, list)
+
+	// the fleet is paused for maintenance. the held leases are still renewed.
+	if mode := l.maintenance.active(l.now()); mode != nil {
+		l.Logger.Debugf("Worker %s does not take leases while the fleet is paused for maintenance: %s", l.WorkerId, mode.Reason)
+		return nil
+	}
+
+	// leave the write capacity to the renewals while the writes are throttled.
+	if l.throttle.check() {
+		l.Logger.Debugf("Worker %s does not take leases while the writes are throttled", l.WorkerId)
+		return nil
+	}
+
+	// progressively steal leases from workers of older version, within the steal budget.
+	for _, lease := range l.stealBudget(ctx, l.filterQuota(l.getHeldLeases(), l.chooseLeasesToUpgrade())) {
+		owner := lease.Owner
+		if err := l.takeLease(ctx, lease, TakeoverForced); err != nil {
+			l.Logger.WithError(err).Debugf("Worker %s could not take lease with key %s from older version worker %s.",
+				l.WorkerId,
+				lease.Key,
+				owner)
+		} else {
+			l.Logger.Debugf("Worker %s took lease %s from older version worker %s.", l.WorkerId, lease.Key, owner)
+		}
+	}
+
+	// balance the leases of the namespaces that have a profile separately.
+	if len(l.Profiles) > 0 {
+		l.balanceProfiles(ctx)
+		return nil
+	}
+	l.balance(ctx)
+	return nil
+}
+
+// balance computes the number of leases this worker should take, and attempts to take
+// them. expired leases are taken first. if there are no expired leases, consider preempting
+// or stealing.
+func (l *leaseTaker) balance(ctx context.Context) {
+	// balance the total load of the workers, rather than their number of leases.
+	if l.BalanceByLoad {
+		l.takeByLoad(ctx)
+		return
+	}
 
 	leaseCounts := l.computeLeaseCounts()
 	numWorkers := len(leaseCounts)
+	// leases that held in shared mode, or semaphore leases cannot be taken.
+	numLeases := len(l.allLeases) - len(l.getSharedLeases())
 	// assuming numLeases <= numWorkers
 	target := 1
 	// our target for each worker is numLeases / numWorkers (+1 if numWorkers doesn't evenly divide numLeases)
-	if len(l.allLeases) > numWorkers {
-		target = len(l.allLeases) / numWorkers
-		if len(list)%numWorkers != 0 {
+	if numLeases > numWorkers {
+		target = numLeases / numWorkers
+		if numLeases%numWorkers != 0 {
 			target++
 		}
 	}
@@ -52,28 +133,45 @@ func (l *leaseTaker) Take() error {
 			l.WorkerId,
 			myCount,
 			target)
-		return nil
+		return
 	}
 
-	var leasesToTake []*Lease
-	expiredLeases := l.getExpiredLeases()
+	var (
+		leasesToTake  []*Lease
+		heldLeases    = l.getHeldLeases()
+		expiredLeases = l.filterQuota(heldLeases, l.getExpiredLeases())
+	)
 
 	if len(expiredLeases) > 0 {
-		// shuffle expiredLeases so workers don't all try to contend for the same leases.
-		shuffle(expiredLeases)
+		// order expiredLeases by worker so workers don't all try to contend for the same leases.
+		l.tiebreak(expiredLeases)
+		l.prioritize(expiredLeases)
 		if numExpired := len(expiredLeases); numToReachTarget > numExpired {
 			numToReachTarget = numExpired
 		}
 		leasesToTake = expiredLeases[:numToReachTarget]
+	} else if leasesToPreempt := l.filterQuota(heldLeases, l.chooseLeasesToPreempt(numToReachTarget)); len(leasesToPreempt) > 0 {
+		// lower tier workers drain the preempted leases and release them on their next
+		// renewal. we take them when they become available.
+		for _, lease := range leasesToPreempt {
+			if err := l.manager.PreemptLease(ctx, lease); err != nil {
+				l.Logger.WithError(err).Debugf("Worker %s could not preempt lease with key %s.",
+					l.WorkerId,
+					lease.Key)
+			} else {
+				l.Logger.Debugf("Worker %s preempted lease %s from lower tier worker %s.", l.WorkerId, lease.Key, lease.Owner)
+			}
+		}
 	} else {
 		l.Logger.Debugf("Worker %s needed %d leases but none were expired. consider stealing",
 			l.WorkerId,
 			numToReachTarget)
 		leasesToTake = l.chooseLeasesToSteal(leaseCounts, numToReachTarget, target)
+		leasesToTake = l.stealBudget(ctx, l.filterQuota(heldLeases, leasesToTake))
 	}
 
 	for _, lease := range leasesToTake {
-		if err := l.manager.TakeLease(lease); err != nil {
+		if err := l.takeLease(ctx, lease, l.takeoverReason(lease)); err != nil {
 			l.Logger.WithError(err).Debugf("Worker %s could not take lease with key %s.",
 				l.WorkerId,
 				lease.Key)
@@ -95,7 +193,6 @@ func (l *leaseTaker) Take() error {
 			len(leasesToTake))
 	}
 
-	return nil
 }
 
 // Choose leases to steal by randomly selecting one or more (up to max) from the most loaded worker.
@@ -123,6 +220,13 @@ func (l *leaseTaker) chooseLeasesToSteal(leaseCounts map[string]int, needed, tar
 		numLeasesToSteal = min(numLeasesToSteal, l.MaxLeasesToStealAtOneTime)
 	}
 
+	// do not steal back leases from workers of newer version.
+	if l.VersionTakeoverRate > 0 && l.Version != "" {
+		if v := l.workerVersions()[mostLoadedWorker]; v != "" && l.CompareVersions(v, l.Version) > 0 {
+			numLeasesToSteal = 0
+		}
+	}
+
 	if numLeasesToSteal <= 0 {
 		l.Logger.Debugf("Worker %s not stealing from most loaded worker %s.\n"+
 			"He has %d, target is %d, and I need %d.",
@@ -150,12 +254,13 @@ func (l *leaseTaker) chooseLeasesToSteal(leaseCounts map[string]int, needed, tar
 		}
 	}
 	shuffle(candidates)
+	l.prioritize(candidates)
 
 	return candidates[:numLeasesToSteal]
 }
 
 // Scan all leases and update lastRenewalTime. Add new leases and delete old leases.
-func (l *leaseTaker) updateLeases(list []*Lease) {
+func (l *leaseTaker) updateLeases(ctx context.Context, list []*Lease) {
 	allLeases := make(map[string]*Lease)
 	for _, newLease := range list {
 		// if we've seen this lease before.
@@ -164,11 +269,12 @@ func (l *leaseTaker) updateLeases(list []*Lease) {
 			if oldLease.Counter != newLease.Counter {
 				allLeases[oldLease.Key] = newLease
 			} else {
-				if oldLease.isExpired(l.ExpireAfter) {
+				if oldLease.isExpired(l.expireAfter(oldLease.Key), l.now()) {
 					// in some cases that "other" worker evict this lease
 					// and set his owner to NULL
 					oldLease.Owner = newLease.Owner
-					if err := l.manager.EvictLease(oldLease); err != nil {
+					l.cache.invalidate(oldLease.Key)
+					if err := l.manager.EvictLease(ctx, oldLease); err != nil {
 						l.Logger.WithError(err).Warnf("Worker %s failed to evict lease with key %s",
 							l.WorkerId,
 							newLease.Key)
@@ -177,16 +283,57 @@ func (l *leaseTaker) updateLeases(list []*Lease) {
 				allLeases[oldLease.Key] = oldLease
 			}
 		} else {
+			l.observeHeartbeat(newLease)
 			allLeases[newLease.Key] = newLease
 		}
 	}
 	l.allLeases = allLeases
+	l.setView(allLeases)
+}
+
+// observeHeartbeat sets the last renewal of a lease that is seen for the first time to
+// its persisted heartbeat, plus the tolerated clock skew, if it's earlier than the scan.
+// See: Config.Heartbeat.
+func (l *leaseTaker) observeHeartbeat(lease *Lease) {
+	if !l.Heartbeat || lease.Heartbeat.IsZero() || lease.hasNoOwner() {
+		return
+	}
+	if renewed := lease.Heartbeat.Add(l.HeartbeatSkew); renewed.Before(lease.lastRenewal) {
+		lease.lastRenewal = renewed
+	}
 }
 
 // Get list of leases that were expired as of our last scan.
 func (l *leaseTaker) getExpiredLeases() (list []*Lease) {
 	for _, lease := range l.allLeases {
-		if lease.isExpired(l.ExpireAfter) || lease.hasNoOwner() {
+		if lease.isShared(l.ExpireAfter, l.now()) || lease.isSemaphore() {
+			continue
+		}
+		// leave the leases that we rotated to other workers.
+		if l.rotated(lease) {
+			continue
+		}
+		if lease.isExpired(l.expireAfter(lease.Key), l.now()) || lease.hasNoOwner() {
+			list = append(list, lease)
+		}
+	}
+	return
+}
+
+// Get list of leases that have active shared holders, or semaphore leases as of our last scan.
+func (l *leaseTaker) getSharedLeases() (list []*Lease) {
+	for _, lease := range l.allLeases {
+		if lease.isShared(l.ExpireAfter, l.now()) || lease.isSemaphore() {
+			list = append(list, lease)
+		}
+	}
+	return
+}
+
+// Get list of leases that are held by this worker as of our last scan.
+func (l *leaseTaker) getHeldLeases() (list []*Lease) {
+	for _, lease := range l.allLeases {
+		if lease.Owner == l.WorkerId {
 			list = append(list, lease)
 		}
 	}
@@ -223,6 +370,24 @@ func shuffle(list []*Lease) {
 	}
 }
 
+// prioritize sorts the given list of leases in-place (stable) by their reservation state.
+// leases that reserved for this worker go first, and leases that reserved by other workers
+// go last.
+func (l *leaseTaker) prioritize(list []*Lease) {
+	rank := func(lease *Lease) int {
+		switch {
+		case lease.isReservedFor(l.WorkerId, l.now()):
+			return 0
+		case lease.isReservedByOther(l.WorkerId, l.now()):
+			return 2
+		}
+		return 1
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return rank(list[i]) < rank(list[j])
+	})
+}
+
 // simple min function implemetation.
 // the standard library accept float64. I want to ignore casting + reduce binary size.
 func min(i, j int) int {
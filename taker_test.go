@@ -1,10 +1,12 @@
 package lease
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/a8m/lease/leasetest"
 )
 
 type takerTest struct {
@@ -97,8 +99,7 @@ var takerTestCases = []takerTest{
 
 func TestTakerCases(t *testing.T) {
 	for _, test := range takerTestCases {
-		logger := logrus.New()
-		logger.Level = logrus.PanicLevel
+		logger := testLogger()
 		manager := newManagerMock(test.managerBehavior)
 		taker := &leaseTaker{
 			Config: &Config{WorkerId: takerId,
@@ -109,7 +110,7 @@ func TestTakerCases(t *testing.T) {
 			manager:   manager,
 			allLeases: test.prevState,
 		}
-		taker.Take()
+		taker.Take(context.Background())
 		// test method calls expectations
 		for method, calls := range test.expectedCalls {
 			if n := manager.calls[method]; n != calls {
@@ -118,3 +119,238 @@ func TestTakerCases(t *testing.T) {
 		}
 	}
 }
+
+func TestTakerPrioritize(t *testing.T) {
+	taker := &leaseTaker{Config: &Config{WorkerId: takerId}}
+	until := time.Now().Add(time.Minute)
+	list := []*Lease{
+		{Key: "foo", ReservedBy: "1", ReservedUntil: until},
+		{Key: "bar"},
+		{Key: "baz", ReservedBy: takerId, ReservedUntil: until},
+		{Key: "qux", ReservedBy: "1", ReservedUntil: time.Now().Add(-time.Minute)},
+	}
+	taker.prioritize(list)
+	expected := []string{"baz", "bar", "qux", "foo"}
+	for i := range list {
+		assert(t, list[i].Key == expected[i], fmt.Sprintf("expect %s to equal %s", list[i].Key, expected[i]))
+	}
+}
+
+func TestTakerTiebreak(t *testing.T) {
+	order := func(worker string) []string {
+		allLeases := map[string]*Lease{
+			"1": {Key: "1", Owner: "1", lastRenewal: time.Now()},
+			"2": {Key: "2", Owner: "2", lastRenewal: time.Now()},
+		}
+		var list []*Lease
+		for i := 0; i < 20; i++ {
+			lease := &Lease{Key: fmt.Sprintf("expired-%d", i), Owner: "9", lastRenewal: time.Now().Add(-time.Hour)}
+			allLeases[lease.Key] = lease
+			list = append(list, lease)
+		}
+		taker := &leaseTaker{Config: &Config{WorkerId: worker, ExpireAfter: time.Minute}, allLeases: allLeases}
+		taker.tiebreak(list)
+		keys := make([]string, len(list))
+		for i := range list {
+			keys[i] = list[i].Key
+		}
+		return keys
+	}
+	first, second := order("1"), order("2")
+	assert(t, fmt.Sprint(first) == fmt.Sprint(order("1")), "expect the order to be deterministic")
+	seen := make(map[string]bool)
+	for _, key := range first[:5] {
+		seen[key] = true
+	}
+	for _, key := range second[:5] {
+		assert(t, !seen[key], "expect the workers to prefer different leases, got "+key)
+	}
+}
+
+func TestTakerClock(t *testing.T) {
+	clock := leasetest.NewFakeClock(time.Now().Add(-time.Hour))
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId, ExpireAfter: time.Minute, Clock: clock},
+		allLeases: map[string]*Lease{
+			"foo": {Key: "foo", Owner: "1", lastRenewal: clock.Now()},
+		},
+	}
+	assert(t, len(taker.getExpiredLeases()) == 0, "expect the lease not to expire before the clock moves")
+	clock.Advance(2 * time.Minute)
+	assert(t, len(taker.getExpiredLeases()) == 1, "expect the lease to expire by the clock")
+}
+
+func TestTakerPreempt(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{
+			{Key: "foo", Owner: "1", OwnerTier: 0, lastRenewal: time.Now()},
+			{Key: "bar", Owner: "1", OwnerTier: 0, lastRenewal: time.Now()},
+			{Key: "baz", Owner: "2", OwnerTier: 2, lastRenewal: time.Now()},
+			{Key: "qux", Owner: "2", OwnerTier: 2, lastRenewal: time.Now()},
+		}},
+		methodPreempt: {nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+			Tier:                      1,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodPreempt] == 1, "expect to preempt 1 lease from the lower tier worker")
+	assert(t, manager.calls[methodTake] == 0, "expect not to take or steal leases")
+}
+
+func TestTakerCanary(t *testing.T) {
+	for _, canary := range []bool{true, false} {
+		logger := testLogger()
+		leases := []*Lease{
+			{Key: "foo", Owner: "NULL", Canary: true},
+			{Key: "bar", Owner: "NULL"},
+			{Key: "baz", Owner: "NULL"},
+		}
+		manager := newManagerMock(map[method]args{
+			methodList: {leases},
+			methodTake: {nil, nil, nil},
+		})
+		taker := &leaseTaker{
+			Config: &Config{WorkerId: takerId,
+				Logger:                    logger,
+				ExpireAfter:               time.Minute,
+				MaxLeasesToStealAtOneTime: 1,
+				Canary:                    canary,
+			},
+			manager:   manager,
+			allLeases: make(map[string]*Lease),
+		}
+		taker.Take(context.Background())
+		for _, lease := range leases {
+			taken := lease.Owner == takerId
+			assert(t, taken == (lease.Canary == canary), fmt.Sprintf("canary worker(%v): unexpected take state of lease %s", canary, lease.Key))
+		}
+	}
+}
+
+func TestTakerStorm(t *testing.T) {
+	logger := testLogger()
+	var storms []TakeoverStorm
+	taker := &leaseTaker{Config: &Config{WorkerId: takerId,
+		Logger:      logger,
+		StormWindow: time.Minute,
+		// more than half of the leases
+		StormPercent:    50,
+		OnTakeoverStorm: func(s TakeoverStorm) { storms = append(storms, s) },
+	}}
+	scan := func(owners ...string) {
+		var list []*Lease
+		for i, owner := range owners {
+			list = append(list, &Lease{Key: fmt.Sprint(i), Owner: owner})
+		}
+		taker.detectStorm(list)
+	}
+	scan("1", "1", "2", "2")
+	scan("1", "1", "2", "NULL")
+	assert(t, len(storms) == 0, "expect evictions not to be counted as takeovers")
+	scan("3", "1", "2", "3")
+	assert(t, len(storms) == 0, "expect not to report a storm below the threshold")
+	scan("3", "3", "2", "3")
+	assert(t, len(storms) == 1 && storms[0].Takeovers == 3 && storms[0].Leases == 4, "expect to report a takeover storm")
+	scan("1", "1", "1", "1")
+	assert(t, len(storms) == 1, "expect to report a storm at most once per window")
+}
+
+func TestTakerGroup(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{
+			{Key: "foo", Owner: "NULL", Group: "g"},
+			{Key: "bar", Owner: "NULL", Group: "g"},
+			{Key: "baz", Owner: "NULL"},
+			{Key: "qux", Owner: "2", lastRenewal: time.Now()},
+			{Key: "quux", Owner: "2", lastRenewal: time.Now()},
+		}},
+		methodTake:      {nil},
+		methodTakeGroup: {nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTakeGroup] == 1, "expect to take the group in a single transaction")
+	assert(t, manager.calls[methodTake] == 1, "expect to take the ungrouped lease alone")
+}
+
+func TestTakerGroupHeld(t *testing.T) {
+	foo := &Lease{Key: "foo", Owner: "NULL", Group: "g"}
+	bar := &Lease{Key: "bar", Owner: "2", Group: "g", lastRenewal: time.Now()}
+	baz := &Lease{Key: "baz", Owner: "3", Group: "g", lastRenewal: time.Now().Add(-2 * time.Minute)}
+	manager := newManagerMock(map[method]args{
+		methodTakeGroup: {nil},
+	})
+	taker := &leaseTaker{
+		Config:    &Config{WorkerId: takerId, Logger: testLogger(), ExpireAfter: time.Minute},
+		manager:   manager,
+		allLeases: map[string]*Lease{"foo": foo, "bar": bar, "baz": baz},
+	}
+	err := taker.takeLease(context.Background(), foo, TakeoverExpired)
+	assert(t, err == ErrGroupHeld && manager.calls[methodTakeGroup] == 0, "expect not to take a group that is held by a live worker")
+
+	// a stolen lease is taken with the other leases of its owner.
+	bar.Owner = "3"
+	baz.lastRenewal = time.Now()
+	err = taker.takeLease(context.Background(), baz, TakeoverStolen)
+	assert(t, err == nil && manager.calls[methodTakeGroup] == 1, "expect to steal the group of the owner")
+}
+
+func TestTakerProfiles(t *testing.T) {
+	logger := testLogger()
+	leases := []*Lease{
+		{Key: "stream/1", Owner: "4", lastRenewal: time.Now().Add(-2 * time.Minute)},
+		{Key: "stream/2", Owner: "4", lastRenewal: time.Now().Add(-2 * time.Minute)},
+		{Key: "batch/1", Owner: "2", lastRenewal: time.Now().Add(-2 * time.Minute)},
+		{Key: "batch/2", Owner: "2", lastRenewal: time.Now().Add(-2 * time.Minute)},
+	}
+	manager := newManagerMock(map[method]args{
+		methodList: {leases},
+		methodTake: {nil, nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+			Namespace:                 PrefixNamespace("/"),
+			Profiles:                  map[string]Profile{"batch": {ExpireAfter: time.Hour}},
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	assert(t, taker.validateProfiles() == nil, "expect the profiles to be valid")
+	assert(t, taker.expireAfter("batch/1") == time.Hour && taker.expireAfter("stream/1") == time.Minute, "expect the expiry of the namespace")
+	assert(t, len(taker.getExpiredLeases()) == 0, "expect no leases before the scan")
+
+	taker.Take(context.Background())
+	taken := make(map[string]int)
+	for _, lease := range leases {
+		if lease.Owner == takerId {
+			taken[taker.namespace(lease.Key)]++
+		}
+	}
+	assert(t, taken["stream"] == 1, "expect to take 1 expired lease of the namespaces without profile")
+	assert(t, taken["batch"] == 1, "expect to steal 1 lease of the profiled namespace, that is not expired")
+	assert(t, taker.Config.Profiles != nil && taker.MaxLeasesToStealAtOneTime == 1, "expect the config to be restored")
+
+	taker.Profiles["batch"] = Profile{ExpireAfter: time.Second}
+	assert(t, taker.validateProfiles() != nil, "expect a shorter expiry to be invalid")
+}
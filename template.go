@@ -0,0 +1,39 @@
+package lease
+
+import "time"
+
+// LeaseTemplate holds the defaults that are applied to every lease that is created using
+// Leaser.Create or Leaser.EnsureLeases. The fields that a created lease already sets are
+// left as is, so each create may override the template.
+type LeaseTemplate struct {
+	// Fields are the extra fields (e.g: labels or owner-team metadata) that are set on
+	// each created lease. See: Lease.Set.
+	Fields map[string]interface{}
+
+	// TTL sets the TTLField extra field of each created lease to the time (unix seconds)
+	// after TTL, to be used as the attribute of DynamoDB Time to Live. The field is extended
+	// on each take and renewal, and it's kept as is on eviction, so DynamoDB deletes only
+	// the leases that are not owned for TTL. It requires the default Codec, that stores the
+	// extra fields as native attributes. A table has a single Time to Live attribute, so it
+	// can't be set with Config.LeaseTTL, that uses the leaseExpiresAt attribute. defaults
+	// to 0, means no TTL field is set.
+	TTL time.Duration
+
+	// TTLField is the name of the TTL extra field. defaults to "ttl".
+	TTLField string
+}
+
+// apply sets the template fields that are missing on the given lease, that is created at
+// the given time.
+func (t *LeaseTemplate) apply(lease *Lease, now time.Time) {
+	for k, v := range t.Fields {
+		if _, ok := lease.Get(k); !ok {
+			lease.Set(k, v)
+		}
+	}
+	if t.TTL > 0 {
+		if _, ok := lease.Get(t.TTLField); !ok {
+			lease.Set(t.TTLField, now.Add(t.TTL).Unix())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package lease
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a8m/lease/leasetest"
+)
+
+func TestTemplateCreate(t *testing.T) {
+	manager := newManagerMock(map[method]args{
+		methodLCreate: {nil, nil},
+	})
+	config := &Config{
+		Logger:     testLogger(),
+		LeaseTable: "test",
+		WorkerId:   "1",
+		Template: LeaseTemplate{
+			Fields: map[string]interface{}{"team": "ingest", "env": "prod"},
+			TTL:    time.Hour,
+		},
+	}
+	config.defaults()
+	coordinator := &Coordinator{Config: config, Manager: manager}
+
+	lease, err := coordinator.Create(context.Background(), NewLease("foo"))
+	assert(t, err == nil, "expect create not to fail")
+	team, _ := lease.Get("team")
+	assert(t, team == "ingest", "expect the template fields to be set")
+	ttl, ok := lease.Get("ttl")
+	assert(t, ok && ttl.(int64) > time.Now().Unix(), "expect the ttl field to be set")
+
+	override := NewLease("bar")
+	override.Set("env", "staging")
+	lease, err = coordinator.Create(context.Background(), override)
+	assert(t, err == nil, "expect create not to fail")
+	env, _ := lease.Get("env")
+	assert(t, env == "staging", "expect the lease fields to override the template")
+}
+
+func TestTemplateRenew(t *testing.T) {
+	manager := newTestManager(nil)
+	manager.Clock = leasetest.NewFakeClock(time.Unix(1000, 0))
+	manager.Template.TTL = time.Hour
+	lease := Lease{Key: "foo", Owner: "1", Counter: 1}
+	renewed := lease
+	renewed.Counter++
+
+	input := manager.condUpdateInput(renewed, lease)
+	assert(t, strings.Contains(*input.UpdateExpression, "#ttl = :ttl"), "expect the ttl field to be extended on renew")
+	assert(t, *input.ExpressionAttributeNames["#ttl"] == "ttl", "expect the ttl field name to be the template field")
+	assert(t, *input.ExpressionAttributeValues[":ttl"].N == "4600", "expect the ttl to be extended by the template ttl")
+
+	evicted := renewed
+	evicted.Owner = "NULL"
+	input = manager.condUpdateInput(evicted, lease)
+	assert(t, !strings.Contains(*input.UpdateExpression, "#ttl"), "expect the ttl field to be kept on evict")
+}
@@ -0,0 +1,109 @@
+package lease
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// throttle detects sustained write throttling of the lease table. While the writes are
+// throttled, the taker stops taking and stealing leases, so the write capacity is left to
+// the renewals of the held leases. See: Config.ThrottleWindow.
+type throttle struct {
+	*Config
+	mu sync.Mutex
+	// since is the time of the first throttled write of the current streak, and last
+	// is the time of the last one.
+	since, last time.Time
+	throttled   bool
+}
+
+// record records the outcome of a write to the lease table.
+func (t *throttle) record(err error) {
+	if t == nil || !isThrottled(err) {
+		return
+	}
+	now := t.now()
+	t.mu.Lock()
+	if t.since.IsZero() || now.Sub(t.last) > t.ThrottleWindow {
+		t.since = now
+	}
+	t.last = now
+	enter := !t.throttled && now.Sub(t.since) >= t.ThrottleWindow
+	if enter {
+		t.throttled = true
+	}
+	t.mu.Unlock()
+	if enter {
+		t.changed(true)
+	}
+}
+
+// active reports whether the writes are throttled, i.e: the worker is in the throttled
+// mode, and a write was throttled within the last Config.ThrottleWindow. It doesn't change
+// the mode, so it's safe to call from outside of the worker loops (e.g: Stats).
+func (t *throttle) active() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.throttled && t.now().Sub(t.last) <= t.ThrottleWindow
+}
+
+// check reports whether the writes are throttled, and exits the throttled mode if no write
+// was throttled within the last Config.ThrottleWindow. It's called by the taker loop, so
+// Config.OnThrottled is called from the worker loops only.
+func (t *throttle) check() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	exit := t.throttled && t.now().Sub(t.last) > t.ThrottleWindow
+	if exit {
+		t.throttled = false
+		t.since = time.Time{}
+	}
+	throttled := t.throttled
+	t.mu.Unlock()
+	if exit {
+		t.changed(false)
+	}
+	return throttled
+}
+
+// changed logs the change of the throttled mode, and calls Config.OnThrottled.
+func (t *throttle) changed(throttled bool) {
+	if throttled {
+		t.Logger.Warnf("Worker %s entered throttled mode. it renews its leases, and stops taking new ones", t.WorkerId)
+	} else {
+		t.Logger.Infof("Worker %s recovered from throttled mode", t.WorkerId)
+	}
+	if t.OnThrottled != nil {
+		t.OnThrottled(throttled)
+	}
+}
+
+// Throttled reports whether the writes to the lease table are throttled, and the worker
+// does not take new leases. See: Config.ThrottleWindow.
+func (c *Coordinator) Throttled() bool {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+	return c.throttle.active()
+}
+
+// isThrottled reports whether err is a throttling error of DynamoDB.
+func isThrottled(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	switch awsErr.Code() {
+	case dynamodb.ErrCodeProvisionedThroughputExceededException, dynamodb.ErrCodeRequestLimitExceeded, "ThrottlingException":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestThrottle(t *testing.T) {
+	var changes []bool
+	th := &throttle{Config: &Config{
+		WorkerId:       takerId,
+		Logger:         testLogger(),
+		ThrottleWindow: time.Minute,
+		OnThrottled:    func(throttled bool) { changes = append(changes, throttled) },
+	}}
+	throttled := awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "", nil)
+
+	th.record(throttled)
+	th.record(errors.New("unavailable"))
+	assert(t, !th.check(), "expect a single throttled write not to enter throttled mode")
+
+	// the streak started a window ago.
+	th.since = time.Now().Add(-time.Minute)
+	th.record(&OpError{Op: "renew", Err: throttled})
+	assert(t, th.check(), "expect sustained throttling to enter throttled mode")
+
+	th.last = time.Now().Add(-2 * time.Minute)
+	assert(t, !th.active() && len(changes) == 1, "expect active not to change the throttled mode")
+	assert(t, !th.check(), "expect to exit throttled mode after a window without throttling")
+	assert(t, len(changes) == 2 && changes[0] && !changes[1], "expect OnThrottled to be called on each change")
+}
+
+func TestTakerThrottled(t *testing.T) {
+	leases := []*Lease{{Key: "foo", Owner: "NULL"}}
+	manager := newManagerMock(map[method]args{
+		methodList: {leases},
+	})
+	config := &Config{WorkerId: takerId, Logger: testLogger(), ExpireAfter: time.Minute, ThrottleWindow: time.Minute}
+	taker := &leaseTaker{
+		Config:    config,
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+		throttle:  &throttle{Config: config, throttled: true, last: time.Now()},
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTake] == 0, "expect not to take leases while throttled")
+	_, ok := taker.Lookup("foo")
+	assert(t, ok, "expect the view to be updated while throttled")
+}
@@ -0,0 +1,64 @@
+package lease
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// tiebreak sorts the given list of expired leases in-place by rendezvous hashing, so workers
+// that scan the same expired leases in the same cycle prefer different leases, and most
+// conflicts are avoided before the conditional writes fail. each lease is ranked for each of
+// the live workers by the hash of the worker id and the lease key. leases that this worker
+// wins (i.e: it has the highest score among the live workers) go first, then the leases in
+// which it has the second highest score, and so on.
+func (l *leaseTaker) tiebreak(list []*Lease) {
+	workers := l.liveWorkers()
+	rank := make(map[string]int, len(list))
+	score := make(map[string]uint64, len(list))
+	for _, lease := range list {
+		s := tiebreakScore(l.WorkerId, lease.Key)
+		for _, worker := range workers {
+			if worker != l.WorkerId && tiebreakScore(worker, lease.Key) > s {
+				rank[lease.Key]++
+			}
+		}
+		score[lease.Key] = s
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		ki, kj := list[i].Key, list[j].Key
+		if rank[ki] != rank[kj] {
+			return rank[ki] < rank[kj]
+		}
+		return score[ki] > score[kj]
+	})
+}
+
+// liveWorkers returns the workers that hold leases that are not expired as of our last
+// scan, and this worker. the owners of expired leases are probably dead, and they do not
+// compete on the expired leases.
+func (l *leaseTaker) liveWorkers() []string {
+	seen := map[string]bool{l.WorkerId: true}
+	workers := []string{l.WorkerId}
+	for _, lease := range l.allLeases {
+		if lease.hasNoOwner() || seen[lease.Owner] || lease.isExpired(l.expireAfter(lease.Key), l.now()) {
+			continue
+		}
+		seen[lease.Owner] = true
+		workers = append(workers, lease.Owner)
+	}
+	return workers
+}
+
+// tiebreakScore returns the rendezvous score of the given worker for the lease with the given key.
+// the FNV hash is finalized with the mixer of splitmix64, because the hashes of similar worker
+// ids (e.g: "1" and "2") are correlated, and they would rank the leases in the same order.
+func tiebreakScore(worker, key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(worker))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,105 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// liveLeases returns the leases that are not tombstoned.
+func liveLeases(list []*Lease) []*Lease {
+	var live []*Lease
+	for _, lease := range list {
+		if !lease.isTombstoned() {
+			live = append(live, lease)
+		}
+	}
+	return live
+}
+
+// tombstoneLease marks the given lease as tombstoned, and releases it. does nothing
+// when passed a lease that does not exist, or that is already tombstoned.
+// The tombstone is conditional on the owner of the lease.
+func (l *LeaseManager) tombstoneLease(ctx context.Context, lease *Lease) error {
+	now := l.now()
+	_, err := l.updateLease(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(l.LeaseTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			LeaseKeyKey: {
+				S: aws.String(lease.Key),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#tombstone": aws.String(LeaseTombstonedAtKey),
+			"#owner":     aws.String(LeaseOwnerKey),
+			"#counter":   aws.String(LeaseCounterKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {
+				N: aws.String(strconv.FormatInt(now.Unix(), 10)),
+			},
+			":null": {
+				S: aws.String("NULL"),
+			},
+			":one": {
+				N: aws.String("1"),
+			},
+			":condOwner": {
+				S: aws.String(lease.Owner),
+			},
+		},
+		UpdateExpression:    aws.String("SET #tombstone = :now, #owner = :null ADD #counter :one"),
+		ConditionExpression: aws.String("#owner = :condOwner AND attribute_not_exists(#tombstone)"),
+		ReturnValues:        aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		// the lease does not exist, or it's already tombstoned.
+		if isConditionalFailed(err) {
+			if _, gerr := l.GetLease(ctx, lease.Key); errors.Is(gerr, ErrLeaseNotFound) {
+				return nil
+			}
+		}
+		return err
+	}
+	lease.TombstonedAt = time.Unix(now.Unix(), 0)
+	lease.Owner = "NULL"
+	lease.Counter = nextCounter(lease.Counter)
+	return nil
+}
+
+// PurgeLease deletes the given tombstoned lease. conditional on the lease not being
+// re-created since it was tombstoned.
+func (l *LeaseManager) PurgeLease(ctx context.Context, lease *Lease) error {
+	return l.wrapError("purge", lease.Key, l.deleteLease(ctx, lease, &dynamodb.DeleteItemInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":tombstone": {
+				N: aws.String(strconv.FormatInt(lease.TombstonedAt.Unix(), 10)),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#tombstone": aws.String(LeaseTombstonedAtKey),
+		},
+		ConditionExpression: aws.String("#tombstone = :tombstone"),
+	}))
+}
+
+// purgeTombstones purges the tombstoned leases in the given list, that their
+// retention window lapsed.
+func (l *leaseTaker) purgeTombstones(ctx context.Context, list []*Lease) {
+	for _, lease := range list {
+		if !lease.isTombstoned() || l.since(lease.TombstonedAt) < l.TombstoneRetention {
+			continue
+		}
+		if err := l.manager.PurgeLease(ctx, lease); err != nil {
+			l.Logger.WithError(err).Debugf("Worker %s could not purge tombstoned lease with key %s.",
+				l.WorkerId,
+				lease.Key)
+		} else {
+			l.Logger.Debugf("Worker %s purged tombstoned lease: %s.", l.WorkerId, lease.Key)
+		}
+	}
+}
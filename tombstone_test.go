@@ -0,0 +1,75 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestSoftDelete(t *testing.T) {
+	client := newClientMock(map[method]args{
+		methodUpdateItem: {
+			&dynamodb.UpdateItemOutput{
+				Attributes: map[string]*dynamodb.AttributeValue{
+					LeaseKeyKey: {S: aws.String("foo")},
+				},
+			},
+			// the lease does not exist, or owned by another worker
+			awserr.New(ConditionalFailed, "", errors.New("")),
+			awserr.New(ConditionalFailed, "", errors.New("")),
+		},
+		methodGetItem: {
+			&dynamodb.GetItemOutput{},
+			&dynamodb.GetItemOutput{
+				Item: map[string]*dynamodb.AttributeValue{
+					LeaseKeyKey:   {S: aws.String("foo")},
+					LeaseOwnerKey: {S: aws.String("2")},
+				},
+			},
+		},
+	})
+	manager := newTestManager(client)
+	manager.SoftDelete = true
+
+	lease := &Lease{Key: "foo", Owner: "1", Counter: 1}
+	err := manager.DeleteLease(context.Background(), lease)
+	assert(t, err == nil, "expect DeleteLease not to fail")
+	assert(t, client.calls[methodDeleteItem] == 0, "expect not to delete the lease")
+	assert(t, lease.isTombstoned() && lease.Owner == "NULL" && lease.Counter == 2, "expect lease to be tombstoned and released")
+
+	err = manager.DeleteLease(context.Background(), &Lease{Key: "bar", Owner: "1"})
+	assert(t, err == nil, "expect not to fail when the lease does not exist")
+
+	err = manager.DeleteLease(context.Background(), &Lease{Key: "foo", Owner: "1"})
+	assert(t, err != nil, "expect to fail when the lease is owned by another worker")
+}
+
+func TestTakerTombstones(t *testing.T) {
+	logger := testLogger()
+	leases := []*Lease{
+		{Key: "foo", Owner: "NULL", TombstonedAt: time.Now().Add(-2 * time.Hour)},
+		{Key: "bar", Owner: "NULL", TombstonedAt: time.Now()},
+	}
+	manager := newManagerMock(map[method]args{
+		methodList:  {leases},
+		methodPurge: {nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+			TombstoneRetention:        time.Hour,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodPurge] == 1, "expect to purge only the tombstones that their retention lapsed")
+	assert(t, manager.calls[methodTake] == 0, "expect not to take tombstoned leases")
+}
@@ -0,0 +1,63 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// ErrTTLUnsupported error will be returns if Config.LeaseTTL is set, and the lease table
+	// is created with a client that does not support updating the Time to Live of tables.
+	ErrTTLUnsupported = errors.New("leaser: client does not support time to live")
+
+	// ErrTTLConflict error will be returns if Config.LeaseTTL is set, and the Time to Live
+	// of the lease table is already enabled on another attribute (e.g: LeaseTemplate.TTLField).
+	// A table has a single Time to Live attribute.
+	ErrTTLConflict = errors.New("leaser: time to live is enabled on another attribute")
+)
+
+// tableTTLUpdater is implemented by the clients that support the Time to Live of tables,
+// e.g: *dynamodb.DynamoDB.
+type tableTTLUpdater interface {
+	DescribeTimeToLiveWithContext(aws.Context, *dynamodb.DescribeTimeToLiveInput, ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error)
+	UpdateTimeToLiveWithContext(aws.Context, *dynamodb.UpdateTimeToLiveInput, ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// enableTTL enables the Time to Live of the lease table on the leaseExpiresAt attribute,
+// if it's not already enabled (e.g: by another worker, or in a previous run). It fails
+// with ErrTTLConflict if it's enabled on another attribute.
+func (l *LeaseManager) enableTTL(ctx context.Context) error {
+	client, ok := l.Client.(tableTTLUpdater)
+	if !ok {
+		return ErrTTLUnsupported
+	}
+	out, err := client.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(l.LeaseTable),
+	})
+	if err != nil {
+		return err
+	}
+	if desc := out.TimeToLiveDescription; desc != nil {
+		switch aws.StringValue(desc.TimeToLiveStatus) {
+		case dynamodb.TimeToLiveStatusEnabled, dynamodb.TimeToLiveStatusEnabling:
+			if name := aws.StringValue(desc.AttributeName); name != LeaseExpiresAtKey {
+				return fmt.Errorf("%w: %q, instead of %q", ErrTTLConflict, name, LeaseExpiresAtKey)
+			}
+			return nil
+		}
+	}
+	l.Logger.WithField("table name", l.LeaseTable).Infof("Worker %s enables the time to live of the lease table on %q", l.WorkerId, LeaseExpiresAtKey)
+	_, err = client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(l.LeaseTable),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(LeaseExpiresAtKey),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	return err
+}
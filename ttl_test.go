@@ -0,0 +1,101 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a8m/lease/leasetest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ttlMock is a clientMock that supports the Time to Live of tables.
+type ttlMock struct {
+	*clientMock
+	status    string
+	attribute string
+	updates   int
+}
+
+func (c *ttlMock) DescribeTimeToLiveWithContext(aws.Context, *dynamodb.DescribeTimeToLiveInput, ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	attribute := c.attribute
+	if attribute == "" {
+		attribute = LeaseExpiresAtKey
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+		AttributeName:    aws.String(attribute),
+		TimeToLiveStatus: aws.String(c.status),
+	}}, nil
+}
+
+func (c *ttlMock) UpdateTimeToLiveWithContext(_ aws.Context, input *dynamodb.UpdateTimeToLiveInput, _ ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	c.updates++
+	if aws.StringValue(input.TimeToLiveSpecification.AttributeName) == LeaseExpiresAtKey {
+		c.status = dynamodb.TimeToLiveStatusEnabling
+	}
+	return new(dynamodb.UpdateTimeToLiveOutput), nil
+}
+
+func TestCreateTableTTL(t *testing.T) {
+	client := &ttlMock{
+		clientMock: newClientMock(map[method]args{
+			methodCreateTable: {new(dynamodb.CreateTableOutput), new(dynamodb.CreateTableOutput), new(dynamodb.CreateTableOutput)},
+			methodDescribeTable: {
+				&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+					TableStatus: aws.String(dynamodb.TableStatusActive),
+				}},
+			},
+		}),
+		status: dynamodb.TimeToLiveStatusDisabled,
+	}
+	manager := newTestManager(client)
+	manager.LeaseTTL = time.Hour
+
+	err := manager.CreateLeaseTable(context.Background())
+	assert(t, err == nil, "expect not to fail")
+	assert(t, client.updates == 1, "expect the time to live to be enabled")
+
+	err = manager.CreateLeaseTable(context.Background())
+	assert(t, err == nil, "expect not to fail")
+	assert(t, client.updates == 1, "expect the time to live not to be enabled twice")
+
+	client.attribute = "ttl"
+	err = manager.CreateLeaseTable(context.Background())
+	assert(t, errors.Is(err, ErrTTLConflict), "expect to fail if the time to live is enabled on another attribute")
+
+	manager.Client = newClientMock(map[method]args{
+		methodCreateTable: {new(dynamodb.CreateTableOutput)},
+		methodDescribeTable: {
+			&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableStatus: aws.String(dynamodb.TableStatusActive),
+			}},
+		},
+	})
+	err = manager.CreateLeaseTable(context.Background())
+	assert(t, errors.Is(err, ErrTTLUnsupported), "expect to fail with clients that can't enable the time to live")
+}
+
+func TestCondUpdateTTL(t *testing.T) {
+	manager := newTestManager(nil)
+	manager.Clock = leasetest.NewFakeClock(time.Unix(1000, 0))
+	lease := Lease{Key: "foo", Owner: "1", Counter: 1}
+	renewed := lease
+	renewed.Counter++
+
+	input := manager.condUpdateInput(renewed, lease)
+	assert(t, !strings.Contains(*input.UpdateExpression, LeaseExpiresAtKey), "expect no expiry without LeaseTTL")
+
+	manager.LeaseTTL = time.Hour
+	input = manager.condUpdateInput(renewed, lease)
+	assert(t, strings.Contains(*input.UpdateExpression, LeaseExpiresAtKey+" = :expiresAt"), "expect the expiry to be extended on renew")
+	assert(t, *input.ExpressionAttributeValues[":expiresAt"].N == "4600", "expect the expiry to be after LeaseTTL")
+
+	evicted := renewed
+	evicted.Owner = "NULL"
+	input = manager.condUpdateInput(evicted, lease)
+	assert(t, strings.Contains(*input.UpdateExpression, "REMOVE "+LeaseExpiresAtKey), "expect the expiry to be removed on evict")
+}
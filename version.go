@@ -0,0 +1,78 @@
+package lease
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two dot-separated versions (e.g: "1.10.2" and "v1.9"),
+// segment by segment. numeric segments are compared as numbers, and others
+// lexically. a leading "v" is ignored.
+// The result will be 0 if a == b, -1 if a < b, and +1 if a > b.
+func CompareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x == y {
+			continue
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil && xn < yn, (xerr != nil || yerr != nil) && x < y:
+			return -1
+		case xerr == nil && yerr == nil && xn > yn, (xerr != nil || yerr != nil) && x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// isOlderVersion test if the given version is older than the version of this worker.
+// leases without version are considered older only if this worker has a version.
+func (c *Config) isOlderVersion(version string) bool {
+	if c.Version == "" {
+		return false
+	}
+	return version == "" || c.CompareVersions(version, c.Version) < 0
+}
+
+// chooseLeasesToUpgrade returns up to VersionTakeoverRate leases that held by workers
+// with older version than this worker.
+func (l *leaseTaker) chooseLeasesToUpgrade() []*Lease {
+	if l.VersionTakeoverRate <= 0 {
+		return nil
+	}
+	var candidates []*Lease
+	for _, lease := range l.allLeases {
+		if lease.hasNoOwner() || lease.Owner == l.WorkerId || lease.isShared(l.ExpireAfter, l.now()) || lease.isSemaphore() {
+			continue
+		}
+		if l.isOlderVersion(lease.OwnerVersion) {
+			candidates = append(candidates, lease)
+		}
+	}
+	shuffle(candidates)
+	if len(candidates) > l.VersionTakeoverRate {
+		candidates = candidates[:l.VersionTakeoverRate]
+	}
+	return candidates
+}
+
+// workerVersions returns the version of each lease owner, as of our last scan.
+func (l *leaseTaker) workerVersions() map[string]string {
+	m := make(map[string]string)
+	for _, lease := range l.allLeases {
+		if !lease.hasNoOwner() {
+			m[lease.Owner] = lease.OwnerVersion
+		}
+	}
+	return m
+}
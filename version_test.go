@@ -0,0 +1,61 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2", "1.2", 0},
+		{"1.9.0", "1.10.0", -1},
+		{"2.0", "1.10.3", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.2.0-rc1", "1.2.0-rc2", -1},
+	} {
+		n := CompareVersions(test.a, test.b)
+		assert(t, n == test.expected, fmt.Sprintf("CompareVersions(%q, %q): got %d, expected %d", test.a, test.b, n, test.expected))
+	}
+}
+
+func TestTakerVersionTakeover(t *testing.T) {
+	logger := testLogger()
+	manager := newManagerMock(map[method]args{
+		methodList: {[]*Lease{
+			{Key: "foo", Owner: "1", OwnerVersion: "1.0", lastRenewal: time.Now()},
+			{Key: "bar", Owner: "1", OwnerVersion: "1.0", lastRenewal: time.Now()},
+			{Key: "baz", Owner: "1", OwnerVersion: "1.0", lastRenewal: time.Now()},
+			{Key: "qux", Owner: "2", OwnerVersion: "2.0", lastRenewal: time.Now()},
+		}},
+		methodTake: {nil, nil},
+	})
+	taker := &leaseTaker{
+		Config: &Config{WorkerId: takerId,
+			Logger:                    logger,
+			ExpireAfter:               time.Minute,
+			MaxLeasesToStealAtOneTime: 1,
+			Version:                   "2.0",
+			VersionTakeoverRate:       2,
+			CompareVersions:           CompareVersions,
+		},
+		manager:   manager,
+		allLeases: make(map[string]*Lease),
+	}
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodTake] == 2, "expect to take 2 leases from the older version worker")
+
+	// the version takeovers are charged against the steal budget.
+	manager.calls = make(map[method]int)
+	manager.result[methodStealBudget] = args{1, 0}
+	taker.StealRate = 1
+	taker.allLeases = make(map[string]*Lease)
+	taker.Take(context.Background())
+	assert(t, manager.calls[methodStealBudget] >= 1, "expect to acquire the steal budget")
+	assert(t, manager.calls[methodTake] == 1, "expect to take the older version leases within the budget")
+}
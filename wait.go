@@ -0,0 +1,45 @@
+package lease
+
+import "context"
+
+// Acquired returns a channel that is closed when the renewer starts holding new leases.
+// a new channel is returned after each close.
+func (l *leaseHolder) Acquired() <-chan struct{} {
+	l.Lock()
+	defer l.Unlock()
+	if l.acquired == nil {
+		l.acquired = make(chan struct{})
+	}
+	return l.acquired
+}
+
+// notifyAcquired wakes up the waiters of Acquired.
+func (l *leaseHolder) notifyAcquired() {
+	l.Lock()
+	defer l.Unlock()
+	if l.acquired != nil {
+		close(l.acquired)
+		l.acquired = nil
+	}
+}
+
+// WaitForOwnership blocks until this worker holds the lease with the given key, and
+// returns it, or until ctx is done. The held leases are checked each time the Renewer
+// picks up new leases, so call sites that need a specific lease don't have to poll
+// GetHeldLeases.
+func (c *Coordinator) WaitForOwnership(ctx context.Context, key string) (Lease, error) {
+	for {
+		// subscribe before the check, to not miss leases that are acquired in between.
+		acquired := c.Renewer.Acquired()
+		for _, lease := range c.Renewer.GetHeldLeases() {
+			if lease.Key == key {
+				return lease, nil
+			}
+		}
+		select {
+		case <-acquired:
+		case <-ctx.Done():
+			return Lease{}, ctx.Err()
+		}
+	}
+}
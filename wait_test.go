@@ -0,0 +1,44 @@
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitForOwnership(t *testing.T) {
+	logger := testLogger()
+	foo, bar := &Lease{Key: "foo", Owner: renewerId}, &Lease{Key: "bar", Owner: "2"}
+	manager := newManagerMock(map[method]args{
+		methodList:  {[]*Lease{bar}, []*Lease{foo, bar}},
+		methodRenew: {nil},
+	})
+	config := &Config{WorkerId: renewerId, Logger: logger, ExpireAfter: 10 * time.Second}
+	holder := &leaseHolder{
+		Config:         config,
+		manager:        manager,
+		heldLeases:     make(map[string]*Lease),
+		sharedLeases:   make(map[string]*Lease),
+		drainingLeases: make(map[string]*Lease),
+	}
+	c := &Coordinator{Config: config, Renewer: holder}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.WaitForOwnership(ctx, "bar")
+	assert(t, err == context.DeadlineExceeded, "expect to wait until the context is done")
+
+	done := make(chan Lease)
+	go func() {
+		lease, _ := c.WaitForOwnership(context.Background(), "foo")
+		done <- lease
+	}()
+	holder.Renew(context.Background())
+	holder.Renew(context.Background())
+	select {
+	case lease := <-done:
+		assert(t, lease.Key == "foo", "expect to return the acquired lease")
+	case <-time.After(time.Second):
+		t.Error("expect to wake up when the lease is acquired")
+	}
+}
@@ -0,0 +1,228 @@
+package lease
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jpillora/backoff"
+)
+
+// Webhook delivers the owner changes to an HTTP endpoint, so external systems can subscribe
+// to them without AWS or Go clients. Each change is sent as a JSON POST request, with a
+// unique id in the X-Lease-Delivery header, the unix time of the request in the
+// X-Lease-Timestamp header, and the HMAC-SHA256 of both and the body in the
+// X-Lease-Signature header ("sha256=<hex>", see: Sign). Receivers verify the signature,
+// reject old timestamps, and ignore the ids they already received, so deliveries can't be
+// replayed. Failed deliveries (network errors, 429 and 5xx responses) are retried with
+// backoff, with the same id, until the webhook is closed.
+//
+// The changes are delivered in the background, in order. Use Notify as Config.OnOwnerChange:
+//
+//	webhook := lease.NewWebhook("https://example.com/leases", secret)
+//	defer webhook.Close()
+//	leaser := lease.New("leases", lease.WithConfig(func(c *lease.Config) {
+//		c.OnOwnerChange = webhook.Notify
+//	}))
+type Webhook struct {
+	URL    string
+	Secret []byte
+	// Client is the HTTP client of the deliveries. defaults to a client with a 10 seconds
+	// timeout.
+	Client *http.Client
+	// MaxRetries is the number of retries of a failed delivery, before it's dropped.
+	// defaults to 3.
+	MaxRetries int
+	// CloseTimeout is the time that Close waits for the pending changes to be delivered,
+	// before the in-flight delivery is cancelled and the rest of them are dropped. defaults
+	// to 10 seconds.
+	CloseTimeout time.Duration
+	// Logger logs the dropped deliveries. defaults to NewSlogLogger(slog.Default()).
+	Logger Logger
+
+	mu    sync.Mutex
+	queue chan OwnerChange
+	stop  chan struct{}
+	done  chan struct{}
+	// ctx is the context of the deliveries. it's cancelled when Close times out.
+	ctx    context.Context
+	cancel context.CancelFunc
+	closed bool
+}
+
+// NewWebhook create new Webhook that posts to the given url, signed with the given secret.
+func NewWebhook(url string, secret []byte) *Webhook {
+	return &Webhook{URL: url, Secret: secret}
+}
+
+const (
+	// webhookQueueSize is the number of pending changes, before new changes are dropped.
+	webhookQueueSize = 1024
+	// webhookTimeout is the default timeout of a delivery, and of Close.
+	webhookTimeout = 10 * time.Second
+)
+
+// webhookClient is the default HTTP client of the deliveries.
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// Notify queues the given change for delivery. It never blocks. the change is dropped
+// if the queue is full, or the webhook is closed.
+func (w *Webhook) Notify(change OwnerChange) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	if w.queue == nil {
+		if w.Logger == nil {
+			w.Logger = NewSlogLogger(slog.Default())
+		}
+		w.queue = make(chan OwnerChange, webhookQueueSize)
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		w.ctx, w.cancel = context.WithCancel(context.Background())
+		go w.deliver()
+	}
+	select {
+	case w.queue <- change:
+	default:
+		w.Logger.Warnf("webhook: queue is full. drop %s event of lease %s", change.Type, change.Key)
+	}
+}
+
+// Close delivers the pending changes, and stops the webhook. The failed deliveries are
+// not retried once the webhook is closed. The in-flight delivery is cancelled, and the
+// changes that are not delivered within CloseTimeout are dropped.
+func (w *Webhook) Close() {
+	w.mu.Lock()
+	if w.closed || w.queue == nil {
+		w.closed = true
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.stop)
+	close(w.queue)
+	w.mu.Unlock()
+	timeout := w.CloseTimeout
+	if timeout <= 0 {
+		timeout = webhookTimeout
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-w.done:
+	case <-t.C:
+		w.cancel()
+		<-w.done
+	}
+	w.cancel()
+}
+
+// deliver posts the queued changes until the webhook is closed.
+func (w *Webhook) deliver() {
+	defer close(w.done)
+	b := &backoff.Backoff{Min: 100 * time.Millisecond, Max: 10 * time.Second, Jitter: true}
+	retries := w.MaxRetries
+	if retries == 0 {
+		retries = 3
+	}
+	var dropped int
+	defer func() {
+		if dropped > 0 {
+			w.Logger.Warnf("webhook: close timed out. drop %d events", dropped)
+		}
+	}()
+	for change := range w.queue {
+		if w.abandoned() {
+			dropped++
+			continue
+		}
+		id, err := uuid()
+		if err != nil {
+			w.Logger.WithError(err).Warnf("webhook: drop %s event of lease %s", change.Type, change.Key)
+			continue
+		}
+		b.Reset()
+		for {
+			retry, err := w.post(id, change)
+			if err == nil {
+				break
+			}
+			if !retry || int(b.Attempt()) >= retries || !w.wait(b.Duration()) {
+				w.Logger.WithError(err).Warnf("webhook: drop %s event of lease %s", change.Type, change.Key)
+				break
+			}
+		}
+	}
+}
+
+// abandoned reports whether Close timed out, and the pending changes should be dropped.
+func (w *Webhook) abandoned() bool {
+	return w.ctx.Err() != nil
+}
+
+// wait pauses the deliveries for the given duration before a retry. It returns false if
+// the webhook is closed, and the delivery should not be retried.
+func (w *Webhook) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
+
+// post sends the given change with the given delivery id, and reports whether a failed
+// delivery should be retried.
+func (w *Webhook) post(id string, change OwnerChange) (bool, error) {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Lease-Event", string(change.Type))
+	req.Header.Set("X-Lease-Delivery", id)
+	req.Header.Set("X-Lease-Timestamp", timestamp)
+	req.Header.Set("X-Lease-Signature", "sha256="+Sign(w.Secret, timestamp, id, body))
+	client := w.Client
+	if client == nil {
+		client = webhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("leaser: webhook responded with status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<id>.<body>", i.e: the values
+// of the X-Lease-Timestamp and X-Lease-Delivery headers, and the body of a delivery.
+// Receivers of the Webhook use it to verify the X-Lease-Signature header (with hmac.Equal).
+func Sign(secret []byte, timestamp, id string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "." + id + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
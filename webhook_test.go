@@ -0,0 +1,110 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		calls    int
+		received []OwnerChange
+		ids      = make(map[string]int)
+	)
+	secret := []byte("secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		// fail the first delivery.
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		id, timestamp := r.Header.Get("X-Lease-Delivery"), r.Header.Get("X-Lease-Timestamp")
+		if id == "" || timestamp == "" || r.Header.Get("X-Lease-Signature") != "sha256="+Sign(secret, timestamp, id, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ids[id]++
+		var change OwnerChange
+		json.Unmarshal(body, &change)
+		received = append(received, change)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL, secret)
+	webhook.Logger = testLogger()
+	webhook.Notify(OwnerChange{Key: "foo", Type: OwnerAcquired, Worker: "1", To: "1", At: time.Now()})
+	webhook.Notify(OwnerChange{Key: "bar", Type: OwnerLost, Worker: "1", From: "1", To: "2", At: time.Now()})
+	webhook.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert(t, calls == 3, "expect the failed delivery to be retried")
+	assert(t, len(received) == 2 && received[0].Key == "foo" && received[1].To == "2", "expect the changes to be delivered in order")
+	assert(t, len(ids) == 2, "expect each change to have its own delivery id")
+}
+
+func TestWebhookClose(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- struct{}{}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL, nil)
+	webhook.Logger = testLogger()
+	webhook.MaxRetries = 5
+	webhook.Notify(OwnerChange{Key: "foo", Type: OwnerAcquired, Worker: "1", To: "1", At: time.Now()})
+	<-calls
+	start := time.Now()
+	webhook.Close()
+	assert(t, time.Since(start) < time.Second, "expect close to interrupt the backoff")
+	assert(t, len(calls) == 0, "expect no retries once the webhook is closed")
+}
+
+func TestWebhookCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	webhook := NewWebhook(server.URL, nil)
+	webhook.Logger = testLogger()
+	webhook.CloseTimeout = 100 * time.Millisecond
+	for i := 0; i < 10; i++ {
+		webhook.Notify(OwnerChange{Key: "foo", Type: OwnerAcquired, Worker: "1", To: "1", At: time.Now()})
+	}
+	start := time.Now()
+	webhook.Close()
+	assert(t, time.Since(start) < time.Second, "expect close to cancel the in-flight delivery, and drop the pending changes")
+}
+
+func TestReleaseOwnerChange(t *testing.T) {
+	var changes []OwnerChange
+	manager := newManagerMock(map[method]args{
+		methodEvict: {nil},
+	})
+	holder := &leaseHolder{
+		Config: &Config{WorkerId: renewerId, Logger: testLogger(), OnOwnerChange: func(c OwnerChange) {
+			changes = append(changes, c)
+		}},
+		manager:    manager,
+		heldLeases: map[string]*Lease{"foo": {Key: "foo", Owner: renewerId}},
+	}
+	err := holder.Release(context.Background(), Lease{Key: "foo"})
+	assert(t, err == nil, "expect release not to fail")
+	assert(t, len(changes) == 1 && changes[0].Type == OwnerReleased && changes[0].Worker == renewerId, "expect the release to be reported")
+}
@@ -0,0 +1,40 @@
+// Package zapadapter adapts zap loggers to lease.Logger. For example:
+//
+//	leaser := lease.New("leases", lease.WithLogger(zapadapter.New(zap.NewExample())))
+package zapadapter
+
+import (
+	"github.com/a8m/lease"
+	"go.uber.org/zap"
+)
+
+type logger struct {
+	l *zap.SugaredLogger
+}
+
+// New returns a lease.Logger that writes to the given zap logger.
+func New(l *zap.Logger) lease.Logger {
+	return &logger{l.Sugar()}
+}
+
+func (l *logger) WithFields(fields lease.Fields) lease.Logger {
+	args := make([]interface{}, 0, 2*len(fields))
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	return &logger{l.l.With(args...)}
+}
+
+func (l *logger) WithField(key string, value interface{}) lease.Logger {
+	return &logger{l.l.With(key, value)}
+}
+
+func (l *logger) WithError(err error) lease.Logger {
+	return &logger{l.l.With(zap.Error(err))}
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) { l.l.Debugf(format, args...) }
+func (l *logger) Infof(format string, args ...interface{})  { l.l.Infof(format, args...) }
+func (l *logger) Warnf(format string, args ...interface{})  { l.l.Warnf(format, args...) }
+func (l *logger) Errorf(format string, args ...interface{}) { l.l.Errorf(format, args...) }
+func (l *logger) Fatalf(format string, args ...interface{}) { l.l.Fatalf(format, args...) }
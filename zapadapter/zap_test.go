@@ -0,0 +1,29 @@
+package zapadapter
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := New(zap.New(core)).WithField("package", "leases")
+
+	logger.Debugf("skipped %d", 1)
+	logger.WithFields(map[string]interface{}{"leaseKey": "foo"}).WithError(errors.New("boom")).Warnf("failed to take %d leases", 2)
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expect debug entries to be skipped, got: %d entries", len(entries))
+	}
+	if entries[0].Message != "failed to take 2 leases" {
+		t.Errorf("expect the message to be formatted, got: %s", entries[0].Message)
+	}
+	fields := entries[0].ContextMap()
+	if fields["package"] != "leases" || fields["leaseKey"] != "foo" || fields["error"] != "boom" {
+		t.Errorf("expect the fields to be attached, got: %v", fields)
+	}
+}